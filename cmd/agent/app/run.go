@@ -39,6 +39,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metadata/inventories"
 	"github.com/DataDog/datadog-agent/pkg/otlp"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
+	"github.com/DataDog/datadog-agent/pkg/snmp/netflow"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -404,6 +405,16 @@ func StartAgent() error {
 		}
 	}
 
+	// Start flow server
+	if netflow.IsEnabled() {
+		sender, err := demux.GetDefaultSender()
+		if err != nil {
+			log.Errorf("Failed to get default sender for the flow server: %s", err)
+		} else if err = netflow.StartServer(sender); err != nil {
+			log.Errorf("Failed to start the flow server: %s", err)
+		}
+	}
+
 	if err = common.SetupSystemProbeConfig(sysProbeConfFilePath); err != nil {
 		log.Infof("System probe config not found, disabling pulling system probe info in the status page: %v", err)
 	}
@@ -423,7 +434,7 @@ func StartAgent() error {
 		if config.Datadog.GetBool("log_enabled") {
 			log.Warn(`"log_enabled" is deprecated, use "logs_enabled" instead`)
 		}
-		if _, err := logs.Start(common.AC); err != nil {
+		if _, err := logs.Start(common.AC, demux); err != nil {
 			log.Error("Could not start logs-agent: ", err)
 		}
 	} else {
@@ -483,6 +494,7 @@ func StopAgent() {
 		common.MetadataScheduler.Stop()
 	}
 	traps.StopServer()
+	netflow.StopServer()
 	api.StopServer()
 	clcrunnerapi.StopCLCRunnerServer()
 	jmx.StopJmxfetch()
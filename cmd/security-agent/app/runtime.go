@@ -36,6 +36,7 @@ import (
 	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/policytest"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -108,8 +109,13 @@ var (
 		withGraph         bool
 		differentiateArgs bool
 		outputDirectory   string
-		outputFormat      string
+		outputFormats     []string
+		compression       bool
+		remoteEndpoint    string
+		remoteFormats     []string
+		remoteCompression bool
 		remote            bool
+		securityProfile   bool
 	}{}
 
 	activityDumpGenerateCmd = &cobra.Command{
@@ -172,6 +178,20 @@ var (
 		RunE:  checkPolicies,
 	}
 
+	testPoliciesCmd = &cobra.Command{
+		Use:   "test",
+		Short: "Replay recorded events through policies and report the matching rules",
+		RunE:  testPolicies,
+	}
+
+	testPoliciesArgs = struct {
+		dir     string
+		events  string
+		format  string
+		match   []string
+		noMatch []string
+	}{}
+
 	downloadPolicyCmd = &cobra.Command{
 		Use:   "download",
 		Short: "Download policies",
@@ -222,11 +242,35 @@ func init() {
 		"/tmp/activity_dumps/",
 		"output directory",
 	)
-	activityDumpGenerateDumpCmd.Flags().StringVar(
-		&activityDumpArgs.outputFormat,
+	activityDumpGenerateDumpCmd.Flags().StringSliceVar(
+		&activityDumpArgs.outputFormats,
 		"format",
-		"msgp",
-		"output format. Available options are \"msgp\" and \"json\".",
+		[]string{"msgp"},
+		"output formats of the dump in the output directory. Available options are \"msgp\", \"json\", \"protobuf\", \"jsonl\", \"sbom\" and \"dot\".",
+	)
+	activityDumpGenerateDumpCmd.Flags().BoolVar(
+		&activityDumpArgs.compression,
+		"compression",
+		false,
+		"compress the files written to the output directory with gzip",
+	)
+	activityDumpGenerateDumpCmd.Flags().StringVar(
+		&activityDumpArgs.remoteEndpoint,
+		"remote-endpoint",
+		"",
+		"HTTP endpoint to which the dump should be sent",
+	)
+	activityDumpGenerateDumpCmd.Flags().StringSliceVar(
+		&activityDumpArgs.remoteFormats,
+		"remote-format",
+		[]string{"protobuf"},
+		"output formats of the dump sent to the remote endpoint",
+	)
+	activityDumpGenerateDumpCmd.Flags().BoolVar(
+		&activityDumpArgs.remoteCompression,
+		"remote-compression",
+		true,
+		"compress the dump sent to the remote endpoint with gzip",
 	)
 
 	activityDumpStopCmd.Flags().StringVar(
@@ -249,6 +293,12 @@ func init() {
 		false,
 		"when set, the profile generation will be done by system-probe, otherwise the current security-agent process will generate the profile",
 	)
+	activityDumpGenerateProfileCmd.Flags().BoolVar(
+		&activityDumpArgs.securityProfile,
+		"security-profile",
+		false,
+		"when set, a security profile used for anomaly detection is generated instead of a policy",
+	)
 
 	activityDumpGenerateGraphCmd.Flags().StringVar(
 		&activityDumpArgs.file,
@@ -290,6 +340,14 @@ func init() {
 	commonPolicyCmd.AddCommand(commonCheckPoliciesCmd)
 
 	commonPolicyCmd.AddCommand(commonReloadPoliciesCmd)
+
+	testPoliciesCmd.Flags().StringVar(&testPoliciesArgs.dir, "policies-dir", coreconfig.DefaultRuntimePoliciesDir, "Path to policies directory")
+	testPoliciesCmd.Flags().StringVar(&testPoliciesArgs.events, "events", "", "Path to the file of recorded events")
+	_ = testPoliciesCmd.MarkFlagRequired("events")
+	testPoliciesCmd.Flags().StringVar(&testPoliciesArgs.format, "format", "json", "Format of the events file. Available options are \"json\", for serialized events, and \"msgp\", for activity dumps.")
+	testPoliciesCmd.Flags().StringSliceVar(&testPoliciesArgs.match, "match", nil, "ID of a rule expected to match at least one event")
+	testPoliciesCmd.Flags().StringSliceVar(&testPoliciesArgs.noMatch, "no-match", nil, "ID of a rule expected to match none of the events")
+	commonPolicyCmd.AddCommand(testPoliciesCmd)
 	runtimeCmd.AddCommand(commonPolicyCmd)
 
 	dumpNetworkNamespaceCmd.Flags().BoolVar(&dumpNetworkNamespaceArgs.snapshotInterfaces, "snapshot-interfaces", true, "snapshot the interfaces of each network namespace during the dump")
@@ -340,6 +398,12 @@ func printSecurityActivityDumpMessage(prefix string, msg *api.SecurityActivityDu
 	}
 	fmt.Printf("%s  with graph: %v\n", prefix, msg.WithGraph)
 	fmt.Printf("%s  differentiate args: %v\n", prefix, msg.DifferentiateArgs)
+	if len(msg.Storage) > 0 {
+		fmt.Printf("%s  storage:\n", prefix)
+		for _, storage := range msg.Storage {
+			fmt.Printf("%s    - %s %s (compression: %v): %s\n", prefix, storage.Type, storage.Format, storage.Compression, storage.File)
+		}
+	}
 }
 
 func generateActivityDump(cmd *cobra.Command, args []string) error {
@@ -352,13 +416,35 @@ func generateActivityDump(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("the output directory cannot be empty if \"--graph\" is provided")
 	}
 
+	var storage []*api.StorageRequestParams
+	if len(activityDumpArgs.outputDirectory) > 0 {
+		for _, format := range activityDumpArgs.outputFormats {
+			storage = append(storage, &api.StorageRequestParams{
+				Type:        "local",
+				Format:      format,
+				Compression: activityDumpArgs.compression,
+				Destination: activityDumpArgs.outputDirectory,
+			})
+		}
+	}
+	if len(activityDumpArgs.remoteEndpoint) > 0 {
+		for _, format := range activityDumpArgs.remoteFormats {
+			storage = append(storage, &api.StorageRequestParams{
+				Type:        "remote",
+				Format:      format,
+				Compression: activityDumpArgs.remoteCompression,
+				Destination: activityDumpArgs.remoteEndpoint,
+			})
+		}
+	}
+
 	client, err := secagent.NewRuntimeSecurityClient()
 	if err != nil {
 		return errors.Wrap(err, "unable to create a runtime security client instance")
 	}
 	defer client.Close()
 
-	output, err := client.GenerateActivityDump(activityDumpArgs.comm, int32(activityDumpArgs.timeout), activityDumpArgs.withGraph, activityDumpArgs.differentiateArgs, activityDumpArgs.outputDirectory, activityDumpArgs.outputFormat)
+	output, err := client.GenerateActivityDump(activityDumpArgs.comm, int32(activityDumpArgs.timeout), activityDumpArgs.withGraph, activityDumpArgs.differentiateArgs, storage)
 	if err != nil {
 		return fmt.Errorf("unable send request to system-probe: %w", err)
 	}
@@ -460,7 +546,17 @@ func generateProfileFromActivityDump(cmd *cobra.Command, args []string) error {
 
 	var profilePath string
 
-	if activityDumpArgs.remote {
+	if activityDumpArgs.securityProfile {
+		if activityDumpArgs.remote {
+			return errors.New("security profiles can't be generated by system-probe")
+		}
+
+		output, err := sprobe.GenerateSecurityProfile(activityDumpArgs.file)
+		if err != nil {
+			return fmt.Errorf("security profile generation failed: %w", err)
+		}
+		profilePath = output
+	} else if activityDumpArgs.remote {
 		client, err := secagent.NewRuntimeSecurityClient()
 		if err != nil {
 			return fmt.Errorf("profile generation failed: %w", err)
@@ -573,6 +669,69 @@ func checkPolicies(cmd *cobra.Command, args []string) error {
 	return checkPoliciesInner(checkPoliciesArgs.dir)
 }
 
+func loadRecordedEvents(path string, format string) ([]*model.Event, error) {
+	switch format {
+	case "json":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return policytest.DecodeEvents(f)
+	case "msgp":
+		return sprobe.LoadActivityDumpEvents(path)
+	default:
+		return nil, fmt.Errorf("unknown events format: %s", format)
+	}
+}
+
+func testPolicies(cmd *cobra.Command, args []string) error {
+	var opts rules.Opts
+	opts.
+		WithConstants(model.SECLConstants).
+		WithVariables(model.SECLVariables).
+		WithSupportedDiscarders(sprobe.SupportedDiscarders).
+		WithEventTypeEnabled(map[eval.EventType]bool{"*": true}).
+		WithReservedRuleIDs(sprobe.AllCustomRuleIDs()).
+		WithLegacyFields(model.SECLLegacyFields).
+		WithLogger(&seclog.PatternLogger{})
+
+	model := &model.Model{}
+	ruleSet := rules.NewRuleSet(model, model.NewEvent, &opts)
+
+	if err := rules.LoadPolicies(testPoliciesArgs.dir, ruleSet); err.ErrorOrNil() != nil {
+		return err
+	}
+
+	events, err := loadRecordedEvents(testPoliciesArgs.events, testPoliciesArgs.format)
+	if err != nil {
+		return fmt.Errorf("couldn't load events: %w", err)
+	}
+
+	tester, err := policytest.NewTester(ruleSet, sprobe.GetCapababilities())
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		tester.Test(event)
+	}
+
+	report := tester.Report(policytest.Expectations{
+		Match:   testPoliciesArgs.match,
+		NoMatch: testPoliciesArgs.noMatch,
+	})
+
+	content, _ := json.MarshalIndent(report, "", "\t")
+	fmt.Printf("%s\n", string(content))
+
+	if !report.Succeeded() {
+		return fmt.Errorf("%d expectation(s) not met", len(report.Failures))
+	}
+
+	return nil
+}
+
 func runRuntimeSelfTest(cmd *cobra.Command, args []string) error {
 	client, err := secagent.NewRuntimeSecurityClient()
 	if err != nil {
@@ -617,7 +776,7 @@ func newRuntimeReporter(stopper startstop.Stopper, sourceName, sourceType string
 	stopper.Add(auditor)
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, context)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, nil, endpoints, context, nil, nil)
 	pipelineProvider.Start()
 	stopper.Add(pipelineProvider)
 
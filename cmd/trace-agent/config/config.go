@@ -207,6 +207,20 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 	if coreconfig.Datadog.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
+	if k := "apm_config.tail_sampling"; coreconfig.Datadog.IsSet(k) {
+		if err := coreconfig.Datadog.UnmarshalKey(k, c.TailSampling); err != nil {
+			log.Errorf("Bad format for %q: %v", k, err)
+		}
+	}
+	if k := "apm_config.tail_sampling.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.tail_sampling.decision_wait"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.DecisionWait = getDuration(coreconfig.Datadog.GetInt(k))
+	}
+	if k := "apm_config.tail_sampling.fallback_rate"; coreconfig.Datadog.IsSet(k) {
+		c.TailSampling.FallbackRate = coreconfig.Datadog.GetFloat64(k)
+	}
 
 	if k := "apm_config.ignore_resources"; coreconfig.Datadog.IsSet(k) {
 		c.Ignore["resource"] = coreconfig.Datadog.GetStringSlice(k)
@@ -226,6 +240,17 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			c.ReplaceTags = rt
 		}
 	}
+	if k := "apm_config.transform_rules"; coreconfig.Datadog.IsSet(k) {
+		rules := make([]*config.TransformRule, 0)
+		if err := coreconfig.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"action\": \"rename\",\"key\":\"tag_name\",\"target\":\"new_tag_name\"}]', error: %v", k, err)
+		} else {
+			if err := compileTransformRules(rules); err != nil {
+				osutil.Exitf("transform_rules: %s", err)
+			}
+			c.TransformRules = rules
+		}
+	}
 
 	if coreconfig.Datadog.IsSet("bind_host") || coreconfig.Datadog.IsSet("apm_config.apm_non_local_traffic") {
 		if coreconfig.Datadog.IsSet("bind_host") {
@@ -304,6 +329,41 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			c.RejectTags = append(c.RejectTags, splitTag(tag))
 		}
 	}
+	for _, rules := range []struct {
+		key  string
+		tags *[]*config.TagRegex
+		glob bool
+	}{
+		{"apm_config.filter_tags_regex.require", &c.RequireTagsRegex, false},
+		{"apm_config.filter_tags_regex.reject", &c.RejectTagsRegex, false},
+		{"apm_config.filter_tags_glob.require", &c.RequireTagsRegex, true},
+		{"apm_config.filter_tags_glob.reject", &c.RejectTagsRegex, true},
+	} {
+		if !coreconfig.Datadog.IsSet(rules.key) {
+			continue
+		}
+		for _, tag := range coreconfig.Datadog.GetStringSlice(rules.key) {
+			kv, err := splitTagRegex(tag, rules.glob)
+			if err != nil {
+				log.Errorf("Invalid %q rule %q, ignoring it: %v", rules.key, tag, err)
+				continue
+			}
+			*rules.tags = append(*rules.tags, kv)
+		}
+	}
+	if k := "apm_config.filter_tags.any_span"; coreconfig.Datadog.IsSet(k) {
+		c.FilterTagsAnySpan = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.extra_aggregation_tags"; coreconfig.Datadog.IsSet(k) {
+		for _, tag := range coreconfig.Datadog.GetStringSlice(k) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				c.ExtraAggregationTags = append(c.ExtraAggregationTags, tag)
+			}
+		}
+	}
+	if k := "apm_config.max_extra_aggregation_tag_sets"; coreconfig.Datadog.IsSet(k) {
+		c.MaxExtraAggregationTagSets = coreconfig.Datadog.GetInt(k)
+	}
 
 	// undocumented
 	if coreconfig.Datadog.IsSet("apm_config.max_cpu_percent") {
@@ -463,6 +523,40 @@ func compileReplaceRules(rules []*config.ReplaceRule) error {
 	return nil
 }
 
+// compileTransformRules validates the transform rules and compiles the regular expressions of
+// their conditions. If it fails it returns the first error.
+func compileTransformRules(rules []*config.TransformRule) error {
+	for i, r := range rules {
+		if r.Key == "" {
+			return fmt.Errorf("rule #%d: all rules must have a \"key\"", i)
+		}
+		switch r.Action {
+		case "delete", "hash", "set", "metric_to_meta":
+		case "rename", "copy":
+			if r.Target == "" || r.Target == r.Key {
+				return fmt.Errorf("rule #%d: %q action on key %q must have a \"target\" different from the key", i, r.Action, r.Key)
+			}
+		default:
+			return fmt.Errorf("rule #%d: unknown action %q, must be one of delete, rename, copy, hash, set or metric_to_meta", i, r.Action)
+		}
+		if r.Service != "" {
+			re, err := regexp.Compile(r.Service)
+			if err != nil {
+				return fmt.Errorf("rule #%d: service: %s", i, err)
+			}
+			r.ServiceRe = re
+		}
+		if r.Operation != "" {
+			re, err := regexp.Compile(r.Operation)
+			if err != nil {
+				return fmt.Errorf("rule #%d: operation: %s", i, err)
+			}
+			r.OperationRe = re
+		}
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
@@ -519,6 +613,46 @@ func splitTag(tag string) *config.Tag {
 	return kv
 }
 
+// splitTagRegex splits a "key" or "key:pattern" filtering rule, compiling the pattern as a glob if
+// glob is true, or as a regular expression otherwise.
+func splitTagRegex(tag string, glob bool) (*config.TagRegex, error) {
+	kv := splitTag(tag)
+	t := &config.TagRegex{K: kv.K}
+	if kv.V != "" {
+		compile := regexp.Compile
+		if glob {
+			compile = compileGlob
+		}
+		re, err := compile(kv.V)
+		if err != nil {
+			return nil, err
+		}
+		t.V = re
+		t.Pattern = kv.V
+		t.Glob = glob
+	}
+	return t, nil
+}
+
+// compileGlob compiles the glob pattern into a regular expression matching whole strings, where '*'
+// matches any sequence of characters and '?' any single character.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
 // validate validates if the current configuration is good for the agent to start with.
 func validate(c *config.AgentConfig) error {
 	if len(c.Endpoints) == 0 || c.Endpoints[0].APIKey == "" {
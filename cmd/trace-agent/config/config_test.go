@@ -23,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
 	"github.com/cihub/seelog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -54,6 +55,38 @@ func TestParseRepaceRules(t *testing.T) {
 	}
 }
 
+func TestCompileTransformRules(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		rules := []*config.TransformRule{
+			{Action: "delete", Key: "a"},
+			{Action: "rename", Key: "a", Target: "b", Service: "^web-"},
+			{Action: "copy", Key: "a", Target: "b", Operation: `^http\.`},
+			{Action: "hash", Key: "user.email"},
+			{Action: "set", Key: "team", Value: "apm"},
+			{Action: "metric_to_meta", Key: "db.rows"},
+		}
+		require.NoError(t, compileTransformRules(rules))
+		assert.Equal(t, "^web-", rules[1].ServiceRe.String())
+		assert.Nil(t, rules[1].OperationRe)
+		assert.Equal(t, `^http\.`, rules[2].OperationRe.String())
+		assert.Nil(t, rules[0].ServiceRe)
+	})
+
+	for name, rule := range map[string]*config.TransformRule{
+		"no-key":         {Action: "delete"},
+		"no-action":      {Key: "a"},
+		"unknown-action": {Action: "move", Key: "a"},
+		"no-target":      {Action: "rename", Key: "a"},
+		"same-target":    {Action: "copy", Key: "a", Target: "a"},
+		"bad-service":    {Action: "delete", Key: "a", Service: "["},
+		"bad-operation":  {Action: "delete", Key: "a", Operation: "("},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, compileTransformRules([]*config.TransformRule{{Action: "delete", Key: "ok"}, rule}))
+		})
+	}
+}
+
 func TestSplitTag(t *testing.T) {
 	for _, tt := range []struct {
 		tag string
@@ -86,6 +119,28 @@ func TestSplitTag(t *testing.T) {
 	}
 }
 
+func TestCompileGlob(t *testing.T) {
+	for pattern, tt := range map[string]struct {
+		match, noMatch []string
+	}{
+		"prod-*":     {match: []string{"prod-", "prod-eu"}, noMatch: []string{"prod", "my-prod-eu"}},
+		"*.internal": {match: []string{"db.internal"}, noMatch: []string{"db-internal", "db.internal.com"}},
+		"v?":         {match: []string{"v1", "v2"}, noMatch: []string{"v", "v10"}},
+		"a+b(c)":     {match: []string{"a+b(c)"}, noMatch: []string{"aab(c)"}},
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			re, err := compileGlob(pattern)
+			assert.NoError(t, err)
+			for _, s := range tt.match {
+				assert.True(t, re.MatchString(s), s)
+			}
+			for _, s := range tt.noMatch {
+				assert.False(t, re.MatchString(s), s)
+			}
+		})
+	}
+}
+
 func TestTelemetryEndpointsConfig(t *testing.T) {
 	t.Run("default", func(t *testing.T) {
 		cfg := config.New()
@@ -442,6 +497,15 @@ func TestFullYamlConfig(t *testing.T) {
 
 	assert.ElementsMatch([]*config.Tag{{K: "env", V: "prod"}, {K: "db", V: "mongodb"}}, c.RequireTags)
 	assert.ElementsMatch([]*config.Tag{{K: "outcome", V: "success"}}, c.RejectTags)
+	assert.Equal([]*config.TagRegex{{K: "env", V: regexp.MustCompile("^prod-[a-z]+$"), Pattern: "^prod-[a-z]+$"}}, c.RequireTagsRegex)
+	assert.Equal([]*config.TagRegex{
+		{K: "http.url", V: regexp.MustCompile("/healthz.*"), Pattern: "/healthz.*"},
+		{K: "peer.hostname", V: regexp.MustCompile(`^.*\.internal$`), Pattern: "*.internal", Glob: true},
+	}, c.RejectTagsRegex)
+	// the rules are reported as configured
+	assert.Equal("peer.hostname:*.internal", c.RejectTagsRegex[1].String())
+	assert.Equal([]string{"peer.service", "db.instance"}, c.ExtraAggregationTags)
+	assert.Equal(200, c.MaxExtraAggregationTagSets)
 
 	assert.ElementsMatch([]*config.ReplaceRule{
 		{
@@ -502,6 +566,16 @@ func TestUndocumentedYamlConfig(t *testing.T) {
 	assert.Equal(37.0, c.ErrorTPS)
 	assert.Equal(true, c.DisableRareSampler)
 	assert.Equal(127.0, c.MaxRemoteTPS)
+	assert.Equal(&config.TailSamplingConfig{
+		Enabled:        true,
+		DecisionWait:   30 * time.Second,
+		MaxMemoryBytes: 50000000,
+		FallbackRate:   0.1,
+		Policies: []config.TailSamplingPolicy{
+			{Name: "slow", Type: "latency", LatencyThresholdMs: 500},
+			{Type: "tag", Key: "http.status_code", Value: "500"},
+		},
+	}, c.TailSampling)
 	assert.Equal(1000.0, c.MaxEPS)
 	assert.Equal(25, c.ReceiverPort)
 	assert.Equal(120*time.Second, c.ConnectionResetInterval)
@@ -512,8 +586,11 @@ func TestUndocumentedYamlConfig(t *testing.T) {
 	// Assert Trace Writer
 	assert.Equal(1, c.TraceWriter.ConnectionLimit)
 	assert.Equal(2, c.TraceWriter.QueueSize)
+	assert.Equal("zstd", c.TraceWriter.Compression)
+	assert.Equal(3, c.TraceWriter.CompressionLevel)
 	assert.Equal(5, c.StatsWriter.ConnectionLimit)
 	assert.Equal(6, c.StatsWriter.QueueSize)
+	assert.Equal("", c.StatsWriter.Compression)
 	// analysis legacy
 	assert.Equal(1.0, c.AnalyzedRateByServiceLegacy["db"])
 	assert.Equal(0.9, c.AnalyzedRateByServiceLegacy["web"])
@@ -817,6 +894,21 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_TRANSFORM_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"action":"rename","key":"customer","target":"customer.id","service":"^web"},{"action":"hash","key":"user.email"}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.TransformRule{
+			{Action: "rename", Key: "customer", Target: "customer.id", Service: "^web", ServiceRe: regexp.MustCompile("^web")},
+			{Action: "hash", Key: "user.email"},
+		}, cfg.TransformRules)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
@@ -829,6 +921,34 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(cfg.RequireTags, []*config.Tag{{K: "important1", V: ""}, {K: "important2", V: "value1"}})
 	})
 
+	env = "DD_APM_FILTER_TAGS_REGEX_REJECT"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `bad1:^value.*$ bad2`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.TagRegex{
+			{K: "bad1", V: regexp.MustCompile("^value.*$"), Pattern: "^value.*$"},
+			{K: "bad2"},
+			{K: "peer.hostname", V: regexp.MustCompile(`^.*\.internal$`), Pattern: "*.internal", Glob: true},
+		}, cfg.RejectTagsRegex)
+	})
+
+	env = "DD_APM_EXTRA_AGGREGATION_TAGS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "peer.service region")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]string{"peer.service", "region"}, cfg.ExtraAggregationTags)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REJECT"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
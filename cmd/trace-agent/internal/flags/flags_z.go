@@ -5,7 +5,10 @@
 
 package flags
 
-import "flag"
+import (
+	"flag"
+	"time"
+)
 
 var (
 	// ConfigPath specifies the path to the configuration file.
@@ -27,6 +30,21 @@ var (
 	// MemProfile specifies the path to output memory profiling information to.
 	// When empty, memory profiling is disabled.
 	MemProfile string
+
+	// CapturePath specifies the path of a file to record the trace and stats payloads
+	// received by the agent to. When empty, payloads are not captured.
+	CapturePath string
+
+	// CaptureDuration specifies for how long payloads are captured. When zero, they are
+	// captured until the agent exits.
+	CaptureDuration time.Duration
+
+	// ReplayPath specifies the path of a capture file to replay to the running agent.
+	ReplayPath string
+
+	// ReplaySpeed specifies the speed of the replay relative to the capture. When zero,
+	// payloads are replayed as fast as possible.
+	ReplaySpeed float64
 )
 
 // Win holds a set of flags which will be populated only during the Windows build.
@@ -48,5 +66,11 @@ func init() {
 	flag.StringVar(&CPUProfile, "cpuprofile", "", "Write cpu profile to file")
 	flag.StringVar(&MemProfile, "memprofile", "", "Write memory profile to `file`")
 
+	// capture and replay
+	flag.StringVar(&CapturePath, "capture", "", "Record the received trace and stats payloads to `file`")
+	flag.DurationVar(&CaptureDuration, "capture-duration", 0, "Stop capturing payloads after this duration (default: on exit)")
+	flag.StringVar(&ReplayPath, "replay", "", "Replay the payloads captured to `file` to the running trace agent and exit")
+	flag.Float64Var(&ReplaySpeed, "replay-speed", 1, "Speed of the replay relative to the capture, 0 for as fast as possible")
+
 	registerOSSpecificFlags()
 }
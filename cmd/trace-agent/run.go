@@ -32,6 +32,7 @@ import (
 	tracelog "github.com/DataDog/datadog-agent/pkg/trace/log"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/grpc"
@@ -84,6 +85,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flags.ReplayPath != "" {
+		if err := replayCapture(ctx, cfg, flags.ReplayPath, flags.ReplaySpeed); err != nil {
+			osutil.Exitf("Failed to replay %s: %v", flags.ReplayPath, err)
+		}
+		return
+	}
+
 	if err := coreconfig.SetupLogger(
 		coreconfig.LoggerName("TRACE"),
 		coreconfig.Datadog.GetString("log_level"),
@@ -204,6 +212,11 @@ func Run(ctx context.Context) {
 
 	agnt := agent.NewAgent(ctx, cfg)
 	log.Infof("Trace agent running on host %s", cfg.Hostname)
+	if flags.CapturePath != "" {
+		if err := agnt.Receiver.StartCapture(flags.CapturePath, flags.CaptureDuration); err != nil {
+			osutil.Exitf("Could not start capturing payloads: %v", err)
+		}
+	}
 	if pcfg := profilingConfig(cfg); pcfg != nil {
 		if err := profiling.Start(*pcfg); err != nil {
 			log.Warn(err)
@@ -232,6 +245,21 @@ func Run(ctx context.Context) {
 	}
 }
 
+// replayCapture replays the capture file at path to the trace-agent running with the
+// given configuration.
+func replayCapture(ctx context.Context, cfg *config.AgentConfig, path string, speed float64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	url := fmt.Sprintf("http://%s:%d", cfg.ReceiverHost, cfg.ReceiverPort)
+	fmt.Printf("Replaying %s to %s...\n", path, url)
+	stats, err := replay.Replay(ctx, f, url, speed)
+	fmt.Printf("Replayed %d payloads, %d of which were not accepted.\n", stats.Sent, stats.Failed)
+	return err
+}
+
 type corelogger struct{}
 
 // Trace implements Logger.
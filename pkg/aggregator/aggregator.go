@@ -109,6 +109,7 @@ var (
 	aggregatorEventsFlushed                    = expvar.Int{}
 	aggregatorNumberOfFlush                    = expvar.Int{}
 	aggregatorDogstatsdMetricSample            = expvar.Int{}
+	aggregatorDogstatsdTimestampedPoints       = expvar.Int{}
 	aggregatorChecksMetricSample               = expvar.Int{}
 	aggregatorCheckHistogramBucketMetricSample = expvar.Int{}
 	aggregatorServiceCheck                     = expvar.Int{}
@@ -131,6 +132,8 @@ var (
 		nil, "Count of hostname update")
 	tlmDogstatsdContexts = telemetry.NewGauge("aggregator", "dogstatsd_contexts",
 		nil, "Count the number of dogstatsd contexts in the aggregator")
+	tlmDogstatsdTimestampedPoints = telemetry.NewCounter("aggregator", "dogstatsd_timestamped_points",
+		nil, "Count of dogstatsd points sent with their own timestamp and flushed without aggregation")
 	tlmDogstatsdContextsByMtype = telemetry.NewGauge("aggregator", "dogstatsd_contexts_by_mtype",
 		[]string{"metric_type"}, "Count the number of dogstatsd contexts in the aggregator, by metric type")
 
@@ -163,6 +166,7 @@ func init() {
 	aggregatorExpvars.Set("EventsFlushed", &aggregatorEventsFlushed)
 	aggregatorExpvars.Set("NumberOfFlush", &aggregatorNumberOfFlush)
 	aggregatorExpvars.Set("DogstatsdMetricSample", &aggregatorDogstatsdMetricSample)
+	aggregatorExpvars.Set("DogstatsdTimestampedPoints", &aggregatorDogstatsdTimestampedPoints)
 	aggregatorExpvars.Set("ChecksMetricSample", &aggregatorChecksMetricSample)
 	aggregatorExpvars.Set("ChecksHistogramBucketMetricSample", &aggregatorCheckHistogramBucketMetricSample)
 	aggregatorExpvars.Set("ServiceCheck", &aggregatorServiceCheck)
@@ -188,6 +192,7 @@ func init() {
 	tagsetTlm = newTagsetTelemetry([]uint64{90, 100})
 
 	aggregatorExpvars.Set("MetricTags", expvar.Func(expMetricTags))
+	aggregatorExpvars.Set("DogstatsdContextsLimiter", expvar.Func(expContextsLimiter))
 }
 
 // InitAggregator returns the Singleton instance
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxContextLimitStatsInStatus is the number of metrics over their contexts
+// limit displayed in the agent status.
+const maxContextLimitStatsInStatus = 10
+
+// ContextLimitStats describes a DogStatsD metric that reached its contexts limit.
+type ContextLimitStats = limiter.Stats
+
+// dogstatsdContextsLimiter is the limiter shared by the time samplers of the
+// last created demultiplexer, nil if the contexts are not limited.
+var (
+	dogstatsdContextsLimiter   *limiter.Limiter
+	dogstatsdContextsLimiterMu sync.Mutex
+)
+
+// metricContextsLimit is an item of the `dogstatsd_context_limiter.metric_limits`
+// parameter, overriding the contexts limit of a metric name.
+type metricContextsLimit struct {
+	Name  string `mapstructure:"name"`
+	Limit int    `mapstructure:"limit"`
+}
+
+// getDogstatsdMetricLimits returns the contexts limits of the metric names that
+// don't use `dogstatsd_context_limiter.metric_limit`. They are configured as a
+// list rather than a map because the keys of the maps are lowercased, while the
+// metric names are case-sensitive.
+func getDogstatsdMetricLimits() (map[string]int, error) {
+	var items []metricContextsLimit
+	if err := config.Datadog.UnmarshalKey("dogstatsd_context_limiter.metric_limits", &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	limits := make(map[string]int, len(items))
+	for _, item := range items {
+		if item.Name == "" {
+			return nil, fmt.Errorf("missing metric name in dogstatsd_context_limiter.metric_limits")
+		}
+		limits[item.Name] = item.Limit
+	}
+	return limits, nil
+}
+
+// newDogstatsdContextsLimiter creates the contexts limiter of the time
+// samplers from the configuration. It returns nil if the contexts are not
+// limited.
+func newDogstatsdContextsLimiter() *limiter.Limiter {
+	metricLimits, err := getDogstatsdMetricLimits()
+	var l *limiter.Limiter
+	if err == nil {
+		l, err = limiter.NewWithMetricLimits(
+			config.Datadog.GetInt("dogstatsd_context_limiter.metric_limit"),
+			config.Datadog.GetInt("dogstatsd_context_limiter.origin_limit"),
+			metricLimits,
+			config.Datadog.GetString("dogstatsd_context_limiter.overflow_strategy"),
+		)
+	}
+	if err != nil {
+		log.Errorf("Invalid DogStatsD contexts limiter configuration, the contexts won't be limited: %s", err)
+		l = nil
+	}
+
+	dogstatsdContextsLimiterMu.Lock()
+	dogstatsdContextsLimiter = l
+	dogstatsdContextsLimiterMu.Unlock()
+
+	return l
+}
+
+// GetDogstatsdContextLimitStats returns the stats of the DogStatsD metrics
+// that reached their contexts limit, the most limited first. It returns nil
+// if the contexts are not limited.
+func GetDogstatsdContextLimitStats() []ContextLimitStats {
+	dogstatsdContextsLimiterMu.Lock()
+	l := dogstatsdContextsLimiter
+	dogstatsdContextsLimiterMu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+	return l.Stats()
+}
+
+func expContextsLimiter() interface{} {
+	dogstatsdContextsLimiterMu.Lock()
+	l := dogstatsdContextsLimiter
+	dogstatsdContextsLimiterMu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+
+	dropped, collapsed := l.Totals()
+	stats := l.Stats()
+	if len(stats) > maxContextLimitStatsInStatus {
+		stats = stats[:maxContextLimitStatsInStatus]
+	}
+	return map[string]interface{}{
+		"MetricLimit":      config.Datadog.GetInt("dogstatsd_context_limiter.metric_limit"),
+		"OriginLimit":      config.Datadog.GetInt("dogstatsd_context_limiter.origin_limit"),
+		"OverflowStrategy": config.Datadog.GetString("dogstatsd_context_limiter.overflow_strategy"),
+		"Dropped":          dropped,
+		"Collapsed":        collapsed,
+		"TopMetrics":       stats,
+	}
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetDogstatsdMetricLimits(t *testing.T) {
+	config.Datadog.Set("dogstatsd_context_limiter.metric_limits", []interface{}{
+		map[interface{}]interface{}{"name": "My.Metric", "limit": 10},
+		map[interface{}]interface{}{"name": "other", "limit": 0},
+	})
+	defer config.Datadog.Set("dogstatsd_context_limiter.metric_limits", nil)
+
+	limits, err := getDogstatsdMetricLimits()
+	require.NoError(t, err)
+	// the metric names are case-sensitive
+	assert.Equal(t, map[string]int{"My.Metric": 10, "other": 0}, limits)
+
+	config.Datadog.Set("dogstatsd_context_limiter.metric_limits", []interface{}{
+		map[interface{}]interface{}{"limit": 10},
+	})
+	_, err = getDogstatsdMetricLimits()
+	assert.Error(t, err)
+}
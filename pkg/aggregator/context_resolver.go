@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -21,6 +22,8 @@ type Context struct {
 	mtype      metrics.MetricType
 	taggerTags *tags.Entry
 	metricTags *tags.Entry
+	origin     string
+	overflow   bool // the context has been created with the collapsed tags of contexts over the limits
 }
 
 // Tags returns tags for the context.
@@ -41,6 +44,7 @@ type contextResolver struct {
 	keyGenerator  *ckey.KeyGenerator
 	taggerBuffer  *tagset.HashingTagsAccumulator
 	metricBuffer  *tagset.HashingTagsAccumulator
+	limiter       *limiter.Limiter
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -59,27 +63,68 @@ func newContextResolver(cache *tags.Store) *contextResolver {
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false if the context is over the limits of the limiter and the sample has to be dropped.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, bool) {
+	defer cr.taggerBuffer.Reset()
+	defer cr.metricBuffer.Reset()
+
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer)                  // tags here are not sorted and can contain duplicates
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
-	if _, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		cr.contextsByKey[contextKey] = &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
+	if _, ok := cr.contextsByKey[contextKey]; ok {
+		return contextKey, true
+	}
+
+	name := metricSampleContext.GetName()
+	origin := sampleOrigin(metricSampleContext)
+	overflow := false
+	if cr.limiter != nil && !cr.limiter.Track(name, origin, cr.metricBuffer.Get()) {
+		overflowKeys := cr.limiter.OverflowTagKeys(name)
+		if overflowKeys == nil {
+			return contextKey, false
 		}
-		cr.countsByMtype[mtype]++
+
+		collapsedTags := limiter.CollapseTags(cr.metricBuffer.Get(), overflowKeys)
+		cr.metricBuffer.Reset()
+		cr.metricBuffer.Append(collapsedTags...)
+		contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+		_, tracked := cr.contextsByKey[contextKey]
+		if !cr.limiter.TrackOverflow(name, contextKey, !tracked) {
+			return contextKey, false
+		}
+		if tracked {
+			return contextKey, true
+		}
+		overflow = true
+	}
+
+	mtype := metricSampleContext.GetMetricType()
+	cr.contextsByKey[contextKey] = &Context{
+		Name:       name,
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		origin:     origin,
+		overflow:   overflow,
 	}
+	cr.countsByMtype[mtype]++
 
-	cr.taggerBuffer.Reset()
-	cr.metricBuffer.Reset()
+	return contextKey, true
+}
 
-	return contextKey
+// sampleOrigin returns the origin of the samples received by DogStatsD, used
+// to enforce the contexts limit per origin.
+func sampleOrigin(metricSampleContext metrics.MetricSampleContext) string {
+	sample, ok := metricSampleContext.(*metrics.MetricSample)
+	if !ok {
+		return ""
+	}
+	if sample.OriginFromUDS != "" {
+		return sample.OriginFromUDS
+	}
+	return sample.OriginFromClient
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -98,6 +143,13 @@ func (cr *contextResolver) removeKeys(expiredContextKeys []ckey.ContextKey) {
 
 		if context != nil {
 			cr.countsByMtype[context.mtype]--
+			if cr.limiter != nil {
+				if context.overflow {
+					cr.limiter.RemoveOverflow(context.Name, expiredContextKey)
+				} else {
+					cr.limiter.Remove(context.Name, context.origin, context.metricTags.Tags())
+				}
+			}
 			context.release()
 		}
 	}
@@ -115,9 +167,11 @@ type timestampContextResolver struct {
 	lastSeenByKey map[ckey.ContextKey]float64
 }
 
-func newTimestampContextResolver(cache *tags.Store) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, contextsLimiter *limiter.Limiter) *timestampContextResolver {
+	resolver := newContextResolver(cache)
+	resolver.limiter = contextsLimiter
 	return &timestampContextResolver{
-		resolver:      newContextResolver(cache),
+		resolver:      resolver,
 		lastSeenByKey: make(map[ckey.ContextKey]float64),
 	}
 }
@@ -133,11 +187,14 @@ func (cr *timestampContextResolver) updateTrackedContext(contextKey ckey.Context
 	return nil
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext)
-	cr.lastSeenByKey[contextKey] = currentTimestamp
-	return contextKey
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false if the sample has to be dropped because its context is over the limits.
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp float64) (ckey.ContextKey, bool) {
+	contextKey, ok := cr.resolver.trackContext(metricSampleContext)
+	if ok {
+		cr.lastSeenByKey[contextKey] = currentTimestamp
+	}
+	return contextKey, ok
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -194,7 +251,7 @@ func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store) *c
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
 func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext)
+	contextKey, _ := cr.resolver.trackContext(metricSampleContext) // no limiter, contexts are always tracked
 	cr.expireCountByKey[contextKey] = cr.expireCount
 	return contextKey
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -81,9 +82,9 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 	contextResolver := newContextResolver(store)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1)
-	contextKey2 := contextResolver.trackContext(&mSample2)
-	contextKey3 := contextResolver.trackContext(&mSample3)
+	contextKey1, _ := contextResolver.trackContext(&mSample1)
+	contextKey2, _ := contextResolver.trackContext(&mSample2)
+	contextKey3, _ := contextResolver.trackContext(&mSample3)
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1]
@@ -122,11 +123,11 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo", "bar", "baz"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store)
+	contextResolver := newTimestampContextResolver(store, nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4)
-	contextKey2 := contextResolver.trackContext(&mSample2, 6)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6)
 
 	// With an expireTimestap of 3, both contexts are still valid
 	assert.Len(t, contextResolver.expireContexts(3), 0)
@@ -151,6 +152,106 @@ func TestExpireContexts(t *testing.T) {
 	testWithTagsStore(t, testExpireContexts)
 }
 
+func testLimitedContexts(t *testing.T, store *tags.Store) {
+	contextsLimiter, err := limiter.New(2, 0, limiter.StrategyDrop)
+	require.NoError(t, err)
+	contextResolver := newTimestampContextResolver(store, contextsLimiter)
+
+	sample := func(tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: "my.metric.name", Mtype: metrics.GaugeType, Tags: tags}
+	}
+
+	contextKey1, ok := contextResolver.trackContext(sample("request_id:1"), 4)
+	assert.True(t, ok)
+	_, ok = contextResolver.trackContext(sample("request_id:2"), 6)
+	assert.True(t, ok)
+	_, ok = contextResolver.trackContext(sample("request_id:3"), 6)
+	assert.False(t, ok)
+	assert.Equal(t, 2, contextResolver.length())
+
+	// already tracked contexts are not limited
+	_, ok = contextResolver.trackContext(sample("request_id:1"), 6)
+	assert.True(t, ok)
+
+	// expired contexts free some room
+	contextResolver.lastSeenByKey[contextKey1] = 4
+	assert.Len(t, contextResolver.expireContexts(5), 1)
+	_, ok = contextResolver.trackContext(sample("request_id:3"), 6)
+	assert.True(t, ok)
+	assert.Equal(t, 2, contextResolver.length())
+}
+func TestLimitedContexts(t *testing.T) {
+	testWithTagsStore(t, testLimitedContexts)
+}
+
+func testCollapsedContexts(t *testing.T, store *tags.Store) {
+	contextsLimiter, err := limiter.New(2, 0, limiter.StrategyCollapse)
+	require.NoError(t, err)
+	contextResolver := newTimestampContextResolver(store, contextsLimiter)
+
+	sample := func(tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: "my.metric.name", Mtype: metrics.GaugeType, Tags: tags}
+	}
+
+	_, ok := contextResolver.trackContext(sample("env:prod", "request_id:1"), 4)
+	assert.True(t, ok)
+	_, ok = contextResolver.trackContext(sample("env:prod", "request_id:2"), 4)
+	assert.True(t, ok)
+
+	// the new contexts are collapsed into the same overflow context
+	overflowKey1, ok := contextResolver.trackContext(sample("env:prod", "request_id:3"), 6)
+	assert.True(t, ok)
+	overflowKey2, ok := contextResolver.trackContext(sample("request_id:4", "env:prod"), 6)
+	assert.True(t, ok)
+	assert.Equal(t, overflowKey1, overflowKey2)
+	assert.Equal(t, 3, contextResolver.length())
+
+	context, ok := contextResolver.get(overflowKey1)
+	require.True(t, ok)
+	assert.True(t, context.overflow)
+	assertContext(t, context, "my.metric.name", []string{"env:prod", "request_id:overflow"}, "")
+
+	// the overflow context expires like the others
+	assert.Len(t, contextResolver.expireContexts(7), 3)
+	assert.Equal(t, []limiter.Stats{{Name: "my.metric.name", Collapsed: 2}}, contextsLimiter.Stats())
+}
+func TestCollapsedContexts(t *testing.T) {
+	testWithTagsStore(t, testCollapsedContexts)
+}
+
+func testCollapsedContextsSharedLimiter(t *testing.T, store *tags.Store) {
+	contextsLimiter, err := limiter.New(2, 0, limiter.StrategyCollapse)
+	require.NoError(t, err)
+	contextResolver1 := newTimestampContextResolver(store, contextsLimiter)
+	contextResolver2 := newTimestampContextResolver(store, contextsLimiter)
+
+	sample := func(tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: "my.metric.name", Mtype: metrics.GaugeType, Tags: tags}
+	}
+
+	_, ok := contextResolver1.trackContext(sample("env:prod", "request_id:1"), 4)
+	assert.True(t, ok)
+	_, ok = contextResolver2.trackContext(sample("env:prod", "request_id:2"), 4)
+	assert.True(t, ok)
+
+	// both time samplers create the same overflow context, which is counted once
+	overflowKey1, ok := contextResolver1.trackContext(sample("env:prod", "request_id:3"), 6)
+	assert.True(t, ok)
+	overflowKey2, ok := contextResolver2.trackContext(sample("env:prod", "request_id:4"), 6)
+	assert.True(t, ok)
+	assert.Equal(t, overflowKey1, overflowKey2)
+	assert.Equal(t, []limiter.Stats{{Name: "my.metric.name", Contexts: 2, OverflowContexts: 1, Collapsed: 2}}, contextsLimiter.Stats())
+
+	// it is tracked until it expired in both of them
+	assert.Len(t, contextResolver1.expireContexts(7), 2)
+	assert.Equal(t, []limiter.Stats{{Name: "my.metric.name", Contexts: 1, OverflowContexts: 1, Collapsed: 2}}, contextsLimiter.Stats())
+	assert.Len(t, contextResolver2.expireContexts(7), 2)
+	assert.Equal(t, []limiter.Stats{{Name: "my.metric.name", Collapsed: 2}}, contextsLimiter.Stats())
+}
+func TestCollapsedContextsSharedLimiter(t *testing.T) {
+	testWithTagsStore(t, testCollapsedContextsSharedLimiter)
+}
+
 func testCountBasedExpireContexts(t *testing.T, store *tags.Store) {
 	mSample1 := metrics.MetricSample{Name: "my.metric.name1"}
 	mSample2 := metrics.MetricSample{Name: "my.metric.name2"}
@@ -181,7 +282,7 @@ func TestCountBasedExpireContexts(t *testing.T) {
 func testTagDeduplication(t *testing.T, store *tags.Store) {
 	resolver := newContextResolver(store)
 
-	ckey := resolver.trackContext(&metrics.MetricSample{
+	ckey, _ := resolver.trackContext(&metrics.MetricSample{
 		Name: "foo",
 		Tags: []string{"bar", "bar"},
 	})
@@ -0,0 +1,398 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+const (
+	// StrategyDrop drops the samples of the contexts over the limit.
+	StrategyDrop = "drop"
+	// StrategyCollapse replaces the values of the tag keys with the most
+	// distinct values with OverflowValue, so that the samples of the contexts
+	// over the limit are aggregated together.
+	StrategyCollapse = "collapse"
+
+	// OverflowValue is the tag value used by StrategyCollapse.
+	OverflowValue = "overflow"
+)
+
+var (
+	tlmDropped = telemetry.NewCounter("aggregator", "dogstatsd_contexts_limit_dropped",
+		nil, "Count of samples dropped because their metric or origin reached its contexts limit")
+	tlmCollapsed = telemetry.NewCounter("aggregator", "dogstatsd_contexts_limit_collapsed",
+		nil, "Count of samples whose tags were collapsed because their metric or origin reached its contexts limit")
+)
+
+// metricEntry tracks the live contexts of a metric name.
+type metricEntry struct {
+	contexts int
+	// overflowContexts counts the time samplers tracking each context created
+	// with collapsed tags, so that a context shared by several time samplers
+	// is only counted once.
+	overflowContexts map[ckey.ContextKey]int
+	dropped          uint64
+	collapsed        uint64
+	// valuesByKey counts the live contexts using each value of each tag key,
+	// it is only maintained by the collapse strategy.
+	valuesByKey map[string]map[string]int
+}
+
+// Limiter bounds the number of live contexts per metric name and per origin.
+//
+// A single Limiter is shared by all the time samplers, it is safe for
+// concurrent use.
+type Limiter struct {
+	mu          sync.Mutex
+	metricLimit int
+	// metricLimits overrides metricLimit for some metric names.
+	metricLimits map[string]int
+	originLimit  int
+	strategy     string
+	byMetric     map[string]*metricEntry
+	byOrigin     map[string]int
+	dropped      uint64
+	collapsed    uint64
+}
+
+// Stats describes the state of a metric that reached its contexts limit.
+type Stats struct {
+	Name             string `json:"name"`
+	Contexts         int    `json:"contexts"`
+	OverflowContexts int    `json:"overflow_contexts"`
+	Dropped          uint64 `json:"dropped"`
+	Collapsed        uint64 `json:"collapsed"`
+}
+
+// New returns a Limiter allowing metricLimit live contexts per metric name and
+// originLimit live contexts per origin. A limit of 0 disables the
+// corresponding check. The contexts created with collapsed tags are limited
+// separately, to metricLimit per metric name, or originLimit if metricLimit is
+// disabled. It returns nil, and no error, when both limits are disabled.
+func New(metricLimit, originLimit int, strategy string) (*Limiter, error) {
+	return NewWithMetricLimits(metricLimit, originLimit, nil, strategy)
+}
+
+// NewWithMetricLimits returns a Limiter like New, where the metrics listed in
+// metricLimits get their own limit instead of metricLimit. A limit of 0 in
+// metricLimits disables the limit of the metric.
+func NewWithMetricLimits(metricLimit, originLimit int, metricLimits map[string]int, strategy string) (*Limiter, error) {
+	if metricLimit < 0 || originLimit < 0 {
+		return nil, fmt.Errorf("invalid contexts limit: limits must be positive")
+	}
+	for name, limit := range metricLimits {
+		if limit < 0 {
+			return nil, fmt.Errorf("invalid contexts limit of the metric %q: limits must be positive", name)
+		}
+	}
+	if strategy != StrategyDrop && strategy != StrategyCollapse {
+		return nil, fmt.Errorf("unknown contexts limit overflow strategy %q, supported strategies are %q and %q", strategy, StrategyDrop, StrategyCollapse)
+	}
+	if metricLimit == 0 && originLimit == 0 && len(metricLimits) == 0 {
+		return nil, nil
+	}
+	return &Limiter{
+		metricLimit:  metricLimit,
+		metricLimits: metricLimits,
+		originLimit:  originLimit,
+		strategy:     strategy,
+		byMetric:     make(map[string]*metricEntry),
+		byOrigin:     make(map[string]int),
+	}, nil
+}
+
+// Track registers a new context of the given metric and origin, unless it
+// would exceed one of the limits, in which case it returns false and the
+// context must not be created.
+func (l *Limiter) Track(name, origin string, tags []string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.byMetric[name]
+	if limit := l.limitOf(name); entry != nil && limit > 0 && entry.contexts >= limit {
+		return false
+	}
+	if origin != "" && l.originLimit > 0 && l.byOrigin[origin] >= l.originLimit {
+		return false
+	}
+
+	if entry == nil {
+		entry = &metricEntry{}
+		l.byMetric[name] = entry
+	}
+	entry.contexts++
+	if origin != "" {
+		l.byOrigin[origin]++
+	}
+
+	if l.strategy == StrategyCollapse {
+		entry.addValues(tags)
+	}
+
+	return true
+}
+
+// OverflowTagKeys returns the tag keys of the metric whose values have to be
+// replaced by OverflowValue for a context that Track refused, before calling
+// TrackOverflow. It returns nil if the sample has to be dropped instead, in
+// which case the drop is counted.
+func (l *Limiter) OverflowTagKeys(name string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.byMetric[name]
+	if entry == nil {
+		// the origin is over its limit before this metric has any context
+		entry = &metricEntry{}
+		l.byMetric[name] = entry
+	}
+
+	var keys []string
+	if l.strategy == StrategyCollapse {
+		keys = entry.mostDistinctKeys()
+	}
+
+	if len(keys) == 0 {
+		l.countDropped(entry)
+		return nil
+	}
+	return keys
+}
+
+func (l *Limiter) countDropped(entry *metricEntry) {
+	entry.dropped++
+	l.dropped++
+	tlmDropped.Inc()
+}
+
+// mostDistinctKeys returns the tag keys with the highest number of distinct
+// values, provided there is more than one value.
+func (e *metricEntry) mostDistinctKeys() []string {
+	var keys []string
+	max := 1
+	for key, values := range e.valuesByKey {
+		switch n := len(values); {
+		case n > max:
+			max = n
+			keys = append(keys[:0], key)
+		case n == max && n > 1:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (e *metricEntry) addValues(tags []string) {
+	if e.valuesByKey == nil {
+		e.valuesByKey = make(map[string]map[string]int)
+	}
+	for _, tag := range tags {
+		key, value, ok := splitTag(tag)
+		if !ok {
+			continue
+		}
+		values := e.valuesByKey[key]
+		if values == nil {
+			values = make(map[string]int)
+			e.valuesByKey[key] = values
+		}
+		values[value]++
+	}
+}
+
+func (e *metricEntry) removeValues(tags []string) {
+	for _, tag := range tags {
+		key, value, ok := splitTag(tag)
+		if !ok {
+			continue
+		}
+		values := e.valuesByKey[key]
+		if values[value] <= 1 {
+			delete(values, value)
+		} else {
+			values[value]--
+		}
+		if len(values) == 0 {
+			delete(e.valuesByKey, key)
+		}
+	}
+}
+
+// TrackOverflow registers a sample whose tags were collapsed into the context
+// key, along with the context itself if it is new to the time sampler. It
+// returns false if the context is new to the limiter and the metric already
+// has too many contexts with collapsed tags, in which case the sample has to
+// be dropped and the drop is counted.
+func (l *Limiter) TrackOverflow(name string, key ckey.ContextKey, isNew bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.byMetric[name]
+	if entry == nil {
+		entry = &metricEntry{}
+		l.byMetric[name] = entry
+	}
+
+	if isNew {
+		samplers, tracked := entry.overflowContexts[key]
+		if !tracked && len(entry.overflowContexts) >= l.overflowLimit(name) {
+			l.countDropped(entry)
+			return false
+		}
+		if entry.overflowContexts == nil {
+			entry.overflowContexts = make(map[ckey.ContextKey]int)
+		}
+		entry.overflowContexts[key] = samplers + 1
+	}
+
+	entry.collapsed++
+	l.collapsed++
+	tlmCollapsed.Inc()
+	return true
+}
+
+// limitOf returns the maximum number of live contexts of a metric, 0 if it
+// is not limited.
+func (l *Limiter) limitOf(name string) int {
+	if limit, ok := l.metricLimits[name]; ok {
+		return limit
+	}
+	return l.metricLimit
+}
+
+// overflowLimit returns the maximum number of contexts with collapsed tags of a metric.
+func (l *Limiter) overflowLimit(name string) int {
+	if limit := l.limitOf(name); limit > 0 {
+		return limit
+	}
+	return l.originLimit
+}
+
+// Remove unregisters a context that expired.
+func (l *Limiter) Remove(name, origin string, tags []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.byMetric[name]
+	if entry == nil {
+		return
+	}
+
+	entry.contexts--
+	if origin != "" {
+		if l.byOrigin[origin] <= 1 {
+			delete(l.byOrigin, origin)
+		} else {
+			l.byOrigin[origin]--
+		}
+	}
+	if l.strategy == StrategyCollapse {
+		entry.removeValues(tags)
+	}
+	l.cleanup(name, entry)
+}
+
+// RemoveOverflow unregisters a context created with collapsed tags that
+// expired in a time sampler.
+func (l *Limiter) RemoveOverflow(name string, key ckey.ContextKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.byMetric[name]
+	if entry == nil {
+		return
+	}
+
+	if entry.overflowContexts[key] <= 1 {
+		delete(entry.overflowContexts, key)
+	} else {
+		entry.overflowContexts[key]--
+	}
+	l.cleanup(name, entry)
+}
+
+func (l *Limiter) cleanup(name string, entry *metricEntry) {
+	// keep the entries of the metrics that reached their limit so that
+	// their stats are reported until the agent restarts.
+	if entry.contexts <= 0 && len(entry.overflowContexts) == 0 && entry.dropped == 0 && entry.collapsed == 0 {
+		delete(l.byMetric, name)
+	}
+}
+
+// Totals returns the total count of dropped and collapsed samples.
+func (l *Limiter) Totals() (dropped uint64, collapsed uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped, l.collapsed
+}
+
+// Stats returns the stats of the metrics that reached their limit, the metrics
+// with the most dropped or collapsed samples first.
+func (l *Limiter) Stats() []Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var stats []Stats
+	for name, entry := range l.byMetric {
+		if entry.dropped == 0 && entry.collapsed == 0 {
+			continue
+		}
+		stats = append(stats, Stats{
+			Name:             name,
+			Contexts:         entry.contexts,
+			OverflowContexts: len(entry.overflowContexts),
+			Dropped:          entry.dropped,
+			Collapsed:        entry.collapsed,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		li := stats[i].Dropped + stats[i].Collapsed
+		lj := stats[j].Dropped + stats[j].Collapsed
+		if li != lj {
+			return li > lj
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// CollapseTags replaces the value of the given tag keys with OverflowValue.
+func CollapseTags(tags []string, keys []string) []string {
+	collapsed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		key, _, ok := splitTag(tag)
+		if ok && contains(keys, key) {
+			tag = key + ":" + OverflowValue
+		}
+		collapsed = append(collapsed, tag)
+	}
+	return collapsed
+}
+
+func splitTag(tag string) (string, string, bool) {
+	idx := strings.IndexByte(tag, ':')
+	if idx <= 0 {
+		return "", "", false
+	}
+	return tag[:idx], tag[idx+1:], true
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+)
+
+func TestNew(t *testing.T) {
+	l, err := New(0, 0, StrategyDrop)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+
+	_, err = New(-1, 0, StrategyDrop)
+	assert.Error(t, err)
+
+	_, err = New(10, 0, "unknown")
+	assert.Error(t, err)
+
+	l, err = New(10, 0, StrategyCollapse)
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+
+	_, err = NewWithMetricLimits(10, 0, map[string]int{"foo": -1}, StrategyDrop)
+	assert.Error(t, err)
+
+	l, err = NewWithMetricLimits(0, 0, map[string]int{"foo": 1}, StrategyDrop)
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+}
+
+func TestMetricLimit(t *testing.T) {
+	l, err := New(2, 0, StrategyDrop)
+	require.NoError(t, err)
+
+	assert.True(t, l.Track("foo", "", []string{"id:1"}))
+	assert.True(t, l.Track("foo", "", []string{"id:2"}))
+	assert.False(t, l.Track("foo", "", []string{"id:3"}))
+	assert.Nil(t, l.OverflowTagKeys("foo"))
+
+	// other metrics are not affected
+	assert.True(t, l.Track("bar", "", []string{"id:3"}))
+
+	// expired contexts free some room
+	l.Remove("foo", "", []string{"id:1"})
+	assert.True(t, l.Track("foo", "", []string{"id:3"}))
+
+	dropped, collapsed := l.Totals()
+	assert.EqualValues(t, 1, dropped)
+	assert.EqualValues(t, 0, collapsed)
+	assert.Equal(t, []Stats{{Name: "foo", Contexts: 2, Dropped: 1}}, l.Stats())
+}
+
+func TestOriginLimit(t *testing.T) {
+	l, err := New(0, 2, StrategyDrop)
+	require.NoError(t, err)
+
+	assert.True(t, l.Track("foo", "container_id://abc", nil))
+	assert.True(t, l.Track("bar", "container_id://abc", nil))
+	assert.False(t, l.Track("baz", "container_id://abc", nil))
+	assert.Nil(t, l.OverflowTagKeys("baz"))
+
+	// other origins and samples without origin are not affected
+	assert.True(t, l.Track("baz", "container_id://def", nil))
+	assert.True(t, l.Track("baz", "", nil))
+	assert.True(t, l.Track("baz", "", nil))
+
+	l.Remove("foo", "container_id://abc", nil)
+	assert.True(t, l.Track("baz", "container_id://abc", nil))
+
+	assert.Equal(t, []Stats{{Name: "baz", Contexts: 4, Dropped: 1}}, l.Stats())
+}
+
+func TestCollapse(t *testing.T) {
+	l, err := New(3, 0, StrategyCollapse)
+	require.NoError(t, err)
+
+	assert.True(t, l.Track("foo", "", []string{"env:prod", "request_id:1"}))
+	assert.True(t, l.Track("foo", "", []string{"env:prod", "request_id:2"}))
+	assert.True(t, l.Track("foo", "", []string{"env:staging", "request_id:3"}))
+	assert.False(t, l.Track("foo", "", []string{"env:prod", "request_id:4"}))
+
+	keys := l.OverflowTagKeys("foo")
+	assert.Equal(t, []string{"request_id"}, keys)
+	assert.Equal(t, []string{"env:prod", "request_id:overflow", "flag"}, CollapseTags([]string{"env:prod", "request_id:4", "flag"}, keys))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), true))
+
+	dropped, collapsed := l.Totals()
+	assert.EqualValues(t, 0, dropped)
+	assert.EqualValues(t, 1, collapsed)
+	assert.Equal(t, []Stats{{Name: "foo", Contexts: 3, OverflowContexts: 1, Collapsed: 1}}, l.Stats())
+
+	// the values of the expired contexts are forgotten
+	l.Remove("foo", "", []string{"env:prod", "request_id:1"})
+	l.Remove("foo", "", []string{"env:prod", "request_id:2"})
+	l.RemoveOverflow("foo", ckey.ContextKey(1))
+	assert.Equal(t, map[string]map[string]int{
+		"env":        {"staging": 1},
+		"request_id": {"3": 1},
+	}, l.byMetric["foo"].valuesByKey)
+}
+
+func TestOverflowLimit(t *testing.T) {
+	l, err := New(2, 0, StrategyCollapse)
+	require.NoError(t, err)
+
+	assert.True(t, l.Track("foo", "", []string{"env:prod", "request_id:1"}))
+	assert.True(t, l.Track("foo", "", []string{"env:staging", "request_id:2"}))
+	assert.False(t, l.Track("foo", "", []string{"env:dev", "request_id:3"}))
+	assert.NotNil(t, l.OverflowTagKeys("foo"))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), true))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(2), true))
+
+	// the contexts with collapsed tags are limited too
+	assert.False(t, l.TrackOverflow("foo", ckey.ContextKey(3), true))
+	// the samples of the tracked ones are still collapsed
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), false))
+
+	dropped, collapsed := l.Totals()
+	assert.EqualValues(t, 1, dropped)
+	assert.EqualValues(t, 3, collapsed)
+	assert.Equal(t, []Stats{{Name: "foo", Contexts: 2, OverflowContexts: 2, Dropped: 1, Collapsed: 3}}, l.Stats())
+
+	// expired contexts free some room
+	l.RemoveOverflow("foo", ckey.ContextKey(1))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(3), true))
+}
+
+func TestOverflowSharedBySamplers(t *testing.T) {
+	l, err := New(1, 0, StrategyCollapse)
+	require.NoError(t, err)
+
+	// the same context is created with collapsed tags by two time samplers
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), true))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), true))
+	assert.Equal(t, []Stats{{Name: "foo", OverflowContexts: 1, Collapsed: 2}}, l.Stats())
+
+	// it is tracked until it expired in both of them
+	l.RemoveOverflow("foo", ckey.ContextKey(1))
+	assert.False(t, l.TrackOverflow("foo", ckey.ContextKey(2), true))
+	l.RemoveOverflow("foo", ckey.ContextKey(1))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(2), true))
+}
+
+func TestCollapseWithoutDistinctValues(t *testing.T) {
+	l, err := New(1, 0, StrategyCollapse)
+	require.NoError(t, err)
+
+	// nothing to collapse, the sample is dropped
+	assert.True(t, l.Track("foo", "", []string{"env:prod"}))
+	assert.False(t, l.Track("foo", "", []string{"env:prod", "flag"}))
+	assert.Nil(t, l.OverflowTagKeys("foo"))
+
+	dropped, collapsed := l.Totals()
+	assert.EqualValues(t, 1, dropped)
+	assert.EqualValues(t, 0, collapsed)
+}
+
+func TestRemoveUnlimitedMetric(t *testing.T) {
+	l, err := New(1, 0, StrategyDrop)
+	require.NoError(t, err)
+
+	assert.True(t, l.Track("foo", "", nil))
+	l.Remove("foo", "", nil)
+	assert.Len(t, l.byMetric, 0)
+	assert.Len(t, l.Stats(), 0)
+}
+
+func TestMetricLimits(t *testing.T) {
+	l, err := NewWithMetricLimits(1, 0, map[string]int{"foo": 2, "bar": 0}, StrategyDrop)
+	require.NoError(t, err)
+
+	// foo has its own limit
+	assert.True(t, l.Track("foo", "", []string{"id:1"}))
+	assert.True(t, l.Track("foo", "", []string{"id:2"}))
+	assert.False(t, l.Track("foo", "", []string{"id:3"}))
+
+	// bar is not limited
+	for _, tag := range []string{"id:1", "id:2", "id:3"} {
+		assert.True(t, l.Track("bar", "", []string{tag}))
+	}
+
+	// the other metrics use the default limit
+	assert.True(t, l.Track("baz", "", []string{"id:1"}))
+	assert.False(t, l.Track("baz", "", []string{"id:2"}))
+}
+
+func TestMetricLimitsOverflow(t *testing.T) {
+	l, err := NewWithMetricLimits(1, 0, map[string]int{"foo": 2}, StrategyCollapse)
+	require.NoError(t, err)
+
+	// the contexts with collapsed tags are limited like the metric
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(1), true))
+	assert.True(t, l.TrackOverflow("foo", ckey.ContextKey(2), true))
+	assert.False(t, l.TrackOverflow("foo", ckey.ContextKey(3), true))
+
+	assert.True(t, l.TrackOverflow("bar", ckey.ContextKey(1), true))
+	assert.False(t, l.TrackOverflow("bar", ckey.ContextKey(2), true))
+}
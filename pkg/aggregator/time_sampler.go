@@ -7,6 +7,7 @@ package aggregator
 
 import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -20,6 +21,18 @@ type SerieSignature struct {
 	nameSuffix string
 }
 
+// timestampedPoint is a point of a sample that is not aggregated because it was
+// sent with its own timestamp. It is flushed as is at the next flush.
+type timestampedPoint struct {
+	contextKey ckey.ContextKey
+	mType      metrics.APIMetricType
+	point      metrics.Point
+}
+
+// maxTimestampedPointsRetainedCapacity is the capacity above which the
+// timestampedPoints slice is released after a flush instead of being reused.
+const maxTimestampedPointsRetainedCapacity = 4096
+
 // TimeSamplerID is a type ID for sharded time samplers.
 type TimeSamplerID int
 
@@ -31,14 +44,16 @@ type TimeSampler struct {
 	counterLastSampledByContext map[ckey.ContextKey]float64
 	lastCutOffTime              int64
 	sketchMap                   sketchMap
+	timestampedPoints           []timestampedPoint
 
 	// id is a number to differentiate multiple time samplers
 	// since we start running more than one with the demultiplexer introduction
 	id TimeSamplerID
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store) *TimeSampler {
+// NewTimeSampler returns a newly initialized TimeSampler. The contextsLimiter
+// is shared by all the time samplers, it can be nil to not limit the contexts.
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, contextsLimiter *limiter.Limiter) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -47,7 +62,7 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store) *TimeSa
 
 	s := &TimeSampler{
 		interval:                    interval,
-		contextResolver:             newTimestampContextResolver(cache),
+		contextResolver:             newTimestampContextResolver(cache, contextsLimiter),
 		metricsByTimestamp:          map[int64]metrics.ContextMetrics{},
 		counterLastSampledByContext: map[ckey.ContextKey]float64{},
 		sketchMap:                   make(sketchMap),
@@ -66,13 +81,21 @@ func (s *TimeSampler) isBucketStillOpen(bucketStartTimestamp, timestamp int64) b
 }
 
 func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float64) {
+	if metricSample.NoAggregation {
+		s.sampleTimestamped(metricSample, timestamp)
+		return
+	}
+
 	// use the timestamp provided in the sample if any
 	if metricSample.Timestamp > 0 {
 		timestamp = metricSample.Timestamp
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, timestamp)
+	contextKey, ok := s.contextResolver.trackContext(metricSample, timestamp)
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
@@ -96,6 +119,80 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 		}
 	}
 }
+
+// sampleTimestamped stores a gauge or count sample with its own timestamp
+// without aggregating it. The context is tracked with the time the sample has
+// been received so that it does not expire before being flushed.
+func (s *TimeSampler) sampleTimestamped(metricSample *metrics.MetricSample, timestamp float64) {
+	var point timestampedPoint
+	switch metricSample.Mtype {
+	case metrics.GaugeType:
+		point.mType = metrics.APIGaugeType
+		point.point = metrics.Point{Ts: metricSample.Timestamp, Value: metricSample.Value}
+	case metrics.CounterType, metrics.CountType:
+		point.mType = metrics.APICountType
+		point.point = metrics.Point{Ts: metricSample.Timestamp, Value: metricSample.Value * (1 / metricSample.SampleRate)}
+	default:
+		log.Debugf("TimeSampler #%d Ignoring timestamped sample '%s' on host '%s' and tags '%s': unsupported metric type %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, metricSample.Mtype)
+		return
+	}
+	contextKey, ok := s.contextResolver.trackContext(metricSample, timestamp)
+	if !ok {
+		return
+	}
+	point.contextKey = contextKey
+	s.timestampedPoints = append(s.timestampedPoints, point)
+}
+
+// flushTimestampedPoints sends the non-aggregated points received since the
+// last flush, grouping the points of the same context in a single serie.
+func (s *TimeSampler) flushTimestampedPoints(series metrics.SerieSink) {
+	if len(s.timestampedPoints) == 0 {
+		return
+	}
+
+	type serieKey struct {
+		contextKey ckey.ContextKey
+		mType      metrics.APIMetricType
+	}
+	seriesByKey := make(map[serieKey]*metrics.Serie)
+	for _, p := range s.timestampedPoints {
+		key := serieKey{p.contextKey, p.mType}
+		if serie, ok := seriesByKey[key]; ok {
+			serie.Points = append(serie.Points, p.point)
+			continue
+		}
+
+		context, ok := s.contextResolver.get(p.contextKey)
+		if !ok {
+			log.Errorf("TimeSampler #%d Ignoring timestamped metric on context key '%v': inconsistent context resolver state: the context is not tracked", s.id, p.contextKey)
+			continue
+		}
+		seriesByKey[key] = &metrics.Serie{
+			Name:       context.Name,
+			Points:     []metrics.Point{p.point},
+			Tags:       context.Tags(),
+			Host:       context.Host,
+			MType:      p.mType,
+			Interval:   s.interval,
+			ContextKey: p.contextKey,
+		}
+	}
+
+	for _, serie := range seriesByKey {
+		series.Append(serie)
+	}
+	aggregatorDogstatsdTimestampedPoints.Add(int64(len(s.timestampedPoints)))
+	tlmDogstatsdTimestampedPoints.Add(float64(len(s.timestampedPoints)))
+
+	// reuse the slice unless it grew much bigger than usual
+	if cap(s.timestampedPoints) > maxTimestampedPointsRetainedCapacity {
+		s.timestampedPoints = nil
+	} else {
+		s.timestampedPoints = s.timestampedPoints[:0]
+	}
+}
+
 func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.SketchPoint) metrics.SketchSeries {
 	ctx, _ := s.contextResolver.get(ck)
 	ss := metrics.SketchSeries{
@@ -211,6 +308,7 @@ func (s *TimeSampler) flush(timestamp float64, series metrics.SerieSink) metrics
 	cutoffTime := s.calculateBucketStart(timestamp)
 
 	s.flushSeries(cutoffTime, series)
+	s.flushTimestampedPoints(series)
 	sketches := s.flushSketches(cutoffTime)
 
 	// expiring contexts
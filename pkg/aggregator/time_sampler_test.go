@@ -33,7 +33,7 @@ func generateSerieContextKey(serie *metrics.Serie) ckey.ContextKey {
 }
 
 func testTimeSampler() *TimeSampler {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"))
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(false, "test"), nil)
 	return sampler
 }
 
@@ -513,6 +513,65 @@ func TestBucketSamplingWithSketchAndSeries(t *testing.T) {
 	testWithTagsStore(t, testBucketSamplingWithSketchAndSeries)
 }
 
+func testTimestampedSamplesNotAggregated(t *testing.T, store *tags.Store) {
+	sampler := testTimeSampler()
+
+	gauge := metrics.MetricSample{
+		Name:          "my.gauge",
+		Value:         1,
+		Mtype:         metrics.GaugeType,
+		Tags:          []string{"foo"},
+		SampleRate:    1,
+		Timestamp:     12001.0,
+		NoAggregation: true,
+	}
+	count := metrics.MetricSample{
+		Name:          "my.count",
+		Value:         2,
+		Mtype:         metrics.CounterType,
+		Tags:          []string{"foo"},
+		SampleRate:    0.5,
+		Timestamp:     12002.0,
+		NoAggregation: true,
+	}
+	sampler.sample(&gauge, 12346.0)
+	gauge.Value = 3
+	gauge.Timestamp = 12003.0
+	sampler.sample(&gauge, 12346.0)
+	sampler.sample(&count, 12346.0)
+
+	// the points are not aggregated in a bucket
+	assert.Len(t, sampler.metricsByTimestamp, 0)
+
+	series, _ := flushSerie(sampler, 12360.0)
+	require.Len(t, series, 2)
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+
+	metrics.AssertSerieEqual(t, &metrics.Serie{
+		Name:     "my.count",
+		Tags:     tagset.CompositeTagsFromSlice([]string{"foo"}),
+		Points:   []metrics.Point{{Ts: 12002.0, Value: 4}},
+		MType:    metrics.APICountType,
+		Interval: 10,
+	}, series[0])
+	metrics.AssertSerieEqual(t, &metrics.Serie{
+		Name:     "my.gauge",
+		Tags:     tagset.CompositeTagsFromSlice([]string{"foo"}),
+		Points:   []metrics.Point{{Ts: 12001.0, Value: 1}, {Ts: 12003.0, Value: 3}},
+		MType:    metrics.APIGaugeType,
+		Interval: 10,
+	}, series[1])
+
+	// the points are only sent once, and the contexts are not expired
+	// as they have been tracked with their arrival time.
+	series, _ = flushSerie(sampler, 12370.0)
+	assert.Len(t, series, 0)
+	assert.Equal(t, 2, sampler.contextResolver.length())
+}
+func TestTimestampedSamplesNotAggregated(t *testing.T) {
+	testWithTagsStore(t, testTimestampedSamplesNotAggregated)
+}
+
 func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
 	sampler := testTimeSampler()
 
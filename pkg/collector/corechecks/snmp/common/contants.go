@@ -8,6 +8,16 @@ package common
 // MetadataDeviceResource is the device resource name
 const MetadataDeviceResource = "device"
 
+// Topology resource names
+const (
+	MetadataLldpLocalSystemResource      = "lldp_local_system"
+	MetadataLldpLocalResource            = "lldp_local"
+	MetadataLldpRemoteResource           = "lldp_remote"
+	MetadataLldpRemoteManagementResource = "lldp_remote_management"
+	MetadataCdpLocalSystemResource       = "cdp_local_system"
+	MetadataCdpRemoteResource            = "cdp_remote"
+)
+
 // SnmpIntegrationName is the name of the snmp integration
 const SnmpIntegrationName = "snmp"
 
@@ -8,6 +8,7 @@ package common
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
@@ -39,6 +40,16 @@ func CopyStrings(tags []string) []string {
 	return newTags
 }
 
+// BuildDeviceID returns the ID of a network device, built from its namespace and IP address
+func BuildDeviceID(namespace string, ipAddress string) string {
+	return namespace + ":" + ipAddress
+}
+
+// BuildInterfaceID returns the ID of a network interface, built from the ID of its device and its ifIndex
+func BuildInterfaceID(deviceID string, ifIndex int32) string {
+	return deviceID + ":" + strconv.Itoa(int(ifIndex))
+}
+
 // GetAgentVersionTag returns agent version tag
 func GetAgentVersionTag() string {
 	return "agent_version:" + version.AgentVersion
@@ -131,3 +131,11 @@ func TestValidateNamespace(t *testing.T) {
 	_, err = NormalizeNamespace(string(b))
 	assert.NotNil(err, "namespace should not contain bad bytes")
 }
+
+func TestBuildDeviceID(t *testing.T) {
+	assert.Equal(t, "default:10.0.0.1", BuildDeviceID("default", "10.0.0.1"))
+}
+
+func TestBuildInterfaceID(t *testing.T) {
+	assert.Equal(t, "default:10.0.0.1:12", BuildInterfaceID("default:10.0.0.1", 12))
+}
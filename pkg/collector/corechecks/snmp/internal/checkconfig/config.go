@@ -63,6 +63,7 @@ type InitConfig struct {
 	OidBatchSize          Number           `yaml:"oid_batch_size"`
 	BulkMaxRepetitions    Number           `yaml:"bulk_max_repetitions"`
 	CollectDeviceMetadata Boolean          `yaml:"collect_device_metadata"`
+	CollectTopology       Boolean          `yaml:"collect_topology"`
 	UseDeviceIDAsHostname Boolean          `yaml:"use_device_id_as_hostname"`
 	MinCollectionInterval int              `yaml:"min_collection_interval"`
 	Namespace             string           `yaml:"namespace"`
@@ -88,6 +89,7 @@ type InstanceConfig struct {
 	Profile               string            `yaml:"profile"`
 	UseGlobalMetrics      bool              `yaml:"use_global_metrics"`
 	CollectDeviceMetadata *Boolean          `yaml:"collect_device_metadata"`
+	CollectTopology       *Boolean          `yaml:"collect_topology"`
 	UseDeviceIDAsHostname *Boolean          `yaml:"use_device_id_as_hostname"`
 
 	// ExtraTags is a workaround to pass tags from snmp listener to snmp integration via AD template
@@ -148,6 +150,7 @@ type CheckConfig struct {
 	ExtraTags             []string
 	InstanceTags          []string
 	CollectDeviceMetadata bool
+	CollectTopology       bool
 	UseDeviceIDAsHostname bool
 	DeviceID              string
 	DeviceIDTags          []string
@@ -176,6 +179,9 @@ func (c *CheckConfig) RefreshWithProfile(profile string) error {
 	c.Profile = profile
 
 	c.Metadata = updateMetadataDefinitionWithLegacyFallback(definition.Metadata)
+	if c.CollectTopology {
+		c.Metadata = updateMetadataDefinitionWithTopology(c.Metadata)
+	}
 	c.Metrics = append(c.Metrics, definition.Metrics...)
 	c.MetricTags = append(c.MetricTags, definition.MetricTags...)
 
@@ -194,7 +200,7 @@ func (c *CheckConfig) RefreshWithProfile(profile string) error {
 // UpdateDeviceIDAndTags updates DeviceID and DeviceIDTags
 func (c *CheckConfig) UpdateDeviceIDAndTags() {
 	c.DeviceIDTags = coreutil.SortUniqInPlace(c.getDeviceIDTags())
-	c.DeviceID = c.Namespace + ":" + c.IPAddress
+	c.DeviceID = common.BuildDeviceID(c.Namespace, c.IPAddress)
 }
 
 func (c *CheckConfig) addUptimeMetric() {
@@ -306,6 +312,14 @@ func NewCheckConfig(rawInstance integration.Data, rawInitConfig integration.Data
 		c.CollectDeviceMetadata = bool(initConfig.CollectDeviceMetadata)
 	}
 
+	// The topology is part of the device metadata.
+	if instance.CollectTopology != nil {
+		c.CollectTopology = bool(*instance.CollectTopology)
+	} else {
+		c.CollectTopology = bool(initConfig.CollectTopology)
+	}
+	c.CollectTopology = c.CollectTopology && c.CollectDeviceMetadata
+
 	if instance.UseDeviceIDAsHostname != nil {
 		c.UseDeviceIDAsHostname = bool(*instance.UseDeviceIDAsHostname)
 	} else {
@@ -439,6 +453,9 @@ func NewCheckConfig(rawInstance integration.Data, rawInitConfig integration.Data
 	c.addUptimeMetric()
 
 	c.Metadata = updateMetadataDefinitionWithLegacyFallback(nil)
+	if c.CollectTopology {
+		c.Metadata = updateMetadataDefinitionWithTopology(c.Metadata)
+	}
 	c.OidConfig.addScalarOids(c.parseScalarOids(c.Metrics, c.MetricTags, c.Metadata))
 	c.OidConfig.addColumnOids(c.parseColumnOids(c.Metrics, c.Metadata))
 
@@ -576,6 +593,7 @@ func (c *CheckConfig) Copy() *CheckConfig {
 	newConfig.ExtraTags = common.CopyStrings(c.ExtraTags)
 	newConfig.InstanceTags = common.CopyStrings(c.InstanceTags)
 	newConfig.CollectDeviceMetadata = c.CollectDeviceMetadata
+	newConfig.CollectTopology = c.CollectTopology
 	newConfig.UseDeviceIDAsHostname = c.UseDeviceIDAsHostname
 	newConfig.DeviceID = c.DeviceID
 
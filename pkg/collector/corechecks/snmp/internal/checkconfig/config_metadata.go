@@ -86,6 +86,151 @@ var LegacyMetadataConfig = MetadataConfig{
 	},
 }
 
+// TopologyMetadataConfig contains the metadata definitions of the LLDP and CDP
+// tables used to build the links between the devices.
+// They are added to the metadata definitions when topology collection is enabled.
+var TopologyMetadataConfig = MetadataConfig{
+	common.MetadataLldpLocalSystemResource: {
+		Fields: map[string]MetadataField{
+			"chassis_id_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.3.1.0",
+					Name: "lldpLocChassisIdSubtype",
+				},
+			},
+			"chassis_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.3.2.0",
+					Name: "lldpLocChassisId",
+				},
+			},
+		},
+	},
+	common.MetadataLldpLocalResource: {
+		Fields: map[string]MetadataField{
+			"interface_id_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.3.7.1.2",
+					Name: "lldpLocPortIdSubtype",
+				},
+			},
+			"interface_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.3.7.1.3",
+					Name: "lldpLocPortId",
+				},
+			},
+			"interface_desc": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.3.7.1.4",
+					Name: "lldpLocPortDesc",
+				},
+			},
+		},
+	},
+	common.MetadataLldpRemoteResource: {
+		Fields: map[string]MetadataField{
+			"chassis_id_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.4",
+					Name: "lldpRemChassisIdSubtype",
+				},
+			},
+			"chassis_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.5",
+					Name: "lldpRemChassisId",
+				},
+			},
+			"interface_id_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.6",
+					Name: "lldpRemPortIdSubtype",
+				},
+			},
+			"interface_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.7",
+					Name: "lldpRemPortId",
+				},
+			},
+			"interface_desc": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.8",
+					Name: "lldpRemPortDesc",
+				},
+			},
+			"device_name": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.9",
+					Name: "lldpRemSysName",
+				},
+			},
+			"device_desc": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.1.1.10",
+					Name: "lldpRemSysDesc",
+				},
+			},
+		},
+	},
+	common.MetadataLldpRemoteManagementResource: {
+		Fields: map[string]MetadataField{
+			// the management addresses are in the indexes of the table
+			"interface_id_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.0.8802.1.1.2.1.4.2.1.3",
+					Name: "lldpRemManAddrIfSubtype",
+				},
+			},
+		},
+	},
+	common.MetadataCdpLocalSystemResource: {
+		Fields: map[string]MetadataField{
+			"device_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.3.4.0",
+					Name: "cdpGlobalDeviceId",
+				},
+			},
+		},
+	},
+	common.MetadataCdpRemoteResource: {
+		Fields: map[string]MetadataField{
+			"address_type": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.2.1.1.3",
+					Name: "cdpCacheAddressType",
+				},
+			},
+			"address": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.2.1.1.4",
+					Name: "cdpCacheAddress",
+				},
+			},
+			"device_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.2.1.1.6",
+					Name: "cdpCacheDeviceId",
+				},
+			},
+			"interface_id": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.2.1.1.7",
+					Name: "cdpCacheDevicePort",
+				},
+			},
+			"device_platform": {
+				Symbol: SymbolConfig{
+					OID:  "1.3.6.1.4.1.9.9.23.1.2.1.1.8",
+					Name: "cdpCachePlatform",
+				},
+			},
+		},
+	},
+}
+
 // MetadataConfig holds configs per resource type
 type MetadataConfig map[string]MetadataResourceConfig
 
@@ -108,9 +253,12 @@ func newMetadataResourceConfig() MetadataResourceConfig {
 }
 
 // IsMetadataResourceWithScalarOids returns true if the resource is based on scalar OIDs
-// at the moment, we only expect "device" resource to be based on scalar OIDs
+// at the moment, we only expect "device" resource and the local systems of the
+// topology to be based on scalar OIDs
 func IsMetadataResourceWithScalarOids(resource string) bool {
-	return resource == common.MetadataDeviceResource
+	return resource == common.MetadataDeviceResource ||
+		resource == common.MetadataLldpLocalSystemResource ||
+		resource == common.MetadataCdpLocalSystemResource
 }
 
 // updateMetadataDefinitionWithLegacyFallback will add metadata config for resources
@@ -126,3 +274,17 @@ func updateMetadataDefinitionWithLegacyFallback(config MetadataConfig) MetadataC
 	}
 	return config
 }
+
+// updateMetadataDefinitionWithTopology returns a copy of the metadata config
+// with the topology definitions, the config is not modified since it might
+// be the definition of a profile shared by several checks.
+func updateMetadataDefinitionWithTopology(config MetadataConfig) MetadataConfig {
+	newConfig := make(MetadataConfig, len(config)+len(TopologyMetadataConfig))
+	for resourceName, resourceConfig := range config {
+		newConfig[resourceName] = resourceConfig
+	}
+	for resourceName, resourceConfig := range TopologyMetadataConfig {
+		newConfig[resourceName] = resourceConfig
+	}
+	return newConfig
+}
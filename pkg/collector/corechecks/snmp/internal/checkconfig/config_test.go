@@ -1065,6 +1065,93 @@ collect_device_metadata: true
 	assert.Equal(t, false, config.CollectDeviceMetadata)
 }
 
+func Test_buildConfig_collectTopology(t *testing.T) {
+	// language=yaml
+	rawInstanceConfig := []byte(`
+ip_address: 1.2.3.4
+community_string: "abc"
+`)
+	// language=yaml
+	rawInitConfig := []byte(`
+oid_batch_size: 10
+`)
+	config, err := NewCheckConfig(rawInstanceConfig, rawInitConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, false, config.CollectTopology)
+	assert.NotContains(t, config.Metadata, "lldp_remote")
+	assert.NotContains(t, config.OidConfig.ColumnOids, "1.0.8802.1.1.2.1.4.1.1.7")
+
+	// language=yaml
+	rawInstanceConfig = []byte(`
+ip_address: 1.2.3.4
+community_string: "abc"
+`)
+	// language=yaml
+	rawInitConfig = []byte(`
+oid_batch_size: 10
+collect_topology: true
+`)
+	config, err = NewCheckConfig(rawInstanceConfig, rawInitConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, true, config.CollectTopology)
+	assert.Contains(t, config.Metadata, "lldp_remote")
+	assert.Contains(t, config.Metadata, "interface")
+	assert.Contains(t, config.OidConfig.ScalarOids, "1.0.8802.1.1.2.1.3.2.0")
+	assert.Contains(t, config.OidConfig.ColumnOids, "1.0.8802.1.1.2.1.4.1.1.7")
+	assert.Contains(t, config.OidConfig.ColumnOids, "1.3.6.1.4.1.9.9.23.1.2.1.1.6")
+
+	// language=yaml
+	rawInstanceConfig = []byte(`
+ip_address: 1.2.3.4
+community_string: "abc"
+collect_topology: false
+`)
+	config, err = NewCheckConfig(rawInstanceConfig, rawInitConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, false, config.CollectTopology)
+
+	// topology is part of the device metadata
+	// language=yaml
+	rawInstanceConfig = []byte(`
+ip_address: 1.2.3.4
+community_string: "abc"
+collect_device_metadata: false
+collect_topology: true
+`)
+	config, err = NewCheckConfig(rawInstanceConfig, rawInitConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, false, config.CollectTopology)
+	assert.NotContains(t, config.Metadata, "lldp_remote")
+}
+
+func TestCheckConfig_RefreshWithProfile_collectTopology(t *testing.T) {
+	profile := profileDefinition{
+		Metadata: MetadataConfig{
+			"device": {
+				Fields: map[string]MetadataField{
+					"vendor": {
+						Value: "a-vendor",
+					},
+				},
+			},
+		},
+	}
+	config := &CheckConfig{
+		CollectDeviceMetadata: true,
+		CollectTopology:       true,
+		Profiles:              profileDefinitionMap{"a-profile": profile},
+	}
+	err := config.RefreshWithProfile("a-profile")
+	assert.Nil(t, err)
+
+	assert.Contains(t, config.Metadata, "device")
+	assert.Contains(t, config.Metadata, "cdp_remote")
+	assert.Contains(t, config.OidConfig.ScalarOids, "1.3.6.1.4.1.9.9.23.1.3.4.0")
+	// the metadata definition of the profile is not modified
+	assert.NotContains(t, profile.Metadata, "cdp_remote")
+	assert.NotContains(t, config.Profiles["a-profile"].Metadata, "cdp_remote")
+}
+
 func Test_buildConfig_namespace(t *testing.T) {
 	defer coreconfig.Datadog.Set("network_devices.namespace", "default")
 
@@ -1585,6 +1672,7 @@ func TestCheckConfig_Copy(t *testing.T) {
 		ExtraTags:             []string{"ExtraTags:tag"},
 		InstanceTags:          []string{"InstanceTags:tag"},
 		CollectDeviceMetadata: true,
+		CollectTopology:       true,
 		UseDeviceIDAsHostname: true,
 		DeviceID:              "123",
 		DeviceIDTags:          []string{"DeviceIDTags:tag"},
@@ -1623,6 +1711,7 @@ func TestCheckConfig_Copy(t *testing.T) {
 	assertNotSameButEqualElements(t, config.ExtraTags, configCopy.ExtraTags)
 	assertNotSameButEqualElements(t, config.InstanceTags, configCopy.InstanceTags)
 	assert.Equal(t, config.CollectDeviceMetadata, configCopy.CollectDeviceMetadata)
+	assert.Equal(t, config.CollectTopology, configCopy.CollectTopology)
 	assert.Equal(t, config.UseDeviceIDAsHostname, configCopy.UseDeviceIDAsHostname)
 	assert.Equal(t, config.DeviceID, configCopy.DeviceID)
 	assertNotSameButEqualElements(t, config.DeviceIDTags, configCopy.DeviceIDTags)
@@ -6,7 +6,7 @@
 package metadata
 
 // PayloadMetadataBatchSize is the number of resources per event payload
-// Resources are devices, interfaces, etc
+// Resources are devices, interfaces, links, etc
 const PayloadMetadataBatchSize = 100
 
 // DeviceStatus enum type
@@ -21,11 +21,12 @@ const (
 
 // NetworkDevicesMetadata contains network devices metadata
 type NetworkDevicesMetadata struct {
-	Subnet           string              `json:"subnet"`
-	Namespace        string              `json:"namespace"`
-	Devices          []DeviceMetadata    `json:"devices,omitempty"`
-	Interfaces       []InterfaceMetadata `json:"interfaces,omitempty"`
-	CollectTimestamp int64               `json:"collect_timestamp"`
+	Subnet           string                 `json:"subnet"`
+	Namespace        string                 `json:"namespace"`
+	Devices          []DeviceMetadata       `json:"devices,omitempty"`
+	Interfaces       []InterfaceMetadata    `json:"interfaces,omitempty"`
+	Links            []TopologyLinkMetadata `json:"links,omitempty"`
+	CollectTimestamp int64                  `json:"collect_timestamp"`
 }
 
 // DeviceMetadata contains device metadata
@@ -63,3 +64,36 @@ type InterfaceMetadata struct {
 	AdminStatus int32    `json:"admin_status,omitempty"` // IF-MIB ifAdminStatus type is INTEGER
 	OperStatus  int32    `json:"oper_status,omitempty"`  // IF-MIB ifOperStatus type is INTEGER
 }
+
+// TopologyLinkDevice contains the device of one side of a link
+type TopologyLinkDevice struct {
+	DDID        string `json:"dd_id,omitempty"` // ID of the device in Datadog, when it is known
+	ID          string `json:"id,omitempty"`
+	IDType      string `json:"id_type,omitempty"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// TopologyLinkInterface contains the interface of one side of a link
+type TopologyLinkInterface struct {
+	DDID        string `json:"dd_id,omitempty"` // ID of the interface in Datadog, when it is known
+	ID          string `json:"id"`
+	IDType      string `json:"id_type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// TopologyLinkSide contains the device and interface of one side of a link
+type TopologyLinkSide struct {
+	Device    *TopologyLinkDevice    `json:"device,omitempty"`
+	Interface *TopologyLinkInterface `json:"interface,omitempty"`
+}
+
+// TopologyLinkMetadata contains a link between a local interface of the
+// device and an interface of a remote device, discovered with LLDP or CDP
+type TopologyLinkMetadata struct {
+	ID         string            `json:"id"` // the same ID is reported by the devices at both ends of the link
+	SourceType string            `json:"source_type"`
+	Local      *TopologyLinkSide `json:"local"`
+	Remote     *TopologyLinkSide `json:"remote"`
+}
@@ -63,6 +63,19 @@ func (s Store) GetColumnAsString(field string, index string) string {
 	return strVal
 }
 
+// GetColumnAsByteArray get column value as byte array
+func (s Store) GetColumnAsByteArray(field string, index string) []byte {
+	column, ok := s.columnValues[field]
+	if !ok {
+		return nil
+	}
+	value, ok := column[index]
+	if !ok {
+		return nil
+	}
+	return valueAsByteArray(value)
+}
+
 // GetColumnAsFloat get column value as float
 func (s Store) GetColumnAsFloat(field string, index string) float64 {
 	column, ok := s.columnValues[field]
@@ -95,6 +108,29 @@ func (s Store) GetScalarAsString(field string) string {
 	return strVal
 }
 
+// GetScalarAsByteArray get scalar value as byte array
+func (s Store) GetScalarAsByteArray(field string) []byte {
+	value, ok := s.scalarValues[field]
+	if !ok {
+		return nil
+	}
+	return valueAsByteArray(value)
+}
+
+// GetScalarAsFloat get scalar value as float
+func (s Store) GetScalarAsFloat(field string) float64 {
+	value, ok := s.scalarValues[field]
+	if !ok {
+		return 0
+	}
+	floatVal, err := value.ToFloat64()
+	if err != nil {
+		log.Debugf("error converting value to float `%v`: %s", value, err)
+		return 0
+	}
+	return floatVal
+}
+
 // ScalarFieldHasValue test if scalar field has value
 func (s Store) ScalarFieldHasValue(field string) bool {
 	_, ok := s.scalarValues[field]
@@ -135,3 +171,13 @@ func (s Store) AddIDTags(resource string, index string, tags []string) {
 	}
 	s.resourceIDTags[resource][index] = append(s.resourceIDTags[resource][index], tags...)
 }
+
+func valueAsByteArray(value valuestore.ResultValue) []byte {
+	switch val := value.Value.(type) {
+	case []byte:
+		return val
+	case string:
+		return []byte(val)
+	}
+	return nil
+}
@@ -85,3 +85,21 @@ func TestStore_IDTags(t *testing.T) {
 	assert.Equal(t, []string(nil), store.GetIDTags("does_not_exist", "2"))
 	assert.Equal(t, []string(nil), store.GetIDTags("interface", "9")) // does not exist
 }
+
+func TestStore_ByteArray(t *testing.T) {
+	store := NewMetadataStore()
+	store.AddScalarValue("lldp_local_system.chassis_id", valuestore.ResultValue{Value: []byte{0x82, 0xa5}})
+	store.AddScalarValue("lldp_local_system.chassis_id_type", valuestore.ResultValue{Value: float64(4)})
+	store.AddColumnValue("lldp_remote.interface_id", "1", valuestore.ResultValue{Value: "eth0"})
+	store.AddColumnValue("lldp_remote.interface_id_type", "1", valuestore.ResultValue{Value: float64(5)})
+
+	assert.Equal(t, []byte{0x82, 0xa5}, store.GetScalarAsByteArray("lldp_local_system.chassis_id"))
+	assert.Equal(t, float64(4), store.GetScalarAsFloat("lldp_local_system.chassis_id_type"))
+	assert.Equal(t, []byte("eth0"), store.GetColumnAsByteArray("lldp_remote.interface_id", "1"))
+
+	// error cases
+	assert.Nil(t, store.GetScalarAsByteArray("does_not_exist"))
+	assert.Equal(t, float64(0), store.GetScalarAsFloat("does_not_exist"))
+	assert.Nil(t, store.GetColumnAsByteArray("lldp_remote.interface_id", "2"))
+	assert.Nil(t, store.GetColumnAsByteArray("lldp_remote.interface_id_type", "1"))
+}
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metadata
+
+// Topology link source types
+const (
+	LinkSourceTypeLldp = "lldp"
+	LinkSourceTypeCdp  = "cdp"
+)
+
+// ID types of the devices and interfaces of the topology links
+const (
+	IDTypeChassisComponent = "chassis_component"
+	IDTypeInterfaceAlias   = "interface_alias"
+	IDTypePortComponent    = "port_component"
+	IDTypeMacAddress       = "mac_address"
+	IDTypeNetworkAddress   = "network_address"
+	IDTypeInterfaceName    = "interface_name"
+	IDTypeAgentCircuitID   = "agent_circuit_id"
+	IDTypeLocal            = "local"
+)
+
+// lldpChassisIDSubtypeMap maps the LLDP-MIB LldpChassisIdSubtype values to ID types
+var lldpChassisIDSubtypeMap = map[int]string{
+	1: IDTypeChassisComponent,
+	2: IDTypeInterfaceAlias,
+	3: IDTypePortComponent,
+	4: IDTypeMacAddress,
+	5: IDTypeNetworkAddress,
+	6: IDTypeInterfaceName,
+	7: IDTypeLocal,
+}
+
+// lldpPortIDSubtypeMap maps the LLDP-MIB LldpPortIdSubtype values to ID types
+var lldpPortIDSubtypeMap = map[int]string{
+	1: IDTypeInterfaceAlias,
+	2: IDTypePortComponent,
+	3: IDTypeMacAddress,
+	4: IDTypeNetworkAddress,
+	5: IDTypeInterfaceName,
+	6: IDTypeAgentCircuitID,
+	7: IDTypeLocal,
+}
+
+// GetChassisIDType returns the ID type of a LLDP chassis ID subtype
+func GetChassisIDType(subtype int) string {
+	return lldpChassisIDSubtypeMap[subtype]
+}
+
+// GetInterfaceIDType returns the ID type of a LLDP port ID subtype
+func GetInterfaceIDType(subtype int) string {
+	return lldpPortIDSubtypeMap[subtype]
+}
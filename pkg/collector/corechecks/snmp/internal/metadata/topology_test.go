@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetIDTypes(t *testing.T) {
+	assert.Equal(t, IDTypeMacAddress, GetChassisIDType(4))
+	assert.Equal(t, IDTypeInterfaceName, GetChassisIDType(6))
+	assert.Equal(t, "", GetChassisIDType(0))
+
+	assert.Equal(t, IDTypeMacAddress, GetInterfaceIDType(3))
+	assert.Equal(t, IDTypeInterfaceName, GetInterfaceIDType(5))
+	assert.Equal(t, "", GetInterfaceIDType(8))
+}
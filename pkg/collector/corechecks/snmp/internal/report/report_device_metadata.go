@@ -32,7 +32,12 @@ func (ms *MetricSender) ReportNetworkDeviceMetadata(config *checkconfig.CheckCon
 
 	interfaces := buildNetworkInterfacesMetadata(config.DeviceID, metadataStore)
 
-	metadataPayloads := batchPayloads(config.Namespace, config.ResolvedSubnetName, collectTime, metadata.PayloadMetadataBatchSize, device, interfaces)
+	var links []metadata.TopologyLinkMetadata
+	if config.CollectTopology {
+		links = buildNetworkTopologyMetadata(config.DeviceID, config.Namespace, metadataStore, interfaces)
+	}
+
+	metadataPayloads := batchPayloads(config.Namespace, config.ResolvedSubnetName, collectTime, metadata.PayloadMetadataBatchSize, device, interfaces, links)
 
 	for _, payload := range metadataPayloads {
 		payloadBytes, err := json.Marshal(payload)
@@ -188,7 +193,7 @@ func buildNetworkInterfacesMetadata(deviceID string, store *metadata.Store) []me
 	return interfaces
 }
 
-func batchPayloads(namespace string, subnet string, collectTime time.Time, batchSize int, device metadata.DeviceMetadata, interfaces []metadata.InterfaceMetadata) []metadata.NetworkDevicesMetadata {
+func batchPayloads(namespace string, subnet string, collectTime time.Time, batchSize int, device metadata.DeviceMetadata, interfaces []metadata.InterfaceMetadata, links []metadata.TopologyLinkMetadata) []metadata.NetworkDevicesMetadata {
 	var payloads []metadata.NetworkDevicesMetadata
 	var resourceCount int
 	payload := metadata.NetworkDevicesMetadata{
@@ -201,7 +206,7 @@ func batchPayloads(namespace string, subnet string, collectTime time.Time, batch
 	}
 	resourceCount++
 
-	for _, interfaceMetadata := range interfaces {
+	nextResource := func() {
 		if resourceCount == batchSize {
 			payloads = append(payloads, payload)
 			payload = metadata.NetworkDevicesMetadata{
@@ -212,9 +217,18 @@ func batchPayloads(namespace string, subnet string, collectTime time.Time, batch
 			resourceCount = 0
 		}
 		resourceCount++
+	}
+
+	for _, interfaceMetadata := range interfaces {
+		nextResource()
 		payload.Interfaces = append(payload.Interfaces, interfaceMetadata)
 	}
 
+	for _, linkMetadata := range links {
+		nextResource()
+		payload.Links = append(payload.Links, linkMetadata)
+	}
+
 	payloads = append(payloads, payload)
 	return payloads
 }
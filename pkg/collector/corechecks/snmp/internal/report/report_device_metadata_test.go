@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"strconv"
 	"testing"
 	"time"
 
@@ -406,7 +407,7 @@ func Test_batchPayloads(t *testing.T) {
 	for i := 0; i < 350; i++ {
 		interfaces = append(interfaces, metadata.InterfaceMetadata{DeviceID: deviceID, Index: int32(i)})
 	}
-	payloads := batchPayloads("my-ns", "127.0.0.0/30", collectTime, 100, device, interfaces)
+	payloads := batchPayloads("my-ns", "127.0.0.0/30", collectTime, 100, device, interfaces, nil)
 
 	assert.Equal(t, 4, len(payloads))
 
@@ -431,3 +432,35 @@ func Test_batchPayloads(t *testing.T) {
 	assert.Equal(t, 51, len(payloads[3].Interfaces))
 	assert.Equal(t, interfaces[299:350], payloads[3].Interfaces)
 }
+
+func Test_batchPayloads_withLinks(t *testing.T) {
+	collectTime := common.MockTimeNow()
+	deviceID := "123"
+	device := metadata.DeviceMetadata{ID: deviceID}
+
+	var interfaces []metadata.InterfaceMetadata
+	for i := 0; i < 150; i++ {
+		interfaces = append(interfaces, metadata.InterfaceMetadata{DeviceID: deviceID, Index: int32(i)})
+	}
+	var links []metadata.TopologyLinkMetadata
+	for i := 0; i < 60; i++ {
+		links = append(links, metadata.TopologyLinkMetadata{ID: strconv.Itoa(i), SourceType: metadata.LinkSourceTypeLldp})
+	}
+	payloads := batchPayloads("my-ns", "127.0.0.0/30", collectTime, 100, device, interfaces, links)
+
+	assert.Equal(t, 3, len(payloads))
+
+	assert.Equal(t, []metadata.DeviceMetadata{device}, payloads[0].Devices)
+	assert.Equal(t, interfaces[0:99], payloads[0].Interfaces)
+	assert.Equal(t, 0, len(payloads[0].Links))
+
+	assert.Equal(t, 0, len(payloads[1].Devices))
+	assert.Equal(t, interfaces[99:150], payloads[1].Interfaces)
+	assert.Equal(t, links[0:49], payloads[1].Links)
+
+	assert.Equal(t, "127.0.0.0/30", payloads[2].Subnet)
+	assert.Equal(t, int64(946684800), payloads[2].CollectTimestamp)
+	assert.Equal(t, 0, len(payloads[2].Devices))
+	assert.Equal(t, 0, len(payloads[2].Interfaces))
+	assert.Equal(t, links[49:60], payloads[2].Links)
+}
@@ -0,0 +1,329 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package report
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/metadata"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/valuestore"
+)
+
+// IANA address family numbers used by LLDP network addresses
+const (
+	ianaAddressFamilyIPv4 = 1
+	ianaAddressFamilyIPv6 = 2
+)
+
+// cdpAddressTypeIP is the CISCO-TC CiscoNetworkProtocol value of IP addresses
+const cdpAddressTypeIP = 1
+
+// buildNetworkTopologyMetadata builds the links between the interfaces of the
+// device and the remote devices discovered with LLDP and CDP.
+// A neighbor discovered with both protocols is only reported once, using LLDP.
+func buildNetworkTopologyMetadata(deviceID string, namespace string, store *metadata.Store, interfaces []metadata.InterfaceMetadata) []metadata.TopologyLinkMetadata {
+	if store == nil {
+		// it's expected that the value store is nil if we can't reach the device
+		return nil
+	}
+
+	var links []metadata.TopologyLinkMetadata
+	linkIDs := make(map[string]bool)
+	lldpNeighbors := make(map[string]bool)
+	for _, link := range buildLldpLinks(deviceID, namespace, store, interfaces) {
+		if linkIDs[link.ID] {
+			continue
+		}
+		linkIDs[link.ID] = true
+		if key := neighborKey(link); key != "" {
+			lldpNeighbors[key] = true
+		}
+		links = append(links, link)
+	}
+	for _, link := range buildCdpLinks(deviceID, namespace, store, interfaces) {
+		if linkIDs[link.ID] || lldpNeighbors[neighborKey(link)] {
+			continue
+		}
+		linkIDs[link.ID] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+func buildLldpLinks(deviceID string, namespace string, store *metadata.Store, interfaces []metadata.InterfaceMetadata) []metadata.TopologyLinkMetadata {
+	indexes := store.GetColumnIndexes("lldp_remote.interface_id")
+	if len(indexes) == 0 {
+		log.Debugf("Unable to build LLDP links: no LLDP remote indexes found")
+		return nil
+	}
+	sort.Strings(indexes)
+
+	remoteAddresses := buildLldpRemoteManagementAddresses(store)
+
+	localChassisIDType := metadata.GetChassisIDType(int(store.GetScalarAsFloat("lldp_local_system.chassis_id_type")))
+	localChassisID := formatTopologyID(store.GetScalarAsByteArray("lldp_local_system.chassis_id"), localChassisIDType)
+
+	var links []metadata.TopologyLinkMetadata
+	for _, strIndex := range indexes {
+		// lldpRemTable index: lldpRemTimeMark.lldpRemLocalPortNum.lldpRemIndex
+		indexElems := strings.Split(strIndex, ".")
+		if len(indexElems) != 3 {
+			log.Debugf("LLDP links: invalid remote index: %s", strIndex)
+			continue
+		}
+
+		remoteChassisIDType := metadata.GetChassisIDType(int(store.GetColumnAsFloat("lldp_remote.chassis_id_type", strIndex)))
+		remoteDevice := &metadata.TopologyLinkDevice{
+			ID:          formatTopologyID(store.GetColumnAsByteArray("lldp_remote.chassis_id", strIndex), remoteChassisIDType),
+			IDType:      remoteChassisIDType,
+			Name:        store.GetColumnAsString("lldp_remote.device_name", strIndex),
+			Description: store.GetColumnAsString("lldp_remote.device_desc", strIndex),
+		}
+		if ipAddress, ok := remoteAddresses[strIndex]; ok {
+			remoteDevice.IPAddress = ipAddress
+			remoteDevice.DDID = common.BuildDeviceID(namespace, ipAddress)
+		}
+
+		remoteInterfaceIDType := metadata.GetInterfaceIDType(int(store.GetColumnAsFloat("lldp_remote.interface_id_type", strIndex)))
+		remoteInterface := &metadata.TopologyLinkInterface{
+			ID:          formatTopologyID(store.GetColumnAsByteArray("lldp_remote.interface_id", strIndex), remoteInterfaceIDType),
+			IDType:      remoteInterfaceIDType,
+			Description: store.GetColumnAsString("lldp_remote.interface_desc", strIndex),
+		}
+
+		localDevice := &metadata.TopologyLinkDevice{
+			DDID:   deviceID,
+			ID:     localChassisID,
+			IDType: localChassisIDType,
+		}
+		localInterface := buildLldpLocalInterface(deviceID, store, interfaces, indexElems[1])
+
+		// The remote device reports our chassis and port IDs, fallback on the
+		// device ID if the local chassis ID is unknown.
+		localDeviceKey := localChassisID
+		if localDeviceKey == "" {
+			localDeviceKey = deviceID
+		}
+		links = append(links, metadata.TopologyLinkMetadata{
+			ID:         buildLinkID(metadata.LinkSourceTypeLldp, localDeviceKey, localInterface.ID, remoteDevice.ID, remoteInterface.ID),
+			SourceType: metadata.LinkSourceTypeLldp,
+			Local: &metadata.TopologyLinkSide{
+				Device:    localDevice,
+				Interface: localInterface,
+			},
+			Remote: &metadata.TopologyLinkSide{
+				Device:    remoteDevice,
+				Interface: remoteInterface,
+			},
+		})
+	}
+	return links
+}
+
+// buildLldpLocalInterface builds the local interface of a LLDP link from its lldpLocPortNum
+func buildLldpLocalInterface(deviceID string, store *metadata.Store, interfaces []metadata.InterfaceMetadata, portNum string) *metadata.TopologyLinkInterface {
+	idType := metadata.GetInterfaceIDType(int(store.GetColumnAsFloat("lldp_local.interface_id_type", portNum)))
+	localInterface := &metadata.TopologyLinkInterface{
+		ID:          formatTopologyID(store.GetColumnAsByteArray("lldp_local.interface_id", portNum), idType),
+		IDType:      idType,
+		Description: store.GetColumnAsString("lldp_local.interface_desc", portNum),
+	}
+
+	itf, found := findInterfaceByID(interfaces, idType, localInterface.ID)
+	if !found {
+		// lldpLocPortNum is the ifIndex of the interface on most devices
+		itf, found = findInterfaceByIndex(interfaces, portNum)
+	}
+	if found {
+		localInterface.DDID = common.BuildInterfaceID(deviceID, itf.Index)
+		if localInterface.ID == "" {
+			localInterface.ID = itf.Name
+			localInterface.IDType = metadata.IDTypeInterfaceName
+		}
+	}
+	if localInterface.ID == "" {
+		localInterface.ID = portNum
+		localInterface.IDType = metadata.IDTypeLocal
+	}
+	return localInterface
+}
+
+// buildLldpRemoteManagementAddresses returns the first IPv4 management address
+// of the remote devices by lldpRemTable index
+func buildLldpRemoteManagementAddresses(store *metadata.Store) map[string]string {
+	addresses := make(map[string]string)
+	indexes := store.GetColumnIndexes("lldp_remote_management.interface_id_type")
+	sort.Strings(indexes)
+	for _, strIndex := range indexes {
+		// lldpRemManAddrTable index: lldpRemTimeMark.lldpRemLocalPortNum.lldpRemIndex.lldpRemManAddrSubtype.lldpRemManAddr
+		// where lldpRemManAddr is an octet string prefixed by its length
+		indexElems := strings.Split(strIndex, ".")
+		if len(indexElems) != 9 || indexElems[3] != strconv.Itoa(ianaAddressFamilyIPv4) || indexElems[4] != "4" {
+			continue
+		}
+		remoteIndex := strings.Join(indexElems[:3], ".")
+		if _, ok := addresses[remoteIndex]; !ok {
+			addresses[remoteIndex] = strings.Join(indexElems[5:], ".")
+		}
+	}
+	return addresses
+}
+
+func buildCdpLinks(deviceID string, namespace string, store *metadata.Store, interfaces []metadata.InterfaceMetadata) []metadata.TopologyLinkMetadata {
+	indexes := store.GetColumnIndexes("cdp_remote.device_id")
+	if len(indexes) == 0 {
+		log.Debugf("Unable to build CDP links: no CDP remote indexes found")
+		return nil
+	}
+	sort.Strings(indexes)
+
+	localDeviceID := store.GetScalarAsString("cdp_local_system.device_id")
+
+	var links []metadata.TopologyLinkMetadata
+	for _, strIndex := range indexes {
+		// cdpCacheTable index: cdpCacheIfIndex.cdpCacheDeviceIndex
+		indexElems := strings.Split(strIndex, ".")
+		if len(indexElems) != 2 {
+			log.Debugf("CDP links: invalid remote index: %s", strIndex)
+			continue
+		}
+		ifIndex := indexElems[0]
+
+		remoteDeviceID := store.GetColumnAsString("cdp_remote.device_id", strIndex)
+		remoteDevice := &metadata.TopologyLinkDevice{
+			ID:          remoteDeviceID,
+			Name:        remoteDeviceID,
+			Description: store.GetColumnAsString("cdp_remote.device_platform", strIndex),
+		}
+		address := store.GetColumnAsByteArray("cdp_remote.address", strIndex)
+		if int(store.GetColumnAsFloat("cdp_remote.address_type", strIndex)) == cdpAddressTypeIP && len(address) == net.IPv4len {
+			remoteDevice.IPAddress = net.IP(address).String()
+			remoteDevice.DDID = common.BuildDeviceID(namespace, remoteDevice.IPAddress)
+		}
+		remoteInterface := &metadata.TopologyLinkInterface{
+			ID:     store.GetColumnAsString("cdp_remote.interface_id", strIndex),
+			IDType: metadata.IDTypeInterfaceName,
+		}
+
+		localDevice := &metadata.TopologyLinkDevice{
+			DDID: deviceID,
+			ID:   localDeviceID,
+		}
+		localInterface := &metadata.TopologyLinkInterface{
+			ID:     ifIndex,
+			IDType: metadata.IDTypeLocal,
+		}
+		if itf, found := findInterfaceByIndex(interfaces, ifIndex); found {
+			localInterface.DDID = common.BuildInterfaceID(deviceID, itf.Index)
+			// CDP advertises the ifDescr of the interface as port ID
+			if itf.Description != "" {
+				localInterface.ID = itf.Description
+				localInterface.IDType = metadata.IDTypeInterfaceName
+			} else if itf.Name != "" {
+				localInterface.ID = itf.Name
+				localInterface.IDType = metadata.IDTypeInterfaceName
+			}
+		}
+
+		localDeviceKey := localDeviceID
+		if localDeviceKey == "" {
+			localDeviceKey = deviceID
+		}
+		links = append(links, metadata.TopologyLinkMetadata{
+			ID:         buildLinkID(metadata.LinkSourceTypeCdp, localDeviceKey, localInterface.ID, remoteDevice.ID, remoteInterface.ID),
+			SourceType: metadata.LinkSourceTypeCdp,
+			Local: &metadata.TopologyLinkSide{
+				Device:    localDevice,
+				Interface: localInterface,
+			},
+			Remote: &metadata.TopologyLinkSide{
+				Device:    remoteDevice,
+				Interface: remoteInterface,
+			},
+		})
+	}
+	return links
+}
+
+// buildLinkID returns the same ID for the links reported by the devices at
+// both ends, since each device reports the IDs advertised by the other one.
+func buildLinkID(sourceType string, localDeviceID string, localInterfaceID string, remoteDeviceID string, remoteInterfaceID string) string {
+	ends := []string{localDeviceID + "/" + localInterfaceID, remoteDeviceID + "/" + remoteInterfaceID}
+	sort.Strings(ends)
+	return sourceType + ":" + ends[0] + "|" + ends[1]
+}
+
+// neighborKey identifies the remote device connected to a local interface
+// independently of the discovery protocol
+func neighborKey(link metadata.TopologyLinkMetadata) string {
+	if link.Local.Interface.DDID == "" || link.Remote.Device.IPAddress == "" {
+		return ""
+	}
+	return link.Local.Interface.DDID + "/" + link.Remote.Device.IPAddress
+}
+
+func findInterfaceByID(interfaces []metadata.InterfaceMetadata, idType string, id string) (metadata.InterfaceMetadata, bool) {
+	if id == "" {
+		return metadata.InterfaceMetadata{}, false
+	}
+	for _, itf := range interfaces {
+		switch idType {
+		case metadata.IDTypeInterfaceName:
+			if itf.Name == id {
+				return itf, true
+			}
+		case metadata.IDTypeInterfaceAlias:
+			if itf.Alias == id {
+				return itf, true
+			}
+		case metadata.IDTypeMacAddress:
+			if itf.MacAddress == id {
+				return itf, true
+			}
+		}
+	}
+	return metadata.InterfaceMetadata{}, false
+}
+
+func findInterfaceByIndex(interfaces []metadata.InterfaceMetadata, strIndex string) (metadata.InterfaceMetadata, bool) {
+	index, err := strconv.ParseInt(strIndex, 10, 32)
+	if err != nil {
+		return metadata.InterfaceMetadata{}, false
+	}
+	for _, itf := range interfaces {
+		if itf.Index == int32(index) {
+			return itf, true
+		}
+	}
+	return metadata.InterfaceMetadata{}, false
+}
+
+// formatTopologyID formats a LLDP chassis or port ID according to its type
+func formatTopologyID(value []byte, idType string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	switch idType {
+	case metadata.IDTypeMacAddress:
+		if len(value) == 6 {
+			return formatColonSepBytes(value)
+		}
+	case metadata.IDTypeNetworkAddress:
+		// the address is prefixed by its IANA address family number
+		if value[0] == ianaAddressFamilyIPv4 && len(value) == 1+net.IPv4len ||
+			value[0] == ianaAddressFamilyIPv6 && len(value) == 1+net.IPv6len {
+			return net.IP(value[1:]).String()
+		}
+	}
+	strValue, _ := valuestore.ResultValue{Value: value}.ToString()
+	return strValue
+}
@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/checkconfig"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/metadata"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/valuestore"
+)
+
+func Test_metricSender_reportNetworkDeviceMetadata_withTopology(t *testing.T) {
+	var storeWithTopology = &valuestore.ResultValueStore{
+		ScalarValues: valuestore.ScalarResultValuesType{
+			// lldpLocChassisIdSubtype, lldpLocChassisId
+			"1.0.8802.1.1.2.1.3.1.0": valuestore.ResultValue{Value: float64(4)},
+			"1.0.8802.1.1.2.1.3.2.0": valuestore.ResultValue{Value: []byte{0x82, 0xa5, 0x6e, 0xa5, 0xc8, 0x01}},
+			// cdpGlobalDeviceId
+			"1.3.6.1.4.1.9.9.23.1.3.4.0": valuestore.ResultValue{Value: []byte("switch-1")},
+		},
+		ColumnValues: valuestore.ColumnResultValuesType{
+			// ifName
+			"1.3.6.1.2.1.31.1.1.1.1": {
+				"1": valuestore.ResultValue{Value: []byte("eth0")},
+				"2": valuestore.ResultValue{Value: []byte("eth1")},
+				"3": valuestore.ResultValue{Value: []byte("eth2")},
+			},
+			// ifDescr
+			"1.3.6.1.2.1.2.2.1.2": {
+				"3": valuestore.ResultValue{Value: []byte("GigabitEthernet0/3")},
+			},
+			// lldpLocPortIdSubtype, lldpLocPortId, lldpLocPortDesc
+			"1.0.8802.1.1.2.1.3.7.1.2": {
+				"1": valuestore.ResultValue{Value: float64(5)},
+			},
+			"1.0.8802.1.1.2.1.3.7.1.3": {
+				"1": valuestore.ResultValue{Value: []byte("eth0")},
+			},
+			"1.0.8802.1.1.2.1.3.7.1.4": {
+				"1": valuestore.ResultValue{Value: []byte("uplink")},
+			},
+			// lldpRemChassisIdSubtype, lldpRemChassisId, lldpRemPortIdSubtype, lldpRemPortId
+			"1.0.8802.1.1.2.1.4.1.1.4": {
+				"0.1.1": valuestore.ResultValue{Value: float64(4)},
+				"0.2.1": valuestore.ResultValue{Value: float64(5)},
+			},
+			"1.0.8802.1.1.2.1.4.1.1.5": {
+				"0.1.1": valuestore.ResultValue{Value: []byte{0x00, 0x1c, 0x73, 0x00, 0x00, 0x99}},
+				"0.2.1": valuestore.ResultValue{Value: []byte{0x01, 10, 0, 0, 3}},
+			},
+			"1.0.8802.1.1.2.1.4.1.1.6": {
+				"0.1.1": valuestore.ResultValue{Value: float64(5)},
+				"0.2.1": valuestore.ResultValue{Value: float64(3)},
+			},
+			"1.0.8802.1.1.2.1.4.1.1.7": {
+				"0.1.1": valuestore.ResultValue{Value: []byte("eth7")},
+				"0.2.1": valuestore.ResultValue{Value: []byte{0x00, 0x1c, 0x73, 0x00, 0x00, 0x98}},
+			},
+			// lldpRemPortDesc, lldpRemSysName, lldpRemSysDesc
+			"1.0.8802.1.1.2.1.4.1.1.8": {
+				"0.1.1": valuestore.ResultValue{Value: []byte("downlink")},
+			},
+			"1.0.8802.1.1.2.1.4.1.1.9": {
+				"0.1.1": valuestore.ResultValue{Value: []byte("switch-2")},
+			},
+			"1.0.8802.1.1.2.1.4.1.1.10": {
+				"0.1.1": valuestore.ResultValue{Value: []byte("Linux")},
+			},
+			// lldpRemManAddrIfSubtype
+			"1.0.8802.1.1.2.1.4.2.1.3": {
+				"0.1.1.1.4.10.0.0.2": valuestore.ResultValue{Value: float64(2)},
+			},
+			// cdpCacheAddressType, cdpCacheAddress, cdpCacheDeviceId, cdpCacheDevicePort, cdpCachePlatform
+			"1.3.6.1.4.1.9.9.23.1.2.1.1.3": {
+				"1.3": valuestore.ResultValue{Value: float64(1)},
+				"3.1": valuestore.ResultValue{Value: float64(1)},
+			},
+			"1.3.6.1.4.1.9.9.23.1.2.1.1.4": {
+				"1.3": valuestore.ResultValue{Value: []byte{10, 0, 0, 2}},
+				"3.1": valuestore.ResultValue{Value: []byte{10, 0, 0, 4}},
+			},
+			"1.3.6.1.4.1.9.9.23.1.2.1.1.6": {
+				"1.3": valuestore.ResultValue{Value: []byte("switch-2")},
+				"3.1": valuestore.ResultValue{Value: []byte("router-1")},
+			},
+			"1.3.6.1.4.1.9.9.23.1.2.1.1.7": {
+				"1.3": valuestore.ResultValue{Value: []byte("eth7")},
+				"3.1": valuestore.ResultValue{Value: []byte("GigabitEthernet0/0")},
+			},
+			"1.3.6.1.4.1.9.9.23.1.2.1.1.8": {
+				"3.1": valuestore.ResultValue{Value: []byte("cisco C2960")},
+			},
+		},
+	}
+
+	sender := mocksender.NewMockSender("testID") // required to initiate aggregator
+	sender.On("EventPlatformEvent", mock.Anything, mock.Anything).Return()
+	ms := &MetricSender{
+		sender: sender,
+	}
+
+	metadataConfig := checkconfig.MetadataConfig{
+		"interface": {
+			Fields: map[string]checkconfig.MetadataField{
+				"name": {
+					Symbol: checkconfig.SymbolConfig{
+						OID:  "1.3.6.1.2.1.31.1.1.1.1",
+						Name: "ifName",
+					},
+				},
+				"description": {
+					Symbol: checkconfig.SymbolConfig{
+						OID:  "1.3.6.1.2.1.2.2.1.2",
+						Name: "ifDescr",
+					},
+				},
+			},
+		},
+	}
+	for resourceName, resourceConfig := range checkconfig.TopologyMetadataConfig {
+		metadataConfig[resourceName] = resourceConfig
+	}
+	config := &checkconfig.CheckConfig{
+		IPAddress:          "1.2.3.4",
+		DeviceID:           "my-ns:1.2.3.4",
+		DeviceIDTags:       []string{"device_name:127.0.0.1"},
+		ResolvedSubnetName: "127.0.0.0/29",
+		Namespace:          "my-ns",
+		CollectTopology:    true,
+		Metadata:           metadataConfig,
+	}
+
+	layout := "2006-01-02 15:04:05"
+	str := "2014-11-12 11:45:26"
+	collectTime, err := time.Parse(layout, str)
+	assert.NoError(t, err)
+	ms.ReportNetworkDeviceMetadata(config, storeWithTopology, []string{"tag1", "tag2"}, collectTime, metadata.DeviceStatusReachable)
+
+	// language=json
+	event := []byte(`
+{
+    "subnet": "127.0.0.0/29",
+    "namespace": "my-ns",
+    "devices": [
+        {
+            "id": "my-ns:1.2.3.4",
+            "id_tags": [
+                "device_name:127.0.0.1"
+            ],
+            "tags": [
+                "tag1",
+                "tag2"
+            ],
+            "ip_address": "1.2.3.4",
+            "status":1,
+            "subnet": "127.0.0.0/29"
+        }
+    ],
+    "interfaces": [
+        {
+            "device_id": "my-ns:1.2.3.4",
+            "id_tags": null,
+            "index": 1,
+            "name": "eth0"
+        },
+        {
+            "device_id": "my-ns:1.2.3.4",
+            "id_tags": null,
+            "index": 2,
+            "name": "eth1"
+        },
+        {
+            "device_id": "my-ns:1.2.3.4",
+            "id_tags": null,
+            "index": 3,
+            "name": "eth2",
+            "description": "GigabitEthernet0/3"
+        }
+    ],
+    "links": [
+        {
+            "id": "lldp:00:1c:73:00:00:99/eth7|82:a5:6e:a5:c8:01/eth0",
+            "source_type": "lldp",
+            "local": {
+                "device": {"dd_id": "my-ns:1.2.3.4", "id": "82:a5:6e:a5:c8:01", "id_type": "mac_address"},
+                "interface": {"dd_id": "my-ns:1.2.3.4:1", "id": "eth0", "id_type": "interface_name", "description": "uplink"}
+            },
+            "remote": {
+                "device": {"dd_id": "my-ns:10.0.0.2", "id": "00:1c:73:00:00:99", "id_type": "mac_address", "ip_address": "10.0.0.2", "name": "switch-2", "description": "Linux"},
+                "interface": {"id": "eth7", "id_type": "interface_name", "description": "downlink"}
+            }
+        },
+        {
+            "id": "lldp:10.0.0.3/00:1c:73:00:00:98|82:a5:6e:a5:c8:01/eth1",
+            "source_type": "lldp",
+            "local": {
+                "device": {"dd_id": "my-ns:1.2.3.4", "id": "82:a5:6e:a5:c8:01", "id_type": "mac_address"},
+                "interface": {"dd_id": "my-ns:1.2.3.4:2", "id": "eth1", "id_type": "interface_name"}
+            },
+            "remote": {
+                "device": {"id": "10.0.0.3", "id_type": "network_address"},
+                "interface": {"id": "00:1c:73:00:00:98", "id_type": "mac_address"}
+            }
+        },
+        {
+            "id": "cdp:router-1/GigabitEthernet0/0|switch-1/GigabitEthernet0/3",
+            "source_type": "cdp",
+            "local": {
+                "device": {"dd_id": "my-ns:1.2.3.4", "id": "switch-1"},
+                "interface": {"dd_id": "my-ns:1.2.3.4:3", "id": "GigabitEthernet0/3", "id_type": "interface_name"}
+            },
+            "remote": {
+                "device": {"dd_id": "my-ns:10.0.0.4", "id": "router-1", "ip_address": "10.0.0.4", "name": "router-1", "description": "cisco C2960"},
+                "interface": {"id": "GigabitEthernet0/0", "id_type": "interface_name"}
+            }
+        }
+    ],
+    "collect_timestamp":1415792726
+}
+`)
+	compactEvent := new(bytes.Buffer)
+	err = json.Compact(compactEvent, event)
+	assert.NoError(t, err)
+
+	sender.AssertEventPlatformEvent(t, compactEvent.String(), "network-devices-metadata")
+}
+
+func Test_buildNetworkTopologyMetadata_noTopology(t *testing.T) {
+	store := metadata.NewMetadataStore()
+	assert.Nil(t, buildNetworkTopologyMetadata("my-ns:1.2.3.4", "my-ns", store, nil))
+	assert.Nil(t, buildNetworkTopologyMetadata("my-ns:1.2.3.4", "my-ns", nil, nil))
+}
+
+func Test_buildLinkID(t *testing.T) {
+	// both ends of a link report the same ID
+	assert.Equal(t,
+		buildLinkID(metadata.LinkSourceTypeLldp, "aa:aa:aa:aa:aa:aa", "eth0", "bb:bb:bb:bb:bb:bb", "eth1"),
+		buildLinkID(metadata.LinkSourceTypeLldp, "bb:bb:bb:bb:bb:bb", "eth1", "aa:aa:aa:aa:aa:aa", "eth0"))
+	assert.NotEqual(t,
+		buildLinkID(metadata.LinkSourceTypeLldp, "aa:aa:aa:aa:aa:aa", "eth0", "bb:bb:bb:bb:bb:bb", "eth1"),
+		buildLinkID(metadata.LinkSourceTypeCdp, "aa:aa:aa:aa:aa:aa", "eth0", "bb:bb:bb:bb:bb:bb", "eth1"))
+}
+
+func Test_formatTopologyID(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    []byte
+		idType   string
+		expected string
+	}{
+		{"empty", nil, metadata.IDTypeMacAddress, ""},
+		{"mac address", []byte{0x82, 0xa5, 0x6e, 0xa5, 0xc8, 0x01}, metadata.IDTypeMacAddress, "82:a5:6e:a5:c8:01"},
+		{"invalid mac address", []byte{0x82, 0xa5}, metadata.IDTypeMacAddress, "0x82a5"},
+		{"ipv4 network address", []byte{0x01, 10, 0, 0, 1}, metadata.IDTypeNetworkAddress, "10.0.0.1"},
+		{"ipv6 network address", []byte{0x02, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, metadata.IDTypeNetworkAddress, "2001:db8::1"},
+		{"unknown network address", []byte{0x03, 10, 0, 0, 1}, metadata.IDTypeNetworkAddress, "0x030a000001"},
+		{"interface name", []byte("eth0"), metadata.IDTypeInterfaceName, "eth0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatTopologyID(tt.value, tt.idType))
+		})
+	}
+}
@@ -34,16 +34,28 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
 	config.SetKnown("apm_config.filter_tags.require")
 	config.SetKnown("apm_config.filter_tags.reject")
+	config.SetKnown("apm_config.filter_tags.any_span")
+	config.SetKnown("apm_config.filter_tags_regex.require")
+	config.SetKnown("apm_config.filter_tags_regex.reject")
+	config.SetKnown("apm_config.filter_tags_glob.require")
+	config.SetKnown("apm_config.filter_tags_glob.reject")
+	config.SetKnown("apm_config.tail_sampling.max_memory_bytes")
+	config.SetKnown("apm_config.tail_sampling.policies")
 	config.SetKnown("apm_config.extra_sample_rate")
 	config.SetKnown("apm_config.dd_agent_bin")
 	config.SetKnown("apm_config.trace_writer.connection_limit")
 	config.SetKnown("apm_config.trace_writer.queue_size")
+	config.SetKnown("apm_config.trace_writer.compression")
+	config.SetKnown("apm_config.trace_writer.compression_level")
 	config.SetKnown("apm_config.service_writer.connection_limit")
 	config.SetKnown("apm_config.service_writer.queue_size")
 	config.SetKnown("apm_config.stats_writer.connection_limit")
 	config.SetKnown("apm_config.stats_writer.queue_size")
+	config.SetKnown("apm_config.stats_writer.compression")
+	config.SetKnown("apm_config.stats_writer.compression_level")
 	config.SetKnown("apm_config.analyzed_rate_by_service.*")
 	config.SetKnown("apm_config.log_throttling")
 	config.SetKnown("apm_config.bucket_size_seconds")
@@ -72,6 +84,9 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.errors_per_second", "DD_APM_ERROR_TPS")
 	config.BindEnv("apm_config.disable_rare_sampler", "DD_APM_DISABLE_RARE_SAMPLER")
 	config.BindEnv("apm_config.max_remote_traces_per_second", "DD_APM_MAX_REMOTE_TPS")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.fallback_rate", "DD_APM_TAIL_SAMPLING_FALLBACK_RATE")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -84,6 +99,7 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.profiling_additional_endpoints", "DD_APM_PROFILING_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")
+	config.BindEnv("apm_config.transform_rules", "DD_APM_TRANSFORM_RULES")
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")
@@ -91,6 +107,13 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.sync_flushing", "DD_APM_SYNC_FLUSHING")
 	config.BindEnv("apm_config.filter_tags.require", "DD_APM_FILTER_TAGS_REQUIRE")
 	config.BindEnv("apm_config.filter_tags.reject", "DD_APM_FILTER_TAGS_REJECT")
+	config.BindEnv("apm_config.filter_tags.any_span", "DD_APM_FILTER_TAGS_ANY_SPAN")
+	config.BindEnv("apm_config.filter_tags_regex.require", "DD_APM_FILTER_TAGS_REGEX_REQUIRE")
+	config.BindEnv("apm_config.filter_tags_regex.reject", "DD_APM_FILTER_TAGS_REGEX_REJECT")
+	config.BindEnv("apm_config.filter_tags_glob.require", "DD_APM_FILTER_TAGS_GLOB_REQUIRE")
+	config.BindEnv("apm_config.filter_tags_glob.reject", "DD_APM_FILTER_TAGS_GLOB_REJECT")
+	config.BindEnv("apm_config.extra_aggregation_tags", "DD_APM_EXTRA_AGGREGATION_TAGS")
+	config.BindEnv("apm_config.max_extra_aggregation_tag_sets", "DD_APM_MAX_EXTRA_AGGREGATION_TAG_SETS")
 	config.BindEnv("apm_config.internal_profiling.enabled", "DD_APM_INTERNAL_PROFILING_ENABLED")
 	config.BindEnv("apm_config.debugger_dd_url", "DD_APM_DEBUGGER_DD_URL")
 	config.BindEnv("apm_config.debugger_api_key", "DD_APM_DEBUGGER_API_KEY")
@@ -117,6 +140,17 @@ func setupAPM(config Config) {
 		return strings.Split(in, " ")
 	})
 
+	for _, key := range []string{
+		"apm_config.filter_tags_regex.require",
+		"apm_config.filter_tags_regex.reject",
+		"apm_config.filter_tags_glob.require",
+		"apm_config.filter_tags_glob.reject",
+	} {
+		config.SetEnvKeyTransformer(key, func(in string) interface{} {
+			return strings.Split(in, " ")
+		})
+	}
+
 	config.SetEnvKeyTransformer("apm_config.replace_tags", func(in string) interface{} {
 		var out []map[string]string
 		if err := json.Unmarshal([]byte(in), &out); err != nil {
@@ -125,6 +159,14 @@ func setupAPM(config Config) {
 		return out
 	})
 
+	config.SetEnvKeyTransformer("apm_config.transform_rules", func(in string) interface{} {
+		var out []map[string]string
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.transform_rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.SetEnvKeyTransformer("apm_config.analyzed_spans", func(in string) interface{} {
 		out, err := parseAnalyzedSpans(in)
 		if err != nil {
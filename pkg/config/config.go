@@ -69,6 +69,9 @@ const (
 	// DefaultRuntimePoliciesDir is the default policies directory used by the runtime security module
 	DefaultRuntimePoliciesDir = "/etc/datadog-agent/runtime-security.d"
 
+	// DefaultRuntimeSecurityProfilesDir is the default security profiles directory used by the runtime security module
+	DefaultRuntimeSecurityProfilesDir = "/etc/datadog-agent/runtime-security.d/profiles"
+
 	// DefaultLogsSenderBackoffFactor is the default logs sender backoff randomness factor
 	DefaultLogsSenderBackoffFactor = 2.0
 
@@ -498,7 +501,13 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("dogstatsd_queue_size", 1024)
 
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
-	config.BindEnvAndSetDefault("dogstatsd_socket", "") // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_socket", "")  // Notice: empty means feature disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0) // Notice: 0 means TCP listener disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "newline")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_idle_timeout", 60*time.Second)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_origin_tagging", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
 	config.BindEnvAndSetDefault("dogstatsd_stats_port", 5000)
@@ -511,6 +520,15 @@ func InitConfig(config Config) {
 	// is 10s), otherwise we won't be able to sample unseen counter as
 	// contexts will be deleted (see 'dogstatsd_expiry_seconds').
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 300)
+	// Samples sent with a timestamp older than this are rejected. 0 disables the check.
+	config.BindEnvAndSetDefault("dogstatsd_timestamp_max_age", time.Hour)
+	// Limits of live contexts per metric name and per origin, 0 means no limit.
+	// The overflow strategy is either "drop" or "collapse".
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.origin_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.overflow_strategy", "drop")
+	// List of {name, limit} overriding metric_limit for some metric names.
+	config.SetKnown("dogstatsd_context_limiter.metric_limits")
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_so_rcvbuf", 0)
@@ -642,6 +660,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("network_devices.snmp_traps.stop_timeout", 5) // in seconds
 	config.SetKnown("network_devices.snmp_traps.users")
 
+	bindEnvAndSetLogsConfigKeys(config, "network_devices.netflow.forwarder.")
+	config.BindEnvAndSetDefault("network_devices.netflow.enabled", false)
+	config.BindEnvAndSetDefault("network_devices.netflow.stop_timeout", 5)                // in seconds
+	config.BindEnvAndSetDefault("network_devices.netflow.aggregator_buffer_size", 10000)  // in flows
+	config.BindEnvAndSetDefault("network_devices.netflow.aggregator_flush_interval", 300) // in seconds
+	config.SetKnown("network_devices.netflow.listeners")
+	config.SetKnown("network_devices.netflow.namespace")
+
 	// Kube ApiServer
 	config.BindEnvAndSetDefault("kubernetes_kubeconfig_path", "")
 	config.BindEnvAndSetDefault("kubernetes_apiserver_ca_path", "")
@@ -842,6 +868,14 @@ func InitConfig(config Config) {
 	// Time in seconds
 	config.BindEnvAndSetDefault("logs_config.file_scan_period", 10.0)
 
+	// On-disk spool storing the logs payloads while the intake is unreachable,
+	// only supported with HTTP transport.
+	config.BindEnvAndSetDefault("logs_config.spool.enabled", false)
+	// Defaults to the "spool" directory of logs_config.run_path when empty.
+	config.BindEnvAndSetDefault("logs_config.spool.path", "")
+	config.BindEnvAndSetDefault("logs_config.spool.max_size", 100*1024*1024) // in bytes
+	config.BindEnvAndSetDefault("logs_config.spool.max_age", 24*time.Hour)   // payloads older than that are dropped
+
 	// The cardinality of tags to send for checks and dogstatsd respectively.
 	// Choices are: low, orchestrator, high.
 	// WARNING: sending orchestrator, or high tags for dogstatsd metrics may create more metrics
@@ -1030,13 +1064,30 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_dump_timeout", 30)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_wait_list_size", 10)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_output_directory", "")
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.local_storage.formats", []string{"msgp", "dot"})
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.local_storage.compression", false)
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.remote_storage.endpoint", "")
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.remote_storage.formats", []string{"protobuf"})
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.remote_storage.compression", true)
 	config.BindEnvAndSetDefault("runtime_security_config.network.enabled", false)
 	config.BindEnvAndSetDefault("runtime_security_config.network.lazy_interface_prefixes", []string{})
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.enabled", false)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.dir", DefaultRuntimeSecurityProfilesDir)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.learning_period", 60)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.merge_period", 60)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.merge_anomalies", false)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.max_entries", 10000)
+	config.BindEnvAndSetDefault("runtime_security_config.security_profile.max_pending_anomalies", 1000)
+	config.BindEnvAndSetDefault("runtime_security_config.enforcement.enabled", true)
+	config.BindEnvAndSetDefault("runtime_security_config.enforcement.rules", []string{})
+	config.BindEnvAndSetDefault("runtime_security_config.enforcement.rate_limiter.rate", 5)
+	config.BindEnvAndSetDefault("runtime_security_config.enforcement.rate_limiter.burst", 10)
 
 	// Serverless Agent
 	config.BindEnvAndSetDefault("serverless.logs_enabled", true)
 	config.BindEnvAndSetDefault("enhanced_metrics", true)
 	config.BindEnvAndSetDefault("capture_lambda_payload", false)
+	config.BindEnvAndSetDefault("trace_managed_services", true)
 
 	// command line options
 	config.SetKnown("cmd.check.fullsketches")
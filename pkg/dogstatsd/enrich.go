@@ -149,6 +149,14 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 
 	mtype := enrichMetricType(ddSample.metricType)
 
+	// samples sent with a timestamp are not aggregated, they are sent as is
+	// at the time provided by the client.
+	var timestamp float64
+	noAggregation := ddSample.timestamp > 0
+	if noAggregation {
+		timestamp = float64(ddSample.timestamp)
+	}
+
 	// if 'ddSample.values' contains values we're enriching a multi-value
 	// dogstatsd message and will create a MetricSample per value. If not
 	// we will use 'ddSample.value'and return a single MetricSample
@@ -163,6 +171,8 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 					Value:            ddSample.values[idx],
 					SampleRate:       ddSample.sampleRate,
 					RawValue:         ddSample.setValue,
+					Timestamp:        timestamp,
+					NoAggregation:    noAggregation,
 					OriginFromUDS:    udsOrigin,
 					OriginFromClient: clientOrigin,
 					Cardinality:      cardinality,
@@ -180,6 +190,8 @@ func enrichMetricSample(metricSamples []metrics.MetricSample, ddSample dogstatsd
 		Value:            ddSample.value,
 		SampleRate:       ddSample.sampleRate,
 		RawValue:         ddSample.setValue,
+		Timestamp:        timestamp,
+		NoAggregation:    noAggregation,
 		OriginFromUDS:    udsOrigin,
 		OriginFromClient: clientOrigin,
 		Cardinality:      cardinality,
@@ -6,154 +6,354 @@
 package listeners
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/DataDog/datadog-agent/pkg/logs/config"
-	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/replay"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
-// A TCPListener listens and accepts TCP connections and delegates the read operations to a tailer.
+const (
+	// TCPFramingNewline is the framing where each message ends with a '\n'.
+	TCPFramingNewline = "newline"
+	// TCPFramingLengthPrefix is the framing where each payload is prefixed by its
+	// length, as a 4 bytes little-endian unsigned integer.
+	TCPFramingLengthPrefix = "length_prefix"
+
+	tcpOriginTagPrefix = "client_ip:"
+)
+
+var tcpTelemetry = newListenerTelemetry("tcp", "TCP")
+
+// TCPListener implements the StatsdListener interface for the TCP protocol,
+// optionally wrapped in TLS. It accepts connections on a given port and sends
+// back packets ready to be processed.
+// Every connection has its own packet assembler so that the messages it
+// receives can be tagged with the origin of the connection.
 type TCPListener struct {
-	pipelineProvider pipeline.Provider
-	source           *config.LogSource
-	idleTimeout      time.Duration
-	frameSize        int
-	listener         net.Listener
-	mu               sync.Mutex
-	stop             chan struct{}
+	listener                net.Listener
+	packetsBuffer           *packets.Buffer
+	sharedPacketPoolManager *packets.PoolManager
+	trafficCapture          *replay.TrafficCapture // Currently ignored
+
+	framing       string
+	bufferSize    int
+	flushTimeout  time.Duration
+	idleTimeout   time.Duration
+	originTagging bool
+
+	mu          sync.Mutex
+	connections map[net.Conn]struct{}
+	stopped     bool
+	wg          sync.WaitGroup
 }
 
-// NewTCPListener returns an initialized TCPListener
-func NewTCPListener(pipelineProvider pipeline.Provider, source *config.LogSource, frameSize int) *TCPListener {
-	var idleTimeout time.Duration
-	if source.Config.IdleTimeout != "" {
-		var err error
-		idleTimeout, err = time.ParseDuration(source.Config.IdleTimeout)
-		if err != nil {
-			log.Errorf("Error parsing log's idle_timeout as a duration: %s", err)
-			idleTimeout = 0
-		}
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager, capture *replay.TrafficCapture) (*TCPListener, error) {
+	var url string
+	if config.Datadog.GetBool("dogstatsd_non_local_traffic") == true {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%d", config.Datadog.GetInt("dogstatsd_tcp_port"))
+	} else {
+		url = net.JoinHostPort(config.GetBindHost(), config.Datadog.GetString("dogstatsd_tcp_port"))
+	}
+
+	framing := config.Datadog.GetString("dogstatsd_tcp_framing")
+	if framing != TCPFramingNewline && framing != TCPFramingLengthPrefix {
+		return nil, fmt.Errorf("dogstatsd-tcp: invalid dogstatsd_tcp_framing %q, must be %q or %q", framing, TCPFramingNewline, TCPFramingLengthPrefix)
+	}
+
+	tlsConfig, err := buildTCPTLSConfig(config.Datadog.GetString("dogstatsd_tcp_tls_cert_file"), config.Datadog.GetString("dogstatsd_tcp_tls_key_file"))
+	if err != nil {
+		return nil, err
+	}
+
+	var listener net.Listener
+	if tlsConfig != nil {
+		listener, err = tls.Listen("tcp", url, tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", url)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := config.Datadog.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := config.Datadog.GetDuration("dogstatsd_packet_buffer_flush_timeout")
 
-	return &TCPListener{
-		pipelineProvider: pipelineProvider,
-		source:           source,
-		idleTimeout:      idleTimeout,
-		frameSize:        frameSize,
-		stop:             make(chan struct{}, 1),
+	l := &TCPListener{
+		listener:                listener,
+		packetsBuffer:           packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut),
+		sharedPacketPoolManager: sharedPacketPoolManager,
+		trafficCapture:          capture,
+		framing:                 framing,
+		bufferSize:              config.Datadog.GetInt("dogstatsd_buffer_size"),
+		flushTimeout:            flushTimeout,
+		idleTimeout:             config.Datadog.GetDuration("dogstatsd_tcp_idle_timeout"),
+		originTagging:           config.Datadog.GetBool("dogstatsd_tcp_origin_tagging"),
+		connections:             make(map[net.Conn]struct{}),
 	}
+
+	log.Debugf("dogstatsd-tcp: %s successfully initialized (framing: %s, tls: %t)", listener.Addr(), framing, tlsConfig != nil)
+	return l, nil
 }
 
-// Start starts the listener to accepts new incoming connections.
-func (l *TCPListener) Start() {
-	log.Infof("Starting TCP forwarder on port %d, with read buffer size: %d", l.source.Config.Port, l.frameSize)
-	err := l.startListener()
+// buildTCPTLSConfig returns the TLS configuration of the listener, or nil if
+// TLS is not configured.
+func buildTCPTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("dogstatsd-tcp: both dogstatsd_tcp_tls_cert_file and dogstatsd_tcp_tls_key_file must be set to enable TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
-		log.Errorf("Can't start TCP forwarder on port %d: %v", l.source.Config.Port, err)
-		l.source.Status.Error(err)
-		return
+		return nil, fmt.Errorf("dogstatsd-tcp: can't load TLS certificate: %s", err)
 	}
-	l.source.Status.Success()
-	go l.run()
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
 }
 
-// Stop stops the listener from accepting new connections
-func (l *TCPListener) Stop() {
-	log.Infof("Stopping TCP forwarder on port %d", l.source.Config.Port)
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// listener has been closed
+			if isClosedConnError(err) {
+				return
+			}
+			log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			continue
+		}
+
+		if !l.trackConnection(conn) {
+			conn.Close()
+			return
+		}
+		tlmTCPConnectionEvents.Inc("accepted")
+		go l.handleConnection(conn)
+	}
+}
+
+// trackConnection registers a new connection, it returns false if the
+// listener is being stopped.
+func (l *TCPListener) trackConnection(conn net.Conn) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.stop <- struct{}{}
-	l.listener.Close()
-	// need to figure out here how to forward  - p
+	if l.stopped {
+		return false
+	}
+	l.connections[conn] = struct{}{}
+	l.wg.Add(1)
+	tlmTCPConnections.Inc()
+	return true
 }
 
-func isClosedConnError(err error) bool {
-	return strings.Contains(err.Error(), "use of closed network connection")
+func (l *TCPListener) isStopped() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopped
 }
 
-// run accepts new TCP connections and create a dedicated tailer for each.
-func (l *TCPListener) run() {
-	defer l.listener.Close()
-	for {
-		select {
-		case <-l.stop:
-			// stop accepting new connections.
+func (l *TCPListener) untrackConnection(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.connections, conn)
+	tlmTCPConnections.Dec()
+	tlmTCPConnectionEvents.Inc("closed")
+	l.wg.Done()
+}
+
+// handleConnection reads the messages sent on a connection until it is
+// closed by the client, times out or the listener is stopped.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	defer l.untrackConnection(conn)
+	defer conn.Close()
+
+	log.Debugf("dogstatsd-tcp: new connection from %s", conn.RemoteAddr())
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if l.idleTimeout > 0 {
+			tlsConn.SetDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			log.Debugf("dogstatsd-tcp: TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+			tlmTCPConnectionEvents.Inc("handshake_error")
 			return
-		default:
-			conn, err := l.listener.Accept()
-			switch {
-			case err != nil && isClosedConnError(err):
-				return
-			case err != nil:
-				// an error occurred, restart the listener.
-				log.Warnf("Can't listen on port %d, restarting a listener: %v", l.source.Config.Port, err)
-				l.listener.Close()
-				err := l.startListener()
-				if err != nil {
-					log.Errorf("Can't restart listener on port %d: %v", l.source.Config.Port, err)
-					l.source.Status.Error(err)
-					return
+		}
+	}
+
+	assembler := packets.NewAssemblerWithTags(l.flushTimeout, l.packetsBuffer, l.sharedPacketPoolManager, packets.TCP, l.originTags(conn))
+	defer func() {
+		// the messages received right before the connection is closed
+		// must not wait for a flush timer that is about to be stopped.
+		assembler.Flush()
+		assembler.Close()
+	}()
+
+	var err error
+	if l.framing == TCPFramingLengthPrefix {
+		err = l.readLengthPrefixed(conn, assembler)
+	} else {
+		err = l.readNewlineDelimited(conn, assembler)
+	}
+
+	switch {
+	case err == nil || err == io.EOF || isClosedConnError(err) || l.isStopped():
+		log.Debugf("dogstatsd-tcp: connection from %s closed", conn.RemoteAddr())
+	case isTimeoutError(err):
+		log.Debugf("dogstatsd-tcp: closing idle connection from %s", conn.RemoteAddr())
+		tlmTCPConnectionEvents.Inc("idle_timeout")
+	default:
+		log.Errorf("dogstatsd-tcp: error reading from %s: %v", conn.RemoteAddr(), err)
+		tcpTelemetry.onReadError()
+	}
+}
+
+// originTags returns the tags identifying the client of a connection, if
+// origin tagging is enabled.
+func (l *TCPListener) originTags(conn net.Conn) []string {
+	if !l.originTagging {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return nil
+	}
+	return []string{tcpOriginTagPrefix + host}
+}
+
+func (l *TCPListener) setReadDeadline(conn net.Conn) {
+	if l.idleTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
+	}
+}
+
+// readNewlineDelimited reads messages separated by '\n'. Incomplete messages
+// are kept in the buffer until the rest of the message is received. The
+// messages bigger than the buffer are dropped, up to their '\n'.
+func (l *TCPListener) readNewlineDelimited(conn net.Conn, assembler *packets.Assembler) error {
+	buffer := make([]byte, l.bufferSize)
+	startWriteIndex := 0
+	// discarding is true while the rest of a message bigger than the buffer
+	// is skipped.
+	discarding := false
+	t1 := time.Now()
+	for {
+		l.setReadDeadline(conn)
+		tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "tcp")
+		bytesRead, err := conn.Read(buffer[startWriteIndex:])
+		t1 = time.Now()
+
+		if bytesRead > 0 {
+			startIndex := 0
+			endIndex := startWriteIndex + bytesRead
+
+			if discarding {
+				// the first '\n' ends the dropped message
+				if skipped := bytes.IndexByte(buffer[:endIndex], '\n'); skipped >= 0 {
+					startIndex = skipped + 1
+					discarding = false
+				} else {
+					startIndex = endIndex
 				}
-				l.source.Status.Success()
-				continue
-			default:
-				l.startTailer(conn)
-				l.source.Status.Success()
 			}
+
+			// When there is no '\n', the message is partial. LastIndexByte returns -1 and messageSize is 0.
+			// If there is a '\n', at least one message is completed and '\n' is part of this message.
+			messageSize := bytes.LastIndexByte(buffer[startIndex:endIndex], '\n') + 1
+			if messageSize > 0 {
+				tcpTelemetry.onReadSuccess(messageSize)
+				// the assembler adds its own separator between the messages
+				assembler.AddMessage(buffer[startIndex : startIndex+messageSize-1])
+			}
+
+			startWriteIndex = endIndex - startIndex - messageSize
+
+			// If the message is bigger than the buffer size, drop it and skip its
+			// remaining bytes before reading the next messages.
+			if startWriteIndex >= len(buffer) {
+				log.Debugf("dogstatsd-tcp: dropping message from %s bigger than dogstatsd_buffer_size", conn.RemoteAddr())
+				tcpTelemetry.onReadError()
+				startWriteIndex = 0
+				discarding = true
+			} else {
+				copy(buffer, buffer[startIndex+messageSize:endIndex])
+			}
+		}
+
+		if err != nil {
+			return err
 		}
 	}
 }
 
-// startListener starts a new listener, returns an error if it failed.
-func (l *TCPListener) startListener() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
-	if err != nil {
-		return err
+// readLengthPrefixed reads payloads prefixed by their length.
+func (l *TCPListener) readLengthPrefixed(conn net.Conn, assembler *packets.Assembler) error {
+	buffer := make([]byte, l.bufferSize)
+	var header [4]byte
+	t1 := time.Now()
+	for {
+		l.setReadDeadline(conn)
+		tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "tcp")
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return err
+		}
+		t1 = time.Now()
+
+		size := int(binary.LittleEndian.Uint32(header[:]))
+		if size > len(buffer) {
+			// the framing can't be trusted anymore, close the connection.
+			return fmt.Errorf("payload of %d bytes is bigger than dogstatsd_buffer_size", size)
+		}
+
+		l.setReadDeadline(conn)
+		if _, err := io.ReadFull(conn, buffer[:size]); err != nil {
+			return err
+		}
+		if size == 0 {
+			continue
+		}
+		tcpTelemetry.onReadSuccess(size)
+		assembler.AddMessage(bytes.TrimSuffix(buffer[:size], []byte{'\n'}))
 	}
-	l.listener = listener
-	return nil
 }
 
-// Here need to implement however UDP is implemented and ensure a TCP listener can be utilized
-
-// read reads data from connection, returns an error if it failed and stop the tailer.
-// func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, error) {
-// 	if l.idleTimeout > 0 {
-// 		tailer.Conn.SetReadDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
-// 	}
-// 	frame := make([]byte, l.frameSize)
-// 	n, err := tailer.Conn.Read(frame)
-// 	if err != nil {
-// 		l.source.Status.Error(err)
-// 		go l.stopTailer(tailer)
-// 		return nil, err
-// 	}
-// 	return frame[:n], nil
-// }
-
-// // startTailer creates and starts a new tailer that reads from the connection.
-// func (l *TCPListener) startTailer(conn net.Conn) {
-// 	l.mu.Lock()
-// 	defer l.mu.Unlock()
-// 	tailer := tailer.NewTailer(l.source, conn, l.pipelineProvider.NextPipelineChan(), l.read)
-// 	l.tailers = append(l.tailers, tailer)
-// 	tailer.Start()
-// }
-
-// // stopTailer stops the tailer.
-// func (l *TCPListener) stopTailer(tailer *tailer.Tailer) {
-// 	tailer.Stop()
-// 	l.mu.Lock()
-// 	defer l.mu.Unlock()
-// 	for i, t := range l.tailers {
-// 		if t == tailer {
-// 			l.tailers = append(l.tailers[:i], l.tailers[i+1:]...)
-// 			break
-// 		}
-// 	}
-// }
+// Stop closes the TCP listener and all the open connections and stops listening
+func (l *TCPListener) Stop() {
+	l.mu.Lock()
+	l.stopped = true
+	l.listener.Close()
+	for conn := range l.connections {
+		// Stop the current execution of net.Conn.Read() and exit the connection loop.
+		conn.SetReadDeadline(time.Now()) //nolint:errcheck
+	}
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	l.packetsBuffer.Close()
+}
+
+func isClosedConnError(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/util/testutil"
+)
+
+var (
+	packetPoolTCP        = packets.NewPool(config.Datadog.GetInt("dogstatsd_buffer_size"))
+	packetPoolManagerTCP = packets.NewPoolManager(packetPoolTCP)
+)
+
+func newTestTCPListener(t *testing.T, packetChannel chan packets.Packets) (*TCPListener, int) {
+	port, err := testutil.GetAvailableTCPPort()
+	require.Nil(t, err)
+	config.Datadog.SetDefault("dogstatsd_tcp_port", port)
+	config.Datadog.SetDefault("dogstatsd_non_local_traffic", false)
+
+	s, err := NewTCPListener(packetChannel, packetPoolManagerTCP, nil)
+	require.Nil(t, err)
+	require.NotNil(t, s)
+	return s, port
+}
+
+func receivePackets(t *testing.T, packetChannel chan packets.Packets) *packets.Packet {
+	select {
+	case pkts := <-packetChannel:
+		require.Equal(t, 1, len(pkts))
+		return pkts[0]
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestStartStopTCPListener(t *testing.T) {
+	s, port := newTestTCPListener(t, nil)
+
+	go s.Listen()
+	// Local port should be unavailable
+	_, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	assert.NotNil(t, err)
+
+	// An open connection should not prevent the listener from stopping
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	s.Stop()
+
+	// check that the port can be bound, try for 100 ms
+	for i := 0; i < 10; i++ {
+		var l net.Listener
+		l, err = net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			l.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err, "port is not available, it should be")
+}
+
+func TestTCPReceiveNewlineFraming(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", TCPFramingNewline)
+	packetChannel := make(chan packets.Packets)
+	s, port := newTestTCPListener(t, packetChannel)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// the second message is split over two writes
+	conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:667|g|#some"))
+	conn.Write([]byte("tag2:somevalue2\n"))
+
+	packet := receivePackets(t, packetChannel)
+	assert.Equal(t, "daemon:666|g|#sometag1:somevalue1\ndaemon:667|g|#sometag2:somevalue2", string(packet.Contents))
+	assert.Equal(t, packets.TCP, packet.Source)
+	assert.Equal(t, "", packet.Origin)
+	assert.Nil(t, packet.Tags)
+}
+
+func TestTCPReceiveNewlineFramingOversizeMessage(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", TCPFramingNewline)
+	config.Datadog.SetDefault("dogstatsd_buffer_size", 16)
+	defer config.Datadog.SetDefault("dogstatsd_buffer_size", 8192)
+	packetChannel := make(chan packets.Packets)
+	s, port := newTestTCPListener(t, packetChannel)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// the rest of the oversize message must not be parsed as a new message
+	conn.Write([]byte("daemon:666|g|#sometag1:somevalue1,sometag2:somevalue2\n"))
+	conn.Write([]byte("daemon:667|g\n"))
+
+	packet := receivePackets(t, packetChannel)
+	assert.Equal(t, "daemon:667|g", string(packet.Contents))
+}
+
+func TestTCPReceiveLengthPrefixFraming(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", TCPFramingLengthPrefix)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_framing", TCPFramingNewline)
+	packetChannel := make(chan packets.Packets)
+	s, port := newTestTCPListener(t, packetChannel)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	payload := []byte("daemon:666|g\ndaemon:667|g")
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint32(header, uint32(len(payload)))
+	conn.Write(append(header, payload...))
+
+	packet := receivePackets(t, packetChannel)
+	assert.Equal(t, payload, packet.Contents)
+	assert.Equal(t, packets.TCP, packet.Source)
+}
+
+func TestTCPFlushOnClose(t *testing.T) {
+	// the flush timer should not be needed to forward the messages of a closed connection
+	config.Datadog.SetDefault("dogstatsd_packet_buffer_flush_timeout", time.Hour)
+	defer config.Datadog.SetDefault("dogstatsd_packet_buffer_flush_timeout", 100*time.Millisecond)
+	config.Datadog.SetDefault("dogstatsd_packet_buffer_size", 1)
+	defer config.Datadog.SetDefault("dogstatsd_packet_buffer_size", 32)
+
+	packetChannel := make(chan packets.Packets)
+	s, port := newTestTCPListener(t, packetChannel)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	conn.Write([]byte("daemon:666|g\n"))
+	conn.Close()
+
+	packet := receivePackets(t, packetChannel)
+	assert.Equal(t, "daemon:666|g", string(packet.Contents))
+}
+
+func TestTCPOriginTagging(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_origin_tagging", true)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_origin_tagging", false)
+	packetChannel := make(chan packets.Packets)
+	s, port := newTestTCPListener(t, packetChannel)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+	conn.Write([]byte("daemon:666|g\n"))
+
+	packet := receivePackets(t, packetChannel)
+	assert.Equal(t, []string{"client_ip:127.0.0.1"}, packet.Tags)
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_idle_timeout", 50*time.Millisecond)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_idle_timeout", 60*time.Second)
+	s, port := newTestTCPListener(t, nil)
+
+	go s.Listen()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	// the server closes the connection, the read returns io.EOF
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.False(t, isTimeoutError(err), "the connection should have been closed by the listener")
+}
+
+func TestTCPInvalidConfiguration(t *testing.T) {
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", "unknown")
+	_, err := NewTCPListener(nil, packetPoolManagerTCP, nil)
+	assert.Error(t, err)
+	config.Datadog.SetDefault("dogstatsd_tcp_framing", TCPFramingNewline)
+
+	config.Datadog.SetDefault("dogstatsd_tcp_tls_cert_file", "/does/not/exist.pem")
+	_, err = NewTCPListener(nil, packetPoolManagerTCP, nil)
+	assert.Error(t, err)
+	config.Datadog.SetDefault("dogstatsd_tcp_tls_cert_file", "")
+}
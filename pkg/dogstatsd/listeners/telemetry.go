@@ -6,6 +6,9 @@
 package listeners
 
 import (
+	"expvar"
+	"fmt"
+
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 )
 
@@ -24,6 +27,12 @@ var (
 	tlmUDSPacketsBytes = telemetry.NewCounter("dogstatsd", "uds_packets_bytes",
 		nil, "Dogstatsd UDS packets bytes")
 
+	// TCP
+	tlmTCPConnections = telemetry.NewGauge("dogstatsd", "tcp_connections",
+		nil, "Dogstatsd TCP active connections count")
+	tlmTCPConnectionEvents = telemetry.NewCounter("dogstatsd", "tcp_connection_events",
+		[]string{"event"}, "Dogstatsd TCP connections events count (accepted, closed, idle_timeout, handshake_error)")
+
 	tlmListener            = telemetry.NewHistogramNoOp()
 	defaultListenerBuckets = []float64{300, 500, 1000, 1500, 2000, 2500, 3000, 10000, 20000, 50000}
 )
@@ -43,3 +52,43 @@ func InitTelemetry(buckets []float64) {
 		"Time in nanoseconds while the listener is not reading data",
 		buckets)
 }
+
+type listenerTelemetry struct {
+	packetReadingErrors expvar.Int
+	packets             expvar.Int
+	bytes               expvar.Int
+	expvars             *expvar.Map
+	tlmPackets          telemetry.Counter
+	tlmPacketsBytes     telemetry.Counter
+}
+
+func newListenerTelemetry(metricName string, name string) *listenerTelemetry {
+	t := &listenerTelemetry{
+		expvars: expvar.NewMap("dogstatsd-" + metricName),
+		tlmPackets: telemetry.NewCounter("dogstatsd", metricName+"_packets",
+			[]string{"state"}, fmt.Sprintf("Dogstatsd %s packets count", name)),
+		tlmPacketsBytes: telemetry.NewCounter("dogstatsd", metricName+"_packets_bytes",
+			nil, fmt.Sprintf("Dogstatsd %s packets bytes count", name)),
+	}
+
+	// the expvars are registered from the struct fields so that they are
+	// the ones updated by onReadSuccess and onReadError.
+	t.expvars.Set("PacketReadingErrors", &t.packetReadingErrors)
+	t.expvars.Set("Packets", &t.packets)
+	t.expvars.Set("Bytes", &t.bytes)
+
+	return t
+}
+
+func (t *listenerTelemetry) onReadSuccess(n int) {
+	t.packets.Add(1)
+	t.tlmPackets.Inc("ok")
+	t.bytes.Add(int64(n))
+	t.tlmPacketsBytes.Add(float64(n))
+}
+
+func (t *listenerTelemetry) onReadError() {
+	t.packets.Add(1)
+	t.packetReadingErrors.Add(1)
+	t.tlmPackets.Inc("error")
+}
@@ -23,11 +23,19 @@ type Assembler struct {
 	flushTimer              *time.Ticker
 	closeChannel            chan struct{}
 	packetSourceType        SourceType
+	packetTags              []string
 	sync.Mutex
 }
 
 // NewAssembler creates a new Assembler instance using the specified flush duration, buffer and pool manager
 func NewAssembler(flushTimer time.Duration, packetsBuffer *Buffer, sharedPacketPoolManager *PoolManager, packetSourceType SourceType) *Assembler {
+	return NewAssemblerWithTags(flushTimer, packetsBuffer, sharedPacketPoolManager, packetSourceType, nil)
+}
+
+// NewAssemblerWithTags creates a new Assembler instance that attaches the given tags to
+// every packet it assembles. It is used by the listeners handling a single origin per
+// connection, the tags slice must not be modified afterwards as it is shared by the packets.
+func NewAssemblerWithTags(flushTimer time.Duration, packetsBuffer *Buffer, sharedPacketPoolManager *PoolManager, packetSourceType SourceType, packetTags []string) *Assembler {
 	packetAssembler := &Assembler{
 		// retrieve an available packet from the packet pool,
 		// which will be pushed back by the server when processed.
@@ -36,6 +44,7 @@ func NewAssembler(flushTimer time.Duration, packetsBuffer *Buffer, sharedPacketP
 		packetsBuffer:           packetsBuffer,
 		flushTimer:              time.NewTicker(flushTimer),
 		packetSourceType:        packetSourceType,
+		packetTags:              packetTags,
 		closeChannel:            make(chan struct{}),
 	}
 	go packetAssembler.flushLoop()
@@ -77,6 +86,7 @@ func (p *Assembler) flush() {
 	}
 	p.packet.Contents = p.packet.Buffer[:p.packetLength]
 	p.packet.Source = p.packetSourceType
+	p.packet.Tags = p.packetTags
 	p.packetsBuffer.Append(p.packet)
 	// retrieve an available packet from the packet pool,
 	// which will be pushed back by the server when processed.
@@ -84,6 +94,14 @@ func (p *Assembler) flush() {
 	p.packetLength = 0
 }
 
+// Flush forwards the messages waiting in the packet assembler to the
+// packets buffer without waiting for the flush timer.
+func (p *Assembler) Flush() {
+	p.Lock()
+	p.flush()
+	p.Unlock()
+}
+
 // Close closes the packet assembler
 func (p *Assembler) Close() {
 	p.Lock()
@@ -65,6 +65,9 @@ func (p *Pool) Put(x interface{}) {
 	if ok && packet.Origin != NoOrigin {
 		packet.Origin = NoOrigin
 	}
+	if ok && packet.Tags != nil {
+		packet.Tags = nil
+	}
 	if p.tlmEnabled {
 		tlmPoolPut.Inc()
 		tlmPool.Dec()
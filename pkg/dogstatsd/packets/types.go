@@ -15,6 +15,8 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener
+	TCP
 )
 
 // Packet represents a statsd packet ready to process,
@@ -28,6 +30,7 @@ type Packet struct {
 	Buffer   []byte     // Underlying buffer for data read
 	Origin   string     // Origin container if identified
 	Source   SourceType // Type of listener that produced the packet
+	Tags     []string   // Tags added by the listener to every message of the packet
 }
 
 // Packets is a slice of packet pointers
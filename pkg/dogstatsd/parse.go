@@ -142,6 +142,7 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 	sampleRate := 1.0
 	var tags []string
 	var containerID []byte
+	var timestamp int64
 	var optionalField []byte
 	for message != nil {
 		optionalField, message = nextField(message)
@@ -153,6 +154,11 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 			if err != nil {
 				return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd sample rate %q", optionalField)
 			}
+		case bytes.HasPrefix(optionalField, timestampFieldPrefix):
+			timestamp, err = parseMetricSampleTimestamp(optionalField[1:])
+			if err != nil {
+				return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd timestamp %q", optionalField)
+			}
 		case p.dsdOriginEnabled && bytes.HasPrefix(optionalField, containerIDFieldPrefix):
 			containerID = p.extractContainerID(optionalField)
 		}
@@ -167,6 +173,7 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 		sampleRate:  sampleRate,
 		tags:        tags,
 		containerID: containerID,
+		timestamp:   timestamp,
 	}, nil
 }
 
@@ -31,6 +31,7 @@ var (
 
 	tagsFieldPrefix       = []byte("#")
 	sampleRateFieldPrefix = []byte("@")
+	timestampFieldPrefix  = []byte("T")
 )
 
 type dogstatsdMetricSample struct {
@@ -46,6 +47,9 @@ type dogstatsdMetricSample struct {
 	tags       []string
 	// containerID represents the container ID of the sender (optional).
 	containerID []byte
+	// timestamp is the unix timestamp (in seconds) provided by the client (optional),
+	// 0 if the sample has to be bucketed at its arrival time.
+	timestamp int64
 }
 
 // sanity checks a given message against the metric sample format
@@ -53,8 +57,10 @@ func hasMetricSampleFormat(message []byte) bool {
 	if message == nil {
 		return false
 	}
+	// the metric type is mandatory, the sample rate, tags, container ID and
+	// timestamp fields are optional.
 	separatorCount := bytes.Count(message, fieldSeparator)
-	if separatorCount < 1 || separatorCount > 3 {
+	if separatorCount < 1 || separatorCount > 5 {
 		return false
 	}
 	return true
@@ -94,3 +100,14 @@ func parseMetricSampleMetricType(rawMetricType []byte) (metricType, error) {
 func parseMetricSampleSampleRate(rawSampleRate []byte) (float64, error) {
 	return parseFloat64(rawSampleRate)
 }
+
+func parseMetricSampleTimestamp(rawTimestamp []byte) (int64, error) {
+	timestamp, err := parseInt64(rawTimestamp)
+	if err != nil {
+		return 0, err
+	}
+	if timestamp <= 0 {
+		return 0, fmt.Errorf("invalid timestamp %d", timestamp)
+	}
+	return timestamp, nil
+}
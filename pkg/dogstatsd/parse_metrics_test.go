@@ -305,4 +305,41 @@ func TestParseMetricError(t *testing.T) {
 	// invalid sample rate
 	_, err = parseMetricSample([]byte("daemon:666|g|@abc"))
 	assert.Error(t, err)
+
+	// invalid timestamp
+	_, err = parseMetricSample([]byte("daemon:666|g|Tabc"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T-1657100430"))
+	assert.Error(t, err)
+
+	_, err = parseMetricSample([]byte("daemon:666|g|T"))
+	assert.Error(t, err)
+}
+
+func TestParseGaugeWithTimestamp(t *testing.T) {
+	sample, err := parseMetricSample([]byte("daemon:666|g|T1657100430"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, int64(1657100430), sample.timestamp)
+}
+
+func TestParseCountWithAllFields(t *testing.T) {
+	parser := newParser(newFloat64ListPool())
+	parser.dsdOriginEnabled = true
+	sample, err := parser.parseMetricSample([]byte("daemon:666|c|@0.5|#sometag:value|c:container-id|T1657100430"))
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, "daemon", sample.name)
+	assert.InEpsilon(t, 666.0, sample.value, epsilon)
+	assert.Equal(t, countType, sample.metricType)
+	assert.InEpsilon(t, 0.5, sample.sampleRate, epsilon)
+	assert.Equal(t, []string{"sometag:value"}, sample.tags)
+	assert.Equal(t, []byte("container-id"), sample.containerID)
+	assert.Equal(t, int64(1657100430), sample.timestamp)
 }
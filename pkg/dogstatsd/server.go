@@ -43,9 +43,12 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricTimestampRejected  = expvar.Int{}
 
 	tlmProcessed = telemetry.NewCounter("dogstatsd", "processed",
 		[]string{"message_type", "state", "origin"}, "Count of service checks/events/metrics processed by dogstatsd")
+	tlmTimestampRejected = telemetry.NewCounter("dogstatsd", "metric_timestamp_rejected",
+		[]string{"reason"}, "Count of metric samples rejected because of their timestamp")
 	tlmProcessedOk    = tlmProcessed.WithValues("metrics", "ok", "")
 	tlmProcessedError = tlmProcessed.WithValues("metrics", "error", "")
 
@@ -55,6 +58,11 @@ var (
 	// different container IDs.
 	maxOriginTagsCached = 200
 
+	// maxTimestampFutureSkew is how far in the future the timestamp of a
+	// metric sample can be, to account for clock skew between the clients
+	// and the agent. The intake rejects the points further in the future.
+	maxTimestampFutureSkew = 10 * time.Minute
+
 	tlmChannel            = telemetry.NewHistogramNoOp()
 	defaultChannelBuckets = []float64{100, 250, 500, 1000, 10000}
 	once                  sync.Once
@@ -68,6 +76,7 @@ func init() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricTimestampRejected", &dogstatsdMetricTimestampRejected)
 }
 
 // used in debug mode to add the origin on the processed metric as a tag
@@ -151,6 +160,9 @@ type Server struct {
 	eolTerminationUDS         bool
 	eolTerminationNamedPipe   bool
 	entityIDPrecedenceEnabled bool
+	// timestampMaxAge is the maximum age of the timestamp sent by the
+	// clients with a metric sample.
+	timestampMaxAge time.Duration
 	// disableVerboseLogs is a feature flag to disable the logs capable
 	// of flooding the logger output (e.g. parsing messages error).
 	// NOTE(remy): this should probably be dropped and use a throttler logger, see
@@ -174,6 +186,8 @@ type metricStat struct {
 	Count    uint64    `json:"count"`
 	LastSeen time.Time `json:"last_seen"`
 	Tags     string    `json:"tags"`
+	// ContextLimit is set when the metric reached its contexts limit in the aggregator.
+	ContextLimit *aggregator.ContextLimitStats `json:"context_limit,omitempty"`
 }
 
 type dsdServerDebug struct {
@@ -251,6 +265,14 @@ func NewServer(demultiplexer aggregator.Demultiplexer, serverless bool) (*Server
 			tmpListeners = append(tmpListeners, udpListener)
 		}
 	}
+	if config.Datadog.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, capture)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
 
 	pipeName := config.Datadog.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
@@ -263,7 +285,7 @@ func NewServer(demultiplexer aggregator.Demultiplexer, serverless bool) (*Server
 	}
 
 	if len(tmpListeners) == 0 {
-		return nil, fmt.Errorf("listening on neither udp nor socket, please check your configuration")
+		return nil, fmt.Errorf("listening on neither udp, tcp nor socket, please check your configuration")
 	}
 
 	// check configuration for custom namespace
@@ -334,6 +356,7 @@ func NewServer(demultiplexer aggregator.Demultiplexer, serverless bool) (*Server
 		eolTerminationUDS:         eolTerminationUDS,
 		eolTerminationNamedPipe:   eolTerminationNamedPipe,
 		entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
+		timestampMaxAge:           config.Datadog.GetDuration("dogstatsd_timestamp_max_age"),
 		disableVerboseLogs:        config.Datadog.GetBool("dogstatsd_disable_verbose_logs"),
 		Debug: &dsdServerDebug{
 			Stats: make(map[ckey.ContextKey]metricStat),
@@ -543,6 +566,7 @@ func (s *Server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 					s.errLog("Dogstatsd: error parsing service check '%q': %s", message, err)
 					continue
 				}
+				serviceCheck.Tags = append(serviceCheck.Tags, packet.Tags...)
 				batcher.appendServiceCheck(serviceCheck)
 			case eventType:
 				event, err := s.parseEventMessage(parser, message, packet.Origin)
@@ -550,6 +574,7 @@ func (s *Server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 					s.errLog("Dogstatsd: error parsing event '%q': %s", message, err)
 					continue
 				}
+				event.Tags = append(event.Tags, packet.Tags...)
 				batcher.appendEvent(event)
 			case metricSampleType:
 				var err error
@@ -563,6 +588,8 @@ func (s *Server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 					continue
 				}
 
+				appendPacketTags(samples, packet.Tags)
+
 				for idx := range samples {
 					if debugEnabled {
 						s.storeMetricStats(samples[idx])
@@ -583,6 +610,22 @@ func (s *Server) parsePackets(batcher *batcher, parser *parser, packets []*packe
 	return samples
 }
 
+// appendPacketTags adds the tags attached to a packet by its listener to the
+// samples parsed from one of its messages.
+func appendPacketTags(samples []metrics.MetricSample, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	for idx := range samples {
+		// All samples share the same Tags slice, see parseMetricMessage.
+		if idx == 0 {
+			samples[idx].Tags = append(samples[idx].Tags, tags...)
+		} else {
+			samples[idx].Tags = samples[0].Tags
+		}
+	}
+}
+
 func (s *Server) errLog(format string, params ...interface{}) {
 	if s.disableVerboseLogs {
 		log.Debugf(format, params...)
@@ -642,6 +685,18 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
+	if sample.timestamp > 0 {
+		if reason, err := s.checkMetricTimestamp(sample, time.Now()); err != nil {
+			if len(sample.values) > 0 {
+				s.sharedFloat64List.put(sample.values)
+			}
+			dogstatsdMetricTimestampRejected.Add(1)
+			tlmTimestampRejected.Inc(reason)
+			errorCnt.Inc()
+			return metricSamples, err
+		}
+	}
+
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
@@ -670,6 +725,24 @@ func (s *Server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	return metricSamples, nil
 }
 
+// checkMetricTimestamp validates the timestamp sent with a metric sample. Only
+// gauges and counts can be sent with a timestamp, which must be in the
+// [now - dogstatsd_timestamp_max_age, now + maxTimestampFutureSkew] window.
+// If the timestamp is rejected, the reason is returned for the telemetry.
+func (s *Server) checkMetricTimestamp(sample dogstatsdMetricSample, now time.Time) (string, error) {
+	if sample.metricType != gaugeType && sample.metricType != countType {
+		return "unsupported_type", fmt.Errorf("timestamps are only supported for gauges and counts")
+	}
+	timestamp := time.Unix(sample.timestamp, 0)
+	if s.timestampMaxAge > 0 && timestamp.Before(now.Add(-s.timestampMaxAge)) {
+		return "too_old", fmt.Errorf("timestamp %d is older than %s", sample.timestamp, s.timestampMaxAge)
+	}
+	if timestamp.After(now.Add(maxTimestampFutureSkew)) {
+		return "in_future", fmt.Errorf("timestamp %d is more than %s in the future", sample.timestamp, maxTimestampFutureSkew)
+	}
+	return "", nil
+}
+
 func (s *Server) parseEventMessage(parser *parser, message []byte, origin string) (*metrics.Event, error) {
 	sample, err := parser.parseEvent(message)
 	if err != nil {
@@ -825,7 +898,22 @@ func (s *Server) DisableMetricsStats() {
 func (s *Server) GetJSONDebugStats() ([]byte, error) {
 	s.Debug.Lock()
 	defer s.Debug.Unlock()
-	return json.Marshal(s.Debug.Stats)
+
+	limits := aggregator.GetDogstatsdContextLimitStats()
+	if len(limits) == 0 {
+		return json.Marshal(s.Debug.Stats)
+	}
+
+	limitsByName := make(map[string]*aggregator.ContextLimitStats, len(limits))
+	for i := range limits {
+		limitsByName[limits[i].Name] = &limits[i]
+	}
+	stats := make(map[ckey.ContextKey]metricStat, len(s.Debug.Stats))
+	for key, stat := range s.Debug.Stats {
+		stat.ContextLimit = limitsByName[stat.Name]
+		stats[key] = stat
+	}
+	return json.Marshal(stats)
 }
 
 // FormatDebugStats returns a printable version of debug stats.
@@ -863,9 +951,39 @@ func FormatDebugStats(stats []byte) (string, error) {
 		buf.Write([]byte("No metrics processed yet."))
 	}
 
+	formatContextLimits(buf, dogStats)
+
 	return buf.String(), nil
 }
 
+// formatContextLimits writes the metrics that reached their contexts limit.
+func formatContextLimits(buf *bytes.Buffer, dogStats map[uint64]metricStat) {
+	limitsByName := make(map[string]*aggregator.ContextLimitStats)
+	for _, stats := range dogStats {
+		if stats.ContextLimit != nil {
+			limitsByName[stats.Name] = stats.ContextLimit
+		}
+	}
+	if len(limitsByName) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(limitsByName))
+	for name := range limitsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.Write([]byte("\nMetrics over their contexts limit:\n"))
+	header := fmt.Sprintf("%-40s | %-10s | %-17s | %-10s | %-10s\n", "Metric", "Contexts", "Overflow Contexts", "Dropped", "Collapsed")
+	buf.Write([]byte(header))
+	buf.Write([]byte(strings.Repeat("-", len(header)) + "\n"))
+	for _, name := range names {
+		limit := limitsByName[name]
+		buf.Write([]byte(fmt.Sprintf("%-40s | %-10d | %-17d | %-10d | %-10d\n", name, limit.Contexts, limit.OverflowContexts, limit.Dropped, limit.Collapsed)))
+	}
+}
+
 // SetExtraTags sets extra tags. All metrics sent to the DogstatsD will be tagged with them.
 func (s *Server) SetExtraTags(tags []string) {
 	s.extraTags = tags
@@ -24,6 +24,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/testutil"
 )
 
 // getAvailableUDPPort requests a random port number and makes sure it is available
@@ -306,6 +307,51 @@ func TestUDPReceive(t *testing.T) {
 	}
 }
 
+func TestTCPReceive(t *testing.T) {
+	port, err := getAvailableUDPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_port", port)
+	tcpPort, err := testutil.GetAvailableTCPPort()
+	require.NoError(t, err)
+	config.Datadog.SetDefault("dogstatsd_tcp_port", tcpPort)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_port", 0)
+	config.Datadog.SetDefault("dogstatsd_tcp_origin_tagging", true)
+	defer config.Datadog.SetDefault("dogstatsd_tcp_origin_tagging", false)
+
+	demux := aggregator.InitTestAgentDemultiplexerWithFlushInterval(10 * time.Millisecond)
+	defer demux.Stop(false)
+	s, err := NewServer(demux, false)
+	require.NoError(t, err, "cannot start DSD")
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", tcpPort))
+	require.NoError(t, err, "cannot connect to DSD TCP port")
+	defer conn.Close()
+
+	// multi-value packet, the samples share the same tags slice
+	conn.Write([]byte("daemon1:666:123|c|#sometag1:somevalue1\ndaemon2:1000|g\n"))
+	samples := demux.WaitForSamples(time.Second * 2)
+	require.Equal(t, 3, len(samples))
+	assert.Equal(t, "daemon1", samples[0].Name)
+	assert.ElementsMatch(t, []string{"sometag1:somevalue1", "client_ip:127.0.0.1"}, samples[0].Tags)
+	assert.Equal(t, "daemon1", samples[1].Name)
+	assert.ElementsMatch(t, []string{"sometag1:somevalue1", "client_ip:127.0.0.1"}, samples[1].Tags)
+	assert.Equal(t, "daemon2", samples[2].Name)
+	assert.ElementsMatch(t, []string{"client_ip:127.0.0.1"}, samples[2].Tags)
+	demux.Reset()
+
+	// Test Event
+	conn.Write([]byte("_e{10,10}:test title|test\\ntext|t:warning\n"))
+	eventOut, _ := demux.GetEventsAndServiceChecksChannels()
+	select {
+	case res := <-eventOut:
+		require.Len(t, res, 1)
+		assert.ElementsMatch(t, []string{"client_ip:127.0.0.1"}, res[0].Tags)
+	case <-time.After(2 * time.Second):
+		assert.FailNow(t, "Timeout on receive channel")
+	}
+}
+
 func TestUDPForward(t *testing.T) {
 	fport, err := getAvailableUDPPort()
 	require.NoError(t, err)
@@ -670,6 +716,29 @@ func TestDebugStats(t *testing.T) {
 	require.Equal(t, hash4, hash5)
 }
 
+func TestFormatDebugStatsContextLimits(t *testing.T) {
+	stats := map[uint64]metricStat{
+		1: {Name: "some.metric1", Count: 2, Tags: "request_id:1", ContextLimit: &aggregator.ContextLimitStats{Name: "some.metric1", Contexts: 10, Dropped: 5}},
+		2: {Name: "some.metric1", Count: 1, Tags: "request_id:2", ContextLimit: &aggregator.ContextLimitStats{Name: "some.metric1", Contexts: 10, Dropped: 5}},
+		3: {Name: "some.metric2", Count: 1},
+	}
+	data, err := json.Marshal(stats)
+	require.NoError(t, err)
+
+	formatted, err := FormatDebugStats(data)
+	require.NoError(t, err)
+	assert.Contains(t, formatted, "Metrics over their contexts limit:")
+	assert.Equal(t, 1, strings.Count(formatted, "| 10         | 0                 | 5          | 0"))
+	assert.NotContains(t, formatted[strings.Index(formatted, "Metrics over their contexts limit:"):], "some.metric2")
+
+	// no section if no metric is limited
+	data, err = json.Marshal(map[uint64]metricStat{3: stats[3]})
+	require.NoError(t, err)
+	formatted, err = FormatDebugStats(data)
+	require.NoError(t, err)
+	assert.NotContains(t, formatted, "Metrics over their contexts limit:")
+}
+
 func TestNoMappingsConfig(t *testing.T) {
 	datadogYaml := ``
 	samples := []metrics.MetricSample{}
@@ -974,3 +1043,54 @@ func TestContainerIDParsing(t *testing.T) {
 	assert.NotNil(serviceCheck)
 	assert.Equal("container_id://service-check-container", serviceCheck.OriginFromClient)
 }
+
+func TestTimestampParsing(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := NewServer(mockDemultiplexer(), false)
+	assert.NoError(err, "starting the DogStatsD server shouldn't fail")
+	s.Stop()
+
+	parser := newParser(newFloat64ListPool())
+	now := time.Now().Unix()
+
+	// gauges and counts are sent as is at their timestamp
+	samples, err := s.parseMetricMessage(nil, parser, []byte(fmt.Sprintf("metric.name:123|g|T%d", now-60)), "", false)
+	assert.NoError(err)
+	require.Len(t, samples, 1)
+	assert.Equal(float64(now-60), samples[0].Timestamp)
+	assert.True(samples[0].NoAggregation)
+
+	samples, err = s.parseMetricMessage(nil, parser, []byte(fmt.Sprintf("metric.name:1:2|c|T%d", now)), "", false)
+	assert.NoError(err)
+	require.Len(t, samples, 2)
+	for _, sample := range samples {
+		assert.Equal(float64(now), sample.Timestamp)
+		assert.True(sample.NoAggregation)
+	}
+
+	// samples without timestamp are aggregated
+	samples, err = s.parseMetricMessage(nil, parser, []byte("metric.name:123|g"), "", false)
+	assert.NoError(err)
+	require.Len(t, samples, 1)
+	assert.Equal(0.0, samples[0].Timestamp)
+	assert.False(samples[0].NoAggregation)
+
+	// rejected samples
+	for _, message := range []string{
+		fmt.Sprintf("metric.name:123|h|T%d", now),
+		fmt.Sprintf("metric.name:123|s|T%d", now),
+		fmt.Sprintf("metric.name:123|g|T%d", now-int64((2*time.Hour).Seconds())),
+		fmt.Sprintf("metric.name:123|g|T%d", now+int64(time.Hour.Seconds())),
+	} {
+		samples, err = s.parseMetricMessage(nil, parser, []byte(message), "", false)
+		assert.Error(err, message)
+		assert.Len(samples, 0, message)
+	}
+
+	// the max age check can be disabled
+	s.timestampMaxAge = 0
+	samples, err = s.parseMetricMessage(nil, parser, []byte(fmt.Sprintf("metric.name:123|g|T%d", now-int64((2*time.Hour).Seconds()))), "", false)
+	assert.NoError(err)
+	assert.Len(samples, 1)
+}
@@ -7,97 +7,58 @@ package retry
 
 import (
 	"expvar"
-	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 )
 
-type counterExpvar struct {
-	counter telemetry.Counter
-	expvar  expvar.Int
-}
-
-func newCounterExpvar(subsystem string, name string, tags []string, help string, parent *expvar.Map) *counterExpvar {
-	c := &counterExpvar{
-		counter: telemetry.NewCounter(subsystem, name, tags, help),
-	}
-	expvarName := toCamelCase(name)
-	parent.Set(expvarName, &c.expvar)
-	return c
-}
-
-func (c *counterExpvar) add(v float64, tagsValue ...string) {
-	c.counter.Add(v, tagsValue...)
-	c.expvar.Add(int64(v))
-}
-
-type gaugeExpvar struct {
-	gauge  telemetry.Gauge
-	expvar expvar.Int
-}
-
-func newGaugeExpvar(subsystem string, name string, tags []string, help string, parent *expvar.Map) *gaugeExpvar {
-	g := &gaugeExpvar{
-		gauge: telemetry.NewGauge(subsystem, name, tags, help),
-	}
-	expvarName := toCamelCase(name)
-	parent.Set(expvarName, &g.expvar)
-	return g
-}
-
-func (g *gaugeExpvar) set(v float64, tagsValue ...string) {
-	g.gauge.Set(v, tagsValue...)
-	g.expvar.Set(int64(v))
-}
-
 var (
 	removalPolicyExpvar                  = expvar.Map{}
-	newRemovalPolicyCountTelemetry       *gaugeExpvar
-	registeredDomainCountTelemetry       *gaugeExpvar
-	outdatedFilesCountTelemetry          *gaugeExpvar
-	filesFromUnknownDomainCountTelemetry *gaugeExpvar
+	newRemovalPolicyCountTelemetry       *telemetry.GaugeExpvar
+	registeredDomainCountTelemetry       *telemetry.GaugeExpvar
+	outdatedFilesCountTelemetry          *telemetry.GaugeExpvar
+	filesFromUnknownDomainCountTelemetry *telemetry.GaugeExpvar
 
 	transactionContainerExpvar        = expvar.Map{}
-	currentMemSizeInBytesTelemetry    *gaugeExpvar
-	transactionsCountTelemetry        *gaugeExpvar
-	transactionsDroppedCountTelemetry *counterExpvar
-	errorsCountTelemetry              *counterExpvar
+	currentMemSizeInBytesTelemetry    *telemetry.GaugeExpvar
+	transactionsCountTelemetry        *telemetry.GaugeExpvar
+	transactionsDroppedCountTelemetry *telemetry.CounterExpvar
+	errorsCountTelemetry              *telemetry.CounterExpvar
 
 	fileStorageExpvar                       = expvar.Map{}
-	serializeCountTelemetry                 *counterExpvar
-	deserializeCountTelemetry               *counterExpvar
-	fileSizeTelemetry                       *gaugeExpvar
-	currentSizeInBytesTelemetry             *gaugeExpvar
-	filesCountTelemetry                     *gaugeExpvar
-	startupReloadedRetryFilesCountTelemetry *gaugeExpvar
-	filesRemovedCountTelemetry              *counterExpvar
-	deserializeErrorsCountTelemetry         *counterExpvar
-	deserializeTransactionsCountTelemetry   *counterExpvar
+	serializeCountTelemetry                 *telemetry.CounterExpvar
+	deserializeCountTelemetry               *telemetry.CounterExpvar
+	fileSizeTelemetry                       *telemetry.GaugeExpvar
+	currentSizeInBytesTelemetry             *telemetry.GaugeExpvar
+	filesCountTelemetry                     *telemetry.GaugeExpvar
+	startupReloadedRetryFilesCountTelemetry *telemetry.GaugeExpvar
+	filesRemovedCountTelemetry              *telemetry.CounterExpvar
+	deserializeErrorsCountTelemetry         *telemetry.CounterExpvar
+	deserializeTransactionsCountTelemetry   *telemetry.CounterExpvar
 )
 
 func init() {
 	transaction.ForwarderExpvars.Set("RemovalPolicy", &removalPolicyExpvar)
 	domainTag := []string{"domain"}
-	newRemovalPolicyCountTelemetry = newGaugeExpvar(
+	newRemovalPolicyCountTelemetry = telemetry.NewGaugeExpvar(
 		"startup_removal_policy",
 		"new_removal_policy_count",
 		nil,
 		"The number of times FileRemovalPolicy is created",
 		&removalPolicyExpvar)
-	registeredDomainCountTelemetry = newGaugeExpvar(
+	registeredDomainCountTelemetry = telemetry.NewGaugeExpvar(
 		"startup_removal_policy",
 		"registered_domain_count",
 		domainTag,
 		"The number of domains registered by FileRemovalPolicy",
 		&removalPolicyExpvar)
-	outdatedFilesCountTelemetry = newGaugeExpvar(
+	outdatedFilesCountTelemetry = telemetry.NewGaugeExpvar(
 		"startup_removal_policy",
 		"outdated_files_count",
 		nil,
 		"The number of outdated files removed",
 		&removalPolicyExpvar)
-	filesFromUnknownDomainCountTelemetry = newGaugeExpvar(
+	filesFromUnknownDomainCountTelemetry = telemetry.NewGaugeExpvar(
 		"startup_removal_policy",
 		"files_from_unknown_domain_count",
 		nil,
@@ -105,25 +66,25 @@ func init() {
 		&removalPolicyExpvar)
 
 	transaction.ForwarderExpvars.Set("TransactionContainer", &transactionContainerExpvar)
-	currentMemSizeInBytesTelemetry = newGaugeExpvar(
+	currentMemSizeInBytesTelemetry = telemetry.NewGaugeExpvar(
 		"transaction_container",
 		"current_mem_size_in_bytes",
 		domainTag,
 		"The retry queue size",
 		&transactionContainerExpvar)
-	transactionsCountTelemetry = newGaugeExpvar(
+	transactionsCountTelemetry = telemetry.NewGaugeExpvar(
 		"transaction_container",
 		"transactions_count",
 		domainTag,
 		"The number of transactions in the retry queue",
 		&transactionContainerExpvar)
-	transactionsDroppedCountTelemetry = newCounterExpvar(
+	transactionsDroppedCountTelemetry = telemetry.NewCounterExpvar(
 		"transaction_container",
 		"transactions_dropped_count",
 		domainTag,
 		"The number of transactions dropped because the retry queue is full",
 		&transactionContainerExpvar)
-	errorsCountTelemetry = newCounterExpvar(
+	errorsCountTelemetry = telemetry.NewCounterExpvar(
 		"transaction_container",
 		"errors_count",
 		domainTag,
@@ -131,55 +92,55 @@ func init() {
 		&transactionContainerExpvar)
 
 	transaction.ForwarderExpvars.Set("FileStorage", &fileStorageExpvar)
-	serializeCountTelemetry = newCounterExpvar(
+	serializeCountTelemetry = telemetry.NewCounterExpvar(
 		"file_storage",
 		"serialize_count",
 		domainTag,
 		"The number of times `transactionsFileStorage.Serialize` is called",
 		&fileStorageExpvar)
-	deserializeCountTelemetry = newCounterExpvar(
+	deserializeCountTelemetry = telemetry.NewCounterExpvar(
 		"file_storage",
 		"deserialize_count",
 		domainTag,
 		"The number of times `transactionsFileStorage.Deserialize` is called",
 		&fileStorageExpvar)
-	fileSizeTelemetry = newGaugeExpvar(
+	fileSizeTelemetry = telemetry.NewGaugeExpvar(
 		"file_storage",
 		"file_size",
 		domainTag,
 		"The last file size stored on the disk",
 		&fileStorageExpvar)
-	currentSizeInBytesTelemetry = newGaugeExpvar(
+	currentSizeInBytesTelemetry = telemetry.NewGaugeExpvar(
 		"file_storage",
 		"current_size_in_bytes",
 		domainTag,
 		"The number of bytes used to store transactions on the disk",
 		&fileStorageExpvar)
-	filesCountTelemetry = newGaugeExpvar(
+	filesCountTelemetry = telemetry.NewGaugeExpvar(
 		"file_storage",
 		"files_count",
 		domainTag,
 		"The number of files",
 		&fileStorageExpvar)
-	startupReloadedRetryFilesCountTelemetry = newGaugeExpvar(
+	startupReloadedRetryFilesCountTelemetry = telemetry.NewGaugeExpvar(
 		"file_storage",
 		"startup_reloaded_retry_files_count",
 		domainTag,
 		"The number of files reloaded from a previous run of the Agent",
 		&fileStorageExpvar)
-	filesRemovedCountTelemetry = newCounterExpvar(
+	filesRemovedCountTelemetry = telemetry.NewCounterExpvar(
 		"file_storage",
 		"files_removed_count",
 		domainTag,
 		"The number of files removed because the disk limit was reached",
 		&fileStorageExpvar)
-	deserializeErrorsCountTelemetry = newCounterExpvar(
+	deserializeErrorsCountTelemetry = telemetry.NewCounterExpvar(
 		"file_storage",
 		"deserialize_errors_count",
 		domainTag,
 		"The number of errors during deserialization",
 		&fileStorageExpvar)
-	deserializeTransactionsCountTelemetry = newCounterExpvar(
+	deserializeTransactionsCountTelemetry = telemetry.NewCounterExpvar(
 		"file_storage",
 		"deserialize_transactions_count",
 		domainTag,
@@ -191,18 +152,18 @@ func init() {
 type FileRemovalPolicyTelemetry struct{}
 
 func (FileRemovalPolicyTelemetry) setNewRemovalPolicyCount(count int) {
-	newRemovalPolicyCountTelemetry.set(float64(count))
+	newRemovalPolicyCountTelemetry.Set(float64(count))
 }
 
 func (FileRemovalPolicyTelemetry) setRegisteredDomainCount(count int, domainName string) {
-	registeredDomainCountTelemetry.set(float64(count), domainName)
+	registeredDomainCountTelemetry.Set(float64(count), domainName)
 }
 func (FileRemovalPolicyTelemetry) setOutdatedFilesCount(count int) {
-	outdatedFilesCountTelemetry.set(float64(count))
+	outdatedFilesCountTelemetry.Set(float64(count))
 }
 
 func (FileRemovalPolicyTelemetry) setFilesFromUnknownDomainCount(count int) {
-	filesFromUnknownDomainCountTelemetry.set(float64(count))
+	filesFromUnknownDomainCountTelemetry.Set(float64(count))
 }
 
 // TransactionRetryQueueTelemetry handles the telemetry for TransactionRetryQueue
@@ -218,19 +179,19 @@ func NewTransactionRetryQueueTelemetry(domainName string) TransactionRetryQueueT
 }
 
 func (t TransactionRetryQueueTelemetry) setCurrentMemSizeInBytes(count int) {
-	currentMemSizeInBytesTelemetry.set(float64(count), t.domainName)
+	currentMemSizeInBytesTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t TransactionRetryQueueTelemetry) setTransactionsCount(count int) {
-	transactionsCountTelemetry.set(float64(count), t.domainName)
+	transactionsCountTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t TransactionRetryQueueTelemetry) addTransactionsDroppedCount(count int) {
-	transactionsDroppedCountTelemetry.add(float64(count), t.domainName)
+	transactionsDroppedCountTelemetry.Add(float64(count), t.domainName)
 }
 
 func (t TransactionRetryQueueTelemetry) incErrorsCount() {
-	errorsCountTelemetry.add(1, t.domainName)
+	errorsCountTelemetry.Add(1, t.domainName)
 }
 
 type onDiskRetryQueueTelemetry struct {
@@ -244,46 +205,37 @@ func newOnDiskRetryQueueTelemetry(domainName string) onDiskRetryQueueTelemetry {
 }
 
 func (t onDiskRetryQueueTelemetry) addSerializeCount() {
-	serializeCountTelemetry.add(1, t.domainName)
+	serializeCountTelemetry.Add(1, t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) addDeserializeCount() {
-	deserializeCountTelemetry.add(1, t.domainName)
+	deserializeCountTelemetry.Add(1, t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) setFileSize(count int64) {
-	fileSizeTelemetry.set(float64(count), t.domainName)
+	fileSizeTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) setCurrentSizeInBytes(count int64) {
-	currentSizeInBytesTelemetry.set(float64(count), t.domainName)
+	currentSizeInBytesTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) setFilesCount(count int) {
-	filesCountTelemetry.set(float64(count), t.domainName)
+	filesCountTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) setReloadedRetryFilesCount(count int) {
-	startupReloadedRetryFilesCountTelemetry.set(float64(count), t.domainName)
+	startupReloadedRetryFilesCountTelemetry.Set(float64(count), t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) addFilesRemovedCount() {
-	filesRemovedCountTelemetry.add(1, t.domainName)
+	filesRemovedCountTelemetry.Add(1, t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) addDeserializeErrorsCount(count int) {
-	deserializeErrorsCountTelemetry.add(float64(count), t.domainName)
+	deserializeErrorsCountTelemetry.Add(float64(count), t.domainName)
 }
 
 func (t onDiskRetryQueueTelemetry) addDeserializeTransactionsCount(count int) {
-	deserializeTransactionsCountTelemetry.add(float64(count), t.domainName)
-}
-
-func toCamelCase(s string) string {
-	parts := strings.Split(s, "_")
-	var camelCase string
-	for _, p := range parts {
-		camelCase += strings.Title(p)
-	}
-	return camelCase
+	deserializeTransactionsCountTelemetry.Add(float64(count), t.domainName)
 }
@@ -7,8 +7,10 @@ package logs
 
 import (
 	"context"
+	"path/filepath"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -26,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/util/containersorpods"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -51,8 +54,9 @@ type Agent struct {
 	started bool
 }
 
-// NewAgent returns a new Logs Agent
-func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, endpoints *config.Endpoints) *Agent {
+// NewAgent returns a new Logs Agent, the metrics generated from the logs are
+// sent to demux.
+func NewAgent(sources *config.LogSources, services *service.Services, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, demux aggregator.Demultiplexer) *Agent {
 	health := health.RegisterLiveness("logs-agent")
 
 	// setup the auditor
@@ -64,7 +68,7 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver()
 
 	// setup the pipeline provider that provides pairs of processor and sender
-	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx)
+	pipelineProvider := pipeline.NewProvider(config.NumberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsCtx, spoolConfig(), demux)
 
 	cop := containersorpods.NewChooser()
 
@@ -105,6 +109,23 @@ func NewAgent(sources *config.LogSources, services *service.Services, processing
 	}
 }
 
+// spoolConfig returns the configuration of the on-disk spool of the
+// pipelines, or nil if it is disabled.
+func spoolConfig() *sender.SpoolConfig {
+	if !coreConfig.Datadog.GetBool("logs_config.spool.enabled") {
+		return nil
+	}
+	path := coreConfig.Datadog.GetString("logs_config.spool.path")
+	if path == "" {
+		path = filepath.Join(coreConfig.Datadog.GetString("logs_config.run_path"), "spool")
+	}
+	return &sender.SpoolConfig{
+		Path:           path,
+		MaxSizeInBytes: coreConfig.Datadog.GetInt64("logs_config.spool.max_size"),
+		MaxAge:         coreConfig.Datadog.GetDuration("logs_config.spool.max_age"),
+	}
+}
+
 // NewServerless returns a Logs Agent instance to run in a serverless environment.
 // The Serverless Logs Agent has only one input being the channel to receive the logs to process.
 // It is using a NullAuditor because we've nothing to do after having sent the logs to the intake.
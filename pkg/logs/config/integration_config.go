@@ -30,6 +30,9 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// SyslogFormat for syslog messages (RFC 5424 or RFC 3164)
+	SyslogFormat string = "syslog"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -39,6 +42,7 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
+	Format      string `mapstructure:"format" json:"format"`             // Network
 	Path        string // File, Journald
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
@@ -144,6 +148,8 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case (c.Type == TCPType || c.Type == UDPType) && c.Format != "" && c.Format != SyslogFormat:
+		return fmt.Errorf("invalid format '%v' for %v source", c.Format, c.Type)
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -16,8 +16,35 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	// ParseStructured parses the log lines as JSON objects or logfmt
+	// key/value pairs to drop, mask or rename some of their attributes and to
+	// promote some of them to the status and the tags of the logs.
+	ParseStructured = "parse_structured"
+	// GenerateMetric generates a metric sample from each log line matching the
+	// rule, the line can then be dropped.
+	GenerateMetric = "generate_metric"
 )
 
+// Metric types supported by the GenerateMetric processing rules
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// MetricValueGroup is the name of the capturing group of the pattern of the
+// GenerateMetric processing rules holding the value of the metric.
+const MetricValueGroup = "value"
+
+// Formats supported by the ParseStructured processing rules
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// DefaultMaskPlaceholder replaces the masked attributes of the ParseStructured
+// processing rules when no placeholder is set.
+const DefaultMaskPlaceholder = "[masked]"
+
 // ProcessingRule defines an exclusion or a masking rule to
 // be applied on log lines
 type ProcessingRule struct {
@@ -25,6 +52,24 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Settings of the ParseStructured rules, the attributes are referenced by
+	// their path, the keys of the nested JSON objects being separated by dots.
+	Format           string
+	DropAttributes   []string          `mapstructure:"drop_attributes" json:"drop_attributes"`
+	MaskAttributes   []string          `mapstructure:"mask_attributes" json:"mask_attributes"`
+	RenameAttributes map[string]string `mapstructure:"rename_attributes" json:"rename_attributes"`
+	StatusAttribute  string            `mapstructure:"status_attribute" json:"status_attribute"`
+	TagAttributes    []string          `mapstructure:"tag_attributes" json:"tag_attributes"`
+	// Settings of the GenerateMetric rules, the pattern is optional and the
+	// Format, TagAttributes and ValueAttribute settings are used to read the
+	// tags and the value of the metric from the parsed log lines.
+	MetricName     string   `mapstructure:"metric_name" json:"metric_name"`
+	MetricType     string   `mapstructure:"metric_type" json:"metric_type"`
+	MetricTags     []string `mapstructure:"metric_tags" json:"metric_tags"`
+	ValueAttribute string   `mapstructure:"value_attribute" json:"value_attribute"`
+	MatchSource    string   `mapstructure:"match_source" json:"match_source"`
+	MatchService   string   `mapstructure:"match_service" json:"match_service"`
+	DropLog        bool     `mapstructure:"drop_log" json:"drop_log"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -35,6 +80,9 @@ type ProcessingRule struct {
 // - a valid name
 // - a valid type
 // - a valid pattern that compiles
+// ParseStructured rules don't have a pattern but must have a valid format and
+// at least one attribute to process. GenerateMetric rules have an optional
+// pattern and must have a metric name.
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +92,16 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case ParseStructured:
+			if err := validateParseStructuredRule(rule); err != nil {
+				return err
+			}
+			continue
+		case GenerateMetric:
+			if err := validateGenerateMetricRule(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -61,15 +119,79 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 	return nil
 }
 
+func validateParseStructuredRule(rule *ProcessingRule) error {
+	switch rule.Format {
+	case FormatJSON, FormatLogfmt:
+		break
+	case "":
+		return fmt.Errorf("format must be set for processing rule `%s`", rule.Name)
+	default:
+		return fmt.Errorf("format %s is not supported for processing rule `%s`, supported formats are %s and %s", rule.Format, rule.Name, FormatJSON, FormatLogfmt)
+	}
+
+	if len(rule.DropAttributes) == 0 && len(rule.MaskAttributes) == 0 && len(rule.RenameAttributes) == 0 &&
+		rule.StatusAttribute == "" && len(rule.TagAttributes) == 0 {
+		return fmt.Errorf("no attribute to process provided for processing rule: %s", rule.Name)
+	}
+	return nil
+}
+
+func validateGenerateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric name provided for processing rule: %s", rule.Name)
+	}
+
+	var re *regexp.Regexp
+	if rule.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+		}
+	}
+
+	switch rule.Format {
+	case "", FormatJSON, FormatLogfmt:
+		break
+	default:
+		return fmt.Errorf("format %s is not supported for processing rule `%s`, supported formats are %s and %s", rule.Format, rule.Name, FormatJSON, FormatLogfmt)
+	}
+	if rule.Format == "" && (rule.ValueAttribute != "" || len(rule.TagAttributes) > 0) {
+		return fmt.Errorf("format must be set to read attributes for processing rule `%s`", rule.Name)
+	}
+
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+		break
+	case MetricTypeDistribution:
+		if rule.ValueAttribute == "" && (re == nil || re.SubexpIndex(MetricValueGroup) < 0) {
+			return fmt.Errorf("a value attribute or a pattern with a `%s` group must be provided for distribution processing rule: %s", MetricValueGroup, rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric type %s is not supported for processing rule `%s`, supported types are %s and %s", rule.MetricType, rule.Name, MetricTypeCount, MetricTypeDistribution)
+	}
+	return nil
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ParseStructured {
+			rule.Placeholder = []byte(rule.ReplacePlaceholder)
+			if len(rule.Placeholder) == 0 {
+				rule.Placeholder = []byte(DefaultMaskPlaceholder)
+			}
+			continue
+		}
+		if rule.Type == GenerateMetric && rule.Pattern == "" {
+			// the rule matches all the logs
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -34,3 +34,49 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParseStructuredRules(t *testing.T) {
+	valid := &ProcessingRule{Type: ParseStructured, Name: "json", Format: FormatJSON, DropAttributes: []string{"password"}}
+	assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{valid}))
+
+	invalidRules := []*ProcessingRule{
+		{Type: ParseStructured, Name: "no_format", DropAttributes: []string{"password"}},
+		{Type: ParseStructured, Name: "unknown_format", Format: "xml", DropAttributes: []string{"password"}},
+		{Type: ParseStructured, Name: "no_attribute", Format: FormatLogfmt},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestCompileParseStructuredRules(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Type: ParseStructured, Format: FormatJSON},
+		{Type: ParseStructured, Format: FormatJSON, ReplacePlaceholder: "***"},
+	}
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.Equal(t, []byte(DefaultMaskPlaceholder), rules[0].Placeholder)
+	assert.Equal(t, []byte("***"), rules[1].Placeholder)
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Type: GenerateMetric, Name: "count", MetricName: "foo"},
+		{Type: GenerateMetric, Name: "regex", MetricName: "foo", MetricType: MetricTypeDistribution, Pattern: "(?P<value>[0-9]+)"},
+		{Type: GenerateMetric, Name: "attribute", MetricName: "foo", MetricType: MetricTypeDistribution, Format: FormatJSON, ValueAttribute: "duration"},
+	}
+	for _, rule := range validRules {
+		assert.Nil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Type: GenerateMetric, Name: "no_metric_name"},
+		{Type: GenerateMetric, Name: "invalid_pattern", MetricName: "foo", Pattern: "(?=abf)"},
+		{Type: GenerateMetric, Name: "unknown_type", MetricName: "foo", MetricType: "gauge"},
+		{Type: GenerateMetric, Name: "no_value", MetricName: "foo", MetricType: MetricTypeDistribution, Pattern: "[0-9]+"},
+		{Type: GenerateMetric, Name: "no_format", MetricName: "foo", ValueAttribute: "duration"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
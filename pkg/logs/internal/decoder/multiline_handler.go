@@ -26,6 +26,7 @@ type MultiLineHandler struct {
 	linesLen       int
 	status         string
 	timestamp      string
+	tags           []string
 	countInfo      *config.CountInfo
 }
 
@@ -80,6 +81,7 @@ func (h *MultiLineHandler) process(message *Message) {
 	h.linesLen += message.RawDataLen
 	h.timestamp = message.Timestamp
 	h.status = message.Status
+	h.tags = message.Tags
 
 	if h.buffer.Len() > 0 {
 		// the buffer already contains some data which means that
@@ -128,6 +130,8 @@ func (h *MultiLineHandler) sendBuffer() {
 	copy(content, data)
 
 	if len(content) > 0 || h.linesLen > 0 {
-		h.outputFn(NewMessage(content, h.status, h.linesLen, h.timestamp))
+		output := NewMessage(content, h.status, h.linesLen, h.timestamp)
+		output.Tags = h.tags
+		h.outputFn(output)
 	}
 }
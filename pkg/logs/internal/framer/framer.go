@@ -38,6 +38,12 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog messages transported over a stream (RFC 6587), either
+	// octet-counted, with each message prefixed by its length and a space, or
+	// newline-terminated.  The result does not include the length prefixes
+	// nor the trailing newlines.
+	Syslog
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -95,6 +101,8 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case Syslog:
+		matcher = &syslogMatcher{contentLenLimit: contentLenLimit}
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import "bytes"
+
+// maxOctetCountDigits is the maximum number of digits of the length prefixing
+// an octet-counted syslog frame.
+const maxOctetCountDigits = 10
+
+// syslogMatcher implements FrameMatcher for syslog messages transported over
+// a stream (RFC 6587).  Frames starting with a digit use octet-counting, where
+// the message is prefixed by its length and a space ("MSG-LEN SP SYSLOG-MSG");
+// other frames are newline-terminated like the UTF-8 framing.
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Messages longer than this value will be split into multiple frames.
+	contentLenLimit int
+
+	// remaining is the number of bytes of an octet-counted message that
+	// still have to be returned after it has been split.
+	remaining int
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (sm *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if sm.remaining > 0 {
+		n := sm.remaining
+		if n > sm.contentLenLimit {
+			n = sm.contentLenLimit
+		}
+		if len(buf) < n {
+			return nil, 0
+		}
+		sm.remaining -= n
+		return buf[:n], n
+	}
+
+	if len(buf) > 0 && buf[0] >= '1' && buf[0] <= '9' {
+		length, headerLen, ok := parseOctetCount(buf)
+		if ok && headerLen == 0 {
+			// the length is not complete yet
+			return nil, 0
+		}
+		if ok {
+			// limit the returned message to contentLenLimit raw bytes, so
+			// that the framer never has to chop the frame itself
+			chunk := sm.contentLenLimit - headerLen
+			if length <= chunk {
+				chunk = length
+			}
+			if len(buf) < headerLen+chunk {
+				return nil, 0
+			}
+			sm.remaining = length - chunk
+			return buf[headerLen : headerLen+chunk], headerLen + chunk
+		}
+	}
+
+	nl := bytes.IndexByte(buf[seen:], '\n')
+	if nl == -1 {
+		return nil, 0
+	}
+
+	// limit the returned line to contentLenLimit bytes
+	eol := nl + seen
+	if eol > sm.contentLenLimit {
+		return buf[:sm.contentLenLimit], sm.contentLenLimit
+	}
+
+	// return the content without the newline, but count the newline in the raw
+	// length
+	return buf[:eol], eol + 1
+}
+
+// parseOctetCount parses the length prefixing an octet-counted frame.  It
+// returns the length and the size of the prefix, including the trailing space.
+// The size is 0 if more data is needed to parse the length, and ok is false
+// if buf does not start with a valid length.
+func parseOctetCount(buf []byte) (length int, headerLen int, ok bool) {
+	for i, b := range buf {
+		switch {
+		case b == ' ' && i > 0:
+			return length, i + 1, true
+		case b >= '0' && b <= '9' && i < maxOctetCountDigits:
+			length = length*10 + int(b-'0')
+		default:
+			return 0, 0, false
+		}
+	}
+	return 0, 0, true
+}
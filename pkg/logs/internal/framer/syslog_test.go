@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func syslogFramerOutput(contentLenLimit int) (*Framer, *[]string, *[]int) {
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(content []byte, rawDataLen int) {
+		gotContent = append(gotContent, string(content))
+		gotLens = append(gotLens, rawDataLen)
+	}
+	return NewFramer(outputFn, Syslog, contentLenLimit), &gotContent, &gotLens
+}
+
+func TestSyslogFraming(t *testing.T) {
+	input := "<34>1 - - - - - - foo\n21 <34>1 - - - - - - bar12 <34>baz\nqux\n<34>last\n"
+	lines := []string{"<34>1 - - - - - - foo", "<34>1 - - - - - - bar", "<34>baz\nqux\n", "<34>last"}
+	lens := []int{22, 24, 15, 9}
+
+	t.Run("one chunk", func(t *testing.T) {
+		fr, gotContent, gotLens := syslogFramerOutput(contentLenLimit)
+		fr.Process([]byte(input))
+		assert.Equal(t, lines, *gotContent)
+		assert.Equal(t, lens, *gotLens)
+	})
+
+	t.Run("one-byte chunks", func(t *testing.T) {
+		fr, gotContent, gotLens := syslogFramerOutput(contentLenLimit)
+		for i := range input {
+			fr.Process([]byte{input[i]})
+		}
+		assert.Equal(t, lines, *gotContent)
+		assert.Equal(t, lens, *gotLens)
+	})
+}
+
+func TestSyslogFramingInvalidOctetCount(t *testing.T) {
+	fr, gotContent, gotLens := syslogFramerOutput(contentLenLimit)
+	fr.Process([]byte("12a <34>foo\n123456789012 <34>bar\n"))
+	assert.Equal(t, []string{"12a <34>foo", "123456789012 <34>bar"}, *gotContent)
+	assert.Equal(t, []int{12, 21}, *gotLens)
+}
+
+func TestSyslogFramingTooLongMessage(t *testing.T) {
+	fr, gotContent, gotLens := syslogFramerOutput(10)
+	fr.Process([]byte("20 " + strings.Repeat("a", 20) + "3 foo"))
+	assert.Equal(t, []string{"aaaaaaa", "aaaaaaaaaa", "aaa", "foo"}, *gotContent)
+	assert.Equal(t, []int{10, 10, 3, 5}, *gotLens)
+}
@@ -58,6 +58,11 @@ var (
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
 	// DestinationExpVars a map of sender utilization metrics for each http destination
 	DestinationExpVars = expvar.Map{}
+	// GeneratedMetricSamples is the total number of metric samples generated from the logs per metric name
+	GeneratedMetricSamples = expvar.Map{}
+	// TlmGeneratedMetricSamples is the total number of metric samples generated from the logs per metric name
+	TlmGeneratedMetricSamples = telemetry.NewCounter("logs", "generated_metric_samples",
+		[]string{"metric_name"}, "Total number of metric samples generated from the logs per metric name")
 	// TODO: Add LogsCollected for the total number of collected logs.
 
 )
@@ -73,4 +78,5 @@ func init() {
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
+	LogsExpvars.Set("GeneratedMetricSamples", &GeneratedMetricSamples)
 }
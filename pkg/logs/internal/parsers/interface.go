@@ -24,6 +24,9 @@ type Message struct {
 	// which do not contain a timestamp (such as files) leave this set to "".
 	Timestamp string
 
+	// Tags are the tags parsed from the message, if any.
+	Tags []string
+
 	// IsPartial indicates that this is a partial message.  If the parser
 	// supports partial lines, then this is true only for the message returned
 	// from the last parsed line in a multi-line message.
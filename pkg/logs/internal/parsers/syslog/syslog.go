@@ -0,0 +1,290 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog parses syslog messages in the RFC 5424 and RFC 3164 (BSD)
+// formats.
+//
+// The severity of the message is mapped to its status, and the hostname,
+// app-name, procid, msgid and structured data are returned as tags.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Tag names of the syslog header fields.
+const (
+	HostnameTag = "syslog.hostname"
+	AppnameTag  = "syslog.appname"
+	ProcidTag   = "syslog.procid"
+	MsgidTag    = "syslog.msgid"
+)
+
+// nilValue is the value of the empty RFC 5424 header fields.
+const nilValue = "-"
+
+// severityStatuses maps the syslog severities (0 to 7) to the statuses of the logs.
+var severityStatuses = [8]string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+type syslogFormat struct {
+	// now returns the current time, used to guess the year of the RFC 3164
+	// timestamps.
+	now func() time.Time
+}
+
+// New creates a new syslog parser.
+//
+// Messages starting with a valid priority are parsed as RFC 5424 messages if
+// the priority is followed by the version 1, and as RFC 3164 messages
+// otherwise.  Messages without a valid priority are returned unchanged, with
+// an error.
+func New() parsers.Parser {
+	return &syslogFormat{
+		now: time.Now,
+	}
+}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg []byte) (parsers.Message, error) {
+	severity, rest, err := parsePriority(msg)
+	if err != nil {
+		return parsers.Message{
+			Content: msg,
+			Status:  message.StatusInfo,
+		}, err
+	}
+
+	var parsed parsers.Message
+	if bytes.HasPrefix(rest, []byte("1 ")) {
+		parsed, err = parseRFC5424(rest[2:])
+	} else {
+		parsed = p.parseRFC3164(rest)
+	}
+	if err != nil {
+		return parsers.Message{
+			Content: msg,
+			Status:  severityStatuses[severity],
+		}, err
+	}
+	parsed.Status = severityStatuses[severity]
+	return parsed, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// parsePriority parses the "<PRIVAL>" prefix of a message and returns the
+// severity and the remainder of the message.
+func parsePriority(msg []byte) (int, []byte, error) {
+	if len(msg) < 3 || msg[0] != '<' {
+		return 0, nil, errors.New("cannot parse syslog message: missing priority")
+	}
+	end := bytes.IndexByte(msg[:min(len(msg), 5)], '>')
+	if end < 2 {
+		return 0, nil, errors.New("cannot parse syslog message: invalid priority")
+	}
+	priority := 0
+	for _, b := range msg[1:end] {
+		if b < '0' || b > '9' {
+			return 0, nil, errors.New("cannot parse syslog message: invalid priority")
+		}
+		priority = priority*10 + int(b-'0')
+	}
+	// the facility goes from 0 to 23
+	if priority > 191 {
+		return 0, nil, fmt.Errorf("cannot parse syslog message: invalid priority %d", priority)
+	}
+	return priority % 8, msg[end+1:], nil
+}
+
+// parseRFC5424 parses the header following the version of a RFC 5424 message:
+// TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parseRFC5424(msg []byte) (parsers.Message, error) {
+	var fields [5]string
+	for i := range fields {
+		end := bytes.IndexByte(msg, ' ')
+		if end <= 0 {
+			return parsers.Message{}, errors.New("cannot parse syslog message: truncated RFC 5424 header")
+		}
+		fields[i] = string(msg[:end])
+		msg = msg[end+1:]
+	}
+
+	var parsed parsers.Message
+	if fields[0] != nilValue {
+		timestamp, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return parsers.Message{}, fmt.Errorf("cannot parse syslog message: invalid timestamp %q", fields[0])
+		}
+		parsed.Timestamp = timestamp.UTC().Format(config.DateFormat)
+	}
+	for i, tag := range []string{HostnameTag, AppnameTag, ProcidTag, MsgidTag} {
+		if value := fields[i+1]; value != nilValue {
+			parsed.Tags = append(parsed.Tags, tag+":"+value)
+		}
+	}
+
+	if bytes.HasPrefix(msg, []byte(nilValue)) {
+		msg = msg[len(nilValue):]
+	} else {
+		tags, rest, err := parseStructuredData(msg)
+		if err != nil {
+			return parsers.Message{}, err
+		}
+		parsed.Tags = append(parsed.Tags, tags...)
+		msg = rest
+	}
+
+	if len(msg) > 0 {
+		if msg[0] != ' ' {
+			return parsers.Message{}, errors.New("cannot parse syslog message: missing space after the structured data")
+		}
+		msg = msg[1:]
+	}
+	// the message may start with a byte order mark
+	parsed.Content = bytes.TrimPrefix(msg, []byte("\xef\xbb\xbf"))
+	return parsed, nil
+}
+
+// parseStructuredData parses the structured data elements at the beginning of
+// msg, each "[SD-ID SP PARAM-NAME="PARAM-VALUE" ...]" element giving the tags
+// "SD-ID.PARAM-NAME:PARAM-VALUE".  It returns the tags and the remainder of msg.
+func parseStructuredData(msg []byte) ([]string, []byte, error) {
+	var tags []string
+	if len(msg) == 0 || msg[0] != '[' {
+		return nil, nil, errors.New("cannot parse syslog message: invalid structured data")
+	}
+	for len(msg) > 0 && msg[0] == '[' {
+		end := bytes.IndexAny(msg, " ]")
+		if end <= 1 {
+			return nil, nil, errors.New("cannot parse syslog message: invalid structured data element")
+		}
+		id := string(msg[1:end])
+		msg = msg[end:]
+
+		for len(msg) > 0 && msg[0] == ' ' {
+			msg = msg[1:]
+			eq := bytes.IndexByte(msg, '=')
+			if eq <= 0 || len(msg) < eq+2 || msg[eq+1] != '"' {
+				return nil, nil, fmt.Errorf("cannot parse syslog message: invalid structured data parameter in %q", id)
+			}
+			name := string(msg[:eq])
+			value, rest, err := parseParamValue(msg[eq+2:])
+			if err != nil {
+				return nil, nil, err
+			}
+			tags = append(tags, id+"."+name+":"+value)
+			msg = rest
+		}
+
+		if len(msg) == 0 || msg[0] != ']' {
+			return nil, nil, fmt.Errorf("cannot parse syslog message: unterminated structured data element %q", id)
+		}
+		msg = msg[1:]
+	}
+	return tags, msg, nil
+}
+
+// parseParamValue parses a structured data parameter value, following its
+// opening quote, and returns it unescaped with the remainder of msg.
+func parseParamValue(msg []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(msg); i++ {
+		switch msg[i] {
+		case '\\':
+			// only '"', '\' and ']' are escaped, the backslash is kept otherwise
+			if i+1 < len(msg) && (msg[i+1] == '"' || msg[i+1] == '\\' || msg[i+1] == ']') {
+				i++
+			}
+		case '"':
+			return string(value), msg[i+1:], nil
+		}
+		value = append(value, msg[i])
+	}
+	return "", nil, errors.New("cannot parse syslog message: unterminated structured data parameter value")
+}
+
+// parseRFC3164 parses the header following the priority of a RFC 3164
+// message: TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+// The header is lenient as the format is loosely followed by the senders, the
+// hostname and the tag may be missing and the message is kept unchanged if the
+// timestamp is missing.
+func (p *syslogFormat) parseRFC3164(msg []byte) parsers.Message {
+	var parsed parsers.Message
+	if len(msg) < len(time.Stamp) {
+		parsed.Content = msg
+		return parsed
+	}
+	timestamp, err := time.ParseInLocation(time.Stamp, string(msg[:len(time.Stamp)]), time.Local)
+	if err != nil {
+		parsed.Content = msg
+		return parsed
+	}
+	parsed.Timestamp = p.withYear(timestamp).UTC().Format(config.DateFormat)
+	msg = bytes.TrimLeft(msg[len(time.Stamp):], " ")
+
+	// the hostname is missing if the first word is the tag
+	if end := bytes.IndexByte(msg, ' '); end > 0 && !isTag(msg[:end]) {
+		parsed.Tags = append(parsed.Tags, HostnameTag+":"+string(msg[:end]))
+		msg = msg[end+1:]
+	}
+
+	if end := bytes.IndexByte(msg, ' '); end > 0 && isTag(msg[:end]) {
+		tag := msg[:end-1]
+		if start := bytes.IndexByte(tag, '['); start > 0 && tag[len(tag)-1] == ']' {
+			parsed.Tags = append(parsed.Tags, ProcidTag+":"+string(tag[start+1:len(tag)-1]))
+			tag = tag[:start]
+		}
+		parsed.Tags = append(parsed.Tags, AppnameTag+":"+string(tag))
+		msg = msg[end+1:]
+	}
+
+	parsed.Content = msg
+	return parsed
+}
+
+// withYear sets the year of a RFC 3164 timestamp, which does not have one, to
+// the current year, or to the previous year if the timestamp would be more than
+// a day in the future.
+func (p *syslogFormat) withYear(timestamp time.Time) time.Time {
+	now := p.now()
+	timestamp = timestamp.AddDate(now.Year()-timestamp.Year(), 0, 0)
+	if timestamp.Sub(now) > 24*time.Hour {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	return timestamp
+}
+
+// isTag returns true if the word is a RFC 3164 tag, an app name optionally
+// followed by a pid between brackets and followed by a colon.
+func isTag(word []byte) bool {
+	return len(word) > 1 && word[len(word)-1] == ':'
+}
+
+// min returns the minimum value between a and b.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
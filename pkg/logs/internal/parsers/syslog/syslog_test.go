@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSyslogParserRFC5424(t *testing.T) {
+	parser := New()
+
+	msg, err := parser.Parse([]byte(`<165>1 2003-10-11T22:14:15.003-07:00 mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][origin ip="192.0.2.1"] An application event`))
+	assert.Nil(t, err)
+	assert.False(t, msg.IsPartial)
+	assert.Equal(t, []byte("An application event"), msg.Content)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "2003-10-12T05:14:15.003000000Z", msg.Timestamp)
+	assert.Equal(t, []string{
+		"syslog.hostname:mymachine.example.com",
+		"syslog.appname:evntslog",
+		"syslog.procid:1234",
+		"syslog.msgid:ID47",
+		"exampleSDID@32473.iut:3",
+		"exampleSDID@32473.eventSource:Application",
+		"origin.ip:192.0.2.1",
+	}, msg.Tags)
+}
+
+func TestSyslogParserRFC5424NilValues(t *testing.T) {
+	parser := New()
+
+	msg, err := parser.Parse([]byte("<34>1 - - su - - - \xef\xbb\xbf'su root' failed"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("'su root' failed"), msg.Content)
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, "", msg.Timestamp)
+	assert.Equal(t, []string{"syslog.appname:su"}, msg.Tags)
+
+	msg, err = parser.Parse([]byte("<34>1 - - - - - -"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msg.Content))
+	assert.Nil(t, msg.Tags)
+}
+
+func TestSyslogParserRFC5424EscapedParamValue(t *testing.T) {
+	parser := New()
+
+	msg, err := parser.Parse([]byte(`<14>1 - - - - - [meta q="a \"b\" \] \\ \c"]`))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(msg.Content))
+	assert.Equal(t, []string{`meta.q:a "b" ] \ \c`}, msg.Tags)
+}
+
+func TestSyslogParserRFC5424ShouldFailWithInvalidHeader(t *testing.T) {
+	parser := New()
+
+	for _, invalid := range []string{
+		"<34>1 2003-10-11 host app - - - foo",
+		"<34>1 - host app",
+		"<34>1 - - - - - [id foo] bar",
+		`<34>1 - - - - - [id foo="bar] baz`,
+		`<34>1 - - - - - [id foo="bar"`,
+		"<34>1 - - - - - -foo",
+	} {
+		msg, err := parser.Parse([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+		assert.Equal(t, []byte(invalid), msg.Content)
+		assert.Equal(t, message.StatusCritical, msg.Status)
+		assert.Nil(t, msg.Tags)
+	}
+}
+
+func TestSyslogParserRFC3164(t *testing.T) {
+	parser := &syslogFormat{now: func() time.Time { return time.Date(2021, time.March, 1, 0, 0, 0, 0, time.Local) }}
+
+	msg, err := parser.Parse([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("'su root' failed for lonvick on /dev/pts/8"), msg.Content)
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	// the timestamp would be in the future in the current year
+	assert.Equal(t, time.Date(2020, time.October, 11, 22, 14, 15, 0, time.Local).UTC().Format("2006-01-02T15:04:05.000000000Z"), msg.Timestamp)
+	assert.Equal(t, []string{"syslog.hostname:mymachine", "syslog.procid:123", "syslog.appname:su"}, msg.Tags)
+
+	msg, err = parser.Parse([]byte("<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("Use the BFG!"), msg.Content)
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, time.Date(2021, time.February, 5, 17, 32, 18, 0, time.Local).UTC().Format("2006-01-02T15:04:05.000000000Z"), msg.Timestamp)
+	assert.Equal(t, []string{"syslog.hostname:10.0.0.99"}, msg.Tags)
+
+	// without hostname
+	msg, err = parser.Parse([]byte("<30>Feb  5 17:32:18 sshd: session opened"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("session opened"), msg.Content)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Equal(t, []string{"syslog.appname:sshd"}, msg.Tags)
+
+	// without timestamp
+	msg, err = parser.Parse([]byte("<30>sshd: session opened"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("sshd: session opened"), msg.Content)
+	assert.Equal(t, "", msg.Timestamp)
+	assert.Nil(t, msg.Tags)
+}
+
+func TestSyslogParserShouldFailWithInvalidPriority(t *testing.T) {
+	parser := New()
+
+	for _, invalid := range []string{"", "foo", "<>1 - - - - - - foo", "<1a>foo", "<1234>foo", "<192>foo", "<34"} {
+		msg, err := parser.Parse([]byte(invalid))
+		assert.NotNil(t, err, invalid)
+		assert.Equal(t, []byte(invalid), msg.Content)
+		assert.Equal(t, message.StatusInfo, msg.Status)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	severity, rest, err := parsePriority([]byte("<0>foo"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, severity)
+	assert.Equal(t, []byte("foo"), rest)
+
+	severity, rest, err = parsePriority([]byte("<191>"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, severity)
+	assert.Equal(t, 0, len(rest))
+}
@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	coreMetrics "github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// metricFlushInterval is the maximum time a generated metric sample stays
+// buffered in the processor before being sent to the sink.
+const metricFlushInterval = time.Second
+
+// MetricSink receives the metric samples generated from the logs by the
+// GenerateMetric processing rules, it is implemented by the aggregator
+// Demultiplexer.
+type MetricSink interface {
+	AddTimeSampleBatch(shard aggregator.TimeSamplerID, samples coreMetrics.MetricSampleBatch)
+	GetMetricSamplePool() *coreMetrics.MetricSamplePool
+}
+
+// metricBatcher buffers the metric samples generated from the logs and sends
+// them by batch to the time sampler shard of their context, like the
+// DogStatsD batcher does.
+type metricBatcher struct {
+	sink          MetricSink
+	samples       []coreMetrics.MetricSampleBatch
+	samplesCount  []int
+	pipelineCount int
+	tagsBuffer    *tagset.HashingTagsAccumulator
+	keyGenerator  *ckey.KeyGenerator
+	mu            sync.Mutex
+}
+
+func newMetricBatcher(sink MetricSink, pipelineCount int) *metricBatcher {
+	if pipelineCount < 1 {
+		pipelineCount = 1
+	}
+	return &metricBatcher{
+		sink:          sink,
+		samples:       make([]coreMetrics.MetricSampleBatch, pipelineCount),
+		samplesCount:  make([]int, pipelineCount),
+		pipelineCount: pipelineCount,
+		tagsBuffer:    tagset.NewHashingTagsAccumulator(),
+		keyGenerator:  ckey.NewKeyGenerator(),
+	}
+}
+
+// fastrange reduces the context key to a shard, see the DogStatsD batcher.
+func fastrange(key ckey.ContextKey, pipelineCount int) uint32 {
+	return uint32((uint64(key>>32) * uint64(pipelineCount)) >> 32)
+}
+
+func (b *metricBatcher) appendSample(sample coreMetrics.MetricSample) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var shard uint32
+	if b.pipelineCount > 1 {
+		b.tagsBuffer.Append(sample.Tags...)
+		shard = fastrange(b.keyGenerator.Generate(sample.Name, sample.Host, b.tagsBuffer), b.pipelineCount)
+		b.tagsBuffer.Reset()
+	}
+
+	if b.samples[shard] == nil {
+		b.samples[shard] = b.sink.GetMetricSamplePool().GetBatch()
+	}
+	b.samples[shard][b.samplesCount[shard]] = sample
+	b.samplesCount[shard]++
+	if b.samplesCount[shard] == len(b.samples[shard]) {
+		b.flushShard(shard)
+	}
+}
+
+// flush sends all the buffered samples to the sink.
+func (b *metricBatcher) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for shard := range b.samples {
+		b.flushShard(uint32(shard))
+	}
+}
+
+func (b *metricBatcher) flushShard(shard uint32) {
+	if b.samplesCount[shard] == 0 {
+		return
+	}
+	// the time sampler puts the batch back in the pool once processed
+	b.sink.AddTimeSampleBatch(aggregator.TimeSamplerID(shard), b.samples[shard][:b.samplesCount[shard]])
+	b.samples[shard] = nil
+	b.samplesCount[shard] = 0
+}
+
+// applyGenerateMetricRule buffers a metric sample in the batcher if the
+// message matches the rule, it returns true if the message matched.
+func applyGenerateMetricRule(rule *config.ProcessingRule, msg *message.Message, content []byte, batcher *metricBatcher) bool {
+	if rule.MatchSource != "" && rule.MatchSource != msg.Origin.Source() {
+		return false
+	}
+	if rule.MatchService != "" && rule.MatchService != msg.Origin.Service() {
+		return false
+	}
+
+	var submatches [][]byte
+	if rule.Regex != nil {
+		submatches = rule.Regex.FindSubmatch(content)
+		if submatches == nil {
+			return false
+		}
+	}
+
+	var log structuredLog
+	switch rule.Format {
+	case config.FormatJSON:
+		log = parseJSON(content)
+	case config.FormatLogfmt:
+		log = parseLogfmt(content)
+	}
+
+	value := 1.0
+	if rule.ValueAttribute != "" {
+		if log == nil {
+			return false
+		}
+		attribute, ok := log.get(rule.ValueAttribute)
+		if !ok {
+			return false
+		}
+		var err error
+		if value, err = strconv.ParseFloat(attributeToString(attribute), 64); err != nil {
+			return false
+		}
+	} else if rule.MetricType == config.MetricTypeDistribution {
+		var err error
+		if value, err = strconv.ParseFloat(string(submatches[rule.Regex.SubexpIndex(config.MetricValueGroup)]), 64); err != nil {
+			return false
+		}
+	}
+
+	tags := make([]string, 0, len(rule.MetricTags)+len(rule.TagAttributes)+2)
+	tags = append(tags, rule.MetricTags...)
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if log != nil {
+		for _, path := range rule.TagAttributes {
+			if attribute, ok := log.get(path); ok {
+				if s := attributeToString(attribute); s != "" {
+					tags = append(tags, path+":"+s)
+				}
+			}
+		}
+	}
+
+	mtype := coreMetrics.CountType
+	if rule.MetricType == config.MetricTypeDistribution {
+		mtype = coreMetrics.DistributionType
+	}
+
+	if batcher != nil {
+		batcher.appendSample(coreMetrics.MetricSample{
+			Name:       rule.MetricName,
+			Value:      value,
+			Mtype:      mtype,
+			Tags:       tags,
+			Host:       msg.GetHostname(),
+			SampleRate: 1,
+			Timestamp:  float64(time.Now().UnixNano()) / float64(time.Second),
+		})
+		metrics.GeneratedMetricSamples.Add(rule.MetricName, 1)
+		metrics.TlmGeneratedMetricSamples.Inc(rule.MetricName)
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+type mockMetricSink struct {
+	samples []metrics.MetricSample
+	shards  []aggregator.TimeSamplerID
+	pool    *metrics.MetricSamplePool
+}
+
+func newMockMetricSink() *mockMetricSink {
+	return &mockMetricSink{pool: metrics.NewMetricSamplePool(4)}
+}
+
+func (s *mockMetricSink) AddTimeSampleBatch(shard aggregator.TimeSamplerID, samples metrics.MetricSampleBatch) {
+	for _, sample := range samples {
+		s.samples = append(s.samples, sample)
+		s.shards = append(s.shards, shard)
+	}
+	s.pool.PutBatch(samples)
+}
+
+func (s *mockMetricSink) GetMetricSamplePool() *metrics.MetricSamplePool {
+	return s.pool
+}
+
+func newGenerateMetricRule(t *testing.T, rule *config.ProcessingRule) *config.ProcessingRule {
+	rule.Type = config.GenerateMetric
+	rule.Name = "metric"
+	require.NoError(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}))
+	require.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	return rule
+}
+
+func TestGenerateCountMetric(t *testing.T) {
+	sink := newMockMetricSink()
+	p := &Processor{
+		processingRules: []*config.ProcessingRule{newGenerateMetricRule(t, &config.ProcessingRule{
+			Pattern:    "GET /api",
+			MetricName: "api.requests",
+			MetricTags: []string{"team:web"},
+		})},
+		metricBatcher: newMetricBatcher(sink, 1),
+	}
+	source := config.NewLogSource("", &config.LogsConfig{Source: "nginx", Service: "web"})
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("GET /api/users 200"), source, ""))
+	assert.True(t, shouldProcess)
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte("POST /api/users 201"), source, ""))
+	assert.True(t, shouldProcess)
+
+	p.flushMetrics()
+	require.Len(t, sink.samples, 1)
+	assert.Equal(t, "api.requests", sink.samples[0].Name)
+	assert.Equal(t, metrics.CountType, sink.samples[0].Mtype)
+	assert.Equal(t, 1.0, sink.samples[0].Value)
+	assert.Equal(t, []string{"team:web", "source:nginx", "service:web"}, sink.samples[0].Tags)
+}
+
+func TestGenerateDistributionMetric(t *testing.T) {
+	sink := newMockMetricSink()
+	p := &Processor{
+		processingRules: []*config.ProcessingRule{
+			newGenerateMetricRule(t, &config.ProcessingRule{
+				Pattern:    `took (?P<value>[0-9.]+)ms`,
+				MetricName: "regex.latency",
+				MetricType: config.MetricTypeDistribution,
+			}),
+			newGenerateMetricRule(t, &config.ProcessingRule{
+				Format:         config.FormatJSON,
+				MetricName:     "json.latency",
+				MetricType:     config.MetricTypeDistribution,
+				ValueAttribute: "http.duration",
+				TagAttributes:  []string{"http.status"},
+				DropLog:        true,
+			}),
+		},
+		metricBatcher: newMetricBatcher(sink, 1),
+	}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	shouldProcess, _ := p.applyRedactingRules(newMessage([]byte("request took 12.5ms"), source, ""))
+	assert.True(t, shouldProcess)
+
+	// the log is dropped once the metric is generated
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte(`{"http":{"duration":42,"status":200}}`), source, ""))
+	assert.False(t, shouldProcess)
+
+	// the logs without value are kept
+	shouldProcess, _ = p.applyRedactingRules(newMessage([]byte(`{"http":{"status":200}}`), source, ""))
+	assert.True(t, shouldProcess)
+
+	p.flushMetrics()
+	require.Len(t, sink.samples, 2)
+	assert.Equal(t, "regex.latency", sink.samples[0].Name)
+	assert.Equal(t, metrics.DistributionType, sink.samples[0].Mtype)
+	assert.Equal(t, 12.5, sink.samples[0].Value)
+	assert.Equal(t, "json.latency", sink.samples[1].Name)
+	assert.Equal(t, 42.0, sink.samples[1].Value)
+	assert.Equal(t, []string{"http.status:200"}, sink.samples[1].Tags)
+}
+
+func TestGenerateMetricMatchSourceAndService(t *testing.T) {
+	sink := newMockMetricSink()
+	p := &Processor{
+		processingRules: []*config.ProcessingRule{newGenerateMetricRule(t, &config.ProcessingRule{
+			MetricName:   "redis.logs",
+			MatchSource:  "redis",
+			MatchService: "cache",
+		})},
+		metricBatcher: newMetricBatcher(sink, 1),
+	}
+
+	p.applyRedactingRules(newMessage([]byte("foo"), config.NewLogSource("", &config.LogsConfig{Source: "redis", Service: "cache"}), ""))
+	p.applyRedactingRules(newMessage([]byte("foo"), config.NewLogSource("", &config.LogsConfig{Source: "redis"}), ""))
+	p.applyRedactingRules(newMessage([]byte("foo"), config.NewLogSource("", &config.LogsConfig{Service: "cache"}), ""))
+	p.flushMetrics()
+	assert.Len(t, sink.samples, 1)
+}
+
+func TestMetricBatcherShards(t *testing.T) {
+	sink := newMockMetricSink()
+	b := newMetricBatcher(sink, 4)
+
+	sample := func(tag string) metrics.MetricSample {
+		return metrics.MetricSample{Name: "logs.metric", Tags: []string{tag}, Mtype: metrics.CountType, Value: 1}
+	}
+
+	// a full batch is sent without waiting for the flush
+	for i := 0; i < 4; i++ {
+		b.appendSample(sample("foo"))
+	}
+	require.Len(t, sink.samples, 4)
+	for _, shard := range sink.shards {
+		assert.Equal(t, sink.shards[0], shard)
+	}
+
+	for i := 0; i < 16; i++ {
+		b.appendSample(sample(fmt.Sprintf("tag:%d", i)))
+	}
+	b.flush()
+	require.Len(t, sink.samples, 20)
+
+	// the samples of a context always go to the same shard
+	shards := make(map[string]aggregator.TimeSamplerID)
+	for i, s := range sink.samples {
+		if shard, ok := shards[s.Tags[0]]; ok {
+			assert.Equal(t, shard, sink.shards[i])
+		}
+		shards[s.Tags[0]] = sink.shards[i]
+	}
+	distinct := make(map[aggregator.TimeSamplerID]struct{})
+	for _, shard := range shards {
+		distinct[shard] = struct{}{}
+	}
+	assert.Greater(t, len(distinct), 1)
+}
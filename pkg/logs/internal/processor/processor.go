@@ -8,7 +8,9 @@ package processor
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
@@ -26,11 +28,18 @@ type Processor struct {
 	encoder                   Encoder
 	done                      chan struct{}
 	diagnosticMessageReceiver diagnostic.MessageReceiver
+	metricBatcher             *metricBatcher
 	mu                        sync.Mutex
 }
 
-// New returns an initialized Processor.
-func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver) *Processor {
+// New returns an initialized Processor. The metric samples generated from the
+// logs are sent to metricSink, which can be nil if no metric is generated.
+func New(inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule, encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, metricSink MetricSink) *Processor {
+	var batcher *metricBatcher
+	if metricSink != nil {
+		_, pipelineCount := aggregator.GetDogStatsDWorkerAndPipelineCount()
+		batcher = newMetricBatcher(metricSink, pipelineCount)
+	}
 	return &Processor{
 		inputChan:                 inputChan,
 		outputChan:                outputChan,
@@ -38,6 +47,7 @@ func New(inputChan, outputChan chan *message.Message, processingRules []*config.
 		encoder:                   encoder,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
+		metricBatcher:             batcher,
 	}
 }
 
@@ -57,6 +67,7 @@ func (p *Processor) Stop() {
 func (p *Processor) Flush(ctx context.Context) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	defer p.flushMetrics()
 	for {
 		select {
 		case <-ctx.Done():
@@ -74,12 +85,30 @@ func (p *Processor) Flush(ctx context.Context) {
 // run starts the processing of the inputChan
 func (p *Processor) run() {
 	defer func() {
+		p.flushMetrics()
 		p.done <- struct{}{}
 	}()
-	for msg := range p.inputChan {
-		p.processMessage(msg)
-		p.mu.Lock() // block here if we're trying to flush synchronously
-		p.mu.Unlock()
+	metricFlushTicker := time.NewTicker(metricFlushInterval)
+	defer metricFlushTicker.Stop()
+	for {
+		select {
+		case msg, ok := <-p.inputChan:
+			if !ok {
+				return
+			}
+			p.processMessage(msg)
+			p.mu.Lock() // block here if we're trying to flush synchronously
+			p.mu.Unlock()
+		case <-metricFlushTicker.C:
+			p.flushMetrics()
+		}
+	}
+}
+
+// flushMetrics sends the buffered metric samples generated from the logs.
+func (p *Processor) flushMetrics() {
+	if p.metricBatcher != nil {
+		p.metricBatcher.flush()
 	}
 }
 
@@ -120,6 +149,12 @@ func (p *Processor) applyRedactingRules(msg *message.Message) (bool, []byte) {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.ParseStructured:
+			content = applyParseStructuredRule(rule, msg, content)
+		case config.GenerateMetric:
+			if applyGenerateMetricRule(rule, msg, content, p.metricBatcher) && rule.DropLog {
+				return false, nil
+			}
 		}
 	}
 	return true, content
@@ -0,0 +1,452 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// statusAliases maps the usual level names to the statuses of the logs.
+var statusAliases = map[string]string{
+	"emerg":         message.StatusEmergency,
+	"emergency":     message.StatusEmergency,
+	"panic":         message.StatusEmergency,
+	"alert":         message.StatusAlert,
+	"crit":          message.StatusCritical,
+	"critical":      message.StatusCritical,
+	"fatal":         message.StatusCritical,
+	"err":           message.StatusError,
+	"error":         message.StatusError,
+	"warn":          message.StatusWarning,
+	"warning":       message.StatusWarning,
+	"notice":        message.StatusNotice,
+	"info":          message.StatusInfo,
+	"information":   message.StatusInfo,
+	"informational": message.StatusInfo,
+	"debug":         message.StatusDebug,
+	"trace":         message.StatusDebug,
+}
+
+// structuredLog is a log line parsed by a ParseStructured rule.
+type structuredLog interface {
+	get(path string) (interface{}, bool)
+	set(path string, value interface{})
+	delete(path string) bool
+	marshal() ([]byte, error)
+}
+
+// applyParseStructuredRule parses the content with the given rule and
+// returns the new content, updating the status and the tags of the message.
+// The status and the tags are read before the attributes are dropped, masked
+// or renamed. The content is returned unchanged if it can't be parsed.
+func applyParseStructuredRule(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	var log structuredLog
+	switch rule.Format {
+	case config.FormatJSON:
+		log = parseJSON(content)
+	case config.FormatLogfmt:
+		log = parseLogfmt(content)
+	}
+	if log == nil {
+		return content
+	}
+
+	if rule.StatusAttribute != "" {
+		if value, ok := log.get(rule.StatusAttribute); ok {
+			if status, ok := statusAliases[strings.ToLower(attributeToString(value))]; ok {
+				msg.SetStatus(status)
+			}
+		}
+	}
+
+	var tags []string
+	for _, path := range rule.TagAttributes {
+		if value, ok := log.get(path); ok {
+			if s := attributeToString(value); s != "" {
+				tags = append(tags, path+":"+s)
+			}
+		}
+	}
+	if len(tags) > 0 {
+		msg.Origin.AddTags(tags...)
+	}
+
+	updated := false
+	for _, path := range rule.DropAttributes {
+		if log.delete(path) {
+			updated = true
+		}
+	}
+	for _, path := range rule.MaskAttributes {
+		if _, ok := log.get(path); ok {
+			log.set(path, string(rule.Placeholder))
+			updated = true
+		}
+	}
+	if renameAttributes(log, rule.RenameAttributes) {
+		updated = true
+	}
+	if !updated {
+		return content
+	}
+
+	encoded, err := log.marshal()
+	if err != nil {
+		return content
+	}
+	return encoded
+}
+
+// renameAttributes renames the attributes of the log all at once, so that the
+// attribute renamed to the path of another one doesn't get renamed again (e.g.
+// with a->b and b->c, the value of a ends up in b and the one of b in c). The
+// renames are applied in the order of their source paths, so that the result
+// is the same on each run when several of them have the same target.
+func renameAttributes(log structuredLog, renames map[string]string) bool {
+	var paths []string
+	values := make(map[string]interface{}, len(renames))
+	for from := range renames {
+		if value, ok := log.get(from); ok {
+			paths = append(paths, from)
+			values[from] = value
+		}
+	}
+	if len(paths) == 0 {
+		return false
+	}
+	sort.Strings(paths)
+
+	for _, from := range paths {
+		log.delete(from)
+	}
+	for _, from := range paths {
+		log.set(renames[from], values[from])
+	}
+	return true
+}
+
+// attributeToString returns the string representation of a scalar attribute,
+// or an empty string for objects and arrays.
+func attributeToString(value interface{}) string {
+	switch v := value.(type) {
+	case json.RawMessage:
+		decoder := json.NewDecoder(bytes.NewReader(v))
+		// keep the numbers as they are
+		decoder.UseNumber()
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return ""
+		}
+		return attributeToString(decoded)
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// jsonLog is a log line holding a JSON object. The order of the keys is kept,
+// and the values which are not objects are kept as they were encoded, so that
+// only the updated attributes are changed in the log.
+type jsonLog struct {
+	keys   []string
+	values map[string]interface{} // *jsonLog or json.RawMessage
+}
+
+func newJSONLog() *jsonLog {
+	return &jsonLog{values: make(map[string]interface{})}
+}
+
+func parseJSON(content []byte) structuredLog {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	log, err := parseJSONObject(trimmed)
+	if err != nil {
+		return nil
+	}
+	return log
+}
+
+// parseJSONObject parses a single JSON object, the nested objects are parsed
+// recursively.
+func parseJSONObject(data []byte) (*jsonLog, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	log := newJSONLog()
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object key %v", token)
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var value interface{} = raw
+		if raw[0] == '{' {
+			if value, err = parseJSONObject(raw); err != nil {
+				return nil, err
+			}
+		}
+		log.setKey(key, value)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected content after the JSON object")
+	}
+	return log, nil
+}
+
+func (l *jsonLog) setKey(key string, value interface{}) {
+	if _, ok := l.values[key]; !ok {
+		l.keys = append(l.keys, key)
+	}
+	l.values[key] = value
+}
+
+// parent returns the object holding the attribute of the given path, creating
+// the intermediate objects if create is true.
+func (l *jsonLog) parent(path string, create bool) (*jsonLog, string) {
+	keys := strings.Split(path, ".")
+	obj := l
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj.values[key].(*jsonLog)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = newJSONLog()
+			obj.setKey(key, next)
+		}
+		obj = next
+	}
+	return obj, keys[len(keys)-1]
+}
+
+func (l *jsonLog) get(path string) (interface{}, bool) {
+	obj, key := l.parent(path, false)
+	if obj == nil {
+		return nil, false
+	}
+	value, ok := obj.values[key]
+	return value, ok
+}
+
+func (l *jsonLog) set(path string, value interface{}) {
+	switch value.(type) {
+	case *jsonLog, json.RawMessage:
+	default:
+		encoded, err := marshalJSONValue(value)
+		if err != nil {
+			return
+		}
+		value = json.RawMessage(encoded)
+	}
+	obj, key := l.parent(path, true)
+	obj.setKey(key, value)
+}
+
+func (l *jsonLog) delete(path string) bool {
+	obj, key := l.parent(path, false)
+	if obj == nil {
+		return false
+	}
+	if _, ok := obj.values[key]; !ok {
+		return false
+	}
+	delete(obj.values, key)
+	for i, k := range obj.keys {
+		if k == key {
+			obj.keys = append(obj.keys[:i], obj.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (l *jsonLog) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := l.marshalTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (l *jsonLog) marshalTo(buf *bytes.Buffer) error {
+	buf.WriteByte('{')
+	for i, key := range l.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := marshalJSONValue(key)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		switch v := l.values[key].(type) {
+		case *jsonLog:
+			if err := v.marshalTo(buf); err != nil {
+				return err
+			}
+		case json.RawMessage:
+			buf.Write(v)
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// marshalJSONValue encodes a value without escaping the HTML characters, which
+// json.Marshal does.
+func marshalJSONValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	// the encoder terminates each value with a newline
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// logfmtLog is a log line made of key=value pairs, the order of the pairs is
+// kept.
+type logfmtLog struct {
+	keys   []string
+	values map[string]string
+	// flags are the keys without value
+	flags map[string]bool
+}
+
+func parseLogfmt(content []byte) structuredLog {
+	log := &logfmtLog{values: make(map[string]string), flags: make(map[string]bool)}
+	line := string(bytes.TrimSpace(content))
+	hasValue := false
+
+	for len(line) > 0 {
+		end := strings.IndexAny(line, "= ")
+		if end == 0 {
+			// a value without key
+			return nil
+		}
+		if end < 0 {
+			end = len(line)
+		}
+		key := line[:end]
+		line = line[end:]
+
+		var value string
+		if !strings.HasPrefix(line, "=") {
+			log.flags[key] = true
+		} else {
+			delete(log.flags, key)
+			hasValue = true
+			line = line[1:]
+			if strings.HasPrefix(line, `"`) {
+				quoted, err := strconv.QuotedPrefix(line)
+				if err != nil {
+					return nil
+				}
+				value, _ = strconv.Unquote(quoted)
+				line = line[len(quoted):]
+			} else {
+				end = strings.IndexByte(line, ' ')
+				if end < 0 {
+					end = len(line)
+				}
+				value = line[:end]
+				line = line[end:]
+			}
+		}
+		if _, ok := log.values[key]; !ok {
+			log.keys = append(log.keys, key)
+		}
+		log.values[key] = value
+		line = strings.TrimLeft(line, " ")
+	}
+
+	if !hasValue {
+		return nil
+	}
+	return log
+}
+
+func (l *logfmtLog) get(path string) (interface{}, bool) {
+	value, ok := l.values[path]
+	return value, ok
+}
+
+func (l *logfmtLog) set(path string, value interface{}) {
+	if _, ok := l.values[path]; !ok {
+		l.keys = append(l.keys, path)
+	}
+	delete(l.flags, path)
+	switch v := value.(type) {
+	case string:
+		l.values[path] = v
+	default:
+		l.values[path] = fmt.Sprint(v)
+	}
+}
+
+func (l *logfmtLog) delete(path string) bool {
+	if _, ok := l.values[path]; !ok {
+		return false
+	}
+	delete(l.values, path)
+	delete(l.flags, path)
+	for i, key := range l.keys {
+		if key == path {
+			l.keys = append(l.keys[:i], l.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (l *logfmtLog) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, key := range l.keys {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(key)
+		if l.flags[key] {
+			continue
+		}
+		buf.WriteByte('=')
+		value := l.values[key]
+		if value == "" || strings.ContainsAny(value, " =\"\\") {
+			buf.WriteString(strconv.Quote(value))
+		} else {
+			buf.WriteString(value)
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newParseStructuredRule(format string) *config.ProcessingRule {
+	return &config.ProcessingRule{
+		Type:             config.ParseStructured,
+		Name:             "parse",
+		Format:           format,
+		DropAttributes:   []string{"password", "user.ssn"},
+		MaskAttributes:   []string{"token"},
+		RenameAttributes: map[string]string{"msg": "message"},
+		StatusAttribute:  "level",
+		TagAttributes:    []string{"trace_id", "user.id"},
+		Placeholder:      []byte(config.DefaultMaskPlaceholder),
+	}
+}
+
+func TestParseStructuredJSON(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newParseStructuredRule(config.FormatJSON)}}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte(`{"level":"WARNING","msg":"hello","password":"secret","token":"abc","trace_id":1234567890123456789,"user":{"id":"42","ssn":"123"}}`), source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `{"level":"WARNING","token":"[masked]","trace_id":1234567890123456789,"user":{"id":"42"},"message":"hello"}`, string(redactedMessage))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, []string{"trace_id:1234567890123456789", "user.id:42"}, msg.Origin.Tags())
+
+	// lines that are not JSON objects are left untouched
+	for _, content := range []string{"password=secret", `["password"]`, `{"password":"secret"`} {
+		msg = newMessage([]byte(content), source, "")
+		_, redactedMessage = p.applyRedactingRules(msg)
+		assert.Equal(t, content, string(redactedMessage))
+		assert.Equal(t, message.StatusInfo, msg.GetStatus())
+	}
+
+	// the content is not reformatted when no attribute is updated
+	content := `{ "level": "error", "msg": "world" }`
+	p.processingRules[0].RenameAttributes = nil
+	msg = newMessage([]byte(content), source, "")
+	_, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, content, string(redactedMessage))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+}
+
+func TestParseStructuredJSONKeepsContent(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newParseStructuredRule(config.FormatJSON)}}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	// the HTML characters aren't escaped, and the keys and the values which aren't updated are kept as they are
+	msg := newMessage([]byte(`{"z":"<a&b>","msg":"<a&b>","password":"x","b":{"y":1.50,"x":"\u00e9"},"a":[{"k":2,"j":1}]}`), source, "")
+	_, redactedMessage := p.applyRedactingRules(msg)
+	assert.Equal(t, `{"z":"<a&b>","b":{"y":1.50,"x":"\u00e9"},"a":[{"k":2,"j":1}],"message":"<a&b>"}`, string(redactedMessage))
+
+	// the masked values aren't escaped either
+	p.processingRules[0].Placeholder = []byte("<masked>")
+	msg = newMessage([]byte(`{"token":"abc","level":"info"}`), source, "")
+	_, redactedMessage = p.applyRedactingRules(msg)
+	assert.Equal(t, `{"token":"<masked>","level":"info"}`, string(redactedMessage))
+}
+
+func TestParseStructuredLogfmt(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{newParseStructuredRule(config.FormatLogfmt)}}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	msg := newMessage([]byte(`level=err msg="hello world" password=secret token=abc trace_id=123 debug`), source, "")
+	shouldProcess, redactedMessage := p.applyRedactingRules(msg)
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `level=err token=[masked] trace_id=123 debug message="hello world"`, string(redactedMessage))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, []string{"trace_id:123"}, msg.Origin.Tags())
+
+	for _, content := range []string{"hello world", `msg="unterminated`, "=value"} {
+		msg = newMessage([]byte(content), source, "")
+		_, redactedMessage = p.applyRedactingRules(msg)
+		assert.Equal(t, content, string(redactedMessage))
+	}
+}
+
+func TestParseStructuredChainedRenames(t *testing.T) {
+	source := config.NewLogSource("", &config.LogsConfig{})
+	for _, test := range []struct {
+		format   string
+		content  string
+		expected string
+	}{
+		{config.FormatJSON, `{"a":"1","b":"2","c":"3","x":"4","y":"5"}`, `{"b":"1","c":"2","d":"3","z":"5"}`},
+		{config.FormatLogfmt, `a=1 b=2 c=3 x=4 y=5`, `b=1 c=2 d=3 z=5`},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			p := &Processor{processingRules: []*config.ProcessingRule{{
+				Type:   config.ParseStructured,
+				Name:   "rename",
+				Format: test.format,
+				// chained renames, and two renames with the same target
+				RenameAttributes: map[string]string{"a": "b", "b": "c", "c": "d", "x": "z", "y": "z"},
+			}}}
+			// the renames don't depend on the iteration order of the map
+			for i := 0; i < 20; i++ {
+				_, redactedMessage := p.applyRedactingRules(newMessage([]byte(test.content), source, ""))
+				assert.Equal(t, test.expected, string(redactedMessage))
+			}
+		})
+	}
+}
+
+func TestParseStructuredBeforeOtherRules(t *testing.T) {
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		newParseStructuredRule(config.FormatJSON),
+		newProcessingRule(config.ExcludeAtMatch, "", "secret"),
+	}}
+	source := config.NewLogSource("", &config.LogsConfig{})
+
+	// the password is dropped before the exclusion rule is applied
+	shouldProcess, redactedMessage := p.applyRedactingRules(newMessage([]byte(`{"password":"secret"}`), source, ""))
+	assert.True(t, shouldProcess)
+	assert.Equal(t, `{}`, string(redactedMessage))
+}
@@ -8,12 +8,15 @@ package socket
 import (
 	"io"
 	"net"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
@@ -36,12 +39,20 @@ func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan *message
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		decoder:    decoder.InitializeDecoder(source, noop.New()),
+		decoder:    buildDecoder(source),
 		stop:       make(chan struct{}, 1),
 		done:       make(chan struct{}, 1),
 	}
 }
 
+// buildDecoder returns the decoder matching the format of the source.
+func buildDecoder(source *config.LogSource) *decoder.Decoder {
+	if source.Config.Format == config.SyslogFormat {
+		return decoder.NewDecoderWithFraming(source, syslog.New(), framer.Syslog, nil)
+	}
+	return decoder.InitializeDecoder(source, noop.New())
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -63,9 +74,23 @@ func (t *Tailer) forwardMessages() {
 		t.done <- struct{}{}
 	}()
 	for output := range t.decoder.OutputChan {
-		if len(output.Content) > 0 {
-			t.outputChan <- message.NewMessageWithSource(output.Content, message.StatusInfo, t.source, output.IngestionTimestamp)
+		if len(output.Content) == 0 {
+			continue
+		}
+		status := output.Status
+		if status == "" {
+			status = message.StatusInfo
+		}
+		msg := message.NewMessageWithSource(output.Content, status, t.source, output.IngestionTimestamp)
+		if len(output.Tags) > 0 {
+			msg.Origin.SetTags(output.Tags)
+		}
+		if output.Timestamp != "" {
+			if timestamp, err := time.Parse(config.DateFormat, output.Timestamp); err == nil {
+				msg.Timestamp = timestamp
+			}
 		}
+		t.outputChan <- msg
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -39,6 +40,33 @@ func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	tailer := NewTailer(config.NewLogSource("", &config.LogsConfig{Format: config.SyslogFormat}), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// should receive and decode an octet-counted message
+	w.Write([]byte("50 <11>1 2003-10-11T22:14:15.003Z host app 42 - - foo"))
+	msg = <-msgChan
+	assert.Equal(t, "foo", string(msg.Content))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, []string{"syslog.hostname:host", "syslog.appname:app", "syslog.procid:42"}, msg.Origin.Tags())
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), msg.Timestamp)
+
+	// should receive and decode a newline-terminated message
+	w.Write([]byte("<15>1 - - - - - - bar\n"))
+	msg = <-msgChan
+	assert.Equal(t, "bar", string(msg.Content))
+	assert.Equal(t, message.StatusDebug, msg.GetStatus())
+	assert.Empty(t, msg.Origin.Tags())
+	assert.True(t, msg.Timestamp.IsZero())
+
+	tailer.Stop()
+}
+
 func TestReadShouldFailWithError(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
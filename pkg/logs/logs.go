@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
@@ -53,13 +54,14 @@ var (
 // instead of directly using it.
 // The parameter serverless indicates whether or not this Logs Agent is running
 // in a serverless environment.
-func Start(ac *autodiscovery.AutoConfig) (*Agent, error) {
-	return start(ac, false)
+// The metrics generated from the logs by the processing rules are sent to demux.
+func Start(ac *autodiscovery.AutoConfig, demux aggregator.Demultiplexer) (*Agent, error) {
+	return start(ac, demux, false)
 }
 
 // StartServerless starts a Serverless instance of the Logs Agent.
 func StartServerless() (*Agent, error) {
-	return start(nil, true)
+	return start(nil, nil, true)
 }
 
 // buildEndpoints builds endpoints for the logs agent
@@ -74,7 +76,7 @@ func buildEndpoints(serverless bool) (*config.Endpoints, error) {
 	return config.BuildEndpointsWithVectorOverride(httpConnectivity, intakeTrackType, AgentJSONIntakeProtocol, config.DefaultIntakeOrigin)
 }
 
-func start(ac *autodiscovery.AutoConfig, serverless bool) (*Agent, error) {
+func start(ac *autodiscovery.AutoConfig, demux aggregator.Demultiplexer, serverless bool) (*Agent, error) {
 	if IsAgentRunning() {
 		return agent, nil
 	}
@@ -117,7 +119,7 @@ func start(ac *autodiscovery.AutoConfig, serverless bool) (*Agent, error) {
 	if !serverless {
 		// regular logs agent
 		log.Info("Starting logs-agent...")
-		agent = NewAgent(sources, services, processingRules, endpoints)
+		agent = NewAgent(sources, services, processingRules, endpoints, demux)
 	} else {
 		// serverless logs agent
 		log.Info("Starting a serverless logs-agent...")
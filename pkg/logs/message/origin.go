@@ -90,6 +90,14 @@ func (o *Origin) SetTags(tags []string) {
 	o.tags = tags
 }
 
+// AddTags appends tags to the tags of the origin, the slice given to SetTags
+// is not modified.
+func (o *Origin) AddTags(tags ...string) {
+	merged := make([]string, 0, len(o.tags)+len(tags))
+	merged = append(merged, o.tags...)
+	o.tags = append(merged, tags...)
+}
+
 // SetSource sets the source of the origin.
 func (o *Origin) SetSource(source string) {
 	o.source = source
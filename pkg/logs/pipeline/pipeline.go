@@ -8,6 +8,8 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
@@ -17,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -34,7 +37,9 @@ func NewPipeline(outputChan chan *message.Payload,
 	destinationsContext *client.DestinationsContext,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	serverless bool,
-	pipelineID int) *Pipeline {
+	pipelineID int,
+	spoolConfig *sender.SpoolConfig,
+	metricSink processor.MetricSink) *Pipeline {
 
 	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineID)
 
@@ -44,7 +49,11 @@ func NewPipeline(outputChan chan *message.Payload,
 	var logsSender *sender.Sender
 
 	strategy := getStrategy(strategyInput, senderInput, endpoints, serverless, pipelineID)
-	logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	if spool := getSpool(spoolConfig, endpoints, pipelineID); spool != nil {
+		logsSender = sender.NewSenderWithSpool(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, spool)
+	} else {
+		logsSender = sender.NewSender(senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize)
+	}
 
 	var encoder processor.Encoder
 	if serverless {
@@ -58,7 +67,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	inputChan := make(chan *message.Message, config.ChanSize)
-	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver)
+	processor := processor.New(inputChan, strategyInput, processingRules, encoder, diagnosticMessageReceiver, metricSink)
 
 	return &Pipeline{
 		InputChan: inputChan,
@@ -111,6 +120,28 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	return client.NewDestinations(reliable, additionals)
 }
 
+// getSpool returns the on-disk spool of the pipeline, each pipeline gets its
+// own directory. It returns nil if the spool is disabled or can't be created.
+func getSpool(spoolConfig *sender.SpoolConfig, endpoints *config.Endpoints, pipelineID int) *sender.Spool {
+	if spoolConfig == nil {
+		return nil
+	}
+	if !endpoints.UseHTTP {
+		log.Warn("The logs spool is only supported with HTTP transport, it is disabled")
+		return nil
+	}
+	spool, err := sender.NewSpool(sender.SpoolConfig{
+		Path:           filepath.Join(spoolConfig.Path, strconv.Itoa(pipelineID)),
+		MaxSizeInBytes: spoolConfig.MaxSizeInBytes,
+		MaxAge:         spoolConfig.MaxAge,
+	}, fmt.Sprintf("logs_%d", pipelineID))
+	if err != nil {
+		log.Warnf("Could not create the logs spool of the pipeline %d, it is disabled: %v", pipelineID, err)
+		return nil
+	}
+	return spool
+}
+
 func getStrategy(inputChan chan *message.Message, outputChan chan *message.Payload, endpoints *config.Endpoints, serverless bool, pipelineID int) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
 		encoder := sender.IdentityContentType
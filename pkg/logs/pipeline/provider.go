@@ -14,7 +14,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sender"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
 )
 
@@ -39,21 +42,33 @@ type provider struct {
 	pipelines            []*Pipeline
 	currentPipelineIndex *atomic.Uint32
 	destinationsContext  *client.DestinationsContext
+	spoolConfig          *sender.SpoolConfig
+	metricSink           processor.MetricSink
 
 	serverless bool
 }
 
-// NewProvider returns a new Provider
-func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false)
+// NewProvider returns a new Provider. When spoolConfig is not nil, the payloads
+// are buffered on the disk while the reliable destinations are failing, the
+// maximum size is shared by all the pipelines. The metric samples generated
+// from the logs are sent to metricSink.
+func NewProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, spoolConfig *sender.SpoolConfig, metricSink processor.MetricSink) Provider {
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, spoolConfig, metricSink, false)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
 func NewServerlessProvider(numberOfPipelines int, auditor auditor.Auditor, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext) Provider {
-	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, true)
+	return newProvider(numberOfPipelines, auditor, &diagnostic.NoopMessageReceiver{}, processingRules, endpoints, destinationsContext, nil, nil, true)
 }
 
-func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, serverless bool) Provider {
+func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, spoolConfig *sender.SpoolConfig, metricSink processor.MetricSink, serverless bool) Provider {
+	if spoolConfig != nil && numberOfPipelines > 0 {
+		spoolConfig = &sender.SpoolConfig{
+			Path:           spoolConfig.Path,
+			MaxSizeInBytes: spoolConfig.MaxSizeInBytes / int64(numberOfPipelines),
+			MaxAge:         spoolConfig.MaxAge,
+		}
+	}
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
 		auditor:                   auditor,
@@ -63,6 +78,8 @@ func newProvider(numberOfPipelines int, auditor auditor.Auditor, diagnosticMessa
 		pipelines:                 []*Pipeline{},
 		currentPipelineIndex:      atomic.NewUint32(0),
 		destinationsContext:       destinationsContext,
+		spoolConfig:               spoolConfig,
+		metricSink:                metricSink,
 		serverless:                serverless,
 	}
 }
@@ -72,8 +89,14 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	if p.spoolConfig != nil && p.numberOfPipelines > 0 {
+		if err := sender.MergeOrphanedSpools(p.spoolConfig.Path, p.numberOfPipelines); err != nil {
+			log.Warnf("Could not merge the logs spools of the removed pipelines: %v", err)
+		}
+	}
+
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.spoolConfig, p.metricSink)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
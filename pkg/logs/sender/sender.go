@@ -12,8 +12,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// spoolReplayInterval is the interval at which the spooled payloads are
+// replayed when no new payload is received.
+const spoolReplayInterval = 100 * time.Millisecond
+
 var (
 	tlmPayloadsDropped = telemetry.NewCounter("logs_sender", "payloads_dropped", []string{"reliable", "destination"}, "Payloads dropped")
 	tlmMessagesDropped = telemetry.NewCounter("logs_sender", "messages_dropped", []string{"reliable", "destination"}, "Messages dropped")
@@ -27,12 +32,21 @@ var (
 // one reliable destination is also sending logs. However they do not update
 // the auditor or block the pipeline if they fail. There will always be at
 // least 1 reliable destination (the main destination).
+//
+// When a spool is set, the payloads that can't be sent because all the reliable
+// destinations are failing are stored on the disk instead of blocking the
+// pipeline, and they are replayed in order once a reliable destination
+// recovers. A payload is forwarded to the auditor as soon as it is spooled,
+// the spool being durable, so the replayed payloads don't carry the messages
+// anymore and don't update the auditor a second time. They are sent to the
+// unreliable destinations as well.
 type Sender struct {
 	inputChan    chan *message.Payload
 	outputChan   chan *message.Payload
 	destinations *client.Destinations
 	done         chan struct{}
 	bufferSize   int
+	spool        *Spool
 }
 
 // NewSender returns a new sender.
@@ -46,6 +60,14 @@ func NewSender(inputChan chan *message.Payload, outputChan chan *message.Payload
 	}
 }
 
+// NewSenderWithSpool returns a new sender buffering the payloads on the disk
+// when the reliable destinations are failing.
+func NewSenderWithSpool(inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, spool *Spool) *Sender {
+	s := NewSender(inputChan, outputChan, destinations, bufferSize)
+	s.spool = spool
+	return s
+}
+
 // Start starts the sender.
 func (s *Sender) Start() {
 	go s.run()
@@ -64,46 +86,23 @@ func (s *Sender) run() {
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		var startInUse = time.Now()
+	if s.spool != nil {
+		s.runWithSpool(reliableDestinations, unreliableDestinations)
+	} else {
+		for payload := range s.inputChan {
+			var startInUse = time.Now()
 
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					sent = true
-				}
-			}
-
-			if !sent {
+			for !sendToReliable(payload, reliableDestinations) {
 				// Throttle the poll loop while waiting for a send to succeed
 				// This will only happen when all reliable destinations
 				// are blocked so logs have no where to go.
 				time.Sleep(100 * time.Millisecond)
 			}
-		}
-
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
-			}
-		}
+			sendToUnreliable(payload, unreliableDestinations)
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
-			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
-			}
+			inUse := float64(time.Since(startInUse) / time.Millisecond)
+			tlmSendWaitTime.Add(inUse)
 		}
-
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
 	}
 
 	// Cleanup the destinations
@@ -117,6 +116,100 @@ func (s *Sender) run() {
 	s.done <- struct{}{}
 }
 
+func (s *Sender) runWithSpool(reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) {
+	ticker := time.NewTicker(spoolReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case payload, ok := <-s.inputChan:
+			if !ok {
+				// the payloads left in the spool are replayed on the next start
+				return
+			}
+			var startInUse = time.Now()
+
+			// the spooled payloads must be sent first to keep the order
+			if s.replaySpool(reliableDestinations, unreliableDestinations) && sendToReliable(payload, reliableDestinations) {
+				sendToUnreliable(payload, unreliableDestinations)
+			} else {
+				s.spoolPayload(payload, reliableDestinations)
+			}
+
+			inUse := float64(time.Since(startInUse) / time.Millisecond)
+			tlmSendWaitTime.Add(inUse)
+		case <-ticker.C:
+			s.replaySpool(reliableDestinations, unreliableDestinations)
+		}
+	}
+}
+
+// replaySpool sends the spooled payloads to the destinations until all the
+// reliable ones fail. It returns true if the spool is empty.
+func (s *Sender) replaySpool(reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) bool {
+	for {
+		payload := s.spool.Peek()
+		if payload == nil {
+			return true
+		}
+		if !sendToReliable(payload, reliableDestinations) {
+			return false
+		}
+		sendToUnreliable(payload, unreliableDestinations)
+		s.spool.Pop()
+	}
+}
+
+// spoolPayload stores the payload on the disk and forwards it to the auditor
+// since it is not lost anymore. If the payload can't be stored, it falls back
+// to blocking the pipeline until a reliable destination accepts it.
+func (s *Sender) spoolPayload(payload *message.Payload, reliableDestinations []*DestinationSender) {
+	if err := s.spool.Push(payload); err != nil {
+		log.Warnf("Could not spool the logs payload, blocking until a destination is available: %v", err)
+		for !sendToReliable(payload, reliableDestinations) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return
+	}
+	s.outputChan <- payload
+}
+
+// sendToReliable tries once to send the payload to the reliable destinations,
+// it returns true if at least one of them accepted it.
+func sendToReliable(payload *message.Payload, reliableDestinations []*DestinationSender) bool {
+	sent := false
+	for _, destSender := range reliableDestinations {
+		if destSender.Send(payload) {
+			sent = true
+		}
+	}
+	if !sent {
+		return false
+	}
+
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
+			if !destSender.NonBlockingSend(payload) {
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
+			}
+		}
+	}
+	return true
+}
+
+// sendToUnreliable attempts to send the payload to the unreliable destinations.
+func sendToUnreliable(payload *message.Payload, unreliableDestinations []*DestinationSender) {
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+		}
+	}
+}
+
 // Drains the output channel from destinations that don't update the auditor.
 func additionalDestinationsSink(bufferSize int) chan *message.Payload {
 	sink := make(chan *message.Payload, bufferSize)
@@ -299,3 +299,98 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	sender.Stop()
 }
+
+func TestSenderWithSpool(t *testing.T) {
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+
+	respond := make(chan int)
+	server := http.NewTestServerWithOptions(200, 0, true, respond)
+
+	destinations := client.NewDestinations([]client.Destination{server.Destination}, nil)
+
+	spool, err := NewSpool(SpoolConfig{Path: t.TempDir(), MaxSizeInBytes: 1024}, "test")
+	assert.NoError(t, err)
+
+	sender := NewSenderWithSpool(input, output, destinations, 0, spool)
+	sender.Start()
+
+	input <- &message.Payload{Encoded: []byte("a")}
+	<-respond
+	assert.Equal(t, []byte("a"), (<-output).Encoded)
+
+	server.ChangeStatus(500)
+
+	input <- &message.Payload{Encoded: []byte("b")}
+	<-respond // let it respond 500 once
+	<-respond // the destination is retrying now
+
+	// the payloads are spooled and forwarded to the auditor without blocking
+	input <- newMessage([]byte("c"), config.NewLogSource("", &config.LogsConfig{}), "")
+	spooled := <-output
+	assert.Equal(t, []byte("c"), spooled.Encoded)
+	assert.Len(t, spooled.Messages, 1)
+	input <- &message.Payload{Encoded: []byte("d")}
+	assert.Equal(t, []byte("d"), (<-output).Encoded)
+
+	server.ChangeStatus(200)
+	for {
+		if (<-respond) == 200 {
+			break
+		}
+	}
+	assert.Equal(t, []byte("b"), (<-output).Encoded)
+
+	// the spooled payloads are replayed in order, without updating the
+	// auditor a second time
+	<-respond
+	replayed := <-output
+	assert.Equal(t, []byte("c"), replayed.Encoded)
+	assert.Empty(t, replayed.Messages)
+	<-respond
+	assert.Equal(t, []byte("d"), (<-output).Encoded)
+
+	input <- &message.Payload{Encoded: []byte("e")}
+	<-respond
+	assert.Equal(t, []byte("e"), (<-output).Encoded)
+
+	server.Stop()
+	sender.Stop()
+}
+
+func TestSenderReplaySpoolToAllDestinations(t *testing.T) {
+	output := make(chan *message.Payload, 10)
+
+	reliableRespond := make(chan int)
+	reliableServer := http.NewTestServerWithOptions(200, 0, true, reliableRespond)
+	unreliableRespond := make(chan int)
+	unreliableServer := http.NewTestServerWithOptions(200, 0, false, unreliableRespond)
+
+	destinations := client.NewDestinations([]client.Destination{reliableServer.Destination}, []client.Destination{unreliableServer.Destination})
+
+	spool, err := NewSpool(SpoolConfig{Path: t.TempDir(), MaxSizeInBytes: 1024}, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, spool.Push(newSpoolPayload("a")))
+	assert.NoError(t, spool.Push(newSpoolPayload("b")))
+
+	sender := NewSenderWithSpool(nil, output, destinations, 10, spool)
+	reliableDestinations := buildDestinationSenders(destinations.Reliable, output, 10)
+	unreliableDestinations := buildDestinationSenders(destinations.Unreliable, additionalDestinationsSink(10), 10)
+
+	assert.True(t, sender.replaySpool(reliableDestinations, unreliableDestinations))
+	assert.Equal(t, 0, spool.Len())
+
+	// both destinations receive the replayed payloads
+	for i := 0; i < 2; i++ {
+		<-reliableRespond
+		<-unreliableRespond
+	}
+	assert.Equal(t, []byte("a"), (<-output).Encoded)
+	assert.Equal(t, []byte("b"), (<-output).Encoded)
+
+	reliableServer.Stop()
+	unreliableServer.Stop()
+	for _, destSender := range append(reliableDestinations, unreliableDestinations...) {
+		destSender.Stop()
+	}
+}
@@ -0,0 +1,336 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	spoolFileExtension = ".spool"
+	spoolFileVersion   = 1
+	// version, encoding length, unencoded size
+	spoolFileHeaderSize = 1 + 2 + 4
+)
+
+// SpoolConfig holds the settings of the on-disk spool of a sender.
+type SpoolConfig struct {
+	// Path is the directory where the payloads are stored.
+	Path string
+	// MaxSizeInBytes is the maximum disk space used by the payloads, the
+	// oldest payloads are removed to make room for the new ones.
+	MaxSizeInBytes int64
+	// MaxAge is the maximum age of the payloads, older payloads are dropped
+	// instead of being replayed. 0 means no limit.
+	MaxAge time.Duration
+}
+
+// Spool is a bounded on-disk FIFO of payloads. It stores the payloads that
+// could not be sent because all the reliable destinations are failing, so that
+// they are replayed in order when one of them recovers, including after a
+// restart of the agent.
+//
+// Each payload is stored in its own file, named after the time it has been
+// spooled so that the files can be sorted.
+//
+// Spool is not thread-safe.
+type Spool struct {
+	config             SpoolConfig
+	filenames          []string
+	currentSizeInBytes int64
+	lastTimestamp      int64
+	// head caches the oldest payload while it is being replayed.
+	head      *message.Payload
+	telemetry spoolTelemetry
+}
+
+// NewSpool creates a Spool and reloads the payloads spooled by a previous run.
+func NewSpool(config SpoolConfig, telemetryName string) (*Spool, error) {
+	if config.MaxSizeInBytes <= 0 {
+		return nil, fmt.Errorf("invalid spool maximum size: %d", config.MaxSizeInBytes)
+	}
+	if err := os.MkdirAll(config.Path, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		config:    config,
+		telemetry: newSpoolTelemetry(telemetryName),
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Len returns the number of spooled payloads.
+func (s *Spool) Len() int {
+	return len(s.filenames)
+}
+
+// Push stores a payload on the disk, removing the oldest payloads if the
+// spool is full.
+func (s *Spool) Push(payload *message.Payload) error {
+	if len(payload.Encoding) > 0xffff {
+		return fmt.Errorf("invalid payload encoding: %q", payload.Encoding)
+	}
+
+	buf := make([]byte, spoolFileHeaderSize, spoolFileHeaderSize+len(payload.Encoding)+len(payload.Encoded))
+	buf[0] = spoolFileVersion
+	binary.LittleEndian.PutUint16(buf[1:3], uint16(len(payload.Encoding)))
+	binary.LittleEndian.PutUint32(buf[3:7], uint32(payload.UnencodedSize))
+	buf = append(buf, payload.Encoding...)
+	buf = append(buf, payload.Encoded...)
+	size := int64(len(buf))
+
+	if err := s.makeRoomFor(size); err != nil {
+		s.telemetry.addPayloadsDroppedCount(1)
+		return err
+	}
+
+	filename := spoolFilename(s.config.Path, s.nextTimestamp())
+	if err := ioutil.WriteFile(filename, buf, 0600); err != nil {
+		_ = os.Remove(filename)
+		s.telemetry.addPayloadsDroppedCount(1)
+		return err
+	}
+
+	s.currentSizeInBytes += size
+	s.filenames = append(s.filenames, filename)
+	s.telemetry.addSerializeCount()
+	s.telemetry.setFileSize(size)
+	s.updateTelemetry()
+	return nil
+}
+
+// Peek returns the oldest payload without removing it from the spool. The
+// payloads older than the maximum age and the files that can't be read are
+// removed. It returns nil if the spool is empty.
+func (s *Spool) Peek() *message.Payload {
+	for len(s.filenames) > 0 {
+		filename := s.filenames[0]
+
+		if s.config.MaxAge > 0 {
+			if timestamp, err := spoolFileTimestamp(filename); err == nil && time.Since(time.Unix(0, timestamp)) > s.config.MaxAge {
+				log.Warnf("Dropping the logs payload %s spooled more than %s ago", filename, s.config.MaxAge)
+				s.telemetry.addFilesExpiredCount()
+				s.removeOldest()
+				continue
+			}
+		}
+
+		if s.head != nil {
+			return s.head
+		}
+
+		payload, err := s.readFile(filename)
+		if err != nil {
+			log.Errorf("Could not read the spooled logs payload %s: %v", filename, err)
+			s.telemetry.addDeserializeErrorsCount()
+			s.removeOldest()
+			continue
+		}
+		s.telemetry.addDeserializeCount()
+		s.head = payload
+		return payload
+	}
+	return nil
+}
+
+// Pop removes the oldest payload, to be called once the payload returned by
+// Peek has been sent.
+func (s *Spool) Pop() {
+	if len(s.filenames) > 0 {
+		s.removeOldest()
+	}
+}
+
+func (s *Spool) readFile(filename string) (*message.Payload, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < spoolFileHeaderSize {
+		return nil, errors.New("truncated file")
+	}
+	if buf[0] != spoolFileVersion {
+		return nil, fmt.Errorf("unsupported version %d", buf[0])
+	}
+	encodingLen := int(binary.LittleEndian.Uint16(buf[1:3]))
+	unencodedSize := int(binary.LittleEndian.Uint32(buf[3:7]))
+	if len(buf) < spoolFileHeaderSize+encodingLen {
+		return nil, errors.New("truncated file")
+	}
+
+	return &message.Payload{
+		Encoding:      string(buf[spoolFileHeaderSize : spoolFileHeaderSize+encodingLen]),
+		Encoded:       buf[spoolFileHeaderSize+encodingLen:],
+		UnencodedSize: unencodedSize,
+	}, nil
+}
+
+// nextTimestamp returns the current time in nanoseconds, making sure that it
+// is strictly increasing so that the files are sorted in the order they have
+// been written.
+func (s *Spool) nextTimestamp() int64 {
+	timestamp := time.Now().UnixNano()
+	if timestamp <= s.lastTimestamp {
+		timestamp = s.lastTimestamp + 1
+	}
+	s.lastTimestamp = timestamp
+	return timestamp
+}
+
+func (s *Spool) makeRoomFor(size int64) error {
+	if size > s.config.MaxSizeInBytes {
+		return fmt.Errorf("the payload is too big to be spooled. Current:%v Maximum:%v", size, s.config.MaxSizeInBytes)
+	}
+	for len(s.filenames) > 0 && s.currentSizeInBytes+size > s.config.MaxSizeInBytes {
+		log.Warnf("Maximum disk space for the logs spool is reached. Removing %s", s.filenames[0])
+		s.telemetry.addFilesRemovedCount()
+		s.removeOldest()
+	}
+	return nil
+}
+
+func (s *Spool) removeOldest() {
+	filename := s.filenames[0]
+	s.filenames = s.filenames[1:]
+	s.head = nil
+
+	if info, err := os.Stat(filename); err == nil {
+		s.currentSizeInBytes -= info.Size()
+	}
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Could not remove the spooled logs payload %s: %v", filename, err)
+	}
+	s.updateTelemetry()
+}
+
+func (s *Spool) reloadExistingFiles() error {
+	entries, err := ioutil.ReadDir(s.config.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || filepath.Ext(entry.Name()) != spoolFileExtension {
+			continue
+		}
+		timestamp, err := spoolFileTimestamp(entry.Name())
+		if err != nil {
+			continue
+		}
+		if timestamp > s.lastTimestamp {
+			s.lastTimestamp = timestamp
+		}
+		s.currentSizeInBytes += entry.Size()
+		s.filenames = append(s.filenames, filepath.Join(s.config.Path, entry.Name()))
+	}
+	// the names are fixed-width timestamps
+	sort.Strings(s.filenames)
+
+	s.telemetry.setReloadedFilesCount(len(s.filenames))
+	s.updateTelemetry()
+	return nil
+}
+
+// MergeOrphanedSpools moves the payloads spooled in the directories of the
+// pipelines that don't exist anymore, because the number of pipelines has
+// shrunk since the previous run, to the directories of the remaining pipelines
+// so that they are still replayed. It must be called before the spools are
+// created.
+func MergeOrphanedSpools(path string, pipelineCount int) error {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pipelineID, err := strconv.Atoi(entry.Name())
+		if err != nil || pipelineID < pipelineCount {
+			continue
+		}
+		orphanPath := filepath.Join(path, entry.Name())
+		targetPath := filepath.Join(path, strconv.Itoa(pipelineID%pipelineCount))
+		if err := moveSpoolFiles(orphanPath, targetPath); err != nil {
+			return err
+		}
+		if err := os.Remove(orphanPath); err != nil {
+			log.Warnf("Could not remove the logs spool directory %s: %v", orphanPath, err)
+		}
+	}
+	return nil
+}
+
+// moveSpoolFiles moves the spool files of a directory to another one, the
+// files are named after their timestamp so they stay sorted with the payloads
+// already spooled in the target directory.
+func moveSpoolFiles(sourcePath string, targetPath string) error {
+	entries, err := ioutil.ReadDir(sourcePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(targetPath, 0700); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || filepath.Ext(entry.Name()) != spoolFileExtension {
+			continue
+		}
+		timestamp, err := spoolFileTimestamp(entry.Name())
+		if err != nil {
+			continue
+		}
+		target := spoolFilename(targetPath, timestamp)
+		// two pipelines may have spooled a payload at the same nanosecond
+		for fileExists(target) {
+			timestamp++
+			target = spoolFilename(targetPath, timestamp)
+		}
+		if err := os.Rename(filepath.Join(sourcePath, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Spool) updateTelemetry() {
+	s.telemetry.setCurrentSizeInBytes(s.currentSizeInBytes)
+	s.telemetry.setFilesCount(len(s.filenames))
+}
+
+func spoolFilename(path string, timestamp int64) string {
+	return filepath.Join(path, fmt.Sprintf("%020d%s", timestamp, spoolFileExtension))
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Lstat(filename)
+	return err == nil
+}
+
+func spoolFileTimestamp(filename string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(filepath.Base(filename), spoolFileExtension), 10, 64)
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"expvar"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	spoolExpvar                        = expvar.Map{}
+	spoolSerializeCountTelemetry       *telemetry.CounterExpvar
+	spoolDeserializeCountTelemetry     *telemetry.CounterExpvar
+	spoolFileSizeTelemetry             *telemetry.GaugeExpvar
+	spoolCurrentSizeInBytesTelemetry   *telemetry.GaugeExpvar
+	spoolFilesCountTelemetry           *telemetry.GaugeExpvar
+	spoolReloadedFilesCountTelemetry   *telemetry.GaugeExpvar
+	spoolFilesRemovedCountTelemetry    *telemetry.CounterExpvar
+	spoolFilesExpiredCountTelemetry    *telemetry.CounterExpvar
+	spoolPayloadsDroppedCountTelemetry *telemetry.CounterExpvar
+	spoolDeserializeErrorsTelemetry    *telemetry.CounterExpvar
+)
+
+func init() {
+	metrics.LogsExpvars.Set("Spool", &spoolExpvar)
+	pipelineTag := []string{"pipeline"}
+	spoolSerializeCountTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"serialize_count",
+		pipelineTag,
+		"The number of payloads stored on the disk",
+		&spoolExpvar)
+	spoolDeserializeCountTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"deserialize_count",
+		pipelineTag,
+		"The number of payloads read from the disk to be replayed",
+		&spoolExpvar)
+	spoolFileSizeTelemetry = telemetry.NewGaugeExpvar(
+		"logs_spool",
+		"file_size",
+		pipelineTag,
+		"The last file size stored on the disk",
+		&spoolExpvar)
+	spoolCurrentSizeInBytesTelemetry = telemetry.NewSummedGaugeExpvar(
+		"logs_spool",
+		"current_size_in_bytes",
+		pipelineTag,
+		"The number of bytes used to store payloads on the disk",
+		&spoolExpvar)
+	spoolFilesCountTelemetry = telemetry.NewSummedGaugeExpvar(
+		"logs_spool",
+		"files_count",
+		pipelineTag,
+		"The number of files",
+		&spoolExpvar)
+	spoolReloadedFilesCountTelemetry = telemetry.NewSummedGaugeExpvar(
+		"logs_spool",
+		"startup_reloaded_files_count",
+		pipelineTag,
+		"The number of files reloaded from a previous run of the Agent",
+		&spoolExpvar)
+	spoolFilesRemovedCountTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"files_removed_count",
+		pipelineTag,
+		"The number of files removed because the disk limit was reached",
+		&spoolExpvar)
+	spoolFilesExpiredCountTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"files_expired_count",
+		pipelineTag,
+		"The number of files removed because they reached the maximum age",
+		&spoolExpvar)
+	spoolPayloadsDroppedCountTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"payloads_dropped_count",
+		pipelineTag,
+		"The number of payloads that could not be stored on the disk",
+		&spoolExpvar)
+	spoolDeserializeErrorsTelemetry = telemetry.NewCounterExpvar(
+		"logs_spool",
+		"deserialize_errors_count",
+		pipelineTag,
+		"The number of errors during deserialization",
+		&spoolExpvar)
+}
+
+type spoolTelemetry struct {
+	pipelineName string
+}
+
+func newSpoolTelemetry(pipelineName string) spoolTelemetry {
+	return spoolTelemetry{
+		pipelineName: pipelineName,
+	}
+}
+
+func (t spoolTelemetry) addSerializeCount() {
+	spoolSerializeCountTelemetry.Add(1, t.pipelineName)
+}
+
+func (t spoolTelemetry) addDeserializeCount() {
+	spoolDeserializeCountTelemetry.Add(1, t.pipelineName)
+}
+
+func (t spoolTelemetry) setFileSize(count int64) {
+	spoolFileSizeTelemetry.Set(float64(count), t.pipelineName)
+}
+
+func (t spoolTelemetry) setCurrentSizeInBytes(count int64) {
+	spoolCurrentSizeInBytesTelemetry.Set(float64(count), t.pipelineName)
+}
+
+func (t spoolTelemetry) setFilesCount(count int) {
+	spoolFilesCountTelemetry.Set(float64(count), t.pipelineName)
+}
+
+func (t spoolTelemetry) setReloadedFilesCount(count int) {
+	spoolReloadedFilesCountTelemetry.Set(float64(count), t.pipelineName)
+}
+
+func (t spoolTelemetry) addFilesRemovedCount() {
+	spoolFilesRemovedCountTelemetry.Add(1, t.pipelineName)
+}
+
+func (t spoolTelemetry) addFilesExpiredCount() {
+	spoolFilesExpiredCountTelemetry.Add(1, t.pipelineName)
+}
+
+func (t spoolTelemetry) addPayloadsDroppedCount(count int) {
+	spoolPayloadsDroppedCountTelemetry.Add(float64(count), t.pipelineName)
+}
+
+func (t spoolTelemetry) addDeserializeErrorsCount() {
+	spoolDeserializeErrorsTelemetry.Add(1, t.pipelineName)
+}
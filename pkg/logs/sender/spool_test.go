@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"expvar"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestSpool(t *testing.T, path string, maxSize int64, maxAge time.Duration) *Spool {
+	s, err := NewSpool(SpoolConfig{Path: path, MaxSizeInBytes: maxSize, MaxAge: maxAge}, "test")
+	require.NoError(t, err)
+	return s
+}
+
+func newSpoolPayload(content string) *message.Payload {
+	return &message.Payload{
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: 2 * len(content),
+	}
+}
+
+func TestSpoolPushPeekPop(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 1024, 0)
+	assert.Nil(t, s.Peek())
+
+	require.NoError(t, s.Push(newSpoolPayload("foo")))
+	require.NoError(t, s.Push(newSpoolPayload("bar")))
+	assert.Equal(t, 2, s.Len())
+
+	assert.Equal(t, newSpoolPayload("foo"), s.Peek())
+	assert.Equal(t, newSpoolPayload("foo"), s.Peek())
+	s.Pop()
+	assert.Equal(t, newSpoolPayload("bar"), s.Peek())
+	s.Pop()
+	assert.Nil(t, s.Peek())
+	assert.Equal(t, 0, s.Len())
+	assert.EqualValues(t, 0, s.currentSizeInBytes)
+}
+
+func TestSpoolReload(t *testing.T) {
+	path := t.TempDir()
+	s := newTestSpool(t, path, 1024, 0)
+	for _, content := range []string{"1", "2", "3"} {
+		require.NoError(t, s.Push(newSpoolPayload(content)))
+	}
+	s.Pop()
+
+	s = newTestSpool(t, path, 1024, 0)
+	assert.Equal(t, 2, s.Len())
+	require.NoError(t, s.Push(newSpoolPayload("4")))
+
+	for _, content := range []string{"2", "3", "4"} {
+		assert.Equal(t, newSpoolPayload(content), s.Peek())
+		s.Pop()
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	// each file holds a 7 bytes header, the encoding and the content
+	s := newTestSpool(t, t.TempDir(), 3*(spoolFileHeaderSize+4+3), 0)
+	for _, content := range []string{"001", "002", "003", "004"} {
+		require.NoError(t, s.Push(newSpoolPayload(content)))
+	}
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, newSpoolPayload("002"), s.Peek())
+
+	assert.Error(t, s.Push(newSpoolPayload(string(make([]byte, 100)))))
+	assert.Equal(t, 3, s.Len())
+}
+
+func TestSpoolMaxAge(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 1024, time.Hour)
+	require.NoError(t, s.Push(newSpoolPayload("old")))
+	require.NoError(t, s.Push(newSpoolPayload("new")))
+
+	// pretend the first payload was spooled two hours ago
+	s.filenames[0] = filepath.Join(filepath.Dir(s.filenames[0]), "00000000000000000001"+spoolFileExtension)
+	require.NoError(t, ioutil.WriteFile(s.filenames[0], []byte{}, 0600))
+
+	assert.Equal(t, newSpoolPayload("new"), s.Peek())
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSpoolCorruptedFile(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 1024, 0)
+	require.NoError(t, s.Push(newSpoolPayload("foo")))
+	require.NoError(t, s.Push(newSpoolPayload("bar")))
+	require.NoError(t, ioutil.WriteFile(s.filenames[0], []byte{42}, 0600))
+
+	assert.Equal(t, newSpoolPayload("bar"), s.Peek())
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSpoolTelemetryPipelines(t *testing.T) {
+	filesCount := func() int64 {
+		return spoolExpvar.Get("FilesCount").(*expvar.Int).Value()
+	}
+	before := filesCount()
+
+	first, err := NewSpool(SpoolConfig{Path: t.TempDir(), MaxSizeInBytes: 100}, "first_pipeline")
+	require.NoError(t, err)
+	second, err := NewSpool(SpoolConfig{Path: t.TempDir(), MaxSizeInBytes: 100}, "second_pipeline")
+	require.NoError(t, err)
+
+	require.NoError(t, first.Push(newSpoolPayload("foo")))
+	require.NoError(t, second.Push(newSpoolPayload("bar")))
+	require.NoError(t, second.Push(newSpoolPayload("baz")))
+	// the pipelines don't overwrite the values of each other
+	assert.Equal(t, before+3, filesCount())
+
+	first.Pop()
+	assert.Equal(t, before+2, filesCount())
+}
+
+func TestMergeOrphanedSpools(t *testing.T) {
+	path := t.TempDir()
+	for pipelineID, contents := range [][]string{{"0"}, {"1"}, {"2", "4"}, {"3"}} {
+		s := newTestSpool(t, filepath.Join(path, strconv.Itoa(pipelineID)), 1024, 0)
+		for _, content := range contents {
+			require.NoError(t, s.Push(newSpoolPayload(content)))
+		}
+	}
+
+	// the number of pipelines shrinks from 4 to 2
+	require.NoError(t, MergeOrphanedSpools(path, 2))
+
+	entries, err := ioutil.ReadDir(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	first := newTestSpool(t, filepath.Join(path, "0"), 1024, 0)
+	for _, content := range []string{"0", "2", "4"} {
+		assert.Equal(t, newSpoolPayload(content), first.Peek())
+		first.Pop()
+	}
+	assert.Equal(t, 0, first.Len())
+
+	second := newTestSpool(t, filepath.Join(path, "1"), 1024, 0)
+	for _, content := range []string{"1", "3"} {
+		assert.Equal(t, newSpoolPayload(content), second.Peek())
+		second.Pop()
+	}
+	assert.Equal(t, 0, second.Len())
+}
+
+func TestMergeOrphanedSpoolsNoDirectory(t *testing.T) {
+	assert.NoError(t, MergeOrphanedSpools(filepath.Join(t.TempDir(), "missing"), 2))
+}
@@ -39,13 +39,14 @@ func NewBuilder(isRunning *atomic.Bool, endpoints *config.Endpoints, sources *co
 // BuildStatus returns the status of the logs-agent.
 func (b *Builder) BuildStatus() Status {
 	return Status{
-		IsRunning:     b.getIsRunning(),
-		Endpoints:     b.getEndpoints(),
-		Integrations:  b.getIntegrations(),
-		StatusMetrics: b.getMetricsStatus(),
-		Warnings:      b.getWarnings(),
-		Errors:        b.getErrors(),
-		UseHTTP:       b.getUseHTTP(),
+		IsRunning:        b.getIsRunning(),
+		Endpoints:        b.getEndpoints(),
+		Integrations:     b.getIntegrations(),
+		StatusMetrics:    b.getMetricsStatus(),
+		GeneratedMetrics: b.getGeneratedMetrics(),
+		Warnings:         b.getWarnings(),
+		Errors:           b.getErrors(),
+		UseHTTP:          b.getUseHTTP(),
 	}
 }
 
@@ -173,3 +174,22 @@ func (b *Builder) getMetricsStatus() map[string]int64 {
 	metrics["EncodedBytesSent"] = b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value()
 	return metrics
 }
+
+// getGeneratedMetrics returns the number of samples of each metric generated
+// from the logs by the processing rules.
+func (b *Builder) getGeneratedMetrics() map[string]int64 {
+	generated, ok := b.logsExpVars.Get("GeneratedMetricSamples").(*expvar.Map)
+	if !ok {
+		return nil
+	}
+	var metrics map[string]int64
+	generated.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			if metrics == nil {
+				metrics = make(map[string]int64)
+			}
+			metrics[kv.Key] = v.Value()
+		}
+	})
+	return metrics
+}
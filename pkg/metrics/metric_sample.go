@@ -89,6 +89,7 @@ type MetricSample struct {
 	SampleRate       float64
 	Timestamp        float64
 	FlushFirstValue  bool
+	NoAggregation    bool // the sample is sent as is at its Timestamp instead of being aggregated
 	OriginFromUDS    string
 	OriginFromClient string
 	Cardinality      string
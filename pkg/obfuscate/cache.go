@@ -20,6 +20,8 @@ type measuredCache struct {
 	// close allows sending shutdown notification.
 	close  chan struct{}
 	statsd StatsClient
+	// name is used in the names of the metrics, e.g. "sql".
+	name string
 }
 
 // Close gracefully closes the cache when active.
@@ -41,8 +43,8 @@ func (c *measuredCache) statsLoop() {
 	for {
 		select {
 		case <-tick.C:
-			c.statsd.Gauge("datadog.trace_agent.ofuscation.sql_cache.hits", float64(mx.Hits()), nil, 1)     //nolint:errcheck
-			c.statsd.Gauge("datadog.trace_agent.ofuscation.sql_cache.misses", float64(mx.Misses()), nil, 1) //nolint:errcheck
+			c.statsd.Gauge("datadog.trace_agent.ofuscation."+c.name+"_cache.hits", float64(mx.Hits()), nil, 1)     //nolint:errcheck
+			c.statsd.Gauge("datadog.trace_agent.ofuscation."+c.name+"_cache.misses", float64(mx.Misses()), nil, 1) //nolint:errcheck
 		case <-c.close:
 			c.Cache.Close()
 			return
@@ -51,6 +53,8 @@ func (c *measuredCache) statsLoop() {
 }
 
 type cacheOptions struct {
+	// Name is used in the names of the metrics of the cache. It defaults to "sql".
+	Name   string
 	On     bool
 	Statsd StatsClient
 }
@@ -84,6 +88,10 @@ func newMeasuredCache(opts cacheOptions) *measuredCache {
 		close:  make(chan struct{}),
 		statsd: opts.Statsd,
 		Cache:  cache,
+		name:   opts.Name,
+	}
+	if c.name == "" {
+		c.name = "sql"
 	}
 	go c.statsLoop()
 	return &c
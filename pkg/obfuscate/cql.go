@@ -0,0 +1,303 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cqlTokenKind specifies the kind of a CQL token.
+type cqlTokenKind int
+
+const (
+	// cqlIdentifier is a keyword, a name or a quoted identifier.
+	cqlIdentifier cqlTokenKind = iota
+	// cqlLiteral is a string, number, blob, UUID, boolean, NULL or a bind marker.
+	cqlLiteral
+	// cqlPunctuation is an operator or a punctuation character.
+	cqlPunctuation
+)
+
+type cqlToken struct {
+	kind cqlTokenKind
+	text string
+}
+
+// cqlObfuscated is the token replacing literals in obfuscated CQL queries.
+var cqlObfuscated = cqlToken{kind: cqlLiteral, text: "?"}
+
+// ObfuscateCQLString quantizes and obfuscates the given Cassandra CQL query. Literals, including
+// collection literals, and bind markers are replaced with "?", parenthesized lists of them are
+// collapsed into "( ? )", comments are removed and whitespace is normalized.
+func (o *Obfuscator) ObfuscateCQLString(in string) (string, error) {
+	if v, ok := o.cqlCache.Get(in); ok {
+		return v.(string), nil
+	}
+	out, err := obfuscateCQL(in)
+	if err != nil {
+		return "", err
+	}
+	o.cqlCache.Set(in, out, int64(len(out)))
+	return out, nil
+}
+
+func obfuscateCQL(in string) (string, error) {
+	tokens, err := tokenizeCQL(in)
+	if err != nil {
+		return "", err
+	}
+	out := make([]cqlToken, 0, len(tokens))
+	var depth []string // the opened brackets
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == cqlLiteral:
+			out = append(out, cqlObfuscated)
+		case t.kind != cqlPunctuation:
+			out = append(out, t)
+		case t.text == "{" || t.text == "[" && !cqlIsSubscript(out):
+			// collection or user-defined type literal
+			end, err := cqlClosingBracket(tokens, i)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, cqlObfuscated)
+			i = end
+		case t.text == "(" || t.text == "[":
+			depth = append(depth, t.text)
+			out = append(out, t)
+		case t.text == ")" || t.text == "]" || t.text == "}":
+			if len(depth) == 0 || cqlBrackets[depth[len(depth)-1]] != t.text {
+				return "", fmt.Errorf("unexpected %q", t.text)
+			}
+			depth = depth[:len(depth)-1]
+			out = append(out, t)
+		default:
+			out = append(out, t)
+		}
+	}
+	if len(depth) > 0 {
+		return "", fmt.Errorf("unclosed %q", depth[len(depth)-1])
+	}
+	for len(out) > 0 && out[len(out)-1].text == ";" {
+		out = out[:len(out)-1]
+	}
+	return formatCQL(collapseCQLLists(out)), nil
+}
+
+// cqlBrackets maps opening brackets to their closing counterpart.
+var cqlBrackets = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// cqlIsSubscript reports whether a "[" following the given tokens selects an element of a
+// collection (e.g. "m['key']"), as opposed to starting a list literal.
+func cqlIsSubscript(prev []cqlToken) bool {
+	if len(prev) == 0 {
+		return false
+	}
+	last := prev[len(prev)-1]
+	return last.kind == cqlIdentifier || last.text == ")" || last.text == "]"
+}
+
+// cqlClosingBracket returns the index of the token closing the bracket opened at tokens[start].
+func cqlClosingBracket(tokens []cqlToken, start int) (int, error) {
+	var stack []string
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind != cqlPunctuation {
+			continue
+		}
+		if closing, ok := cqlBrackets[t.text]; ok {
+			stack = append(stack, closing)
+			continue
+		}
+		if t.text == ")" || t.text == "]" || t.text == "}" {
+			if stack[len(stack)-1] != t.text {
+				return 0, fmt.Errorf("unexpected %q", t.text)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unclosed %q", tokens[start].text)
+}
+
+// collapseCQLLists replaces parenthesized lists made only of obfuscated literals, such as
+// "( ?, ?, ? )", with "( ? )".
+func collapseCQLLists(tokens []cqlToken) []cqlToken {
+	out := tokens[:0]
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		if tokens[i].text != "(" {
+			continue
+		}
+		j := i + 1
+		for j+1 < len(tokens) && tokens[j] == cqlObfuscated && tokens[j+1].text == "," {
+			j += 2
+		}
+		if j+1 < len(tokens) && tokens[j] == cqlObfuscated && tokens[j+1].text == ")" {
+			out = append(out, cqlObfuscated, tokens[j+1])
+			i = j + 1
+		}
+	}
+	return out
+}
+
+// formatCQL joins the tokens with single spaces, except around dots and before commas and semicolons.
+func formatCQL(tokens []cqlToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && t.text != "," && t.text != ";" && t.text != "." && tokens[i-1].text != "." {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// tokenizeCQL splits the given CQL query into tokens, leaving out whitespace and comments.
+func tokenizeCQL(in string) ([]cqlToken, error) {
+	var tokens []cqlToken
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(in[i:], "--") || strings.HasPrefix(in[i:], "//"):
+			end := strings.IndexByte(in[i:], '\n')
+			if end == -1 {
+				end = len(in) - i
+			}
+			i += end
+		case strings.HasPrefix(in[i:], "/*"):
+			end := strings.Index(in[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment at position %d", i)
+			}
+			i += end + 4
+		case c == '\'' || c == '"':
+			end, err := cqlQuotedEnd(in, i)
+			if err != nil {
+				return nil, err
+			}
+			kind := cqlLiteral
+			if c == '"' {
+				// quoted identifier
+				kind = cqlIdentifier
+			}
+			tokens = append(tokens, cqlToken{kind: kind, text: in[i:end]})
+			i = end
+		case strings.HasPrefix(in[i:], "$$"):
+			end := strings.Index(in[i+2:], "$$")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, cqlToken{kind: cqlLiteral, text: in[i : i+end+4]})
+			i += end + 4
+		case isCQLUUID(in[i:]):
+			tokens = append(tokens, cqlToken{kind: cqlLiteral, text: in[i : i+36]})
+			i += 36
+		case isDigit(rune(c)) || c == '-' && i+1 < len(in) && isDigit(rune(in[i+1])) && !cqlIsOperand(tokens):
+			end := i + 1
+			for end < len(in) && (isCQLIdentifierChar(in[end]) || in[end] == '.' ||
+				(in[end] == '-' || in[end] == '+') && (in[end-1] == 'e' || in[end-1] == 'E')) {
+				end++
+			}
+			tokens = append(tokens, cqlToken{kind: cqlLiteral, text: in[i:end]})
+			i = end
+		case isCQLIdentifierChar(c):
+			end := i + 1
+			for end < len(in) && isCQLIdentifierChar(in[end]) {
+				end++
+			}
+			word := in[i:end]
+			kind := cqlIdentifier
+			switch strings.ToLower(word) {
+			case "true", "false", "null", "nan", "infinity":
+				kind = cqlLiteral
+			}
+			tokens = append(tokens, cqlToken{kind: kind, text: word})
+			i = end
+		case c == ':' && i+1 < len(in) && isCQLIdentifierChar(in[i+1]):
+			// named bind marker
+			end := i + 1
+			for end < len(in) && isCQLIdentifierChar(in[end]) {
+				end++
+			}
+			tokens = append(tokens, cqlToken{kind: cqlLiteral, text: in[i:end]})
+			i = end
+		case c == '?':
+			tokens = append(tokens, cqlToken{kind: cqlLiteral, text: "?"})
+			i++
+		case strings.HasPrefix(in[i:], "<=") || strings.HasPrefix(in[i:], ">=") || strings.HasPrefix(in[i:], "!="):
+			tokens = append(tokens, cqlToken{kind: cqlPunctuation, text: in[i : i+2]})
+			i += 2
+		case strings.IndexByte("()[]{},;.=<>+-*/%:", c) != -1:
+			tokens = append(tokens, cqlToken{kind: cqlPunctuation, text: in[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// cqlQuotedEnd returns the position following the string or quoted identifier starting at
+// in[start]. The quote is escaped by doubling it.
+func cqlQuotedEnd(in string, start int) (int, error) {
+	quote := in[start]
+	for i := start + 1; i < len(in); i++ {
+		if in[i] != quote {
+			continue
+		}
+		if i+1 < len(in) && in[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1, nil
+	}
+	return 0, fmt.Errorf("unterminated quote at position %d", start)
+}
+
+// cqlIsOperand reports whether the last of the given tokens is an operand, making a following
+// "-" a subtraction instead of the sign of a number.
+func cqlIsOperand(prev []cqlToken) bool {
+	if len(prev) == 0 {
+		return false
+	}
+	last := prev[len(prev)-1]
+	return last.kind != cqlPunctuation || last.text == ")" || last.text == "]"
+}
+
+func isCQLIdentifierChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// isCQLUUID reports whether s starts with an unquoted UUID constant.
+func isCQLUUID(s string) bool {
+	if len(s) < 36 || len(s) > 36 && isCQLIdentifierChar(s[36]) {
+		return false
+	}
+	for i := 0; i < 36; i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(s[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
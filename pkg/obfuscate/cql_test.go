@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateCQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"SELECT * FROM users WHERE id = 42",
+			"SELECT * FROM users WHERE id = ?",
+		},
+		{
+			"SELECT name, email FROM ks.users WHERE name = 'O''Brien' AND age > -3 ALLOW FILTERING;",
+			"SELECT name, email FROM ks.users WHERE name = ? AND age > ? ALLOW FILTERING",
+		},
+		{
+			"INSERT INTO users (id, name, tags, attrs) VALUES (123e4567-e89b-12d3-a456-426614174000, 'bob', {'a', 'b'}, {'k': [1, 2]}) USING TTL 86400 AND TIMESTAMP 1656000000",
+			"INSERT INTO users ( id, name, tags, attrs ) VALUES ( ? ) USING TTL ? AND TIMESTAMP ?",
+		},
+		{
+			"UPDATE users SET emails = emails + ['x@example.com'], counter = counter - 1 WHERE id IN (1, 2, 3)",
+			"UPDATE users SET emails = emails + ?, counter = counter - ? WHERE id IN ( ? )",
+		},
+		{
+			"SELECT m['key'], \"Quoted\"\"Name\" FROM t WHERE blob = 0xCAFE AND ok = true AND d = 1h30m AND f = 1.5e-3 AND n IS NOT NULL",
+			"SELECT m [ ? ], \"Quoted\"\"Name\" FROM t WHERE blob = ? AND ok = ? AND d = ? AND f = ? AND n IS NOT ?",
+		},
+		{
+			"SELECT * FROM t WHERE a = ? AND b = :name AND c IN ?",
+			"SELECT * FROM t WHERE a = ? AND b = ? AND c IN ?",
+		},
+		{
+			"-- leading comment\nSELECT /* inline */ *\n\tFROM   t // trailing",
+			"SELECT * FROM t",
+		},
+		{
+			"BEGIN BATCH INSERT INTO t (a) VALUES ($$multi\nline$$); DELETE FROM t WHERE a = 1; APPLY BATCH;",
+			"BEGIN BATCH INSERT INTO t ( a ) VALUES ( ? ); DELETE FROM t WHERE a = ?; APPLY BATCH",
+		},
+		{
+			"SELECT now(), token(id) FROM t WHERE token(id) > token(5)",
+			"SELECT now ( ), token ( id ) FROM t WHERE token ( id ) > token ( ? )",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			out, err := NewObfuscator(Config{}).ObfuscateCQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateCQLErrors(t *testing.T) {
+	for _, in := range []string{
+		"SELECT * FROM t WHERE a = 'unterminated",
+		"SELECT * FROM t /* unterminated",
+		"SELECT * FROM t WHERE a IN (1, 2",
+		"SELECT * FROM t WHERE a = {1, 2)",
+		"SELECT * FROM t WHERE a = 1)",
+		"SELECT * FROM t WHERE a = #",
+	} {
+		t.Run("", func(t *testing.T) {
+			_, err := NewObfuscator(Config{}).ObfuscateCQLString(in)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// graphQLTokenKind specifies the kind of a GraphQL token.
+type graphQLTokenKind int
+
+const (
+	// graphQLName is a name, such as a keyword, a field, a type or an enum value.
+	graphQLName graphQLTokenKind = iota
+	// graphQLValue is a string, block string or number.
+	graphQLValue
+	// graphQLPunctuator is a punctuator, such as "{", "$" or "...".
+	graphQLPunctuator
+)
+
+type graphQLToken struct {
+	kind graphQLTokenKind
+	text string
+}
+
+// ObfuscateGraphQLString quantizes and obfuscates the given GraphQL query. The values of the
+// arguments and the default values of the variables are replaced with "?", except for variable
+// references and enum values. Comments and commas are removed and whitespace is normalized.
+func (o *Obfuscator) ObfuscateGraphQLString(in string) (string, error) {
+	if v, ok := o.graphQLCache.Get(in); ok {
+		return v.(string), nil
+	}
+	out, err := obfuscateGraphQL(in)
+	if err != nil {
+		return "", err
+	}
+	o.graphQLCache.Set(in, out, int64(len(out)))
+	return out, nil
+}
+
+// graphQLObfuscator holds the state of the obfuscation of a GraphQL query.
+type graphQLObfuscator struct {
+	tokens []graphQLToken
+	pos    int
+	out    []string
+}
+
+func obfuscateGraphQL(in string) (string, error) {
+	tokens, err := tokenizeGraphQL(in)
+	if err != nil {
+		return "", err
+	}
+	g := graphQLObfuscator{tokens: tokens, out: make([]string, 0, len(tokens))}
+	if err := g.obfuscate(); err != nil {
+		return "", err
+	}
+	return formatGraphQL(g.out), nil
+}
+
+// obfuscate walks through the document, obfuscating the values found in arguments and variable
+// definitions.
+func (g *graphQLObfuscator) obfuscate() error {
+	const (
+		selectionSet = "{"
+		arguments    = "("
+		variables    = "(variables"
+		listType     = "["
+	)
+	var stack []string
+	selections := 0 // number of selection sets in stack
+	for g.pos < len(g.tokens) {
+		t := g.tokens[g.pos]
+		var top string
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch {
+		case t.text == "{":
+			stack = append(stack, selectionSet)
+			selections++
+		case t.text == "(":
+			// parentheses outside of selection sets, other than directive arguments, hold
+			// the variable definitions of an operation.
+			if selections == 0 && !(len(g.out) >= 2 && g.out[len(g.out)-2] == "@") {
+				stack = append(stack, variables)
+			} else {
+				stack = append(stack, arguments)
+			}
+		case t.text == "[" && top == variables || t.text == "[" && top == listType:
+			stack = append(stack, listType)
+		case t.text == "}" || t.text == ")" || t.text == "]":
+			if top == "" || top[:1] != graphQLClosing[t.text] {
+				return fmt.Errorf("unexpected %q", t.text)
+			}
+			if top == selectionSet {
+				selections--
+			}
+			stack = stack[:len(stack)-1]
+		case top == arguments && t.kind == graphQLName && g.peek(1).text == ":":
+			g.out = append(g.out, t.text, ":")
+			g.pos += 2
+			if err := g.value(); err != nil {
+				return err
+			}
+			continue
+		case top == variables && t.text == "=":
+			g.out = append(g.out, t.text)
+			g.pos++
+			if err := g.value(); err != nil {
+				return err
+			}
+			continue
+		case t.kind == graphQLValue:
+			return fmt.Errorf("unexpected value %s", t.text)
+		}
+		g.out = append(g.out, t.text)
+		g.pos++
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1][:1])
+	}
+	return nil
+}
+
+// graphQLClosing maps closing brackets to their opening counterpart.
+var graphQLClosing = map[string]string{"}": "{", ")": "(", "]": "["}
+
+// peek returns the token n positions after the current one, or an empty token.
+func (g *graphQLObfuscator) peek(n int) graphQLToken {
+	if g.pos+n < len(g.tokens) {
+		return g.tokens[g.pos+n]
+	}
+	return graphQLToken{}
+}
+
+// value obfuscates the value starting at the current token, leaving g.pos on the token following it.
+func (g *graphQLObfuscator) value() error {
+	if g.pos >= len(g.tokens) {
+		return fmt.Errorf("missing value")
+	}
+	t := g.tokens[g.pos]
+	switch {
+	case t.text == "$":
+		// variable reference
+		if g.peek(1).kind != graphQLName {
+			return fmt.Errorf("invalid variable")
+		}
+		g.out = append(g.out, "$", g.peek(1).text)
+		g.pos += 2
+	case t.kind == graphQLValue:
+		g.out = append(g.out, "?")
+		g.pos++
+	case t.kind == graphQLName:
+		switch t.text {
+		case "true", "false", "null":
+			g.out = append(g.out, "?")
+		default:
+			// enum value
+			g.out = append(g.out, t.text)
+		}
+		g.pos++
+	case t.text == "[":
+		// lists are obfuscated as a whole
+		depth := 0
+		for ; g.pos < len(g.tokens); g.pos++ {
+			switch g.tokens[g.pos].text {
+			case "[":
+				depth++
+			case "]":
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if depth > 0 {
+			return fmt.Errorf("unclosed %q", "[")
+		}
+		g.out = append(g.out, "?")
+		g.pos++
+	case t.text == "{":
+		// input object
+		g.out = append(g.out, "{")
+		g.pos++
+		for g.pos < len(g.tokens) && g.tokens[g.pos].text != "}" {
+			if g.tokens[g.pos].kind != graphQLName || g.peek(1).text != ":" {
+				return fmt.Errorf("invalid input object field")
+			}
+			g.out = append(g.out, g.tokens[g.pos].text, ":")
+			g.pos += 2
+			if err := g.value(); err != nil {
+				return err
+			}
+		}
+		if g.pos >= len(g.tokens) {
+			return fmt.Errorf("unclosed %q", "{")
+		}
+		g.out = append(g.out, "}")
+		g.pos++
+	default:
+		return fmt.Errorf("unexpected %q", t.text)
+	}
+	return nil
+}
+
+// formatGraphQL joins the tokens with single spaces, except inside parentheses and square
+// brackets, before the "(", ":" and "!" punctuators and after the "$" and "@" ones.
+func formatGraphQL(tokens []string) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			switch prev := tokens[i-1]; {
+			case t == "(" || t == ")" || t == "]" || t == ":" || t == "!":
+			case prev == "(" || prev == "[" || prev == "$" || prev == "@":
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// tokenizeGraphQL splits the given GraphQL document into tokens, leaving out whitespace,
+// commas and comments.
+func tokenizeGraphQL(in string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			end := strings.IndexByte(in[i:], '\n')
+			if end == -1 {
+				end = len(in) - i
+			}
+			i += end
+		case strings.HasPrefix(in[i:], `"""`):
+			end := i + 3
+			for ; end < len(in) && !strings.HasPrefix(in[end:], `"""`); end++ {
+				if strings.HasPrefix(in[end:], `\"""`) {
+					end += 3
+				}
+			}
+			if end >= len(in) {
+				return nil, fmt.Errorf("unterminated block string at position %d", i)
+			}
+			tokens = append(tokens, graphQLToken{kind: graphQLValue, text: in[i : end+3]})
+			i = end + 3
+		case c == '"':
+			end := i + 1
+			for ; end < len(in) && in[end] != '"'; end++ {
+				if in[end] == '\\' {
+					end++
+				} else if in[end] == '\n' {
+					break
+				}
+			}
+			if end >= len(in) || in[end] != '"' {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, graphQLToken{kind: graphQLValue, text: in[i : end+1]})
+			i = end + 1
+		case isDigit(rune(c)) || c == '-':
+			end := i + 1
+			for end < len(in) && (isGraphQLNameChar(in[end]) || in[end] == '.' ||
+				(in[end] == '-' || in[end] == '+') && (in[end-1] == 'e' || in[end-1] == 'E')) {
+				end++
+			}
+			tokens = append(tokens, graphQLToken{kind: graphQLValue, text: in[i:end]})
+			i = end
+		case isGraphQLNameChar(c):
+			end := i + 1
+			for end < len(in) && isGraphQLNameChar(in[end]) {
+				end++
+			}
+			tokens = append(tokens, graphQLToken{kind: graphQLName, text: in[i:end]})
+			i = end
+		case strings.HasPrefix(in[i:], "..."):
+			tokens = append(tokens, graphQLToken{kind: graphQLPunctuator, text: "..."})
+			i += 3
+		case strings.IndexByte("!$&()=:@[]{}|", c) != -1:
+			tokens = append(tokens, graphQLToken{kind: graphQLPunctuator, text: in[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
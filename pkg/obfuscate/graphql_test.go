@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"query GetUser",
+			"query GetUser",
+		},
+		{
+			`{ user(id: 42) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			"query GetUser($id: ID!, $first: Int = 10, $ids: [ID!]! = [\"a\"]) {\n  user(id: $id) {\n    # the friends\n    friends(first: $first, orderBy: NAME_ASC) { name }\n  }\n}",
+			"query GetUser($id: ID! $first: Int = ? $ids: [ID!]! = ?) { user(id: $id) { friends(first: $first orderBy: NAME_ASC) { name } } }",
+		},
+		{
+			`mutation { createUser(input: {name: "Jane", email: "jane@example.com", admin: false, tags: ["a", "b"], address: {zip: 75001, city: $city}}) { id } }`,
+			`mutation { createUser(input: { name: ? email: ? admin: ? tags: ? address: { zip: ? city: $city } }) { id } }`,
+		},
+		{
+			`query Search @cached(ttl: 60) { search(text: """block "quoted" string""", limit: -1.5e3) { ... on User { name } ...PostFields } }`,
+			`query Search @cached(ttl: ?) { search(text: ? limit: ?) { ... on User { name } ... PostFields } }`,
+		},
+		{
+			`{ me: user(id: "x") @include(if: true) { name } }`,
+			`{ me: user(id: ?) @include(if: ?) { name } }`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			out, err := NewObfuscator(Config{}).ObfuscateGraphQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateGraphQLErrors(t *testing.T) {
+	for _, in := range []string{
+		`{ user(id: "unterminated) { name } }`,
+		`{ user(id: 1) { name }`,
+		`{ user(id: 1)) { name } }`,
+		`{ user(id: [1, 2) { name } }`,
+		`{ user(id: ) { name } }`,
+		`{ user(id: 1) ~ }`,
+	} {
+		t.Run("", func(t *testing.T) {
+			_, err := NewObfuscator(Config{}).ObfuscateGraphQLString(in)
+			assert.Error(t, err)
+		})
+	}
+}
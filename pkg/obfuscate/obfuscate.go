@@ -32,7 +32,10 @@ type Obfuscator struct {
 	sqlLiteralEscapes *atomic.Bool
 	// queryCache keeps a cache of already obfuscated queries.
 	queryCache *measuredCache
-	log        Logger
+	// cqlCache and graphQLCache keep caches of already obfuscated CQL and GraphQL queries.
+	cqlCache     *measuredCache
+	graphQLCache *measuredCache
+	log          Logger
 }
 
 // Logger is able to log certain log messages.
@@ -82,6 +85,12 @@ type Config struct {
 	// HTTP holds the obfuscation settings for HTTP URLs.
 	HTTP HTTPConfig
 
+	// CQL holds the obfuscation configuration for Cassandra CQL queries.
+	CQL CQLConfig
+
+	// GraphQL holds the obfuscation configuration for GraphQL queries.
+	GraphQL GraphQLConfig
+
 	// Statsd specifies the statsd client to use for reporting metrics.
 	Statsd StatsClient
 
@@ -152,6 +161,18 @@ type HTTPConfig struct {
 	RemovePathDigits bool
 }
 
+// CQLConfig holds the configuration for obfuscating Cassandra CQL queries.
+type CQLConfig struct {
+	// Cache reports whether the obfuscator should use a LRU look-up cache for CQL obfuscations.
+	Cache bool
+}
+
+// GraphQLConfig holds the configuration for obfuscating GraphQL queries.
+type GraphQLConfig struct {
+	// Cache reports whether the obfuscator should use a LRU look-up cache for GraphQL obfuscations.
+	Cache bool
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -175,6 +196,8 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	o := Obfuscator{
 		opts:              &cfg,
 		queryCache:        newMeasuredCache(cacheOptions{On: cfg.SQL.Cache, Statsd: cfg.Statsd}),
+		cqlCache:          newMeasuredCache(cacheOptions{Name: "cql", On: cfg.CQL.Cache, Statsd: cfg.Statsd}),
+		graphQLCache:      newMeasuredCache(cacheOptions{Name: "graphql", On: cfg.GraphQL.Cache, Statsd: cfg.Statsd}),
 		sqlLiteralEscapes: atomic.NewBool(false),
 	}
 	if cfg.ES.Enabled {
@@ -198,6 +221,8 @@ func NewObfuscator(cfg Config) *Obfuscator {
 // Stop cleans up after a finished Obfuscator.
 func (o *Obfuscator) Stop() {
 	o.queryCache.Close()
+	o.cqlCache.Close()
+	o.graphQLCache.Close()
 }
 
 // compactWhitespaces compacts all whitespaces in t.
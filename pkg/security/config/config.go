@@ -118,6 +118,18 @@ type Config struct {
 	// ActivityDumpCgroupOutputDirectory defines the output directory for the cgroup activity dumps and graphs. Leave
 	// this field empty to prevent writing any output to disk.
 	ActivityDumpCgroupOutputDirectory string
+	// ActivityDumpLocalFormats defines the formats of the cgroup activity dumps written to the output directory
+	ActivityDumpLocalFormats []string
+	// ActivityDumpLocalCompression defines if the cgroup activity dumps written to the output directory should be
+	// compressed
+	ActivityDumpLocalCompression bool
+	// ActivityDumpRemoteEndpoint defines the HTTP endpoint to which the cgroup activity dumps are sent. Leave this field
+	// empty to disable the remote storage.
+	ActivityDumpRemoteEndpoint string
+	// ActivityDumpRemoteFormats defines the formats of the cgroup activity dumps sent to the remote endpoint
+	ActivityDumpRemoteFormats []string
+	// ActivityDumpRemoteCompression defines if the cgroup activity dumps sent to the remote endpoint should be compressed
+	ActivityDumpRemoteCompression bool
 	// RuntimeMonitor defines if the runtime monitor should be enabled
 	RuntimeMonitor bool
 	// NetworkEnabled defines if the network probes should be activated
@@ -134,6 +146,34 @@ type Config struct {
 	RuntimeCompiledConstantsIsSet bool
 	// EventMonitoring enabled event monitoring
 	EventMonitoring bool
+	// SecurityProfileEnabled defines if the security profiles should be loaded to detect anomalies
+	SecurityProfileEnabled bool
+	// SecurityProfileDir defines the folder in which the security profiles are located, and written when updated
+	SecurityProfileDir string
+	// SecurityProfileLearningPeriod defines how long a new security profile learns the behaviour of its workloads
+	// before reporting anomalies
+	SecurityProfileLearningPeriod time.Duration
+	// SecurityProfileMergePeriod defines the period at which the anomalies of the security profiles are reset, and
+	// merged into them if SecurityProfileMergeAnomalies is set
+	SecurityProfileMergePeriod time.Duration
+	// SecurityProfileMergeAnomalies defines if the anomalies which didn't trigger any rule should be merged into the
+	// security profiles, and no longer reported
+	SecurityProfileMergeAnomalies bool
+	// SecurityProfileMaxEntries defines the maximum number of processes, files and DNS names of a security profile,
+	// the behaviour observed beyond it is dropped. 0 means no limit.
+	SecurityProfileMaxEntries int
+	// SecurityProfileMaxPendingAnomalies defines the maximum number of anomalies of a security profile waiting to be
+	// merged, the anomalies observed beyond it are dropped. 0 means no limit.
+	SecurityProfileMaxPendingAnomalies int
+	// EnforcementEnabled defines if the kill actions of the rules should be performed. It is a global kill switch, the
+	// rules allowed to enforce are listed in EnforcementRules.
+	EnforcementEnabled bool
+	// EnforcementRules is the list of the IDs of the rules whose kill actions are performed
+	EnforcementRules []string
+	// EnforcementRate defines the rate, per second, at which kill actions can be performed
+	EnforcementRate int
+	// EnforcementBurst defines the maximum burst of kill actions that can be performed
+	EnforcementBurst int
 }
 
 // IsEnabled returns true if any feature is enabled. Has to be applied in config package too
@@ -195,9 +235,25 @@ func NewConfig(cfg *config.Config) (*Config, error) {
 		ActivityDumpCgroupDumpTimeout:      time.Duration(aconfig.Datadog.GetInt("runtime_security_config.activity_dump.cgroup_dump_timeout")) * time.Minute,
 		ActivityDumpCgroupWaitListSize:     aconfig.Datadog.GetInt("runtime_security_config.activity_dump.cgroup_wait_list_size"),
 		ActivityDumpCgroupOutputDirectory:  aconfig.Datadog.GetString("runtime_security_config.activity_dump.cgroup_output_directory"),
+		ActivityDumpLocalFormats:           aconfig.Datadog.GetStringSlice("runtime_security_config.activity_dump.local_storage.formats"),
+		ActivityDumpLocalCompression:       aconfig.Datadog.GetBool("runtime_security_config.activity_dump.local_storage.compression"),
+		ActivityDumpRemoteEndpoint:         aconfig.Datadog.GetString("runtime_security_config.activity_dump.remote_storage.endpoint"),
+		ActivityDumpRemoteFormats:          aconfig.Datadog.GetStringSlice("runtime_security_config.activity_dump.remote_storage.formats"),
+		ActivityDumpRemoteCompression:      aconfig.Datadog.GetBool("runtime_security_config.activity_dump.remote_storage.compression"),
 		RuntimeMonitor:                     aconfig.Datadog.GetBool("runtime_security_config.runtime_monitor.enabled"),
 		NetworkEnabled:                     aconfig.Datadog.GetBool("runtime_security_config.network.enabled"),
 		NetworkLazyInterfacePrefixes:       aconfig.Datadog.GetStringSlice("runtime_security_config.network.lazy_interface_prefixes"),
+		SecurityProfileEnabled:             aconfig.Datadog.GetBool("runtime_security_config.security_profile.enabled"),
+		SecurityProfileDir:                 aconfig.Datadog.GetString("runtime_security_config.security_profile.dir"),
+		SecurityProfileLearningPeriod:      time.Duration(aconfig.Datadog.GetInt("runtime_security_config.security_profile.learning_period")) * time.Minute,
+		SecurityProfileMergePeriod:         time.Duration(aconfig.Datadog.GetInt("runtime_security_config.security_profile.merge_period")) * time.Minute,
+		SecurityProfileMergeAnomalies:      aconfig.Datadog.GetBool("runtime_security_config.security_profile.merge_anomalies"),
+		SecurityProfileMaxEntries:          aconfig.Datadog.GetInt("runtime_security_config.security_profile.max_entries"),
+		SecurityProfileMaxPendingAnomalies: aconfig.Datadog.GetInt("runtime_security_config.security_profile.max_pending_anomalies"),
+		EnforcementEnabled:                 aconfig.Datadog.GetBool("runtime_security_config.enforcement.enabled"),
+		EnforcementRules:                   aconfig.Datadog.GetStringSlice("runtime_security_config.enforcement.rules"),
+		EnforcementRate:                    aconfig.Datadog.GetInt("runtime_security_config.enforcement.rate_limiter.rate"),
+		EnforcementBurst:                   aconfig.Datadog.GetInt("runtime_security_config.enforcement.rate_limiter.burst"),
 		// runtime compilation
 		RuntimeCompilationEnabled:       aconfig.Datadog.GetBool("runtime_security_config.runtime_compilation.enabled"),
 		RuntimeCompiledConstantsEnabled: aconfig.Datadog.GetBool("runtime_security_config.runtime_compilation.compiled_constants_enabled"),
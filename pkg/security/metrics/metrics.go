@@ -40,6 +40,12 @@ var (
 	// Tags: rule_id
 	MetricRateLimiterAllow = newRuntimeMetric(".rules.rate_limiter.allow")
 
+	// Enforcement metrics
+
+	// MetricEnforcementActions is the name of the metric used to count the kill actions of the rules, performed or not
+	// Tags: rule_id, status
+	MetricEnforcementActions = newRuntimeMetric(".enforcement.actions")
+
 	// Syscall monitoring metrics
 
 	// MetricSyscalls is the name of the metric used to count each syscall executed on the host
@@ -148,6 +154,20 @@ var (
 	// Tags: -
 	MetricActivityDumpActiveDumps = newRuntimeMetric(".activity_dump.active_dumps")
 
+	// Security profile metrics
+
+	// MetricSecurityProfileProfiles is the name of the metric used to report the number of loaded security profiles
+	// Tags: state
+	MetricSecurityProfileProfiles = newRuntimeMetric(".security_profile.profiles")
+	// MetricSecurityProfileAnomalies is the name of the metric used to count the anomalies detected with the security
+	// profiles
+	// Tags: anomaly_kind
+	MetricSecurityProfileAnomalies = newRuntimeMetric(".security_profile.anomalies")
+	// MetricSecurityProfileDropped is the name of the metric used to count the behaviours dropped because a security
+	// profile reached its maximum number of entries or of pending anomalies
+	// Tags: anomaly_kind
+	MetricSecurityProfileDropped = newRuntimeMetric(".security_profile.dropped")
+
 	// Namespace resolver metrics
 
 	// MetricNamespaceResolverNetNSHandle is the name of the metric used to report the count of netns handles
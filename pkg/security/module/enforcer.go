@@ -0,0 +1,286 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/DataDog/gopsutil/process"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"golang.org/x/time/rate"
+
+	sconfig "github.com/DataDog/datadog-agent/pkg/security/config"
+	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
+	"github.com/DataDog/datadog-agent/pkg/security/metrics"
+	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// EnforcementStatus describes the outcome of a kill action
+type EnforcementStatus string
+
+const (
+	// EnforcementPerformed means that the signal was sent
+	EnforcementPerformed EnforcementStatus = "performed"
+	// EnforcementDisabled means that the enforcement is disabled by the configuration
+	EnforcementDisabled EnforcementStatus = "disabled"
+	// EnforcementRuleNotAllowed means that the rule isn't allowed to enforce by the configuration
+	EnforcementRuleNotAllowed EnforcementStatus = "rule_not_allowed"
+	// EnforcementRateLimited means that too many kill actions were performed recently
+	EnforcementRateLimited EnforcementStatus = "rate_limited"
+	// EnforcementFailed means that the signal couldn't be sent
+	EnforcementFailed EnforcementStatus = "failed"
+)
+
+// startTimeTolerance is the maximum difference between the fork time of a process known by the probe and the start
+// time read from procfs, beyond which the pid is considered to be reused.
+const startTimeTolerance = time.Second
+
+var enforcementStatuses = []EnforcementStatus{
+	EnforcementPerformed,
+	EnforcementDisabled,
+	EnforcementRuleNotAllowed,
+	EnforcementRateLimited,
+	EnforcementFailed,
+}
+
+// EnforcementReport is the audit of a kill action, attached to the event sent for the rule. The pid is negative when
+// the signal is sent to a process group.
+type EnforcementReport struct {
+	Action string            `json:"action"`
+	Signal string            `json:"signal"`
+	Scope  rules.KillScope   `json:"scope"`
+	Pid    int               `json:"pid,omitempty"`
+	Status EnforcementStatus `json:"status"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// Enforcer performs the kill actions of the rules allowed to enforce
+type Enforcer struct {
+	sync.Mutex
+	enabled      bool
+	rules        map[rules.RuleID]bool
+	limiter      *rate.Limiter
+	statsdClient statsd.ClientInterface
+	stats        map[rules.RuleID]map[EnforcementStatus]int64
+}
+
+// NewEnforcer returns a new enforcer configured by the enforcement section of the runtime security configuration
+func NewEnforcer(cfg *sconfig.Config, client statsd.ClientInterface) *Enforcer {
+	allowed := make(map[rules.RuleID]bool, len(cfg.EnforcementRules))
+	for _, id := range cfg.EnforcementRules {
+		allowed[id] = true
+	}
+
+	return &Enforcer{
+		enabled:      cfg.EnforcementEnabled,
+		rules:        allowed,
+		limiter:      rate.NewLimiter(rate.Limit(cfg.EnforcementRate), cfg.EnforcementBurst),
+		statsdClient: client,
+		stats:        make(map[rules.RuleID]map[EnforcementStatus]int64),
+	}
+}
+
+// Enforce performs the kill actions of the rule on the process of the event, and returns their audits
+func (e *Enforcer) Enforce(rule *rules.Rule, event *sprobe.Event) []*EnforcementReport {
+	var reports []*EnforcementReport
+
+	for _, action := range rule.Definition.Actions {
+		if action.Kill == nil {
+			continue
+		}
+
+		report := &EnforcementReport{
+			Action: "kill",
+			Signal: action.Kill.GetSignal(),
+			Scope:  action.Kill.GetScope(),
+		}
+		e.perform(rule.ID, event, action.Kill.SignalValue, report)
+
+		e.Lock()
+		counts, exists := e.stats[rule.ID]
+		if !exists {
+			counts = make(map[EnforcementStatus]int64)
+			e.stats[rule.ID] = counts
+		}
+		counts[report.Status]++
+		e.Unlock()
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// perform sends the signal, resolved by the ruleset when the rule was loaded, to the target of the kill action
+func (e *Enforcer) perform(ruleID rules.RuleID, event *sprobe.Event, signal int, report *EnforcementReport) {
+	switch {
+	case !e.enabled:
+		report.Status = EnforcementDisabled
+		return
+	case !e.rules[ruleID]:
+		report.Status = EnforcementRuleNotAllowed
+		return
+	}
+
+	if signal <= 0 {
+		report.Status = EnforcementFailed
+		report.Error = fmt.Sprintf("unresolved signal '%s'", report.Signal)
+		return
+	}
+
+	pid, process, err := getKillTarget(event, report.Scope)
+	if err != nil {
+		report.Status = EnforcementFailed
+		report.Error = err.Error()
+		return
+	}
+	report.Pid = pid
+
+	// the rate limiter only applies to the actions which would be performed
+	if !e.limiter.Allow() {
+		report.Status = EnforcementRateLimited
+		return
+	}
+
+	if err := sendSignal(pid, process, syscall.Signal(signal)); err != nil {
+		report.Status = EnforcementFailed
+		report.Error = err.Error()
+		seclog.Errorf("failed to send %s to %d for rule `%s`: %s", report.Signal, pid, ruleID, err)
+		return
+	}
+
+	seclog.Infof("%s sent to %d (%s) for rule `%s`", report.Signal, pid, report.Scope, ruleID)
+	report.Status = EnforcementPerformed
+}
+
+// getKillTarget returns the pid to signal for the given scope, negative for a process group, along with the process
+// whose identity has to be checked before signaling it: the target itself, or the process of the event for a process
+// group.
+func getKillTarget(event *sprobe.Event, scope rules.KillScope) (int, *model.Process, error) {
+	process := &event.ProcessContext.Process
+	pid := int(process.Pid)
+
+	switch scope {
+	case rules.KillScopeProcessGroup:
+		pgid, err := syscall.Getpgid(pid)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get the process group of %d: %w", pid, err)
+		}
+		if pgid == syscall.Getpgrp() {
+			return 0, nil, errors.New("the process group of the agent can't be killed")
+		}
+		pid = -pgid
+	case rules.KillScopeContainer:
+		containerID := event.ProcessContext.ContainerID
+		if containerID == "" {
+			return 0, nil, errors.New("the process doesn't run in a container")
+		}
+
+		// the init process of the container is the oldest ancestor in the same container
+		for ancestor := event.ProcessContext.Ancestor; ancestor != nil; ancestor = ancestor.Ancestor {
+			if ancestor.ContainerID != containerID {
+				break
+			}
+			process = &ancestor.Process
+			pid = int(process.Pid)
+		}
+	}
+
+	if target := abs(pid); target <= 1 || target == os.Getpid() {
+		return 0, nil, fmt.Errorf("pid %d can't be killed", pid)
+	}
+
+	return pid, process, nil
+}
+
+// sendSignal sends the signal to the target, once it has checked that the pid of the process still refers to the
+// process of the event and not to a process which reused its pid. The process is pinned by a pidfd during the check,
+// so that the signal can't be delivered to another process, except for a process group or on the kernels without
+// pidfd, which only rely on the check.
+func sendSignal(target int, process *model.Process, signal syscall.Signal) error {
+	pid := int(process.Pid)
+
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	switch {
+	case err == nil:
+		defer unix.Close(pidfd)
+	case errors.Is(err, unix.ENOSYS):
+		pidfd = -1
+	default:
+		return fmt.Errorf("failed to open pid %d: %w", pid, err)
+	}
+
+	if err := checkProcessStartTime(pid, process.ForkTime); err != nil {
+		return err
+	}
+
+	if target == pid && pidfd >= 0 {
+		return unix.PidfdSendSignal(pidfd, signal, nil, 0)
+	}
+	return syscall.Kill(target, signal)
+}
+
+// checkProcessStartTime returns an error if the process with the given pid didn't start at forkTime, which means that
+// the pid was reused. The start time read from procfs has a precision of about a second. A zero forkTime, unknown, is
+// not checked.
+func checkProcessStartTime(pid int, forkTime time.Time) error {
+	if forkTime.IsZero() {
+		return nil
+	}
+
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to get the process %d: %w", pid, err)
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return fmt.Errorf("failed to get the start time of %d: %w", pid, err)
+	}
+
+	if delta := time.Unix(0, createTime*int64(time.Millisecond)).Sub(forkTime); delta > startTimeTolerance || delta < -startTimeTolerance {
+		return fmt.Errorf("pid %d was reused by another process", pid)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// SendStats sends the counts of the kill actions
+func (e *Enforcer) SendStats() error {
+	e.Lock()
+	stats := e.stats
+	e.stats = make(map[rules.RuleID]map[EnforcementStatus]int64)
+	e.Unlock()
+
+	for ruleID, counts := range stats {
+		for _, status := range enforcementStatuses {
+			if count := counts[status]; count > 0 {
+				tags := []string{"rule_id:" + ruleID, "status:" + string(status)}
+				if err := e.statsdClient.Count(metrics.MetricEnforcementActions, count, tags, 1.0); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/DataDog/gopsutil/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sconfig "github.com/DataDog/datadog-agent/pkg/security/config"
+	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+func newKillRule(id string, scope rules.KillScope) *rules.Rule {
+	return &rules.Rule{
+		Rule: &eval.Rule{ID: id},
+		Definition: &rules.RuleDefinition{
+			ID: id,
+			Actions: []rules.ActionDefinition{{
+				Kill: &rules.KillDefinition{Scope: scope, SignalValue: int(syscall.SIGKILL)},
+			}},
+		},
+	}
+}
+
+func newKillEvent(pid int) *sprobe.Event {
+	event := &sprobe.Event{}
+	event.ProcessContext.Pid = uint32(pid)
+	return event
+}
+
+// startKillTarget starts a process which can be targeted by the enforcer, in case it would really be signaled
+func startKillTarget(t *testing.T) int {
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	return cmd.Process.Pid
+}
+
+func TestEnforcerStatus(t *testing.T) {
+	pid := startKillTarget(t)
+
+	for _, test := range []struct {
+		name   string
+		config *sconfig.Config
+		status EnforcementStatus
+	}{
+		{
+			name:   "disabled",
+			config: &sconfig.Config{EnforcementRules: []string{"kill_rule"}, EnforcementRate: 10, EnforcementBurst: 10},
+			status: EnforcementDisabled,
+		},
+		{
+			name:   "rule-not-allowed",
+			config: &sconfig.Config{EnforcementEnabled: true, EnforcementRules: []string{"other_rule"}, EnforcementRate: 10, EnforcementBurst: 10},
+			status: EnforcementRuleNotAllowed,
+		},
+		{
+			name:   "rate-limited",
+			config: &sconfig.Config{EnforcementEnabled: true, EnforcementRules: []string{"kill_rule"}},
+			status: EnforcementRateLimited,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enforcer := NewEnforcer(test.config, &statsd.NoOpClient{})
+
+			reports := enforcer.Enforce(newKillRule("kill_rule", ""), newKillEvent(pid))
+			require.Len(t, reports, 1)
+			assert.Equal(t, "kill", reports[0].Action)
+			assert.Equal(t, rules.DefaultKillSignal, reports[0].Signal)
+			assert.Equal(t, rules.KillScopeProcess, reports[0].Scope)
+			assert.Equal(t, test.status, reports[0].Status)
+			assert.Empty(t, reports[0].Error)
+
+			assert.Equal(t, map[rules.RuleID]map[EnforcementStatus]int64{"kill_rule": {test.status: 1}}, enforcer.stats)
+			assert.NoError(t, enforcer.SendStats())
+			assert.Empty(t, enforcer.stats)
+		})
+	}
+
+	// the target wasn't signaled
+	assert.NoError(t, syscall.Kill(pid, 0))
+}
+
+func TestEnforcerUnresolvedSignal(t *testing.T) {
+	pid := startKillTarget(t)
+	enforcer := NewEnforcer(&sconfig.Config{EnforcementEnabled: true, EnforcementRules: []string{"kill_rule"}, EnforcementRate: 10, EnforcementBurst: 10}, &statsd.NoOpClient{})
+
+	// the signal value is only set when the rule is added to a ruleset
+	rule := newKillRule("kill_rule", "")
+	rule.Definition.Actions[0].Kill.SignalValue = 0
+
+	reports := enforcer.Enforce(rule, newKillEvent(pid))
+	require.Len(t, reports, 1)
+	assert.Equal(t, EnforcementFailed, reports[0].Status)
+	assert.NoError(t, syscall.Kill(pid, 0))
+}
+
+func TestEnforcerIgnoresOtherActions(t *testing.T) {
+	enforcer := NewEnforcer(&sconfig.Config{EnforcementEnabled: true}, &statsd.NoOpClient{})
+
+	rule := newKillRule("set_rule", "")
+	rule.Definition.Actions = []rules.ActionDefinition{{Set: &rules.SetDefinition{Name: "var1", Value: true}}}
+
+	assert.Empty(t, enforcer.Enforce(rule, newKillEvent(startKillTarget(t))))
+	assert.Empty(t, enforcer.stats)
+}
+
+func TestGetKillTarget(t *testing.T) {
+	t.Run("protected-pids", func(t *testing.T) {
+		for _, pid := range []int{0, 1, os.Getpid()} {
+			_, _, err := getKillTarget(newKillEvent(pid), rules.KillScopeProcess)
+			assert.Error(t, err, "pid %d", pid)
+		}
+	})
+
+	t.Run("process", func(t *testing.T) {
+		event := newKillEvent(1234)
+		pid, process, err := getKillTarget(event, rules.KillScopeProcess)
+		require.NoError(t, err)
+		assert.Equal(t, 1234, pid)
+		assert.Equal(t, &event.ProcessContext.Process, process)
+	})
+
+	t.Run("agent-process-group", func(t *testing.T) {
+		_, _, err := getKillTarget(newKillEvent(os.Getpid()), rules.KillScopeProcessGroup)
+		assert.Error(t, err)
+	})
+
+	t.Run("container-without-container", func(t *testing.T) {
+		_, _, err := getKillTarget(newKillEvent(1234), rules.KillScopeContainer)
+		assert.Error(t, err)
+	})
+
+	t.Run("container", func(t *testing.T) {
+		newAncestor := func(pid uint32, containerID string, ancestor *model.ProcessCacheEntry) *model.ProcessCacheEntry {
+			entry := &model.ProcessCacheEntry{}
+			entry.Pid = pid
+			entry.ContainerID = containerID
+			entry.Ancestor = ancestor
+			return entry
+		}
+
+		// the oldest ancestor in the container of the process is its init process
+		host := newAncestor(100, "", nil)
+		containerInit := newAncestor(200, "abc", host)
+		shell := newAncestor(300, "abc", containerInit)
+
+		event := newKillEvent(400)
+		event.ProcessContext.ContainerID = "abc"
+		event.ProcessContext.Ancestor = shell
+
+		pid, process, err := getKillTarget(event, rules.KillScopeContainer)
+		require.NoError(t, err)
+		assert.Equal(t, 200, pid)
+		assert.Equal(t, &containerInit.Process, process)
+
+		// the pid of the agent is refused even when it is found by the walk
+		containerInit.Pid = uint32(os.Getpid())
+		_, _, err = getKillTarget(event, rules.KillScopeContainer)
+		assert.Error(t, err)
+	})
+}
+
+func TestSendSignal(t *testing.T) {
+	startTime := func(t *testing.T, pid int) time.Time {
+		proc, err := process.NewProcess(int32(pid))
+		require.NoError(t, err)
+		createTime, err := proc.CreateTime()
+		require.NoError(t, err)
+		return time.Unix(0, createTime*int64(time.Millisecond))
+	}
+
+	t.Run("same-process", func(t *testing.T) {
+		cmd := exec.Command("sleep", "60")
+		require.NoError(t, cmd.Start())
+		pid := cmd.Process.Pid
+		target := &model.Process{Pid: uint32(pid), ForkTime: startTime(t, pid)}
+
+		require.NoError(t, sendSignal(pid, target, syscall.SIGKILL))
+		assert.EqualError(t, cmd.Wait(), "signal: killed")
+	})
+
+	t.Run("reused-pid", func(t *testing.T) {
+		pid := startKillTarget(t)
+		// the process of the event started long before the process which now has its pid
+		target := &model.Process{Pid: uint32(pid), ForkTime: startTime(t, pid).Add(-time.Hour)}
+
+		assert.Error(t, sendSignal(pid, target, syscall.SIGKILL))
+		assert.NoError(t, syscall.Kill(pid, 0))
+	})
+
+	t.Run("exited-process", func(t *testing.T) {
+		cmd := exec.Command("true")
+		require.NoError(t, cmd.Run())
+
+		assert.Error(t, sendSignal(cmd.Process.Pid, &model.Process{Pid: uint32(cmd.Process.Pid)}, syscall.SIGKILL))
+	})
+}
+
+func TestHasPerformedAction(t *testing.T) {
+	assert.False(t, hasPerformedAction(nil))
+	assert.False(t, hasPerformedAction([]*EnforcementReport{{Status: EnforcementRateLimited}, {Status: EnforcementFailed}}))
+	assert.True(t, hasPerformedAction([]*EnforcementReport{{Status: EnforcementDisabled}, {Status: EnforcementPerformed}}))
+}
@@ -9,6 +9,7 @@
 package module
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -62,6 +63,7 @@ type Module struct {
 	grpcServer       *grpc.Server
 	listener         net.Listener
 	rateLimiter      *RateLimiter
+	enforcer         *Enforcer
 	sigupChan        chan os.Signal
 	ctx              context.Context
 	cancelFnc        context.CancelFunc
@@ -292,6 +294,7 @@ func (m *Module) Reload() error {
 	opts.WithStateScopes(map[rules.Scope]rules.VariableProviderFactory{
 		"process": m.probe.GetResolvers().ProcessResolver.NewProcessVariables,
 	})
+	opts.WithEventSnapshotter(m.snapshotEvent)
 
 	ruleSet := m.probe.NewRuleSet(&opts)
 	loadErr := rules.LoadPolicies(policiesDir, ruleSet)
@@ -398,14 +401,113 @@ func (m *Module) HandleCustomEvent(rule *rules.Rule, event *sprobe.CustomEvent)
 
 // RuleMatch is called by the ruleset when a rule matches
 func (m *Module) RuleMatch(rule *rules.Rule, event eval.Event) {
+	m.sendRuleEvent(rule, event.(*sprobe.Event), nil)
+}
+
+// SequenceMatch is called by the ruleset when the events matching all the steps of a sequence rule
+// were found. The steps are the JSON snapshots of the events which matched all but the last step.
+func (m *Module) SequenceMatch(rule *rules.Rule, steps []interface{}, event eval.Event) {
+	m.sendRuleEvent(rule, event.(*sprobe.Event), steps)
+}
+
+// matchedEvent is an event sent along with the events which matched the previous steps of a
+// sequence rule, or with the audit of the kill actions performed for the rule
+type matchedEvent struct {
+	*sprobe.Event
+	steps   []interface{}
+	actions []*EnforcementReport
+}
+
+// MarshalJSON returns the JSON encoding of the event, with the previous steps in a `sequence` field
+// and the kill actions in an `actions` field
+func (e *matchedEvent) MarshalJSON() ([]byte, error) {
+	data, err := e.Event.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []jsonField
+	if len(e.steps) > 0 {
+		fields = append(fields, jsonField{name: "sequence", value: e.steps})
+	}
+	if len(e.actions) > 0 {
+		fields = append(fields, jsonField{name: "actions", value: e.actions})
+	}
+	return appendJSONFields(data, fields...)
+}
+
+// jsonField is a field added to a JSON object by appendJSONFields
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+// appendJSONFields returns the JSON object with the given fields added after its own fields
+func appendJSONFields(object []byte, fields ...jsonField) ([]byte, error) {
+	object = bytes.TrimSpace(object)
+	if len(object) < 2 || object[0] != '{' || object[len(object)-1] != '}' {
+		return nil, fmt.Errorf("not a JSON object: %s", object)
+	}
+	if len(fields) == 0 {
+		return object, nil
+	}
+
+	data := object[:len(object)-1]
+	// an empty object has no field to separate from the new ones
+	empty := len(bytes.TrimSpace(data[1:])) == 0
+	if empty {
+		data = data[:1]
+	}
+
+	for i, field := range fields {
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 || !empty {
+			data = append(data, ',')
+		}
+		data = append(data, name...)
+		data = append(data, ':')
+		data = append(data, value...)
+	}
+
+	return append(data, '}'), nil
+}
+
+// snapshotEvent returns the JSON encoding of an event matching a step of a sequence rule, as
+// the event is reused by the probe once evaluated
+func (m *Module) snapshotEvent(event eval.Event) interface{} {
+	data, err := event.(*sprobe.Event).MarshalJSON()
+	if err != nil {
+		seclog.Errorf("failed to marshal sequence step event: %s", err)
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+func (m *Module) sendRuleEvent(rule *rules.Rule, ev *sprobe.Event, steps []interface{}) {
 	// prepare the event
-	m.probe.OnRuleMatch(rule, event.(*sprobe.Event))
+	m.probe.OnRuleMatch(rule, ev)
+
+	// the kill actions are performed before anything else so that the process is stopped as soon as possible
+	actions := m.enforcer.Enforce(rule, ev)
+
+	var event Event = ev
+	if len(steps) > 0 || len(actions) > 0 {
+		event = &matchedEvent{Event: ev, steps: steps, actions: actions}
+	}
 
 	// needs to be resolved here, outside of the callback as using process tree
 	// which can be modified during queuing
-	service := event.(*sprobe.Event).GetProcessServiceTag()
+	service := ev.GetProcessServiceTag()
 
-	id := event.(*sprobe.Event).ContainerContext.ID
+	id := ev.ContainerContext.ID
 
 	extTagsCb := func() []string {
 		var tags []string
@@ -423,11 +525,27 @@ func (m *Module) RuleMatch(rule *rules.Rule, event eval.Event) {
 	}
 
 	if m.selfTester != nil {
-		m.selfTester.SendEventIfExpecting(rule, event)
+		m.selfTester.SendEventIfExpecting(rule, ev)
+	}
+
+	// the audit of a signal which was sent must not be dropped by the rate limiter
+	if hasPerformedAction(actions) {
+		m.apiServer.SendEvent(rule, event, extTagsCb, service)
+		return
 	}
 	m.SendEvent(rule, event, extTagsCb, service)
 }
 
+// hasPerformedAction returns whether one of the kill actions was performed
+func hasPerformedAction(actions []*EnforcementReport) bool {
+	for _, action := range actions {
+		if action.Status == EnforcementPerformed {
+			return true
+		}
+	}
+	return false
+}
+
 // SendEvent sends an event to the backend after checking that the rate limiter allows it for the provided rule
 func (m *Module) SendEvent(rule *rules.Rule, event Event, extTagsCb func() []string, service string) {
 	if m.rateLimiter.Allow(rule.ID) {
@@ -459,6 +577,9 @@ func (m *Module) metricsSender() {
 			if err := m.rateLimiter.SendStats(); err != nil {
 				log.Debug(err)
 			}
+			if err := m.enforcer.SendStats(); err != nil {
+				log.Debug(err)
+			}
 			if err := m.apiServer.SendStats(); err != nil {
 				log.Debug(err)
 			}
@@ -555,6 +676,7 @@ func NewModule(cfg *sconfig.Config, opts ...Opts) (module.Module, error) {
 		apiServer:    NewAPIServer(cfg, probe, statsdClient),
 		grpcServer:   grpc.NewServer(),
 		rateLimiter:  NewRateLimiter(statsdClient, LimiterOpts{Limits: limits}),
+		enforcer:     NewEnforcer(cfg, statsdClient),
 		sigupChan:    make(chan os.Signal, 1),
 		ctx:          ctx,
 		cancelFnc:    cancelFnc,
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendJSONFields(t *testing.T) {
+	fields := []jsonField{
+		{name: "sequence", value: []string{"a"}},
+		{name: "actions", value: []int{1}},
+	}
+
+	for _, test := range []struct {
+		name     string
+		object   string
+		expected string
+	}{
+		{name: "fields", object: `{"evt":{"name":"open"}}`, expected: `{"evt":{"name":"open"},"sequence":["a"],"actions":[1]}`},
+		{name: "empty", object: `{}`, expected: `{"sequence":["a"],"actions":[1]}`},
+		{name: "empty-with-spaces", object: " { \n} \n", expected: `{"sequence":["a"],"actions":[1]}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := appendJSONFields([]byte(test.object), fields...)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(data))
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+
+	data, err := appendJSONFields([]byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+
+	_, err = appendJSONFields([]byte(`[]`), fields...)
+	assert.Error(t, err)
+}
@@ -23,7 +23,6 @@ import (
 
 	"github.com/DataDog/gopsutil/process"
 	"github.com/pkg/errors"
-	"github.com/tinylib/msgp/msgp"
 
 	"github.com/DataDog/datadog-agent/pkg/security/api"
 	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
@@ -41,8 +40,29 @@ var (
 	JSON OutputFormat = "json"
 	// MSGP is used to request the message pack format
 	MSGP OutputFormat = "msgp"
+	// PROTOBUF is used to request the protobuf format
+	PROTOBUF OutputFormat = "protobuf"
+	// JSONL is used to request the timeline of the events of the dump, one JSON event per line
+	JSONL OutputFormat = "jsonl"
+	// SBOM is used to request the summary of the binaries executed during the dump
+	SBOM OutputFormat = "sbom"
+	// DOT is used to request the graphviz graph of the activity tree
+	DOT OutputFormat = "dot"
 )
 
+// AllOutputFormats lists the available output formats
+var AllOutputFormats = []OutputFormat{JSON, MSGP, PROTOBUF, JSONL, SBOM, DOT}
+
+// ParseOutputFormat returns the output format matching the provided string
+func ParseOutputFormat(input string) (OutputFormat, error) {
+	for _, format := range AllOutputFormats {
+		if string(format) == input {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format \"%s\", options are %v", input, AllOutputFormats)
+}
+
 // NodeGenerationType is used to indicate if a node was generated by a runtime or snapshot event
 type NodeGenerationType string
 
@@ -67,12 +87,7 @@ type ActivityDump struct {
 	DifferentiateArgs   bool                            `msg:"differentiate_args"`
 	WithGraph           bool                            `msg:"with_graph"`
 
-	OutputDirectory string       `msg:"-"`
-	OutputFormat    OutputFormat `msg:"format"`
-	OutputFile      string       `msg:"-"`
-	outputFile      *os.File
-	GraphFile       string `msg:"-"`
-	graphFile       *os.File
+	StorageRequests []*StorageRequest `msg:"-"`
 
 	Comm        string        `msg:"comm,omitempty"`
 	ContainerID string        `msg:"container_id,omitempty"`
@@ -114,34 +129,15 @@ func NewActivityDump(adm *ActivityDumpManager, options ...WithDumpOption) (*Acti
 		ad.addedSnapshotCount[i] = &snapshot
 	}
 
-	if len(ad.OutputDirectory) > 0 {
-		// generate random output file
-		_ = os.MkdirAll(ad.OutputDirectory, 0400)
-		ad.outputFile, err = os.CreateTemp(ad.OutputDirectory, "activity-dump-*."+string(ad.OutputFormat))
-		if err != nil {
-			return nil, err
+	// create the output files of the local storage requests
+	for _, request := range ad.StorageRequests {
+		if request.Format == DOT {
+			ad.WithGraph = true
 		}
-
-		if err = os.Chmod(ad.outputFile.Name(), 0400); err != nil {
+		if err = request.open(); err != nil {
 			ad.close()
 			return nil, err
 		}
-		ad.OutputFile = ad.outputFile.Name()
-
-		// generate random graph file
-		if ad.WithGraph {
-			ad.graphFile, err = os.CreateTemp(ad.OutputDirectory, "graph-dump-*.dot")
-			if err != nil {
-				ad.close()
-				return nil, err
-			}
-
-			if err = os.Chmod(ad.graphFile.Name(), 0400); err != nil {
-				ad.close()
-				return nil, err
-			}
-			ad.GraphFile = ad.graphFile.Name()
-		}
 	}
 	return &ad, nil
 }
@@ -155,11 +151,8 @@ func (ad *ActivityDump) Close() {
 
 // close thread unsafe version of Close
 func (ad *ActivityDump) close() {
-	if ad.graphFile != nil {
-		_ = ad.graphFile.Close()
-	}
-	if ad.outputFile != nil {
-		_ = ad.outputFile.Close()
+	for _, request := range ad.StorageRequests {
+		request.close()
 	}
 }
 
@@ -212,8 +205,8 @@ func (ad *ActivityDump) Matches(entry *model.ProcessCacheEntry) bool {
 	return true
 }
 
-// Done stops an active dump
-func (ad *ActivityDump) Done() {
+// Stop stops an active dump by removing its kernel space filters. The dump should then be persisted with Finalize.
+func (ad *ActivityDump) Stop() {
 	ad.Lock()
 	defer ad.Unlock()
 
@@ -238,74 +231,98 @@ func (ad *ActivityDump) Done() {
 	}
 
 	ad.End = time.Now()
+}
+
+// Finalize encodes a stopped dump in the requested formats, persists it to the requested storages and releases its
+// resources. Encoding a dump can take a while, the binaries are hashed for the SBOM format for example, so this
+// shouldn't be called while holding the lock of the activity dump manager.
+func (ad *ActivityDump) Finalize() {
+	ad.Lock()
+	defer ad.Unlock()
+
 	ad.dump()
+	ad.release()
+}
 
-	if ad.graphFile != nil {
-		err := ad.generateGraph()
-		if err != nil {
-			seclog.Errorf("couldn't generate activity graph: %v", err)
-		} else {
-			seclog.Infof("activity graph for [%s] written at: %s", ad.GetSelectorStr(), ad.GraphFile)
-		}
-	}
+// Discard releases the resources of a stopped dump without persisting it
+func (ad *ActivityDump) Discard() {
+	ad.Lock()
+	defer ad.Unlock()
 
-	ad.close()
+	ad.release()
+}
 
-	// release all shared resources
+// release closes the output files and releases all shared resources
+func (ad *ActivityDump) release() {
+	ad.close()
 	for _, p := range ad.ProcessActivityTree {
 		p.recursiveRelease()
 	}
 }
 
+// dump encodes the activity dump in the requested formats and persists it to the requested storages
 func (ad *ActivityDump) dump() {
-	if ad.outputFile == nil {
-		return
-	}
+	encoded := make(map[OutputFormat][]byte)
 
-	var size int
-	switch ad.OutputFormat {
-	case "msgp":
-		size = ad.Msgsize()
-		msgWriter := msgp.NewWriter(ad.outputFile)
-		err := ad.EncodeMsg(msgWriter)
-		if err != nil {
-			seclog.Errorf("couldn't marshal ActivityDump: %v\n", err)
-			return
-		}
-		if err = msgWriter.Flush(); err != nil {
-			seclog.Errorf("couldn't flush msgp writer: %v\n", err)
-			return
+	for _, request := range ad.StorageRequests {
+		raw, ok := encoded[request.Format]
+		if !ok {
+			buf, err := ad.encode(request.Format)
+			if err != nil {
+				seclog.Errorf("couldn't encode activity dump [%s] to %s: %v", ad.GetSelectorStr(), request.Format, err)
+				continue
+			}
+			raw = buf.Bytes()
+			encoded[request.Format] = raw
 		}
-	case "json":
-		raw, err := ad.MarshalMsg(nil)
-		if err != nil {
-			seclog.Errorf("couldn't marshal ActivityDump: %v\n", err)
-			return
+
+		data := raw
+		if request.Compression {
+			var err error
+			if data, err = compress(raw); err != nil {
+				seclog.Errorf("couldn't compress activity dump [%s]: %v", ad.GetSelectorStr(), err)
+				continue
+			}
 		}
-		_, err = msgp.UnmarshalAsJSON(ad.outputFile, raw)
-		if err != nil {
-			seclog.Errorf("couldn't marshal ActivityDump to JSON: %v\n", err)
-			return
+
+		if err := ad.persist(request, data); err != nil {
+			seclog.Errorf("couldn't persist activity dump [%s] to %s: %v", ad.GetSelectorStr(), request, err)
+			continue
 		}
-		if err = ad.outputFile.Sync(); err != nil {
-			seclog.Errorf("couldn't flush content to disk: %v\n", err)
-			return
+
+		// send dump size
+		tags := []string{
+			"format:" + string(request.Format),
+			"storage_type:" + string(request.Type),
+			fmt.Sprintf("compression:%v", request.Compression),
 		}
-		// get file size
-		fi, err := ad.outputFile.Stat()
-		if fi != nil && err == nil {
-			size = int(fi.Size())
+		if err := ad.adm.probe.statsdClient.Gauge(metrics.MetricActivityDumpSizeInBytes, float64(len(data)), tags, 1.0); err != nil {
+			seclog.Warnf("couldn't send %s metric: %v", metrics.MetricActivityDumpSizeInBytes, err)
 		}
 	}
+}
 
-	// send dump size
-	if size > 0 {
-		tags := []string{"format:" + string(ad.OutputFormat)}
-		if err := ad.adm.probe.statsdClient.Gauge(metrics.MetricActivityDumpSizeInBytes, float64(size), tags, 1.0); err != nil {
-			seclog.Warnf("couldn't send %s metric: %v", metrics.MetricActivityDumpSizeInBytes, err)
+// persist writes the encoded activity dump to the storage of the provided request
+func (ad *ActivityDump) persist(request *StorageRequest, data []byte) error {
+	switch request.Type {
+	case LocalStorage:
+		if request.file == nil {
+			return fmt.Errorf("output file not created")
+		}
+		if _, err := request.file.Write(data); err != nil {
+			return err
+		}
+		if err := request.file.Sync(); err != nil {
+			return err
 		}
+		seclog.Infof("activity dump for [%s] written at: %s", ad.GetSelectorStr(), request.File)
+	case RemoteStorage:
+		if err := ad.adm.remoteStorage.Persist(request, ad.GetSelectorStr(), data); err != nil {
+			return err
+		}
+		seclog.Infof("activity dump for [%s] queued for %s", ad.GetSelectorStr(), request.Destination)
 	}
-	seclog.Infof("activity dump for [%s] written at: %s", ad.GetSelectorStr(), ad.OutputFile)
+	return nil
 }
 
 // nolint: unused
@@ -363,6 +380,8 @@ func (ad *ActivityDump) Insert(event *Event) (newEntry bool) {
 	switch event.GetEventType() {
 	case model.FileOpenEventType:
 		return node.InsertFileEvent(&event.Open.File, event, Runtime)
+	case model.DNSEventType:
+		return node.InsertDNSEvent(event, Runtime)
 	}
 	return false
 }
@@ -641,9 +660,7 @@ func (ad *ActivityDump) resolveTags() error {
 // ToSecurityActivityDumpMessage returns a pointer to a SecurityActivityDumpMessage struct populated with current dump
 // information.
 func (ad *ActivityDump) ToSecurityActivityDumpMessage() *api.SecurityActivityDumpMessage {
-	return &api.SecurityActivityDumpMessage{
-		OutputFilename:    ad.OutputFile,
-		GraphFilename:     ad.GraphFile,
+	msg := &api.SecurityActivityDumpMessage{
 		Comm:              ad.Comm,
 		ContainerID:       ad.ContainerID,
 		Tags:              ad.Tags,
@@ -653,6 +670,24 @@ func (ad *ActivityDump) ToSecurityActivityDumpMessage() *api.SecurityActivityDum
 		Start:             ad.Start.String(),
 		Left:              ad.Start.Add(ad.Timeout).Sub(time.Now()).String(),
 	}
+
+	for _, request := range ad.StorageRequests {
+		msg.Storage = append(msg.Storage, request.ToStorageRequestMessage())
+
+		// the first local files are also reported in the legacy fields
+		if request.Type != LocalStorage {
+			continue
+		}
+		if request.Format == DOT {
+			if len(msg.GraphFilename) == 0 {
+				msg.GraphFilename = request.File
+			}
+		} else if len(msg.OutputFilename) == 0 {
+			msg.OutputFilename = request.File
+		}
+	}
+
+	return msg
 }
 
 // ProcessActivityNode holds the activity of a process
@@ -662,6 +697,7 @@ type ProcessActivityNode struct {
 	GenerationType NodeGenerationType `msg:"generation_type"`
 
 	Files    map[string]*FileActivityNode `msg:"files,omitempty"`
+	DNSNames map[string]*DNSNode          `msg:"dns_names,omitempty"`
 	Children []*ProcessActivityNode       `msg:"children,omitempty"`
 }
 
@@ -679,6 +715,7 @@ func NewProcessActivityNode(entry *model.ProcessCacheEntry, generationType NodeG
 		Process:        entry.Process,
 		GenerationType: generationType,
 		Files:          make(map[string]*FileActivityNode),
+		DNSNames:       make(map[string]*DNSNode),
 	}
 	_ = pan.GetID()
 	pan.retain()
@@ -811,6 +848,26 @@ func (pan *ProcessActivityNode) InsertFileEvent(fileEvent *model.FileEvent, even
 	return true
 }
 
+// InsertDNSEvent inserts the provided DNS event in the current node. This function returns true if a new entry was
+// added, false if the event was dropped.
+func (pan *ProcessActivityNode) InsertDNSEvent(event *Event, generationType NodeGenerationType) bool {
+	if pan.DNSNames == nil {
+		pan.DNSNames = make(map[string]*DNSNode)
+	}
+
+	name := strings.ToLower(event.DNS.Name)
+	if len(name) == 0 {
+		return false
+	}
+
+	if node, ok := pan.DNSNames[name]; ok {
+		return node.insertType(event.DNS.Type)
+	}
+
+	pan.DNSNames[name] = NewDNSNode(name, event, generationType)
+	return true
+}
+
 // snapshot uses procfs to retrieve information about the current process
 func (pan *ProcessActivityNode) snapshot(ad *ActivityDump) error {
 	// call snapshot for all the children of the current node
@@ -1015,3 +1072,31 @@ func (fan *FileActivityNode) debug(prefix string) {
 		child.debug("\t" + prefix)
 	}
 }
+
+// DNSNode holds the DNS requests of a process for a domain name
+type DNSNode struct {
+	Name           string             `msg:"name"`
+	Types          []uint16           `msg:"types"`
+	GenerationType NodeGenerationType `msg:"generation_type"`
+	FirstSeen      time.Time          `msg:"first_seen,omitempty"`
+}
+
+// NewDNSNode returns a new DNSNode instance
+func NewDNSNode(name string, event *Event, generationType NodeGenerationType) *DNSNode {
+	return &DNSNode{
+		Name:           name,
+		Types:          []uint16{event.DNS.Type},
+		GenerationType: generationType,
+		FirstSeen:      event.ResolveEventTimestamp(),
+	}
+}
+
+func (dn *DNSNode) insertType(qtype uint16) bool {
+	for _, t := range dn.Types {
+		if t == qtype {
+			return false
+		}
+	}
+	dn.Types = append(dn.Types, qtype)
+	return true
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+// LoadActivityDumpEvents returns the events recorded in the input activity dump, so that they can
+// be replayed through a rule set
+func LoadActivityDumpEvents(inputFile string) ([]*model.Event, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open activity dump file: %w", err)
+	}
+	defer f.Close()
+
+	var dump ActivityDump
+	if err = dump.DecodeMsg(msgp.NewReader(f)); err != nil {
+		return nil, fmt.Errorf("couldn't parse activity dump file: %w", err)
+	}
+
+	return dump.GenerateEvents(), nil
+}
+
+// GenerateEvents returns an exec event for each process of the activity dump, followed by an
+// open event for each file opened by the process and a dns event for each domain it resolved
+func (ad *ActivityDump) GenerateEvents() []*model.Event {
+	var events []*model.Event
+	for _, node := range ad.ProcessActivityTree {
+		events = append(events, node.generateEvents(nil)...)
+	}
+	return events
+}
+
+func (pan *ProcessActivityNode) generateEvents(parent *model.ProcessCacheEntry) []*model.Event {
+	entry := &model.ProcessCacheEntry{
+		ProcessContext: model.ProcessContext{
+			Process:  pan.Process,
+			Ancestor: parent,
+		},
+	}
+	process := &entry.Process
+
+	// args and envs aren't resolved from the dump
+	if process.ArgsEntry != nil && len(process.ArgsEntry.Values) > 0 {
+		process.Argv0 = process.ArgsEntry.Values[0]
+		process.Argv = process.ArgsEntry.Values[1:]
+		process.Args = strings.Join(process.Argv, " ")
+	}
+	if process.EnvsEntry != nil {
+		process.Envp = process.EnvsEntry.Values
+		process.Envs = make([]string, 0, len(process.Envp))
+		for _, env := range process.Envp {
+			process.Envs = append(process.Envs, strings.SplitN(env, "=", 2)[0])
+		}
+	}
+
+	exec := &model.Event{
+		Type:           uint64(model.ExecEventType),
+		Timestamp:      process.ExecTime,
+		ProcessContext: entry.ProcessContext,
+	}
+	exec.Exec.Process = *process
+	events := []*model.Event{exec}
+
+	for _, name := range sortedFileNames(pan.Files) {
+		events = append(events, pan.Files[name].generateEvents(&entry.ProcessContext)...)
+	}
+
+	for _, name := range sortedDNSNames(pan.DNSNames) {
+		events = append(events, pan.DNSNames[name].generateEvents(&entry.ProcessContext)...)
+	}
+
+	for _, child := range pan.Children {
+		events = append(events, child.generateEvents(entry)...)
+	}
+
+	return events
+}
+
+func (fan *FileActivityNode) generateEvents(pc *model.ProcessContext) []*model.Event {
+	var events []*model.Event
+
+	if fan.File != nil && fan.Open != nil {
+		event := &model.Event{
+			Type:           uint64(model.FileOpenEventType),
+			Timestamp:      fan.FirstSeen,
+			ProcessContext: *pc,
+		}
+		event.Open = model.OpenEvent{
+			SyscallEvent: fan.Open.SyscallEvent,
+			File:         *fan.File,
+			Flags:        fan.Open.Flags,
+			Mode:         fan.Open.Mode,
+		}
+		events = append(events, event)
+	}
+
+	for _, name := range sortedFileNames(fan.Children) {
+		events = append(events, fan.Children[name].generateEvents(pc)...)
+	}
+
+	return events
+}
+
+func (dn *DNSNode) generateEvents(pc *model.ProcessContext) []*model.Event {
+	events := make([]*model.Event, 0, len(dn.Types))
+	for _, qtype := range dn.Types {
+		event := &model.Event{
+			Type:           uint64(model.DNSEventType),
+			Timestamp:      dn.FirstSeen,
+			ProcessContext: *pc,
+		}
+		event.DNS = model.DNSEvent{
+			Name:  dn.Name,
+			Type:  qtype,
+			Class: 1,
+			Count: 1,
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// sortedFileNames returns the names of the given file nodes, sorted so that the events are
+// always generated in the same order
+func sortedFileNames(files map[string]*FileActivityNode) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedDNSNames returns the domain names of the given DNS nodes, sorted
+func sortedDNSNames(names map[string]*DNSNode) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -70,16 +70,6 @@ func (z *ActivityDump) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "WithGraph")
 				return
 			}
-		case "format":
-			{
-				var zb0003 string
-				zb0003, err = dc.ReadString()
-				if err != nil {
-					err = msgp.WrapError(err, "OutputFormat")
-					return
-				}
-				z.OutputFormat = OutputFormat(zb0003)
-			}
 		case "comm":
 			z.Comm, err = dc.ReadString()
 			if err != nil {
@@ -93,16 +83,16 @@ func (z *ActivityDump) DecodeMsg(dc *msgp.Reader) (err error) {
 				return
 			}
 		case "tags":
-			var zb0004 uint32
-			zb0004, err = dc.ReadArrayHeader()
+			var zb0003 uint32
+			zb0003, err = dc.ReadArrayHeader()
 			if err != nil {
 				err = msgp.WrapError(err, "Tags")
 				return
 			}
-			if cap(z.Tags) >= int(zb0004) {
-				z.Tags = (z.Tags)[:zb0004]
+			if cap(z.Tags) >= int(zb0003) {
+				z.Tags = (z.Tags)[:zb0003]
 			} else {
-				z.Tags = make([]string, zb0004)
+				z.Tags = make([]string, zb0003)
 			}
 			for za0002 := range z.Tags {
 				z.Tags[za0002], err = dc.ReadString()
@@ -137,23 +127,23 @@ func (z *ActivityDump) DecodeMsg(dc *msgp.Reader) (err error) {
 // EncodeMsg implements msgp.Encodable
 func (z *ActivityDump) EncodeMsg(en *msgp.Writer) (err error) {
 	// omitempty: check for empty values
-	zb0001Len := uint32(9)
-	var zb0001Mask uint16 /* 9 bits */
+	zb0001Len := uint32(8)
+	var zb0001Mask uint8 /* 8 bits */
 	if z.ProcessActivityTree == nil {
 		zb0001Len--
 		zb0001Mask |= 0x1
 	}
 	if z.Comm == "" {
 		zb0001Len--
-		zb0001Mask |= 0x10
+		zb0001Mask |= 0x8
 	}
 	if z.ContainerID == "" {
 		zb0001Len--
-		zb0001Mask |= 0x20
+		zb0001Mask |= 0x10
 	}
 	if z.Tags == nil {
 		zb0001Len--
-		zb0001Mask |= 0x40
+		zb0001Mask |= 0x20
 	}
 	// variable map header, size zb0001Len
 	err = en.Append(0x80 | uint8(zb0001Len))
@@ -209,17 +199,7 @@ func (z *ActivityDump) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "WithGraph")
 		return
 	}
-	// write "format"
-	err = en.Append(0xa6, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74)
-	if err != nil {
-		return
-	}
-	err = en.WriteString(string(z.OutputFormat))
-	if err != nil {
-		err = msgp.WrapError(err, "OutputFormat")
-		return
-	}
-	if (zb0001Mask & 0x10) == 0 { // if not empty
+	if (zb0001Mask & 0x8) == 0 { // if not empty
 		// write "comm"
 		err = en.Append(0xa4, 0x63, 0x6f, 0x6d, 0x6d)
 		if err != nil {
@@ -231,7 +211,7 @@ func (z *ActivityDump) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
-	if (zb0001Mask & 0x20) == 0 { // if not empty
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// write "container_id"
 		err = en.Append(0xac, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64)
 		if err != nil {
@@ -243,7 +223,7 @@ func (z *ActivityDump) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
-	if (zb0001Mask & 0x40) == 0 { // if not empty
+	if (zb0001Mask & 0x20) == 0 { // if not empty
 		// write "tags"
 		err = en.Append(0xa4, 0x74, 0x61, 0x67, 0x73)
 		if err != nil {
@@ -289,23 +269,23 @@ func (z *ActivityDump) EncodeMsg(en *msgp.Writer) (err error) {
 func (z *ActivityDump) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
 	// omitempty: check for empty values
-	zb0001Len := uint32(9)
-	var zb0001Mask uint16 /* 9 bits */
+	zb0001Len := uint32(8)
+	var zb0001Mask uint8 /* 8 bits */
 	if z.ProcessActivityTree == nil {
 		zb0001Len--
 		zb0001Mask |= 0x1
 	}
 	if z.Comm == "" {
 		zb0001Len--
-		zb0001Mask |= 0x10
+		zb0001Mask |= 0x8
 	}
 	if z.ContainerID == "" {
 		zb0001Len--
-		zb0001Mask |= 0x20
+		zb0001Mask |= 0x10
 	}
 	if z.Tags == nil {
 		zb0001Len--
-		zb0001Mask |= 0x40
+		zb0001Mask |= 0x20
 	}
 	// variable map header, size zb0001Len
 	o = append(o, 0x80|uint8(zb0001Len))
@@ -334,20 +314,17 @@ func (z *ActivityDump) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "with_graph"
 	o = append(o, 0xaa, 0x77, 0x69, 0x74, 0x68, 0x5f, 0x67, 0x72, 0x61, 0x70, 0x68)
 	o = msgp.AppendBool(o, z.WithGraph)
-	// string "format"
-	o = append(o, 0xa6, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74)
-	o = msgp.AppendString(o, string(z.OutputFormat))
-	if (zb0001Mask & 0x10) == 0 { // if not empty
+	if (zb0001Mask & 0x8) == 0 { // if not empty
 		// string "comm"
 		o = append(o, 0xa4, 0x63, 0x6f, 0x6d, 0x6d)
 		o = msgp.AppendString(o, z.Comm)
 	}
-	if (zb0001Mask & 0x20) == 0 { // if not empty
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// string "container_id"
 		o = append(o, 0xac, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x69, 0x64)
 		o = msgp.AppendString(o, z.ContainerID)
 	}
-	if (zb0001Mask & 0x40) == 0 { // if not empty
+	if (zb0001Mask & 0x20) == 0 { // if not empty
 		// string "tags"
 		o = append(o, 0xa4, 0x74, 0x61, 0x67, 0x73)
 		o = msgp.AppendArrayHeader(o, uint32(len(z.Tags)))
@@ -424,16 +401,6 @@ func (z *ActivityDump) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "WithGraph")
 				return
 			}
-		case "format":
-			{
-				var zb0003 string
-				zb0003, bts, err = msgp.ReadStringBytes(bts)
-				if err != nil {
-					err = msgp.WrapError(err, "OutputFormat")
-					return
-				}
-				z.OutputFormat = OutputFormat(zb0003)
-			}
 		case "comm":
 			z.Comm, bts, err = msgp.ReadStringBytes(bts)
 			if err != nil {
@@ -447,16 +414,16 @@ func (z *ActivityDump) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				return
 			}
 		case "tags":
-			var zb0004 uint32
-			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
 			if err != nil {
 				err = msgp.WrapError(err, "Tags")
 				return
 			}
-			if cap(z.Tags) >= int(zb0004) {
-				z.Tags = (z.Tags)[:zb0004]
+			if cap(z.Tags) >= int(zb0003) {
+				z.Tags = (z.Tags)[:zb0003]
 			} else {
-				z.Tags = make([]string, zb0004)
+				z.Tags = make([]string, zb0003)
 			}
 			for za0002 := range z.Tags {
 				z.Tags[za0002], bts, err = msgp.ReadStringBytes(bts)
@@ -499,7 +466,7 @@ func (z *ActivityDump) Msgsize() (s int) {
 			s += z.ProcessActivityTree[za0001].Msgsize()
 		}
 	}
-	s += 19 + msgp.BoolSize + 11 + msgp.BoolSize + 7 + msgp.StringPrefixSize + len(string(z.OutputFormat)) + 5 + msgp.StringPrefixSize + len(z.Comm) + 13 + msgp.StringPrefixSize + len(z.ContainerID) + 5 + msgp.ArrayHeaderSize
+	s += 19 + msgp.BoolSize + 11 + msgp.BoolSize + 5 + msgp.StringPrefixSize + len(z.Comm) + 13 + msgp.StringPrefixSize + len(z.ContainerID) + 5 + msgp.ArrayHeaderSize
 	for za0002 := range z.Tags {
 		s += msgp.StringPrefixSize + len(z.Tags[za0002])
 	}
@@ -507,6 +474,257 @@ func (z *ActivityDump) Msgsize() (s int) {
 	return
 }
 
+// DecodeMsg implements msgp.Decodable
+func (z *DNSNode) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "name":
+			z.Name, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Name")
+				return
+			}
+		case "types":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Types")
+				return
+			}
+			if cap(z.Types) >= int(zb0002) {
+				z.Types = (z.Types)[:zb0002]
+			} else {
+				z.Types = make([]uint16, zb0002)
+			}
+			for za0001 := range z.Types {
+				z.Types[za0001], err = dc.ReadUint16()
+				if err != nil {
+					err = msgp.WrapError(err, "Types", za0001)
+					return
+				}
+			}
+		case "generation_type":
+			{
+				var zb0003 string
+				zb0003, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "GenerationType")
+					return
+				}
+				z.GenerationType = NodeGenerationType(zb0003)
+			}
+		case "first_seen":
+			z.FirstSeen, err = dc.ReadTime()
+			if err != nil {
+				err = msgp.WrapError(err, "FirstSeen")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *DNSNode) EncodeMsg(en *msgp.Writer) (err error) {
+	// omitempty: check for empty values
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	if z.FirstSeen == (time.Time{}) {
+		zb0001Len--
+		zb0001Mask |= 0x8
+	}
+	// variable map header, size zb0001Len
+	err = en.Append(0x80 | uint8(zb0001Len))
+	if err != nil {
+		return
+	}
+	if zb0001Len == 0 {
+		return
+	}
+	// write "name"
+	err = en.Append(0xa4, 0x6e, 0x61, 0x6d, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Name)
+	if err != nil {
+		err = msgp.WrapError(err, "Name")
+		return
+	}
+	// write "types"
+	err = en.Append(0xa5, 0x74, 0x79, 0x70, 0x65, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Types)))
+	if err != nil {
+		err = msgp.WrapError(err, "Types")
+		return
+	}
+	for za0001 := range z.Types {
+		err = en.WriteUint16(z.Types[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "Types", za0001)
+			return
+		}
+	}
+	// write "generation_type"
+	err = en.Append(0xaf, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(string(z.GenerationType))
+	if err != nil {
+		err = msgp.WrapError(err, "GenerationType")
+		return
+	}
+	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// write "first_seen"
+		err = en.Append(0xaa, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e)
+		if err != nil {
+			return
+		}
+		err = en.WriteTime(z.FirstSeen)
+		if err != nil {
+			err = msgp.WrapError(err, "FirstSeen")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *DNSNode) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// omitempty: check for empty values
+	zb0001Len := uint32(4)
+	var zb0001Mask uint8 /* 4 bits */
+	if z.FirstSeen == (time.Time{}) {
+		zb0001Len--
+		zb0001Mask |= 0x8
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+	if zb0001Len == 0 {
+		return
+	}
+	// string "name"
+	o = append(o, 0xa4, 0x6e, 0x61, 0x6d, 0x65)
+	o = msgp.AppendString(o, z.Name)
+	// string "types"
+	o = append(o, 0xa5, 0x74, 0x79, 0x70, 0x65, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Types)))
+	for za0001 := range z.Types {
+		o = msgp.AppendUint16(o, z.Types[za0001])
+	}
+	// string "generation_type"
+	o = append(o, 0xaf, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	o = msgp.AppendString(o, string(z.GenerationType))
+	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// string "first_seen"
+		o = append(o, 0xaa, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e)
+		o = msgp.AppendTime(o, z.FirstSeen)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *DNSNode) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "name":
+			z.Name, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Name")
+				return
+			}
+		case "types":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Types")
+				return
+			}
+			if cap(z.Types) >= int(zb0002) {
+				z.Types = (z.Types)[:zb0002]
+			} else {
+				z.Types = make([]uint16, zb0002)
+			}
+			for za0001 := range z.Types {
+				z.Types[za0001], bts, err = msgp.ReadUint16Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Types", za0001)
+					return
+				}
+			}
+		case "generation_type":
+			{
+				var zb0003 string
+				zb0003, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "GenerationType")
+					return
+				}
+				z.GenerationType = NodeGenerationType(zb0003)
+			}
+		case "first_seen":
+			z.FirstSeen, bts, err = msgp.ReadTimeBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "FirstSeen")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *DNSNode) Msgsize() (s int) {
+	s = 1 + 5 + msgp.StringPrefixSize + len(z.Name) + 6 + msgp.ArrayHeaderSize + (len(z.Types) * (msgp.Uint16Size)) + 16 + msgp.StringPrefixSize + len(string(z.GenerationType)) + 11 + msgp.TimeSize
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *FileActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
@@ -1451,33 +1669,75 @@ func (z *ProcessActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 				}
 				z.Files[za0001] = za0002
 			}
-		case "children":
+		case "dns_names":
 			var zb0004 uint32
-			zb0004, err = dc.ReadArrayHeader()
+			zb0004, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if z.DNSNames == nil {
+				z.DNSNames = make(map[string]*DNSNode, zb0004)
+			} else if len(z.DNSNames) > 0 {
+				for key := range z.DNSNames {
+					delete(z.DNSNames, key)
+				}
+			}
+			for zb0004 > 0 {
+				zb0004--
+				var za0003 string
+				var za0004 *DNSNode
+				za0003, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames")
+					return
+				}
+				if dc.IsNil() {
+					err = dc.ReadNil()
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+					za0004 = nil
+				} else {
+					if za0004 == nil {
+						za0004 = new(DNSNode)
+					}
+					err = za0004.DecodeMsg(dc)
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+				}
+				z.DNSNames[za0003] = za0004
+			}
+		case "children":
+			var zb0005 uint32
+			zb0005, err = dc.ReadArrayHeader()
 			if err != nil {
 				err = msgp.WrapError(err, "Children")
 				return
 			}
-			if cap(z.Children) >= int(zb0004) {
-				z.Children = (z.Children)[:zb0004]
+			if cap(z.Children) >= int(zb0005) {
+				z.Children = (z.Children)[:zb0005]
 			} else {
-				z.Children = make([]*ProcessActivityNode, zb0004)
+				z.Children = make([]*ProcessActivityNode, zb0005)
 			}
-			for za0003 := range z.Children {
+			for za0005 := range z.Children {
 				if dc.IsNil() {
 					err = dc.ReadNil()
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
-					z.Children[za0003] = nil
+					z.Children[za0005] = nil
 				} else {
-					if z.Children[za0003] == nil {
-						z.Children[za0003] = new(ProcessActivityNode)
+					if z.Children[za0005] == nil {
+						z.Children[za0005] = new(ProcessActivityNode)
 					}
-					err = z.Children[za0003].DecodeMsg(dc)
+					err = z.Children[za0005].DecodeMsg(dc)
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
 				}
@@ -1496,16 +1756,20 @@ func (z *ProcessActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 // EncodeMsg implements msgp.Encodable
 func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 	// omitempty: check for empty values
-	zb0001Len := uint32(4)
-	var zb0001Mask uint8 /* 4 bits */
+	zb0001Len := uint32(5)
+	var zb0001Mask uint8 /* 5 bits */
 	if z.Files == nil {
 		zb0001Len--
 		zb0001Mask |= 0x4
 	}
-	if z.Children == nil {
+	if z.DNSNames == nil {
 		zb0001Len--
 		zb0001Mask |= 0x8
 	}
+	if z.Children == nil {
+		zb0001Len--
+		zb0001Mask |= 0x10
+	}
 	// variable map header, size zb0001Len
 	err = en.Append(0x80 | uint8(zb0001Len))
 	if err != nil {
@@ -1566,6 +1830,37 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 		}
 	}
 	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// write "dns_names"
+		err = en.Append(0xa9, 0x64, 0x6e, 0x73, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73)
+		if err != nil {
+			return
+		}
+		err = en.WriteMapHeader(uint32(len(z.DNSNames)))
+		if err != nil {
+			err = msgp.WrapError(err, "DNSNames")
+			return
+		}
+		for za0003, za0004 := range z.DNSNames {
+			err = en.WriteString(za0003)
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if za0004 == nil {
+				err = en.WriteNil()
+				if err != nil {
+					return
+				}
+			} else {
+				err = za0004.EncodeMsg(en)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames", za0003)
+					return
+				}
+			}
+		}
+	}
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// write "children"
 		err = en.Append(0xa8, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65, 0x6e)
 		if err != nil {
@@ -1576,16 +1871,16 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 			err = msgp.WrapError(err, "Children")
 			return
 		}
-		for za0003 := range z.Children {
-			if z.Children[za0003] == nil {
+		for za0005 := range z.Children {
+			if z.Children[za0005] == nil {
 				err = en.WriteNil()
 				if err != nil {
 					return
 				}
 			} else {
-				err = z.Children[za0003].EncodeMsg(en)
+				err = z.Children[za0005].EncodeMsg(en)
 				if err != nil {
-					err = msgp.WrapError(err, "Children", za0003)
+					err = msgp.WrapError(err, "Children", za0005)
 					return
 				}
 			}
@@ -1598,16 +1893,20 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 func (z *ProcessActivityNode) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
 	// omitempty: check for empty values
-	zb0001Len := uint32(4)
-	var zb0001Mask uint8 /* 4 bits */
+	zb0001Len := uint32(5)
+	var zb0001Mask uint8 /* 5 bits */
 	if z.Files == nil {
 		zb0001Len--
 		zb0001Mask |= 0x4
 	}
-	if z.Children == nil {
+	if z.DNSNames == nil {
 		zb0001Len--
 		zb0001Mask |= 0x8
 	}
+	if z.Children == nil {
+		zb0001Len--
+		zb0001Mask |= 0x10
+	}
 	// variable map header, size zb0001Len
 	o = append(o, 0x80|uint8(zb0001Len))
 	if zb0001Len == 0 {
@@ -1641,16 +1940,33 @@ func (z *ProcessActivityNode) MarshalMsg(b []byte) (o []byte, err error) {
 		}
 	}
 	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// string "dns_names"
+		o = append(o, 0xa9, 0x64, 0x6e, 0x73, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73)
+		o = msgp.AppendMapHeader(o, uint32(len(z.DNSNames)))
+		for za0003, za0004 := range z.DNSNames {
+			o = msgp.AppendString(o, za0003)
+			if za0004 == nil {
+				o = msgp.AppendNil(o)
+			} else {
+				o, err = za0004.MarshalMsg(o)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames", za0003)
+					return
+				}
+			}
+		}
+	}
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// string "children"
 		o = append(o, 0xa8, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65, 0x6e)
 		o = msgp.AppendArrayHeader(o, uint32(len(z.Children)))
-		for za0003 := range z.Children {
-			if z.Children[za0003] == nil {
+		for za0005 := range z.Children {
+			if z.Children[za0005] == nil {
 				o = msgp.AppendNil(o)
 			} else {
-				o, err = z.Children[za0003].MarshalMsg(o)
+				o, err = z.Children[za0005].MarshalMsg(o)
 				if err != nil {
-					err = msgp.WrapError(err, "Children", za0003)
+					err = msgp.WrapError(err, "Children", za0005)
 					return
 				}
 			}
@@ -1734,32 +2050,73 @@ func (z *ProcessActivityNode) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				}
 				z.Files[za0001] = za0002
 			}
-		case "children":
+		case "dns_names":
 			var zb0004 uint32
-			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			zb0004, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if z.DNSNames == nil {
+				z.DNSNames = make(map[string]*DNSNode, zb0004)
+			} else if len(z.DNSNames) > 0 {
+				for key := range z.DNSNames {
+					delete(z.DNSNames, key)
+				}
+			}
+			for zb0004 > 0 {
+				var za0003 string
+				var za0004 *DNSNode
+				zb0004--
+				za0003, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames")
+					return
+				}
+				if msgp.IsNil(bts) {
+					bts, err = msgp.ReadNilBytes(bts)
+					if err != nil {
+						return
+					}
+					za0004 = nil
+				} else {
+					if za0004 == nil {
+						za0004 = new(DNSNode)
+					}
+					bts, err = za0004.UnmarshalMsg(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+				}
+				z.DNSNames[za0003] = za0004
+			}
+		case "children":
+			var zb0005 uint32
+			zb0005, bts, err = msgp.ReadArrayHeaderBytes(bts)
 			if err != nil {
 				err = msgp.WrapError(err, "Children")
 				return
 			}
-			if cap(z.Children) >= int(zb0004) {
-				z.Children = (z.Children)[:zb0004]
+			if cap(z.Children) >= int(zb0005) {
+				z.Children = (z.Children)[:zb0005]
 			} else {
-				z.Children = make([]*ProcessActivityNode, zb0004)
+				z.Children = make([]*ProcessActivityNode, zb0005)
 			}
-			for za0003 := range z.Children {
+			for za0005 := range z.Children {
 				if msgp.IsNil(bts) {
 					bts, err = msgp.ReadNilBytes(bts)
 					if err != nil {
 						return
 					}
-					z.Children[za0003] = nil
+					z.Children[za0005] = nil
 				} else {
-					if z.Children[za0003] == nil {
-						z.Children[za0003] = new(ProcessActivityNode)
+					if z.Children[za0005] == nil {
+						z.Children[za0005] = new(ProcessActivityNode)
 					}
-					bts, err = z.Children[za0003].UnmarshalMsg(bts)
+					bts, err = z.Children[za0005].UnmarshalMsg(bts)
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
 				}
@@ -1790,12 +2147,24 @@ func (z *ProcessActivityNode) Msgsize() (s int) {
 			}
 		}
 	}
+	s += 10 + msgp.MapHeaderSize
+	if z.DNSNames != nil {
+		for za0003, za0004 := range z.DNSNames {
+			_ = za0004
+			s += msgp.StringPrefixSize + len(za0003)
+			if za0004 == nil {
+				s += msgp.NilSize
+			} else {
+				s += za0004.Msgsize()
+			}
+		}
+	}
 	s += 9 + msgp.ArrayHeaderSize
-	for za0003 := range z.Children {
-		if z.Children[za0003] == nil {
+	for za0005 := range z.Children {
+		if z.Children[za0005] == nil {
 			s += msgp.NilSize
 		} else {
-			s += z.Children[za0003].Msgsize()
+			s += z.Children[za0005].Msgsize()
 		}
 	}
 	return
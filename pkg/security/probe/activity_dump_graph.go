@@ -10,6 +10,7 @@ package probe
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/template"
@@ -46,7 +47,8 @@ type graph struct {
 	Edges []edge
 }
 
-func (ad *ActivityDump) generateGraph() error {
+// generateGraph writes the graphviz graph of the activity tree to the provided writer
+func (ad *ActivityDump) generateGraph(w io.Writer) error {
 	tmpl := `digraph {
 		label = "{{ .Title }}"
 		labelloc =  "t"
@@ -71,7 +73,7 @@ func (ad *ActivityDump) generateGraph() error {
 	title := fmt.Sprintf("Activity tree: %s", ad.GetSelectorStr())
 	data := ad.prepareGraphData(title)
 	t := template.Must(template.New("tmpl").Parse(tmpl))
-	return t.Execute(ad.graphFile, data)
+	return t.Execute(w, data)
 }
 
 func (ad *ActivityDump) prepareGraphData(title string) graph {
@@ -166,18 +168,19 @@ func GenerateGraph(inputFile string) (string, error) {
 	}
 
 	// create profile output file
-	dump.graphFile, err = os.CreateTemp("/tmp", "graph-")
+	graphFile, err := os.CreateTemp("/tmp", "graph-")
 	if err != nil {
 		return "", fmt.Errorf("couldn't create profile file: %w", err)
 	}
+	defer graphFile.Close()
 
-	if err = os.Chmod(dump.graphFile.Name(), 0400); err != nil {
+	if err = os.Chmod(graphFile.Name(), 0400); err != nil {
 		return "", fmt.Errorf("couldn't change the mode of the profile file: %w", err)
 	}
 
-	if err = dump.generateGraph(); err != nil {
+	if err = dump.generateGraph(graphFile); err != nil {
 		return "", fmt.Errorf("couldn't generate graph from activity dump %s: %w", inputFile, err)
 	}
 
-	return dump.graphFile.Name(), nil
+	return graphFile.Name(), nil
 }
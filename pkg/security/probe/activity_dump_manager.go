@@ -23,6 +23,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
 )
 
+const (
+	// stoppedDumpsQueueSize is the maximum count of stopped dumps waiting to be persisted
+	stoppedDumpsQueueSize = 100
+	// stoppedDumpsDrainTimeout is the time given to persist the stopped dumps on shutdown
+	stoppedDumpsDrainTimeout = 10 * time.Second
+)
+
 func getTracedCgroupsCount(p *Probe) uint64 {
 	return uint64(p.config.ActivityDumpTracedCgroupsCount)
 }
@@ -44,9 +51,13 @@ type ActivityDumpManager struct {
 	cgroupWaitListMap    *ebpf.Map
 	tracedEventTypes     []model.EventType
 	outputDirectory      string
+	localFormats         []OutputFormat
+	remoteFormats        []OutputFormat
+	remoteStorage        *ActivityDumpRemoteStorage
 
 	activeDumps   []*ActivityDump
 	snapshotQueue chan *ActivityDump
+	stoppedDumps  chan *ActivityDump
 }
 
 // Start runs the ActivityDumpManager
@@ -62,6 +73,15 @@ func (adm *ActivityDumpManager) Start(ctx context.Context, wg *sync.WaitGroup) {
 	tagsTicker := time.NewTicker(adm.tagsResolutionPeriod)
 	defer tagsTicker.Stop()
 
+	// the remote storage is stopped once the stopped dumps are persisted, so that their last uploads are sent
+	storageCtx, stopStorage := context.WithCancel(context.Background())
+	wg.Add(2)
+	go adm.remoteStorage.Start(storageCtx, wg)
+	go func() {
+		defer stopStorage()
+		adm.persistStoppedDumps(ctx, wg)
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -87,7 +107,7 @@ func (adm *ActivityDumpManager) cleanup() {
 
 	for i, d := range adm.activeDumps {
 		if time.Now().After(d.Start.Add(d.Timeout)) {
-			d.Done()
+			adm.stopDump(d)
 
 			// prepend dump ids to delete
 			toDelete = append([]int{i}, toDelete...)
@@ -99,6 +119,50 @@ func (adm *ActivityDumpManager) cleanup() {
 	}
 }
 
+// stopDump stops an active dump and queues it so that it is persisted outside of the lock of the manager
+func (adm *ActivityDumpManager) stopDump(dump *ActivityDump) {
+	dump.Stop()
+
+	select {
+	case adm.stoppedDumps <- dump:
+	default:
+		seclog.Errorf("couldn't persist activity dump [%s]: too many stopped dumps waiting to be persisted", dump.GetSelectorStr())
+		dump.Discard()
+	}
+}
+
+// persistStoppedDumps persists the stopped dumps until the provided context is cancelled, the remaining dumps are then
+// persisted within stoppedDumpsDrainTimeout
+func (adm *ActivityDumpManager) persistStoppedDumps(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			adm.drainStoppedDumps(time.Now().Add(stoppedDumpsDrainTimeout))
+			return
+		case dump := <-adm.stoppedDumps:
+			dump.Finalize()
+		}
+	}
+}
+
+func (adm *ActivityDumpManager) drainStoppedDumps(deadline time.Time) {
+	for {
+		select {
+		case dump := <-adm.stoppedDumps:
+			if time.Now().After(deadline) {
+				seclog.Errorf("couldn't persist activity dump [%s]: shutdown timeout exceeded", dump.GetSelectorStr())
+				dump.Discard()
+				continue
+			}
+			dump.Finalize()
+		default:
+			return
+		}
+	}
+}
+
 // NewActivityDumpManager returns a new ActivityDumpManager instance
 func NewActivityDumpManager(p *Probe) (*ActivityDumpManager, error) {
 	tracedPIDs, found, err := p.manager.GetMap("traced_pids")
@@ -150,6 +214,21 @@ func NewActivityDumpManager(p *Probe) (*ActivityDumpManager, error) {
 		return nil, fmt.Errorf("couldn't find traced_cgroups map")
 	}
 
+	localFormats, err := parseOutputFormats(p.config.ActivityDumpLocalFormats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local storage formats: %w", err)
+	}
+
+	var remoteFormats []OutputFormat
+	if len(p.config.ActivityDumpRemoteEndpoint) > 0 {
+		if _, err = NewStorageRequest(RemoteStorage, JSON, false, p.config.ActivityDumpRemoteEndpoint); err != nil {
+			return nil, err
+		}
+		if remoteFormats, err = parseOutputFormats(p.config.ActivityDumpRemoteFormats); err != nil {
+			return nil, fmt.Errorf("invalid remote storage formats: %w", err)
+		}
+	}
+
 	return &ActivityDumpManager{
 		probe:                p,
 		tracedPIDsMap:        tracedPIDs,
@@ -161,10 +240,50 @@ func NewActivityDumpManager(p *Probe) (*ActivityDumpManager, error) {
 		cleanupPeriod:        p.config.ActivityDumpCleanupPeriod,
 		tagsResolutionPeriod: p.config.ActivityDumpTagsResolutionPeriod,
 		snapshotQueue:        make(chan *ActivityDump, 100),
+		stoppedDumps:         make(chan *ActivityDump, stoppedDumpsQueueSize),
 		outputDirectory:      p.config.ActivityDumpCgroupOutputDirectory,
+		localFormats:         localFormats,
+		remoteFormats:        remoteFormats,
+		remoteStorage:        NewActivityDumpRemoteStorage(),
 	}, nil
 }
 
+func parseOutputFormats(formats []string) ([]OutputFormat, error) {
+	var outputFormats []OutputFormat
+	for _, input := range formats {
+		format, err := ParseOutputFormat(input)
+		if err != nil {
+			return nil, err
+		}
+		outputFormats = append(outputFormats, format)
+	}
+	return outputFormats, nil
+}
+
+// newCgroupStorageRequests returns the storage requests of a cgroup activity dump, as configured
+func (adm *ActivityDumpManager) newCgroupStorageRequests() []*StorageRequest {
+	var requests []*StorageRequest
+	if len(adm.outputDirectory) > 0 {
+		for _, format := range adm.localFormats {
+			requests = append(requests, &StorageRequest{
+				Type:        LocalStorage,
+				Format:      format,
+				Compression: adm.probe.config.ActivityDumpLocalCompression,
+				Destination: adm.outputDirectory,
+			})
+		}
+	}
+	for _, format := range adm.remoteFormats {
+		requests = append(requests, &StorageRequest{
+			Type:        RemoteStorage,
+			Format:      format,
+			Compression: adm.probe.config.ActivityDumpRemoteCompression,
+			Destination: adm.probe.config.ActivityDumpRemoteEndpoint,
+		})
+	}
+	return requests
+}
+
 // insertActivityDump inserts an activity dump in the list of activity dumps handled by the manager
 func (adm *ActivityDumpManager) insertActivityDump(newDump *ActivityDump) error {
 	// sanity checks
@@ -237,12 +356,10 @@ func (adm *ActivityDumpManager) HandleCgroupTracingEvent(event *model.CgroupTrac
 		ad.ContainerID = event.ContainerContext.ID
 		ad.Timeout = adm.probe.resolvers.TimeResolver.ResolveMonotonicTimestamp(event.TimeoutRaw).Sub(time.Now())
 		ad.DifferentiateArgs = true
-		ad.WithGraph = true
-		ad.OutputDirectory = adm.outputDirectory
-		ad.OutputFormat = MSGP
+		ad.StorageRequests = adm.newCgroupStorageRequests()
 	})
 	if err != nil {
-		seclog.Errorf("couldn't start tracing [%s]: %v", newDump.GetSelectorStr(), err)
+		seclog.Errorf("couldn't start tracing [container_id:%s]: %v", event.ContainerContext.ID, err)
 		return
 	}
 
@@ -259,11 +376,9 @@ func (adm *ActivityDumpManager) DumpActivity(params *api.DumpActivityParams) (*a
 	adm.Lock()
 	defer adm.Unlock()
 
-	switch OutputFormat(params.GetOutputFormat()) {
-	case JSON, MSGP:
-		break
-	default:
-		errMsg := fmt.Errorf("unknown output format \"%s\", options are \"json\" and \"msgp\"", params.OutputFormat)
+	storageRequests, err := newStorageRequests(params)
+	if err != nil {
+		errMsg := fmt.Errorf("invalid storage request: %w", err)
 		return &api.SecurityActivityDumpMessage{Error: errMsg.Error()}, errMsg
 	}
 
@@ -271,13 +386,10 @@ func (adm *ActivityDumpManager) DumpActivity(params *api.DumpActivityParams) (*a
 		ad.Comm = params.GetComm()
 		ad.Timeout = time.Duration(params.Timeout) * time.Minute
 		ad.DifferentiateArgs = params.GetDifferentiateArgs()
-		ad.WithGraph = params.GetWithGraph()
-		ad.OutputDirectory = params.GetOutputDirectory()
-		ad.OutputFormat = OutputFormat(params.GetOutputFormat())
+		ad.StorageRequests = storageRequests
 	})
 	if err != nil {
-		newDump.Close()
-		errMsg := fmt.Errorf("couldn't start tracing [%s]: %v", newDump.GetSelectorStr(), err)
+		errMsg := fmt.Errorf("couldn't start tracing [comm:%s]: %v", params.GetComm(), err)
 		return &api.SecurityActivityDumpMessage{Error: errMsg.Error()}, errMsg
 	}
 
@@ -313,7 +425,7 @@ func (adm *ActivityDumpManager) StopActivityDump(params *api.StopActivityDumpPar
 	toDelete := -1
 	for i, d := range adm.activeDumps {
 		if d.CommMatches(params.GetComm()) {
-			d.Done()
+			adm.stopDump(d)
 			seclog.Infof("tracing stopped for [%s]", d.GetSelectorStr())
 			toDelete = i
 			break
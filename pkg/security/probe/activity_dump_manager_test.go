@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/api"
+)
+
+func TestActivityDumpManagerPersistOutsideOfLock(t *testing.T) {
+	binary, inode, hash := newTestBinary(t)
+
+	adm := &ActivityDumpManager{
+		probe:         &Probe{statsdClient: &statsd.NoOpClient{}},
+		stoppedDumps:  make(chan *ActivityDump, stoppedDumpsQueueSize),
+		remoteStorage: NewActivityDumpRemoteStorage(),
+	}
+
+	ad := newTestActivityDump(t, binary, inode)
+	ad.adm = adm
+	// no kernel filter to remove
+	ad.Comm = ""
+
+	// the dump is written to a full pipe, which blocks its persistence until the pipe is read
+	output, input := newFullPipe(t)
+	request, err := NewStorageRequest(LocalStorage, SBOM, false, t.TempDir())
+	require.NoError(t, err)
+	request.file = input
+	ad.StorageRequests = []*StorageRequest{request}
+	adm.activeDumps = []*ActivityDump{ad}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go adm.persistStoppedDumps(ctx, &wg)
+	defer cancel()
+
+	_, err = adm.StopActivityDump(&api.StopActivityDumpParams{})
+	require.NoError(t, err)
+	assert.Empty(t, adm.activeDumps)
+
+	// wait for the dump to be picked up by the persistence worker
+	require.Eventually(t, func() bool { return len(adm.stoppedDumps) == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	processed := make(chan struct{})
+	go func() {
+		adm.ProcessEvent(&Event{})
+		close(processed)
+	}()
+
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ProcessEvent blocked while an activity dump is persisted")
+	}
+
+	// let the persistence complete
+	written := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(output)
+		written <- data
+	}()
+	cancel()
+	wg.Wait()
+	input.Close()
+
+	assert.Contains(t, string(<-written), hash)
+}
+
+// newFullPipe returns a pipe whose buffer is full, the writes to it block until it is read
+func newFullPipe(t *testing.T) (*os.File, *os.File) {
+	var fds [2]int
+	require.NoError(t, syscall.Pipe(fds[:]))
+	require.NoError(t, syscall.SetNonblock(fds[1], true))
+	filler := make([]byte, 4096)
+	for {
+		if _, err := syscall.Write(fds[1], filler); err != nil {
+			require.Equal(t, syscall.EAGAIN, err)
+			break
+		}
+	}
+	require.NoError(t, syscall.SetNonblock(fds[1], false))
+
+	output, input := os.NewFile(uintptr(fds[0]), "output"), os.NewFile(uintptr(fds[1]), "input")
+	t.Cleanup(func() {
+		output.Close()
+		input.Close()
+	})
+	return output, input
+}
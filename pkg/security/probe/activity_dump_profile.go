@@ -14,6 +14,9 @@ import (
 	"text/template"
 
 	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/utils"
 )
 
 var profileTmpl = `---
@@ -61,3 +64,82 @@ func GenerateProfile(inputFile string) (string, error) {
 
 	return profile.Name(), nil
 }
+
+// GenerateSecurityProfile creates a security profile, in learning state, from the input activity dump. The profile
+// selects the image of the workload of the dump.
+func GenerateSecurityProfile(inputFile string) (string, error) {
+	// open and parse activity dump file
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return "", fmt.Errorf("couldn't open activity dump file: %w", err)
+	}
+	defer f.Close()
+
+	var dump ActivityDump
+	if err = dump.DecodeMsg(msgp.NewReader(f)); err != nil {
+		return "", fmt.Errorf("couldn't parse activity dump file: %w", err)
+	}
+
+	profile, err := dump.GenerateSecurityProfile()
+	if err != nil {
+		return "", err
+	}
+
+	// create security profile output file
+	output, err := os.CreateTemp("/tmp", "security-profile-*.profile")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create security profile file: %w", err)
+	}
+	output.Close()
+
+	if err = profile.SaveAs(output.Name()); err != nil {
+		return "", err
+	}
+
+	return output.Name(), nil
+}
+
+// GenerateSecurityProfile returns a security profile holding the processes, files and DNS names of the activity dump
+func (ad *ActivityDump) GenerateSecurityProfile() (*SecurityProfile, error) {
+	selector := SecurityProfileSelector{
+		ImageName: utils.GetTagValue("image_name", ad.Tags),
+		ImageTag:  utils.GetTagValue("image_tag", ad.Tags),
+	}
+	if len(selector.ImageName) == 0 {
+		return nil, fmt.Errorf("the activity dump doesn't have an image_name tag")
+	}
+
+	profile := NewSecurityProfile("profile_"+eval.RandString(5), selector)
+	for _, node := range ad.ProcessActivityTree {
+		node.addToSecurityProfile(profile)
+	}
+
+	return profile, nil
+}
+
+func (pan *ProcessActivityNode) addToSecurityProfile(profile *SecurityProfile) {
+	processPath := pan.Process.FileEvent.PathnameStr
+	profile.add(behaviour{kind: ProcessAnomaly, value: processPath})
+
+	for _, file := range pan.Files {
+		file.addToSecurityProfile(profile, processPath)
+	}
+
+	for name := range pan.DNSNames {
+		profile.add(behaviour{kind: DNSAnomaly, processPath: processPath, value: name})
+	}
+
+	for _, child := range pan.Children {
+		child.addToSecurityProfile(profile)
+	}
+}
+
+func (fan *FileActivityNode) addToSecurityProfile(profile *SecurityProfile, processPath string) {
+	if fan.File != nil && fan.Open != nil {
+		profile.add(behaviour{kind: FileAnomaly, processPath: processPath, value: fan.File.PathnameStr})
+	}
+
+	for _, child := range fan.Children {
+		child.addToSecurityProfile(profile, processPath)
+	}
+}
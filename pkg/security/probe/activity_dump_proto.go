@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/api"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+// ToActivityDumpMessage returns a pointer to an ActivityDumpMessage struct populated with the activity tree of the
+// dump. The arguments of the processes are scrubbed and only the names of the environment variables are kept.
+func (ad *ActivityDump) ToActivityDumpMessage() *api.ActivityDumpMessage {
+	msg := &api.ActivityDumpMessage{
+		Comm:              ad.Comm,
+		ContainerID:       ad.ContainerID,
+		Tags:              ad.Tags,
+		DifferentiateArgs: ad.DifferentiateArgs,
+		Start:             timestampToProto(ad.Start),
+		End:               timestampToProto(ad.End),
+		Tree:              make([]*api.ProcessActivityNodeMessage, 0, len(ad.ProcessActivityTree)),
+	}
+
+	for _, node := range ad.ProcessActivityTree {
+		msg.Tree = append(msg.Tree, ad.processActivityNodeToProto(node))
+	}
+	return msg
+}
+
+func (ad *ActivityDump) processActivityNodeToProto(pan *ProcessActivityNode) *api.ProcessActivityNodeMessage {
+	msg := &api.ProcessActivityNodeMessage{
+		Process:        ad.processToProto(&pan.Process),
+		GenerationType: string(pan.GenerationType),
+		Files:          make([]*api.FileActivityNodeMessage, 0, len(pan.Files)),
+		DNSNames:       make([]*api.DNSNodeMessage, 0, len(pan.DNSNames)),
+		Children:       make([]*api.ProcessActivityNodeMessage, 0, len(pan.Children)),
+	}
+
+	for _, name := range sortedFileNames(pan.Files) {
+		msg.Files = append(msg.Files, fileActivityNodeToProto(pan.Files[name]))
+	}
+
+	for _, name := range sortedDNSNames(pan.DNSNames) {
+		msg.DNSNames = append(msg.DNSNames, dnsNodeToProto(pan.DNSNames[name]))
+	}
+
+	for _, child := range pan.Children {
+		msg.Children = append(msg.Children, ad.processActivityNodeToProto(child))
+	}
+
+	return msg
+}
+
+func (ad *ActivityDump) processToProto(process *model.Process) *api.ProcessInfoMessage {
+	msg := &api.ProcessInfoMessage{
+		Pid:         process.Pid,
+		Tid:         process.Tid,
+		PPid:        process.PPid,
+		Cookie:      process.Cookie,
+		File:        fileToProto(&process.FileEvent),
+		ContainerID: process.ContainerID,
+		TTY:         process.TTYName,
+		Comm:        process.Comm,
+		ForkTime:    timestampToProto(process.ForkTime),
+		ExitTime:    timestampToProto(process.ExitTime),
+		ExecTime:    timestampToProto(process.ExecTime),
+		UID:         process.UID,
+		GID:         process.GID,
+		User:        process.User,
+		Group:       process.Group,
+	}
+
+	argv, truncated := getDumpProcessArgv(process)
+	if len(argv) > 0 {
+		msg.Argv0 = argv[0]
+		msg.Args = ad.scrubArgs(argv[1:])
+	}
+	msg.ArgsTruncated = truncated
+	msg.Envs, msg.EnvsTruncated = getDumpProcessEnvs(process)
+
+	return msg
+}
+
+func fileToProto(file *model.FileEvent) *api.FileInfoMessage {
+	if file == nil {
+		return nil
+	}
+
+	return &api.FileInfoMessage{
+		Path:         file.PathnameStr,
+		Name:         file.BasenameStr,
+		Inode:        file.Inode,
+		MountID:      file.MountID,
+		Mode:         uint32(file.Mode),
+		UID:          file.UID,
+		GID:          file.GID,
+		User:         file.User,
+		Group:        file.Group,
+		MTime:        file.MTime,
+		CTime:        file.CTime,
+		Filesystem:   file.Filesystem,
+		InUpperLayer: file.InUpperLayer,
+	}
+}
+
+func fileActivityNodeToProto(fan *FileActivityNode) *api.FileActivityNodeMessage {
+	msg := &api.FileActivityNodeMessage{
+		Name:           fan.Name,
+		File:           fileToProto(fan.File),
+		GenerationType: string(fan.GenerationType),
+		FirstSeen:      timestampToProto(fan.FirstSeen),
+		Children:       make([]*api.FileActivityNodeMessage, 0, len(fan.Children)),
+	}
+
+	if fan.Open != nil {
+		msg.Open = &api.OpenNodeMessage{
+			Retval: fan.Open.Retval,
+			Flags:  fan.Open.Flags,
+			Mode:   fan.Open.Mode,
+		}
+	}
+
+	for _, name := range sortedFileNames(fan.Children) {
+		msg.Children = append(msg.Children, fileActivityNodeToProto(fan.Children[name]))
+	}
+
+	return msg
+}
+
+func dnsNodeToProto(dn *DNSNode) *api.DNSNodeMessage {
+	msg := &api.DNSNodeMessage{
+		Name:           dn.Name,
+		Types:          make([]uint32, 0, len(dn.Types)),
+		GenerationType: string(dn.GenerationType),
+		FirstSeen:      timestampToProto(dn.FirstSeen),
+	}
+	for _, qtype := range dn.Types {
+		msg.Types = append(msg.Types, uint32(qtype))
+	}
+	return msg
+}
+
+// timestampToProto returns the provided time as a number of nanoseconds since epoch, 0 for the zero time
+func timestampToProto(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// scrubArgs removes the sensitive values from the provided arguments
+func (ad *ActivityDump) scrubArgs(args []string) []string {
+	if ad.adm == nil || ad.adm.probe == nil || ad.adm.probe.scrubber == nil {
+		return args
+	}
+	scrubbed, _ := ad.adm.probe.scrubber.ScrubCommand(args)
+	return scrubbed
+}
+
+// getDumpProcessArgv returns the arguments of a process of the dump, argv0 included. The values are read directly
+// when the dump was decoded from a file, the args cache entries are released at this point.
+func getDumpProcessArgv(process *model.Process) ([]string, bool) {
+	if process.ArgsEntry == nil {
+		return nil, process.ArgsTruncated
+	}
+
+	argv, truncated := process.ArgsEntry.Values, process.ArgsEntry.Truncated
+	if process.ArgsEntry.ArgsEnvsCacheEntry != nil {
+		argv, truncated = process.ArgsEntry.ToArray()
+	}
+	return argv, process.ArgsTruncated || truncated
+}
+
+// getDumpProcessEnvs returns the names of the environment variables of a process of the dump
+func getDumpProcessEnvs(process *model.Process) ([]string, bool) {
+	if process.EnvsEntry == nil {
+		return nil, process.EnvsTruncated
+	}
+
+	envp, truncated := process.EnvsEntry.Values, process.EnvsEntry.Truncated
+	if process.EnvsEntry.ArgsEnvsCacheEntry != nil {
+		envp, truncated = process.EnvsEntry.ToArray()
+	}
+
+	envs := make([]string, 0, len(envp))
+	for _, env := range envp {
+		envs = append(envs, strings.SplitN(env, "=", 2)[0])
+	}
+	return envs, process.EnvsTruncated || truncated
+}
@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/utils"
+)
+
+const (
+	// sbomMaxBinarySize is the size above which the executed binaries aren't hashed
+	sbomMaxBinarySize = 256 * 1024 * 1024
+	// sbomHashTimeout is the time given to hash the binaries of a dump, the binaries which aren't hashed in time are
+	// reported with an error
+	sbomHashTimeout = 30 * time.Second
+)
+
+var (
+	errSBOMBinaryNotFound = errors.New("binary not found")
+	errSBOMHashTimeout    = errors.New("hashing timeout exceeded")
+	errSBOMNotRegularFile = errors.New("not a regular file")
+)
+
+// deadlineReader fails the reads once its deadline is exceeded
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	if time.Now().After(dr.deadline) {
+		return 0, errSBOMHashTimeout
+	}
+	return dr.r.Read(p)
+}
+
+// ActivityDumpSBOM summarizes the binaries executed during an activity dump
+type ActivityDumpSBOM struct {
+	Selector    string        `json:"selector"`
+	ContainerID string        `json:"container_id,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	Binaries    []*SBOMBinary `json:"binaries"`
+}
+
+// SBOMBinary describes a binary executed during an activity dump. The hash is computed when the dump is persisted, an
+// error is reported instead if the binary was removed or replaced in the meantime.
+type SBOMBinary struct {
+	Path         string    `json:"path"`
+	Inode        uint64    `json:"inode,omitempty"`
+	MountID      uint32    `json:"mount_id,omitempty"`
+	Mode         uint32    `json:"mode,omitempty"`
+	UID          uint32    `json:"uid"`
+	GID          uint32    `json:"gid"`
+	User         string    `json:"user,omitempty"`
+	Group        string    `json:"group,omitempty"`
+	InUpperLayer bool      `json:"in_upper_layer"`
+	SHA256       string    `json:"sha256,omitempty"`
+	HashError    string    `json:"hash_error,omitempty"`
+	Executions   int       `json:"executions"`
+	FirstSeen    time.Time `json:"first_seen,omitempty"`
+
+	pids        []uint32
+	containerID string
+}
+
+type sbomBinaryKey struct {
+	path  string
+	inode uint64
+}
+
+// generateSBOM writes the summary of the binaries executed during the dump to the provided writer
+func (ad *ActivityDump) generateSBOM(w io.Writer) error {
+	binaries := make(map[sbomBinaryKey]*SBOMBinary)
+	for _, node := range ad.ProcessActivityTree {
+		node.collectBinaries(binaries)
+	}
+
+	sbom := ActivityDumpSBOM{
+		Selector:    ad.GetSelectorStr(),
+		ContainerID: ad.ContainerID,
+		Tags:        ad.Tags,
+		Start:       ad.Start,
+		End:         ad.End,
+		Binaries:    make([]*SBOMBinary, 0, len(binaries)),
+	}
+
+	deadline := time.Now().Add(sbomHashTimeout)
+	for _, binary := range binaries {
+		if hash, err := binary.hash(deadline); err != nil {
+			binary.HashError = err.Error()
+		} else {
+			binary.SHA256 = hash
+		}
+		sbom.Binaries = append(sbom.Binaries, binary)
+	}
+
+	sort.Slice(sbom.Binaries, func(i, j int) bool {
+		if sbom.Binaries[i].Path != sbom.Binaries[j].Path {
+			return sbom.Binaries[i].Path < sbom.Binaries[j].Path
+		}
+		return sbom.Binaries[i].Inode < sbom.Binaries[j].Inode
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sbom)
+}
+
+// collectBinaries indexes the binaries executed by the process of the node and its children
+func (pan *ProcessActivityNode) collectBinaries(binaries map[sbomBinaryKey]*SBOMBinary) {
+	file := &pan.Process.FileEvent
+	if len(file.PathnameStr) > 0 {
+		key := sbomBinaryKey{path: file.PathnameStr, inode: file.Inode}
+		binary, ok := binaries[key]
+		if !ok {
+			binary = &SBOMBinary{
+				Path:         file.PathnameStr,
+				Inode:        file.Inode,
+				MountID:      file.MountID,
+				Mode:         uint32(file.Mode),
+				UID:          file.UID,
+				GID:          file.GID,
+				User:         file.User,
+				Group:        file.Group,
+				InUpperLayer: file.InUpperLayer,
+				containerID:  pan.Process.ContainerID,
+			}
+			binaries[key] = binary
+		}
+
+		binary.Executions++
+		binary.pids = append(binary.pids, pan.Process.Pid)
+		if execTime := pan.Process.ExecTime; !execTime.IsZero() && (binary.FirstSeen.IsZero() || execTime.Before(binary.FirstSeen)) {
+			binary.FirstSeen = execTime
+		}
+	}
+
+	for _, child := range pan.Children {
+		child.collectBinaries(binaries)
+	}
+}
+
+// hash returns the sha256 of the binary, read through the root of one of the processes which executed it. The host
+// path is only used for the binaries executed outside of a container.
+func (b *SBOMBinary) hash(deadline time.Time) (string, error) {
+	candidates := make([]string, 0, len(b.pids)+1)
+	for _, pid := range b.pids {
+		candidates = append(candidates, filepath.Join(utils.RootPath(int32(pid)), b.Path))
+	}
+	if len(b.containerID) == 0 {
+		candidates = append(candidates, b.Path)
+	}
+
+	for _, candidate := range candidates {
+		hash, err := b.hashFile(candidate, deadline)
+		if err == nil {
+			return hash, nil
+		}
+		if err != errSBOMBinaryNotFound {
+			return "", err
+		}
+	}
+	return "", errSBOMBinaryNotFound
+}
+
+// hashFile hashes the provided file. The path is controlled by the workload, the file is opened without blocking so
+// that a FIFO or a device can't stall the persistence of the dumps, and only regular files are read.
+func (b *SBOMBinary) hashFile(filename string, deadline time.Time) (string, error) {
+	if time.Now().After(deadline) {
+		return "", errSBOMHashTimeout
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return "", errSBOMBinaryNotFound
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", errSBOMBinaryNotFound
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", errSBOMNotRegularFile
+	}
+
+	// make sure that the binary wasn't replaced since it was executed
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && b.Inode != 0 && stat.Ino != b.Inode {
+		return "", errSBOMBinaryNotFound
+	}
+
+	if info.Size() > sbomMaxBinarySize {
+		return "", fmt.Errorf("binary too large to be hashed (%d bytes)", info.Size())
+	}
+
+	h := sha256.New()
+	if _, err = io.Copy(h, &deadlineReader{r: f, deadline: deadline}); err != nil {
+		return "", fmt.Errorf("couldn't hash binary: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,391 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/DataDog/datadog-agent/pkg/security/api"
+	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+// StorageType is used to define the type of storage of a dump
+type StorageType string
+
+var (
+	// LocalStorage is used to request a file in a local directory
+	LocalStorage StorageType = "local"
+	// RemoteStorage is used to request an upload to a remote HTTP endpoint
+	RemoteStorage StorageType = "remote"
+)
+
+// ParseStorageType returns the storage type matching the provided string
+func ParseStorageType(input string) (StorageType, error) {
+	switch StorageType(input) {
+	case LocalStorage, RemoteStorage:
+		return StorageType(input), nil
+	default:
+		return "", fmt.Errorf("unknown storage type \"%s\", options are \"%s\" and \"%s\"", input, LocalStorage, RemoteStorage)
+	}
+}
+
+var formatExtensions = map[OutputFormat]string{
+	JSON:     ".json",
+	MSGP:     ".msgp",
+	PROTOBUF: ".protobuf",
+	JSONL:    ".jsonl",
+	SBOM:     ".sbom.json",
+	DOT:      ".dot",
+}
+
+var formatContentTypes = map[OutputFormat]string{
+	JSON:     "application/json",
+	MSGP:     "application/msgpack",
+	PROTOBUF: "application/x-protobuf",
+	JSONL:    "application/x-ndjson",
+	SBOM:     "application/json",
+	DOT:      "text/vnd.graphviz",
+}
+
+// StorageRequest describes where and how a dump should be persisted
+type StorageRequest struct {
+	Type        StorageType
+	Format      OutputFormat
+	Compression bool
+	// Destination is the output directory of a local storage, and the URL of a remote storage
+	Destination string
+
+	// File is the output file of a local storage
+	File string
+	file *os.File
+}
+
+// NewStorageRequest returns a new StorageRequest instance, after checking that the destination is valid for the
+// requested storage type
+func NewStorageRequest(storageType StorageType, format OutputFormat, compression bool, destination string) (*StorageRequest, error) {
+	if len(destination) == 0 {
+		return nil, fmt.Errorf("missing destination for %s storage", storageType)
+	}
+
+	if storageType == RemoteStorage {
+		endpoint, err := url.Parse(destination)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote storage endpoint: %w", err)
+		}
+		if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+			return nil, fmt.Errorf("invalid remote storage endpoint \"%s\": only http and https are supported", destination)
+		}
+	}
+
+	return &StorageRequest{
+		Type:        storageType,
+		Format:      format,
+		Compression: compression,
+		Destination: destination,
+	}, nil
+}
+
+// newStorageRequests returns the storage requests of the provided activity dump request. The legacy output directory
+// and format are used when no storage is requested.
+func newStorageRequests(params *api.DumpActivityParams) ([]*StorageRequest, error) {
+	var requests []*StorageRequest
+	var graphDirectory string
+
+	for _, storage := range params.GetStorage() {
+		storageType, err := ParseStorageType(storage.GetType())
+		if err != nil {
+			return nil, err
+		}
+		format, err := ParseOutputFormat(storage.GetFormat())
+		if err != nil {
+			return nil, err
+		}
+		request, err := NewStorageRequest(storageType, format, storage.GetCompression(), storage.GetDestination())
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+
+		if storageType == LocalStorage && len(graphDirectory) == 0 {
+			graphDirectory = request.Destination
+		}
+	}
+
+	if len(params.GetStorage()) == 0 && len(params.GetOutputDirectory()) > 0 {
+		format, err := ParseOutputFormat(params.GetOutputFormat())
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, &StorageRequest{
+			Type:        LocalStorage,
+			Format:      format,
+			Destination: params.GetOutputDirectory(),
+		})
+		graphDirectory = params.GetOutputDirectory()
+	}
+
+	// the graph is generated next to the first local file, unless it was explicitly requested
+	if params.GetWithGraph() {
+		for _, request := range requests {
+			if request.Format == DOT {
+				return requests, nil
+			}
+		}
+		if len(graphDirectory) == 0 {
+			return nil, fmt.Errorf("a local storage is required to generate a graph")
+		}
+		requests = append(requests, &StorageRequest{
+			Type:        LocalStorage,
+			Format:      DOT,
+			Destination: graphDirectory,
+		})
+	}
+
+	return requests, nil
+}
+
+// String returns a string representation of the storage request
+func (sr *StorageRequest) String() string {
+	return fmt.Sprintf("%s storage (format: %s, compression: %v, destination: %s)", sr.Type, sr.Format, sr.Compression, sr.Destination)
+}
+
+// ToStorageRequestMessage returns an api.StorageRequestMessage populated with the storage request
+func (sr *StorageRequest) ToStorageRequestMessage() *api.StorageRequestMessage {
+	msg := &api.StorageRequestMessage{
+		Type:        string(sr.Type),
+		Format:      string(sr.Format),
+		Compression: sr.Compression,
+		File:        sr.File,
+	}
+	if sr.Type == RemoteStorage {
+		msg.File = sr.Destination
+	}
+	return msg
+}
+
+// open creates the output file of a local storage request
+func (sr *StorageRequest) open() error {
+	if sr.Type != LocalStorage {
+		return nil
+	}
+
+	prefix := "activity-dump-"
+	if sr.Format == DOT {
+		prefix = "graph-dump-"
+	}
+	extension := formatExtensions[sr.Format]
+	if sr.Compression {
+		extension += ".gz"
+	}
+
+	// generate random output file
+	_ = os.MkdirAll(sr.Destination, 0400)
+	file, err := os.CreateTemp(sr.Destination, prefix+"*"+extension)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Chmod(file.Name(), 0400); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	sr.file = file
+	sr.File = file.Name()
+	return nil
+}
+
+// close closes the output file of a local storage request
+func (sr *StorageRequest) close() {
+	if sr.file != nil {
+		_ = sr.file.Close()
+		sr.file = nil
+	}
+}
+
+// encode thread unsafe version of Encode
+func (ad *ActivityDump) encode(format OutputFormat) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case MSGP:
+		if err := msgp.Encode(&buf, ad); err != nil {
+			return nil, fmt.Errorf("couldn't marshal ActivityDump: %w", err)
+		}
+	case JSON:
+		raw, err := ad.MarshalMsg(nil)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal ActivityDump: %w", err)
+		}
+		if _, err = msgp.UnmarshalAsJSON(&buf, raw); err != nil {
+			return nil, fmt.Errorf("couldn't marshal ActivityDump to JSON: %w", err)
+		}
+	case PROTOBUF:
+		raw, err := proto.Marshal(ad.ToActivityDumpMessage())
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal ActivityDump to protobuf: %w", err)
+		}
+		buf.Write(raw)
+	case JSONL:
+		if err := ad.generateTimeline(&buf); err != nil {
+			return nil, fmt.Errorf("couldn't generate the timeline of the ActivityDump: %w", err)
+		}
+	case SBOM:
+		if err := ad.generateSBOM(&buf); err != nil {
+			return nil, fmt.Errorf("couldn't generate the SBOM of the ActivityDump: %w", err)
+		}
+	case DOT:
+		if err := ad.generateGraph(&buf); err != nil {
+			return nil, fmt.Errorf("couldn't generate activity graph: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format \"%s\"", format)
+	}
+
+	return &buf, nil
+}
+
+// Encode encodes the activity dump in the provided format
+func (ad *ActivityDump) Encode(format OutputFormat) (*bytes.Buffer, error) {
+	ad.Lock()
+	defer ad.Unlock()
+	return ad.encode(format)
+}
+
+// compress returns the gzip compressed version of the provided data
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+const (
+	remoteStorageQueueSize = 100
+	remoteStorageTimeout   = 30 * time.Second
+	// remoteStorageDrainTimeout is the time given to send the queued dumps on shutdown
+	remoteStorageDrainTimeout = 10 * time.Second
+)
+
+// remoteStorageUpload is a dump waiting to be sent to a remote storage
+type remoteStorageUpload struct {
+	request  *StorageRequest
+	selector string
+	data     []byte
+}
+
+// ActivityDumpRemoteStorage sends the dumps to remote HTTP endpoints. The dumps are sent in the background so that
+// a slow endpoint doesn't block the activity dump manager.
+type ActivityDumpRemoteStorage struct {
+	client *http.Client
+	queue  chan *remoteStorageUpload
+}
+
+// NewActivityDumpRemoteStorage returns a new instance of ActivityDumpRemoteStorage
+func NewActivityDumpRemoteStorage() *ActivityDumpRemoteStorage {
+	return &ActivityDumpRemoteStorage{
+		client: &http.Client{
+			Timeout:   remoteStorageTimeout,
+			Transport: httputils.CreateHTTPTransport(),
+		},
+		queue: make(chan *remoteStorageUpload, remoteStorageQueueSize),
+	}
+}
+
+// Start sends the queued dumps until the provided context is cancelled, the remaining dumps are then sent within
+// remoteStorageDrainTimeout. An upload in progress isn't interrupted by the cancellation, it is bounded by the timeout
+// of the client.
+func (storage *ActivityDumpRemoteStorage) Start(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			storage.drain(remoteStorageDrainTimeout)
+			return
+		case upload := <-storage.queue:
+			storage.upload(context.Background(), upload)
+		}
+	}
+}
+
+func (storage *ActivityDumpRemoteStorage) drain(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		select {
+		case upload := <-storage.queue:
+			if ctx.Err() != nil {
+				seclog.Errorf("couldn't send activity dump [%s] to %s: shutdown timeout exceeded", upload.selector, upload.request.Destination)
+				continue
+			}
+			storage.upload(ctx, upload)
+		default:
+			return
+		}
+	}
+}
+
+func (storage *ActivityDumpRemoteStorage) upload(ctx context.Context, upload *remoteStorageUpload) {
+	if err := storage.send(ctx, upload); err != nil {
+		seclog.Errorf("couldn't send activity dump [%s] to %s: %v", upload.selector, upload.request.Destination, err)
+		return
+	}
+	seclog.Infof("activity dump for [%s] sent to %s", upload.selector, upload.request.Destination)
+}
+
+// Persist queues the provided encoded dump for the remote storage of the request
+func (storage *ActivityDumpRemoteStorage) Persist(request *StorageRequest, selector string, data []byte) error {
+	select {
+	case storage.queue <- &remoteStorageUpload{request: request, selector: selector, data: data}:
+		return nil
+	default:
+		return fmt.Errorf("remote storage queue is full")
+	}
+}
+
+func (storage *ActivityDumpRemoteStorage) send(ctx context.Context, upload *remoteStorageUpload) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.request.Destination, bytes.NewReader(upload.data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", formatContentTypes[upload.request.Format])
+	if upload.request.Compression {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+	return nil
+}
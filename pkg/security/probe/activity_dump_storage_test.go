@@ -0,0 +1,398 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/api"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/policytest"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, format := range AllOutputFormats {
+		parsed, err := ParseOutputFormat(string(format))
+		assert.NoError(t, err)
+		assert.Equal(t, format, parsed)
+	}
+
+	_, err := ParseOutputFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestNewStorageRequests(t *testing.T) {
+	t.Run("legacy", func(t *testing.T) {
+		requests, err := newStorageRequests(&api.DumpActivityParams{
+			OutputDirectory: "/tmp/dumps",
+			OutputFormat:    "json",
+			WithGraph:       true,
+		})
+		require.NoError(t, err)
+		require.Len(t, requests, 2)
+		assert.Equal(t, StorageRequest{Type: LocalStorage, Format: JSON, Destination: "/tmp/dumps"}, *requests[0])
+		assert.Equal(t, StorageRequest{Type: LocalStorage, Format: DOT, Destination: "/tmp/dumps"}, *requests[1])
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		requests, err := newStorageRequests(&api.DumpActivityParams{
+			Storage: []*api.StorageRequestParams{
+				{Type: "local", Format: "jsonl", Compression: true, Destination: "/tmp/dumps"},
+				{Type: "remote", Format: "protobuf", Compression: true, Destination: "https://example.com/dumps"},
+			},
+			OutputDirectory: "/tmp/ignored",
+			OutputFormat:    "msgp",
+		})
+		require.NoError(t, err)
+		require.Len(t, requests, 2)
+		assert.Equal(t, StorageRequest{Type: LocalStorage, Format: JSONL, Compression: true, Destination: "/tmp/dumps"}, *requests[0])
+		assert.Equal(t, StorageRequest{Type: RemoteStorage, Format: PROTOBUF, Compression: true, Destination: "https://example.com/dumps"}, *requests[1])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, storage := range []*api.StorageRequestParams{
+			{Type: "s3", Format: "json", Destination: "/tmp/dumps"},
+			{Type: "local", Format: "yaml", Destination: "/tmp/dumps"},
+			{Type: "local", Format: "json"},
+			{Type: "remote", Format: "json", Destination: "ftp://example.com/dumps"},
+		} {
+			_, err := newStorageRequests(&api.DumpActivityParams{Storage: []*api.StorageRequestParams{storage}})
+			assert.Error(t, err, "%v", storage)
+		}
+
+		_, err := newStorageRequests(&api.DumpActivityParams{
+			Storage: []*api.StorageRequestParams{
+				{Type: "remote", Format: "json", Destination: "https://example.com/dumps"},
+			},
+			WithGraph: true,
+		})
+		assert.Error(t, err, "a graph requires a local storage")
+	})
+}
+
+func newTestActivityDump(t *testing.T, binary string, inode uint64) *ActivityDump {
+	start := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	newProcess := func(pid uint32, path string, ino uint64, execTime time.Time, args ...string) model.Process {
+		process := model.Process{
+			Pid:      pid,
+			Tid:      pid,
+			Comm:     filepath.Base(path),
+			ExecTime: execTime,
+			ArgsEntry: &model.ArgsEntry{
+				Values: append([]string{path}, args...),
+			},
+			EnvsEntry: &model.EnvsEntry{
+				Values: []string{"PATH=/usr/bin", "API_KEY=secret"},
+			},
+		}
+		process.FileEvent.PathnameStr = path
+		process.FileEvent.BasenameStr = filepath.Base(path)
+		process.FileEvent.Inode = ino
+		process.Credentials.UID = 1000
+		process.Credentials.User = "user"
+		return process
+	}
+
+	shell := &ProcessActivityNode{
+		Process:        newProcess(uint32(os.Getpid()), binary, inode, start),
+		GenerationType: Snapshot,
+		Files:          make(map[string]*FileActivityNode),
+		DNSNames:       make(map[string]*DNSNode),
+	}
+
+	curl := &ProcessActivityNode{
+		Process:        newProcess(uint32(os.Getpid()), "/usr/bin/curl", 42, start.Add(time.Second), "-s", "datadoghq.com"),
+		GenerationType: Runtime,
+		Files:          make(map[string]*FileActivityNode),
+		DNSNames:       make(map[string]*DNSNode),
+	}
+	curl.Process.PPid = shell.Process.Pid
+
+	hosts := &model.FileEvent{PathnameStr: "/etc/hosts", BasenameStr: "hosts"}
+	hosts.Inode = 10
+	curl.Files["etc"] = &FileActivityNode{
+		Name:           "etc",
+		GenerationType: Runtime,
+		Children: map[string]*FileActivityNode{
+			"hosts": {
+				Name:           "hosts",
+				File:           hosts,
+				GenerationType: Runtime,
+				FirstSeen:      start.Add(2 * time.Second),
+				Open:           &OpenNode{Flags: syscall.O_RDONLY | syscall.O_CLOEXEC},
+				Children:       make(map[string]*FileActivityNode),
+			},
+		},
+	}
+	curl.DNSNames["datadoghq.com"] = &DNSNode{
+		Name:           "datadoghq.com",
+		Types:          []uint16{1, 28},
+		GenerationType: Runtime,
+		FirstSeen:      start.Add(3 * time.Second),
+	}
+	shell.Children = append(shell.Children, curl)
+
+	return &ActivityDump{
+		ProcessActivityTree: []*ProcessActivityNode{shell},
+		Comm:                shell.Process.Comm,
+		Start:               start,
+		End:                 start.Add(time.Minute),
+	}
+}
+
+func newTestBinary(t *testing.T) (string, uint64, string) {
+	binary := filepath.Join(t.TempDir(), "sh")
+	content := []byte("#!/bin/true\n")
+	require.NoError(t, os.WriteFile(binary, content, 0755))
+
+	info, err := os.Stat(binary)
+	require.NoError(t, err)
+
+	hash := sha256.Sum256(content)
+	return binary, info.Sys().(*syscall.Stat_t).Ino, hex.EncodeToString(hash[:])
+}
+
+func TestActivityDumpProtobuf(t *testing.T) {
+	binary, inode, _ := newTestBinary(t)
+	ad := newTestActivityDump(t, binary, inode)
+
+	buf, err := ad.Encode(PROTOBUF)
+	require.NoError(t, err)
+
+	var msg api.ActivityDumpMessage
+	require.NoError(t, proto.Unmarshal(buf.Bytes(), &msg))
+
+	assert.Equal(t, uint64(ad.Start.UnixNano()), msg.GetStart())
+	require.Len(t, msg.GetTree(), 1)
+	shell := msg.GetTree()[0]
+	assert.Equal(t, binary, shell.GetProcess().GetFile().GetPath())
+	assert.Equal(t, []string{"PATH", "API_KEY"}, shell.GetProcess().GetEnvs())
+
+	require.Len(t, shell.GetChildren(), 1)
+	curl := shell.GetChildren()[0]
+	assert.Equal(t, "/usr/bin/curl", curl.GetProcess().GetArgv0())
+	assert.Equal(t, []string{"-s", "datadoghq.com"}, curl.GetProcess().GetArgs())
+	require.Len(t, curl.GetFiles(), 1)
+	require.Len(t, curl.GetFiles()[0].GetChildren(), 1)
+	assert.Equal(t, "/etc/hosts", curl.GetFiles()[0].GetChildren()[0].GetFile().GetPath())
+	require.Len(t, curl.GetDNSNames(), 1)
+	assert.Equal(t, []uint32{1, 28}, curl.GetDNSNames()[0].GetTypes())
+}
+
+func TestActivityDumpTimeline(t *testing.T) {
+	binary, inode, _ := newTestBinary(t)
+	ad := newTestActivityDump(t, binary, inode)
+
+	buf, err := ad.Encode(JSONL)
+	require.NoError(t, err)
+	assert.Equal(t, 5, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	// the timeline can be replayed through a rule set
+	events, err := policytest.DecodeEvents(buf)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+
+	var types []model.EventType
+	for _, event := range events {
+		types = append(types, model.EventType(event.Type))
+	}
+	assert.Equal(t, []model.EventType{model.ExecEventType, model.ExecEventType, model.FileOpenEventType, model.DNSEventType, model.DNSEventType}, types)
+
+	open := events[2]
+	assert.Equal(t, "/etc/hosts", open.Open.File.PathnameStr)
+	assert.Equal(t, uint32(syscall.O_RDONLY|syscall.O_CLOEXEC), open.Open.Flags)
+	assert.Equal(t, "/usr/bin/curl", open.ProcessContext.FileEvent.PathnameStr)
+	require.NotNil(t, open.ProcessContext.Ancestor)
+	assert.Equal(t, binary, open.ProcessContext.Ancestor.FileEvent.PathnameStr)
+
+	assert.Equal(t, "datadoghq.com", events[3].DNS.Name)
+	assert.Equal(t, uint16(28), events[4].DNS.Type)
+}
+
+func TestActivityDumpSBOM(t *testing.T) {
+	binary, inode, hash := newTestBinary(t)
+	ad := newTestActivityDump(t, binary, inode)
+
+	buf, err := ad.Encode(SBOM)
+	require.NoError(t, err)
+
+	var sbom ActivityDumpSBOM
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &sbom))
+	require.Len(t, sbom.Binaries, 2)
+
+	assert.Equal(t, binary, sbom.Binaries[0].Path)
+	assert.Equal(t, hash, sbom.Binaries[0].SHA256)
+	assert.Equal(t, 1, sbom.Binaries[0].Executions)
+	assert.True(t, ad.Start.Equal(sbom.Binaries[0].FirstSeen))
+
+	// the inode of the binary doesn't match the file found on disk
+	assert.Equal(t, "/usr/bin/curl", sbom.Binaries[1].Path)
+	assert.Empty(t, sbom.Binaries[1].SHA256)
+	assert.Equal(t, errSBOMBinaryNotFound.Error(), sbom.Binaries[1].HashError)
+}
+
+func TestActivityDumpSBOMFIFO(t *testing.T) {
+	// a workload can replace an executed binary with a FIFO, which would block a regular open
+	binary := filepath.Join(t.TempDir(), "sh")
+	require.NoError(t, syscall.Mkfifo(binary, 0600))
+	info, err := os.Stat(binary)
+	require.NoError(t, err)
+	ad := newTestActivityDump(t, binary, info.Sys().(*syscall.Stat_t).Ino)
+
+	encoded := make(chan *bytes.Buffer)
+	go func() {
+		buf, err := ad.Encode(SBOM)
+		assert.NoError(t, err)
+		encoded <- buf
+	}()
+
+	var buf *bytes.Buffer
+	select {
+	case buf = <-encoded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hashing a FIFO blocked")
+	}
+
+	var sbom ActivityDumpSBOM
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &sbom))
+	require.Len(t, sbom.Binaries, 2)
+	assert.Equal(t, binary, sbom.Binaries[0].Path)
+	assert.Empty(t, sbom.Binaries[0].SHA256)
+	assert.Equal(t, errSBOMNotRegularFile.Error(), sbom.Binaries[0].HashError)
+}
+
+func TestSBOMBinaryHashDeadline(t *testing.T) {
+	binary, inode, _ := newTestBinary(t)
+	b := &SBOMBinary{Path: binary, Inode: inode}
+
+	_, err := b.hash(time.Now().Add(-time.Second))
+	assert.Equal(t, errSBOMHashTimeout, err)
+}
+
+func TestActivityDumpLocalStorage(t *testing.T) {
+	binary, inode, _ := newTestBinary(t)
+	ad := newTestActivityDump(t, binary, inode)
+
+	request, err := NewStorageRequest(LocalStorage, JSONL, true, t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, request.open())
+	defer request.close()
+	assert.Equal(t, ".gz", filepath.Ext(request.File))
+
+	buf, err := ad.Encode(JSONL)
+	require.NoError(t, err)
+	data, err := compress(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, ad.persist(request, data))
+
+	f, err := os.Open(request.File)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), raw)
+
+	msg := request.ToStorageRequestMessage()
+	assert.Equal(t, &api.StorageRequestMessage{Type: "local", Format: "jsonl", Compression: true, File: request.File}, msg)
+}
+
+func TestActivityDumpRemoteStorageSend(t *testing.T) {
+	type received struct {
+		contentType     string
+		contentEncoding string
+		body            []byte
+	}
+	requests := make(chan received, 1)
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		code := status
+		requests <- received{
+			contentType:     r.Header.Get("Content-Type"),
+			contentEncoding: r.Header.Get("Content-Encoding"),
+			body:            body,
+		}
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	storage := NewActivityDumpRemoteStorage()
+
+	request, err := NewStorageRequest(RemoteStorage, PROTOBUF, true, server.URL)
+	require.NoError(t, err)
+	require.NoError(t, storage.send(context.Background(), &remoteStorageUpload{request: request, data: []byte("dump")}))
+	r := <-requests
+	assert.Equal(t, formatContentTypes[PROTOBUF], r.contentType)
+	assert.Equal(t, "gzip", r.contentEncoding)
+	assert.Equal(t, []byte("dump"), r.body)
+
+	request, err = NewStorageRequest(RemoteStorage, JSON, false, server.URL)
+	require.NoError(t, err)
+	require.NoError(t, storage.send(context.Background(), &remoteStorageUpload{request: request, data: []byte("{}")}))
+	r = <-requests
+	assert.Equal(t, formatContentTypes[JSON], r.contentType)
+	assert.Empty(t, r.contentEncoding)
+
+	status = http.StatusInternalServerError
+	err = storage.send(context.Background(), &remoteStorageUpload{request: request, data: []byte("{}")})
+	<-requests
+	assert.EqualError(t, err, "unexpected status code: 500 Internal Server Error")
+}
+
+func TestActivityDumpRemoteStorageDrain(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+	}))
+	defer server.Close()
+
+	request, err := NewStorageRequest(RemoteStorage, JSON, false, server.URL)
+	require.NoError(t, err)
+
+	storage := NewActivityDumpRemoteStorage()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, storage.Persist(request, "comm:sh", []byte("{}")))
+	}
+
+	// the queued dumps are sent even though the storage is already stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	storage.Start(ctx, &wg)
+	wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	assert.Empty(t, storage.queue)
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"io"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/mailru/easyjson/jwriter"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+// generateTimeline writes the events of the activity dump to the provided writer, sorted by date, one JSON event per
+// line. The events are serialized like the events sent by the probe so that the timeline can be replayed through a
+// rule set.
+func (ad *ActivityDump) generateTimeline(w io.Writer) error {
+	events := ad.GenerateEvents()
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	for _, event := range events {
+		writer := &jwriter.Writer{
+			Flags: jwriter.NilSliceAsEmpty | jwriter.NilMapAsEmpty,
+		}
+		ad.newTimelineEventSerializer(event).MarshalEasyJSON(writer)
+		if writer.Error != nil {
+			return writer.Error
+		}
+		writer.RawByte('\n')
+		if _, err := writer.DumpTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ad *ActivityDump) newTimelineEventSerializer(event *model.Event) *EventSerializer {
+	eventType := model.EventType(event.Type)
+
+	s := &EventSerializer{
+		EventContextSerializer: EventContextSerializer{
+			Name:     eventType.String(),
+			Category: model.GetEventTypeCategory(eventType.String()),
+		},
+		ProcessContextSerializer: ad.newTimelineProcessContextSerializer(&event.ProcessContext),
+		Date:                     event.Timestamp,
+	}
+
+	if id := event.ProcessContext.ContainerID; id != "" {
+		s.ContainerContextSerializer = &ContainerContextSerializer{
+			ID: id,
+		}
+	}
+
+	switch eventType {
+	case model.ExecEventType:
+		s.FileEventSerializer = &FileEventSerializer{
+			FileSerializer: *newTimelineFileSerializer(&event.Exec.Process.FileEvent),
+		}
+		s.EventContextSerializer.Outcome = serializeSyscallRetval(0)
+	case model.FileOpenEventType:
+		s.FileEventSerializer = &FileEventSerializer{
+			FileSerializer: *newTimelineFileSerializer(&event.Open.File),
+		}
+		if event.Open.Flags&syscall.O_CREAT > 0 {
+			s.FileEventSerializer.Destination = &FileSerializer{
+				Mode: &event.Open.Mode,
+			}
+		}
+		s.FileSerializer.Flags = model.OpenFlags(event.Open.Flags).StringArray()
+		s.EventContextSerializer.Outcome = serializeSyscallRetval(event.Open.Retval)
+	case model.DNSEventType:
+		s.EventContextSerializer.Outcome = serializeSyscallRetval(0)
+		s.DNSEventSerializer = newDNSEventSerializer(&event.DNS)
+	}
+
+	return s
+}
+
+func (ad *ActivityDump) newTimelineProcessContextSerializer(pc *model.ProcessContext) *ProcessContextSerializer {
+	if pc.Pid == 0 {
+		return nil
+	}
+
+	ps := &ProcessContextSerializer{
+		ProcessSerializer: ad.newTimelineProcessSerializer(&pc.Process),
+	}
+
+	for ancestor := pc.Ancestor; ancestor != nil; ancestor = ancestor.Ancestor {
+		s := ad.newTimelineProcessSerializer(&ancestor.Process)
+		if ps.Parent == nil {
+			ps.Parent = s
+		}
+		ps.Ancestors = append(ps.Ancestors, s)
+	}
+	return ps
+}
+
+func (ad *ActivityDump) newTimelineProcessSerializer(process *model.Process) *ProcessSerializer {
+	envs, envsTruncated := getDumpProcessEnvs(process)
+	argv, argsTruncated := getDumpProcessArgv(process)
+
+	ps := &ProcessSerializer{
+		ForkTime: getTimeIfNotZero(process.ForkTime),
+		ExecTime: getTimeIfNotZero(process.ExecTime),
+		ExitTime: getTimeIfNotZero(process.ExitTime),
+
+		Pid:           process.Pid,
+		Tid:           process.Tid,
+		PPid:          process.PPid,
+		Comm:          process.Comm,
+		TTY:           process.TTYName,
+		Executable:    newTimelineFileSerializer(&process.FileEvent),
+		ArgsTruncated: argsTruncated,
+		Envs:          envs,
+		EnvsTruncated: envsTruncated,
+	}
+	if len(argv) > 0 {
+		ps.Argv0 = argv[0]
+		ps.Args = ad.scrubArgs(argv[1:])
+	}
+
+	credsSerializer := newCredentialsSerializer(&process.Credentials)
+	ps.UID = credsSerializer.UID
+	ps.User = credsSerializer.User
+	ps.GID = credsSerializer.GID
+	ps.Group = credsSerializer.Group
+	ps.Credentials = &ProcessCredentialsSerializer{
+		CredentialsSerializer: credsSerializer,
+	}
+
+	if len(process.ContainerID) != 0 {
+		ps.Container = &ContainerContextSerializer{
+			ID: process.ContainerID,
+		}
+	}
+	return ps
+}
+
+// newTimelineFileSerializer serializes a file of the activity dump, the fields were resolved when the file was inserted
+func newTimelineFileSerializer(fe *model.FileEvent) *FileSerializer {
+	inode := fe.Inode
+	mountID := fe.MountID
+	mode := uint32(fe.Mode)
+	inUpperLayer := fe.InUpperLayer
+
+	return &FileSerializer{
+		Path:         fe.PathnameStr,
+		Name:         fe.BasenameStr,
+		Inode:        getUint64Pointer(&inode),
+		MountID:      getUint32Pointer(&mountID),
+		Filesystem:   fe.Filesystem,
+		Mode:         getUint32Pointer(&mode),
+		UID:          int64(fe.UID),
+		GID:          int64(fe.GID),
+		User:         fe.User,
+		Group:        fe.Group,
+		Mtime:        getTimeIfNotZero(time.Unix(0, int64(fe.MTime))),
+		Ctime:        getTimeIfNotZero(time.Unix(0, int64(fe.CTime))),
+		InUpperLayer: &inUpperLayer,
+	}
+}
@@ -30,6 +30,8 @@ const (
 	NoisyProcessRuleID = "noisy_process"
 	// AbnormalPathRuleID is the rule ID for the abnormal_path events
 	AbnormalPathRuleID = "abnormal_path"
+	// AnomalyDetectionRuleID is the rule ID for the anomaly_detection events
+	AnomalyDetectionRuleID = "anomaly_detection"
 )
 
 // AllCustomRuleIDs returns the list of custom rule IDs
@@ -39,6 +41,7 @@ func AllCustomRuleIDs() []string {
 		RulesetLoadedRuleID,
 		NoisyProcessRuleID,
 		AbnormalPathRuleID,
+		AnomalyDetectionRuleID,
 	}
 }
 
@@ -142,10 +145,11 @@ func NewEventLostWriteEvent(mapName string, perEventPerCPU map[string]uint64) (*
 // RuleIgnored defines a ignored
 // easyjson:json
 type RuleIgnored struct {
-	ID         string `json:"id"`
-	Version    string `json:"version,omitempty"`
-	Expression string `json:"expression"`
-	Reason     string `json:"reason"`
+	ID         string   `json:"id"`
+	Version    string   `json:"version,omitempty"`
+	Expression string   `json:"expression"`
+	Steps      []string `json:"steps,omitempty"`
+	Reason     string   `json:"reason"`
 }
 
 // PoliciesIgnored holds the errors
@@ -185,9 +189,10 @@ func (r *PoliciesIgnored) UnmarshalJSON(data []byte) error {
 // RuleLoaded defines a loaded rule
 // easyjson:json
 type RuleLoaded struct {
-	ID         string `json:"id"`
-	Version    string `json:"version,omitempty"`
-	Expression string `json:"expression"`
+	ID         string   `json:"id"`
+	Version    string   `json:"version,omitempty"`
+	Expression string   `json:"expression"`
+	Steps      []string `json:"steps,omitempty"`
 }
 
 // PolicyLoaded is used to report policy was loaded
@@ -207,6 +212,21 @@ type RulesetLoadedEvent struct {
 	MacrosLoaded    []rules.MacroID  `json:"macros_loaded"`
 }
 
+// getSequenceSteps returns the expressions of the steps of a sequence rule, which has no expression of its own
+func getSequenceSteps(def *rules.RuleDefinition) []string {
+	if def.Sequence == nil {
+		return nil
+	}
+
+	steps := make([]string, 0, len(def.Sequence.Steps))
+	for _, step := range def.Sequence.Steps {
+		if step != nil {
+			steps = append(steps, step.Expression)
+		}
+	}
+	return steps
+}
+
 // NewRuleSetLoadedEvent returns the rule and a populated custom event for a new_rules_loaded event
 func NewRuleSetLoadedEvent(rs *rules.RuleSet, err *multierror.Error) (*rules.Rule, *CustomEvent) {
 	mp := make(map[string]*PolicyLoaded)
@@ -226,6 +246,7 @@ func NewRuleSetLoadedEvent(rs *rules.RuleSet, err *multierror.Error) (*rules.Rul
 			ID:         rule.ID,
 			Version:    rule.Definition.Version,
 			Expression: rule.Definition.Expression,
+			Steps:      getSequenceSteps(rule.Definition),
 		})
 	}
 
@@ -243,6 +264,7 @@ func NewRuleSetLoadedEvent(rs *rules.RuleSet, err *multierror.Error) (*rules.Rul
 					ID:         rerr.Definition.ID,
 					Version:    rerr.Definition.Version,
 					Expression: rerr.Definition.Expression,
+					Steps:      getSequenceSteps(rerr.Definition),
 					Reason:     rerr.Err.Error(),
 				})
 			}
@@ -324,3 +346,28 @@ func NewAbnormalPathEvent(event *Event, pathResolutionError error) (*rules.Rule,
 			PathResolutionError: pathResolutionError.Error(),
 		})
 }
+
+// AnomalyDetectionEvent is used to report a behaviour of a workload outside of its security profile
+// easyjson:json
+type AnomalyDetectionEvent struct {
+	Timestamp      time.Time        `json:"date"`
+	Event          *EventSerializer `json:"triggering_event"`
+	Kind           AnomalyKind      `json:"anomaly_kind"`
+	Value          string           `json:"anomaly_value"`
+	ProfileName    string           `json:"profile_name"`
+	ProfileVersion uint64           `json:"profile_version"`
+}
+
+// NewAnomalyDetectionEvent returns the rule and a populated custom event for an anomaly_detection event
+func NewAnomalyDetectionEvent(event *Event, profile *SecurityProfile, kind AnomalyKind, value string) (*rules.Rule, *CustomEvent) {
+	return newRule(&rules.RuleDefinition{
+			ID: AnomalyDetectionRuleID,
+		}), newCustomEvent(model.CustomAnomalyDetectionEventType, AnomalyDetectionEvent{
+			Timestamp:      event.ResolveEventTimestamp(),
+			Event:          NewEventSerializer(event),
+			Kind:           kind,
+			Value:          value,
+			ProfileName:    profile.Name,
+			ProfileVersion: profile.Version,
+		})
+}
@@ -79,6 +79,7 @@ type Event struct {
 	pathResolutionError error
 	scrubber            *pconfig.DataScrubber
 	probe               *Probe
+	ruleMatched         bool
 }
 
 // Retain the event
@@ -470,6 +471,11 @@ func (ev *Event) ResolveEventTimestamp() time.Time {
 	return ev.Timestamp
 }
 
+// GetTimestamp returns the absolute time at which the event occurred
+func (ev *Event) GetTimestamp() time.Time {
+	return ev.ResolveEventTimestamp()
+}
+
 // ResolveProcessCacheEntry queries the ProcessResolver to retrieve the ProcessCacheEntry of the event
 func (ev *Event) ResolveProcessCacheEntry() *model.ProcessCacheEntry {
 	if ev.processCacheEntry == nil {
@@ -758,6 +758,9 @@ func (p *Probe) OnRuleMatch(rule *rules.Rule, event *Event) {
 	// ensure that all the fields are resolved before sending
 	event.ResolveContainerID(&event.ContainerContext)
 	event.ResolveContainerTags(&event.ContainerContext)
+
+	// the behaviour which triggered a rule is never merged into a security profile
+	event.ruleMatched = true
 }
 
 // OnNewDiscarder is called when a new discarder is found
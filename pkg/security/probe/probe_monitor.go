@@ -27,13 +27,14 @@ import (
 type Monitor struct {
 	probe *Probe
 
-	loadController      *LoadController
-	perfBufferMonitor   *PerfBufferMonitor
-	syscallMonitor      *SyscallMonitor
-	reordererMonitor    *ReordererMonitor
-	activityDumpManager *ActivityDumpManager
-	runtimeMonitor      *RuntimeMonitor
-	discarderMonitor    *DiscarderMonitor
+	loadController         *LoadController
+	perfBufferMonitor      *PerfBufferMonitor
+	syscallMonitor         *SyscallMonitor
+	reordererMonitor       *ReordererMonitor
+	activityDumpManager    *ActivityDumpManager
+	securityProfileManager *SecurityProfileManager
+	runtimeMonitor         *RuntimeMonitor
+	discarderMonitor       *DiscarderMonitor
 }
 
 // NewMonitor returns a new instance of a ProbeMonitor
@@ -67,6 +68,13 @@ func NewMonitor(p *Probe) (*Monitor, error) {
 		}
 	}
 
+	if p.config.SecurityProfileEnabled {
+		m.securityProfileManager, err = NewSecurityProfileManager(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't create the security profile manager")
+		}
+	}
+
 	// create a new syscall monitor if requested
 	if p.config.SyscallMonitor {
 		m.syscallMonitor, err = NewSyscallMonitor(p.manager)
@@ -98,6 +106,9 @@ func (m *Monitor) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	if m.activityDumpManager != nil {
 		delta++
 	}
+	if m.securityProfileManager != nil {
+		delta++
+	}
 	wg.Add(delta)
 
 	go m.loadController.Start(ctx, wg)
@@ -106,6 +117,9 @@ func (m *Monitor) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	if m.activityDumpManager != nil {
 		go m.activityDumpManager.Start(ctx, wg)
 	}
+	if m.securityProfileManager != nil {
+		go m.securityProfileManager.Start(ctx, wg)
+	}
 	return nil
 }
 
@@ -150,6 +164,12 @@ func (m *Monitor) SendStats() error {
 		}
 	}
 
+	if m.securityProfileManager != nil {
+		if err := m.securityProfileManager.SendStats(); err != nil {
+			return errors.Wrap(err, "failed to send security profile manager stats")
+		}
+	}
+
 	if m.probe.config.RuntimeMonitor {
 		if err := m.runtimeMonitor.SendStats(); err != nil {
 			return errors.Wrap(err, "failed to send runtime monitor stats")
@@ -194,6 +214,9 @@ func (m *Monitor) ProcessEvent(event *Event, size uint64, CPU int, perfMap *mana
 		if m.activityDumpManager != nil {
 			m.activityDumpManager.ProcessEvent(event)
 		}
+		if m.securityProfileManager != nil {
+			m.securityProfileManager.ProcessEvent(event)
+		}
 	}
 }
 
@@ -0,0 +1,487 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecurityProfileState defines how the behaviour of a workload is compared to its security profile
+type SecurityProfileState string
+
+const (
+	// LearningState means that the behaviour of the workload is added to its security profile
+	LearningState SecurityProfileState = "learning"
+	// EnforcingState means that the behaviour outside of the security profile is reported as an anomaly
+	EnforcingState SecurityProfileState = "enforcing"
+)
+
+// AnomalyKind describes the kind of behaviour found outside of a security profile
+type AnomalyKind string
+
+const (
+	// ProcessAnomaly is the execution of a binary which isn't part of the security profile
+	ProcessAnomaly AnomalyKind = "process"
+	// FileAnomaly is a file access which isn't part of the security profile
+	FileAnomaly AnomalyKind = "file"
+	// DNSAnomaly is a DNS lookup which isn't part of the security profile
+	DNSAnomaly AnomalyKind = "dns"
+)
+
+// SecurityProfileSelector selects the workloads of a security profile
+type SecurityProfileSelector struct {
+	ImageName string `yaml:"image_name"`
+	ImageTag  string `yaml:"image_tag,omitempty"`
+}
+
+// Matches returns true if the selector matches the provided image. An empty or `*` tag matches all the tags of the
+// image.
+func (s SecurityProfileSelector) Matches(imageName, imageTag string) bool {
+	if s.ImageName != imageName {
+		return false
+	}
+	return s.ImageTag == "" || s.ImageTag == "*" || s.ImageTag == imageTag
+}
+
+// ProcessProfile lists the files accessed and the domains resolved by the processes executing a binary. Both lists
+// accept patterns, `/proc/*/status` or `*.datadoghq.com` for example.
+type ProcessProfile struct {
+	Files []string `yaml:"files,omitempty"`
+	DNS   []string `yaml:"dns,omitempty"`
+}
+
+// SecurityProfile describes the expected behaviour of a workload. The behaviour observed outside of the profile is
+// added to it in learning state, and reported as an anomaly in enforcing state. In enforcing state, the anomalies
+// are only merged into the profile, bumping its version, when Merge is explicitly asked to merge them, and only if
+// they didn't trigger a rule.
+type SecurityProfile struct {
+	sync.Mutex `yaml:"-"`
+
+	Name          string                     `yaml:"name"`
+	Version       uint64                     `yaml:"version"`
+	Selector      SecurityProfileSelector    `yaml:"selector"`
+	State         SecurityProfileState       `yaml:"state"`
+	LearningStart time.Time                  `yaml:"learning_start,omitempty"`
+	Processes     map[string]*ProcessProfile `yaml:"processes"`
+
+	path      string
+	processes map[string]*processBehaviour
+	// processPatterns lists the process paths of the profile which are patterns, the other paths are only looked up
+	// in processes
+	processPatterns []string
+	pending         map[behaviour]bool
+	dirty           bool
+
+	// entries is the number of processes, files and DNS names of the profile
+	entries int
+	// maxEntries caps the growth of the profile, maxPending the anomalies waiting for a merge. 0 means no limit.
+	maxEntries int
+	maxPending int
+	// dropped counts the behaviour which couldn't be added to the profile, or tracked as an anomaly, because of
+	// the limits
+	dropped map[AnomalyKind]uint64
+}
+
+// processBehaviour is the index of the behaviour of a ProcessProfile
+type processBehaviour struct {
+	files behaviourSet
+	dns   behaviourSet
+}
+
+// behaviour is an entry of a security profile, the process path is empty for a process behaviour
+type behaviour struct {
+	kind        AnomalyKind
+	processPath string
+	value       string
+}
+
+// behaviourSet is a set of values and patterns
+type behaviourSet struct {
+	values   map[string]bool
+	patterns []string
+}
+
+// add adds a value or a pattern to the set, and returns false if it was already part of it
+func (s *behaviourSet) add(value string) bool {
+	if strings.Contains(value, "*") {
+		for _, pattern := range s.patterns {
+			if pattern == value {
+				return false
+			}
+		}
+		s.patterns = append(s.patterns, value)
+		return true
+	}
+
+	if s.values[value] {
+		return false
+	}
+	if s.values == nil {
+		s.values = make(map[string]bool)
+	}
+	s.values[value] = true
+	return true
+}
+
+// match returns true if the value or a pattern of the set matches the provided value
+func (s *behaviourSet) match(value string) bool {
+	if s.values[value] {
+		return true
+	}
+	for _, pattern := range s.patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *behaviourSet) list() []string {
+	list := make([]string, 0, len(s.values)+len(s.patterns))
+	for value := range s.values {
+		list = append(list, value)
+	}
+	list = append(list, s.patterns...)
+	sort.Strings(list)
+	return list
+}
+
+// NewSecurityProfile returns a new empty security profile, in learning state
+func NewSecurityProfile(name string, selector SecurityProfileSelector) *SecurityProfile {
+	sp := &SecurityProfile{
+		Name:      name,
+		Selector:  selector,
+		State:     LearningState,
+		Processes: make(map[string]*ProcessProfile),
+	}
+	sp.index()
+	return sp
+}
+
+// SetLimits caps the number of entries of the profile, and the number of anomalies waiting for a merge. The
+// behaviour observed beyond the limits is dropped. 0 means no limit.
+func (sp *SecurityProfile) SetLimits(maxEntries, maxPending int) {
+	sp.Lock()
+	defer sp.Unlock()
+
+	sp.maxEntries = maxEntries
+	sp.maxPending = maxPending
+}
+
+// PopDropped returns the number of behaviours dropped because of the limits of the profile since the last call
+func (sp *SecurityProfile) PopDropped() map[AnomalyKind]uint64 {
+	sp.Lock()
+	defer sp.Unlock()
+
+	dropped := sp.dropped
+	sp.dropped = make(map[AnomalyKind]uint64)
+	return dropped
+}
+
+// LoadSecurityProfile loads a security profile from a YAML file
+func LoadSecurityProfile(filename string) (*SecurityProfile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open security profile: %w", err)
+	}
+	defer f.Close()
+
+	var sp SecurityProfile
+	if err := yaml.NewDecoder(f).Decode(&sp); err != nil {
+		return nil, fmt.Errorf("couldn't parse security profile %s: %w", filename, err)
+	}
+
+	if err := sp.check(); err != nil {
+		return nil, fmt.Errorf("invalid security profile %s: %w", filename, err)
+	}
+
+	sp.path = filename
+	sp.index()
+	return &sp, nil
+}
+
+func (sp *SecurityProfile) check() error {
+	if sp.Name == "" {
+		return fmt.Errorf("missing profile name")
+	}
+
+	if sp.Selector.ImageName == "" {
+		return fmt.Errorf("missing image name in profile selector")
+	}
+
+	switch sp.State {
+	case "":
+		sp.State = LearningState
+	case LearningState, EnforcingState:
+	default:
+		return fmt.Errorf("unknown profile state `%s`", sp.State)
+	}
+
+	return nil
+}
+
+func (sp *SecurityProfile) index() {
+	sp.processes = make(map[string]*processBehaviour, len(sp.Processes))
+	sp.processPatterns = nil
+	sp.pending = make(map[behaviour]bool)
+	sp.dropped = make(map[AnomalyKind]uint64)
+	sp.entries = 0
+
+	for processPath, process := range sp.Processes {
+		pb := &processBehaviour{}
+		if process != nil {
+			for _, file := range process.Files {
+				if pb.files.add(file) {
+					sp.entries++
+				}
+			}
+			for _, name := range process.DNS {
+				if pb.dns.add(strings.ToLower(name)) {
+					sp.entries++
+				}
+			}
+		}
+		sp.addProcess(processPath, pb)
+	}
+}
+
+// addProcess adds an entry to the profile for the provided process path or pattern
+func (sp *SecurityProfile) addProcess(processPath string, pb *processBehaviour) {
+	sp.processes[processPath] = pb
+	if strings.Contains(processPath, "*") {
+		sp.processPatterns = append(sp.processPatterns, processPath)
+	}
+	sp.entries++
+}
+
+// getProcess returns the entry of the profile matching the provided process path
+func (sp *SecurityProfile) getProcess(processPath string) *processBehaviour {
+	if pb, ok := sp.processes[processPath]; ok {
+		return pb
+	}
+	for _, pattern := range sp.processPatterns {
+		if matched, _ := path.Match(pattern, processPath); matched {
+			return sp.processes[pattern]
+		}
+	}
+	return nil
+}
+
+func (sp *SecurityProfile) contains(b behaviour) bool {
+	if b.kind == ProcessAnomaly {
+		return sp.getProcess(b.value) != nil
+	}
+
+	pb := sp.getProcess(b.processPath)
+	if pb == nil {
+		return false
+	}
+
+	switch b.kind {
+	case FileAnomaly:
+		return pb.files.match(b.value)
+	case DNSAnomaly:
+		return pb.dns.match(b.value)
+	}
+	return false
+}
+
+func (sp *SecurityProfile) add(b behaviour) {
+	if b.kind == ProcessAnomaly {
+		if sp.getProcess(b.value) == nil {
+			sp.addProcess(b.value, &processBehaviour{})
+		}
+		return
+	}
+
+	pb := sp.getProcess(b.processPath)
+	if pb == nil {
+		pb = &processBehaviour{}
+		sp.addProcess(b.processPath, pb)
+	}
+
+	var added bool
+	switch b.kind {
+	case FileAnomaly:
+		added = pb.files.add(b.value)
+	case DNSAnomaly:
+		added = pb.dns.add(b.value)
+	}
+	if added {
+		sp.entries++
+	}
+}
+
+// fits returns true if the behaviour can be added without exceeding the maximum number of entries of the profile. A
+// file or DNS behaviour also adds its process when it isn't part of the profile.
+func (sp *SecurityProfile) fits(b behaviour) bool {
+	if sp.maxEntries <= 0 {
+		return true
+	}
+
+	needed := 1
+	if b.kind != ProcessAnomaly && sp.getProcess(b.processPath) == nil {
+		needed++
+	}
+	return sp.entries+needed <= sp.maxEntries
+}
+
+// UpdateState moves the profile to the enforcing state once the learning period is over
+func (sp *SecurityProfile) UpdateState(now time.Time, learningPeriod time.Duration) {
+	sp.Lock()
+	defer sp.Unlock()
+
+	if sp.State != LearningState {
+		return
+	}
+
+	if sp.LearningStart.IsZero() {
+		sp.LearningStart = now
+		sp.dirty = true
+	}
+
+	if now.Sub(sp.LearningStart) >= learningPeriod {
+		sp.State = EnforcingState
+		sp.Version++
+		sp.dirty = true
+	}
+}
+
+// Observe checks an observed behaviour against the profile, and returns true if it is an anomaly which should be
+// reported. In learning state, the behaviour is added to the profile. In enforcing state, an unknown behaviour is
+// reported once until the next merge, and can only be merged if it never matched a rule. The behaviour exceeding the
+// limits of the profile is dropped.
+func (sp *SecurityProfile) Observe(kind AnomalyKind, processPath, value string, ruleMatched bool) bool {
+	if kind == ProcessAnomaly {
+		processPath = ""
+	}
+	if kind == DNSAnomaly {
+		value = strings.ToLower(value)
+	}
+	b := behaviour{kind: kind, processPath: processPath, value: value}
+
+	sp.Lock()
+	defer sp.Unlock()
+
+	if sp.contains(b) {
+		return false
+	}
+
+	if sp.State == LearningState {
+		if !sp.fits(b) {
+			sp.dropped[kind]++
+			return false
+		}
+		sp.add(b)
+		sp.dirty = true
+		return false
+	}
+
+	benign, seen := sp.pending[b]
+	if !seen && sp.maxPending > 0 && len(sp.pending) >= sp.maxPending {
+		sp.dropped[kind]++
+		return false
+	}
+	sp.pending[b] = (benign || !seen) && !ruleMatched
+	return !seen
+}
+
+// Merge resets the anomalies observed in enforcing state, so that they are reported again, and returns whether the
+// profile changed since it was last saved. If mergeAnomalies is true, the anomalies which never matched a rule are
+// added to the profile instead: not matching a rule doesn't make a behaviour benign, merging them is opt-in.
+func (sp *SecurityProfile) Merge(mergeAnomalies bool) bool {
+	sp.Lock()
+	defer sp.Unlock()
+
+	var merged bool
+	for b, benign := range sp.pending {
+		if !mergeAnomalies || !benign {
+			continue
+		}
+		if !sp.fits(b) {
+			sp.dropped[b.kind]++
+			continue
+		}
+		sp.add(b)
+		merged = true
+	}
+	sp.pending = make(map[behaviour]bool)
+
+	if merged {
+		sp.Version++
+		sp.dirty = true
+	}
+
+	return sp.dirty
+}
+
+// SaveAs writes the profile to the provided file, which is used by the next calls to Save
+func (sp *SecurityProfile) SaveAs(filename string) error {
+	sp.Lock()
+	sp.path = filename
+	sp.Unlock()
+
+	return sp.Save()
+}
+
+// Save writes the profile to the file it was loaded from
+func (sp *SecurityProfile) Save() error {
+	sp.Lock()
+	defer sp.Unlock()
+
+	if sp.path == "" {
+		return fmt.Errorf("no file defined for security profile %s", sp.Name)
+	}
+
+	sp.Processes = make(map[string]*ProcessProfile, len(sp.processes))
+	for processPath, pb := range sp.processes {
+		sp.Processes[processPath] = &ProcessProfile{
+			Files: pb.files.list(),
+			DNS:   pb.dns.list(),
+		}
+	}
+
+	data, err := yaml.Marshal(sp)
+	if err != nil {
+		return fmt.Errorf("couldn't encode security profile %s: %w", sp.Name, err)
+	}
+
+	// write the profile atomically so that a partial profile is never loaded
+	tmp, err := os.CreateTemp(filepath.Dir(sp.path), ".profile-*")
+	if err != nil {
+		return fmt.Errorf("couldn't create security profile file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("couldn't write security profile %s: %w", sp.Name, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("couldn't write security profile %s: %w", sp.Name, err)
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("couldn't change the mode of the security profile file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), sp.path); err != nil {
+		return fmt.Errorf("couldn't write security profile %s: %w", sp.Name, err)
+	}
+
+	sp.dirty = false
+	return nil
+}
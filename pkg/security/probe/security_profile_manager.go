@@ -0,0 +1,275 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
+	"github.com/DataDog/datadog-agent/pkg/security/metrics"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/utils"
+)
+
+// securityProfileStatePeriod is the period at which the end of the learning period of the profiles is checked
+const securityProfileStatePeriod = time.Minute
+
+// SecurityProfileManager loads the security profiles and reports the behaviour of the workloads that falls outside
+// of their profile. Only the events which reach user space are checked, the in-kernel filters still apply.
+type SecurityProfileManager struct {
+	sync.RWMutex
+	probe          *Probe
+	dir            string
+	learningPeriod time.Duration
+	mergePeriod    time.Duration
+	mergeAnomalies bool
+	maxEntries     int
+	maxPending     int
+
+	profiles []*SecurityProfile
+	// containers caches the profile of the containers, nil if no profile selects the image of the container
+	containers map[string]*SecurityProfile
+	anomalies  map[AnomalyKind]*uint64
+}
+
+// NewSecurityProfileManager returns a new instance of SecurityProfileManager, loaded with the security profiles of
+// the configured directory
+func NewSecurityProfileManager(p *Probe) (*SecurityProfileManager, error) {
+	spm := &SecurityProfileManager{
+		probe:          p,
+		dir:            p.config.SecurityProfileDir,
+		learningPeriod: p.config.SecurityProfileLearningPeriod,
+		mergePeriod:    p.config.SecurityProfileMergePeriod,
+		mergeAnomalies: p.config.SecurityProfileMergeAnomalies,
+		maxEntries:     p.config.SecurityProfileMaxEntries,
+		maxPending:     p.config.SecurityProfileMaxPendingAnomalies,
+		containers:     make(map[string]*SecurityProfile),
+		anomalies:      make(map[AnomalyKind]*uint64),
+	}
+
+	for _, kind := range []AnomalyKind{ProcessAnomaly, FileAnomaly, DNSAnomaly} {
+		count := uint64(0)
+		spm.anomalies[kind] = &count
+	}
+
+	if err := spm.loadProfiles(); err != nil {
+		return nil, err
+	}
+	return spm, nil
+}
+
+func (spm *SecurityProfileManager) loadProfiles() error {
+	files, err := os.ReadDir(spm.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			seclog.Infof("security profiles directory %s not found", spm.dir)
+			return nil
+		}
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".profile" {
+			continue
+		}
+
+		profile, err := LoadSecurityProfile(filepath.Join(spm.dir, file.Name()))
+		if err != nil {
+			seclog.Errorf("couldn't load security profile: %v", err)
+			continue
+		}
+		profile.SetLimits(spm.maxEntries, spm.maxPending)
+		spm.profiles = append(spm.profiles, profile)
+	}
+
+	seclog.Infof("%d security profiles loaded from %s", len(spm.profiles), spm.dir)
+	return nil
+}
+
+// Start runs the SecurityProfileManager
+func (spm *SecurityProfileManager) Start(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	stateTicker := time.NewTicker(securityProfileStatePeriod)
+	defer stateTicker.Stop()
+
+	mergeTicker := time.NewTicker(spm.mergePeriod)
+	defer mergeTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			spm.merge()
+			return
+		case <-stateTicker.C:
+			spm.updateStates()
+		case <-mergeTicker.C:
+			spm.merge()
+		}
+	}
+}
+
+// updateStates moves the profiles at the end of their learning period to the enforcing state
+func (spm *SecurityProfileManager) updateStates() {
+	now := time.Now()
+	for _, profile := range spm.profiles {
+		profile.UpdateState(now, spm.learningPeriod)
+	}
+}
+
+// merge resets the anomalies of the profiles, merging them if configured to, and saves the updated profiles
+func (spm *SecurityProfileManager) merge() {
+	for _, profile := range spm.profiles {
+		if !profile.Merge(spm.mergeAnomalies) {
+			continue
+		}
+		if err := profile.Save(); err != nil {
+			seclog.Errorf("couldn't save security profile: %v", err)
+			continue
+		}
+		seclog.Debugf("security profile %s saved, version %d", profile.Name, profile.Version)
+	}
+
+	// the containers are looked up again so that the cache doesn't grow with the containers which stopped
+	spm.Lock()
+	spm.containers = make(map[string]*SecurityProfile)
+	spm.Unlock()
+}
+
+// getProfile returns the profile of the container of the event
+func (spm *SecurityProfileManager) getProfile(event *Event) *SecurityProfile {
+	containerID := event.ContainerContext.ID
+	if len(containerID) == 0 {
+		return nil
+	}
+
+	spm.RLock()
+	profile, found := spm.containers[containerID]
+	spm.RUnlock()
+	if found {
+		return profile
+	}
+
+	tags := spm.probe.resolvers.TagsResolver.Resolve(containerID)
+	if len(tags) == 0 {
+		// the tags of the container aren't resolved yet, try again with the next event
+		return nil
+	}
+
+	imageName := utils.GetTagValue("image_name", tags)
+	imageTag := utils.GetTagValue("image_tag", tags)
+	for _, p := range spm.profiles {
+		if p.Selector.Matches(imageName, imageTag) {
+			profile = p
+			break
+		}
+	}
+
+	spm.Lock()
+	spm.containers[containerID] = profile
+	spm.Unlock()
+
+	return profile
+}
+
+// ProcessEvent checks the process executions, file accesses and DNS lookups against the security profile of the
+// workload of the event, and reports the anomalies
+func (spm *SecurityProfileManager) ProcessEvent(event *Event) {
+	if len(spm.profiles) == 0 {
+		return
+	}
+
+	var kind AnomalyKind
+	switch event.GetEventType() {
+	case model.ExecEventType:
+		kind = ProcessAnomaly
+	case model.FileOpenEventType:
+		kind = FileAnomaly
+	case model.DNSEventType:
+		kind = DNSAnomaly
+	default:
+		return
+	}
+
+	profile := spm.getProfile(event)
+	if profile == nil {
+		return
+	}
+
+	processPath := event.ResolveProcessCacheEntry().FileEvent.PathnameStr
+
+	var value string
+	switch kind {
+	case ProcessAnomaly:
+		value = processPath
+	case FileAnomaly:
+		value = event.ResolveFilePath(&event.Open.File)
+	case DNSAnomaly:
+		value = event.DNS.Name
+	}
+	if len(value) == 0 {
+		return
+	}
+
+	if profile.Observe(kind, processPath, value, event.ruleMatched) {
+		atomic.AddUint64(spm.anomalies[kind], 1)
+		spm.probe.DispatchCustomEvent(NewAnomalyDetectionEvent(event, profile, kind, value))
+	}
+}
+
+// SendStats sends security profile stats
+func (spm *SecurityProfileManager) SendStats() error {
+	states := map[SecurityProfileState]float64{
+		LearningState:  0,
+		EnforcingState: 0,
+	}
+	for _, profile := range spm.profiles {
+		profile.Lock()
+		states[profile.State]++
+		profile.Unlock()
+	}
+
+	for state, count := range states {
+		tags := []string{"state:" + string(state)}
+		if err := spm.probe.statsdClient.Gauge(metrics.MetricSecurityProfileProfiles, count, tags, 1.0); err != nil {
+			return err
+		}
+	}
+
+	for kind, count := range spm.anomalies {
+		if value := atomic.SwapUint64(count, 0); value > 0 {
+			tags := []string{"anomaly_kind:" + string(kind)}
+			if err := spm.probe.statsdClient.Count(metrics.MetricSecurityProfileAnomalies, int64(value), tags, 1.0); err != nil {
+				return err
+			}
+		}
+	}
+
+	dropped := make(map[AnomalyKind]uint64)
+	for _, profile := range spm.profiles {
+		for kind, count := range profile.PopDropped() {
+			dropped[kind] += count
+		}
+	}
+	for kind, count := range dropped {
+		if count > 0 {
+			tags := []string{"anomaly_kind:" + string(kind)}
+			if err := spm.probe.statsdClient.Count(metrics.MetricSecurityProfileDropped, int64(count), tags, 1.0); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
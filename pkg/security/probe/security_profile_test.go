@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecurityProfile = `name: nginx
+version: 3
+selector:
+  image_name: nginx
+state: enforcing
+processes:
+  /usr/sbin/nginx:
+    files:
+      - /etc/nginx/nginx.conf
+      - /proc/*/status
+    dns:
+      - "*.datadoghq.com"
+  /bin/sh: {}
+`
+
+func loadTestSecurityProfile(t *testing.T) (*SecurityProfile, string) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "nginx.profile")
+	require.NoError(t, os.WriteFile(filename, []byte(testSecurityProfile), 0644))
+
+	profile, err := LoadSecurityProfile(filename)
+	require.NoError(t, err)
+	return profile, filename
+}
+
+func TestSecurityProfileSelector(t *testing.T) {
+	selector := SecurityProfileSelector{ImageName: "nginx"}
+	assert.True(t, selector.Matches("nginx", "1.21"))
+	assert.False(t, selector.Matches("redis", "1.21"))
+
+	selector.ImageTag = "1.20"
+	assert.False(t, selector.Matches("nginx", "1.21"))
+	assert.True(t, selector.Matches("nginx", "1.20"))
+}
+
+func TestSecurityProfileEnforcing(t *testing.T) {
+	profile, _ := loadTestSecurityProfile(t)
+
+	assert.False(t, profile.Observe(ProcessAnomaly, "/usr/sbin/nginx", "/usr/sbin/nginx", false))
+	assert.False(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/etc/nginx/nginx.conf", false))
+	assert.False(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/proc/42/status", false))
+	assert.False(t, profile.Observe(DNSAnomaly, "/usr/sbin/nginx", "Intake.DatadogHQ.com", false))
+
+	// anomalies are only reported once until the next merge
+	assert.True(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+	assert.False(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+	assert.True(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/etc/shadow", true))
+	assert.True(t, profile.Observe(FileAnomaly, "/bin/sh", "/etc/nginx/nginx.conf", false))
+	assert.True(t, profile.Observe(DNSAnomaly, "/usr/sbin/nginx", "example.com", false))
+	assert.False(t, profile.Observe(DNSAnomaly, "/usr/sbin/nginx", "example.com", true))
+
+	// only the anomalies which never matched a rule are merged
+	assert.True(t, profile.Merge(true))
+	assert.EqualValues(t, 4, profile.Version)
+	assert.False(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+	assert.False(t, profile.Observe(FileAnomaly, "/bin/sh", "/etc/nginx/nginx.conf", false))
+	assert.True(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/etc/shadow", false))
+	assert.True(t, profile.Observe(DNSAnomaly, "/usr/sbin/nginx", "example.com", false))
+}
+
+func TestSecurityProfileNoMerge(t *testing.T) {
+	profile, _ := loadTestSecurityProfile(t)
+
+	assert.True(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+	assert.False(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+
+	// the anomalies aren't merged unless asked to, and are reported again after the merge
+	assert.False(t, profile.Merge(false))
+	assert.EqualValues(t, 3, profile.Version)
+	assert.True(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+}
+
+func TestSecurityProfileLearning(t *testing.T) {
+	profile := NewSecurityProfile("redis", SecurityProfileSelector{ImageName: "redis"})
+
+	now := time.Now()
+	profile.UpdateState(now, time.Hour)
+	assert.Equal(t, LearningState, profile.State)
+
+	assert.False(t, profile.Observe(ProcessAnomaly, "/usr/bin/redis-server", "/usr/bin/redis-server", false))
+	assert.False(t, profile.Observe(FileAnomaly, "/usr/bin/redis-server", "/data/dump.rdb", false))
+
+	profile.UpdateState(now.Add(time.Hour), time.Hour)
+	assert.Equal(t, EnforcingState, profile.State)
+	assert.EqualValues(t, 1, profile.Version)
+
+	assert.False(t, profile.Observe(FileAnomaly, "/usr/bin/redis-server", "/data/dump.rdb", false))
+	assert.True(t, profile.Observe(FileAnomaly, "/usr/bin/redis-server", "/etc/passwd", false))
+}
+
+func TestSecurityProfileSave(t *testing.T) {
+	profile, filename := loadTestSecurityProfile(t)
+
+	assert.True(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/var/log/nginx/access.log", false))
+	assert.True(t, profile.Merge(true))
+	require.NoError(t, profile.Save())
+	assert.False(t, profile.Merge(true))
+
+	saved, err := LoadSecurityProfile(filename)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, saved.Version)
+	assert.Equal(t, EnforcingState, saved.State)
+	assert.Equal(t, []string{"/etc/nginx/nginx.conf", "/proc/*/status", "/var/log/nginx/access.log"}, saved.Processes["/usr/sbin/nginx"].Files)
+	assert.Equal(t, []string{"*.datadoghq.com"}, saved.Processes["/usr/sbin/nginx"].DNS)
+	assert.Contains(t, saved.Processes, "/bin/sh")
+}
+
+func TestSecurityProfileInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no_name.profile":     "selector:\n  image_name: nginx\n",
+		"no_selector.profile": "name: nginx\n",
+		"bad_state.profile":   "name: nginx\nselector:\n  image_name: nginx\nstate: sleeping\n",
+	} {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0644))
+
+		_, err := LoadSecurityProfile(filename)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSecurityProfileLimits(t *testing.T) {
+	t.Run("learning", func(t *testing.T) {
+		profile := NewSecurityProfile("redis", SecurityProfileSelector{ImageName: "redis"})
+		profile.SetLimits(3, 0)
+
+		// adds the process and its file
+		assert.False(t, profile.Observe(FileAnomaly, "/usr/bin/redis-server", "/data/dump.rdb", false))
+		assert.False(t, profile.Observe(FileAnomaly, "/usr/bin/redis-server", "/etc/passwd", false))
+		// the profile is full
+		assert.False(t, profile.Observe(DNSAnomaly, "/usr/bin/redis-server", "example.com", false))
+		assert.False(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+
+		assert.Equal(t, 3, profile.entries)
+		assert.Equal(t, map[AnomalyKind]uint64{DNSAnomaly: 1, ProcessAnomaly: 1}, profile.PopDropped())
+		assert.Empty(t, profile.PopDropped())
+	})
+
+	t.Run("enforcing", func(t *testing.T) {
+		profile, _ := loadTestSecurityProfile(t)
+		assert.Equal(t, 5, profile.entries)
+		profile.SetLimits(6, 2)
+
+		assert.True(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/etc/shadow", false))
+		assert.True(t, profile.Observe(ProcessAnomaly, "/usr/bin/curl", "/usr/bin/curl", false))
+		// too many pending anomalies, the new ones are dropped but the known ones are still tracked
+		assert.False(t, profile.Observe(DNSAnomaly, "/usr/sbin/nginx", "example.com", false))
+		assert.False(t, profile.Observe(FileAnomaly, "/usr/sbin/nginx", "/etc/shadow", false))
+		assert.Equal(t, map[AnomalyKind]uint64{DNSAnomaly: 1}, profile.PopDropped())
+
+		// only one of the benign anomalies fits in the profile
+		assert.True(t, profile.Merge(true))
+		assert.Equal(t, 6, profile.entries)
+		var dropped uint64
+		for _, count := range profile.PopDropped() {
+			dropped += count
+		}
+		assert.EqualValues(t, 1, dropped)
+	})
+}
+
+func TestSecurityProfileProcessPatterns(t *testing.T) {
+	profile := NewSecurityProfile("php", SecurityProfileSelector{ImageName: "php"})
+	profile.Processes = map[string]*ProcessProfile{
+		"/usr/sbin/php-fpm*": {Files: []string{"/etc/php/*"}},
+		"/usr/bin/php":       {},
+	}
+	profile.index()
+	profile.State = EnforcingState
+
+	assert.Equal(t, []string{"/usr/sbin/php-fpm*"}, profile.processPatterns)
+	assert.False(t, profile.Observe(ProcessAnomaly, "", "/usr/bin/php", false))
+	assert.False(t, profile.Observe(ProcessAnomaly, "", "/usr/sbin/php-fpm8.1", false))
+	assert.False(t, profile.Observe(FileAnomaly, "/usr/sbin/php-fpm8.1", "/etc/php/php.ini", false))
+	assert.True(t, profile.Observe(FileAnomaly, "/usr/bin/php", "/etc/php/php.ini", false))
+
+	// the processes added to the profile are exact paths
+	assert.True(t, profile.Merge(true))
+	assert.Equal(t, []string{"/usr/sbin/php-fpm*"}, profile.processPatterns)
+	assert.Len(t, profile.processes, 2)
+}
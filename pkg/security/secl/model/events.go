@@ -104,6 +104,8 @@ const (
 	CustomForkBombEventType
 	// CustomTruncatedParentsEventType is the custom event used to report that the parents of a path were truncated
 	CustomTruncatedParentsEventType
+	// CustomAnomalyDetectionEventType is the custom event used to report a behaviour outside of a security profile
+	CustomAnomalyDetectionEventType
 	// MaxAllEventType is used internally to get the maximum number of events.
 	MaxAllEventType
 )
@@ -193,6 +195,8 @@ func (t EventType) String() string {
 		return "fork_bomb"
 	case CustomTruncatedParentsEventType:
 		return "truncated_parents"
+	case CustomAnomalyDetectionEventType:
+		return "anomaly_detection"
 	default:
 		return "unknown"
 	}
@@ -185,6 +185,11 @@ func (e *Event) GetEventType() EventType {
 	return EventType(e.Type)
 }
 
+// GetTimestamp returns the time at which the event occurred
+func (e *Event) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
 // GetTags returns the list of tags specific to this event
 func (e *Event) GetTags() []string {
 	tags := []string{"type:" + e.GetType()}
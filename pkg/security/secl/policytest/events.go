@@ -0,0 +1,433 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+// The types below mirror the subset of the JSON shape produced by the probe serializers
+// which is needed to rebuild the fields available to rules.
+
+type fileSerializer struct {
+	Path           string   `json:"path"`
+	Name           string   `json:"name"`
+	Inode          uint64   `json:"inode"`
+	Mode           uint32   `json:"mode"`
+	InUpperLayer   bool     `json:"in_upper_layer"`
+	MountID        uint32   `json:"mount_id"`
+	Filesystem     string   `json:"filesystem"`
+	UID            int64    `json:"uid"`
+	GID            int64    `json:"gid"`
+	User           string   `json:"user"`
+	Group          string   `json:"group"`
+	XAttrName      string   `json:"attribute_name"`
+	XAttrNamespace string   `json:"attribute_namespace"`
+	Flags          []string `json:"flags"`
+}
+
+type fileEventSerializer struct {
+	fileSerializer
+	Destination *fileSerializer `json:"destination"`
+}
+
+type credentialsSerializer struct {
+	UID          uint32   `json:"uid"`
+	User         string   `json:"user"`
+	GID          uint32   `json:"gid"`
+	Group        string   `json:"group"`
+	EUID         uint32   `json:"euid"`
+	EUser        string   `json:"euser"`
+	EGID         uint32   `json:"egid"`
+	EGroup       string   `json:"egroup"`
+	FSUID        uint32   `json:"fsuid"`
+	FSUser       string   `json:"fsuser"`
+	FSGID        uint32   `json:"fsgid"`
+	FSGroup      string   `json:"fsgroup"`
+	CapEffective []string `json:"cap_effective"`
+	CapPermitted []string `json:"cap_permitted"`
+}
+
+type containerSerializer struct {
+	ID string `json:"id"`
+}
+
+type processSerializer struct {
+	Pid           uint32                 `json:"pid"`
+	PPid          uint32                 `json:"ppid"`
+	Tid           uint32                 `json:"tid"`
+	Comm          string                 `json:"comm"`
+	TTY           string                 `json:"tty"`
+	ForkTime      *time.Time             `json:"fork_time"`
+	ExecTime      *time.Time             `json:"exec_time"`
+	Credentials   *credentialsSerializer `json:"credentials"`
+	Executable    *fileSerializer        `json:"executable"`
+	Container     *containerSerializer   `json:"container"`
+	Argv0         string                 `json:"argv0"`
+	Args          []string               `json:"args"`
+	ArgsTruncated bool                   `json:"args_truncated"`
+	Envs          []string               `json:"envs"`
+	EnvsTruncated bool                   `json:"envs_truncated"`
+}
+
+type processContextSerializer struct {
+	processSerializer
+	Parent    *processSerializer   `json:"parent"`
+	Ancestors []*processSerializer `json:"ancestors"`
+}
+
+type dnsSerializer struct {
+	ID       uint16 `json:"id"`
+	Question *struct {
+		Class string `json:"class"`
+		Type  string `json:"type"`
+		Name  string `json:"name"`
+		Size  uint16 `json:"size"`
+		Count uint16 `json:"count"`
+	} `json:"question"`
+}
+
+type eventSerializer struct {
+	Evt struct {
+		Name    string `json:"name"`
+		Outcome string `json:"outcome"`
+	} `json:"evt"`
+	File      *fileEventSerializer      `json:"file"`
+	Process   *processContextSerializer `json:"process"`
+	DNS       *dnsSerializer            `json:"dns"`
+	Container *containerSerializer      `json:"container"`
+	Date      time.Time                 `json:"date"`
+}
+
+// DecodeEvents reads events serialized to JSON by the runtime security probe, either as a JSON
+// array or as a stream of JSON objects, one per line for example.
+func DecodeEvents(r io.Reader) ([]*model.Event, error) {
+	var events []*model.Event
+
+	decoder := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		raws := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &raws); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, data := range raws {
+			event, err := decodeEvent(data)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", len(events), err)
+			}
+			events = append(events, event)
+		}
+	}
+}
+
+func decodeEvent(data []byte) (*model.Event, error) {
+	var s eventSerializer
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	eventType := model.ParseEvalEventType(s.Evt.Name)
+	if eventType == model.UnknownEventType {
+		return nil, fmt.Errorf("unknown event type `%s`", s.Evt.Name)
+	}
+
+	event := &model.Event{
+		Type:      uint64(eventType),
+		Timestamp: s.Date,
+	}
+	if s.Container != nil {
+		event.ContainerContext.ID = s.Container.ID
+	}
+
+	if s.Process != nil {
+		pc, err := newProcessContext(s.Process)
+		if err != nil {
+			return nil, err
+		}
+		event.ProcessContext = *pc
+	}
+
+	retval := outcomeRetval(s.Evt.Outcome)
+
+	if eventType == model.ExecEventType {
+		event.Exec.Process = event.ProcessContext.Process
+		return event, nil
+	}
+
+	if eventType == model.DNSEventType {
+		if s.DNS == nil || s.DNS.Question == nil {
+			return nil, fmt.Errorf("missing dns question")
+		}
+		q := s.DNS.Question
+		event.DNS = model.DNSEvent{
+			ID:    s.DNS.ID,
+			Name:  q.Name,
+			Type:  uint16(model.DNSQTypeConstants[q.Type]),
+			Class: uint16(model.DNSQClassConstants[q.Class]),
+			Size:  q.Size,
+			Count: q.Count,
+		}
+		return event, nil
+	}
+
+	f := s.File
+	if f == nil {
+		return nil, fmt.Errorf("missing file for `%s` event", s.Evt.Name)
+	}
+	dest := f.Destination
+	if dest == nil {
+		dest = &fileSerializer{}
+	}
+
+	switch eventType {
+	case model.FileOpenEventType:
+		flags, err := parseConstants(f.Flags)
+		if err != nil {
+			return nil, err
+		}
+		event.Open = model.OpenEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			Flags:        uint32(flags),
+			Mode:         dest.Mode,
+		}
+	case model.FileChmodEventType:
+		event.Chmod = model.ChmodEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			Mode:         dest.Mode,
+		}
+	case model.FileChownEventType:
+		event.Chown = model.ChownEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			UID:          dest.UID,
+			User:         dest.User,
+			GID:          dest.GID,
+			Group:        dest.Group,
+		}
+	case model.FileMkdirEventType:
+		event.Mkdir = model.MkdirEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			Mode:         dest.Mode,
+		}
+	case model.FileRmdirEventType:
+		event.Rmdir = model.RmdirEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+		}
+	case model.FileUnlinkEventType:
+		flags, err := parseConstants(f.Flags)
+		if err != nil {
+			return nil, err
+		}
+		event.Unlink = model.UnlinkEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			Flags:        uint32(flags),
+		}
+	case model.FileRenameEventType:
+		event.Rename = model.RenameEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			Old:          newFileEvent(&f.fileSerializer),
+			New:          newFileEvent(dest),
+		}
+	case model.FileLinkEventType:
+		event.Link = model.LinkEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			Source:       newFileEvent(&f.fileSerializer),
+			Target:       newFileEvent(dest),
+		}
+	case model.FileUtimesEventType:
+		event.Utimes = model.UtimesEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+		}
+	case model.FileSetXAttrEventType, model.FileRemoveXAttrEventType:
+		xattr := model.SetXAttrEvent{
+			SyscallEvent: model.SyscallEvent{Retval: retval},
+			File:         newFileEvent(&f.fileSerializer),
+			Namespace:    dest.XAttrNamespace,
+			Name:         dest.XAttrName,
+		}
+		if eventType == model.FileSetXAttrEventType {
+			event.SetXAttr = xattr
+		} else {
+			event.RemoveXAttr = xattr
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event type `%s`", s.Evt.Name)
+	}
+
+	return event, nil
+}
+
+// outcomeRetval returns a syscall return value leading to the given serialized outcome.
+func outcomeRetval(outcome string) int64 {
+	switch outcome {
+	case "Refused":
+		return -int64(syscall.EACCES)
+	case "Error":
+		return -int64(syscall.EINVAL)
+	default:
+		return 0
+	}
+}
+
+func newFileEvent(s *fileSerializer) model.FileEvent {
+	name := s.Name
+	if name == "" && s.Path != "" {
+		name = s.Path[strings.LastIndex(s.Path, "/")+1:]
+	}
+
+	return model.FileEvent{
+		FileFields: model.FileFields{
+			UID:          uint32(s.UID),
+			User:         s.User,
+			GID:          uint32(s.GID),
+			Group:        s.Group,
+			Mode:         uint16(s.Mode),
+			MountID:      s.MountID,
+			Inode:        s.Inode,
+			InUpperLayer: s.InUpperLayer,
+		},
+		PathnameStr:           s.Path,
+		BasenameStr:           name,
+		Filesystem:            s.Filesystem,
+		IsPathnameStrResolved: true,
+		IsBasenameStrResolved: true,
+	}
+}
+
+func newProcess(s *processSerializer) (model.Process, error) {
+	p := model.Process{
+		Pid:           s.Pid,
+		Tid:           s.Tid,
+		PPid:          s.PPid,
+		Comm:          s.Comm,
+		TTYName:       s.TTY,
+		Argv0:         s.Argv0,
+		Args:          strings.Join(s.Args, " "),
+		Argv:          s.Args,
+		ArgsTruncated: s.ArgsTruncated,
+		Envs:          s.Envs,
+		EnvsTruncated: s.EnvsTruncated,
+	}
+	if s.ForkTime != nil {
+		p.ForkTime = *s.ForkTime
+	}
+	if s.ExecTime != nil {
+		p.ExecTime = *s.ExecTime
+	}
+	if s.Executable != nil {
+		p.FileEvent = newFileEvent(s.Executable)
+	}
+	if s.Container != nil {
+		p.ContainerID = s.Container.ID
+	}
+
+	if c := s.Credentials; c != nil {
+		capEffective, err := parseCapabilities(c.CapEffective)
+		if err != nil {
+			return p, err
+		}
+		capPermitted, err := parseCapabilities(c.CapPermitted)
+		if err != nil {
+			return p, err
+		}
+
+		p.Credentials = model.Credentials{
+			UID:          c.UID,
+			GID:          c.GID,
+			User:         c.User,
+			Group:        c.Group,
+			EUID:         c.EUID,
+			EGID:         c.EGID,
+			EUser:        c.EUser,
+			EGroup:       c.EGroup,
+			FSUID:        c.FSUID,
+			FSGID:        c.FSGID,
+			FSUser:       c.FSUser,
+			FSGroup:      c.FSGroup,
+			CapEffective: capEffective,
+			CapPermitted: capPermitted,
+		}
+	}
+
+	return p, nil
+}
+
+func newProcessContext(s *processContextSerializer) (*model.ProcessContext, error) {
+	process, err := newProcess(&s.processSerializer)
+	if err != nil {
+		return nil, err
+	}
+	pc := &model.ProcessContext{Process: process}
+
+	// the parent is also the first ancestor, when ancestors are serialized
+	ancestors := s.Ancestors
+	if len(ancestors) == 0 && s.Parent != nil {
+		ancestors = []*processSerializer{s.Parent}
+	}
+
+	prev := pc
+	for _, as := range ancestors {
+		ancestor, err := newProcess(as)
+		if err != nil {
+			return nil, err
+		}
+		entry := &model.ProcessCacheEntry{ProcessContext: model.ProcessContext{Process: ancestor}}
+		prev.Ancestor = entry
+		prev = &entry.ProcessContext
+	}
+
+	return pc, nil
+}
+
+// parseConstants returns the bitmask made of the given SECL constants, open flags for example.
+func parseConstants(names []string) (int, error) {
+	var value int
+	for _, name := range names {
+		c, ok := model.SECLConstants[name].(*eval.IntEvaluator)
+		if !ok {
+			return 0, fmt.Errorf("unknown constant `%s`", name)
+		}
+		value |= c.Value
+	}
+	return value, nil
+}
+
+func parseCapabilities(names []string) (uint64, error) {
+	var value uint64
+	for _, name := range names {
+		c, ok := model.KernelCapabilityConstants[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown capability `%s`", name)
+		}
+		value |= c
+	}
+	return value, nil
+}
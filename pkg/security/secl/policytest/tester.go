@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package policytest replays recorded events through a rule set, the way the runtime security
+// probe would evaluate them, so that policies can be tested without a kernel.
+package policytest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// Expectations lists the rules expected to match at least one of the replayed events, and the
+// rules expected to match none of them
+type Expectations struct {
+	Match   []eval.RuleID
+	NoMatch []eval.RuleID
+}
+
+// Report describes the outcome of the replay of events through a rule set
+type Report struct {
+	Events              int                   `json:"events"`
+	DroppedByApprovers  int                   `json:"dropped_by_approvers"`
+	DroppedByDiscarders int                   `json:"dropped_by_discarders"`
+	Matched             map[eval.RuleID][]int `json:"matched"`
+	Missed              []eval.RuleID         `json:"missed"`
+	Failures            []string              `json:"failures,omitempty"`
+}
+
+// Succeeded returns whether all the expectations were met
+func (r *Report) Succeeded() bool {
+	return len(r.Failures) == 0
+}
+
+// Tester evaluates events against a rule set, dropping the events which wouldn't reach user
+// space because of the approvers and discarders pushed to the kernel
+type Tester struct {
+	ruleSet    *rules.RuleSet
+	approvers  map[eval.EventType]rules.Approvers
+	discarders map[eval.EventType]map[eval.Field]map[string]bool
+
+	index   int
+	matched map[eval.RuleID][]int
+	report  Report
+}
+
+// NewTester returns a tester for the given rule set. Approvers are computed from the given
+// field capabilities, no approver is applied if they are nil.
+func NewTester(rs *rules.RuleSet, capabilities map[eval.EventType]rules.FieldCapabilities) (*Tester, error) {
+	t := &Tester{
+		ruleSet:    rs,
+		discarders: make(map[eval.EventType]map[eval.Field]map[string]bool),
+		matched:    make(map[eval.RuleID][]int),
+	}
+
+	if capabilities != nil {
+		approvers, err := rs.GetApprovers(capabilities)
+		if err != nil {
+			return nil, err
+		}
+		t.approvers = approvers
+	}
+
+	rs.AddListener(t)
+
+	return t, nil
+}
+
+// RuleMatch is called by the rule set when a rule matches an event
+func (t *Tester) RuleMatch(rule *rules.Rule, event eval.Event) {
+	t.matched[rule.ID] = append(t.matched[rule.ID], t.index)
+}
+
+// EventDiscarderFound is called by the rule set when a discarder is discovered
+func (t *Tester) EventDiscarderFound(rs *rules.RuleSet, event eval.Event, field eval.Field, eventType eval.EventType) {
+	value, err := event.GetFieldValue(field)
+	if err != nil {
+		return
+	}
+
+	fields, exists := t.discarders[eventType]
+	if !exists {
+		fields = make(map[eval.Field]map[string]bool)
+		t.discarders[eventType] = fields
+	}
+	values, exists := fields[field]
+	if !exists {
+		values = make(map[string]bool)
+		fields[field] = values
+	}
+	values[fmt.Sprint(value)] = true
+}
+
+// Test evaluates the given event, unless it is filtered by an approver or a discarder. It
+// returns whether a rule matched the event.
+func (t *Tester) Test(event *model.Event) bool {
+	defer func() { t.index++ }()
+	t.report.Events++
+
+	eventType := event.GetType()
+
+	if !t.isApproved(event, eventType) {
+		t.report.DroppedByApprovers++
+		return false
+	}
+
+	if t.isDiscarded(event, eventType) {
+		t.report.DroppedByDiscarders++
+		return false
+	}
+
+	return t.ruleSet.Evaluate(event)
+}
+
+func (t *Tester) isApproved(event *model.Event, eventType eval.EventType) bool {
+	approvers, exists := t.approvers[eventType]
+	if !exists {
+		return true
+	}
+
+	for field, values := range approvers {
+		value, err := event.GetFieldValue(field)
+		if err != nil {
+			continue
+		}
+		for _, fv := range values {
+			if approves(field, fv, value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// approves returns whether an event having the given value for the field passes the given
+// approver. Paths are matched on their basename, as done by the kernel filters.
+func approves(field eval.Field, fv rules.FilterValue, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		approver, ok := fv.Value.(string)
+		if !ok {
+			return true
+		}
+		if strings.HasSuffix(field, model.PathSuffix) {
+			return path.Base(approver) == path.Base(v)
+		}
+		if fv.Type == eval.ScalarValueType {
+			return approver == v
+		}
+		glob, err := eval.NewGlob(approver, false)
+		if err != nil {
+			return true
+		}
+		return glob.Matches(v)
+	case int:
+		approver, ok := fv.Value.(int)
+		if !ok {
+			return true
+		}
+		if fv.Type == eval.BitmaskValueType {
+			return v&approver != 0
+		}
+		return v == approver
+	default:
+		// not supported by the kernel filters, let the rules decide
+		return true
+	}
+}
+
+func (t *Tester) isDiscarded(event *model.Event, eventType eval.EventType) bool {
+	for field, values := range t.discarders[eventType] {
+		value, err := event.GetFieldValue(field)
+		if err != nil {
+			continue
+		}
+		if values[fmt.Sprint(value)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns the rules which matched the tested events, and checks the given expectations
+func (t *Tester) Report(expectations Expectations) *Report {
+	report := t.report
+	report.Matched = make(map[eval.RuleID][]int, len(t.matched))
+	for id, indexes := range t.matched {
+		report.Matched[id] = indexes
+	}
+	report.Missed = nil
+	report.Failures = nil
+
+	ruleIDs := t.ruleSet.ListRuleIDs()
+	sort.Strings(ruleIDs)
+	exists := make(map[eval.RuleID]bool, len(ruleIDs))
+	for _, id := range ruleIDs {
+		exists[id] = true
+		if _, matched := t.matched[id]; !matched {
+			report.Missed = append(report.Missed, id)
+		}
+	}
+
+	for _, id := range expectations.Match {
+		switch {
+		case !exists[id]:
+			report.Failures = append(report.Failures, fmt.Sprintf("rule `%s` not found", id))
+		case len(t.matched[id]) == 0:
+			report.Failures = append(report.Failures, fmt.Sprintf("rule `%s` was expected to match", id))
+		}
+	}
+
+	for _, id := range expectations.NoMatch {
+		switch {
+		case !exists[id]:
+			report.Failures = append(report.Failures, fmt.Sprintf("rule `%s` not found", id))
+		case len(t.matched[id]) != 0:
+			report.Failures = append(report.Failures, fmt.Sprintf("rule `%s` wasn't expected to match, matched events %v", id, t.matched[id]))
+		}
+	}
+
+	return &report
+}
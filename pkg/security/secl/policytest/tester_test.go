@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package policytest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+var testCapabilities = map[eval.EventType]rules.FieldCapabilities{
+	"open": {
+		{Field: "open.file.path", Types: eval.ScalarValueType | eval.GlobValueType},
+		{Field: "open.file.name", Types: eval.ScalarValueType},
+	},
+}
+
+func loadTestRuleSet(t *testing.T) *rules.RuleSet {
+	var opts rules.Opts
+	opts.
+		WithConstants(model.SECLConstants).
+		WithVariables(model.SECLVariables).
+		WithEventTypeEnabled(map[eval.EventType]bool{"*": true}).
+		WithLogger(&rules.NullLogger{})
+
+	rs := rules.NewRuleSet(&model.Model{}, func() eval.Event { return &model.Event{} }, &opts)
+	if err := rules.LoadPolicies("testdata/policies", rs); err.ErrorOrNil() != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func loadTestEvents(t *testing.T) []*model.Event {
+	f, err := os.Open("testdata/events.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	events, err := DecodeEvents(f)
+	require.NoError(t, err)
+	return events
+}
+
+func TestDecodeEvents(t *testing.T) {
+	events := loadTestEvents(t)
+	require.Len(t, events, 8)
+
+	open := events[0]
+	assert.Equal(t, "open", open.GetType())
+	assert.Equal(t, "/etc/shadow", open.Open.File.PathnameStr)
+	assert.Equal(t, "cat", open.ProcessContext.Comm)
+	assert.Equal(t, "/etc/shadow", open.ProcessContext.Args)
+	require.NotNil(t, open.ProcessContext.Ancestor)
+	assert.Equal(t, "bash", open.ProcessContext.Ancestor.FileEvent.BasenameStr)
+
+	create := events[1]
+	assert.NotZero(t, create.Open.Flags&uint32(os.O_CREATE))
+	assert.EqualValues(t, 0644, create.Open.Mode)
+
+	exec := events[2]
+	assert.Equal(t, "curl", exec.Exec.FileEvent.BasenameStr)
+	require.NotNil(t, exec.ProcessContext.Ancestor)
+	require.NotNil(t, exec.ProcessContext.Ancestor.Ancestor, "the parent shouldn't be duplicated")
+	assert.Equal(t, "systemd", exec.ProcessContext.Ancestor.Ancestor.Comm)
+	assert.Nil(t, exec.ProcessContext.Ancestor.Ancestor.Ancestor)
+
+	dns := events[3]
+	assert.EqualValues(t, 1, dns.DNS.Type)
+	assert.EqualValues(t, 1, dns.DNS.Class)
+
+	chmod := events[4]
+	assert.EqualValues(t, 06755, chmod.Chmod.Mode)
+	assert.EqualValues(t, model.KernelCapabilityConstants["CAP_CHOWN"]|model.KernelCapabilityConstants["CAP_FOWNER"], chmod.ProcessContext.CapEffective)
+
+	assert.Negative(t, events[5].Unlink.Retval)
+	assert.Zero(t, events[6].Unlink.Retval)
+}
+
+func TestDecodeEventsStream(t *testing.T) {
+	stream := `{"evt": {"name": "mkdir"}, "file": {"path": "/tmp/a", "destination": {"mode": 493}}}
+{"evt": {"name": "rename"}, "file": {"path": "/tmp/a"}, "process": {"pid": 1}}
+`
+	events, err := DecodeEvents(strings.NewReader(stream + stream))
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, "a", events[0].Mkdir.File.BasenameStr)
+	assert.EqualValues(t, 0755, events[0].Mkdir.Mode)
+	assert.Equal(t, "rename", events[3].GetType())
+
+	_, err = DecodeEvents(strings.NewReader(`{"evt": {"name": "bpf"}}`))
+	assert.Error(t, err)
+
+	_, err = DecodeEvents(strings.NewReader(`{"evt": {"name": "open"}, "file": {"path": "/tmp/a", "flags": ["O_UNKNOWN"]}}`))
+	assert.Error(t, err)
+}
+
+func TestTester(t *testing.T) {
+	events := loadTestEvents(t)
+
+	tester, err := NewTester(loadTestRuleSet(t), nil)
+	require.NoError(t, err)
+	for _, event := range events {
+		tester.Test(event)
+	}
+
+	report := tester.Report(Expectations{
+		Match:   []eval.RuleID{"shadow_read", "curl_from_shell"},
+		NoMatch: []eval.RuleID{"tmp_unlink"},
+	})
+	assert.True(t, report.Succeeded(), report.Failures)
+	assert.Equal(t, 8, report.Events)
+	assert.Equal(t, 0, report.DroppedByApprovers)
+	assert.Equal(t, 1, report.DroppedByDiscarders)
+	assert.Equal(t, map[eval.RuleID][]int{
+		"shadow_read":     {0},
+		"curl_from_shell": {2},
+		"evil_dns":        {3},
+		"suid_chmod":      {4},
+	}, report.Matched)
+	assert.Equal(t, []eval.RuleID{"passwd_write", "tmp_unlink"}, report.Missed)
+
+	report = tester.Report(Expectations{
+		Match:   []eval.RuleID{"passwd_write", "unknown_rule"},
+		NoMatch: []eval.RuleID{"evil_dns"},
+	})
+	assert.False(t, report.Succeeded())
+	assert.Len(t, report.Failures, 3)
+}
+
+func TestTesterApprovers(t *testing.T) {
+	events := loadTestEvents(t)
+
+	tester, err := NewTester(loadTestRuleSet(t), testCapabilities)
+	require.NoError(t, err)
+
+	assert.True(t, tester.Test(events[0]))
+	assert.False(t, tester.Test(events[1]))
+
+	report := tester.Report(Expectations{Match: []eval.RuleID{"shadow_read"}})
+	assert.True(t, report.Succeeded(), report.Failures)
+	assert.Equal(t, 2, report.Events)
+	assert.Equal(t, 1, report.DroppedByApprovers)
+}
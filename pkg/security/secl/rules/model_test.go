@@ -8,6 +8,7 @@ package rules
 import (
 	"reflect"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/pkg/errors"
@@ -34,8 +35,9 @@ type testMkdir struct {
 }
 
 type testEvent struct {
-	id   string
-	kind string
+	id        string
+	kind      string
+	timestamp time.Time
 
 	process testProcess
 	open    testOpen
@@ -49,6 +51,10 @@ func (e *testEvent) GetType() string {
 	return e.kind
 }
 
+func (e *testEvent) GetTimestamp() time.Time {
+	return e.timestamp
+}
+
 func (e *testEvent) GetTags() []string {
 	return nil
 }
@@ -354,6 +360,10 @@ var testConstants = map[string]interface{}{
 	"O_EXCL":   &eval.IntEvaluator{Value: syscall.O_EXCL},
 	"O_SYNC":   &eval.IntEvaluator{Value: syscall.O_SYNC},
 	"O_TRUNC":  &eval.IntEvaluator{Value: syscall.O_TRUNC},
+
+	// signals
+	"SIGKILL": &eval.IntEvaluator{Value: int(syscall.SIGKILL)},
+	"SIGUSR1": &eval.IntEvaluator{Value: int(syscall.SIGUSR1)},
 }
 
 var testSupportedDiscarders = map[eval.Field]bool{
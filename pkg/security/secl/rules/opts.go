@@ -25,6 +25,7 @@ type Opts struct {
 	EventTypeEnabled    map[eval.EventType]bool
 	StateScopes         map[Scope]VariableProviderFactory
 	Logger              Logger
+	EventSnapshotter    EventSnapshotter
 }
 
 // WithConstants set constants
@@ -86,3 +87,9 @@ func (o *Opts) WithStateScopes(stateScopes map[Scope]VariableProviderFactory) *O
 	o.StateScopes = stateScopes
 	return o
 }
+
+// WithEventSnapshotter set the function used to keep the events matching the steps of sequence rules
+func (o *Opts) WithEventSnapshotter(snapshotter EventSnapshotter) *Opts {
+	o.EventSnapshotter = snapshotter
+	return o
+}
@@ -70,11 +70,16 @@ func (p *Policy) GetValidMacroAndRules() ([]*MacroDefinition, []*RuleDefinition,
 			continue
 		}
 
-		if ruleDef.Expression == "" && !ruleDef.Disabled {
+		if ruleDef.Expression == "" && ruleDef.Sequence == nil && !ruleDef.Disabled {
 			result = multierror.Append(result, &ErrRuleLoad{Definition: ruleDef, Err: errors.New("no expression defined")})
 			continue
 		}
 
+		if ruleDef.Expression != "" && ruleDef.Sequence != nil {
+			result = multierror.Append(result, &ErrRuleLoad{Definition: ruleDef, Err: errors.New("a rule can't define both an expression and a sequence")})
+			continue
+		}
+
 		rules = append(rules, ruleDef)
 	}
 
@@ -397,6 +397,11 @@ func TestActionSetVariableConflict(t *testing.T) {
 }
 
 func loadPolicy(t *testing.T, testPolicy *Policy) *multierror.Error {
+	_, err := loadPolicyRuleSet(t, testPolicy)
+	return err
+}
+
+func loadPolicyRuleSet(t *testing.T, testPolicy *Policy) (*RuleSet, *multierror.Error) {
 	enabled := map[eval.EventType]bool{"*": true}
 	var opts Opts
 	opts.
@@ -416,7 +421,7 @@ func loadPolicy(t *testing.T, testPolicy *Policy) *multierror.Error {
 		t.Fatal(err)
 	}
 
-	return LoadPolicies(tmpDir, rs)
+	return rs, LoadPolicies(tmpDir, rs)
 }
 
 func TestActionSetVariableInvalid(t *testing.T) {
@@ -615,3 +620,98 @@ func TestActionSetVariableInvalid(t *testing.T) {
 		}
 	})
 }
+
+func TestActionKill(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		testPolicy := &Policy{
+			Name: "test-policy",
+			Rules: []*RuleDefinition{{
+				ID:         "test_rule",
+				Expression: `open.filename == "/tmp/test"`,
+				Actions: []ActionDefinition{{
+					Kill: &KillDefinition{},
+				}},
+			}, {
+				ID:         "test_rule2",
+				Expression: `open.filename == "/tmp/test2"`,
+				Actions: []ActionDefinition{{
+					Kill: &KillDefinition{
+						Signal: "SIGUSR1",
+						Scope:  KillScopeProcessGroup,
+					},
+				}},
+			}},
+		}
+
+		rs, err := loadPolicyRuleSet(t, testPolicy)
+		if err != nil {
+			t.Error(err)
+		}
+
+		kill := testPolicy.Rules[0].Actions[0].Kill
+		if kill.GetSignal() != "SIGKILL" || kill.GetScope() != KillScopeProcess {
+			t.Errorf("unexpected kill defaults: %s %s", kill.GetSignal(), kill.GetScope())
+		}
+
+		// the signal value is resolved from the constants of the ruleset
+		if rule := rs.GetRules()["test_rule"]; rule == nil || rule.Definition.Actions[0].Kill.SignalValue != 9 {
+			t.Errorf("expected the SIGKILL value to be resolved: %v", rule)
+		}
+	})
+
+	for name, action := range map[string]ActionDefinition{
+		"unknown-signal": {Kill: &KillDefinition{Signal: "SIGUNKNOWN"}},
+		"not-a-signal":   {Kill: &KillDefinition{Signal: "O_CREAT"}},
+	} {
+		action := action
+		t.Run(name, func(t *testing.T) {
+			testPolicy := &Policy{
+				Name: "test-policy",
+				Rules: []*RuleDefinition{{
+					ID:         "test_rule",
+					Expression: `open.filename == "/tmp/test"`,
+					Actions:    []ActionDefinition{action},
+				}},
+			}
+
+			rs, err := loadPolicyRuleSet(t, testPolicy)
+			if err == nil {
+				t.Error("expected policy to fail to load")
+			} else {
+				t.Log(err)
+			}
+
+			// the rule is rejected before being added to the ruleset
+			if rule := rs.GetRules()["test_rule"]; rule != nil {
+				t.Errorf("the invalid rule shouldn't be added: %v", rule)
+			}
+			if len(rs.GetEventTypes()) != 0 {
+				t.Errorf("the invalid rule shouldn't be added to the buckets: %v", rs.GetEventTypes())
+			}
+		})
+	}
+
+	for name, action := range map[string]ActionDefinition{
+		"unknown-scope": {Kill: &KillDefinition{Scope: "host"}},
+		"set-and-kill":  {Kill: &KillDefinition{}, Set: &SetDefinition{Name: "var1", Value: true}},
+		"empty":         {},
+	} {
+		action := action
+		t.Run(name, func(t *testing.T) {
+			testPolicy := &Policy{
+				Name: "test-policy",
+				Rules: []*RuleDefinition{{
+					ID:         "test_rule",
+					Expression: `open.filename == "/tmp/test"`,
+					Actions:    []ActionDefinition{action},
+				}},
+			}
+
+			if err := loadPolicy(t, testPolicy); err == nil {
+				t.Error("expected policy to fail to load")
+			} else {
+				t.Log(err)
+			}
+		})
+	}
+}
@@ -64,14 +64,15 @@ type RuleID = string
 
 // RuleDefinition holds the definition of a rule
 type RuleDefinition struct {
-	ID          RuleID             `yaml:"id"`
-	Version     string             `yaml:"version"`
-	Expression  string             `yaml:"expression"`
-	Description string             `yaml:"description"`
-	Tags        map[string]string  `yaml:"tags"`
-	Disabled    bool               `yaml:"disabled"`
-	Combine     CombinePolicy      `yaml:"combine"`
-	Actions     []ActionDefinition `yaml:"actions"`
+	ID          RuleID              `yaml:"id"`
+	Version     string              `yaml:"version"`
+	Expression  string              `yaml:"expression"`
+	Description string              `yaml:"description"`
+	Tags        map[string]string   `yaml:"tags"`
+	Disabled    bool                `yaml:"disabled"`
+	Combine     CombinePolicy       `yaml:"combine"`
+	Actions     []ActionDefinition  `yaml:"actions"`
+	Sequence    *SequenceDefinition `yaml:"sequence"`
 	Policy      *Policy
 }
 
@@ -91,6 +92,7 @@ func (rd *RuleDefinition) MergeWith(rd2 *RuleDefinition) error {
 	switch rd2.Combine {
 	case OverridePolicy:
 		rd.Expression = rd2.Expression
+		rd.Sequence = rd2.Sequence
 	default:
 		if !rd2.Disabled {
 			return &ErrRuleLoad{Definition: rd2, Err: ErrInternalIDConflict}
@@ -102,13 +104,22 @@ func (rd *RuleDefinition) MergeWith(rd2 *RuleDefinition) error {
 
 // ActionDefinition describes a rule action section
 type ActionDefinition struct {
-	Set *SetDefinition `yaml:"set"`
+	Set  *SetDefinition  `yaml:"set"`
+	Kill *KillDefinition `yaml:"kill"`
 }
 
 // Check returns an error if the action in invalid
 func (a *ActionDefinition) Check() error {
-	if a.Set == nil {
-		return errors.New("missing 'set' section in action")
+	if a.Set == nil && a.Kill == nil {
+		return errors.New("missing 'set' or 'kill' section in action")
+	}
+
+	if a.Set != nil && a.Kill != nil {
+		return errors.New("an action can't define both 'set' and 'kill' sections")
+	}
+
+	if a.Kill != nil {
+		return a.Kill.Check()
 	}
 
 	if a.Set.Name == "" {
@@ -134,10 +145,66 @@ type SetDefinition struct {
 	Scope  Scope       `yaml:"scope"`
 }
 
+// KillScope describes the processes targeted by a kill action
+type KillScope string
+
+const (
+	// KillScopeProcess targets the process which triggered the rule
+	KillScopeProcess KillScope = "process"
+	// KillScopeProcessGroup targets the process group of the process which triggered the rule
+	KillScopeProcessGroup KillScope = "process_group"
+	// KillScopeContainer targets the init process of the container of the process which triggered the rule
+	KillScopeContainer KillScope = "container"
+)
+
+// DefaultKillSignal is the signal sent by a kill action which doesn't specify one
+const DefaultKillSignal = "SIGKILL"
+
+// KillDefinition describes the 'kill' section of a rule action, sending a signal to the process
+// which triggered the rule
+type KillDefinition struct {
+	Signal string    `yaml:"signal"`
+	Scope  KillScope `yaml:"scope"`
+
+	// SignalValue is the value of the signal, resolved when the rule is added to a ruleset
+	SignalValue int `yaml:"-"`
+}
+
+// Check returns an error if the kill action is invalid
+func (k *KillDefinition) Check() error {
+	switch k.Scope {
+	case "", KillScopeProcess, KillScopeProcessGroup, KillScopeContainer:
+	default:
+		return fmt.Errorf("invalid kill scope '%s'", k.Scope)
+	}
+
+	return nil
+}
+
+// GetSignal returns the name of the signal sent by the kill action
+func (k *KillDefinition) GetSignal() string {
+	if k.Signal == "" {
+		return DefaultKillSignal
+	}
+	return k.Signal
+}
+
+// GetScope returns the processes targeted by the kill action
+func (k *KillDefinition) GetScope() KillScope {
+	if k.Scope == "" {
+		return KillScopeProcess
+	}
+	return k.Scope
+}
+
 // Rule describes a rule of a ruleset
 type Rule struct {
 	*eval.Rule
 	Definition *RuleDefinition
+
+	// set for the steps of sequence rules
+	sequence *sequence
+	step     int
 }
 
 // RuleSetListener describes the methods implemented by an object used to be
@@ -164,6 +231,8 @@ type RuleSet struct {
 	fields []string
 	logger Logger
 	pool   *eval.ContextPool
+	// evaluations counts the evaluated events, so that an event matches a single step of a sequence
+	evaluations uint64
 }
 
 // ListRuleIDs returns the list of RuleIDs from the ruleset
@@ -297,6 +366,23 @@ func (rs *RuleSet) AddRule(ruleDef *RuleDefinition) (*eval.Rule, error) {
 		Definition: ruleDef,
 	}
 
+	if err := rs.resolveKillActions(ruleDef); err != nil {
+		return nil, err
+	}
+
+	if ruleDef.Sequence != nil {
+		if err := rs.addSequenceRule(rule); err != nil {
+			return nil, err
+		}
+		rs.rules[ruleDef.ID] = rule
+
+		if err := rs.genActionEvaluators(ruleDef); err != nil {
+			return nil, err
+		}
+
+		return rule.Rule, nil
+	}
+
 	if err := rule.Parse(); err != nil {
 		return nil, &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrap(err, "syntax error")}
 	}
@@ -334,20 +420,57 @@ func (rs *RuleSet) AddRule(ruleDef *RuleDefinition) (*eval.Rule, error) {
 
 	rs.rules[ruleDef.ID] = rule
 
-	// Generate evaluator for fields that are used in variables
-	for _, action := range rule.Definition.Actions {
+	if err := rs.genActionEvaluators(ruleDef); err != nil {
+		return nil, err
+	}
+
+	return rule.Rule, nil
+}
+
+// genActionEvaluators generates the evaluators of the fields that are used in variables
+func (rs *RuleSet) genActionEvaluators(ruleDef *RuleDefinition) error {
+	for _, action := range ruleDef.Actions {
 		if action.Set != nil && action.Set.Field != "" {
 			if _, found := rs.fieldEvaluators[action.Set.Field]; !found {
 				evaluator, err := rs.model.GetEvaluator(action.Set.Field, "")
 				if err != nil {
-					return nil, err
+					return err
 				}
 				rs.fieldEvaluators[action.Set.Field] = evaluator
 			}
 		}
 	}
 
-	return rule.Rule, nil
+	return nil
+}
+
+// resolveKillActions resolves the signal values of the kill actions of the rule, it returns an error if a kill
+// action sends an unknown signal
+func (rs *RuleSet) resolveKillActions(ruleDef *RuleDefinition) error {
+	for _, action := range ruleDef.Actions {
+		if action.Kill != nil {
+			value, err := rs.GetSignalValue(action.Kill.GetSignal())
+			if err != nil {
+				return &ErrRuleLoad{Definition: ruleDef, Err: err}
+			}
+			action.Kill.SignalValue = value
+		}
+	}
+
+	return nil
+}
+
+// GetSignalValue returns the value of the given signal, resolved from the constants of the ruleset
+func (rs *RuleSet) GetSignalValue(signal string) (int, error) {
+	if !strings.HasPrefix(signal, "SIG") {
+		return 0, fmt.Errorf("unknown signal '%s'", signal)
+	}
+
+	evaluator, ok := rs.opts.Constants[signal].(*eval.IntEvaluator)
+	if !ok {
+		return 0, fmt.Errorf("unknown signal '%s'", signal)
+	}
+	return evaluator.Value, nil
 }
 
 // NotifyRuleMatch notifies all the ruleset listeners that an event matched a rule
@@ -419,10 +542,13 @@ func (rs *RuleSet) GetEventApprovers(eventType eval.EventType, fieldCaps FieldCa
 func (rs *RuleSet) GetFieldValues(field eval.Field) []eval.FieldValue {
 	var values []eval.FieldValue
 
-	for _, rule := range rs.rules {
-		rv := rule.GetFieldValues(field)
-		if len(rv) > 0 {
-			values = append(values, rv...)
+	// iterate over the buckets as the rules of the sequences are split into steps
+	for _, bucket := range rs.eventRuleBuckets {
+		for _, rule := range bucket.rules {
+			rv := rule.GetFieldValues(field)
+			if len(rv) > 0 {
+				values = append(values, rv...)
+			}
 		}
 	}
 
@@ -506,13 +632,26 @@ func (rs *RuleSet) Evaluate(event eval.Event) bool {
 	}
 	rs.logger.Tracef("Evaluating event of type `%s` against set of %d rules", eventType, len(bucket.rules))
 
+	rs.evaluations++
+
 	for _, rule := range bucket.rules {
 		if rule.GetEvaluator().Eval(ctx) {
 			rs.logger.Tracef("Rule `%s` matches with event `%s`\n", rule.ID, event)
-
-			rs.NotifyRuleMatch(rule, event)
 			result = true
 
+			if seq := rule.sequence; seq != nil {
+				steps, completed := seq.stepMatch(ctx, rule.step, event, rs.evaluations, rs.opts.EventSnapshotter)
+				if !completed {
+					continue
+				}
+
+				rule = seq.rule
+				rs.logger.Tracef("Sequence rule `%s` matches with event `%s`\n", rule.ID, event)
+				rs.NotifySequenceMatch(rule, steps, event)
+			} else {
+				rs.NotifyRuleMatch(rule, event)
+			}
+
 			if err := rs.runRuleActions(ctx, rule); err != nil {
 				rs.logger.Errorf("Error while executing rule actions: %s", err)
 			}
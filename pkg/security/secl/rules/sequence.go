@@ -0,0 +1,292 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+)
+
+// DefaultSequenceMaxKeys is the default maximum number of sequences of a rule tracked at the same time
+const DefaultSequenceMaxKeys = 1024
+
+// SequenceDefinition describes a rule matching a sequence of events sharing the same value for
+// the `by` field, a process or a container for example, within a time window
+type SequenceDefinition struct {
+	By      eval.Field                `yaml:"by"`
+	Within  time.Duration             `yaml:"within"`
+	MaxKeys int                       `yaml:"max_keys"`
+	Steps   []*SequenceStepDefinition `yaml:"steps"`
+}
+
+// SequenceStepDefinition describes a step of a sequence rule
+type SequenceStepDefinition struct {
+	Expression string `yaml:"expression"`
+}
+
+// Check returns an error if the sequence is invalid
+func (s *SequenceDefinition) Check() error {
+	if s.By == "" {
+		return errors.New("missing 'by' field in sequence")
+	}
+
+	if s.Within <= 0 {
+		return errors.New("'within' must be a positive duration")
+	}
+
+	if s.MaxKeys < 0 {
+		return errors.New("'max_keys' can't be negative")
+	}
+
+	if len(s.Steps) < 2 {
+		return errors.New("a sequence requires at least 2 steps")
+	}
+
+	for i, step := range s.Steps {
+		if step.Expression == "" {
+			return fmt.Errorf("no expression defined for step %d", i)
+		}
+	}
+
+	return nil
+}
+
+// SequenceListener describes the methods implemented by a rule set listener which wants the
+// events matching all the steps of a sequence rule. The other listeners are notified of the
+// matches of sequence rules with the event matching their last step.
+type SequenceListener interface {
+	SequenceMatch(rule *Rule, steps []interface{}, event eval.Event)
+}
+
+// EventSnapshotter returns a copy of an event which remains valid once the evaluation of the
+// event is done
+type EventSnapshotter func(event eval.Event) interface{}
+
+// timestampedEvent is implemented by the events knowing when they occurred
+type timestampedEvent interface {
+	GetTimestamp() time.Time
+}
+
+func getEventTimestamp(event eval.Event) time.Time {
+	if e, ok := event.(timestampedEvent); ok {
+		if ts := e.GetTimestamp(); !ts.IsZero() {
+			return ts
+		}
+	}
+	return time.Now()
+}
+
+// sequence holds the state of a sequence rule, for each of the values of its `by` field
+type sequence struct {
+	rule    *Rule
+	key     eval.Evaluator
+	within  time.Duration
+	maxKeys int
+	last    int
+	// evaluation is the last evaluation which matched a step, an event can only match a single step
+	evaluation uint64
+
+	states map[string]*list.Element
+	// states ordered by start time, the oldest first
+	lru *list.List
+}
+
+// sequenceState tracks the steps matched by the events having the same `by` value
+type sequenceState struct {
+	key   string
+	next  int
+	start time.Time
+	steps []interface{}
+}
+
+func newSequence(rule *Rule, key eval.Evaluator) *sequence {
+	def := rule.Definition.Sequence
+
+	maxKeys := def.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = DefaultSequenceMaxKeys
+	}
+
+	return &sequence{
+		rule:    rule,
+		key:     key,
+		within:  def.Within,
+		maxKeys: maxKeys,
+		last:    len(def.Steps) - 1,
+		states:  make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// evict drops the states which started before the time window, and the oldest ones when there
+// are too many states
+func (s *sequence) evict(now time.Time, maxKeys int) {
+	for elem := s.lru.Front(); elem != nil && len(s.states) > 0; elem = s.lru.Front() {
+		state := elem.Value.(*sequenceState)
+		if len(s.states) < maxKeys && now.Sub(state.start) <= s.within {
+			return
+		}
+		s.lru.Remove(elem)
+		delete(s.states, state.key)
+	}
+}
+
+// stepMatch handles the match of the given step by the event of the context. It returns the
+// snapshots of the events which matched the previous steps when the event completes a sequence.
+// The steps are evaluated from the last one to the first one so that an event matching several
+// steps advances a pending sequence instead of restarting it.
+func (s *sequence) stepMatch(ctx *eval.Context, step int, event eval.Event, evaluation uint64, snapshot EventSnapshotter) ([]interface{}, bool) {
+	// an event can only match a single step of a sequence
+	if s.evaluation == evaluation {
+		return nil, false
+	}
+
+	now := getEventTimestamp(event)
+	key := fmt.Sprint(s.key.Eval(ctx))
+
+	s.evict(now, s.maxKeys+1)
+
+	elem, exists := s.states[key]
+
+	if step == 0 {
+		// the sequence restarts from the most recent event matching the first step
+		if exists {
+			s.lru.Remove(elem)
+			delete(s.states, key)
+		}
+		s.evict(now, s.maxKeys)
+
+		state := &sequenceState{
+			key:   key,
+			next:  1,
+			start: now,
+			steps: []interface{}{takeSnapshot(event, snapshot)},
+		}
+		s.states[key] = s.lru.PushBack(state)
+		s.evaluation = evaluation
+		return nil, false
+	}
+
+	if !exists {
+		return nil, false
+	}
+
+	state := elem.Value.(*sequenceState)
+	if state.next != step {
+		return nil, false
+	}
+	s.evaluation = evaluation
+
+	if step == s.last {
+		s.lru.Remove(elem)
+		delete(s.states, key)
+		return state.steps, true
+	}
+
+	state.steps = append(state.steps, takeSnapshot(event, snapshot))
+	state.next++
+
+	return nil, false
+}
+
+func takeSnapshot(event eval.Event, snapshot EventSnapshotter) interface{} {
+	if snapshot == nil {
+		return event
+	}
+	return snapshot(event)
+}
+
+// addSequenceRule creates the evaluators of the steps of a sequence rule, and adds them to the
+// buckets of their events
+func (rs *RuleSet) addSequenceRule(rule *Rule) error {
+	ruleDef := rule.Definition
+
+	if err := ruleDef.Sequence.Check(); err != nil {
+		return &ErrRuleLoad{Definition: ruleDef, Err: err}
+	}
+
+	key, err := rs.model.GetEvaluator(ruleDef.Sequence.By, "")
+	if err != nil {
+		return &ErrRuleLoad{Definition: ruleDef, Err: err}
+	}
+
+	seq := newSequence(rule, key)
+
+	var steps []*Rule
+	for i, stepDef := range ruleDef.Sequence.Steps {
+		step := &Rule{
+			Rule: &eval.Rule{
+				ID:         fmt.Sprintf("%s[%d]", ruleDef.ID, i),
+				Expression: stepDef.Expression,
+				Tags:       rule.Tags,
+			},
+			Definition: ruleDef,
+			sequence:   seq,
+			step:       i,
+		}
+
+		if err := step.Parse(); err != nil {
+			return &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrapf(err, "syntax error in step %d", i)}
+		}
+
+		if err := step.GenEvaluator(rs.model, &rs.opts.Opts); err != nil {
+			return &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrapf(err, "step %d", i)}
+		}
+
+		eventType, err := GetRuleEventType(step.Rule)
+		if err != nil {
+			return &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrapf(err, "step %d", i)}
+		}
+
+		// ignore event types not supported
+		if _, exists := rs.opts.EventTypeEnabled["*"]; !exists {
+			if _, exists := rs.opts.EventTypeEnabled[eventType]; !exists {
+				return &ErrRuleLoad{Definition: ruleDef, Err: ErrEventTypeNotEnabled}
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	// the steps are only added once they all compiled, from the last one to the first one as
+	// expected by stepMatch
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		for _, event := range step.GetEvaluator().EventTypes {
+			bucket, exists := rs.eventRuleBuckets[event]
+			if !exists {
+				bucket = &RuleBucket{}
+				rs.eventRuleBuckets[event] = bucket
+			}
+
+			if err := bucket.AddRule(step); err != nil {
+				return err
+			}
+		}
+
+		rs.AddFields(step.GetEvaluator().GetFields())
+	}
+	rs.AddFields([]eval.Field{ruleDef.Sequence.By})
+
+	return nil
+}
+
+// NotifySequenceMatch notifies all the ruleset listeners that a sequence of events matched a
+// sequence rule
+func (rs *RuleSet) NotifySequenceMatch(rule *Rule, steps []interface{}, event eval.Event) {
+	for _, listener := range rs.listeners {
+		if l, ok := listener.(SequenceListener); ok {
+			l.SequenceMatch(rule, steps, event)
+		} else {
+			listener.RuleMatch(rule, event)
+		}
+	}
+}
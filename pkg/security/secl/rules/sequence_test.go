@@ -0,0 +1,253 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+)
+
+type testSequenceHandler struct {
+	matches   []eval.RuleID
+	sequences [][]interface{}
+}
+
+func (h *testSequenceHandler) RuleMatch(rule *Rule, event eval.Event) {
+	h.matches = append(h.matches, rule.ID)
+}
+
+func (h *testSequenceHandler) SequenceMatch(rule *Rule, steps []interface{}, event eval.Event) {
+	h.matches = append(h.matches, rule.ID)
+	h.sequences = append(h.sequences, append(steps, event.(*testEvent).id))
+}
+
+func (h *testSequenceHandler) EventDiscarderFound(rs *RuleSet, event eval.Event, field eval.Field, eventType eval.EventType) {
+}
+
+func newSequenceRuleSet(t *testing.T, maxKeys int) (*RuleSet, *testSequenceHandler) {
+	rs := newRuleSet()
+	rs.opts.WithEventSnapshotter(func(event eval.Event) interface{} {
+		return event.(*testEvent).id
+	})
+
+	handler := &testSequenceHandler{}
+	rs.AddListener(handler)
+
+	ruleDefs := []*RuleDefinition{
+		{
+			ID: "write_then_exec",
+			Sequence: &SequenceDefinition{
+				By:      "process.uid",
+				Within:  10 * time.Second,
+				MaxKeys: maxKeys,
+				Steps: []*SequenceStepDefinition{
+					{Expression: `open.filename =~ "/tmp/*" && open.flags & O_CREAT > 0`},
+					{Expression: `mkdir.filename =~ "/tmp/*"`},
+				},
+			},
+		},
+		{
+			ID:         "tmp_open",
+			Expression: `open.filename =~ "/tmp/*"`,
+		},
+	}
+	if err := rs.AddRules(ruleDefs); err != nil {
+		t.Fatal(err)
+	}
+
+	return rs, handler
+}
+
+func newSequenceEvents(start time.Time) (func(id string, uid int, offset time.Duration) *testEvent, func(id string, uid int, offset time.Duration) *testEvent) {
+	open := func(id string, uid int, offset time.Duration) *testEvent {
+		return &testEvent{
+			id:        id,
+			kind:      "open",
+			timestamp: start.Add(offset),
+			process:   testProcess{uid: uid},
+			open:      testOpen{filename: "/tmp/test", flags: syscall.O_CREAT},
+		}
+	}
+	mkdir := func(id string, uid int, offset time.Duration) *testEvent {
+		return &testEvent{
+			id:        id,
+			kind:      "mkdir",
+			timestamp: start.Add(offset),
+			process:   testProcess{uid: uid},
+			mkdir:     testMkdir{filename: "/tmp/dir"},
+		}
+	}
+	return open, mkdir
+}
+
+func TestSequenceRule(t *testing.T) {
+	rs, handler := newSequenceRuleSet(t, 0)
+	open, mkdir := newSequenceEvents(time.Now())
+
+	ids := rs.ListRuleIDs()
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 rules, got %v", ids)
+	}
+
+	// the last step alone doesn't match
+	rs.Evaluate(mkdir("mkdir0", 1, 0))
+	// the first step of the sequence, and the tmp_open rule, match
+	rs.Evaluate(open("open1", 1, time.Second))
+	// another key
+	rs.Evaluate(mkdir("mkdir2", 2, 2*time.Second))
+	// completes the sequence
+	rs.Evaluate(mkdir("mkdir3", 1, 3*time.Second))
+	// the sequence was reset
+	rs.Evaluate(mkdir("mkdir4", 1, 4*time.Second))
+
+	if strings.Join(handler.matches, ",") != "tmp_open,write_then_exec" {
+		t.Fatalf("unexpected matches: %v", handler.matches)
+	}
+	if len(handler.sequences) != 1 || handler.sequences[0][0] != "open1" || handler.sequences[0][1] != "mkdir3" {
+		t.Fatalf("unexpected sequence events: %v", handler.sequences)
+	}
+}
+
+func TestSequenceRuleWindow(t *testing.T) {
+	rs, handler := newSequenceRuleSet(t, 0)
+	open, mkdir := newSequenceEvents(time.Now())
+
+	rs.Evaluate(open("open0", 1, 0))
+	rs.Evaluate(mkdir("mkdir1", 1, 11*time.Second))
+
+	// a new match of the first step restarts the sequence
+	rs.Evaluate(open("open2", 1, 20*time.Second))
+	rs.Evaluate(open("open3", 1, 25*time.Second))
+	rs.Evaluate(mkdir("mkdir4", 1, 34*time.Second))
+
+	if len(handler.sequences) != 1 || handler.sequences[0][0] != "open3" {
+		t.Fatalf("unexpected sequence events: %v", handler.sequences)
+	}
+}
+
+func TestSequenceRuleMaxKeys(t *testing.T) {
+	rs, handler := newSequenceRuleSet(t, 2)
+	open, mkdir := newSequenceEvents(time.Now())
+
+	rs.Evaluate(open("open0", 1, 0))
+	rs.Evaluate(open("open1", 2, time.Second))
+	// evicts the sequence of the uid 1
+	rs.Evaluate(open("open2", 3, 2*time.Second))
+
+	rs.Evaluate(mkdir("mkdir3", 1, 3*time.Second))
+	rs.Evaluate(mkdir("mkdir4", 2, 4*time.Second))
+	rs.Evaluate(mkdir("mkdir5", 3, 5*time.Second))
+
+	if len(handler.sequences) != 2 || handler.sequences[0][1] != "mkdir4" || handler.sequences[1][1] != "mkdir5" {
+		t.Fatalf("unexpected sequence events: %v", handler.sequences)
+	}
+}
+
+func TestSequenceRuleOverlappingSteps(t *testing.T) {
+	rs := newRuleSet()
+	rs.opts.WithEventSnapshotter(func(event eval.Event) interface{} {
+		return event.(*testEvent).id
+	})
+
+	handler := &testSequenceHandler{}
+	rs.AddListener(handler)
+
+	ruleDefs := []*RuleDefinition{
+		{
+			ID: "tmp_twice",
+			Sequence: &SequenceDefinition{
+				By:     "process.uid",
+				Within: 10 * time.Second,
+				Steps: []*SequenceStepDefinition{
+					{Expression: `open.filename =~ "/tmp/*"`},
+					{Expression: `open.filename =~ "/tmp/*"`},
+				},
+			},
+		},
+		{
+			ID: "tmp_then_test",
+			Sequence: &SequenceDefinition{
+				By:     "process.uid",
+				Within: 10 * time.Second,
+				Steps: []*SequenceStepDefinition{
+					{Expression: `open.filename =~ "/tmp/*"`},
+					{Expression: `open.filename == "/tmp/test"`},
+				},
+			},
+		},
+	}
+	if err := rs.AddRules(ruleDefs); err != nil {
+		t.Fatal(err)
+	}
+
+	open, _ := newSequenceEvents(time.Now())
+
+	// starts both sequences
+	rs.Evaluate(open("open0", 1, 0))
+	// matches the first step of both sequences too, but completes them instead of restarting them
+	rs.Evaluate(open("open1", 1, time.Second))
+	// an event completing a sequence doesn't restart it
+	rs.Evaluate(open("open2", 1, 2*time.Second))
+	rs.Evaluate(open("open3", 1, 3*time.Second))
+
+	if strings.Join(handler.matches, ",") != "tmp_twice,tmp_then_test,tmp_twice,tmp_then_test" {
+		t.Fatalf("unexpected matches: %v", handler.matches)
+	}
+	for i, expected := range [][]interface{}{{"open0", "open1"}, {"open0", "open1"}, {"open2", "open3"}, {"open2", "open3"}} {
+		if len(handler.sequences[i]) != 2 || handler.sequences[i][0] != expected[0] || handler.sequences[i][1] != expected[1] {
+			t.Fatalf("unexpected sequence events: %v", handler.sequences)
+		}
+	}
+}
+
+func TestSequenceRuleDefinition(t *testing.T) {
+	rs := newRuleSet()
+
+	for _, seq := range []*SequenceDefinition{
+		{Within: time.Second, Steps: []*SequenceStepDefinition{{Expression: `open.filename == "/tmp/a"`}, {Expression: `mkdir.filename == "/tmp/a"`}}},
+		{By: "process.uid", Steps: []*SequenceStepDefinition{{Expression: `open.filename == "/tmp/a"`}, {Expression: `mkdir.filename == "/tmp/a"`}}},
+		{By: "process.uid", Within: time.Second, Steps: []*SequenceStepDefinition{{Expression: `open.filename == "/tmp/a"`}}},
+		{By: "process.unknown", Within: time.Second, Steps: []*SequenceStepDefinition{{Expression: `open.filename == "/tmp/a"`}, {Expression: `mkdir.filename == "/tmp/a"`}}},
+		{By: "process.uid", Within: time.Second, Steps: []*SequenceStepDefinition{{Expression: `open.filename == "/tmp/a"`}, {Expression: `mkdir.unknown == "/tmp/a"`}}},
+	} {
+		if _, err := rs.AddRule(&RuleDefinition{ID: "invalid", Sequence: seq}); err == nil {
+			t.Errorf("expected an error for %+v", seq)
+		}
+	}
+
+	if len(rs.GetEventTypes()) != 0 {
+		t.Errorf("invalid sequences shouldn't be added: %v", rs.GetEventTypes())
+	}
+}
+
+func TestSequenceRulePolicy(t *testing.T) {
+	policy := `
+rules:
+  - id: write_then_exec
+    sequence:
+      by: process.uid
+      within: 30s
+      steps:
+        - expression: open.filename =~ "/tmp/*" && open.flags & O_CREAT > 0
+        - expression: mkdir.filename =~ "/tmp/*"
+`
+	p, err := LoadPolicy(strings.NewReader(policy), "test.policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seq := p.Rules[0].Sequence
+	if seq == nil || seq.By != "process.uid" || seq.Within != 30*time.Second || len(seq.Steps) != 2 {
+		t.Fatalf("unexpected sequence definition: %+v", seq)
+	}
+	if err := seq.Check(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -132,6 +132,10 @@ rules:
           {{- end}}
           scope: {{$Action.Set.Scope}}
           append: {{$Action.Set.Append}}
+{{- else if $Action.Kill}}
+      - kill:
+          signal: {{$Action.Kill.GetSignal}}
+          scope: {{$Action.Kill.GetScope}}
 {{- end}}
 {{- end}}
 {{end}}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/serverless/trace/inferredspan"
 	"github.com/DataDog/datadog-agent/pkg/trace/api"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
@@ -31,6 +32,10 @@ type executionStartInfo struct {
 	spanID         uint64
 	parentID       uint64
 	requestPayload string
+	// inferredSpan represents the managed service which triggered the invocation, if any
+	inferredSpan *inferredspan.InferredSpan
+	// spanLinks reference the producers of a batch of messages when inferred spans are disabled
+	spanLinks []inferredspan.SpanLink
 }
 type invocationPayload struct {
 	Headers map[string]string `json:"headers"`
@@ -46,6 +51,8 @@ func startExecutionSpan(startTime time.Time, rawPayload string, invokeEventHeade
 	currentExecutionInfo.traceID = random.Uint64()
 	currentExecutionInfo.spanID = random.Uint64()
 	currentExecutionInfo.parentID = 0
+	currentExecutionInfo.inferredSpan = nil
+	currentExecutionInfo.spanLinks = nil
 
 	payload := convertRawPayload(rawPayload)
 
@@ -69,6 +76,36 @@ func startExecutionSpan(startTime time.Time, rawPayload string, invokeEventHeade
 	if e2 == nil && parentID != 0 {
 		currentExecutionInfo.parentID = parentID
 	}
+
+	startInferredSpan(startTime, rawPayload)
+}
+
+// startInferredSpan creates the span of the managed service which triggered the invocation,
+// making it the parent of the function execution span. The trace contexts propagated in
+// the messages of the event are used even when inferred spans are disabled.
+func startInferredSpan(startTime time.Time, rawPayload string) {
+	s := inferredspan.FromEvent([]byte(extractEventJSON(rawPayload)), startTime)
+	if s == nil {
+		return
+	}
+	if s.TraceID != 0 {
+		if currentExecutionInfo.parentID == 0 {
+			// no trace context in the headers, continue the trace propagated in the message
+			currentExecutionInfo.traceID = s.TraceID
+			currentExecutionInfo.parentID = s.ParentID
+		} else {
+			s.Links = append([]inferredspan.SpanLink{{TraceID: s.TraceID, SpanID: s.ParentID}}, s.Links...)
+		}
+	}
+	if !config.Datadog.GetBool("trace_managed_services") {
+		currentExecutionInfo.spanLinks = s.Links
+		return
+	}
+	s.Span.TraceID = currentExecutionInfo.traceID
+	s.Span.SpanID = random.Uint64()
+	s.Span.ParentID = currentExecutionInfo.parentID
+	inferredspan.SetSpanLinks(s.Span, s.Links)
+	currentExecutionInfo.inferredSpan = s
 }
 
 // endExecutionSpan builds the function execution span and sends it to the intake.
@@ -99,10 +136,18 @@ func endExecutionSpan(processTrace func(p *api.Payload), requestID string, endTi
 	if isError {
 		executionSpan.Error = 1
 	}
+	inferredspan.SetSpanLinks(executionSpan, currentExecutionInfo.spanLinks)
+
+	spans := []*pb.Span{executionSpan}
+	if s := currentExecutionInfo.inferredSpan; s != nil {
+		executionSpan.ParentID = s.Span.SpanID
+		s.Complete(currentExecutionInfo.startTime, endTime, isError)
+		spans = append(spans, s.Span)
+	}
 
 	traceChunk := &pb.TraceChunk{
 		Priority: int32(sampler.PriorityNone),
-		Spans:    []*pb.Span{executionSpan},
+		Spans:    spans,
 	}
 
 	tracerPayload := &pb.TracerPayload{
@@ -116,9 +161,7 @@ func endExecutionSpan(processTrace func(p *api.Payload), requestID string, endTi
 }
 
 func convertRawPayload(rawPayload string) invocationPayload {
-	//Need to remove unwanted text from the initial payload
-	reg := regexp.MustCompile(`{(?:|(.*))*}`)
-	subString := reg.FindString(rawPayload)
+	subString := extractEventJSON(rawPayload)
 
 	payload := invocationPayload{}
 
@@ -130,6 +173,13 @@ func convertRawPayload(rawPayload string) invocationPayload {
 	return payload
 }
 
+// extractEventJSON returns the JSON invocation event contained in the raw payload.
+func extractEventJSON(rawPayload string) string {
+	//Need to remove unwanted text from the initial payload
+	reg := regexp.MustCompile(`{(?:|(.*))*}`)
+	return reg.FindString(rawPayload)
+}
+
 func convertStrToUnit64(s string) (uint64, error) {
 	num, err := strconv.ParseUint(s, 0, 64)
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/api"
 	"github.com/stretchr/testify/assert"
 )
@@ -148,6 +149,98 @@ func TestEndExecutionSpanWithError(t *testing.T) {
 	assert.Equal(t, executionSpan.Error, int32(1))
 }
 
+func TestEndExecutionSpanWithInferredSpan(t *testing.T) {
+	defer reset()
+	testString := `a5a{"resource":"/users/create","path":"/users/create","httpMethod":"GET","headers":{"x-datadog-parent-id":"1480558859903409531","x-datadog-trace-id":"5736943178450432258"},"requestContext":{"stage":"dev","httpMethod":"GET","requestTimeEpoch":1428582896000,"domainName":"70ixmpl4fl.execute-api.us-east-2.amazonaws.com"}}0`
+	startTime := time.Now()
+	startExecutionSpan(startTime, testString, LambdaInvokeEventHeaders{})
+
+	duration := 1 * time.Second
+	endTime := startTime.Add(duration)
+	var tracePayload *api.Payload
+	mockProcessTrace := func(payload *api.Payload) {
+		tracePayload = payload
+	}
+
+	endExecutionSpan(mockProcessTrace, "test-request-id", endTime, true, []byte("{}"))
+	spans := tracePayload.TracerPayload.Chunks[0].Spans
+	assert.Len(t, spans, 2)
+	executionSpan, inferredSpan := spans[0], spans[1]
+	assert.Equal(t, "aws.apigateway.rest", inferredSpan.Name)
+	assert.Equal(t, "70ixmpl4fl.execute-api.us-east-2.amazonaws.com", inferredSpan.Service)
+	assert.Equal(t, uint64(5736943178450432258), inferredSpan.TraceID)
+	assert.Equal(t, uint64(1480558859903409531), inferredSpan.ParentID)
+	assert.Equal(t, inferredSpan.TraceID, executionSpan.TraceID)
+	assert.Equal(t, inferredSpan.SpanID, executionSpan.ParentID)
+	assert.Equal(t, int64(1428582896000*time.Millisecond), inferredSpan.Start)
+	assert.Equal(t, endTime.UnixNano()-inferredSpan.Start, inferredSpan.Duration)
+	assert.Equal(t, int32(1), inferredSpan.Error)
+}
+
+func TestEndExecutionSpanWithAsyncInferredSpan(t *testing.T) {
+	defer reset()
+	testString := `{"Records":[{"messageId":"059f36b4-87a3-44ab-83d2-661975830a7d","attributes":{"SentTimestamp":"1634662094538"},"messageAttributes":{"_datadog":{"stringValue":"{\"x-datadog-trace-id\":\"2684756524522091840\",\"x-datadog-parent-id\":\"7431398482019833808\"}","dataType":"String"}},"eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:sa-east-1:601427279990:InferredSpansQueueNode"}]}`
+	startTime := time.Now()
+	startExecutionSpan(startTime, testString, LambdaInvokeEventHeaders{})
+	assert.Equal(t, uint64(2684756524522091840), currentExecutionInfo.traceID)
+	assert.Equal(t, uint64(7431398482019833808), currentExecutionInfo.parentID)
+
+	endTime := startTime.Add(time.Second)
+	var tracePayload *api.Payload
+	mockProcessTrace := func(payload *api.Payload) {
+		tracePayload = payload
+	}
+
+	endExecutionSpan(mockProcessTrace, "test-request-id", endTime, true, []byte("{}"))
+	spans := tracePayload.TracerPayload.Chunks[0].Spans
+	assert.Len(t, spans, 2)
+	executionSpan, inferredSpan := spans[0], spans[1]
+	assert.Equal(t, "aws.sqs", inferredSpan.Name)
+	assert.Equal(t, "InferredSpansQueueNode", inferredSpan.Resource)
+	assert.Equal(t, uint64(2684756524522091840), inferredSpan.TraceID)
+	assert.Equal(t, uint64(7431398482019833808), inferredSpan.ParentID)
+	assert.Equal(t, inferredSpan.SpanID, executionSpan.ParentID)
+	assert.Equal(t, startTime.UnixNano()-inferredSpan.Start, inferredSpan.Duration)
+	assert.Equal(t, int32(0), inferredSpan.Error)
+	assert.Equal(t, int32(1), executionSpan.Error)
+}
+
+func TestEndExecutionSpanWithBatchedMessages(t *testing.T) {
+	defer reset()
+	testString := `{"Records":[` +
+		`{"messageId":"1","messageAttributes":{"_datadog":{"stringValue":"{\"x-datadog-trace-id\":\"1111\",\"x-datadog-parent-id\":\"1112\"}","dataType":"String"}},"eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:sa-east-1:601427279990:queue"},` +
+		`{"messageId":"2","messageAttributes":{"_datadog":{"stringValue":"{\"x-datadog-trace-id\":\"2221\",\"x-datadog-parent-id\":\"2222\"}","dataType":"String"}},"eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:sa-east-1:601427279990:queue"}` +
+		`]}`
+	wantLinks := `[{"trace_id":"000000000000000000000000000008ad","span_id":"00000000000008ae","attributes":{"messaging.message_id":"2"}}]`
+	var tracePayload *api.Payload
+	mockProcessTrace := func(payload *api.Payload) {
+		tracePayload = payload
+	}
+
+	startTime := time.Now()
+	startExecutionSpan(startTime, testString, LambdaInvokeEventHeaders{})
+	endExecutionSpan(mockProcessTrace, "test-request-id", startTime.Add(time.Second), false, []byte("{}"))
+	spans := tracePayload.TracerPayload.Chunks[0].Spans
+	assert.Len(t, spans, 2)
+	executionSpan, inferredSpan := spans[0], spans[1]
+	assert.Equal(t, uint64(1111), inferredSpan.TraceID)
+	assert.Equal(t, uint64(1112), inferredSpan.ParentID)
+	assert.Equal(t, uint64(1111), executionSpan.TraceID)
+	assert.JSONEq(t, wantLinks, inferredSpan.Meta["_dd.span_links"])
+	assert.NotContains(t, executionSpan.Meta, "_dd.span_links")
+
+	// without inferred spans, the function span continues the trace and holds the links
+	config.Datadog.Set("trace_managed_services", false)
+	defer config.Datadog.Set("trace_managed_services", true)
+	startExecutionSpan(startTime, testString, LambdaInvokeEventHeaders{})
+	endExecutionSpan(mockProcessTrace, "test-request-id", startTime.Add(time.Second), false, []byte("{}"))
+	spans = tracePayload.TracerPayload.Chunks[0].Spans
+	assert.Len(t, spans, 1)
+	assert.Equal(t, uint64(1111), spans[0].TraceID)
+	assert.Equal(t, uint64(1112), spans[0].ParentID)
+	assert.JSONEq(t, wantLinks, spans[0].Meta["_dd.span_links"])
+}
+
 func TestConvertRawPayloadWithHeaders(t *testing.T) {
 
 	s := `a5a{"resource":"/users/create","path":"/users/create","httpMethod":"GET","headers":{"Accept":"*/*","Accept-Encoding":"gzip","x-datadog-parent-id":"1480558859903409531","x-datadog-sampling-priority":"1","x-datadog-trace-id":"5736943178450432258"}}0`
@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package inferredspan
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// eventSource is the AWS service which invoked the function.
+type eventSource int
+
+const (
+	unknownEventSource eventSource = iota
+	apiGatewayRESTEventSource
+	apiGatewayHTTPEventSource
+	apiGatewayWebsocketEventSource
+	functionURLEventSource
+	sqsEventSource
+	snsEventSource
+	eventBridgeEventSource
+	kinesisEventSource
+	dynamoDBEventSource
+	s3EventSource
+)
+
+// eventProbe holds the fields used to detect the source of an invocation event.
+type eventProbe struct {
+	RequestContext *struct {
+		DomainName   string          `json:"domainName"`
+		HTTPMethod   string          `json:"httpMethod"`
+		HTTP         json.RawMessage `json:"http"`
+		ConnectionID string          `json:"connectionId"`
+	} `json:"requestContext"`
+	Records []struct {
+		EventSource    string `json:"eventSource"`
+		SNSEventSource string `json:"EventSource"`
+	} `json:"Records"`
+	DetailType *string `json:"detail-type"`
+	Source     string  `json:"source"`
+}
+
+// parseEventSource returns the source of the given invocation event.
+func parseEventSource(event []byte) eventSource {
+	var probe eventProbe
+	if err := json.Unmarshal(event, &probe); err != nil {
+		return unknownEventSource
+	}
+	if rc := probe.RequestContext; rc != nil {
+		switch {
+		case rc.ConnectionID != "":
+			return apiGatewayWebsocketEventSource
+		case strings.Contains(rc.DomainName, ".lambda-url."):
+			return functionURLEventSource
+		case len(rc.HTTP) > 0:
+			return apiGatewayHTTPEventSource
+		case rc.HTTPMethod != "":
+			return apiGatewayRESTEventSource
+		}
+		return unknownEventSource
+	}
+	if len(probe.Records) > 0 {
+		switch r := probe.Records[0]; {
+		case r.EventSource == "aws:sqs":
+			return sqsEventSource
+		case r.SNSEventSource == "aws:sns":
+			return snsEventSource
+		case r.EventSource == "aws:kinesis":
+			return kinesisEventSource
+		case r.EventSource == "aws:dynamodb":
+			return dynamoDBEventSource
+		case r.EventSource == "aws:s3":
+			return s3EventSource
+		}
+		return unknownEventSource
+	}
+	if probe.DetailType != nil && probe.Source != "" {
+		return eventBridgeEventSource
+	}
+	return unknownEventSource
+}
+
+// apiGatewayRESTEvent is an event sent by an API Gateway REST API (payload format 1.0).
+type apiGatewayRESTEvent struct {
+	Resource       string `json:"resource"`
+	Path           string `json:"path"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		Stage            string `json:"stage"`
+		RequestID        string `json:"requestId"`
+		APIID            string `json:"apiId"`
+		DomainName       string `json:"domainName"`
+		RequestTimeEpoch int64  `json:"requestTimeEpoch"`
+	} `json:"requestContext"`
+}
+
+// apiGatewayHTTPEvent is an event sent by an API Gateway HTTP API or a Lambda function
+// URL (payload format 2.0).
+type apiGatewayHTTPEvent struct {
+	RouteKey       string `json:"routeKey"`
+	RawPath        string `json:"rawPath"`
+	RequestContext struct {
+		Stage      string `json:"stage"`
+		RequestID  string `json:"requestId"`
+		APIID      string `json:"apiId"`
+		DomainName string `json:"domainName"`
+		TimeEpoch  int64  `json:"timeEpoch"`
+		HTTP       struct {
+			Method    string `json:"method"`
+			Path      string `json:"path"`
+			Protocol  string `json:"protocol"`
+			SourceIP  string `json:"sourceIp"`
+			UserAgent string `json:"userAgent"`
+		} `json:"http"`
+	} `json:"requestContext"`
+}
+
+// apiGatewayWebsocketEvent is an event sent by an API Gateway WebSocket API.
+type apiGatewayWebsocketEvent struct {
+	RequestContext struct {
+		Stage            string `json:"stage"`
+		RequestID        string `json:"requestId"`
+		APIID            string `json:"apiId"`
+		DomainName       string `json:"domainName"`
+		RouteKey         string `json:"routeKey"`
+		EventType        string `json:"eventType"`
+		ConnectionID     string `json:"connectionId"`
+		MessageDirection string `json:"messageDirection"`
+		RequestTimeEpoch int64  `json:"requestTimeEpoch"`
+	} `json:"requestContext"`
+}
+
+// sqsEvent is an event sent by SQS.
+type sqsEvent struct {
+	Records []sqsRecord `json:"Records"`
+}
+
+// sqsRecord is a message of an sqsEvent.
+type sqsRecord struct {
+	MessageID      string `json:"messageId"`
+	Body           string `json:"body"`
+	EventSourceARN string `json:"eventSourceARN"`
+	Attributes     struct {
+		SentTimestamp string `json:"SentTimestamp"`
+		SenderID      string `json:"SenderId"`
+	} `json:"attributes"`
+	MessageAttributes map[string]sqsMessageAttribute `json:"messageAttributes"`
+}
+
+// sqsMessageAttribute is a message attribute of an sqsRecord.
+type sqsMessageAttribute struct {
+	StringValue *string `json:"stringValue"`
+	BinaryValue []byte  `json:"binaryValue"`
+	DataType    string  `json:"dataType"`
+}
+
+// snsEvent is an event sent by SNS.
+type snsEvent struct {
+	Records []struct {
+		EventSubscriptionARN string     `json:"EventSubscriptionArn"`
+		SNS                  snsMessage `json:"Sns"`
+	} `json:"Records"`
+}
+
+// snsMessage is a notification sent by SNS, either in an snsEvent or in the body of an
+// sqsRecord when a queue is subscribed to a topic.
+type snsMessage struct {
+	Type              string                         `json:"Type"`
+	MessageID         string                         `json:"MessageId"`
+	TopicARN          string                         `json:"TopicArn"`
+	Subject           string                         `json:"Subject"`
+	Timestamp         string                         `json:"Timestamp"`
+	MessageAttributes map[string]snsMessageAttribute `json:"MessageAttributes"`
+}
+
+// snsMessageAttribute is a message attribute of an snsMessage.
+type snsMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// eventBridgeEvent is an event sent by EventBridge.
+type eventBridgeEvent struct {
+	ID         string `json:"id"`
+	DetailType string `json:"detail-type"`
+	Source     string `json:"source"`
+	Time       string `json:"time"`
+	Detail     struct {
+		TraceContext json.RawMessage `json:"_datadog"`
+	} `json:"detail"`
+}
+
+// kinesisEvent is an event sent by a Kinesis data stream.
+type kinesisEvent struct {
+	Records []struct {
+		EventID        string `json:"eventID"`
+		EventName      string `json:"eventName"`
+		EventSourceARN string `json:"eventSourceARN"`
+		Kinesis        struct {
+			PartitionKey                string  `json:"partitionKey"`
+			ApproximateArrivalTimestamp float64 `json:"approximateArrivalTimestamp"`
+		} `json:"kinesis"`
+	} `json:"Records"`
+}
+
+// dynamoDBEvent is an event sent by a DynamoDB stream.
+type dynamoDBEvent struct {
+	Records []struct {
+		EventID        string `json:"eventID"`
+		EventName      string `json:"eventName"`
+		EventVersion   string `json:"eventVersion"`
+		EventSourceARN string `json:"eventSourceARN"`
+		DynamoDB       struct {
+			ApproximateCreationDateTime float64 `json:"ApproximateCreationDateTime"`
+			SizeBytes                   int64   `json:"SizeBytes"`
+			StreamViewType              string  `json:"StreamViewType"`
+		} `json:"dynamodb"`
+	} `json:"Records"`
+}
+
+// s3Event is an event sent by S3.
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		EventTime string `json:"eventTime"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+				ARN  string `json:"arn"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
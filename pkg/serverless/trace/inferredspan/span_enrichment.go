@@ -0,0 +1,389 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package inferredspan
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// tagInferredSpanSynchronicity tells whether the managed service waited for the
+	// response of the function. Expected options are "sync" and "async".
+	tagInferredSpanSynchronicity = "_inferred_span.synchronicity"
+)
+
+// InferredSpan is a span representing the managed service which triggered an invocation.
+type InferredSpan struct {
+	Span *pb.Span
+	// IsAsync is true when the managed service did not wait for the response of the
+	// function, in which case the span ends when the invocation starts.
+	IsAsync bool
+	// TraceID and ParentID hold the trace context propagated in the message which
+	// triggered the invocation, if any.
+	TraceID  uint64
+	ParentID uint64
+	// Links reference the producers of the other messages of a batch, whose trace
+	// contexts differ from the one continued by the span.
+	Links []SpanLink
+}
+
+// FromEvent creates the inferred span of the invocation triggered by the given event,
+// or returns nil if the source of the event is not supported. The span starts when the
+// event was emitted, or at invocationStart when the event doesn't say.
+func FromEvent(event []byte, invocationStart time.Time) *InferredSpan {
+	var (
+		s   *InferredSpan
+		err error
+	)
+	switch parseEventSource(event) {
+	case apiGatewayRESTEventSource:
+		s, err = enrichAPIGatewayREST(event)
+	case apiGatewayHTTPEventSource:
+		s, err = enrichAPIGatewayHTTP(event, "aws.httpapi")
+	case apiGatewayWebsocketEventSource:
+		s, err = enrichAPIGatewayWebsocket(event)
+	case functionURLEventSource:
+		s, err = enrichAPIGatewayHTTP(event, "aws.lambda.url")
+	case sqsEventSource:
+		s, err = enrichSQS(event)
+	case snsEventSource:
+		s, err = enrichSNS(event)
+	case eventBridgeEventSource:
+		s, err = enrichEventBridge(event)
+	case kinesisEventSource:
+		s, err = enrichKinesis(event)
+	case dynamoDBEventSource:
+		s, err = enrichDynamoDB(event)
+	case s3EventSource:
+		s, err = enrichS3(event)
+	default:
+		return nil
+	}
+	if err != nil {
+		log.Debugf("Could not create the inferred span of the invocation event: %v", err)
+		return nil
+	}
+	span := s.Span
+	span.Meta[tagInferredSpanTagSource] = "self"
+	span.Meta["operation_name"] = span.Name
+	span.Meta["resource_names"] = span.Resource
+	if s.IsAsync {
+		span.Meta[tagInferredSpanSynchronicity] = "async"
+	} else {
+		span.Meta[tagInferredSpanSynchronicity] = "sync"
+	}
+	if span.Start <= 0 || span.Start > invocationStart.UnixNano() {
+		span.Start = invocationStart.UnixNano()
+	}
+	return s
+}
+
+// Complete sets the duration of the span given the times at which the invocation
+// started and ended, flagging it as an error if the invocation failed and the
+// managed service waited for its response.
+func (s *InferredSpan) Complete(invocationStart, invocationEnd time.Time, isError bool) {
+	end := invocationEnd
+	if s.IsAsync {
+		end = invocationStart
+	}
+	s.Span.Duration = end.UnixNano() - s.Span.Start
+	if s.Span.Duration < 0 {
+		s.Span.Duration = 0
+	}
+	if isError && !s.IsAsync {
+		s.Span.Error = 1
+	}
+}
+
+func enrichAPIGatewayREST(event []byte) (*InferredSpan, error) {
+	var e apiGatewayRESTEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	rc := e.RequestContext
+	return &InferredSpan{
+		Span: &pb.Span{
+			Name:     "aws.apigateway.rest",
+			Service:  rc.DomainName,
+			Resource: e.HTTPMethod + " " + e.Resource,
+			Type:     "http",
+			Start:    msToNano(rc.RequestTimeEpoch),
+			Meta: map[string]string{
+				"endpoint":    e.Path,
+				"http.url":    rc.DomainName + e.Path,
+				"http.method": e.HTTPMethod,
+				"request_id":  rc.RequestID,
+				"apiid":       rc.APIID,
+				"stage":       rc.Stage,
+			},
+		},
+	}, nil
+}
+
+func enrichAPIGatewayHTTP(event []byte, name string) (*InferredSpan, error) {
+	var e apiGatewayHTTPEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	rc := e.RequestContext
+	return &InferredSpan{
+		Span: &pb.Span{
+			Name:     name,
+			Service:  rc.DomainName,
+			Resource: rc.HTTP.Method + " " + e.RawPath,
+			Type:     "http",
+			Start:    msToNano(rc.TimeEpoch),
+			Meta: map[string]string{
+				"endpoint":        e.RawPath,
+				"http.url":        rc.DomainName + e.RawPath,
+				"http.method":     rc.HTTP.Method,
+				"http.protocol":   rc.HTTP.Protocol,
+				"http.source_ip":  rc.HTTP.SourceIP,
+				"http.user_agent": rc.HTTP.UserAgent,
+				"request_id":      rc.RequestID,
+				"apiid":           rc.APIID,
+				"stage":           rc.Stage,
+			},
+		},
+	}, nil
+}
+
+func enrichAPIGatewayWebsocket(event []byte) (*InferredSpan, error) {
+	var e apiGatewayWebsocketEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	rc := e.RequestContext
+	return &InferredSpan{
+		Span: &pb.Span{
+			Name:     "aws.apigateway.websocket",
+			Service:  rc.DomainName,
+			Resource: rc.RouteKey,
+			Type:     "web",
+			Start:    msToNano(rc.RequestTimeEpoch),
+			Meta: map[string]string{
+				"endpoint":          rc.RouteKey,
+				"http.url":          rc.DomainName + rc.RouteKey,
+				"request_id":        rc.RequestID,
+				"apiid":             rc.APIID,
+				"stage":             rc.Stage,
+				"connection_id":     rc.ConnectionID,
+				"event_type":        rc.EventType,
+				"message_direction": rc.MessageDirection,
+			},
+		},
+	}, nil
+}
+
+func enrichSQS(event []byte) (*InferredSpan, error) {
+	var e sqsEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	r := e.Records[0]
+	queueName := arnResource(r.EventSourceARN)
+	sent, _ := strconv.ParseInt(r.Attributes.SentTimestamp, 10, 64)
+	s := &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.sqs",
+			Service:  "sqs",
+			Resource: queueName,
+			Type:     "web",
+			Start:    msToNano(sent),
+			Meta: map[string]string{
+				"queuename":        queueName,
+				"event_source_arn": r.EventSourceARN,
+				"message_id":       r.MessageID,
+				"sender_id":        r.Attributes.SenderID,
+			},
+		},
+	}
+	msgs := make([]messageContext, 0, len(e.Records))
+	for _, r := range e.Records {
+		msgs = append(msgs, messageContext{traceContext: sqsTraceContext(r), messageID: r.MessageID})
+	}
+	s.setMessageContexts(msgs)
+	return s, nil
+}
+
+func enrichSNS(event []byte) (*InferredSpan, error) {
+	var e snsEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	r := e.Records[0].SNS
+	topicName := arnResource(r.TopicARN)
+	s := &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.sns",
+			Service:  "sns",
+			Resource: topicName,
+			Type:     "web",
+			Start:    rfc3339ToNano(r.Timestamp),
+			Meta: map[string]string{
+				"topicname":              topicName,
+				"topic_arn":              r.TopicARN,
+				"message_id":             r.MessageID,
+				"type":                   r.Type,
+				"subject":                r.Subject,
+				"event_subscription_arn": e.Records[0].EventSubscriptionARN,
+			},
+		},
+	}
+	msgs := make([]messageContext, 0, len(e.Records))
+	for _, r := range e.Records {
+		msgs = append(msgs, messageContext{traceContext: snsTraceContext(r.SNS), messageID: r.SNS.MessageID})
+	}
+	s.setMessageContexts(msgs)
+	return s, nil
+}
+
+func enrichEventBridge(event []byte) (*InferredSpan, error) {
+	var e eventBridgeEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	s := &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.eventbridge",
+			Service:  "eventbridge",
+			Resource: e.Source,
+			Type:     "web",
+			Start:    rfc3339ToNano(e.Time),
+			Meta: map[string]string{
+				"detail_type": e.DetailType,
+				"event_id":    e.ID,
+			},
+		},
+	}
+	if len(e.Detail.TraceContext) > 0 {
+		tc := parseTraceContext(e.Detail.TraceContext)
+		s.TraceID, s.ParentID = tc.traceID, tc.parentID
+	}
+	return s, nil
+}
+
+func enrichKinesis(event []byte) (*InferredSpan, error) {
+	var e kinesisEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	r := e.Records[0]
+	// the resource of a stream ARN is "stream/<name>"
+	streamName := arnResource(r.EventSourceARN)
+	streamName = streamName[strings.LastIndex(streamName, "/")+1:]
+	shardID := r.EventID
+	if i := strings.Index(shardID, ":"); i >= 0 {
+		shardID = shardID[:i]
+	}
+	return &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.kinesis",
+			Service:  "kinesis",
+			Resource: streamName,
+			Type:     "web",
+			Start:    int64(r.Kinesis.ApproximateArrivalTimestamp * float64(time.Second)),
+			Meta: map[string]string{
+				"streamname":       streamName,
+				"shardid":          shardID,
+				"event_source_arn": r.EventSourceARN,
+				"event_id":         r.EventID,
+				"event_name":       r.EventName,
+				"partition_key":    r.Kinesis.PartitionKey,
+			},
+		},
+	}, nil
+}
+
+func enrichDynamoDB(event []byte) (*InferredSpan, error) {
+	var e dynamoDBEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	r := e.Records[0]
+	// the resource of a stream ARN is "table/<name>/stream/<label>"
+	tableName := strings.TrimPrefix(arnResource(r.EventSourceARN), "table/")
+	if i := strings.Index(tableName, "/"); i >= 0 {
+		tableName = tableName[:i]
+	}
+	return &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.dynamodb",
+			Service:  "dynamodb",
+			Resource: tableName,
+			Type:     "web",
+			Start:    int64(r.DynamoDB.ApproximateCreationDateTime * float64(time.Second)),
+			Meta: map[string]string{
+				"tablename":        tableName,
+				"event_source_arn": r.EventSourceARN,
+				"event_id":         r.EventID,
+				"event_name":       r.EventName,
+				"event_version":    r.EventVersion,
+				"stream_view_type": r.DynamoDB.StreamViewType,
+				"size_bytes":       strconv.FormatInt(r.DynamoDB.SizeBytes, 10),
+			},
+		},
+	}, nil
+}
+
+func enrichS3(event []byte) (*InferredSpan, error) {
+	var e s3Event
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+	r := e.Records[0]
+	return &InferredSpan{
+		IsAsync: true,
+		Span: &pb.Span{
+			Name:     "aws.s3",
+			Service:  "s3",
+			Resource: r.S3.Bucket.Name,
+			Type:     "web",
+			Start:    rfc3339ToNano(r.EventTime),
+			Meta: map[string]string{
+				"bucketname":  r.S3.Bucket.Name,
+				"bucket_arn":  r.S3.Bucket.ARN,
+				"object_key":  r.S3.Object.Key,
+				"object_size": strconv.FormatInt(r.S3.Object.Size, 10),
+				"object_etag": r.S3.Object.ETag,
+				"event_name":  r.EventName,
+			},
+		},
+	}, nil
+}
+
+// arnResource returns the resource part of the given ARN, e.g. "my-queue" for
+// "arn:aws:sqs:us-east-1:123456789012:my-queue".
+func arnResource(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[5]
+}
+
+func msToNano(ms int64) int64 {
+	return ms * int64(time.Millisecond)
+}
+
+func rfc3339ToNano(s string) int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0
+	}
+	return t.UnixNano()
+}
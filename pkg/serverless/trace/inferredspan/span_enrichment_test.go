@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package inferredspan
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readEvent(t *testing.T, name string) []byte {
+	event, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return event
+}
+
+func TestParseEventSource(t *testing.T) {
+	for name, want := range map[string]eventSource{
+		"api-gateway-rest.json":      apiGatewayRESTEventSource,
+		"api-gateway-http.json":      apiGatewayHTTPEventSource,
+		"api-gateway-websocket.json": apiGatewayWebsocketEventSource,
+		"function-url.json":          functionURLEventSource,
+		"sqs.json":                   sqsEventSource,
+		"sns.json":                   snsEventSource,
+		"eventbridge.json":           eventBridgeEventSource,
+		"kinesis.json":               kinesisEventSource,
+		"dynamodb.json":              dynamoDBEventSource,
+		"s3.json":                    s3EventSource,
+	} {
+		assert.Equal(t, want, parseEventSource(readEvent(t, name)), name)
+	}
+	for _, event := range []string{``, `{}`, `not json`, `{"Records":[]}`, `{"Records":[{"eventSource":"aws:unknown"}]}`, `{"requestContext":{}}`} {
+		assert.Equal(t, unknownEventSource, parseEventSource([]byte(event)), event)
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	invocationStart := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		file     string
+		name     string
+		service  string
+		resource string
+		start    int64
+		async    bool
+		traceID  uint64
+		parentID uint64
+		meta     map[string]string
+	}{
+		{
+			file:     "api-gateway-rest.json",
+			name:     "aws.apigateway.rest",
+			service:  "70ixmpl4fl.execute-api.us-east-2.amazonaws.com",
+			resource: "GET /users/create",
+			start:    1428582896000 * int64(time.Millisecond),
+			meta: map[string]string{
+				"http.url":    "70ixmpl4fl.execute-api.us-east-2.amazonaws.com/users/create",
+				"http.method": "GET",
+				"stage":       "dev",
+			},
+		},
+		{
+			file:     "api-gateway-http.json",
+			name:     "aws.httpapi",
+			service:  "x02yirxc7a.execute-api.sa-east-1.amazonaws.com",
+			resource: "GET /httpapi/get",
+			start:    1631212283738 * int64(time.Millisecond),
+			meta: map[string]string{
+				"http.url":        "x02yirxc7a.execute-api.sa-east-1.amazonaws.com/httpapi/get",
+				"http.method":     "GET",
+				"http.user_agent": "curl/7.64.1",
+			},
+		},
+		{
+			file:     "api-gateway-websocket.json",
+			name:     "aws.apigateway.websocket",
+			service:  "p62c47itsb.execute-api.sa-east-1.amazonaws.com",
+			resource: "$default",
+			start:    1631885092806 * int64(time.Millisecond),
+			meta: map[string]string{
+				"connection_id": "Fc2tgH1RmjQCIOg=",
+				"event_type":    "MESSAGE",
+			},
+		},
+		{
+			file:     "function-url.json",
+			name:     "aws.lambda.url",
+			service:  "a8hyhsshac.lambda-url.eu-south-1.amazonaws.com",
+			resource: "GET /",
+			start:    1643927787028 * int64(time.Millisecond),
+			meta: map[string]string{
+				"http.url":    "a8hyhsshac.lambda-url.eu-south-1.amazonaws.com/",
+				"http.method": "GET",
+			},
+		},
+		{
+			file:     "sqs.json",
+			name:     "aws.sqs",
+			service:  "sqs",
+			resource: "InferredSpansQueueNode",
+			start:    1634662094538 * int64(time.Millisecond),
+			async:    true,
+			traceID:  2684756524522091840,
+			parentID: 7431398482019833808,
+			meta: map[string]string{
+				"queuename": "InferredSpansQueueNode",
+				"sender_id": "AROAYLRBL3L4MHUYRDTD4:dummy",
+			},
+		},
+		{
+			file:     "sns.json",
+			name:     "aws.sns",
+			service:  "sns",
+			resource: "serverlessTracingTopicPy",
+			start:    time.Date(2022, 1, 31, 14, 13, 41, 637000000, time.UTC).UnixNano(),
+			async:    true,
+			traceID:  4948377316357291421,
+			parentID: 6146661445371922739,
+			meta: map[string]string{
+				"topicname": "serverlessTracingTopicPy",
+				"type":      "Notification",
+			},
+		},
+		{
+			file:     "eventbridge.json",
+			name:     "aws.eventbridge",
+			service:  "eventbridge",
+			resource: "my.event",
+			start:    time.Date(2022, 1, 24, 16, 0, 10, 0, time.UTC).UnixNano(),
+			async:    true,
+			traceID:  5827606813695714842,
+			parentID: 4726693487091824375,
+			meta: map[string]string{
+				"detail_type": "UserSignUp",
+			},
+		},
+		{
+			file:     "kinesis.json",
+			name:     "aws.kinesis",
+			service:  "kinesis",
+			resource: "kinesisStream",
+			start:    1643638425163 * int64(time.Millisecond),
+			async:    true,
+			meta: map[string]string{
+				"streamname": "kinesisStream",
+				"shardid":    "shardId-000000000002",
+			},
+		},
+		{
+			file:     "dynamodb.json",
+			name:     "aws.dynamodb",
+			service:  "dynamodb",
+			resource: "ExampleTableWithStream",
+			start:    1428537600 * int64(time.Second),
+			async:    true,
+			meta: map[string]string{
+				"tablename":  "ExampleTableWithStream",
+				"event_name": "INSERT",
+				"size_bytes": "26",
+			},
+		},
+		{
+			file:     "s3.json",
+			name:     "aws.s3",
+			service:  "s3",
+			resource: "example-bucket",
+			// the event time is the epoch, so the invocation start is used
+			start: invocationStart.UnixNano(),
+			async: true,
+			meta: map[string]string{
+				"bucketname": "example-bucket",
+				"object_key": "test/key",
+			},
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			s := FromEvent(readEvent(t, tt.file), invocationStart)
+			require.NotNil(t, s)
+			assert.Equal(t, tt.name, s.Span.Name)
+			assert.Equal(t, tt.service, s.Span.Service)
+			assert.Equal(t, tt.resource, s.Span.Resource)
+			assert.InDelta(t, tt.start, s.Span.Start, float64(time.Microsecond))
+			assert.Equal(t, tt.async, s.IsAsync)
+			assert.Equal(t, tt.traceID, s.TraceID)
+			assert.Equal(t, tt.parentID, s.ParentID)
+			assert.True(t, CheckIsInferredSpan(s.Span))
+			assert.Equal(t, tt.name, s.Span.Meta["operation_name"])
+			assert.Equal(t, tt.resource, s.Span.Meta["resource_names"])
+			for k, v := range tt.meta {
+				assert.Equal(t, v, s.Span.Meta[k], k)
+			}
+		})
+	}
+
+	assert.Nil(t, FromEvent([]byte(`{"hello":"world"}`), invocationStart))
+}
+
+func TestComplete(t *testing.T) {
+	eventTime := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	invocationStart := eventTime.Add(time.Second)
+	invocationEnd := invocationStart.Add(time.Second)
+
+	sync := &InferredSpan{Span: &pb.Span{Start: eventTime.UnixNano()}}
+	sync.Complete(invocationStart, invocationEnd, true)
+	assert.Equal(t, int64(2*time.Second), sync.Span.Duration)
+	assert.Equal(t, int32(1), sync.Span.Error)
+
+	async := &InferredSpan{Span: &pb.Span{Start: eventTime.UnixNano()}, IsAsync: true}
+	async.Complete(invocationStart, invocationEnd, true)
+	assert.Equal(t, int64(time.Second), async.Span.Duration)
+	assert.Equal(t, int32(0), async.Span.Error)
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package inferredspan
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// datadogAttribute is the message attribute or detail field holding the trace
+	// context propagated through SQS, SNS and EventBridge.
+	datadogAttribute = "_datadog"
+
+	traceIDHeader     = "x-datadog-trace-id"
+	parentIDHeader    = "x-datadog-parent-id"
+	traceParentHeader = "traceparent"
+
+	// tagSpanLinks is the meta key holding the JSON encoded links of a span.
+	tagSpanLinks = "_dd.span_links"
+)
+
+// traceContext is the trace context propagated by the producer of a message.
+type traceContext struct {
+	traceID  uint64
+	parentID uint64
+}
+
+// messageContext is the trace context propagated in a message of a batch.
+type messageContext struct {
+	traceContext
+	messageID string
+}
+
+// SpanLink references a span, usually of another trace, which is causally related
+// to the span holding the link without being its parent.
+type SpanLink struct {
+	TraceID    uint64
+	SpanID     uint64
+	Attributes map[string]string
+}
+
+// spanLinkJSON is the encoding of a SpanLink in the meta of a span.
+type spanLinkJSON struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SetSpanLinks adds the given links to span. The links are stored as JSON in its meta, the
+// way tracers send them to agents which don't support a dedicated field.
+func SetSpanLinks(span *pb.Span, links []SpanLink) {
+	if len(links) == 0 {
+		return
+	}
+	out := make([]spanLinkJSON, 0, len(links))
+	for _, l := range links {
+		out = append(out, spanLinkJSON{
+			TraceID:    fmt.Sprintf("%032x", l.TraceID),
+			SpanID:     fmt.Sprintf("%016x", l.SpanID),
+			Attributes: l.Attributes,
+		})
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		log.Debugf("Could not encode the span links: %v", err)
+		return
+	}
+	if span.Meta == nil {
+		span.Meta = make(map[string]string)
+	}
+	span.Meta[tagSpanLinks] = string(b)
+}
+
+// setMessageContexts makes the first trace context propagated in a batch of messages
+// the parent of s, and links s to the other distinct ones, so that every producer of
+// the batch is connected to the invocation.
+func (s *InferredSpan) setMessageContexts(msgs []messageContext) {
+	seen := make(map[traceContext]struct{}, len(msgs))
+	for _, msg := range msgs {
+		if msg.traceID == 0 {
+			continue
+		}
+		if _, ok := seen[msg.traceContext]; ok {
+			continue
+		}
+		seen[msg.traceContext] = struct{}{}
+		if s.TraceID == 0 {
+			s.TraceID, s.ParentID = msg.traceID, msg.parentID
+			continue
+		}
+		s.Links = append(s.Links, SpanLink{
+			TraceID:    msg.traceID,
+			SpanID:     msg.parentID,
+			Attributes: map[string]string{"messaging.message_id": msg.messageID},
+		})
+	}
+}
+
+// sqsTraceContext returns the trace context propagated in the given SQS message, either
+// in its own attributes or in those of the SNS notification it holds.
+func sqsTraceContext(r sqsRecord) traceContext {
+	if attr, ok := r.MessageAttributes[datadogAttribute]; ok {
+		if attr.StringValue != nil {
+			return parseTraceContext([]byte(*attr.StringValue))
+		}
+		return parseTraceContext(attr.BinaryValue)
+	}
+	if attr, ok := r.MessageAttributes[traceParentHeader]; ok && attr.StringValue != nil {
+		return parseTraceParent(*attr.StringValue)
+	}
+	var msg snsMessage
+	if err := json.Unmarshal([]byte(r.Body), &msg); err == nil && msg.Type == "Notification" {
+		return snsTraceContext(msg)
+	}
+	return traceContext{}
+}
+
+// snsTraceContext returns the trace context propagated in the attributes of the given
+// SNS message.
+func snsTraceContext(msg snsMessage) traceContext {
+	if attr, ok := msg.MessageAttributes[datadogAttribute]; ok {
+		v := []byte(attr.Value)
+		if attr.Type == "Binary" {
+			var err error
+			if v, err = base64.StdEncoding.DecodeString(attr.Value); err != nil {
+				log.Debugf("Could not decode the trace context of the invocation event: %v", err)
+				return traceContext{}
+			}
+		}
+		return parseTraceContext(v)
+	}
+	if attr, ok := msg.MessageAttributes[traceParentHeader]; ok {
+		return parseTraceParent(attr.Value)
+	}
+	return traceContext{}
+}
+
+// parseTraceContext returns the trace context held by the given JSON object of
+// propagation headers. Datadog headers take precedence over the W3C ones.
+func parseTraceContext(v []byte) traceContext {
+	var headers map[string]string
+	if err := json.Unmarshal(v, &headers); err != nil {
+		log.Debugf("Could not unmarshal the trace context of the invocation event: %v", err)
+		return traceContext{}
+	}
+	traceID, err1 := strconv.ParseUint(headers[traceIDHeader], 10, 64)
+	parentID, err2 := strconv.ParseUint(headers[parentIDHeader], 10, 64)
+	if err1 == nil && err2 == nil && traceID != 0 {
+		return traceContext{traceID: traceID, parentID: parentID}
+	}
+	return parseTraceParent(headers[traceParentHeader])
+}
+
+// parseTraceParent returns the trace context of a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". The Datadog trace ID is
+// made of the lower 64 bits of the W3C one.
+func parseTraceParent(s string) traceContext {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceContext{}
+	}
+	traceID, err1 := strconv.ParseUint(parts[1][16:], 16, 64)
+	parentID, err2 := strconv.ParseUint(parts[2], 16, 64)
+	if err1 != nil || err2 != nil {
+		return traceContext{}
+	}
+	return traceContext{traceID: traceID, parentID: parentID}
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package inferredspan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQSBatchTraceContexts(t *testing.T) {
+	s := FromEvent(readEvent(t, "sqs-batch.json"), time.Now())
+	require.NotNil(t, s)
+	assert.Equal(t, uint64(1111), s.TraceID)
+	assert.Equal(t, uint64(1112), s.ParentID)
+	assert.Equal(t, []SpanLink{
+		{TraceID: 3333, SpanID: 2222, Attributes: map[string]string{"messaging.message_id": "11111111-0000-0000-0000-000000000002"}},
+		{TraceID: 4444, SpanID: 4445, Attributes: map[string]string{"messaging.message_id": "11111111-0000-0000-0000-000000000003"}},
+	}, s.Links)
+
+	// a batch without trace context
+	s = FromEvent(readEvent(t, "kinesis.json"), time.Now())
+	require.NotNil(t, s)
+	assert.Zero(t, s.TraceID)
+	assert.Empty(t, s.Links)
+}
+
+func TestParseTraceContext(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want traceContext
+	}{
+		{`{"x-datadog-trace-id":"1","x-datadog-parent-id":"2"}`, traceContext{1, 2}},
+		{`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, traceContext{0xa3ce929d0e0e4736, 0x00f067aa0ba902b7}},
+		{`{"x-datadog-trace-id":"1","x-datadog-parent-id":"2","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, traceContext{1, 2}},
+		{`{"x-datadog-trace-id":"invalid","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`, traceContext{0xa3ce929d0e0e4736, 0x00f067aa0ba902b7}},
+		{`{"traceparent":"00-4bf92f3577b34da6-00f067aa0ba902b7-01"}`, traceContext{}},
+		{`{"traceparent":"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01"}`, traceContext{}},
+		{`{}`, traceContext{}},
+		{`invalid`, traceContext{}},
+	} {
+		assert.Equal(t, tt.want, parseTraceContext([]byte(tt.in)), tt.in)
+	}
+}
+
+func TestSetSpanLinks(t *testing.T) {
+	span := &pb.Span{}
+	SetSpanLinks(span, nil)
+	assert.Nil(t, span.Meta)
+
+	SetSpanLinks(span, []SpanLink{
+		{TraceID: 0xa3ce929d0e0e4736, SpanID: 0xf067aa0ba902b7, Attributes: map[string]string{"messaging.message_id": "1"}},
+		{TraceID: 1, SpanID: 2},
+	})
+	assert.JSONEq(t, `[
+		{"trace_id":"0000000000000000a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","attributes":{"messaging.message_id":"1"}},
+		{"trace_id":"00000000000000000000000000000001","span_id":"0000000000000002"}
+	]`, span.Meta["_dd.span_links"])
+}
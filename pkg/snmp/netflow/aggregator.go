@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/epforwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// FlowAggregator aggregates the flows received by the listeners over a flush
+// interval, and sends the aggregated flows to the event platform.
+type FlowAggregator struct {
+	flowIn        chan *Flow
+	flushInterval time.Duration
+	namespace     string
+	sender        aggregator.Sender
+	flows         map[flowKey]*Flow
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+// NewFlowAggregator returns a new FlowAggregator.
+func NewFlowAggregator(sender aggregator.Sender, config *Config) *FlowAggregator {
+	return &FlowAggregator{
+		flowIn:        make(chan *Flow, config.AggregatorBufferSize),
+		flushInterval: time.Duration(config.AggregatorFlushInterval) * time.Second,
+		namespace:     config.Namespace,
+		sender:        sender,
+		flows:         make(map[flowKey]*Flow),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// GetFlowInChan returns the channel the flows to aggregate are sent to.
+func (agg *FlowAggregator) GetFlowInChan() chan *Flow {
+	return agg.flowIn
+}
+
+// Start starts the aggregation loop.
+func (agg *FlowAggregator) Start() {
+	go agg.run()
+}
+
+// Stop stops the aggregation loop, the pending flows are flushed.
+func (agg *FlowAggregator) Stop() {
+	close(agg.stop)
+	<-agg.done
+}
+
+func (agg *FlowAggregator) run() {
+	defer close(agg.done)
+	ticker := time.NewTicker(agg.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case flow := <-agg.flowIn:
+			agg.add(flow)
+		case <-ticker.C:
+			agg.flush(time.Now())
+		case <-agg.stop:
+			// aggregate the flows already received before the last flush
+			for len(agg.flowIn) > 0 {
+				agg.add(<-agg.flowIn)
+			}
+			agg.flush(time.Now())
+			return
+		}
+	}
+}
+
+func (agg *FlowAggregator) add(flow *Flow) {
+	netflowFlowsReceived.Add(1)
+	key := flow.key()
+	if aggregated, ok := agg.flows[key]; ok {
+		aggregated.merge(flow)
+		return
+	}
+	agg.flows[key] = flow
+}
+
+// flush sends the aggregated flows to the event platform.
+func (agg *FlowAggregator) flush(now time.Time) {
+	if len(agg.flows) == 0 {
+		return
+	}
+	flushTimestamp := now.UnixNano() / int64(time.Millisecond)
+	for _, flow := range agg.flows {
+		payloadBytes, err := json.Marshal(buildPayload(flow, agg.namespace, flushTimestamp))
+		if err != nil {
+			log.Errorf("Error marshalling flow: %s", err)
+			continue
+		}
+		agg.sender.EventPlatformEvent(string(payloadBytes), epforwarder.EventTypeNetworkDevicesNetFlow)
+	}
+	log.Debugf("Flushed %d aggregated flows", len(agg.flows))
+	netflowFlowsFlushed.Add(int64(len(agg.flows)))
+	agg.flows = make(map[flowKey]*Flow)
+}
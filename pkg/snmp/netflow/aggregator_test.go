@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/epforwarder"
+)
+
+func newTestFlow() *Flow {
+	return &Flow{
+		FlowType:        TypeNetFlow9,
+		ExporterAddr:    net.IP{127, 0, 0, 1},
+		SamplingRate:    1,
+		Direction:       directionIngress,
+		StartTimestamp:  1640000000,
+		EndTimestamp:    1640000010,
+		Bytes:           100,
+		Packets:         1,
+		EtherType:       etherTypeIPv4,
+		IPProtocol:      ipProtocolTCP,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         2000,
+		DstPort:         80,
+		TCPFlags:        0x02,
+		InputInterface:  1,
+		OutputInterface: 2,
+	}
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+	agg := NewFlowAggregator(sender, &Config{AggregatorBufferSize: 10, AggregatorFlushInterval: 10, Namespace: "default"})
+
+	agg.add(newTestFlow())
+	other := newTestFlow()
+	other.StartTimestamp = 1639999990
+	other.EndTimestamp = 1640000020
+	other.Bytes = 200
+	other.Packets = 2
+	other.TCPFlags = 0x10
+	agg.add(other)
+	assert.Len(t, agg.flows, 1)
+
+	agg.flush(time.Unix(1640000030, 0))
+
+	expectedEvent := `{"flush_timestamp":1640000030000,"type":"netflow9","sampling_rate":1,"direction":"ingress","start":1639999990,"end":1640000020,"bytes":300,"packets":3,"ether_type":"IPv4","ip_protocol":"TCP","device":{"id":"default:127.0.0.1","namespace":"default"},"exporter":{"ip":"127.0.0.1"},"source":{"ip":"10.0.0.1","port":2000},"destination":{"ip":"10.0.0.2","port":80},"ingress":{"interface":{"id":"default:127.0.0.1:1","device_id":"default:127.0.0.1","index":1}},"egress":{"interface":{"id":"default:127.0.0.1:2","device_id":"default:127.0.0.1","index":2}},"tcp_flags":["SYN","ACK"]}`
+	sender.AssertEventPlatformEvent(t, expectedEvent, epforwarder.EventTypeNetworkDevicesNetFlow)
+	sender.AssertNumberOfCalls(t, "EventPlatformEvent", 1)
+	assert.Empty(t, agg.flows)
+
+	// nothing is sent when there is no flow
+	agg.flush(time.Unix(1640000040, 0))
+	sender.AssertNumberOfCalls(t, "EventPlatformEvent", 1)
+}
+
+func TestAggregatorStopFlushesPendingFlows(t *testing.T) {
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+	agg := NewFlowAggregator(sender, &Config{AggregatorBufferSize: 10, AggregatorFlushInterval: 300, Namespace: "default"})
+	agg.Start()
+
+	first := newTestFlow()
+	second := newTestFlow()
+	second.DstPort = 443
+	agg.GetFlowInChan() <- first
+	agg.GetFlowInChan() <- second
+	agg.Stop()
+
+	sender.AssertNumberOfCalls(t, "EventPlatformEvent", 2)
+}
+
+func TestBuildPayloadEgressIPv6(t *testing.T) {
+	flow := newTestFlow()
+	flow.FlowType = TypeSFlow5
+	flow.Direction = directionEgress
+	flow.EtherType = etherTypeIPv6
+	flow.IPProtocol = 200
+	flow.SrcAddr = net.ParseIP("2001:db8::1")
+	flow.DstAddr = net.ParseIP("2001:db8::2")
+
+	payload := buildPayload(flow, "ns", 1)
+	assert.Equal(t, "egress", payload.Direction)
+	assert.Equal(t, "IPv6", payload.EtherType)
+	assert.Equal(t, "200", payload.IPProtocol)
+	assert.Equal(t, "2001:db8::1", payload.Source.IP)
+	assert.Equal(t, "ns:127.0.0.1", payload.Device.ID)
+	assert.Nil(t, payload.TCPFlags)
+}
+
+func TestBuildPayloadIDs(t *testing.T) {
+	flow := newTestFlow()
+	flow.ExporterAddr = net.IP{10, 1, 2, 3}
+	flow.InputInterface = 7
+	flow.OutputInterface = 12
+
+	payload := buildPayload(flow, "my-ns", 1)
+	// the IDs are the ones of the network devices metadata sent by the SNMP check
+	assert.Equal(t, Device{ID: "my-ns:10.1.2.3", Namespace: "my-ns"}, payload.Device)
+	assert.Equal(t, Interface{ID: "my-ns:10.1.2.3:7", DeviceID: "my-ns:10.1.2.3", Index: 7}, payload.Ingress.Interface)
+	assert.Equal(t, Interface{ID: "my-ns:10.1.2.3:12", DeviceID: "my-ns:10.1.2.3", Index: 12}, payload.Egress.Interface)
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// IsEnabled returns whether flow collection is enabled in the Agent configuration.
+func IsEnabled() bool {
+	return config.Datadog.GetBool("network_devices.netflow.enabled")
+}
+
+// ListenerConfig contains the configuration of one flow listener.
+// YAML field tags provided for test marshalling purposes.
+type ListenerConfig struct {
+	FlowType FlowType `mapstructure:"flow_type" yaml:"flow_type"`
+	BindHost string   `mapstructure:"bind_host" yaml:"bind_host"`
+	Port     uint16   `mapstructure:"port" yaml:"port"`
+}
+
+// Config contains configuration for the flow listeners.
+// YAML field tags provided for test marshalling purposes.
+type Config struct {
+	Enabled                 bool             `mapstructure:"enabled" yaml:"enabled"`
+	Listeners               []ListenerConfig `mapstructure:"listeners" yaml:"listeners"`
+	StopTimeout             int              `mapstructure:"stop_timeout" yaml:"stop_timeout"`
+	AggregatorBufferSize    int              `mapstructure:"aggregator_buffer_size" yaml:"aggregator_buffer_size"`
+	AggregatorFlushInterval int              `mapstructure:"aggregator_flush_interval" yaml:"aggregator_flush_interval"`
+	Namespace               string           `mapstructure:"namespace" yaml:"namespace"`
+}
+
+// ReadConfig builds and returns configuration from Agent configuration.
+func ReadConfig() (*Config, error) {
+	var c Config
+	err := config.Datadog.UnmarshalKey("network_devices.netflow", &c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Enabled {
+		return nil, errors.New("flow listener is disabled")
+	}
+	if len(c.Listeners) == 0 {
+		return nil, errors.New("no flow listener configured")
+	}
+
+	// Set defaults.
+	for i := range c.Listeners {
+		listener := &c.Listeners[i]
+		defaultPort, ok := defaultPorts[listener.FlowType]
+		if !ok {
+			return nil, fmt.Errorf("unsupported flow type: %q", listener.FlowType)
+		}
+		if listener.Port == 0 {
+			listener.Port = defaultPort
+		}
+		if listener.BindHost == "" {
+			listener.BindHost = defaultBindHost
+		}
+	}
+	if c.StopTimeout == 0 {
+		c.StopTimeout = defaultStopTimeout
+	}
+	if c.AggregatorBufferSize == 0 {
+		c.AggregatorBufferSize = defaultAggregatorBufferSize
+	}
+	if c.AggregatorFlushInterval == 0 {
+		c.AggregatorFlushInterval = defaultAggregatorFlushInterval
+	}
+
+	if c.Namespace == "" {
+		c.Namespace = config.Datadog.GetString("network_devices.namespace")
+	}
+	c.Namespace, err = common.NormalizeNamespace(c.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load config: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Addr returns the host:port address to listen on.
+func (c *ListenerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.BindHost, c.Port)
+}
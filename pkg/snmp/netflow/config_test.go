@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// configure sets Datadog Agent configuration from a config object.
+func configure(t *testing.T, flowConfig Config) {
+	datadogYaml := map[string]map[string]interface{}{
+		"network_devices": {
+			"netflow": flowConfig,
+		},
+	}
+
+	config.Datadog.SetConfigType("yaml")
+	out, err := yaml.Marshal(datadogYaml)
+	require.NoError(t, err)
+
+	err = config.Datadog.ReadConfig(strings.NewReader(string(out)))
+	require.NoError(t, err)
+}
+
+func TestFullConfig(t *testing.T) {
+	configure(t, Config{
+		Enabled: true,
+		Listeners: []ListenerConfig{
+			{FlowType: TypeNetFlow9, BindHost: "127.0.0.1", Port: 1234},
+		},
+		StopTimeout:             10,
+		AggregatorBufferSize:    100,
+		AggregatorFlushInterval: 60,
+		Namespace:               "foo",
+	})
+	flowConfig, err := ReadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []ListenerConfig{{FlowType: TypeNetFlow9, BindHost: "127.0.0.1", Port: 1234}}, flowConfig.Listeners)
+	assert.Equal(t, "127.0.0.1:1234", flowConfig.Listeners[0].Addr())
+	assert.Equal(t, 10, flowConfig.StopTimeout)
+	assert.Equal(t, 100, flowConfig.AggregatorBufferSize)
+	assert.Equal(t, 60, flowConfig.AggregatorFlushInterval)
+	assert.Equal(t, "foo", flowConfig.Namespace)
+}
+
+func TestMinimalConfig(t *testing.T) {
+	configure(t, Config{
+		Enabled: true,
+		Listeners: []ListenerConfig{
+			{FlowType: TypeNetFlow5},
+			{FlowType: TypeIPFIX},
+			{FlowType: TypeSFlow5},
+		},
+	})
+	flowConfig, err := ReadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []ListenerConfig{
+		{FlowType: TypeNetFlow5, BindHost: "0.0.0.0", Port: 2055},
+		{FlowType: TypeIPFIX, BindHost: "0.0.0.0", Port: 4739},
+		{FlowType: TypeSFlow5, BindHost: "0.0.0.0", Port: 6343},
+	}, flowConfig.Listeners)
+	assert.Equal(t, 5, flowConfig.StopTimeout)
+	assert.Equal(t, 10000, flowConfig.AggregatorBufferSize)
+	assert.Equal(t, 300, flowConfig.AggregatorFlushInterval)
+	assert.Equal(t, "default", flowConfig.Namespace)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for name, flowConfig := range map[string]Config{
+		"disabled":          {Listeners: []ListenerConfig{{FlowType: TypeNetFlow5}}},
+		"no listener":       {Enabled: true},
+		"invalid flow type": {Enabled: true, Listeners: []ListenerConfig{{FlowType: "netflow7"}}},
+		"invalid namespace": {Enabled: true, Listeners: []ListenerConfig{{FlowType: TypeNetFlow5}}, Namespace: strings.Repeat("a", 101)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			configure(t, flowConfig)
+			_, err := ReadConfig()
+			assert.Error(t, err)
+		})
+	}
+}
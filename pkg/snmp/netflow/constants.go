@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+const (
+	defaultBindHost                = "0.0.0.0"
+	defaultStopTimeout             = 5
+	defaultAggregatorBufferSize    = 10000
+	defaultAggregatorFlushInterval = 300 // in seconds
+
+	// Standard UDP ports of the flow protocols.
+	defaultNetFlowPort = uint16(2055)
+	defaultIPFIXPort   = uint16(4739)
+	defaultSFlowPort   = uint16(6343)
+
+	// maxPacketSize is the maximum size of an UDP datagram.
+	maxPacketSize = 65535
+
+	// maxTemplates is the maximum number of NetFlow v9 or IPFIX templates kept
+	// by a listener, the least recently used templates are evicted first.
+	maxTemplates = 10000
+)
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var errTruncatedPacket = errors.New("truncated packet")
+
+// packetDecoder decodes the flows of the packets sent by the exporters.
+//
+// A packetDecoder is not thread-safe, the template-based decoders keep the
+// templates sent by the exporters.
+type packetDecoder interface {
+	decode(payload []byte, exporter net.IP, receivedAt time.Time) ([]*Flow, error)
+}
+
+// newPacketDecoder returns the decoder of the given flow type.
+func newPacketDecoder(flowType FlowType) (packetDecoder, error) {
+	switch flowType {
+	case TypeNetFlow5:
+		return &netflow5Decoder{}, nil
+	case TypeNetFlow9:
+		return newTemplateDecoder(TypeNetFlow9), nil
+	case TypeIPFIX:
+		return newTemplateDecoder(TypeIPFIX), nil
+	case TypeSFlow5:
+		return &sflow5Decoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported flow type: %q", flowType)
+	}
+}
+
+// reader reads big-endian values from a packet, it records the first read
+// past the end of the packet in err and then returns zero values.
+type reader struct {
+	buf []byte
+	err error
+}
+
+// bytes returns the next n bytes of the packet, or nil if the packet is too short.
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errTruncatedPacket
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// fixed returns the next n bytes of the packet, or n zero bytes if the
+// packet is too short.
+func (r *reader) fixed(n int) []byte {
+	if b := r.bytes(n); b != nil {
+		return b
+	}
+	return make([]byte, n)
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) uint8() uint8 {
+	return r.fixed(1)[0]
+}
+
+func (r *reader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.fixed(2))
+}
+
+func (r *reader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.fixed(4))
+}
+
+func (r *reader) ip(n int) net.IP {
+	ip := make(net.IP, n)
+	copy(ip, r.fixed(n))
+	return ip
+}
+
+// decodeUint decodes a big-endian unsigned integer of up to 8 bytes.
+func decodeUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// uptimeToTimestamp converts a time relative to the boot of the exporter, in
+// milliseconds, to seconds since the epoch using the export time and uptime
+// of the packet.
+func uptimeToTimestamp(exportTimeMs int64, sysUptimeMs uint32, uptimeMs uint32) uint64 {
+	timestamp := exportTimeMs - int64(sysUptimeMs-uptimeMs)
+	if timestamp < 0 {
+		return 0
+	}
+	return uint64(timestamp / 1000)
+}
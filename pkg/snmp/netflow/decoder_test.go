@@ -0,0 +1,297 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exporterIP = net.IP{127, 0, 0, 1}
+
+// packet builds big-endian packets for the tests.
+type packet struct {
+	bytes.Buffer
+}
+
+func (p *packet) put(values ...interface{}) *packet {
+	for _, v := range values {
+		binary.Write(&p.Buffer, binary.BigEndian, v) //nolint:errcheck
+	}
+	return p
+}
+
+func (p *packet) set(setID uint16, content []byte) *packet {
+	return p.put(setID, uint16(len(content)+4), content)
+}
+
+func TestNewPacketDecoder(t *testing.T) {
+	for _, flowType := range []FlowType{TypeNetFlow5, TypeNetFlow9, TypeIPFIX, TypeSFlow5} {
+		decoder, err := newPacketDecoder(flowType)
+		assert.NoError(t, err)
+		assert.NotNil(t, decoder)
+	}
+	_, err := newPacketDecoder("netflow7")
+	assert.EqualError(t, err, `unsupported flow type: "netflow7"`)
+}
+
+func TestDecodeNetFlow5(t *testing.T) {
+	p := &packet{}
+	// header: version, count, uptime, secs, nsecs, sequence, engine, sampling
+	p.put(uint16(5), uint16(2), uint32(100000), uint32(1640000000), uint32(0), uint32(1), uint16(0), uint16(0x4000|10))
+	for i := 0; i < 2; i++ {
+		p.put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, []byte{0, 0, 0, 0}, uint16(1), uint16(2))
+		// packets, bytes, first and last switched
+		p.put(uint32(3), uint32(300), uint32(40000), uint32(90000))
+		// ports, padding, tcp flags, protocol, tos, AS numbers, masks and padding
+		p.put(uint16(2000+i), uint16(80), uint8(0), uint8(0x12), uint8(6), uint8(0), make([]byte, 8))
+	}
+
+	flows, err := (&netflow5Decoder{}).decode(p.Bytes(), exporterIP, time.Now())
+	require.NoError(t, err)
+	require.Len(t, flows, 2)
+	assert.Equal(t, &Flow{
+		FlowType:        TypeNetFlow5,
+		ExporterAddr:    exporterIP,
+		SamplingRate:    10,
+		Direction:       directionIngress,
+		StartTimestamp:  1639999940,
+		EndTimestamp:    1639999990,
+		Bytes:           300,
+		Packets:         3,
+		EtherType:       etherTypeIPv4,
+		IPProtocol:      ipProtocolTCP,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         2000,
+		DstPort:         80,
+		TCPFlags:        0x12,
+		InputInterface:  1,
+		OutputInterface: 2,
+	}, flows[0])
+	assert.Equal(t, uint16(2001), flows[1].SrcPort)
+}
+
+func TestDecodeNetFlow5Errors(t *testing.T) {
+	decoder := &netflow5Decoder{}
+
+	_, err := decoder.decode([]byte{0, 9, 0, 0}, exporterIP, time.Now())
+	assert.EqualError(t, err, "unexpected NetFlow version 9")
+
+	_, err = decoder.decode([]byte{0, 5, 0}, exporterIP, time.Now())
+	assert.Equal(t, errTruncatedPacket, err)
+
+	// a header announcing a record which is not in the packet
+	p := (&packet{}).put(uint16(5), uint16(1), make([]byte, 20))
+	_, err = decoder.decode(p.Bytes(), exporterIP, time.Now())
+	assert.Equal(t, errTruncatedPacket, err)
+}
+
+func TestDecodeNetFlow9(t *testing.T) {
+	template := (&packet{}).put(uint16(256), uint16(7),
+		uint16(fieldIPv4SrcAddr), uint16(4),
+		uint16(fieldIPv4DstAddr), uint16(4),
+		uint16(fieldL4SrcPort), uint16(2),
+		uint16(fieldL4DstPort), uint16(2),
+		uint16(fieldProtocol), uint16(1),
+		uint16(fieldInBytes), uint16(4),
+		uint16(fieldFirstSwitched), uint16(4),
+	)
+	record := (&packet{}).put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, uint16(53), uint16(5353), uint8(17), uint32(1500), uint32(40000))
+	// padded data set
+	data := append(record.Bytes(), 0, 0)
+
+	decoder := newTemplateDecoder(TypeNetFlow9)
+	header := func() *packet {
+		// version, count, uptime, secs, sequence, source ID
+		return (&packet{}).put(uint16(9), uint16(1), uint32(100000), uint32(1640000000), uint32(1), uint32(42))
+	}
+
+	// the data received before its template is dropped
+	flows, err := decoder.decode(header().set(256, data).Bytes(), exporterIP, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, flows)
+
+	flows, err = decoder.decode(header().set(netflow9TemplateSetID, template.Bytes()).set(256, data).Bytes(), exporterIP, time.Now())
+	require.NoError(t, err)
+	require.Len(t, flows, 1)
+	assert.Equal(t, &Flow{
+		FlowType:       TypeNetFlow9,
+		ExporterAddr:   exporterIP,
+		SamplingRate:   1,
+		Direction:      directionIngress,
+		StartTimestamp: 1639999940,
+		EndTimestamp:   1640000000,
+		Bytes:          1500,
+		EtherType:      etherTypeIPv4,
+		IPProtocol:     ipProtocolUDP,
+		SrcAddr:        net.IP{10, 0, 0, 1},
+		DstAddr:        net.IP{10, 0, 0, 2},
+		SrcPort:        53,
+		DstPort:        5353,
+	}, flows[0])
+
+	// the templates are scoped by exporter
+	flows, err = decoder.decode(header().set(256, data).Bytes(), net.IP{127, 0, 0, 2}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, flows)
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	template := (&packet{}).put(uint16(300), uint16(5),
+		uint16(fieldIPv6SrcAddr), uint16(16),
+		uint16(fieldIPv6DstAddr), uint16(16),
+		uint16(fieldInPackets), uint16(8),
+		uint16(fieldFlowStartMillis), uint16(8),
+		// variable-length enterprise field
+		uint16(ipfixEnterpriseBit|1), uint16(ipfixVariableLength), uint32(12345),
+	)
+	srcIP := net.ParseIP("2001:db8::1")
+	dstIP := net.ParseIP("2001:db8::2")
+	data := (&packet{}).put([]byte(srcIP), []byte(dstIP), uint64(7), uint64(1639999950123), uint8(3), []byte("foo"))
+
+	sets := (&packet{}).set(ipfixTemplateSetID, template.Bytes()).set(300, data.Bytes())
+	// version, length, export time, sequence, observation domain
+	p := (&packet{}).put(uint16(10), uint16(ipfixHeaderSize+sets.Len()), uint32(1640000000), uint32(1), uint32(0), sets.Bytes())
+
+	decoder := newTemplateDecoder(TypeIPFIX)
+	flows, err := decoder.decode(p.Bytes(), exporterIP, time.Now())
+	require.NoError(t, err)
+	require.Len(t, flows, 1)
+	assert.Equal(t, &Flow{
+		FlowType:       TypeIPFIX,
+		ExporterAddr:   exporterIP,
+		SamplingRate:   1,
+		Direction:      directionIngress,
+		StartTimestamp: 1639999950,
+		EndTimestamp:   1640000000,
+		Packets:        7,
+		EtherType:      etherTypeIPv6,
+		SrcAddr:        srcIP,
+		DstAddr:        dstIP,
+	}, flows[0])
+
+	// template withdrawal
+	withdrawal := (&packet{}).set(ipfixTemplateSetID, (&packet{}).put(uint16(300), uint16(0)).Bytes())
+	p = (&packet{}).put(uint16(10), uint16(ipfixHeaderSize+withdrawal.Len()), uint32(1640000000), uint32(2), uint32(0), withdrawal.Bytes())
+	_, err = decoder.decode(p.Bytes(), exporterIP, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, decoder.templates)
+}
+
+func TestTemplateEviction(t *testing.T) {
+	decoder := newTemplateDecoder(TypeIPFIX)
+	decoder.maxTemplates = 2
+	key := func(domainID uint32) templateKey {
+		return templateKey{exporter: string(exporterIP.To16()), domainID: domainID, templateID: 256}
+	}
+	fields := []templateField{{fieldType: fieldInBytes, length: 4}}
+	evicted := netflowEvictedTemplates.Value()
+
+	decoder.setTemplate(key(1), fields)
+	decoder.setTemplate(key(2), fields)
+	// the template of the domain 1 becomes the most recently used
+	_, ok := decoder.getTemplate(key(1))
+	require.True(t, ok)
+
+	decoder.setTemplate(key(3), fields)
+	assert.Len(t, decoder.templates, 2)
+	assert.Equal(t, 2, decoder.lru.Len())
+	assert.Equal(t, evicted+1, netflowEvictedTemplates.Value())
+	_, ok = decoder.getTemplate(key(2))
+	assert.False(t, ok)
+	_, ok = decoder.getTemplate(key(1))
+	assert.True(t, ok)
+	_, ok = decoder.getTemplate(key(3))
+	assert.True(t, ok)
+
+	// updating a template doesn't evict any other
+	decoder.setTemplate(key(1), fields)
+	assert.Len(t, decoder.templates, 2)
+	assert.Equal(t, evicted+1, netflowEvictedTemplates.Value())
+}
+
+func TestDecodeTemplateErrors(t *testing.T) {
+	_, err := newTemplateDecoder(TypeIPFIX).decode((&packet{}).put(uint16(9), make([]byte, 14)).Bytes(), exporterIP, time.Now())
+	assert.EqualError(t, err, "unexpected IPFIX version 9")
+
+	// the length of the header is larger than the packet
+	_, err = newTemplateDecoder(TypeIPFIX).decode((&packet{}).put(uint16(10), uint16(100), make([]byte, 12)).Bytes(), exporterIP, time.Now())
+	assert.Equal(t, errTruncatedPacket, err)
+
+	header := (&packet{}).put(uint16(9), uint16(1), make([]byte, 16))
+	_, err = newTemplateDecoder(TypeNetFlow9).decode(header.put(uint16(256), uint16(2)).Bytes(), exporterIP, time.Now())
+	assert.EqualError(t, err, "invalid set length 2")
+
+	header = (&packet{}).put(uint16(9), uint16(1), make([]byte, 16))
+	_, err = newTemplateDecoder(TypeNetFlow9).decode(header.put(uint16(256), uint16(100)).Bytes(), exporterIP, time.Now())
+	assert.Equal(t, errTruncatedPacket, err)
+}
+
+func TestDecodeSFlow5(t *testing.T) {
+	// ethernet, IPv4 and TCP headers of a sampled packet
+	ethernet := (&packet{}).put(make([]byte, 12), uint16(etherTypeVLAN), uint16(10), uint16(etherTypeIPv4))
+	ethernet.put(uint8(0x45), uint8(0x10), make([]byte, 7), uint8(ipProtocolTCP), uint16(0), []byte{10, 0, 0, 1}, []byte{10, 0, 0, 2})
+	ethernet.put(uint16(443), uint16(3000), make([]byte, 9), uint8(0x18))
+
+	// header protocol, frame length, stripped, header
+	record := (&packet{}).put(uint32(sflowHeaderProtocolEth), uint32(1514), uint32(4), uint32(ethernet.Len()), ethernet.Bytes())
+	// sequence, source ID, sampling rate, pool, drops, input, output, records
+	sample := (&packet{}).put(uint32(1), uint32(3), uint32(512), uint32(0), uint32(0), uint32(3), uint32(4), uint32(2))
+	sample.put(uint32(1001), uint32(4), uint32(0)) // extended switch data, ignored
+	sample.put(uint32(sflowRawPacketHeader), uint32(record.Len()), record.Bytes())
+
+	// version, agent address, sub agent, sequence, uptime, samples
+	p := (&packet{}).put(uint32(5), uint32(sflowAddressIPv4), []byte{192, 168, 0, 1}, uint32(0), uint32(1), uint32(1000), uint32(2))
+	p.put(uint32(2), uint32(4), uint32(0)) // counter sample, ignored
+	p.put(uint32(sflowFlowSample), uint32(sample.Len()), sample.Bytes())
+
+	receivedAt := time.Unix(1640000000, 0)
+	flows, err := (&sflow5Decoder{}).decode(p.Bytes(), exporterIP, receivedAt)
+	require.NoError(t, err)
+	require.Len(t, flows, 1)
+	assert.Equal(t, &Flow{
+		FlowType:        TypeSFlow5,
+		ExporterAddr:    exporterIP,
+		SamplingRate:    512,
+		Direction:       directionIngress,
+		StartTimestamp:  1640000000,
+		EndTimestamp:    1640000000,
+		Bytes:           1514,
+		Packets:         1,
+		EtherType:       etherTypeIPv4,
+		IPProtocol:      ipProtocolTCP,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         443,
+		DstPort:         3000,
+		Tos:             0x10,
+		TCPFlags:        0x18,
+		InputInterface:  3,
+		OutputInterface: 4,
+	}, flows[0])
+}
+
+func TestDecodeSFlow5Errors(t *testing.T) {
+	decoder := &sflow5Decoder{}
+
+	_, err := decoder.decode((&packet{}).put(uint32(4)).Bytes(), exporterIP, time.Now())
+	assert.EqualError(t, err, "unexpected sFlow version 4")
+
+	_, err = decoder.decode((&packet{}).put(uint32(5), uint32(3)).Bytes(), exporterIP, time.Now())
+	assert.EqualError(t, err, "unexpected sFlow agent address type 3")
+
+	// a sample larger than the datagram
+	p := (&packet{}).put(uint32(5), uint32(sflowAddressIPv4), make([]byte, 16), uint32(1), uint32(sflowFlowSample), uint32(1000))
+	_, err = decoder.decode(p.Bytes(), exporterIP, time.Now())
+	assert.Equal(t, errTruncatedPacket, err)
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"net"
+)
+
+// FlowType is the protocol used by the devices to export the flows.
+type FlowType string
+
+// Flow types
+const (
+	TypeNetFlow5 FlowType = "netflow5"
+	TypeNetFlow9 FlowType = "netflow9"
+	TypeIPFIX    FlowType = "ipfix"
+	TypeSFlow5   FlowType = "sflow5"
+)
+
+var defaultPorts = map[FlowType]uint16{
+	TypeNetFlow5: defaultNetFlowPort,
+	TypeNetFlow9: defaultNetFlowPort,
+	TypeIPFIX:    defaultIPFIXPort,
+	TypeSFlow5:   defaultSFlowPort,
+}
+
+// Flow directions
+const (
+	directionIngress = 0
+	directionEgress  = 1
+)
+
+// Ethernet types
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+)
+
+// IP protocols
+const (
+	ipProtocolTCP = 6
+	ipProtocolUDP = 17
+)
+
+// Flow contains the data of a flow exported by a device, or the data of
+// several flows once aggregated.
+type Flow struct {
+	FlowType     FlowType
+	ExporterAddr net.IP
+	// SamplingRate is the number of packets the flow has been sampled from,
+	// the bytes and packets counts are not scaled by the rate.
+	SamplingRate uint64
+	Direction    uint32
+
+	// StartTimestamp and EndTimestamp are in seconds since the epoch.
+	StartTimestamp uint64
+	EndTimestamp   uint64
+	Bytes          uint64
+	Packets        uint64
+
+	EtherType  uint32
+	IPProtocol uint32
+	SrcAddr    net.IP
+	DstAddr    net.IP
+	SrcPort    uint16
+	DstPort    uint16
+	Tos        uint8
+	TCPFlags   uint8
+
+	InputInterface  uint32
+	OutputInterface uint32
+}
+
+// flowKey is the key used to aggregate the flows: the 5-tuple of the flows,
+// their exporter and their interfaces.
+type flowKey struct {
+	flowType        FlowType
+	exporterAddr    string
+	direction       uint32
+	srcAddr         string
+	dstAddr         string
+	srcPort         uint16
+	dstPort         uint16
+	ipProtocol      uint32
+	tos             uint8
+	inputInterface  uint32
+	outputInterface uint32
+}
+
+func (f *Flow) key() flowKey {
+	return flowKey{
+		flowType:        f.FlowType,
+		exporterAddr:    string(f.ExporterAddr.To16()),
+		direction:       f.Direction,
+		srcAddr:         string(f.SrcAddr.To16()),
+		dstAddr:         string(f.DstAddr.To16()),
+		srcPort:         f.SrcPort,
+		dstPort:         f.DstPort,
+		ipProtocol:      f.IPProtocol,
+		tos:             f.Tos,
+		inputInterface:  f.InputInterface,
+		outputInterface: f.OutputInterface,
+	}
+}
+
+// merge adds the counters of other to the flow, which must have the same key.
+func (f *Flow) merge(other *Flow) {
+	f.Bytes += other.Bytes
+	f.Packets += other.Packets
+	if other.StartTimestamp < f.StartTimestamp {
+		f.StartTimestamp = other.StartTimestamp
+	}
+	if other.EndTimestamp > f.EndTimestamp {
+		f.EndTimestamp = other.EndTimestamp
+	}
+	f.TCPFlags |= other.TCPFlags
+	f.SamplingRate = other.SamplingRate
+}
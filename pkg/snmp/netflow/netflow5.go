@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const netflow5RecordSize = 48
+
+// netflow5Decoder decodes NetFlow v5 packets, made of a header followed by
+// fixed-size flow records.
+type netflow5Decoder struct{}
+
+func (d *netflow5Decoder) decode(payload []byte, exporter net.IP, _ time.Time) ([]*Flow, error) {
+	r := &reader{buf: payload}
+
+	version := r.uint16()
+	if r.err == nil && version != 5 {
+		return nil, fmt.Errorf("unexpected NetFlow version %d", version)
+	}
+	count := int(r.uint16())
+	sysUptime := r.uint32()
+	unixSecs := r.uint32()
+	unixNsecs := r.uint32()
+	r.skip(4) // flow sequence
+	r.skip(2) // engine type and id
+	samplingInterval := r.uint16() & 0x3fff
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.buf) < count*netflow5RecordSize {
+		return nil, errTruncatedPacket
+	}
+
+	samplingRate := uint64(samplingInterval)
+	if samplingRate == 0 {
+		samplingRate = 1
+	}
+	exportTimeMs := int64(unixSecs)*1000 + int64(unixNsecs)/int64(time.Millisecond)
+
+	flows := make([]*Flow, 0, count)
+	for i := 0; i < count; i++ {
+		flow := &Flow{
+			FlowType:     TypeNetFlow5,
+			ExporterAddr: exporter,
+			SamplingRate: samplingRate,
+			Direction:    directionIngress,
+			EtherType:    etherTypeIPv4,
+		}
+		flow.SrcAddr = r.ip(4)
+		flow.DstAddr = r.ip(4)
+		r.skip(4) // next hop
+		flow.InputInterface = uint32(r.uint16())
+		flow.OutputInterface = uint32(r.uint16())
+		flow.Packets = uint64(r.uint32())
+		flow.Bytes = uint64(r.uint32())
+		flow.StartTimestamp = uptimeToTimestamp(exportTimeMs, sysUptime, r.uint32())
+		flow.EndTimestamp = uptimeToTimestamp(exportTimeMs, sysUptime, r.uint32())
+		flow.SrcPort = r.uint16()
+		flow.DstPort = r.uint16()
+		r.skip(1) // padding
+		flow.TCPFlags = r.uint8()
+		flow.IPProtocol = uint32(r.uint8())
+		flow.Tos = r.uint8()
+		r.skip(8) // AS numbers, masks and padding
+		flows = append(flows, flow)
+	}
+	return flows, r.err
+}
@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	ipfixHeaderSize = 16
+
+	netflow9TemplateSetID = 0
+	ipfixTemplateSetID    = 2
+	// the sets with a lower ID contain templates or options templates
+	minDataSetID = 256
+
+	// ipfixVariableLength is the length of the variable-length IPFIX fields.
+	ipfixVariableLength = 65535
+	// ipfixEnterpriseBit is set in the type of the enterprise-specific
+	// fields, which are followed by the enterprise number.
+	ipfixEnterpriseBit       = 0x8000
+	ipfixEnterpriseNumberLen = 4
+)
+
+// Field types shared by NetFlow v9 and IPFIX (IANA IPFIX Information Elements).
+const (
+	fieldInBytes          = 1
+	fieldInPackets        = 2
+	fieldProtocol         = 4
+	fieldTos              = 5
+	fieldTCPFlags         = 6
+	fieldL4SrcPort        = 7
+	fieldIPv4SrcAddr      = 8
+	fieldInputSnmp        = 10
+	fieldL4DstPort        = 11
+	fieldIPv4DstAddr      = 12
+	fieldOutputSnmp       = 14
+	fieldLastSwitched     = 21
+	fieldFirstSwitched    = 22
+	fieldIPv6SrcAddr      = 27
+	fieldIPv6DstAddr      = 28
+	fieldSamplingInterval = 34
+	fieldDirection        = 61
+	fieldFlowStartSeconds = 150
+	fieldFlowEndSeconds   = 151
+	fieldFlowStartMillis  = 152
+	fieldFlowEndMillis    = 153
+	fieldEthernetType     = 256
+)
+
+// templateField is a field of a template, enterprise-specific fields are kept
+// to compute the size of the records but are not decoded.
+type templateField struct {
+	fieldType  uint16
+	length     uint16
+	enterprise bool
+}
+
+// templateKey identifies a template: the template IDs are scoped by exporter
+// and observation domain (or source ID).
+type templateKey struct {
+	exporter   string
+	domainID   uint32
+	templateID uint16
+}
+
+type template struct {
+	key    templateKey
+	fields []templateField
+}
+
+// templateDecoder decodes NetFlow v9 and IPFIX packets, whose data records
+// are described by templates sent beforehand by the exporters.
+//
+// The templates are keyed by values sent over UDP by unauthenticated
+// exporters, at most maxTemplates are kept and the least recently used ones
+// are evicted.
+type templateDecoder struct {
+	flowType     FlowType
+	templates    map[templateKey]*list.Element
+	lru          *list.List // of *template, most recently used first
+	maxTemplates int
+}
+
+func newTemplateDecoder(flowType FlowType) *templateDecoder {
+	return &templateDecoder{
+		flowType:     flowType,
+		templates:    make(map[templateKey]*list.Element),
+		lru:          list.New(),
+		maxTemplates: maxTemplates,
+	}
+}
+
+func (d *templateDecoder) getTemplate(key templateKey) ([]templateField, bool) {
+	elem, ok := d.templates[key]
+	if !ok {
+		return nil, false
+	}
+	d.lru.MoveToFront(elem)
+	return elem.Value.(*template).fields, true
+}
+
+func (d *templateDecoder) setTemplate(key templateKey, fields []templateField) {
+	if elem, ok := d.templates[key]; ok {
+		elem.Value.(*template).fields = fields
+		d.lru.MoveToFront(elem)
+		return
+	}
+	for len(d.templates) >= d.maxTemplates {
+		d.deleteTemplate(d.lru.Back().Value.(*template).key)
+		netflowEvictedTemplates.Add(1)
+	}
+	d.templates[key] = d.lru.PushFront(&template{key: key, fields: fields})
+}
+
+func (d *templateDecoder) deleteTemplate(key templateKey) {
+	if elem, ok := d.templates[key]; ok {
+		d.lru.Remove(elem)
+		delete(d.templates, key)
+	}
+}
+
+// packetContext holds the header values needed to decode the records.
+type packetContext struct {
+	exporter     net.IP
+	exportTimeMs int64
+	sysUptimeMs  uint32
+	domainID     uint32
+}
+
+func (d *templateDecoder) decode(payload []byte, exporter net.IP, _ time.Time) ([]*Flow, error) {
+	r := &reader{buf: payload}
+	ctx := packetContext{exporter: exporter}
+
+	version := r.uint16()
+	if d.flowType == TypeIPFIX {
+		if r.err == nil && version != 10 {
+			return nil, fmt.Errorf("unexpected IPFIX version %d", version)
+		}
+		length := int(r.uint16())
+		ctx.exportTimeMs = int64(r.uint32()) * 1000
+		r.skip(4) // sequence number
+		ctx.domainID = r.uint32()
+		if r.err == nil {
+			if length < ipfixHeaderSize || length > len(payload) {
+				return nil, errTruncatedPacket
+			}
+			r.buf = payload[ipfixHeaderSize:length]
+		}
+	} else {
+		if r.err == nil && version != 9 {
+			return nil, fmt.Errorf("unexpected NetFlow version %d", version)
+		}
+		r.skip(2) // count
+		ctx.sysUptimeMs = r.uint32()
+		ctx.exportTimeMs = int64(r.uint32()) * 1000
+		r.skip(4) // sequence number
+		ctx.domainID = r.uint32()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var flows []*Flow
+	for len(r.buf) >= 4 {
+		setID := r.uint16()
+		setLength := int(r.uint16())
+		if setLength < 4 {
+			return flows, fmt.Errorf("invalid set length %d", setLength)
+		}
+		set := &reader{buf: r.bytes(setLength - 4)}
+		if r.err != nil {
+			return flows, r.err
+		}
+
+		switch {
+		case setID == netflow9TemplateSetID && d.flowType == TypeNetFlow9,
+			setID == ipfixTemplateSetID && d.flowType == TypeIPFIX:
+			if err := d.decodeTemplateSet(set, ctx); err != nil {
+				return flows, err
+			}
+		case setID >= minDataSetID:
+			fields, ok := d.getTemplate(templateKey{exporter: string(exporter.To16()), domainID: ctx.domainID, templateID: setID})
+			if !ok {
+				netflowMissingTemplates.Add(1)
+				continue
+			}
+			decoded, err := d.decodeDataSet(set, fields, ctx)
+			flows = append(flows, decoded...)
+			if err != nil {
+				return flows, err
+			}
+		default:
+			// options templates and reserved sets
+		}
+	}
+	return flows, nil
+}
+
+func (d *templateDecoder) decodeTemplateSet(set *reader, ctx packetContext) error {
+	// the set may be padded
+	for len(set.buf) >= 4 {
+		templateID := set.uint16()
+		fieldCount := int(set.uint16())
+		key := templateKey{exporter: string(ctx.exporter.To16()), domainID: ctx.domainID, templateID: templateID}
+		if fieldCount == 0 {
+			// IPFIX template withdrawal
+			d.deleteTemplate(key)
+			continue
+		}
+
+		fields := make([]templateField, 0, fieldCount)
+		for i := 0; i < fieldCount; i++ {
+			field := templateField{
+				fieldType: set.uint16(),
+				length:    set.uint16(),
+			}
+			if d.flowType == TypeIPFIX && field.fieldType&ipfixEnterpriseBit != 0 {
+				field.enterprise = true
+				set.skip(ipfixEnterpriseNumberLen)
+			}
+			fields = append(fields, field)
+		}
+		if set.err != nil {
+			return set.err
+		}
+		d.setTemplate(key, fields)
+	}
+	return nil
+}
+
+func (d *templateDecoder) decodeDataSet(set *reader, fields []templateField, ctx packetContext) ([]*Flow, error) {
+	minRecordSize := 0
+	for _, field := range fields {
+		if field.length == ipfixVariableLength {
+			minRecordSize++
+		} else {
+			minRecordSize += int(field.length)
+		}
+	}
+	if minRecordSize == 0 {
+		return nil, nil
+	}
+
+	var flows []*Flow
+	// the set may be padded
+	for len(set.buf) >= minRecordSize {
+		flow := &Flow{
+			FlowType:     d.flowType,
+			ExporterAddr: ctx.exporter,
+			SamplingRate: 1,
+			Direction:    directionIngress,
+		}
+		var startUptime, endUptime uint32
+		var hasStartUptime, hasEndUptime bool
+
+		for _, field := range fields {
+			length := int(field.length)
+			if field.length == ipfixVariableLength {
+				length = int(set.uint8())
+				if length == 255 {
+					length = int(set.uint16())
+				}
+			}
+			value := set.bytes(length)
+			if set.err != nil {
+				return flows, set.err
+			}
+			if field.enterprise {
+				continue
+			}
+
+			switch field.fieldType {
+			case fieldInBytes:
+				flow.Bytes = decodeUint(value)
+			case fieldInPackets:
+				flow.Packets = decodeUint(value)
+			case fieldProtocol:
+				flow.IPProtocol = uint32(decodeUint(value))
+			case fieldTos:
+				flow.Tos = uint8(decodeUint(value))
+			case fieldTCPFlags:
+				flow.TCPFlags = uint8(decodeUint(value))
+			case fieldL4SrcPort:
+				flow.SrcPort = uint16(decodeUint(value))
+			case fieldL4DstPort:
+				flow.DstPort = uint16(decodeUint(value))
+			case fieldIPv4SrcAddr, fieldIPv6SrcAddr:
+				flow.SrcAddr = append(net.IP(nil), value...)
+			case fieldIPv4DstAddr, fieldIPv6DstAddr:
+				flow.DstAddr = append(net.IP(nil), value...)
+			case fieldInputSnmp:
+				flow.InputInterface = uint32(decodeUint(value))
+			case fieldOutputSnmp:
+				flow.OutputInterface = uint32(decodeUint(value))
+			case fieldFirstSwitched:
+				startUptime, hasStartUptime = uint32(decodeUint(value)), true
+			case fieldLastSwitched:
+				endUptime, hasEndUptime = uint32(decodeUint(value)), true
+			case fieldFlowStartSeconds:
+				flow.StartTimestamp = decodeUint(value)
+			case fieldFlowEndSeconds:
+				flow.EndTimestamp = decodeUint(value)
+			case fieldFlowStartMillis:
+				flow.StartTimestamp = decodeUint(value) / 1000
+			case fieldFlowEndMillis:
+				flow.EndTimestamp = decodeUint(value) / 1000
+			case fieldSamplingInterval:
+				if rate := decodeUint(value); rate > 0 {
+					flow.SamplingRate = rate
+				}
+			case fieldDirection:
+				flow.Direction = uint32(decodeUint(value))
+			case fieldEthernetType:
+				flow.EtherType = uint32(decodeUint(value))
+			}
+		}
+
+		// the IPFIX header does not have the uptime of the exporter
+		if hasStartUptime && d.flowType == TypeNetFlow9 {
+			flow.StartTimestamp = uptimeToTimestamp(ctx.exportTimeMs, ctx.sysUptimeMs, startUptime)
+		}
+		if hasEndUptime && d.flowType == TypeNetFlow9 {
+			flow.EndTimestamp = uptimeToTimestamp(ctx.exportTimeMs, ctx.sysUptimeMs, endUptime)
+		}
+		if flow.StartTimestamp == 0 {
+			flow.StartTimestamp = uint64(ctx.exportTimeMs / 1000)
+		}
+		if flow.EndTimestamp == 0 {
+			flow.EndTimestamp = uint64(ctx.exportTimeMs / 1000)
+		}
+		if flow.EtherType == 0 {
+			if len(flow.SrcAddr) == net.IPv6len {
+				flow.EtherType = etherTypeIPv6
+			} else {
+				flow.EtherType = etherTypeIPv4
+			}
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
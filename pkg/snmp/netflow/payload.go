@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+)
+
+// FlowPayload is the payload of an aggregated flow sent to the event platform.
+type FlowPayload struct {
+	FlushTimestamp int64       `json:"flush_timestamp"`
+	FlowType       FlowType    `json:"type"`
+	SamplingRate   uint64      `json:"sampling_rate"`
+	Direction      string      `json:"direction"`
+	Start          uint64      `json:"start"` // in seconds
+	End            uint64      `json:"end"`   // in seconds
+	Bytes          uint64      `json:"bytes"`
+	Packets        uint64      `json:"packets"`
+	EtherType      string      `json:"ether_type,omitempty"`
+	IPProtocol     string      `json:"ip_protocol"`
+	Device         Device      `json:"device"`
+	Exporter       Exporter    `json:"exporter"`
+	Source         Endpoint    `json:"source"`
+	Destination    Endpoint    `json:"destination"`
+	Ingress        Observation `json:"ingress"`
+	Egress         Observation `json:"egress"`
+	TCPFlags       []string    `json:"tcp_flags,omitempty"`
+}
+
+// Device identifies the device exporting the flows, with the same ID as the
+// one of the network devices metadata sent by the SNMP check.
+type Device struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace"`
+}
+
+// Exporter contains the exporter of the flows.
+type Exporter struct {
+	IP string `json:"ip"`
+}
+
+// Endpoint contains the source or destination of a flow.
+type Endpoint struct {
+	IP   string `json:"ip"`
+	Port uint16 `json:"port"`
+}
+
+// Observation contains the interface the flow has been observed on.
+type Observation struct {
+	Interface Interface `json:"interface"`
+}
+
+// Interface identifies an interface, with the same ID, device ID and index as the
+// ones of the network interfaces metadata sent by the SNMP check.
+type Interface struct {
+	ID       string `json:"id"`
+	DeviceID string `json:"device_id"`
+	Index    uint32 `json:"index"`
+}
+
+var ipProtocolNames = map[uint32]string{
+	1:             "ICMP",
+	2:             "IGMP",
+	ipProtocolTCP: "TCP",
+	ipProtocolUDP: "UDP",
+	47:            "GRE",
+	50:            "ESP",
+	51:            "AH",
+	58:            "IPv6-ICMP",
+	132:           "SCTP",
+}
+
+var tcpFlagNames = []string{"FIN", "SYN", "RST", "PSH", "ACK", "URG", "ECE", "CWR"}
+
+// buildInterface returns the interface of the given device with the given ifIndex.
+func buildInterface(deviceID string, index uint32) Interface {
+	return Interface{
+		ID:       common.BuildInterfaceID(deviceID, int32(index)),
+		DeviceID: deviceID,
+		Index:    index,
+	}
+}
+
+// buildPayload builds the payload of an aggregated flow.
+func buildPayload(flow *Flow, namespace string, flushTimestamp int64) FlowPayload {
+	exporterIP := flow.ExporterAddr.String()
+	deviceID := common.BuildDeviceID(namespace, exporterIP)
+
+	payload := FlowPayload{
+		FlushTimestamp: flushTimestamp,
+		FlowType:       flow.FlowType,
+		SamplingRate:   flow.SamplingRate,
+		Direction:      "ingress",
+		Start:          flow.StartTimestamp,
+		End:            flow.EndTimestamp,
+		Bytes:          flow.Bytes,
+		Packets:        flow.Packets,
+		IPProtocol:     strconv.FormatUint(uint64(flow.IPProtocol), 10),
+		Device: Device{
+			ID:        deviceID,
+			Namespace: namespace,
+		},
+		Exporter: Exporter{
+			IP: exporterIP,
+		},
+		Source: Endpoint{
+			IP:   flow.SrcAddr.String(),
+			Port: flow.SrcPort,
+		},
+		Destination: Endpoint{
+			IP:   flow.DstAddr.String(),
+			Port: flow.DstPort,
+		},
+		Ingress: Observation{
+			Interface: buildInterface(deviceID, flow.InputInterface),
+		},
+		Egress: Observation{
+			Interface: buildInterface(deviceID, flow.OutputInterface),
+		},
+	}
+	if flow.Direction == directionEgress {
+		payload.Direction = "egress"
+	}
+	switch flow.EtherType {
+	case etherTypeIPv4:
+		payload.EtherType = "IPv4"
+	case etherTypeIPv6:
+		payload.EtherType = "IPv6"
+	}
+	if name, ok := ipProtocolNames[flow.IPProtocol]; ok {
+		payload.IPProtocol = name
+	}
+	if flow.IPProtocol == ipProtocolTCP {
+		for i, name := range tcpFlagNames {
+			if flow.TCPFlags&(1<<i) != 0 {
+				payload.TCPFlags = append(payload.TCPFlags, name)
+			}
+		}
+	}
+	return payload
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Server manages the flow listeners and the aggregation of their flows.
+type Server struct {
+	config     *Config
+	listeners  []*flowListener
+	aggregator *FlowAggregator
+}
+
+// flowListener receives the packets of one flow type on an UDP socket.
+type flowListener struct {
+	config  ListenerConfig
+	conn    *net.UDPConn
+	decoder packetDecoder
+	flowOut chan *Flow
+	done    chan struct{}
+}
+
+var (
+	serverInstance *Server
+	startError     error
+)
+
+// StartServer starts the global flow server.
+func StartServer(sender aggregator.Sender) error {
+	server, err := NewNetflowServer(sender)
+	serverInstance = server
+	startError = err
+	return err
+}
+
+// StopServer stops the global flow server, if it is running.
+func StopServer() {
+	if serverInstance != nil {
+		serverInstance.Stop()
+		serverInstance = nil
+		startError = nil
+	}
+}
+
+// IsRunning returns whether the flow server is currently running.
+func IsRunning() bool {
+	return serverInstance != nil
+}
+
+// NewNetflowServer configures and returns a running flow server, the
+// aggregated flows are sent to the event platform with the given sender.
+func NewNetflowServer(sender aggregator.Sender) (*Server, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	flowAgg := NewFlowAggregator(sender, config)
+	flowAgg.Start()
+
+	server := &Server{
+		config:     config,
+		aggregator: flowAgg,
+	}
+	for _, listenerConfig := range config.Listeners {
+		listener, err := startFlowListener(listenerConfig, flowAgg.GetFlowInChan())
+		if err != nil {
+			server.Stop()
+			return nil, err
+		}
+		server.listeners = append(server.listeners, listener)
+	}
+
+	return server, nil
+}
+
+func startFlowListener(c ListenerConfig, flowOut chan *Flow) (*flowListener, error) {
+	decoder, err := newPacketDecoder(c.FlowType)
+	if err != nil {
+		return nil, err
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	listener := &flowListener{
+		config:  c,
+		conn:    conn,
+		decoder: decoder,
+		flowOut: flowOut,
+		done:    make(chan struct{}),
+	}
+	log.Infof("Start listening for %s flows on %s", c.FlowType, c.Addr())
+	go listener.run()
+	return listener, nil
+}
+
+func (l *flowListener) run() {
+	defer close(l.done)
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Warnf("Error reading %s flows on %s: %s", l.config.FlowType, l.config.Addr(), err)
+			continue
+		}
+		netflowPackets.Add(1)
+
+		flows, err := l.decoder.decode(buf[:n], addr.IP, time.Now())
+		if err != nil {
+			log.Debugf("Error decoding %s packet from %s: %s", l.config.FlowType, addr, err)
+			netflowDecodingErrors.Add(1)
+		}
+		// the flows are dropped rather than blocking the listener when the aggregator is late
+		for _, flow := range flows {
+			select {
+			case l.flowOut <- flow:
+			default:
+				netflowFlowsDropped.Add(1)
+			}
+		}
+	}
+}
+
+// Stop stops the flow listeners and flushes the aggregated flows.
+func (s *Server) Stop() {
+	stopped := make(chan interface{})
+
+	go func() {
+		for _, listener := range s.listeners {
+			log.Infof("Stop listening on %s", listener.config.Addr())
+			listener.conn.Close()
+			<-listener.done
+		}
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Duration(s.config.StopTimeout) * time.Second):
+		log.Errorf("Stopping server. Timeout after %d seconds", s.config.StopTimeout)
+	}
+
+	s.aggregator.Stop()
+}
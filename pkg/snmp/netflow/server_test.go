@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/epforwarder"
+)
+
+func getFreePort() uint16 {
+	for i := 0; i < 5; i++ {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	}
+	panic("unable to find free port for starting the flow listener")
+}
+
+func TestServerNetFlow5(t *testing.T) {
+	port := getFreePort()
+	configure(t, Config{
+		Enabled:   true,
+		Listeners: []ListenerConfig{{FlowType: TypeNetFlow5, BindHost: "127.0.0.1", Port: port}},
+	})
+	sender := mocksender.NewMockSender("")
+	sender.SetupAcceptAll()
+
+	err := StartServer(sender)
+	require.NoError(t, err)
+	assert.True(t, IsRunning())
+
+	packets := netflowPackets.Value()
+	p := (&packet{}).put(uint16(5), uint16(1), uint32(100000), uint32(1640000000), uint32(0), uint32(1), uint16(0), uint16(0))
+	p.put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, make([]byte, 8), uint32(1), uint32(100), uint32(90000), uint32(100000))
+	p.put(uint16(2000), uint16(53), uint8(0), uint8(0), uint8(ipProtocolUDP), uint8(0), make([]byte, 8))
+
+	conn, err := net.Dial("udp", (&ListenerConfig{BindHost: "127.0.0.1", Port: port}).Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(p.Bytes())
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return netflowPackets.Value() > packets }, 5*time.Second, 10*time.Millisecond)
+
+	StopServer()
+	assert.False(t, IsRunning())
+	sender.AssertCalled(t, "EventPlatformEvent", mock.Anything, epforwarder.EventTypeNetworkDevicesNetFlow)
+}
+
+func TestFlowListenerDropsFlows(t *testing.T) {
+	port := getFreePort()
+	config := ListenerConfig{FlowType: TypeNetFlow5, BindHost: "127.0.0.1", Port: port}
+
+	// nothing reads the flows
+	listener, err := startFlowListener(config, make(chan *Flow))
+	require.NoError(t, err)
+
+	dropped := netflowFlowsDropped.Value()
+	p := (&packet{}).put(uint16(5), uint16(2), uint32(100000), uint32(1640000000), uint32(0), uint32(1), uint16(0), uint16(0))
+	for i := 0; i < 2; i++ {
+		p.put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, make([]byte, 8), uint32(1), uint32(100), uint32(90000), uint32(100000))
+		p.put(uint16(2000), uint16(53), uint8(0), uint8(0), uint8(ipProtocolUDP), uint8(0), make([]byte, 8))
+	}
+
+	conn, err := net.Dial("udp", config.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(p.Bytes())
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return netflowFlowsDropped.Value() == dropped+2 }, 5*time.Second, 10*time.Millisecond)
+
+	// the listener isn't blocked and stops right away
+	listener.conn.Close()
+	select {
+	case <-listener.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the flow listener didn't stop")
+	}
+}
+
+func TestServerStartError(t *testing.T) {
+	configure(t, Config{Enabled: true})
+	err := StartServer(mocksender.NewMockSender(""))
+	assert.Error(t, err)
+	assert.False(t, IsRunning())
+	assert.Contains(t, GetStatus(), "error")
+	StopServer()
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// sFlow v5 sample and record formats, in the standard enterprise (0).
+const (
+	sflowAddressIPv4 = 1
+	sflowAddressIPv6 = 2
+
+	sflowFlowSample         = 1
+	sflowExpandedFlowSample = 3
+
+	sflowRawPacketHeader   = 1
+	sflowHeaderProtocolEth = 1
+
+	// sflowInterfaceFormatIndex is the format of the interfaces given by their ifIndex
+	sflowInterfaceFormatIndex = 0
+)
+
+// sflow5Decoder decodes sFlow v5 datagrams. Only the flow samples holding
+// raw packet headers are decoded, the counter samples are ignored.
+//
+// Each sample becomes a flow of one packet, with the sampling rate of the sample.
+type sflow5Decoder struct{}
+
+func (d *sflow5Decoder) decode(payload []byte, exporter net.IP, receivedAt time.Time) ([]*Flow, error) {
+	r := &reader{buf: payload}
+
+	version := r.uint32()
+	if r.err == nil && version != 5 {
+		return nil, fmt.Errorf("unexpected sFlow version %d", version)
+	}
+	switch addressType := r.uint32(); addressType {
+	case sflowAddressIPv4:
+		r.skip(net.IPv4len)
+	case sflowAddressIPv6:
+		r.skip(net.IPv6len)
+	default:
+		if r.err == nil {
+			return nil, fmt.Errorf("unexpected sFlow agent address type %d", addressType)
+		}
+	}
+	r.skip(4) // sub agent ID
+	r.skip(4) // sequence number
+	r.skip(4) // uptime
+	sampleCount := int(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	timestamp := uint64(receivedAt.Unix())
+	var flows []*Flow
+	for i := 0; i < sampleCount; i++ {
+		format := r.uint32()
+		sample := &reader{buf: r.bytes(int(r.uint32()))}
+		if r.err != nil {
+			return flows, r.err
+		}
+
+		// the counter samples and the samples of other enterprises, whose
+		// number is in the 20 most significant bits of the format, are ignored
+		if format != sflowFlowSample && format != sflowExpandedFlowSample {
+			continue
+		}
+		flow := &Flow{
+			FlowType:       TypeSFlow5,
+			ExporterAddr:   exporter,
+			Direction:      directionIngress,
+			StartTimestamp: timestamp,
+			EndTimestamp:   timestamp,
+			Packets:        1,
+		}
+		if err := decodeFlowSample(sample, format, flow); err != nil {
+			return flows, err
+		}
+		if flow.EtherType != 0 {
+			flows = append(flows, flow)
+		}
+	}
+	return flows, nil
+}
+
+// decodeFlowSample decodes a flow sample into the flow.
+func decodeFlowSample(sample *reader, format uint32, flow *Flow) error {
+	sample.skip(4) // sequence number
+	if format == sflowExpandedFlowSample {
+		sample.skip(8) // source ID type and index
+	} else {
+		sample.skip(4) // source ID
+	}
+	flow.SamplingRate = uint64(sample.uint32())
+	if flow.SamplingRate == 0 {
+		flow.SamplingRate = 1
+	}
+	sample.skip(4) // sample pool
+	sample.skip(4) // drops
+	if format == sflowExpandedFlowSample {
+		if inputFormat, input := sample.uint32(), sample.uint32(); inputFormat == sflowInterfaceFormatIndex {
+			flow.InputInterface = input
+		}
+		if outputFormat, output := sample.uint32(), sample.uint32(); outputFormat == sflowInterfaceFormatIndex {
+			flow.OutputInterface = output
+		}
+	} else {
+		// the two most significant bits give the format
+		if input := sample.uint32(); input>>30 == sflowInterfaceFormatIndex {
+			flow.InputInterface = input
+		}
+		if output := sample.uint32(); output>>30 == sflowInterfaceFormatIndex {
+			flow.OutputInterface = output
+		}
+	}
+
+	recordCount := int(sample.uint32())
+	for i := 0; i < recordCount; i++ {
+		recordFormat := sample.uint32()
+		record := &reader{buf: sample.bytes(int(sample.uint32()))}
+		if sample.err != nil {
+			return sample.err
+		}
+		if recordFormat != sflowRawPacketHeader {
+			continue
+		}
+
+		headerProtocol := record.uint32()
+		frameLength := record.uint32()
+		record.skip(4) // stripped
+		header := record.bytes(int(record.uint32()))
+		if record.err != nil {
+			return record.err
+		}
+		if headerProtocol != sflowHeaderProtocolEth {
+			continue
+		}
+		flow.Bytes = uint64(frameLength)
+		decodeEthernetHeader(header, flow)
+	}
+	return sample.err
+}
+
+// decodeEthernetHeader decodes the addresses and ports of a sampled packet.
+// The header is usually truncated, the fields which are not in the header are
+// left unset.
+func decodeEthernetHeader(header []byte, flow *Flow) {
+	r := &reader{buf: header}
+	r.skip(12) // destination and source MAC addresses
+	etherType := r.uint16()
+	if etherType == etherTypeVLAN {
+		r.skip(2)
+		etherType = r.uint16()
+	}
+	if r.err != nil {
+		return
+	}
+
+	var transport []byte
+	switch etherType {
+	case etherTypeIPv4:
+		if len(r.buf) < 20 {
+			return
+		}
+		headerLength := int(r.buf[0]&0x0f) * 4
+		flow.Tos = r.buf[1]
+		flow.IPProtocol = uint32(r.buf[9])
+		flow.SrcAddr = append(net.IP(nil), r.buf[12:16]...)
+		flow.DstAddr = append(net.IP(nil), r.buf[16:20]...)
+		if headerLength >= 20 && len(r.buf) >= headerLength {
+			transport = r.buf[headerLength:]
+		}
+	case etherTypeIPv6:
+		if len(r.buf) < 40 {
+			return
+		}
+		flow.Tos = r.buf[0]<<4 | r.buf[1]>>4
+		flow.IPProtocol = uint32(r.buf[6])
+		flow.SrcAddr = append(net.IP(nil), r.buf[8:24]...)
+		flow.DstAddr = append(net.IP(nil), r.buf[24:40]...)
+		transport = r.buf[40:]
+	default:
+		return
+	}
+	flow.EtherType = uint32(etherType)
+
+	if (flow.IPProtocol == ipProtocolTCP || flow.IPProtocol == ipProtocolUDP) && len(transport) >= 4 {
+		flow.SrcPort = uint16(transport[0])<<8 | uint16(transport[1])
+		flow.DstPort = uint16(transport[2])<<8 | uint16(transport[3])
+	}
+	if flow.IPProtocol == ipProtocolTCP && len(transport) >= 14 {
+		flow.TCPFlags = transport[13]
+	}
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/json"
+	"expvar"
+)
+
+var (
+	netflowExpvars          = expvar.NewMap("netflow")
+	netflowPackets          = expvar.Int{}
+	netflowDecodingErrors   = expvar.Int{}
+	netflowMissingTemplates = expvar.Int{}
+	netflowEvictedTemplates = expvar.Int{}
+	netflowFlowsReceived    = expvar.Int{}
+	netflowFlowsFlushed     = expvar.Int{}
+	netflowFlowsDropped     = expvar.Int{}
+)
+
+func init() {
+	netflowExpvars.Set("Packets", &netflowPackets)
+	netflowExpvars.Set("DecodingErrors", &netflowDecodingErrors)
+	netflowExpvars.Set("MissingTemplates", &netflowMissingTemplates)
+	netflowExpvars.Set("EvictedTemplates", &netflowEvictedTemplates)
+	netflowExpvars.Set("FlowsReceived", &netflowFlowsReceived)
+	netflowExpvars.Set("FlowsFlushed", &netflowFlowsFlushed)
+	netflowExpvars.Set("FlowsDropped", &netflowFlowsDropped)
+}
+
+// GetStatus returns key-value data for use in status reporting of the flow server.
+func GetStatus() map[string]interface{} {
+	status := make(map[string]interface{})
+
+	metricsJSON := []byte(expvar.Get("netflow").String())
+	metrics := make(map[string]interface{})
+	json.Unmarshal(metricsJSON, &metrics) //nolint:errcheck
+	status["metrics"] = metrics
+
+	if startError != nil {
+		status["error"] = startError.Error()
+	}
+
+	return status
+}
@@ -6,6 +6,7 @@
 package traps
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash/fnv"
@@ -22,13 +23,15 @@ func IsEnabled() bool {
 }
 
 // UserV3 contains the definition of one SNMPv3 user with its username and its auth
-// parameters.
+// parameters. When EngineID is set, the user only matches the packets sent by
+// the SNMP engine with this ID.
 type UserV3 struct {
 	Username     string `mapstructure:"user" yaml:"user"`
 	AuthKey      string `mapstructure:"authKey" yaml:"authKey"`
 	AuthProtocol string `mapstructure:"authProtocol" yaml:"authProtocol"`
 	PrivKey      string `mapstructure:"privKey" yaml:"privKey"`
 	PrivProtocol string `mapstructure:"privProtocol" yaml:"privProtocol"`
+	EngineID     string `mapstructure:"engineID" yaml:"engineID"`
 }
 
 // Config contains configuration for SNMP trap listeners.
@@ -56,9 +59,8 @@ func ReadConfig(agentHostname string) (*Config, error) {
 		return nil, errors.New("traps listener is disabled")
 	}
 
-	// gosnmp only supports one v3 user at the moment.
-	if len(c.Users) > 1 {
-		return nil, errors.New("only one user is currently supported in SNMP Traps Listener configuration")
+	if err := validateUsers(c.Users); err != nil {
+		return nil, fmt.Errorf("invalid SNMPv3 users: %w", err)
 	}
 
 	// Set defaults.
@@ -102,47 +104,33 @@ func (c *Config) Addr() string {
 	return fmt.Sprintf("%s:%d", c.BindHost, c.Port)
 }
 
-// BuildSNMPParams returns a valid GoSNMP params structure from configuration.
-func (c *Config) BuildSNMPParams() (*gosnmp.GoSNMP, error) {
-	if len(c.Users) == 0 {
-		return &gosnmp.GoSNMP{
-			Port:      c.Port,
-			Transport: "udp",
-			Version:   gosnmp.Version2c, // No user configured, let's use Version2 which is enough and doesn't require setting up fake security data.
-			Logger:    gosnmp.NewLogger(&trapLogger{}),
-		}, nil
-	}
-	user := c.Users[0]
-	var authProtocol gosnmp.SnmpV3AuthProtocol
-	switch lowerAuthProtocol := strings.ToLower(user.AuthProtocol); lowerAuthProtocol {
-	case "":
-		authProtocol = gosnmp.NoAuth
-	case "md5":
-		authProtocol = gosnmp.MD5
-	case "sha":
-		authProtocol = gosnmp.SHA
-	default:
-		return nil, fmt.Errorf("unsupported authentication protocol: %s", user.AuthProtocol)
+// BuildSNMPParams returns the GoSNMP params used to decode the SNMPv1 and SNMPv2c packets.
+func (c *Config) BuildSNMPParams() *gosnmp.GoSNMP {
+	return &gosnmp.GoSNMP{
+		Port:      c.Port,
+		Transport: "udp",
+		Version:   gosnmp.Version2c, // Version2 is enough to decode the v1 and v2c packets and doesn't require setting up security data.
+		Logger:    gosnmp.NewLogger(&trapLogger{}),
 	}
+}
 
-	var privProtocol gosnmp.SnmpV3PrivProtocol
-	switch lowerPrivProtocol := strings.ToLower(user.PrivProtocol); lowerPrivProtocol {
-	case "":
-		privProtocol = gosnmp.NoPriv
-	case "des":
+// BuildV3Params returns the GoSNMP params used to decode the SNMPv3 packets of a user.
+func (c *Config) BuildV3Params(user UserV3) (*gosnmp.GoSNMP, error) {
+	authProtocol, err := parseAuthProtocol(user.AuthProtocol)
+	if err != nil {
+		return nil, err
+	}
+	privProtocol, err := parsePrivProtocol(user.PrivProtocol)
+	if err != nil {
+		return nil, err
+	}
+
+	// As documented, the protocols default to MD5 and DES when their key is set.
+	if authProtocol == gosnmp.NoAuth && user.AuthKey != "" {
+		authProtocol = gosnmp.MD5
+	}
+	if privProtocol == gosnmp.NoPriv && user.PrivKey != "" {
 		privProtocol = gosnmp.DES
-	case "aes":
-		privProtocol = gosnmp.AES
-	case "aes192":
-		privProtocol = gosnmp.AES192
-	case "aes192c":
-		privProtocol = gosnmp.AES192C
-	case "aes256":
-		privProtocol = gosnmp.AES256
-	case "aes256c":
-		privProtocol = gosnmp.AES256C
-	default:
-		return nil, fmt.Errorf("unsupported privacy protocol: %s", user.PrivProtocol)
 	}
 
 	msgFlags := gosnmp.NoAuthNoPriv
@@ -152,15 +140,25 @@ func (c *Config) BuildSNMPParams() (*gosnmp.GoSNMP, error) {
 		msgFlags = gosnmp.AuthNoPriv
 	}
 
+	// The keys are localized with the engine ID of each packet, the
+	// authoritative engine ID is only used until the first packet is received.
+	engineID := c.authoritativeEngineID
+	if user.EngineID != "" {
+		engineID, err = parseEngineID(user.EngineID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &gosnmp.GoSNMP{
 		Port:          c.Port,
 		Transport:     "udp",
-		Version:       gosnmp.Version3, // Always using version3 for traps, only option that works with all SNMP versions simultaneously
+		Version:       gosnmp.Version3,
 		SecurityModel: gosnmp.UserSecurityModel,
 		MsgFlags:      msgFlags,
 		SecurityParameters: &gosnmp.UsmSecurityParameters{
 			UserName:                 user.Username,
-			AuthoritativeEngineID:    c.authoritativeEngineID,
+			AuthoritativeEngineID:    engineID,
 			AuthenticationProtocol:   authProtocol,
 			AuthenticationPassphrase: user.AuthKey,
 			PrivacyProtocol:          privProtocol,
@@ -169,3 +167,85 @@ func (c *Config) BuildSNMPParams() (*gosnmp.GoSNMP, error) {
 		Logger: gosnmp.NewLogger(&trapLogger{}),
 	}, nil
 }
+
+func parseAuthProtocol(authProtocol string) (gosnmp.SnmpV3AuthProtocol, error) {
+	switch strings.ToLower(authProtocol) {
+	case "":
+		return gosnmp.NoAuth, nil
+	case "md5":
+		return gosnmp.MD5, nil
+	case "sha":
+		return gosnmp.SHA, nil
+	case "sha224":
+		return gosnmp.SHA224, nil
+	case "sha256":
+		return gosnmp.SHA256, nil
+	case "sha384":
+		return gosnmp.SHA384, nil
+	case "sha512":
+		return gosnmp.SHA512, nil
+	default:
+		return gosnmp.NoAuth, fmt.Errorf("unsupported authentication protocol: %s", authProtocol)
+	}
+}
+
+func parsePrivProtocol(privProtocol string) (gosnmp.SnmpV3PrivProtocol, error) {
+	switch strings.ToLower(privProtocol) {
+	case "":
+		return gosnmp.NoPriv, nil
+	case "des":
+		return gosnmp.DES, nil
+	case "aes":
+		return gosnmp.AES, nil
+	case "aes192":
+		return gosnmp.AES192, nil
+	case "aes192c":
+		return gosnmp.AES192C, nil
+	case "aes256":
+		return gosnmp.AES256, nil
+	case "aes256c":
+		return gosnmp.AES256C, nil
+	default:
+		return gosnmp.NoPriv, fmt.Errorf("unsupported privacy protocol: %s", privProtocol)
+	}
+}
+
+// parseEngineID decodes an engine ID given as an hexadecimal string,
+// optionally prefixed by 0x.
+func parseEngineID(engineID string) (string, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(engineID), "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid engine ID %q: %w", engineID, err)
+	}
+	// RFC3411 section 5: an SnmpEngineID is between 5 and 32 bytes long.
+	if len(decoded) < 5 || len(decoded) > 32 {
+		return "", fmt.Errorf("invalid engine ID %q: must be between 5 and 32 bytes long", engineID)
+	}
+	return string(decoded), nil
+}
+
+// validateUsers checks that the users can be used to decode the packets: a
+// username can be used several times, but only with different engine IDs.
+func validateUsers(users []UserV3) error {
+	seen := make(map[string]map[string]bool)
+	for _, user := range users {
+		if user.Username == "" {
+			return errors.New("missing username")
+		}
+		engineID := ""
+		if user.EngineID != "" {
+			var err error
+			if engineID, err = parseEngineID(user.EngineID); err != nil {
+				return err
+			}
+		}
+		if seen[user.Username] == nil {
+			seen[user.Username] = make(map[string]bool)
+		}
+		if seen[user.Username][engineID] {
+			return fmt.Errorf("user %q is defined several times for the same engine ID", user.Username)
+		}
+		seen[user.Username][engineID] = true
+	}
+	return nil
+}
@@ -33,6 +33,12 @@ func TestFullConfig(t *testing.T) {
 				PrivKey:      "password",
 				PrivProtocol: "AES",
 			},
+			{
+				Username:     "user",
+				AuthKey:      "password2",
+				AuthProtocol: "SHA256",
+				EngineID:     "0x8000000001020304",
+			},
 		},
 		BindHost:         "127.0.0.1",
 		CommunityStrings: []string{"public"},
@@ -54,9 +60,15 @@ func TestFullConfig(t *testing.T) {
 			PrivKey:      "password",
 			PrivProtocol: "AES",
 		},
+		{
+			Username:     "user",
+			AuthKey:      "password2",
+			AuthProtocol: "SHA256",
+			EngineID:     "0x8000000001020304",
+		},
 	}, config.Users)
 
-	params, err := config.BuildSNMPParams()
+	params, err := config.BuildV3Params(config.Users[0])
 	assert.NoError(t, err)
 	assert.Equal(t, uint16(1234), params.Port)
 	assert.Equal(t, gosnmp.Version3, params.Version)
@@ -71,6 +83,53 @@ func TestFullConfig(t *testing.T) {
 		PrivacyProtocol:          gosnmp.AES,
 		PrivacyPassphrase:        "password",
 	}, params.SecurityParameters)
+	assert.Equal(t, gosnmp.AuthPriv, params.MsgFlags)
+
+	params, err = config.BuildV3Params(config.Users[1])
+	assert.NoError(t, err)
+	assert.Equal(t, &gosnmp.UsmSecurityParameters{
+		UserName:                 "user",
+		AuthoritativeEngineID:    "\x80\x00\x00\x00\x01\x02\x03\x04",
+		AuthenticationProtocol:   gosnmp.SHA256,
+		AuthenticationPassphrase: "password2",
+		PrivacyProtocol:          gosnmp.NoPriv,
+	}, params.SecurityParameters)
+	assert.Equal(t, gosnmp.AuthNoPriv, params.MsgFlags)
+}
+
+func TestDefaultProtocols(t *testing.T) {
+	config := Config{authoritativeEngineID: expectedEngineID}
+	params, err := config.BuildV3Params(UserV3{Username: "user", AuthKey: "password", PrivKey: "password"})
+	assert.NoError(t, err)
+	securityParams := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	assert.Equal(t, gosnmp.MD5, securityParams.AuthenticationProtocol)
+	assert.Equal(t, gosnmp.DES, securityParams.PrivacyProtocol)
+	assert.Equal(t, expectedEngineID, securityParams.AuthoritativeEngineID)
+
+	params, err = config.BuildV3Params(UserV3{Username: "user"})
+	assert.NoError(t, err)
+	assert.Equal(t, gosnmp.NoAuthNoPriv, params.MsgFlags)
+
+	_, err = config.BuildV3Params(UserV3{Username: "user", AuthKey: "password", AuthProtocol: "sha1024"})
+	assert.EqualError(t, err, "unsupported authentication protocol: sha1024")
+	_, err = config.BuildV3Params(UserV3{Username: "user", PrivKey: "password", PrivProtocol: "rot13"})
+	assert.EqualError(t, err, "unsupported privacy protocol: rot13")
+}
+
+func TestInvalidUsers(t *testing.T) {
+	for name, users := range map[string][]UserV3{
+		"missing username":   {{AuthKey: "password"}},
+		"invalid engine ID":  {{Username: "user", EngineID: "foo"}},
+		"short engine ID":    {{Username: "user", EngineID: "80000001"}},
+		"duplicate user":     {{Username: "user"}, {Username: "user", AuthKey: "password"}},
+		"duplicate engineID": {{Username: "user", EngineID: "8000000001"}, {Username: "user", EngineID: "0x8000000001"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			Configure(t, Config{Users: users})
+			_, err := ReadConfig("")
+			assert.Error(t, err)
+		})
+	}
 }
 
 func TestMinimalConfig(t *testing.T) {
@@ -84,8 +143,7 @@ func TestMinimalConfig(t *testing.T) {
 	assert.Equal(t, []UserV3{}, config.Users)
 	assert.Equal(t, "default", config.Namespace)
 
-	params, err := config.BuildSNMPParams()
-	assert.NoError(t, err)
+	params := config.BuildSNMPParams()
 	assert.Equal(t, uint16(9162), params.Port)
 	assert.Equal(t, gosnmp.Version2c, params.Version)
 	assert.Equal(t, "udp", params.Transport)
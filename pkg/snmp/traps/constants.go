@@ -10,5 +10,6 @@ const (
 	defaultStopTimeout = 5
 	defaultNamespace   = "default"
 	packetsChanSize    = 100
+	maxPacketSize      = 65535
 	genericTrapOid     = "1.3.6.1.6.3.1.1.5"
 )
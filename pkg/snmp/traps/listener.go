@@ -0,0 +1,243 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/gosnmp/gosnmp"
+)
+
+// usmStatsUnknownEngineIDs is the OID of the counter reported to the senders
+// discovering the authoritative engine ID of the listener.
+const usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+
+// macLengths are the lengths of the message authentication codes of the
+// authentication protocols (RFC3414 and RFC7860).
+var macLengths = map[gosnmp.SnmpV3AuthProtocol]int{
+	gosnmp.MD5:    12,
+	gosnmp.SHA:    12,
+	gosnmp.SHA224: 16,
+	gosnmp.SHA256: 24,
+	gosnmp.SHA384: 32,
+	gosnmp.SHA512: 48,
+}
+
+// errUnknownCredentials is returned for the packets which cannot be
+// authenticated with the community strings or SNMPv3 users of the listener.
+var errUnknownCredentials = errors.New("unknown credentials")
+
+// v3User holds the params used to decode the packets of an SNMPv3 user.
+type v3User struct {
+	// engineID is empty if the user matches the packets of any engine.
+	engineID string
+	params   *gosnmp.GoSNMP
+}
+
+// trapListener receives trap packets on an UDP socket. Unlike the gosnmp
+// listener, it selects the params used to decode each SNMPv3 packet from its
+// username and engine ID, to support several SNMPv3 users.
+type trapListener struct {
+	config  *Config
+	conn    *net.UDPConn
+	params  *gosnmp.GoSNMP
+	users   map[string][]v3User
+	packets PacketsChannel
+	done    chan struct{}
+
+	unknownEngineIDs uint32
+}
+
+func startSNMPTrapListener(c *Config, packets PacketsChannel) (*trapListener, error) {
+	listener := &trapListener{
+		config:  c,
+		params:  c.BuildSNMPParams(),
+		users:   make(map[string][]v3User),
+		packets: packets,
+		done:    make(chan struct{}),
+	}
+	for _, user := range c.Users {
+		params, err := c.BuildV3Params(user)
+		if err != nil {
+			return nil, err
+		}
+		engineID := ""
+		if user.EngineID != "" {
+			if engineID, err = parseEngineID(user.EngineID); err != nil {
+				return nil, err
+			}
+		}
+		listener.users[user.Username] = append(listener.users[user.Username], v3User{engineID: engineID, params: params})
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+	listener.conn, err = net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Start listening for traps on %s", c.Addr())
+	go listener.run()
+	return listener, nil
+}
+
+// Close stops the listener and waits for the packet being handled, if any.
+func (l *trapListener) Close() {
+	l.conn.Close()
+	<-l.done
+}
+
+func (l *trapListener) run() {
+	defer close(l.done)
+	var buf [maxPacketSize]byte
+	for {
+		n, addr, err := l.conn.ReadFromUDP(buf[:])
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Warnf("Error reading packet on listener %s: %s", l.config.Addr(), err)
+			continue
+		}
+		l.handlePacket(buf[:n], addr)
+	}
+}
+
+func (l *trapListener) handlePacket(msg []byte, addr *net.UDPAddr) {
+	p, err := l.decodePacket(msg, addr)
+	if err != nil {
+		if errors.Is(err, errUnknownCredentials) {
+			log.Warnf("Invalid credentials from %s on listener %s, dropping packet: %s", addr.String(), l.config.Addr(), err)
+			trapsPacketsAuthErrors.Add(1)
+		} else {
+			log.Debugf("Invalid packet from %s on listener %s, dropping packet: %s", addr.String(), l.config.Addr(), err)
+		}
+		return
+	}
+	if p == nil {
+		// engine ID discovery
+		return
+	}
+	log.Debugf("Packet received from %s on listener %s", addr.String(), l.config.Addr())
+	trapsPackets.Add(1)
+	l.packets <- &SnmpPacket{Content: p, Addr: addr}
+
+	if p.PDUType == gosnmp.InformRequest {
+		// The response to an inform is the packet itself, with the same variables.
+		p.PDUType = gosnmp.GetResponse
+		p.Error = gosnmp.NoError
+		p.ErrorIndex = 0
+		if err := l.send(p, addr); err != nil {
+			log.Warnf("Error responding to inform from %s on listener %s: %s", addr.String(), l.config.Addr(), err)
+		}
+	}
+}
+
+// decodePacket decodes and authenticates a packet. It returns a nil packet
+// when the packet has been answered with a report of the engine ID of the listener.
+func (l *trapListener) decodePacket(msg []byte, addr *net.UDPAddr) (*gosnmp.SnmpPacket, error) {
+	header, err := parseUsmHeader(msg)
+	if errors.Is(err, errNotV3) {
+		// SNMPv1 and SNMPv2c packets are authenticated by their community string.
+		p, err := l.params.UnmarshalTrap(msg, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := validatePacket(p, l.config); err != nil {
+			return nil, fmt.Errorf("%w: %s", errUnknownCredentials, err)
+		}
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC3414 section 3.2.3: the senders of informs discover the engine ID of
+	// the listener with packets having an empty engine ID.
+	if len(header.engineID) < 5 || len(header.engineID) > 32 {
+		return nil, l.reportEngineID(msg, addr)
+	}
+
+	users, ok := l.users[header.userName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown user %q", errUnknownCredentials, header.userName)
+	}
+	for _, user := range users {
+		if user.engineID != "" && user.engineID != header.engineID {
+			continue
+		}
+		if !hasSecurityLevel(header, user.params) {
+			continue
+		}
+		// gosnmp modifies the packet while authenticating it.
+		p, err := user.params.UnmarshalTrap(append([]byte(nil), msg...), false)
+		if err == nil {
+			return p, nil
+		}
+	}
+	trapsUsersAuthErrors.Add(header.userName, 1)
+	return nil, fmt.Errorf("%w: authentication failed for user %q", errUnknownCredentials, header.userName)
+}
+
+// hasSecurityLevel returns whether a packet is authenticated and encrypted as
+// required by the user: gosnmp uses the security level of the user instead of
+// the one of the packet, and would accept a packet without authentication code.
+func hasSecurityLevel(header *usmHeader, params *gosnmp.GoSNMP) bool {
+	required := params.MsgFlags & gosnmp.AuthPriv
+	if header.msgFlags&required != required {
+		return false
+	}
+	if required&gosnmp.AuthNoPriv != 0 {
+		authProtocol := params.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthenticationProtocol
+		return header.authParamsLength == macLengths[authProtocol]
+	}
+	return true
+}
+
+// reportEngineID answers an engine ID discovery request with the authoritative engine ID of the listener.
+func (l *trapListener) reportEngineID(msg []byte, addr *net.UDPAddr) error {
+	params := &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.NoAuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			AuthoritativeEngineID: l.config.authoritativeEngineID,
+		},
+		Logger: l.params.Logger,
+	}
+	p, err := params.UnmarshalTrap(msg, false)
+	if err != nil {
+		return err
+	}
+	securityParams, ok := p.SecurityParameters.Copy().(*gosnmp.UsmSecurityParameters)
+	if !ok {
+		return errors.New("unable to cast SecurityParams to UsmSecurityParameters")
+	}
+	l.unknownEngineIDs++
+	securityParams.AuthoritativeEngineID = l.config.authoritativeEngineID
+	p.PDUType = gosnmp.Report
+	p.MsgFlags &= gosnmp.AuthPriv
+	p.SecurityParameters = securityParams
+	p.Variables = []gosnmp.SnmpPDU{
+		{Name: usmStatsUnknownEngineIDs, Type: gosnmp.Integer, Value: int(l.unknownEngineIDs)},
+	}
+	return l.send(p, addr)
+}
+
+func (l *trapListener) send(p *gosnmp.SnmpPacket, addr *net.UDPAddr) error {
+	msg, err := p.MarshalMsg()
+	if err != nil {
+		return fmt.Errorf("error marshaling SnmpPacket: %w", err)
+	}
+	_, err = l.conn.WriteToUDP(msg, addr)
+	return err
+}
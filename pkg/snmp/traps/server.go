@@ -26,7 +26,7 @@ type PacketsChannel = chan *SnmpPacket
 type TrapServer struct {
 	Addr     string
 	config   *Config
-	listener *gosnmp.TrapListener
+	listener *trapListener
 	packets  PacketsChannel
 }
 
@@ -93,51 +93,6 @@ func NewTrapServer(agentHostname string) (*TrapServer, error) {
 	return server, nil
 }
 
-func startSNMPTrapListener(c *Config, packets PacketsChannel) (*gosnmp.TrapListener, error) {
-	var err error
-	listener := gosnmp.NewTrapListener()
-	listener.Params, err = c.BuildSNMPParams()
-	if err != nil {
-		return nil, err
-	}
-
-	listener.OnNewTrap = func(p *gosnmp.SnmpPacket, u *net.UDPAddr) {
-		if err := validatePacket(p, c); err != nil {
-			log.Warnf("Invalid credentials from %s on listener %s, dropping packet", u.String(), c.Addr())
-			trapsPacketsAuthErrors.Add(1)
-			return
-		}
-		log.Debugf("Packet received from %s on listener %s", u.String(), c.Addr())
-		trapsPackets.Add(1)
-		packets <- &SnmpPacket{Content: p, Addr: u}
-	}
-
-	errors := make(chan error, 1)
-
-	// Start actually listening in the background.
-	go func() {
-		log.Infof("Start listening for traps on %s", c.Addr())
-		err := listener.Listen(c.Addr())
-		if err != nil {
-			errors <- err
-		}
-	}()
-
-	select {
-	// Wait for listener to be started and listening to traps.
-	// See: https://godoc.org/github.com/gosnmp/gosnmp#TrapListener.Listening
-	case <-listener.Listening():
-		break
-	// If the listener failed to start (eg because it couldn't bind to a socket),
-	// we'll get an error here.
-	case err := <-errors:
-		close(errors)
-		return nil, err
-	}
-
-	return listener, nil
-}
-
 // Stop stops the TrapServer.
 func (s *TrapServer) Stop() {
 	stopped := make(chan interface{})
@@ -6,9 +6,11 @@
 package traps
 
 import (
+	"expvar"
 	"net"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gosnmp/gosnmp"
 	"github.com/stretchr/testify/assert"
@@ -152,6 +154,77 @@ func TestServerV3BadCredentials(t *testing.T) {
 	assertNoPacketReceived(t)
 }
 
+func TestServerV3MultipleUsers(t *testing.T) {
+	config := Config{Port: serverPort, Users: []UserV3{
+		{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"},
+		{Username: "user", AuthKey: "other_password", AuthProtocol: "sha256", EngineID: "0x666f6f62617a"},
+		{Username: "other_user", AuthKey: "password", AuthProtocol: "md5", PrivKey: "password", PrivProtocol: "des"},
+		{Username: "noauth_user"},
+	}}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	for _, securityParams := range []*gosnmp.UsmSecurityParameters{
+		{UserName: "user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.SHA, PrivacyPassphrase: "password", PrivacyProtocol: gosnmp.AES},
+		{UserName: "user", AuthoritativeEngineID: "foobaz", AuthenticationPassphrase: "other_password", AuthenticationProtocol: gosnmp.SHA256},
+		{UserName: "other_user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.MD5, PrivacyPassphrase: "password", PrivacyProtocol: gosnmp.DES},
+		{UserName: "noauth_user", AuthoritativeEngineID: "foobarbaz"},
+	} {
+		sendTestV3Trap(t, config, securityParams)
+		packet := receivePacket(t)
+		require.NotNil(t, packet, securityParams.UserName)
+		assertVariables(t, packet)
+	}
+}
+
+func TestServerV3MultipleUsersBadCredentials(t *testing.T) {
+	config := Config{Port: serverPort, Users: []UserV3{
+		{Username: "user", AuthKey: "password", AuthProtocol: "sha", PrivKey: "password", PrivProtocol: "aes"},
+		{Username: "engine_user", AuthKey: "password", AuthProtocol: "sha256", EngineID: "0x666f6f62617a"},
+	}}
+	Configure(t, config)
+
+	err := StartServer("dummy_hostname")
+	require.NoError(t, err)
+	defer StopServer()
+
+	userErrors := func(username string) int64 {
+		if v, ok := trapsUsersAuthErrors.Get(username).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	authErrors, userAuthErrors, engineUserAuthErrors := trapsPacketsAuthErrors.Value(), userErrors("user"), userErrors("engine_user")
+
+	for _, securityParams := range []*gosnmp.UsmSecurityParameters{
+		// wrong privacy key
+		{UserName: "user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.SHA, PrivacyPassphrase: "wrong_password", PrivacyProtocol: gosnmp.AES},
+		// not encrypted although the user requires it
+		{UserName: "user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.SHA},
+		// not authenticated although the user requires it
+		{UserName: "user", AuthoritativeEngineID: "foobarbaz"},
+		// sent by another engine than the one of the user
+		{UserName: "engine_user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.SHA256},
+		// unknown user
+		{UserName: "unknown_user", AuthoritativeEngineID: "foobarbaz", AuthenticationPassphrase: "password", AuthenticationProtocol: gosnmp.SHA},
+	} {
+		sendTestV3Trap(t, config, securityParams)
+		assertNoPacketReceived(t)
+	}
+
+	assert.Eventually(t, func() bool { return trapsPacketsAuthErrors.Value() == authErrors+5 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, userAuthErrors+3, userErrors("user"))
+	assert.Equal(t, engineUserAuthErrors+1, userErrors("engine_user"))
+	assert.Nil(t, trapsUsersAuthErrors.Get("unknown_user"))
+
+	status := GetStatus()
+	assert.NotContains(t, status["metrics"], "UsersAuthErrors")
+	assert.Contains(t, status["usersAuthErrors"], "user")
+}
+
 func TestStartFailure(t *testing.T) {
 	/*
 		Start two servers with the same config to trigger an "address already in use" error.
@@ -14,11 +14,14 @@ var (
 	trapsExpvars           = expvar.NewMap("snmp_traps")
 	trapsPackets           = expvar.Int{}
 	trapsPacketsAuthErrors = expvar.Int{}
+	// trapsUsersAuthErrors counts the authentication errors of the packets of each SNMPv3 user.
+	trapsUsersAuthErrors = expvar.Map{}
 )
 
 func init() {
 	trapsExpvars.Set("Packets", &trapsPackets)
 	trapsExpvars.Set("PacketsAuthErrors", &trapsPacketsAuthErrors)
+	trapsExpvars.Set("UsersAuthErrors", &trapsUsersAuthErrors)
 }
 
 // GetStatus returns key-value data for use in status reporting of the traps server.
@@ -28,6 +31,11 @@ func GetStatus() map[string]interface{} {
 	metricsJSON := []byte(expvar.Get("snmp_traps").String())
 	metrics := make(map[string]interface{})
 	json.Unmarshal(metricsJSON, &metrics) //nolint:errcheck
+	// The errors of each user are reported separately from the global counters.
+	if usersAuthErrors, ok := metrics["UsersAuthErrors"]; ok {
+		delete(metrics, "UsersAuthErrors")
+		status["usersAuthErrors"] = usersAuthErrors
+	}
 	status["metrics"] = metrics
 
 	if startError != nil {
@@ -91,14 +91,13 @@ func ConfigureWithGlobalNamespace(t *testing.T, trapConfig Config, globalNamespa
 }
 
 func sendTestV1GenericTrap(t *testing.T, trapConfig Config, community string) *gosnmp.GoSNMP {
-	params, err := trapConfig.BuildSNMPParams()
-	require.NoError(t, err)
+	params := trapConfig.BuildSNMPParams()
 	params.Community = community
 	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
 	params.Retries = 1               // Must be non-zero when sending traps.
 	params.Version = gosnmp.Version1
 
-	err = params.Connect()
+	err := params.Connect()
 	require.NoError(t, err)
 	defer params.Conn.Close()
 
@@ -109,14 +108,13 @@ func sendTestV1GenericTrap(t *testing.T, trapConfig Config, community string) *g
 }
 
 func sendTestV1SpecificTrap(t *testing.T, trapConfig Config, community string) *gosnmp.GoSNMP {
-	params, err := trapConfig.BuildSNMPParams()
-	require.NoError(t, err)
+	params := trapConfig.BuildSNMPParams()
 	params.Community = community
 	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
 	params.Retries = 1               // Must be non-zero when sending traps.
 	params.Version = gosnmp.Version1
 
-	err = params.Connect()
+	err := params.Connect()
 	require.NoError(t, err)
 	defer params.Conn.Close()
 
@@ -127,13 +125,12 @@ func sendTestV1SpecificTrap(t *testing.T, trapConfig Config, community string) *
 }
 
 func sendTestV2Trap(t *testing.T, trapConfig Config, community string) *gosnmp.GoSNMP {
-	params, err := trapConfig.BuildSNMPParams()
-	require.NoError(t, err)
+	params := trapConfig.BuildSNMPParams()
 	params.Community = community
 	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
 	params.Retries = 1               // Must be non-zero when sending traps.
 
-	err = params.Connect()
+	err := params.Connect()
 	require.NoError(t, err)
 	defer params.Conn.Close()
 
@@ -145,14 +142,20 @@ func sendTestV2Trap(t *testing.T, trapConfig Config, community string) *gosnmp.G
 }
 
 func sendTestV3Trap(t *testing.T, trapConfig Config, securityParams *gosnmp.UsmSecurityParameters) *gosnmp.GoSNMP {
-	params, err := trapConfig.BuildSNMPParams()
-	require.NoError(t, err)
-	params.MsgFlags = gosnmp.AuthPriv
+	params := trapConfig.BuildSNMPParams()
+	params.Version = gosnmp.Version3
+	params.SecurityModel = gosnmp.UserSecurityModel
+	params.MsgFlags = gosnmp.NoAuthNoPriv
+	if securityParams.PrivacyProtocol > gosnmp.NoPriv {
+		params.MsgFlags = gosnmp.AuthPriv
+	} else if securityParams.AuthenticationProtocol > gosnmp.NoAuth {
+		params.MsgFlags = gosnmp.AuthNoPriv
+	}
 	params.SecurityParameters = securityParams
 	params.Timeout = 1 * time.Second // Must be non-zero when sending traps.
 	params.Retries = 1               // Must be non-zero when sending traps.
 
-	err = params.Connect()
+	err := params.Connect()
 	require.NoError(t, err)
 	defer params.Conn.Close()
 
@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"errors"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// BER tags of the fields of the SNMPv3 header.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berSequence    = 0x30
+)
+
+const (
+	snmpVersion3      = 3
+	usmSecurityModel  = 3
+	maxBerLengthBytes = 4
+)
+
+var (
+	errInvalidHeader = errors.New("invalid SNMPv3 header")
+	errNotV3         = errors.New("not an SNMPv3 packet")
+)
+
+// usmHeader contains the fields of the header of an SNMPv3 packet needed to
+// find the user the packet has been sent by, before decoding it.
+type usmHeader struct {
+	msgFlags gosnmp.SnmpV3MsgFlags
+	engineID string
+	userName string
+	// authParamsLength is the length of the message authentication code of the packet.
+	authParamsLength int
+}
+
+// parseUsmHeader parses the header of an SNMPv3 packet using the User-based
+// Security Model (RFC3412 section 6 and RFC3414 section 2.4). It returns
+// errNotV3 for the packets of the other SNMP versions.
+func parseUsmHeader(packet []byte) (*usmHeader, error) {
+	r := berReader(packet)
+	message, err := r.expect(berSequence)
+	if err != nil {
+		return nil, err
+	}
+	version, err := message.integer()
+	if err != nil {
+		return nil, err
+	}
+	if version != snmpVersion3 {
+		return nil, errNotV3
+	}
+
+	globalData, err := message.expect(berSequence)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := globalData.integer(); err != nil { // msgID
+		return nil, err
+	}
+	if _, err := globalData.integer(); err != nil { // msgMaxSize
+		return nil, err
+	}
+	flags, err := globalData.expect(berOctetString)
+	if err != nil || len(flags) != 1 {
+		return nil, errInvalidHeader
+	}
+	if securityModel, err := globalData.integer(); err != nil || securityModel != usmSecurityModel {
+		return nil, errInvalidHeader
+	}
+
+	securityParameters, err := message.expect(berOctetString)
+	if err != nil {
+		return nil, err
+	}
+	usm, err := securityParameters.expect(berSequence)
+	if err != nil {
+		return nil, err
+	}
+	engineID, err := usm.expect(berOctetString)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := usm.integer(); err != nil { // msgAuthoritativeEngineBoots
+		return nil, err
+	}
+	if _, err := usm.integer(); err != nil { // msgAuthoritativeEngineTime
+		return nil, err
+	}
+	userName, err := usm.expect(berOctetString)
+	if err != nil {
+		return nil, err
+	}
+	authParams, err := usm.expect(berOctetString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usmHeader{
+		msgFlags:         gosnmp.SnmpV3MsgFlags(flags[0]),
+		engineID:         string(engineID),
+		userName:         string(userName),
+		authParamsLength: len(authParams),
+	}, nil
+}
+
+// berReader reads the successive BER-encoded TLVs of a buffer.
+type berReader []byte
+
+// expect reads the next TLV, which must have the given tag, and returns its value.
+func (r *berReader) expect(tag byte) (berReader, error) {
+	buf := *r
+	if len(buf) < 2 || buf[0] != tag {
+		return nil, errInvalidHeader
+	}
+	length, offset := int(buf[1]), 2
+	if length&0x80 != 0 {
+		lengthBytes := length & 0x7f
+		if lengthBytes == 0 || lengthBytes > maxBerLengthBytes || len(buf) < offset+lengthBytes {
+			return nil, errInvalidHeader
+		}
+		length = 0
+		for _, b := range buf[offset : offset+lengthBytes] {
+			length = length<<8 | int(b)
+		}
+		offset += lengthBytes
+	}
+	if length < 0 || len(buf)-offset < length {
+		return nil, errInvalidHeader
+	}
+	*r = buf[offset+length:]
+	return buf[offset : offset+length], nil
+}
+
+// integer reads the next TLV, which must be a non-negative integer fitting in 32 bits.
+func (r *berReader) integer() (int64, error) {
+	value, err := r.expect(berInteger)
+	if err != nil {
+		return 0, err
+	}
+	if len(value) == 0 || len(value) > 5 || value[0]&0x80 != 0 {
+		return 0, errInvalidHeader
+	}
+	var i int64
+	for _, b := range value {
+		i = i<<8 | int64(b)
+	}
+	return i, nil
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2020-present Datadog, Inc.
+
+package traps
+
+import (
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestPacket(t *testing.T, params *gosnmp.GoSNMP) []byte {
+	params.Logger = gosnmp.NewLogger(&trapLogger{})
+	packet, err := params.SnmpEncodePacket(gosnmp.SNMPv2Trap, NetSNMPExampleHeartbeatNotification.Variables, 0, 0)
+	require.NoError(t, err)
+	return packet
+}
+
+func TestParseUsmHeader(t *testing.T) {
+	packet := encodeTestPacket(t, &gosnmp.GoSNMP{
+		Version:       gosnmp.Version3,
+		SecurityModel: gosnmp.UserSecurityModel,
+		MsgFlags:      gosnmp.AuthNoPriv,
+		SecurityParameters: &gosnmp.UsmSecurityParameters{
+			UserName:                 "user",
+			AuthoritativeEngineID:    "foobarbaz",
+			AuthenticationProtocol:   gosnmp.SHA256,
+			AuthenticationPassphrase: "password",
+		},
+	})
+
+	header, err := parseUsmHeader(packet)
+	require.NoError(t, err)
+	assert.Equal(t, &usmHeader{
+		msgFlags:         gosnmp.AuthNoPriv | gosnmp.Reportable,
+		engineID:         "foobarbaz",
+		userName:         "user",
+		authParamsLength: 24,
+	}, header)
+
+	_, err = parseUsmHeader(packet[:30])
+	assert.Equal(t, errInvalidHeader, err)
+}
+
+func TestParseUsmHeaderLongFormLength(t *testing.T) {
+	packet := []byte{
+		0x30, 0x81, 0x22, // message, with a long-form length
+		0x02, 0x01, 0x03, // version
+		0x30, 0x0d, 0x02, 0x01, 0x01, 0x02, 0x02, 0x05, 0xdc, 0x04, 0x01, 0x04, 0x02, 0x01, 0x03, // global data
+		0x04, 0x0e, 0x30, 0x0c, // security parameters
+		0x04, 0x00, // engine ID
+		0x02, 0x01, 0x00, 0x02, 0x01, 0x00, // engine boots and time
+		0x04, 0x00, 0x04, 0x00, 0x04, 0x00, // user name, auth and priv parameters
+	}
+	header, err := parseUsmHeader(packet)
+	require.NoError(t, err)
+	assert.Equal(t, &usmHeader{msgFlags: gosnmp.Reportable}, header)
+}
+
+func TestParseUsmHeaderNotV3(t *testing.T) {
+	packet := encodeTestPacket(t, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"})
+	_, err := parseUsmHeader(packet)
+	assert.Equal(t, errNotV3, err)
+
+	_, err = parseUsmHeader([]byte("foo"))
+	assert.Equal(t, errInvalidHeader, err)
+}
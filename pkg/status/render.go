@@ -17,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/otlp"
+	"github.com/DataDog/datadog-agent/pkg/snmp/netflow"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -55,6 +56,7 @@ func FormatStatus(data []byte) (string, error) {
 	systemProbeStats := stats["systemProbeStats"]
 	processAgentStatus := stats["processAgentStatus"]
 	snmpTrapsStats := stats["snmpTrapsStats"]
+	netflowStats := stats["netflowStats"]
 	title := fmt.Sprintf("Agent (v%s)", stats["version"])
 	stats["title"] = title
 
@@ -86,6 +88,11 @@ func FormatStatus(data []byte) (string, error) {
 			renderStatusTemplate(b, "/snmp-traps.tmpl", snmpTrapsStats)
 		}
 	}
+	netflowFunc := func() {
+		if netflow.IsEnabled() {
+			renderStatusTemplate(b, "/netflow.tmpl", netflowStats)
+		}
+	}
 	autodiscoveryFunc := func() {
 		if config.IsContainerized() {
 			renderAutodiscoveryStats(b, stats["adEnabledFeatures"], stats["adConfigErrors"],
@@ -107,7 +114,7 @@ func FormatStatus(data []byte) (string, error) {
 	} else {
 		renderFuncs = []func(){headerFunc, checkStatsFunc, jmxFetchFunc, forwarderFunc, endpointsFunc,
 			logsAgentFunc, systemProbeFunc, processAgentFunc, traceAgentFunc, aggregatorFunc, dogstatsdFunc,
-			clusterAgentFunc, snmpTrapFunc, autodiscoveryFunc, otlpFunc}
+			clusterAgentFunc, snmpTrapFunc, netflowFunc, autodiscoveryFunc, otlpFunc}
 	}
 
 	renderAgentSections(renderFuncs)
@@ -25,6 +25,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs"
 	"github.com/DataDog/datadog-agent/pkg/metadata/host"
+	"github.com/DataDog/datadog-agent/pkg/snmp/netflow"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
 	"github.com/DataDog/datadog-agent/pkg/util"
 	"github.com/DataDog/datadog-agent/pkg/util/containers"
@@ -439,6 +440,7 @@ func expvarStats(stats map[string]interface{}) (map[string]interface{}, error) {
 	}
 
 	stats["snmpTrapsStats"] = traps.GetStatus()
+	stats["netflowStats"] = netflow.GetStatus()
 
 	complianceVar := expvar.Get("compliance")
 	if complianceVar != nil {
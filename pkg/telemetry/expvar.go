@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"expvar"
+	"strings"
+	"sync"
+)
+
+// CounterExpvar is a Counter also reported as an expvar. The expvar holds the sum of all the tags values.
+type CounterExpvar struct {
+	counter Counter
+	expvar  expvar.Int
+}
+
+// NewCounterExpvar creates a CounterExpvar, whose expvar is added to parent under the camel case version of name.
+func NewCounterExpvar(subsystem string, name string, tags []string, help string, parent *expvar.Map) *CounterExpvar {
+	c := &CounterExpvar{
+		counter: NewCounter(subsystem, name, tags, help),
+	}
+	parent.Set(toCamelCase(name), &c.expvar)
+	return c
+}
+
+// Add adds the given value to the counter with the given tags value, and to the expvar.
+func (c *CounterExpvar) Add(v float64, tagsValue ...string) {
+	c.counter.Add(v, tagsValue...)
+	c.expvar.Add(int64(v))
+}
+
+// GaugeExpvar is a Gauge also reported as an expvar. The expvar holds the last value set, or the sum of the last
+// values set for each tags values when created with NewSummedGaugeExpvar.
+type GaugeExpvar struct {
+	gauge  Gauge
+	expvar expvar.Int
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGaugeExpvar creates a GaugeExpvar, whose expvar is added to parent under the camel case version of name.
+func NewGaugeExpvar(subsystem string, name string, tags []string, help string, parent *expvar.Map) *GaugeExpvar {
+	g := &GaugeExpvar{
+		gauge: NewGauge(subsystem, name, tags, help),
+	}
+	parent.Set(toCamelCase(name), &g.expvar)
+	return g
+}
+
+// NewSummedGaugeExpvar creates a GaugeExpvar whose expvar holds the sum of the last values set for each tags values,
+// it is added to parent under the camel case version of name.
+func NewSummedGaugeExpvar(subsystem string, name string, tags []string, help string, parent *expvar.Map) *GaugeExpvar {
+	g := NewGaugeExpvar(subsystem, name, tags, help, parent)
+	g.values = make(map[string]float64)
+	return g
+}
+
+// Set sets the value of the gauge with the given tags value, and updates the expvar.
+func (g *GaugeExpvar) Set(v float64, tagsValue ...string) {
+	g.gauge.Set(v, tagsValue...)
+
+	if g.values == nil {
+		g.expvar.Set(int64(v))
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[strings.Join(tagsValue, "\x00")] = v
+	var sum float64
+	for _, value := range g.values {
+		sum += value
+	}
+	g.expvar.Set(int64(sum))
+}
+
+func toCamelCase(s string) string {
+	parts := strings.Split(s, "_")
+	var camelCase string
+	for _, p := range parts {
+		camelCase += strings.Title(p)
+	}
+	return camelCase
+}
@@ -0,0 +1,39 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package telemetry
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpvarTelemetry(t *testing.T) {
+	// Reset telemetry registry data
+	Reset()
+
+	parent := expvar.Map{}
+	counter := NewCounterExpvar("subsystem", "dropped_count", []string{"domain"}, "help docs", &parent)
+	gauge := NewGaugeExpvar("subsystem", "file_size", []string{"domain"}, "help docs", &parent)
+	summedGauge := NewSummedGaugeExpvar("subsystem", "current_size_in_bytes", []string{"domain"}, "help docs", &parent)
+
+	counter.Add(2, "a")
+	counter.Add(3, "b")
+	for _, g := range []*GaugeExpvar{gauge, summedGauge} {
+		g.Set(10, "a")
+		g.Set(4, "b")
+		g.Set(6, "a")
+	}
+
+	assert.Equal(t, "5", parent.Get("DroppedCount").String())
+	assert.Equal(t, "6", parent.Get("FileSize").String())
+	assert.Equal(t, "10", parent.Get("CurrentSizeInBytes").String())
+
+	metrics, err := telemetryRegistry.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 3)
+}
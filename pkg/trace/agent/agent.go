@@ -42,10 +42,12 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	Transformer           *filters.Transformer
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	TailSampler           *sampler.TailSampler // nil unless tail-based sampling is enabled
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -87,6 +89,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		Transformer:           filters.NewTransformer(conf.TransformRules),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(),
@@ -102,6 +105,14 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
+	if conf.TailSampling != nil && conf.TailSampling.Enabled {
+		ts, err := sampler.NewTailSampler(conf.TailSampling, agnt.tailSampled)
+		if err != nil {
+			log.Errorf("Tail-based sampling disabled: %v", err)
+		} else {
+			agnt.TailSampler = ts
+		}
+	}
 	return agnt
 }
 
@@ -119,6 +130,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -169,6 +183,10 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.TailSampler != nil {
+				// flush the buffered traces before the writer stops
+				a.TailSampler.Stop()
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
@@ -202,6 +220,8 @@ func (a *Agent) Process(p *api.Payload) {
 	defer timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	ss := new(writer.SampledChunks)
+	// tailPayload holds the metadata of p, shared by its chunks buffered by the tail sampler.
+	var tailPayload *pb.TracerPayload
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
@@ -237,9 +257,14 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
-		if filteredByTags(root, a.conf.RequireTags, a.conf.RejectTags) {
-			log.Debugf("Trace rejected as it fails to meet tag requirements. root: %v", root)
+		filterSpans := []*pb.Span{root}
+		if a.conf.FilterTagsAnySpan {
+			filterSpans = chunk.Spans
+		}
+		if rule, ok := filteredByTags(filterSpans, a.conf.RequireTags, a.conf.RejectTags, a.conf.RequireTagsRegex, a.conf.RejectTagsRegex); ok {
+			log.Debugf("Trace rejected as it fails to meet tag requirements (rule %q). root: %v", rule, root)
 			atomic.AddInt64(&ts.TracesFiltered, 1)
+			ts.TracesFilteredByRule.Count(rule)
 			atomic.AddInt64(&ts.SpansFiltered, tracen)
 			p.RemoveChunk(i)
 			continue
@@ -264,6 +289,7 @@ func (a *Agent) Process(p *api.Payload) {
 			}
 		}
 		a.Replacer.Replace(chunk.Spans)
+		a.Transformer.Transform(chunk.Spans)
 
 		{
 			// this section sets up any necessary tags on the root:
@@ -304,7 +330,12 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, pt)
 		}
 
-		numEvents, keep, filteredChunk := a.sample(now, ts, pt)
+		if a.TailSampler != nil && tailPayload == nil {
+			tp := *p.TracerPayload
+			tp.Chunks = nil
+			tailPayload = &tp
+		}
+		numEvents, keep, filteredChunk := a.sample(now, ts, pt, tailPayload)
 		if !keep {
 			if numEvents == 0 {
 				// the trace was dropped and no analyzed span were kept
@@ -432,7 +463,11 @@ func (a *Agent) ProcessStats(in pb.ClientStatsPayload, lang, tracerVersion strin
 }
 
 // sample reports the number of events found in pt and whether the chunk should be kept as a trace.
-func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (numEvents int64, keep bool, filteredChunk *pb.TraceChunk) {
+// When tail-based sampling is enabled, the chunk is buffered by the tail sampler along with the
+// metadata of the tracer payload tp, and the decision is made later on. The other samplers still
+// see the chunk beforehand, so that the rates sent back to the tracers keep being computed, but
+// their decision is ignored.
+func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace, tp *pb.TracerPayload) (numEvents int64, keep bool, filteredChunk *pb.TraceChunk) {
 	priority, hasPriority := sampler.GetSamplingPriority(pt.TraceChunk)
 
 	if hasPriority {
@@ -445,6 +480,23 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedT
 		return 0, false, nil
 	}
 
+	if a.TailSampler != nil && priority < sampler.PriorityUserKeep {
+		a.runSamplers(now, pt, hasPriority)
+		// Extract the analyzed spans now, to send them in case the trace is dropped.
+		events := new(pb.TraceChunk)
+		*events = *pt.TraceChunk
+		events.DroppedTrace = true
+		numEvents, numExtracted := a.EventProcessor.Process(pt.Root, events)
+		atomic.AddInt64(&ts.EventsExtracted, numExtracted)
+		atomic.AddInt64(&ts.EventsSampled, numEvents)
+		c := sampler.TailChunk{Payload: tp, Chunk: pt.TraceChunk, NumEvents: numEvents}
+		if numEvents > 0 {
+			c.Events = events
+		}
+		a.TailSampler.Add(now, c)
+		return 0, false, nil
+	}
+
 	sampled := a.runSamplers(now, pt, hasPriority)
 
 	filteredChunk = pt.TraceChunk
@@ -461,6 +513,38 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedT
 	return numEvents, sampled, filteredChunk
 }
 
+// tailSampled sends the chunks of a trace on which the tail sampler made a decision to the writer.
+// The chunks of dropped traces are replaced by their analyzed spans, if any.
+func (a *Agent) tailSampled(t sampler.TailSampledTrace) {
+	var payloads []*writer.SampledChunks
+	byPayload := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	for _, c := range t.Chunks {
+		chunk := c.Chunk
+		if !t.Keep {
+			if c.Events == nil {
+				continue
+			}
+			chunk = c.Events
+		}
+		ss, ok := byPayload[c.Payload]
+		if !ok {
+			tp := *c.Payload
+			ss = &writer.SampledChunks{TracerPayload: &tp}
+			byPayload[c.Payload] = ss
+			payloads = append(payloads, ss)
+		}
+		ss.TracerPayload.Chunks = append(ss.TracerPayload.Chunks, chunk)
+		if !chunk.DroppedTrace {
+			ss.SpanCount += int64(len(chunk.Spans))
+		}
+		ss.EventCount += c.NumEvents
+		ss.Size += chunk.Msgsize()
+	}
+	for _, ss := range payloads {
+		a.TraceWriter.In <- ss
+	}
+}
+
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
 // along with the sampling rate.
 func (a *Agent) runSamplers(now time.Time, pt traceutil.ProcessedTrace, hasPriority bool) bool {
@@ -508,15 +592,56 @@ func traceContainsError(trace pb.Trace) bool {
 	return false
 }
 
-func filteredByTags(root *pb.Span, require, reject []*config.Tag) bool {
+// filteredByTags reports whether a trace chunk should be filtered out based on the tags found on the given
+// spans, along with the name of the rule which filtered it. A reject rule filters the chunk when any of
+// the spans matches it, and a require rule when none of them does.
+func filteredByTags(spans []*pb.Span, require, reject []*config.Tag, requireRegex, rejectRegex []*config.TagRegex) (rule string, filtered bool) {
 	for _, tag := range reject {
-		if v, ok := root.Meta[tag.K]; ok && (tag.V == "" || v == tag.V) {
-			return true
+		if anySpanHasTag(spans, tag) {
+			return "reject:" + tag.String(), true
+		}
+	}
+	for _, tag := range rejectRegex {
+		if anySpanMatchesTag(spans, tag) {
+			return tagRegexRule("reject", tag), true
 		}
 	}
 	for _, tag := range require {
-		v, ok := root.Meta[tag.K]
-		if !ok || (tag.V != "" && v != tag.V) {
+		if !anySpanHasTag(spans, tag) {
+			return "require:" + tag.String(), true
+		}
+	}
+	for _, tag := range requireRegex {
+		if !anySpanMatchesTag(spans, tag) {
+			return tagRegexRule("require", tag), true
+		}
+	}
+	return "", false
+}
+
+// tagRegexRule returns the name of a regex or glob filtering rule, as reported in the stats.
+func tagRegexRule(action string, tag *config.TagRegex) string {
+	if tag.Glob {
+		return action + "_glob:" + tag.String()
+	}
+	return action + "_regex:" + tag.String()
+}
+
+// anySpanHasTag reports whether any of the spans has the given tag, with any value if tag.V is empty.
+func anySpanHasTag(spans []*pb.Span, tag *config.Tag) bool {
+	for _, span := range spans {
+		if v, ok := span.Meta[tag.K]; ok && (tag.V == "" || v == tag.V) {
+			return true
+		}
+	}
+	return false
+}
+
+// anySpanMatchesTag reports whether any of the spans has the given tag, with a value matching the
+// pattern tag.V if it is set.
+func anySpanMatchesTag(spans []*pb.Span, tag *config.TagRegex) bool {
+	for _, span := range spans {
+		if v, ok := span.Meta[tag.K]; ok && (tag.V == nil || tag.V.MatchString(v)) {
 			return true
 		}
 	}
@@ -8,6 +8,7 @@ package agent
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -103,6 +104,49 @@ func TestProcess(t *testing.T) {
 		assert.Equal("SELECT name FROM people WHERE age = ? AND extra = ?", span.Meta["sql.query"])
 	})
 
+	t.Run("Transformer", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.TransformRules = []*config.TransformRule{
+			{Action: "metric_to_meta", Key: "http.status_code"},
+			{Action: "rename", Key: "customer", Target: "customer.id"},
+			{Action: "set", Key: "tier", Value: "backend", ServiceRe: regexp.MustCompile("^db-")},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Service:  "db-users",
+			Resource: "GET /users",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"customer": "42"},
+			Metrics:  map[string]float64{"http.status_code": 404},
+		}
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		})
+
+		assert := assert.New(t)
+		assert.Equal("404", span.Meta["http.status_code"])
+		assert.NotContains(span.Metrics, "http.status_code")
+		assert.Equal("42", span.Meta["customer.id"])
+		assert.NotContains(span.Meta, "customer")
+		assert.Equal("backend", span.Meta["tier"])
+
+		// stats are computed on the transformed spans
+		require.Len(t, agnt.Concentrator.In, 1)
+		in := <-agnt.Concentrator.In
+		require.Len(t, in.Traces, 1)
+		aggr := stats.NewAggregationFromSpan(in.Traces[0].Root, "", stats.PayloadAggregationKey{})
+		assert.EqualValues(404, aggr.StatusCode)
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -150,6 +194,43 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, want.SpansFiltered)
 	})
 
+	t.Run("FilterTags", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.RejectTags = []*config.Tag{{K: "db.instance", V: "cache"}}
+		cfg.RejectTagsRegex = []*config.TagRegex{{K: "http.url", V: regexp.MustCompile("/healthz.*")}}
+		cfg.FilterTagsAnySpan = true
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		now := time.Now()
+		chunk := func(meta map[string]string) *pb.TraceChunk {
+			return testutil.TraceChunkWithSpans([]*pb.Span{
+				{TraceID: 1, SpanID: 1, Start: now.Add(-time.Second).UnixNano(), Duration: int64(time.Millisecond)},
+				{TraceID: 1, SpanID: 2, ParentID: 1, Start: now.Add(-time.Second).UnixNano(), Duration: int64(time.Millisecond), Meta: meta},
+			})
+		}
+
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunks([]*pb.TraceChunk{
+				chunk(map[string]string{"http.url": "http://localhost/healthz"}),
+				chunk(map[string]string{"http.url": "http://localhost/healthz/ready"}),
+				chunk(map[string]string{"db.instance": "cache"}),
+				chunk(map[string]string{"http.url": "http://localhost/api"}),
+			}),
+			Source: want,
+		})
+		assert := assert.New(t)
+		assert.EqualValues(3, want.TracesFiltered)
+		assert.EqualValues(6, want.SpansFiltered)
+		assert.Equal(map[string]int64{
+			"reject_regex:http.url:/healthz.*": 2,
+			"reject:db.instance:cache":         1,
+		}, want.TracesFilteredByRule.TagValues())
+	})
+
 	t.Run("BlacklistPayload", func(t *testing.T) {
 		// Regression test for DataDog/datadog-agent#6500
 		cfg := config.New()
@@ -756,13 +837,79 @@ func TestFilteredByTags(t *testing.T) {
 		},
 	} {
 		t.Run("", func(t *testing.T) {
-			if filteredByTags(&tt.span, tt.require, tt.reject) != tt.drop {
+			if _, drop := filteredByTags([]*pb.Span{&tt.span}, tt.require, tt.reject, nil, nil); drop != tt.drop {
 				t.Fatal()
 			}
 		})
 	}
 }
 
+func TestFilteredByTagsRegex(t *testing.T) {
+	for _, tt := range []struct {
+		require []*config.TagRegex
+		reject  []*config.TagRegex
+		spans   []*pb.Span
+		rule    string
+	}{
+		{
+			require: []*config.TagRegex{{K: "env", V: regexp.MustCompile("^prod-.*$")}},
+			spans:   []*pb.Span{{Meta: map[string]string{"env": "prod-eu"}}},
+		},
+		{
+			require: []*config.TagRegex{{K: "env", V: regexp.MustCompile("^prod-.*$")}},
+			spans:   []*pb.Span{{Meta: map[string]string{"env": "staging"}}},
+			rule:    "require_regex:env:^prod-.*$",
+		},
+		{
+			require: []*config.TagRegex{{K: "env"}},
+			spans:   []*pb.Span{{Meta: map[string]string{"key": "value"}}},
+			rule:    "require_regex:env",
+		},
+		{
+			reject: []*config.TagRegex{{K: "http.url", V: regexp.MustCompile("/healthz.*")}},
+			spans:  []*pb.Span{{Meta: map[string]string{"http.url": "http://host/api"}}},
+		},
+		{
+			reject: []*config.TagRegex{{K: "http.url", V: regexp.MustCompile("/healthz.*")}},
+			spans:  []*pb.Span{{Meta: map[string]string{"http.url": "http://host/healthz/live"}}},
+			rule:   "reject_regex:http.url:/healthz.*",
+		},
+		{
+			// matched on any span
+			reject: []*config.TagRegex{{K: "http.url", V: regexp.MustCompile("/healthz.*")}},
+			spans: []*pb.Span{
+				{Meta: map[string]string{"http.url": "http://host/api"}},
+				{Meta: map[string]string{"http.url": "http://host/healthz"}},
+			},
+			rule: "reject_regex:http.url:/healthz.*",
+		},
+		{
+			require: []*config.TagRegex{{K: "env", V: regexp.MustCompile("^prod-.*$")}},
+			spans: []*pb.Span{
+				{Meta: map[string]string{"key": "value"}},
+				{Meta: map[string]string{"env": "prod-us"}},
+			},
+		},
+		{
+			// reported with the configured glob rather than its compiled expression
+			reject: []*config.TagRegex{{K: "env", V: regexp.MustCompile("^prod-.*$"), Pattern: "prod-*", Glob: true}},
+			spans:  []*pb.Span{{Meta: map[string]string{"env": "prod-eu"}}},
+			rule:   "reject_glob:env:prod-*",
+		},
+		{
+			// unanchored regular expressions match any part of the value
+			require: []*config.TagRegex{{K: "env", V: regexp.MustCompile("prod"), Pattern: "prod"}},
+			spans:   []*pb.Span{{Meta: map[string]string{"env": "eu-prod-1"}}},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			rule, drop := filteredByTags(tt.spans, nil, nil, tt.require, tt.reject)
+			assert.Equal(t, tt.rule, rule)
+			assert.Equal(t, tt.rule != "", drop)
+		})
+	}
+}
+
 func TestClientComputedStats(t *testing.T) {
 	cfg := config.New()
 	cfg.Endpoints[0].APIKey = "test"
@@ -948,6 +1095,7 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		Transformer:       filters.NewTransformer(cfg.TransformRules),
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -1546,7 +1694,99 @@ func TestSampleWithPriorityNone(t *testing.T) {
 	numEvents, keep, _ := agnt.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), traceutil.ProcessedTrace{
 		TraceChunk: testutil.TraceChunkWithSpan(span),
 		Root:       span,
-	})
+	}, nil)
 	assert.True(t, keep) // Score Sampler should keep the trace.
 	assert.EqualValues(t, numEvents, 0)
 }
+
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.DecisionWait = time.Hour
+	cfg.TailSampling.Policies = []config.TailSamplingPolicy{{Name: "errors", Type: "error"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	agnt := NewAgent(ctx, cfg)
+	defer cancel()
+	require.NotNil(t, agnt.TailSampler)
+	agnt.TailSampler.Start()
+
+	now := time.Now()
+	span := func(traceID, spanID, parentID uint64, isError int32) *pb.Span {
+		return &pb.Span{
+			Service:  "service",
+			Name:     "operation",
+			Resource: "resource",
+			TraceID:  traceID,
+			SpanID:   spanID,
+			ParentID: parentID,
+			Start:    now.UnixNano(),
+			Duration: int64(time.Millisecond),
+			Error:    isError,
+		}
+	}
+	process := func(s *pb.Span, priority int32) {
+		agnt.Process(&api.Payload{
+			TracerPayload: &pb.TracerPayload{
+				ContainerID: fmt.Sprintf("container-%d", s.SpanID),
+				Chunks:      []*pb.TraceChunk{testutil.TraceChunkWithSpanAndPriority(s, priority)},
+			},
+			Source: agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	// trace 1 is received in two payloads, the second one holding an error
+	process(span(1, 1, 0, 0), 0)
+	process(span(1, 2, 1, 1), 1)
+	// trace 2 has no error
+	process(span(2, 3, 0, 0), 1)
+	// trace 3 is kept by the user
+	process(span(3, 4, 0, 0), 2)
+
+	require.Len(t, agnt.TraceWriter.In, 1)
+	ss := <-agnt.TraceWriter.In
+	assert.EqualValues(t, 4, ss.TracerPayload.Chunks[0].Spans[0].SpanID)
+
+	// the decision is made on the buffered traces when stopping
+	agnt.TailSampler.Stop()
+	require.Len(t, agnt.TraceWriter.In, 2)
+	for _, spanID := range []uint64{1, 2} {
+		ss := <-agnt.TraceWriter.In
+		assert.Equal(t, fmt.Sprintf("container-%d", spanID), ss.TracerPayload.ContainerID)
+		require.Len(t, ss.TracerPayload.Chunks, 1)
+		chunk := ss.TracerPayload.Chunks[0]
+		assert.EqualValues(t, sampler.PriorityAutoKeep, chunk.Priority)
+		assert.Equal(t, "errors", chunk.Tags[sampler.KeyTailSamplingPolicy])
+		assert.False(t, chunk.DroppedTrace)
+		assert.EqualValues(t, spanID, chunk.Spans[0].SpanID)
+		assert.EqualValues(t, 1, ss.SpanCount)
+	}
+}
+
+func TestTailSamplingPrioritySampler(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.DecisionWait = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	agnt := NewAgent(ctx, cfg)
+	defer cancel()
+	require.NotNil(t, agnt.TailSampler)
+	dynConf := sampler.NewDynamicConfig()
+	agnt.PrioritySampler = sampler.NewPrioritySampler(cfg, dynConf)
+
+	// the rates are updated when the chunks are counted in a new bucket
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(10 * time.Second)} {
+		span := &pb.Span{Service: "service", Name: "operation", TraceID: 1, SpanID: 1}
+		numEvents, keep, _ := agnt.sample(at, info.NewReceiverStats().GetTagStats(info.Tags{}), traceutil.ProcessedTrace{
+			TraceChunk: testutil.TraceChunkWithSpanAndPriority(span, 1),
+			Root:       span,
+		}, &pb.TracerPayload{})
+		// the chunk is buffered by the tail sampler
+		assert.False(t, keep)
+		assert.EqualValues(t, 0, numEvents)
+	}
+
+	// the buffered chunks were still counted by the priority sampler to compute the rates of the tracers
+	assert.Contains(t, dynConf.RateByService.GetNewState("").Rates, "service:service,env:"+cfg.DefaultEnv)
+}
@@ -22,16 +22,20 @@ const (
 	tagElasticBody      = "elasticsearch.body"
 	tagSQLQuery         = "sql.query"
 	tagHTTPURL          = "http.url"
+	tagGraphQLQuery     = "graphql.query"
+	tagGraphQLSource    = "graphql.source"
 )
 
 const (
-	textNonParsable = "Non-parsable SQL query"
+	textNonParsable        = "Non-parsable SQL query"
+	textNonParsableCQL     = "Non-parsable CQL query"
+	textNonParsableGraphQL = "Non-parsable GraphQL query"
 )
 
 func (a *Agent) obfuscateSpan(span *pb.Span) {
 	o := a.obfuscator
 	switch span.Type {
-	case "sql", "cassandra":
+	case "sql":
 		if span.Resource == "" {
 			return
 		}
@@ -59,6 +63,53 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		traceutil.SetMeta(span, tagSQLQuery, oq.Query)
+	case "cassandra":
+		if span.Resource == "" {
+			return
+		}
+		oq, err := o.ObfuscateCQLString(span.Resource)
+		if err != nil {
+			// we have an error, discard the CQL to avoid polluting user resources.
+			log.Debugf("Error parsing CQL query: %v. Resource: %q", err, span.Resource)
+			if span.Meta == nil {
+				span.Meta = make(map[string]string, 1)
+			}
+			if _, ok := span.Meta[tagSQLQuery]; !ok {
+				span.Meta[tagSQLQuery] = textNonParsableCQL
+			}
+			span.Resource = textNonParsableCQL
+			return
+		}
+		span.Resource = oq
+		if span.Meta != nil && span.Meta[tagSQLQuery] != "" {
+			// "sql.query" tag already set by user, do not change it.
+			return
+		}
+		traceutil.SetMeta(span, tagSQLQuery, oq)
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		if span.Resource != "" {
+			oq, err := o.ObfuscateGraphQLString(span.Resource)
+			if err != nil {
+				log.Debugf("Error parsing GraphQL query: %v. Resource: %q", err, span.Resource)
+				oq = textNonParsableGraphQL
+			}
+			span.Resource = oq
+		}
+		for _, k := range []string{tagGraphQLQuery, tagGraphQLSource} {
+			v, ok := span.Meta[k]
+			if !ok || v == "" {
+				continue
+			}
+			oq, err := o.ObfuscateGraphQLString(v)
+			if err != nil {
+				log.Debugf("Error parsing GraphQL query: %v. Tag %s: %q", err, k, v)
+				oq = textNonParsableGraphQL
+			}
+			span.Meta[k] = oq
+		}
 	case "redis":
 		span.Resource = o.QuantizeRedisString(span.Resource)
 		if a.conf.Obfuscation.Redis.Enabled {
@@ -103,7 +154,7 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	o := a.obfuscator
 	switch b.Type {
-	case "sql", "cassandra":
+	case "sql":
 		oq, err := o.ObfuscateSQLString(b.Resource)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
@@ -111,6 +162,25 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		} else {
 			b.Resource = oq.Query
 		}
+	case "cassandra":
+		oq, err := o.ObfuscateCQLString(b.Resource)
+		if err != nil {
+			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
+			b.Resource = textNonParsableCQL
+		} else {
+			b.Resource = oq
+		}
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		oq, err := o.ObfuscateGraphQLString(b.Resource)
+		if err != nil {
+			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
+			b.Resource = textNonParsableGraphQL
+		} else {
+			b.Resource = oq
+		}
 	case "redis":
 		b.Resource = o.QuantizeRedisString(b.Resource)
 	}
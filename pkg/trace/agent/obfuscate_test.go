@@ -63,6 +63,9 @@ func TestObfuscateStatsGroup(t *testing.T) {
 	}{
 		{statsGroup("sql", "SELECT 1 FROM db"), "SELECT ? FROM db"},
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
+		{statsGroup("cassandra", "SELECT * FROM users WHERE id IN (1, 2) USING TTL 60"), "SELECT * FROM users WHERE id IN ( ? ) USING TTL ?"},
+		{statsGroup("cassandra", "SELECT * FROM users WHERE name = 'bob"), textNonParsableCQL},
+		{statsGroup("graphql", `query { user(id: 1) { name } }`), `query { user(id: 1) { name } }`},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
 	} {
@@ -105,6 +108,76 @@ func TestObfuscateDefaults(t *testing.T) {
 	})
 }
 
+func TestObfuscateCQL(t *testing.T) {
+	for _, tt := range []struct {
+		resource, query       string // input
+		outResource, outQuery string
+	}{
+		{
+			resource:    "INSERT INTO ks.users (id, tags) VALUES (1, {'a', 'b'}) USING TTL 3600",
+			outResource: "INSERT INTO ks.users ( id, tags ) VALUES ( ? ) USING TTL ?",
+			outQuery:    "INSERT INTO ks.users ( id, tags ) VALUES ( ? ) USING TTL ?",
+		},
+		{
+			resource:    "SELECT * FROM users WHERE id = 1",
+			query:       "SELECT * FROM users WHERE id = ?",
+			outResource: "SELECT * FROM users WHERE id = ?",
+			outQuery:    "SELECT * FROM users WHERE id = ?",
+		},
+		{
+			resource:    "SELECT * FROM users WHERE name = 'bob",
+			outResource: textNonParsableCQL,
+			outQuery:    textNonParsableCQL,
+		},
+	} {
+		span := &pb.Span{Type: "cassandra", Resource: tt.resource}
+		if tt.query != "" {
+			span.Meta = map[string]string{"sql.query": tt.query}
+		}
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, tt.outResource, span.Resource)
+		assert.Equal(t, tt.outQuery, span.Meta["sql.query"])
+	}
+}
+
+func TestObfuscateGraphQL(t *testing.T) {
+	query := `query GetUser($id: ID!) { user(id: $id, name: "bob") { name } }`
+	newSpan := func() *pb.Span {
+		return &pb.Span{
+			Type:     "graphql",
+			Resource: query,
+			Meta:     map[string]string{"graphql.query": query, "graphql.source": "{"},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		span := newSpan()
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, query, span.Resource)
+		assert.Equal(t, query, span.Meta["graphql.query"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.conf.Obfuscation.GraphQL.Enabled = true
+		span := newSpan()
+		agnt.obfuscateSpan(span)
+		out := "query GetUser($id: ID!) { user(id: $id name: ?) { name } }"
+		assert.Equal(t, out, span.Resource)
+		assert.Equal(t, out, span.Meta["graphql.query"])
+		assert.Equal(t, textNonParsableGraphQL, span.Meta["graphql.source"])
+
+		group := &pb.ClientGroupedStats{Type: "graphql", Resource: query}
+		agnt.obfuscateStatsGroup(group)
+		assert.Equal(t, out, group.Resource)
+	})
+}
+
 func agentWithDefaults() (agnt *Agent, stop func()) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	cfg := config.New()
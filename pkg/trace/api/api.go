@@ -71,9 +71,13 @@ type HTTPReceiver struct {
 	server         *http.Server
 	statsProcessor StatsProcessor
 	appsecHandler  http.Handler
+	otlp           *OTLPReceiver // converts the traces received by the third-party intakes
 
 	rateLimiterResponse int // HTTP status code when refusing
 
+	captureMu sync.RWMutex
+	capture   *capture // ongoing capture of the received payloads, if any
+
 	wg   sync.WaitGroup // waits for all requests to be processed
 	exit chan struct{}
 }
@@ -97,6 +101,7 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 		conf:           conf,
 		dynConf:        dynConf,
 		appsecHandler:  appsecHandler,
+		otlp:           NewOTLPReceiver(out, conf),
 
 		rateLimiterResponse: rateLimiterResponse,
 
@@ -294,6 +299,7 @@ func (r *HTTPReceiver) Stop() error {
 		return err
 	}
 	r.wg.Wait()
+	r.StopCapture()
 	close(r.out)
 	return nil
 }
@@ -306,6 +312,8 @@ func (r *HTTPReceiver) handleWithVersion(v Version, f func(Version, http.Respons
 			return
 		}
 
+		r.captureRequest(req)
+
 		// TODO(x): replace with http.MaxBytesReader?
 		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 
@@ -492,6 +500,7 @@ type StatsProcessor interface {
 func (r *HTTPReceiver) handleStats(w http.ResponseWriter, req *http.Request) {
 	defer timing.Since("datadog.trace_agent.receiver.stats_process_ms", time.Now())
 
+	r.captureRequest(req)
 	ts := r.tagStats(V07, req.Header)
 	rd := apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
 	req.Header.Set("Accept", "application/msgpack")
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+)
+
+// capture records the requests received by the HTTPReceiver to a capture file,
+// which can later be replayed using replay.Replay.
+type capture struct {
+	path  string
+	file  *os.File
+	w     *replay.Writer
+	start time.Time
+	timer *time.Timer
+
+	mu  sync.Mutex // guards below fields
+	n   int        // number of recorded requests
+	err error      // first write error, or os.ErrClosed once stopped
+}
+
+// StartCapture starts recording the trace and stats payloads received by r to the file at
+// path, along with their headers. The capture stops after d, or when r is stopped if d is zero.
+func (r *HTTPReceiver) StartCapture(path string, d time.Duration) error {
+	r.captureMu.Lock()
+	defer r.captureMu.Unlock()
+	if r.capture != nil {
+		return errors.New("a capture is already in progress")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w, err := replay.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c := &capture{path: path, file: f, w: w, start: time.Now()}
+	if d > 0 {
+		c.timer = time.AfterFunc(d, func() { r.stopCapture(c) })
+	}
+	r.capture = c
+	log.Infof("Capturing the received payloads to %s.", path)
+	return nil
+}
+
+// StopCapture stops the capture started with StartCapture, if any.
+func (r *HTTPReceiver) StopCapture() {
+	r.captureMu.Lock()
+	c := r.capture
+	r.captureMu.Unlock()
+	r.stopCapture(c)
+}
+
+// stopCapture stops c, if it is the ongoing capture.
+func (r *HTTPReceiver) stopCapture(c *capture) {
+	r.captureMu.Lock()
+	if c == nil || r.capture != c {
+		r.captureMu.Unlock()
+		return
+	}
+	r.capture = nil
+	r.captureMu.Unlock()
+	c.stop()
+}
+
+// captureRequest records req to the ongoing capture, if any. The body of req is read
+// and replaced by an equivalent reader.
+func (r *HTTPReceiver) captureRequest(req *http.Request) {
+	r.captureMu.RLock()
+	c := r.capture
+	r.captureMu.RUnlock()
+	if c == nil {
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, r.conf.MaxRequestBytes+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	if err != nil || int64(len(body)) > r.conf.MaxRequestBytes {
+		// the request will be rejected by its handler
+		return
+	}
+	c.record(&replay.Record{
+		Offset: time.Since(c.start),
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+}
+
+// record writes rec to the capture file.
+func (c *capture) record(rec *replay.Record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err := c.w.Write(rec); err != nil {
+		log.Errorf("Error writing to capture file %s, no more payloads will be captured: %v", c.path, err)
+		c.err = err
+		return
+	}
+	c.n++
+}
+
+// stop terminates the capture, flushing and closing its file.
+func (c *capture) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// requests which were being handled while stopping are not recorded.
+	c.err = os.ErrClosed
+	err := c.w.Close()
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Errorf("Error closing capture file %s: %v", c.path, err)
+		return
+	}
+	log.Infof("Captured %d payloads to %s.", c.n, c.path)
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.dog.zst")
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	bts, err := testutil.GetTestTraces(3, 2, true).MarshalMsg(nil)
+	require.NoError(t, err)
+	post := func() {
+		req, err := http.NewRequest("PUT", server.URL+"/v0.4/traces", bytes.NewReader(bts))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set(headerLang, "go")
+		req.Header.Set(headerTracerVersion, "1.38.0")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	post() // not captured
+	require.NoError(t, r.StartCapture(path, 0))
+	assert.Error(t, r.StartCapture(path, 0))
+	post()
+	r.StopCapture()
+	post() // not captured
+
+	// all requests were processed, the captured one included
+	for i := 0; i < 3; i++ {
+		select {
+		case p := <-r.out:
+			assert.Len(t, p.Chunks(), 3)
+			assert.Equal(t, "go", p.Source.Lang)
+		case <-time.After(time.Second):
+			t.Fatal("no payload received")
+		}
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	cr, err := replay.NewReader(f)
+	require.NoError(t, err)
+	rec, err := cr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", rec.Method)
+	assert.Equal(t, "/v0.4/traces", rec.Path)
+	assert.Equal(t, "go", rec.Header.Get(headerLang))
+	assert.Equal(t, "1.38.0", rec.Header.Get(headerTracerVersion))
+	assert.Equal(t, bts, rec.Body)
+	_, err = cr.Next()
+	assert.Equal(t, io.EOF, err)
+	cr.Close()
+
+	// the captured payload is processed again when replayed
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	stats, err := replay.Replay(context.Background(), f, server.URL, 0)
+	require.NoError(t, err)
+	assert.Equal(t, replay.Stats{Sent: 1}, stats)
+	select {
+	case p := <-r.out:
+		assert.Len(t, p.Chunks(), 3)
+		assert.Equal(t, "1.38.0", p.Source.TracerVersion)
+	case <-time.After(time.Second):
+		t.Fatal("no payload received")
+	}
+}
+
+func TestCaptureDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.dog.zst")
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	require.NoError(t, r.StartCapture(path, time.Millisecond))
+	assert.Eventually(t, func() bool {
+		r.captureMu.RLock()
+		defer r.captureMu.RUnlock()
+		return r.capture == nil
+	}, time.Second, time.Millisecond)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	cr, err := replay.NewReader(f)
+	require.NoError(t, err)
+	defer cr.Close()
+	_, err = cr.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -116,4 +116,16 @@ var endpoints = []Endpoint{
 		Pattern: "/debugger/v1/input",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.debuggerProxyHandler() },
 	},
+	{
+		// Zipkin v2 collector API
+		Pattern: "/api/v2/spans",
+		Handler: func(r *HTTPReceiver) http.Handler { return r.handleIntake(zipkinIntake) },
+		Hidden:  true,
+	},
+	{
+		// Jaeger collector Thrift over HTTP API
+		Pattern: "/api/traces",
+		Handler: func(r *HTTPReceiver) http.Handler { return r.handleIntake(jaegerIntake) },
+		Hidden:  true,
+	},
 }
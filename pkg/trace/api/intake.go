@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// traceIntake specifies an endpoint receiving traces in a third-party format, such as Zipkin or Jaeger.
+// The traces are converted to OpenTelemetry traces and then processed like the traces received by the
+// OTLPReceiver.
+type traceIntake struct {
+	// name specifies the name of the format. It is used as the instrumentation scope of the spans
+	// and as the tracer of the payloads.
+	name string
+
+	// endpointVersion specifies the endpoint version reported in the receiver stats.
+	endpointVersion string
+
+	// decode decodes the body of a request having the given media type.
+	decode func(mediaType string, body []byte) (ptrace.Traces, error)
+}
+
+// handleIntake returns an http.Handler which receives traces in the format of the given intake.
+func (r *HTTPReceiver) handleIntake(intake traceIntake) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer timing.Since(fmt.Sprintf("datadog.trace_agent.receiver.%s_process_ms", intake.name), time.Now())
+		ts := r.Stats.GetTagStats(info.Tags{
+			Lang:            req.Header.Get(headerLang),
+			EndpointVersion: intake.endpointVersion,
+		})
+		tags := []string{"handler:traces", "v:" + intake.endpointVersion}
+
+		var rd io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == "gzip" {
+			gzipr, err := gzip.NewReader(rd)
+			if err != nil {
+				httpDecodingError(err, tags, w)
+				atomic.AddInt64(&ts.TracesDropped.DecodingError, 1)
+				return
+			}
+			defer gzipr.Close()
+			rd = gzipr
+		}
+		lr := apiutil.NewLimitedReader(ioutil.NopCloser(rd), r.conf.MaxRequestBytes)
+		body, err := ioutil.ReadAll(lr)
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			if err == apiutil.ErrLimitedReaderLimitReached {
+				atomic.AddInt64(&ts.TracesDropped.PayloadTooLarge, 1)
+			} else {
+				atomic.AddInt64(&ts.TracesDropped.EOF, 1)
+			}
+			return
+		}
+		traces, err := intake.decode(getMediaType(req), body)
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			atomic.AddInt64(&ts.TracesDropped.DecodingError, 1)
+			log.Errorf("Cannot decode %s traces payload: %v", intake.name, err)
+			return
+		}
+
+		tracen := traceCountFromOTLP(traces)
+		if r.rateLimited(tracen) {
+			// this payload can not be accepted
+			w.WriteHeader(r.rateLimiterResponse)
+			atomic.AddInt64(&ts.PayloadRefused, 1)
+			return
+		}
+
+		for i := 0; i < traces.ResourceSpans().Len(); i++ {
+			r.otlp.receiveResourceSpans(traces.ResourceSpans().At(i), req.Header, intake.endpointVersion, intake.name)
+		}
+		atomic.AddInt64(&ts.TracesReceived, tracen)
+		atomic.AddInt64(&ts.TracesBytes, lr.Count)
+		atomic.AddInt64(&ts.PayloadAccepted, 1)
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// traceCountFromOTLP returns the number of distinct traces the spans of traces belong to.
+func traceCountFromOTLP(traces ptrace.Traces) int64 {
+	ids := make(map[uint64]struct{})
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		libspans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < libspans.Len(); j++ {
+			spans := libspans.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				ids[traceIDToUint64(spans.At(k).TraceID().Bytes())] = struct{}{}
+			}
+		}
+	}
+	return int64(len(ids))
+}
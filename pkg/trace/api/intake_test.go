@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleIntake(t *testing.T) {
+	post := func(r *HTTPReceiver, intake traceIntake, contentType string, body []byte) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		r.handleIntake(intake).ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("zipkin", func(t *testing.T) {
+		assert := assert.New(t)
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		rr := post(r, zipkinIntake, "application/json", zipkinTestJSON)
+		assert.Equal(http.StatusAccepted, rr.Code)
+
+		// one payload per service
+		services := make(map[string]int)
+		for i := 0; i < 2; i++ {
+			p := <-r.out
+			assert.Equal("zipkin_v2", p.Source.EndpointVersion)
+			assert.Equal("zipkin-", p.TracerPayload.TracerVersion)
+			require.Len(t, p.TracerPayload.Chunks, 1)
+			for _, span := range p.TracerPayload.Chunks[0].Spans {
+				assert.Equal(uint64(0x5af7183fb1d4cf5f), span.TraceID)
+				services[span.Service]++
+			}
+		}
+		// the client span is attributed to the remote service, as with OTLP
+		assert.Equal(map[string]int{"frontend": 1, "backend": 2}, services)
+		assert.Len(r.out, 0)
+
+		ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: "zipkin_v2"})
+		assert.EqualValues(1, ts.TracesReceived)
+		assert.EqualValues(len(zipkinTestJSON), ts.TracesBytes)
+		assert.EqualValues(1, ts.PayloadAccepted)
+	})
+
+	t.Run("jaeger", func(t *testing.T) {
+		assert := assert.New(t)
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		rr := post(r, jaegerIntake, "application/x-thrift", jaegerTestBatch())
+		assert.Equal(http.StatusAccepted, rr.Code)
+
+		p := <-r.out
+		assert.Equal("jaeger_thrift", p.Source.EndpointVersion)
+		assert.Equal("go", p.TracerPayload.LanguageName)
+		assert.Equal("jaeger-2.30.0", p.TracerPayload.TracerVersion)
+		assert.Equal("my-host", p.TracerPayload.Hostname)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		spans := p.TracerPayload.Chunks[0].Spans
+		require.Len(t, spans, 2)
+		assert.Equal("frontend", spans[0].Service)
+		assert.Equal("HTTP GET /dispatch", spans[0].Resource)
+		assert.EqualValues(1, spans[0].Error)
+		assert.Equal(spans[0].SpanID, spans[1].ParentID)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(zipkinTestJSON)
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/", &buf)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		r.handleIntake(zipkinIntake).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Len(t, r.out, 2)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		rr := post(r, zipkinIntake, "application/json", []byte(`[{"traceId": "xyz"}]`))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Len(t, r.out, 0)
+		ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: "zipkin_v2"})
+		assert.EqualValues(t, 1, ts.TracesDropped.DecodingError)
+	})
+
+	t.Run("too-large", func(t *testing.T) {
+		conf := newTestReceiverConfig()
+		conf.MaxRequestBytes = 10
+		r := newTestReceiverFromConfig(conf)
+		rr := post(r, zipkinIntake, "application/json", zipkinTestJSON)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Len(t, r.out, 0)
+		ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: "zipkin_v2"})
+		assert.EqualValues(t, 1, ts.TracesDropped.PayloadTooLarge)
+	})
+
+	t.Run("rate-limited", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		r.RateLimiter.SetTargetRate(0.000001) // Make sure we sample aggressively
+
+		// the first payload is always let through
+		rr := post(r, zipkinIntake, "application/json", zipkinTestJSON)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Len(t, r.out, 2)
+
+		rr = post(r, zipkinIntake, "application/json", zipkinTestJSON)
+		assert.Equal(t, r.rateLimiterResponse, rr.Code)
+		assert.Len(t, r.out, 2)
+		ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: "zipkin_v2"})
+		assert.EqualValues(t, 1, ts.PayloadRefused)
+	})
+}
+
+func TestTraceCountFromOTLP(t *testing.T) {
+	traces, err := jaegerIntake.decode("application/x-thrift", jaegerTestBatch())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, traceCountFromOTLP(traces))
+}
@@ -0,0 +1,588 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// jaegerIntakeName is the name of the Jaeger intake.
+const jaegerIntakeName = "jaeger"
+
+// jaegerIntake receives Jaeger batches encoded with the Thrift binary protocol, as sent to the
+// /api/traces endpoint of the Jaeger collector.
+var jaegerIntake = traceIntake{
+	name:            jaegerIntakeName,
+	endpointVersion: "jaeger_thrift",
+	decode: func(_ string, body []byte) (ptrace.Traces, error) {
+		r := thriftReader(body)
+		batch, err := r.jaegerBatch()
+		if err != nil {
+			return ptrace.NewTraces(), err
+		}
+		return jaegerToTraces(batch), nil
+	},
+}
+
+// jaegerBatch, jaegerProcess, jaegerSpan, jaegerSpanRef, jaegerTag and jaegerLog hold the
+// structures of the Jaeger Thrift model, as defined in
+// https://github.com/jaegertracing/jaeger-idl/blob/main/thrift/jaeger.thrift.
+type jaegerBatch struct {
+	process jaegerProcess
+	spans   []jaegerSpan
+}
+
+type jaegerProcess struct {
+	serviceName string
+	tags        []jaegerTag
+}
+
+type jaegerSpan struct {
+	traceIDLow    int64
+	traceIDHigh   int64
+	spanID        int64
+	parentSpanID  int64
+	operationName string
+	references    []jaegerSpanRef
+	startTime     int64 // microseconds
+	duration      int64 // microseconds
+	tags          []jaegerTag
+	logs          []jaegerLog
+}
+
+type jaegerSpanRef struct {
+	refType     int32
+	traceIDLow  int64
+	traceIDHigh int64
+	spanID      int64
+}
+
+type jaegerTag struct {
+	key     string
+	vType   int32
+	vStr    string
+	vDouble float64
+	vBool   bool
+	vLong   int64
+	vBinary []byte
+}
+
+type jaegerLog struct {
+	timestamp int64 // microseconds
+	fields    []jaegerTag
+}
+
+// Jaeger tag value types.
+const (
+	jaegerTagString = iota
+	jaegerTagDouble
+	jaegerTagBool
+	jaegerTagLong
+	jaegerTagBinary
+)
+
+// jaegerRefChildOf is the type of the references to the parent span.
+const jaegerRefChildOf = 0
+
+// jaegerKinds maps the values of the "span.kind" tag to OpenTelemetry span kinds.
+var jaegerKinds = map[string]ptrace.SpanKind{
+	"client":   ptrace.SpanKindClient,
+	"server":   ptrace.SpanKindServer,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+	"internal": ptrace.SpanKindInternal,
+}
+
+// jaegerErrorLogFields maps the fields of the logs of errors to the attributes of exception events.
+var jaegerErrorLogFields = map[string]string{
+	"message":      semconv.AttributeExceptionMessage,
+	"error.object": semconv.AttributeExceptionMessage,
+	"error.kind":   semconv.AttributeExceptionType,
+	"stack":        semconv.AttributeExceptionStacktrace,
+}
+
+// jaegerToTraces converts the given Jaeger batch to OpenTelemetry traces.
+func jaegerToTraces(batch *jaegerBatch) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	rspans := traces.ResourceSpans().AppendEmpty()
+	rattrs := rspans.Resource().Attributes()
+	if batch.process.serviceName != "" {
+		rattrs.InsertString(semconv.AttributeServiceName, batch.process.serviceName)
+	}
+	for _, tag := range batch.process.tags {
+		switch tag.key {
+		case "hostname":
+			rattrs.UpsertString(semconv.AttributeHostName, tag.vStr)
+		case "jaeger.version":
+			// e.g. "Go-2.30.0"
+			if i := strings.IndexByte(tag.vStr, '-'); i > 0 {
+				rattrs.UpsertString(semconv.AttributeTelemetrySDKLanguage, strings.ToLower(tag.vStr[:i]))
+				rattrs.UpsertString(semconv.AttributeTelemetrySDKVersion, tag.vStr[i+1:])
+			}
+			rattrs.Upsert(tag.key, tag.value())
+		default:
+			rattrs.Upsert(tag.key, tag.value())
+		}
+	}
+	libspans := rspans.ScopeSpans().AppendEmpty()
+	libspans.Scope().SetName(jaegerIntakeName)
+	for _, js := range batch.spans {
+		jaegerToSpan(js, libspans.Spans().AppendEmpty())
+	}
+	return traces
+}
+
+// jaegerToSpan converts the Jaeger span js into span.
+func jaegerToSpan(js jaegerSpan, span ptrace.Span) {
+	var traceID [16]byte
+	binary.BigEndian.PutUint64(traceID[:8], uint64(js.traceIDHigh))
+	binary.BigEndian.PutUint64(traceID[8:], uint64(js.traceIDLow))
+	span.SetTraceID(pcommon.NewTraceID(traceID))
+	span.SetSpanID(jaegerSpanID(js.spanID))
+	parentID := js.parentSpanID
+	if parentID == 0 {
+		for _, ref := range js.references {
+			if ref.refType == jaegerRefChildOf && ref.traceIDLow == js.traceIDLow && ref.traceIDHigh == js.traceIDHigh {
+				parentID = ref.spanID
+				break
+			}
+		}
+	}
+	span.SetParentSpanID(jaegerSpanID(parentID))
+	span.SetName(js.operationName)
+	span.SetStartTimestamp(pcommon.Timestamp(js.startTime * 1000))
+	span.SetEndTimestamp(pcommon.Timestamp((js.startTime + js.duration) * 1000))
+
+	attrs := span.Attributes()
+	for _, tag := range js.tags {
+		switch tag.key {
+		case "span.kind":
+			if kind, ok := jaegerKinds[tag.vStr]; ok {
+				span.SetKind(kind)
+			}
+		case "error":
+			if tag.vBool || tag.vStr == "true" {
+				span.Status().SetCode(ptrace.StatusCodeError)
+			}
+		case "otel.status_code":
+			switch tag.vStr {
+			case "ERROR":
+				span.Status().SetCode(ptrace.StatusCodeError)
+			case "OK":
+				span.Status().SetCode(ptrace.StatusCodeOk)
+			}
+		case "otel.status_description":
+			span.Status().SetMessage(tag.vStr)
+		default:
+			attrs.Upsert(tag.key, tag.value())
+		}
+	}
+	for _, l := range js.logs {
+		event := span.Events().AppendEmpty()
+		event.SetTimestamp(pcommon.Timestamp(l.timestamp * 1000))
+		var isError bool
+		for _, field := range l.fields {
+			if field.key == "event" && field.vType == jaegerTagString {
+				isError = field.vStr == "error"
+				event.SetName(field.vStr)
+			}
+		}
+		if isError {
+			// OpenTracing logs errors as "error" events, which are exceptions for OpenTelemetry.
+			event.SetName("exception")
+		}
+		eattrs := event.Attributes()
+		for _, field := range l.fields {
+			if field.key == "event" {
+				continue
+			}
+			if k, ok := jaegerErrorLogFields[field.key]; ok && isError {
+				eattrs.UpsertString(k, field.value().AsString())
+				continue
+			}
+			eattrs.Upsert(field.key, field.value())
+		}
+	}
+}
+
+func jaegerSpanID(id int64) pcommon.SpanID {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id))
+	return pcommon.NewSpanID(b)
+}
+
+// value returns the value of the tag as an OpenTelemetry attribute value.
+func (t *jaegerTag) value() pcommon.Value {
+	switch t.vType {
+	case jaegerTagDouble:
+		return pcommon.NewValueDouble(t.vDouble)
+	case jaegerTagBool:
+		return pcommon.NewValueBool(t.vBool)
+	case jaegerTagLong:
+		return pcommon.NewValueInt(t.vLong)
+	case jaegerTagBinary:
+		return pcommon.NewValueString(base64.StdEncoding.EncodeToString(t.vBinary))
+	default:
+		return pcommon.NewValueString(t.vStr)
+	}
+}
+
+// Thrift binary protocol types.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// thriftMaxDepth is the maximum nesting of the skipped structures.
+const thriftMaxDepth = 64
+
+var errThriftInvalid = errors.New("invalid thrift payload")
+
+// thriftReader reads the values of a buffer encoded with the Thrift binary protocol.
+type thriftReader []byte
+
+func (r *thriftReader) read(n int) ([]byte, error) {
+	if n < 0 || len(*r) < n {
+		return nil, errThriftInvalid
+	}
+	b := (*r)[:n]
+	*r = (*r)[n:]
+	return b, nil
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	b, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) i16() (int16, error) {
+	b, err := r.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) i32() (int32, error) {
+	b, err := r.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) i64() (int64, error) {
+	b, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) double() (float64, error) {
+	v, err := r.i64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (r *thriftReader) bool() (bool, error) {
+	b, err := r.byte()
+	return b != 0, err
+}
+
+func (r *thriftReader) binary() ([]byte, error) {
+	n, err := r.i32()
+	if err != nil {
+		return nil, err
+	}
+	return r.read(int(n))
+}
+
+func (r *thriftReader) string() (string, error) {
+	b, err := r.binary()
+	return string(b), err
+}
+
+// readStruct calls fn with the ID and type of each field of a struct. fn must read the value of
+// the field, or skip it.
+func (r *thriftReader) readStruct(fn func(id int16, typ byte) error) error {
+	for {
+		typ, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.i16()
+		if err != nil {
+			return err
+		}
+		if err := fn(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList calls fn for each element of a list, which must have elements of the given type.
+func (r *thriftReader) readList(elemType byte, fn func() error) error {
+	typ, err := r.byte()
+	if err != nil {
+		return err
+	}
+	n, err := r.i32()
+	if err != nil {
+		return err
+	}
+	if typ != elemType || n < 0 || int(n) > len(*r) {
+		return errThriftInvalid
+	}
+	for i := 0; i < int(n); i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte, depth int) error {
+	if depth > thriftMaxDepth {
+		return errThriftInvalid
+	}
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.read(1)
+	case thriftI16:
+		_, err = r.read(2)
+	case thriftI32:
+		_, err = r.read(4)
+	case thriftDouble, thriftI64:
+		_, err = r.read(8)
+	case thriftString:
+		_, err = r.binary()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error { return r.skip(typ, depth+1) })
+	case thriftMap:
+		var kt, vt byte
+		var n int32
+		if kt, err = r.byte(); err != nil {
+			return err
+		}
+		if vt, err = r.byte(); err != nil {
+			return err
+		}
+		if n, err = r.i32(); err != nil {
+			return err
+		}
+		if n < 0 || int(n) > len(*r) {
+			return errThriftInvalid
+		}
+		for i := 0; i < int(n) && err == nil; i++ {
+			if err = r.skip(kt, depth+1); err == nil {
+				err = r.skip(vt, depth+1)
+			}
+		}
+	case thriftSet, thriftList:
+		var et byte
+		var n int32
+		if et, err = r.byte(); err != nil {
+			return err
+		}
+		if n, err = r.i32(); err != nil {
+			return err
+		}
+		if n < 0 || int(n) > len(*r) {
+			return errThriftInvalid
+		}
+		for i := 0; i < int(n) && err == nil; i++ {
+			err = r.skip(et, depth+1)
+		}
+	default:
+		return fmt.Errorf("unknown thrift type %d", typ)
+	}
+	return err
+}
+
+// field reads the value of a field having the type typ with read, when it has the expected
+// type, and skips it otherwise.
+func (r *thriftReader) field(typ, expected byte, read func() error) error {
+	if typ != expected {
+		return r.skip(typ, 0)
+	}
+	return read()
+}
+
+func (r *thriftReader) jaegerBatch() (*jaegerBatch, error) {
+	var batch jaegerBatch
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1:
+			return r.field(typ, thriftStruct, func() error { return r.jaegerProcess(&batch.process) })
+		case 2:
+			return r.field(typ, thriftList, func() error {
+				return r.readList(thriftStruct, func() error {
+					var span jaegerSpan
+					if err := r.jaegerSpan(&span); err != nil {
+						return err
+					}
+					batch.spans = append(batch.spans, span)
+					return nil
+				})
+			})
+		default:
+			return r.skip(typ, 0)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(*r) != 0 {
+		return nil, errThriftInvalid
+	}
+	return &batch, nil
+}
+
+func (r *thriftReader) jaegerProcess(p *jaegerProcess) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch id {
+		case 1:
+			return r.field(typ, thriftString, func() error { p.serviceName, err = r.string(); return err })
+		case 2:
+			return r.field(typ, thriftList, func() error { p.tags, err = r.jaegerTags(); return err })
+		default:
+			return r.skip(typ, 0)
+		}
+	})
+}
+
+func (r *thriftReader) jaegerSpan(s *jaegerSpan) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch id {
+		case 1:
+			return r.field(typ, thriftI64, func() error { s.traceIDLow, err = r.i64(); return err })
+		case 2:
+			return r.field(typ, thriftI64, func() error { s.traceIDHigh, err = r.i64(); return err })
+		case 3:
+			return r.field(typ, thriftI64, func() error { s.spanID, err = r.i64(); return err })
+		case 4:
+			return r.field(typ, thriftI64, func() error { s.parentSpanID, err = r.i64(); return err })
+		case 5:
+			return r.field(typ, thriftString, func() error { s.operationName, err = r.string(); return err })
+		case 6:
+			return r.field(typ, thriftList, func() error {
+				return r.readList(thriftStruct, func() error {
+					var ref jaegerSpanRef
+					if err := r.jaegerSpanRef(&ref); err != nil {
+						return err
+					}
+					s.references = append(s.references, ref)
+					return nil
+				})
+			})
+		case 8:
+			return r.field(typ, thriftI64, func() error { s.startTime, err = r.i64(); return err })
+		case 9:
+			return r.field(typ, thriftI64, func() error { s.duration, err = r.i64(); return err })
+		case 10:
+			return r.field(typ, thriftList, func() error { s.tags, err = r.jaegerTags(); return err })
+		case 11:
+			return r.field(typ, thriftList, func() error {
+				return r.readList(thriftStruct, func() error {
+					var l jaegerLog
+					err := r.readStruct(func(id int16, typ byte) error {
+						var err error
+						switch id {
+						case 1:
+							return r.field(typ, thriftI64, func() error { l.timestamp, err = r.i64(); return err })
+						case 2:
+							return r.field(typ, thriftList, func() error { l.fields, err = r.jaegerTags(); return err })
+						default:
+							return r.skip(typ, 0)
+						}
+					})
+					s.logs = append(s.logs, l)
+					return err
+				})
+			})
+		default:
+			return r.skip(typ, 0)
+		}
+	})
+}
+
+func (r *thriftReader) jaegerSpanRef(ref *jaegerSpanRef) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch id {
+		case 1:
+			return r.field(typ, thriftI32, func() error { ref.refType, err = r.i32(); return err })
+		case 2:
+			return r.field(typ, thriftI64, func() error { ref.traceIDLow, err = r.i64(); return err })
+		case 3:
+			return r.field(typ, thriftI64, func() error { ref.traceIDHigh, err = r.i64(); return err })
+		case 4:
+			return r.field(typ, thriftI64, func() error { ref.spanID, err = r.i64(); return err })
+		default:
+			return r.skip(typ, 0)
+		}
+	})
+}
+
+func (r *thriftReader) jaegerTags() ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var t jaegerTag
+		err := r.readStruct(func(id int16, typ byte) error {
+			var err error
+			switch id {
+			case 1:
+				return r.field(typ, thriftString, func() error { t.key, err = r.string(); return err })
+			case 2:
+				return r.field(typ, thriftI32, func() error { t.vType, err = r.i32(); return err })
+			case 3:
+				return r.field(typ, thriftString, func() error { t.vStr, err = r.string(); return err })
+			case 4:
+				return r.field(typ, thriftDouble, func() error { t.vDouble, err = r.double(); return err })
+			case 5:
+				return r.field(typ, thriftBool, func() error { t.vBool, err = r.bool(); return err })
+			case 6:
+				return r.field(typ, thriftI64, func() error { t.vLong, err = r.i64(); return err })
+			case 7:
+				return r.field(typ, thriftString, func() error { t.vBinary, err = r.binary(); return err })
+			default:
+				return r.skip(typ, 0)
+			}
+		})
+		tags = append(tags, t)
+		return err
+	})
+	return tags, err
+}
@@ -0,0 +1,208 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// thriftWriter encodes values with the Thrift binary protocol.
+type thriftWriter []byte
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(id))
+	*w = append(append(*w, typ), b[:]...)
+}
+
+func (w *thriftWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	*w = append(*w, b[:]...)
+}
+
+func (w *thriftWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	*w = append(*w, b[:]...)
+}
+
+func (w *thriftWriter) stop() { *w = append(*w, thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	w.uint32(uint32(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	w.uint64(uint64(v))
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(thriftString, id)
+	w.uint32(uint32(len(v)))
+	*w = append(*w, v...)
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, n int) {
+	w.field(thriftList, id)
+	*w = append(*w, elemType)
+	w.uint32(uint32(n))
+}
+
+func (w *thriftWriter) tag(key string, vType int32, v interface{}) {
+	w.string(1, key)
+	w.i32(2, vType)
+	switch v := v.(type) {
+	case string:
+		w.string(3, v)
+	case float64:
+		w.field(thriftDouble, 4)
+		w.uint64(math.Float64bits(v))
+	case bool:
+		w.field(thriftBool, 5)
+		if v {
+			*w = append(*w, 1)
+		} else {
+			*w = append(*w, 0)
+		}
+	case int64:
+		w.i64(6, v)
+	}
+	w.stop()
+}
+
+// jaegerTestBatch returns a Jaeger batch holding a server span and its child, encoded with
+// the Thrift binary protocol.
+func jaegerTestBatch() []byte {
+	var w thriftWriter
+	// process
+	w.field(thriftStruct, 1)
+	w.string(1, "frontend")
+	w.list(2, thriftStruct, 2)
+	w.tag("hostname", jaegerTagString, "my-host")
+	w.tag("jaeger.version", jaegerTagString, "Go-2.30.0")
+	w.stop()
+	// spans
+	w.list(2, thriftStruct, 2)
+	// server span
+	w.i64(1, 0x0102030405060708)
+	w.i64(2, 0x1112131415161718)
+	w.i64(3, 0x2122232425262728)
+	w.i64(4, 0)
+	w.string(5, "HTTP GET /dispatch")
+	w.i32(7, 1)
+	w.i64(8, 1556604172355737)
+	w.i64(9, 1431)
+	w.list(10, thriftStruct, 4)
+	w.tag("span.kind", jaegerTagString, "server")
+	w.tag("error", jaegerTagBool, true)
+	w.tag("http.status_code", jaegerTagLong, int64(500))
+	w.tag("sampler.param", jaegerTagDouble, 0.5)
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1556604172355800)
+	w.list(2, thriftStruct, 3)
+	w.tag("event", jaegerTagString, "error")
+	w.tag("message", jaegerTagString, "boom")
+	w.tag("error.kind", jaegerTagString, "*errors.errorString")
+	w.stop()
+	w.stop()
+	// child span, referring to its parent
+	w.i64(1, 0x0102030405060708)
+	w.i64(2, 0x1112131415161718)
+	w.i64(3, 0x3132333435363738)
+	w.string(5, "SQL SELECT")
+	w.list(6, thriftStruct, 1)
+	w.i32(1, jaegerRefChildOf)
+	w.i64(2, 0x0102030405060708)
+	w.i64(3, 0x1112131415161718)
+	w.i64(4, 0x2122232425262728)
+	w.stop()
+	w.i64(8, 1556604172355800)
+	w.i64(9, 1000)
+	w.list(10, thriftStruct, 1)
+	w.tag("span.kind", jaegerTagString, "client")
+	// unknown field
+	w.field(thriftMap, 42)
+	w = append(w, thriftString, thriftI32, 0, 0, 0, 1, 0, 0, 0, 1, 'a', 0, 0, 0, 1)
+	w.stop()
+	w.stop()
+	return w
+}
+
+func TestJaegerDecode(t *testing.T) {
+	assert := assert.New(t)
+	traces, err := jaegerIntake.decode("application/x-thrift", jaegerTestBatch())
+	require.NoError(t, err)
+	require.Equal(t, 1, traces.ResourceSpans().Len())
+	assert.Equal(2, traces.SpanCount())
+
+	rspans := traces.ResourceSpans().At(0)
+	assert.Equal(map[string]interface{}{
+		semconv.AttributeServiceName:          "frontend",
+		semconv.AttributeHostName:             "my-host",
+		semconv.AttributeTelemetrySDKLanguage: "go",
+		semconv.AttributeTelemetrySDKVersion:  "2.30.0",
+		"jaeger.version":                      "Go-2.30.0",
+	}, rspans.Resource().Attributes().AsRaw())
+	assert.Equal("jaeger", rspans.ScopeSpans().At(0).Scope().Name())
+
+	spans := rspans.ScopeSpans().At(0).Spans()
+	server := spans.At(0)
+	traceID := pcommon.NewTraceID([16]byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})
+	assert.Equal(traceID, server.TraceID())
+	assert.Equal(pcommon.NewSpanID([8]byte{0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28}), server.SpanID())
+	assert.True(server.ParentSpanID().IsEmpty())
+	assert.Equal("HTTP GET /dispatch", server.Name())
+	assert.Equal(ptrace.SpanKindServer, server.Kind())
+	assert.Equal(ptrace.StatusCodeError, server.Status().Code())
+	assert.Equal(pcommon.Timestamp(1556604172355737000), server.StartTimestamp())
+	assert.Equal(pcommon.Timestamp(1556604172357168000), server.EndTimestamp())
+	assert.Equal(map[string]interface{}{
+		"http.status_code": int64(500),
+		"sampler.param":    0.5,
+	}, server.Attributes().AsRaw())
+	require.Equal(t, 1, server.Events().Len())
+	event := server.Events().At(0)
+	assert.Equal("exception", event.Name())
+	assert.Equal(pcommon.Timestamp(1556604172355800000), event.Timestamp())
+	assert.Equal(map[string]interface{}{
+		semconv.AttributeExceptionMessage: "boom",
+		semconv.AttributeExceptionType:    "*errors.errorString",
+	}, event.Attributes().AsRaw())
+
+	child := spans.At(1)
+	assert.Equal(traceID, child.TraceID())
+	assert.Equal(server.SpanID(), child.ParentSpanID())
+	assert.Equal(ptrace.SpanKindClient, child.Kind())
+	assert.Equal(ptrace.StatusCodeUnset, child.Status().Code())
+	assert.Equal(0, child.Attributes().Len())
+}
+
+func TestJaegerDecodeInvalid(t *testing.T) {
+	batch := jaegerTestBatch()
+	for name, body := range map[string][]byte{
+		"empty":     {},
+		"truncated": batch[:len(batch)-10],
+		"trailing":  append(append([]byte{}, batch...), 0),
+		"list-size": {thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff},
+		"type":      {42, 0, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := jaegerIntake.decode("application/x-thrift", body)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -218,6 +218,12 @@ type OTLPIngestSummary struct {
 
 // ReceiveResourceSpans processes the given rspans and sends them to writer.
 func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header http.Header, protocol string) OTLPIngestSummary {
+	return o.receiveResourceSpans(rspans, header, fmt.Sprintf("opentelemetry_%s_v1", protocol), "otlp")
+}
+
+// receiveResourceSpans processes the given rspans and sends them to writer. The payload is reported
+// as received on the given endpoint version, from the given tracer, e.g. "otlp" or "zipkin".
+func (o *OTLPReceiver) receiveResourceSpans(rspans ptrace.ResourceSpans, header http.Header, endpointVersion, tracer string) OTLPIngestSummary {
 	// each rspans is coming from a different resource and should be considered
 	// a separate payload; typically there is only one item in this slice
 	attr := rspans.Resource().Attributes()
@@ -255,8 +261,8 @@ func (o *OTLPReceiver) ReceiveResourceSpans(rspans ptrace.ResourceSpans, header
 			LangVersion:     fastHeaderGet(header, headerLangVersion),
 			Interpreter:     fastHeaderGet(header, headerLangInterpreter),
 			LangVendor:      fastHeaderGet(header, headerLangInterpreterVendor),
-			TracerVersion:   fmt.Sprintf("otlp-%s", rattr[string(semconv.AttributeTelemetrySDKVersion)]),
-			EndpointVersion: fmt.Sprintf("opentelemetry_%s_v1", protocol),
+			TracerVersion:   fmt.Sprintf("%s-%s", tracer, rattr[string(semconv.AttributeTelemetrySDKVersion)]),
+			EndpointVersion: endpointVersion,
 		},
 		Stats: info.NewStats(),
 	}
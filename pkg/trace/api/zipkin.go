@@ -0,0 +1,352 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// zipkinIntakeName is the name of the Zipkin intake.
+const zipkinIntakeName = "zipkin"
+
+// zipkinIntake receives Zipkin v2 spans, encoded in JSON or protobuf, as sent to the
+// /api/v2/spans endpoint of the Zipkin collector.
+var zipkinIntake = traceIntake{
+	name:            zipkinIntakeName,
+	endpointVersion: "zipkin_v2",
+	decode: func(mediaType string, body []byte) (ptrace.Traces, error) {
+		var (
+			spans []zipkinSpan
+			err   error
+		)
+		switch mediaType {
+		case "application/x-protobuf":
+			spans, err = decodeZipkinProto(body)
+		case "application/json":
+			fallthrough
+		default:
+			err = json.Unmarshal(body, &spans)
+		}
+		if err != nil {
+			return ptrace.NewTraces(), err
+		}
+		return zipkinToTraces(spans)
+	},
+}
+
+// zipkinSpan is a span of the Zipkin v2 model. IDs are lower-hex encoded and times are
+// in microseconds.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId,omitempty"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Timestamp      uint64             `json:"timestamp,omitempty"`
+	Duration       uint64             `json:"duration,omitempty"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+}
+
+// zipkinEndpoint is the network context of a node in the service graph.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int64  `json:"port,omitempty"`
+}
+
+// zipkinAnnotation associates an event that explains latency with a timestamp.
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// zipkinKinds maps the Zipkin span kinds to OpenTelemetry span kinds. Spans without kind are
+// local spans.
+var zipkinKinds = map[string]ptrace.SpanKind{
+	"CLIENT":   ptrace.SpanKindClient,
+	"SERVER":   ptrace.SpanKindServer,
+	"PRODUCER": ptrace.SpanKindProducer,
+	"CONSUMER": ptrace.SpanKindConsumer,
+}
+
+// zipkinToTraces converts the given Zipkin spans to OpenTelemetry traces, grouping them into
+// a resource per local service.
+func zipkinToTraces(spans []zipkinSpan) (ptrace.Traces, error) {
+	traces := ptrace.NewTraces()
+	byService := make(map[string]ptrace.SpanSlice)
+	for _, zs := range spans {
+		var service string
+		if zs.LocalEndpoint != nil {
+			service = zs.LocalEndpoint.ServiceName
+		}
+		slice, ok := byService[service]
+		if !ok {
+			rspans := traces.ResourceSpans().AppendEmpty()
+			if service != "" {
+				rspans.Resource().Attributes().InsertString(semconv.AttributeServiceName, service)
+			}
+			libspans := rspans.ScopeSpans().AppendEmpty()
+			libspans.Scope().SetName(zipkinIntakeName)
+			slice = libspans.Spans()
+			byService[service] = slice
+		}
+		if err := zipkinToSpan(zs, slice.AppendEmpty()); err != nil {
+			return ptrace.NewTraces(), err
+		}
+	}
+	return traces, nil
+}
+
+// zipkinToSpan converts the Zipkin span zs into span.
+func zipkinToSpan(zs zipkinSpan, span ptrace.Span) error {
+	var traceID [16]byte
+	if err := decodeHexID(traceID[:], zs.TraceID); err != nil {
+		return fmt.Errorf("invalid trace ID %q", zs.TraceID)
+	}
+	var spanID, parentID [8]byte
+	if err := decodeHexID(spanID[:], zs.ID); err != nil {
+		return fmt.Errorf("invalid span ID %q", zs.ID)
+	}
+	if zs.ParentID != "" {
+		if err := decodeHexID(parentID[:], zs.ParentID); err != nil {
+			return fmt.Errorf("invalid parent ID %q", zs.ParentID)
+		}
+	}
+	span.SetTraceID(pcommon.NewTraceID(traceID))
+	span.SetSpanID(pcommon.NewSpanID(spanID))
+	span.SetParentSpanID(pcommon.NewSpanID(parentID))
+	span.SetName(zs.Name)
+	if kind, ok := zipkinKinds[strings.ToUpper(zs.Kind)]; ok {
+		span.SetKind(kind)
+	} else {
+		span.SetKind(ptrace.SpanKindInternal)
+	}
+	span.SetStartTimestamp(pcommon.Timestamp(zs.Timestamp * 1000))
+	span.SetEndTimestamp(pcommon.Timestamp((zs.Timestamp + zs.Duration) * 1000))
+
+	attrs := span.Attributes()
+	if ep := zs.LocalEndpoint; ep != nil {
+		if ip := ep.ip(); ip != "" {
+			attrs.InsertString(semconv.AttributeNetHostIP, ip)
+		}
+		if ep.Port != 0 {
+			attrs.InsertInt(semconv.AttributeNetHostPort, ep.Port)
+		}
+	}
+	if ep := zs.RemoteEndpoint; ep != nil {
+		if ep.ServiceName != "" {
+			attrs.InsertString(semconv.AttributePeerService, ep.ServiceName)
+		}
+		if ip := ep.ip(); ip != "" {
+			attrs.InsertString(semconv.AttributeNetPeerIP, ip)
+		}
+		if ep.Port != 0 {
+			attrs.InsertInt(semconv.AttributeNetPeerPort, ep.Port)
+		}
+	}
+	for k, v := range zs.Tags {
+		if k == "error" {
+			// Zipkin marks failed spans with the "error" tag, holding the error message.
+			span.Status().SetCode(ptrace.StatusCodeError)
+			if v != "" && v != "true" {
+				span.Status().SetMessage(v)
+			}
+			continue
+		}
+		attrs.UpsertString(k, v)
+	}
+	for _, a := range zs.Annotations {
+		event := span.Events().AppendEmpty()
+		event.SetTimestamp(pcommon.Timestamp(a.Timestamp * 1000))
+		event.SetName(a.Value)
+	}
+	return nil
+}
+
+// ip returns the IPv4 address of the endpoint, or its IPv6 address.
+func (ep *zipkinEndpoint) ip() string {
+	if ep.IPv4 != "" {
+		return ep.IPv4
+	}
+	return ep.IPv6
+}
+
+// decodeHexID decodes the hexadecimal ID s into b. IDs shorter than b are left-padded with zeros.
+func decodeHexID(b []byte, s string) error {
+	if s == "" || len(s) > 2*len(b) {
+		return fmt.Errorf("invalid ID length %d", len(s))
+	}
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	_, err := hex.Decode(b[len(b)-len(s)/2:], []byte(s))
+	return err
+}
+
+// decodeZipkinProto decodes a Zipkin ListOfSpans protobuf message, as defined in
+// https://github.com/openzipkin/zipkin-api/blob/master/zipkin.proto.
+func decodeZipkinProto(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := walkProtoFields(b, func(num protowire.Number, _ uint64, buf []byte) error {
+		if num != 1 {
+			return nil
+		}
+		span, err := decodeZipkinProtoSpan(buf)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, span)
+		return nil
+	})
+	return spans, err
+}
+
+var zipkinProtoKinds = map[uint64]string{
+	1: "CLIENT",
+	2: "SERVER",
+	3: "PRODUCER",
+	4: "CONSUMER",
+}
+
+func decodeZipkinProtoSpan(b []byte) (zipkinSpan, error) {
+	var span zipkinSpan
+	err := walkProtoFields(b, func(num protowire.Number, v uint64, buf []byte) error {
+		switch num {
+		case 1:
+			span.TraceID = hex.EncodeToString(buf)
+		case 2:
+			span.ParentID = hex.EncodeToString(buf)
+		case 3:
+			span.ID = hex.EncodeToString(buf)
+		case 4:
+			span.Kind = zipkinProtoKinds[v]
+		case 5:
+			span.Name = string(buf)
+		case 6:
+			span.Timestamp = v
+		case 7:
+			span.Duration = v
+		case 8, 9:
+			ep, err := decodeZipkinProtoEndpoint(buf)
+			if err != nil {
+				return err
+			}
+			if num == 8 {
+				span.LocalEndpoint = ep
+			} else {
+				span.RemoteEndpoint = ep
+			}
+		case 10:
+			var a zipkinAnnotation
+			err := walkProtoFields(buf, func(num protowire.Number, v uint64, buf []byte) error {
+				switch num {
+				case 1:
+					a.Timestamp = v
+				case 2:
+					a.Value = string(buf)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			span.Annotations = append(span.Annotations, a)
+		case 11:
+			var key, value string
+			err := walkProtoFields(buf, func(num protowire.Number, _ uint64, buf []byte) error {
+				switch num {
+				case 1:
+					key = string(buf)
+				case 2:
+					value = string(buf)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if span.Tags == nil {
+				span.Tags = make(map[string]string)
+			}
+			span.Tags[key] = value
+		}
+		return nil
+	})
+	return span, err
+}
+
+func decodeZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	var ep zipkinEndpoint
+	err := walkProtoFields(b, func(num protowire.Number, v uint64, buf []byte) error {
+		switch num {
+		case 1:
+			ep.ServiceName = string(buf)
+		case 2:
+			if len(buf) == net.IPv4len {
+				ep.IPv4 = net.IP(buf).String()
+			}
+		case 3:
+			if len(buf) == net.IPv6len {
+				ep.IPv6 = net.IP(buf).String()
+			}
+		case 4:
+			ep.Port = int64(int32(v))
+		}
+		return nil
+	})
+	return &ep, err
+}
+
+// walkProtoFields calls fn for each field of the protobuf message b, passing the value of
+// varint and fixed-size fields as v and the value of length-delimited fields as buf.
+func walkProtoFields(b []byte, fn func(num protowire.Number, v uint64, buf []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var (
+			v   uint64
+			buf []byte
+		)
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.BytesType:
+			buf, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, v, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var zipkinTestJSON = []byte(`[
+	{
+		"traceId": "5af7183fb1d4cf5f",
+		"parentId": "6b221d5bc9e6496c",
+		"id": "352bff9a74ca9ad2",
+		"kind": "CLIENT",
+		"name": "get /api",
+		"timestamp": 1556604172355737,
+		"duration": 1431,
+		"localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1", "port": 3306},
+		"remoteEndpoint": {"serviceName": "backend", "ipv4": "172.19.0.2", "port": 9000},
+		"annotations": [{"timestamp": 1556604172355800, "value": "ws"}],
+		"tags": {"http.method": "GET", "error": "connection refused"}
+	},
+	{
+		"traceId": "5af7183fb1d4cf5f",
+		"id": "6b221d5bc9e6496c",
+		"name": "render",
+		"timestamp": 1556604172355000,
+		"duration": 3000,
+		"localEndpoint": {"serviceName": "frontend"}
+	},
+	{
+		"traceId": "5af7183fb1d4cf5f",
+		"parentId": "352bff9a74ca9ad2",
+		"id": "a4f1b2c3d4e5f607",
+		"kind": "SERVER",
+		"name": "get /api",
+		"timestamp": 1556604172355900,
+		"duration": 1000,
+		"localEndpoint": {"serviceName": "backend"}
+	}
+]`)
+
+func TestZipkinDecode(t *testing.T) {
+	traces, err := zipkinIntake.decode("application/json", zipkinTestJSON)
+	require.NoError(t, err)
+	assertZipkinTestTraces(t, traces)
+}
+
+func TestZipkinDecodeProto(t *testing.T) {
+	endpoint := func(service string, ip []byte, port uint64) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, service)
+		if ip != nil {
+			b = protowire.AppendTag(b, 2, protowire.BytesType)
+			b = protowire.AppendBytes(b, ip)
+		}
+		if port != 0 {
+			b = protowire.AppendTag(b, 4, protowire.VarintType)
+			b = protowire.AppendVarint(b, port)
+		}
+		return b
+	}
+	span := func(traceID, parentID, id []byte, kind uint64, name string, ts, duration uint64, local, remote []byte, fn func([]byte) []byte) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, traceID)
+		if parentID != nil {
+			b = protowire.AppendTag(b, 2, protowire.BytesType)
+			b = protowire.AppendBytes(b, parentID)
+		}
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, id)
+		if kind != 0 {
+			b = protowire.AppendTag(b, 4, protowire.VarintType)
+			b = protowire.AppendVarint(b, kind)
+		}
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendString(b, name)
+		b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, ts)
+		b = protowire.AppendTag(b, 7, protowire.VarintType)
+		b = protowire.AppendVarint(b, duration)
+		b = protowire.AppendTag(b, 8, protowire.BytesType)
+		b = protowire.AppendBytes(b, local)
+		if remote != nil {
+			b = protowire.AppendTag(b, 9, protowire.BytesType)
+			b = protowire.AppendBytes(b, remote)
+		}
+		if fn != nil {
+			b = fn(b)
+		}
+		return b
+	}
+	traceID := []byte{0x5a, 0xf7, 0x18, 0x3f, 0xb1, 0xd4, 0xcf, 0x5f}
+	id1 := []byte{0x35, 0x2b, 0xff, 0x9a, 0x74, 0xca, 0x9a, 0xd2}
+	id2 := []byte{0x6b, 0x22, 0x1d, 0x5b, 0xc9, 0xe6, 0x49, 0x6c}
+	id3 := []byte{0xa4, 0xf1, 0xb2, 0xc3, 0xd4, 0xe5, 0xf6, 0x07}
+	spans := [][]byte{
+		span(traceID, id2, id1, 1, "get /api", 1556604172355737, 1431,
+			endpoint("frontend", []byte{192, 168, 99, 1}, 3306),
+			endpoint("backend", []byte{172, 19, 0, 2}, 9000),
+			func(b []byte) []byte {
+				var a []byte
+				a = protowire.AppendTag(a, 1, protowire.Fixed64Type)
+				a = protowire.AppendFixed64(a, 1556604172355800)
+				a = protowire.AppendTag(a, 2, protowire.BytesType)
+				a = protowire.AppendString(a, "ws")
+				b = protowire.AppendTag(b, 10, protowire.BytesType)
+				b = protowire.AppendBytes(b, a)
+				for _, kv := range [][2]string{{"http.method", "GET"}, {"error", "connection refused"}} {
+					var e []byte
+					e = protowire.AppendTag(e, 1, protowire.BytesType)
+					e = protowire.AppendString(e, kv[0])
+					e = protowire.AppendTag(e, 2, protowire.BytesType)
+					e = protowire.AppendString(e, kv[1])
+					b = protowire.AppendTag(b, 11, protowire.BytesType)
+					b = protowire.AppendBytes(b, e)
+				}
+				return b
+			}),
+		span(traceID, nil, id2, 0, "render", 1556604172355000, 3000, endpoint("frontend", nil, 0), nil, nil),
+		span(traceID, id1, id3, 2, "get /api", 1556604172355900, 1000, endpoint("backend", nil, 0), nil, nil),
+	}
+	var body []byte
+	for _, s := range spans {
+		body = protowire.AppendTag(body, 1, protowire.BytesType)
+		body = protowire.AppendBytes(body, s)
+	}
+
+	traces, err := zipkinIntake.decode("application/x-protobuf", body)
+	require.NoError(t, err)
+	assertZipkinTestTraces(t, traces)
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := zipkinIntake.decode("application/x-protobuf", body[:len(body)-3])
+		assert.Error(t, err)
+	})
+}
+
+// assertZipkinTestTraces asserts that traces holds the spans of zipkinTestJSON.
+func assertZipkinTestTraces(t *testing.T, traces ptrace.Traces) {
+	assert := assert.New(t)
+	require.Equal(t, 2, traces.ResourceSpans().Len())
+	assert.Equal(3, traces.SpanCount())
+
+	frontend := traces.ResourceSpans().At(0)
+	service, _ := frontend.Resource().Attributes().Get(semconv.AttributeServiceName)
+	assert.Equal("frontend", service.StringVal())
+	assert.Equal("zipkin", frontend.ScopeSpans().At(0).Scope().Name())
+	require.Equal(t, 2, frontend.ScopeSpans().At(0).Spans().Len())
+
+	span := frontend.ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(pcommon.NewTraceID([16]byte{8: 0x5a, 0xf7, 0x18, 0x3f, 0xb1, 0xd4, 0xcf, 0x5f}), span.TraceID())
+	assert.Equal(pcommon.NewSpanID([8]byte{0x35, 0x2b, 0xff, 0x9a, 0x74, 0xca, 0x9a, 0xd2}), span.SpanID())
+	assert.Equal(pcommon.NewSpanID([8]byte{0x6b, 0x22, 0x1d, 0x5b, 0xc9, 0xe6, 0x49, 0x6c}), span.ParentSpanID())
+	assert.Equal("get /api", span.Name())
+	assert.Equal(ptrace.SpanKindClient, span.Kind())
+	assert.Equal(pcommon.Timestamp(1556604172355737000), span.StartTimestamp())
+	assert.Equal(pcommon.Timestamp(1556604172357168000), span.EndTimestamp())
+	assert.Equal(ptrace.StatusCodeError, span.Status().Code())
+	assert.Equal("connection refused", span.Status().Message())
+	assert.Equal(map[string]interface{}{
+		semconv.AttributeNetHostIP:   "192.168.99.1",
+		semconv.AttributeNetHostPort: int64(3306),
+		semconv.AttributePeerService: "backend",
+		semconv.AttributeNetPeerIP:   "172.19.0.2",
+		semconv.AttributeNetPeerPort: int64(9000),
+		"http.method":                "GET",
+	}, span.Attributes().AsRaw())
+	require.Equal(t, 1, span.Events().Len())
+	assert.Equal("ws", span.Events().At(0).Name())
+	assert.Equal(pcommon.Timestamp(1556604172355800000), span.Events().At(0).Timestamp())
+
+	root := frontend.ScopeSpans().At(0).Spans().At(1)
+	assert.True(root.ParentSpanID().IsEmpty())
+	assert.Equal(ptrace.SpanKindInternal, root.Kind())
+	assert.Equal(ptrace.StatusCodeUnset, root.Status().Code())
+
+	backend := traces.ResourceSpans().At(1)
+	service, _ = backend.Resource().Attributes().Get(semconv.AttributeServiceName)
+	assert.Equal("backend", service.StringVal())
+	assert.Equal(ptrace.SpanKindServer, backend.ScopeSpans().At(0).Spans().At(0).Kind())
+}
+
+func TestZipkinDecodeInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"json":     `[{"traceId": 12}]`,
+		"trace-id": `[{"traceId": "xyz", "id": "352bff9a74ca9ad2"}]`,
+		"span-id":  `[{"traceId": "5af7183fb1d4cf5f", "id": "352bff9a74ca9ad2352bff9a74ca9ad2"}]`,
+		"no-id":    `[{"traceId": "5af7183fb1d4cf5f"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := zipkinIntake.decode("application/json", []byte(body))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeHexID(t *testing.T) {
+	var b [8]byte
+	require.NoError(t, decodeHexID(b[:], "abc"))
+	assert.Equal(t, [8]byte{6: 0x0a, 7: 0xbc}, b)
+}
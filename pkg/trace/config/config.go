@@ -92,6 +92,10 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the resource and the "graphql.query"
+	// and "graphql.source" tags of spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards CreditCardsConfig `mapstructure:"credit_cards"`
 }
@@ -118,6 +122,12 @@ func (o *ObfuscationConfig) Export() obfuscate.Config {
 			DollarQuotedFunc: features.Has("dollar_quoted_func"),
 			Cache:            features.Has("sql_cache"),
 		},
+		CQL: obfuscate.CQLConfig{
+			Cache: features.Has("cql_cache"),
+		},
+		GraphQL: obfuscate.GraphQLConfig{
+			Cache: features.Has("graphql_cache"),
+		},
 		ES: obfuscate.JSONConfig{
 			Enabled:            o.ES.Enabled,
 			KeepValues:         o.ES.KeepValues,
@@ -178,6 +188,44 @@ type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
 }
 
+// TailSamplingConfig holds the configuration of the tail-based sampler.
+type TailSamplingConfig struct {
+	// Enabled specifies whether traces are buffered until complete and sampled based on the
+	// policies below, instead of being sampled upon arrival.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait specifies how long the spans of a trace are buffered after the first
+	// of them was received, before a sampling decision is made.
+	DecisionWait time.Duration `mapstructure:"-"`
+
+	// MaxMemoryBytes specifies the approximate maximum size of the buffered traces, including the
+	// analyzed spans extracted from them. Once reached, the oldest traces are dropped.
+	MaxMemoryBytes int64 `mapstructure:"max_memory_bytes"`
+
+	// Policies specifies the policies keeping traces. A trace is kept when any of them matches.
+	Policies []TailSamplingPolicy `mapstructure:"policies"`
+
+	// FallbackRate specifies the rate at which the traces matching none of the policies are kept.
+	FallbackRate float64 `mapstructure:"fallback_rate"`
+}
+
+// TailSamplingPolicy specifies a policy of the tail-based sampler.
+type TailSamplingPolicy struct {
+	// Name specifies the name of the policy, reported in the tags of the trace chunks it keeps.
+	Name string `mapstructure:"name"`
+
+	// Type specifies the type of the policy: "latency", "tag" or "error".
+	Type string `mapstructure:"type"`
+
+	// LatencyThresholdMs specifies the duration above which a span makes a "latency" policy keep its trace.
+	LatencyThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Key and Value specify the tag which makes a "tag" policy keep the traces having it on any of
+	// their spans. If Value is empty, any value matches.
+	Key   string `mapstructure:"key"`
+	Value string `mapstructure:"value"`
+}
+
 // TelemetryConfig holds Instrumentation telemetry Endpoints information
 type TelemetryConfig struct {
 	Enabled   bool `mapstructure:"enabled"`
@@ -217,6 +265,39 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// TransformRule specifies a rule transforming the tags of the spans.
+type TransformRule struct {
+	// Action specifies the transformation to apply:
+	// • "delete" removes the Key tag
+	// • "rename" moves the Key tag to the Target tag
+	// • "copy" copies the Key tag to the Target tag
+	// • "hash" replaces the value of the Key tag with its SHA-256 hash, in hexadecimal
+	// • "set" sets the Key tag to Value
+	// • "metric_to_meta" moves the Key metric to the Target tag, or to the Key tag if Target is empty
+	Action string `mapstructure:"action"`
+
+	// Key specifies the tag, or metric for "metric_to_meta", the rule applies to.
+	Key string `mapstructure:"key"`
+
+	// Target specifies the destination tag of the "rename", "copy" and "metric_to_meta" actions.
+	Target string `mapstructure:"target"`
+
+	// Value specifies the value of the tag set by the "set" action.
+	Value string `mapstructure:"value"`
+
+	// Service optionally specifies a regexp pattern restricting the rule to the spans of matching services.
+	Service string `mapstructure:"service"`
+
+	// Operation optionally specifies a regexp pattern restricting the rule to the spans of matching
+	// operation names.
+	Operation string `mapstructure:"operation"`
+
+	// ServiceRe and OperationRe hold the compiled Service and Operation patterns and are only
+	// used internally. They are nil when the patterns are empty.
+	ServiceRe   *regexp.Regexp `mapstructure:"-"`
+	OperationRe *regexp.Regexp `mapstructure:"-"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -230,6 +311,14 @@ type WriterConfig struct {
 	// FlushPeriodSeconds specifies the frequency at which the writer's buffer
 	// will be flushed to the sender, in seconds. Fractions are permitted.
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
+
+	// Compression specifies the algorithm used to compress the payloads, "gzip"
+	// (default) or "zstd".
+	Compression string `mapstructure:"compression"`
+
+	// CompressionLevel specifies the level of compression of the payloads. Zero
+	// selects the fastest level of the algorithm.
+	CompressionLevel int `mapstructure:"compression_level"`
 }
 
 // FargateOrchestratorName is a Fargate orchestrator name.
@@ -287,6 +376,12 @@ type AgentConfig struct {
 	// Concentrator
 	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
 	ExtraAggregators []string
+	// ExtraAggregationTags specifies the span tags by which stats are aggregated, in addition
+	// to the service, name, resource, type, status code and synthetics dimensions.
+	ExtraAggregationTags []string
+	// MaxExtraAggregationTagSets specifies the maximum number of distinct sets of values of the
+	// ExtraAggregationTags in a stats bucket. Beyond it, spans are aggregated without extra tags.
+	MaxExtraAggregationTagSets int
 
 	// Sampler configuration
 	ExtraSampleRate    float64
@@ -295,6 +390,7 @@ type AgentConfig struct {
 	DisableRareSampler bool
 	MaxEPS             float64
 	MaxRemoteTPS       float64
+	TailSampling       *TailSamplingConfig
 
 	// Receiver
 	ReceiverHost    string
@@ -341,6 +437,9 @@ type AgentConfig struct {
 	// ReplaceTags is used to filter out sensitive information from tag values.
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
+	// TransformRules holds the rules transforming the tags of the spans, applied in order
+	// before stats are computed.
+	TransformRules []*TransformRule
 
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
@@ -361,6 +460,18 @@ type AgentConfig struct {
 	// RejectTags specifies a list of tags which must be absent on the root span in order for a trace to be accepted.
 	RejectTags []*Tag
 
+	// RequireTagsRegex specifies a list of tags whose value must match the given pattern on the root span in
+	// order for a trace to be accepted. See TagRegex for how the patterns are matched.
+	RequireTagsRegex []*TagRegex
+
+	// RejectTagsRegex specifies a list of tags whose value must not match the given pattern on the root span in
+	// order for a trace to be accepted.
+	RejectTagsRegex []*TagRegex
+
+	// FilterTagsAnySpan specifies whether the tag filtering rules above are matched against all the spans of
+	// a trace chunk instead of only its root span.
+	FilterTagsAnySpan bool
+
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
@@ -408,6 +519,37 @@ type Tag struct {
 	K, V string
 }
 
+// String returns the tag in its configuration format: "key" or "key:value".
+func (t *Tag) String() string {
+	if t.V == "" {
+		return t.K
+	}
+	return t.K + ":" + t.V
+}
+
+// TagRegex represents a key/value pattern pair. A nil V matches any value. Like the patterns of
+// the replace rules, a regular expression matches any part of the value unless it is anchored with
+// ^ and $, while a glob always matches the whole value.
+type TagRegex struct {
+	K string
+	V *regexp.Regexp
+	// Pattern is the value pattern as configured, a regular expression or a glob.
+	Pattern string
+	// Glob reports whether Pattern is a glob, compiled into V.
+	Glob bool
+}
+
+// String returns the tag pattern in its configuration format: "key" or "key:pattern".
+func (t *TagRegex) String() string {
+	if t.V == nil {
+		return t.K
+	}
+	if t.Pattern == "" {
+		return t.K + ":" + t.V.String()
+	}
+	return t.K + ":" + t.Pattern
+}
+
 // New returns a configuration with the default values.
 func New() *AgentConfig {
 	return &AgentConfig{
@@ -418,13 +560,18 @@ func New() *AgentConfig {
 		Site:                "datadoghq.com",
 		MaxCatalogEntries:   5000,
 
-		BucketInterval: time.Duration(10) * time.Second,
+		BucketInterval:             time.Duration(10) * time.Second,
+		MaxExtraAggregationTagSets: 1000,
 
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
 		ErrorTPS:        10,
 		MaxEPS:          200,
 		MaxRemoteTPS:    100,
+		TailSampling: &TailSamplingConfig{
+			DecisionWait:   10 * time.Second,
+			MaxMemoryBytes: 100 * 1024 * 1024, // 100MB
+		},
 
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// Transformer is a filter which deletes, renames, copies, hashes and sets tags based
+// on its settings. It keeps all spans.
+type Transformer struct {
+	rules []*config.TransformRule
+}
+
+// NewTransformer returns a new Transformer which will use the given set of rules. The rules
+// are expected to be valid, with their patterns compiled.
+func NewTransformer(rules []*config.TransformRule) *Transformer {
+	return &Transformer{rules: rules}
+}
+
+// Transform applies the Transformer's rules, in order, to all the spans of the trace.
+func (f Transformer) Transform(trace pb.Trace) {
+	for _, rule := range f.rules {
+		for _, s := range trace {
+			if rule.ServiceRe != nil && !rule.ServiceRe.MatchString(s.Service) {
+				continue
+			}
+			if rule.OperationRe != nil && !rule.OperationRe.MatchString(s.Name) {
+				continue
+			}
+			transform(s, rule)
+		}
+	}
+}
+
+// transform applies the rule to the span.
+func transform(s *pb.Span, rule *config.TransformRule) {
+	switch rule.Action {
+	case "delete":
+		delete(s.Meta, rule.Key)
+	case "rename", "copy":
+		v, ok := s.Meta[rule.Key]
+		if !ok {
+			return
+		}
+		if rule.Action == "rename" {
+			delete(s.Meta, rule.Key)
+		}
+		s.Meta[rule.Target] = v
+	case "hash":
+		if v, ok := s.Meta[rule.Key]; ok {
+			sum := sha256.Sum256([]byte(v))
+			s.Meta[rule.Key] = hex.EncodeToString(sum[:])
+		}
+	case "set":
+		traceutil.SetMeta(s, rule.Key, rule.Value)
+	case "metric_to_meta":
+		v, ok := s.Metrics[rule.Key]
+		if !ok {
+			return
+		}
+		delete(s.Metrics, rule.Key)
+		target := rule.Target
+		if target == "" {
+			target = rule.Key
+		}
+		traceutil.SetMeta(s, target, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
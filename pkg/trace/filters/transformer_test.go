@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformer(t *testing.T) {
+	for name, tt := range map[string]struct {
+		rules       []*config.TransformRule
+		meta        map[string]string
+		metrics     map[string]float64
+		wantMeta    map[string]string
+		wantMetrics map[string]float64
+	}{
+		"delete": {
+			rules:    []*config.TransformRule{{Action: "delete", Key: "a"}, {Action: "delete", Key: "missing"}},
+			meta:     map[string]string{"a": "1", "b": "2"},
+			wantMeta: map[string]string{"b": "2"},
+		},
+		"rename": {
+			rules:    []*config.TransformRule{{Action: "rename", Key: "a", Target: "c"}, {Action: "rename", Key: "missing", Target: "b"}},
+			meta:     map[string]string{"a": "1", "b": "2"},
+			wantMeta: map[string]string{"b": "2", "c": "1"},
+		},
+		"copy": {
+			rules:    []*config.TransformRule{{Action: "copy", Key: "a", Target: "b"}},
+			meta:     map[string]string{"a": "1", "b": "2"},
+			wantMeta: map[string]string{"a": "1", "b": "1"},
+		},
+		"hash": {
+			rules:    []*config.TransformRule{{Action: "hash", Key: "user.email"}},
+			meta:     map[string]string{"user.email": "jane@example.com"},
+			wantMeta: map[string]string{"user.email": "8c87b489ce35cf2e2f39f80e282cb2e804932a56a213983eeeb428407d43b52d"},
+		},
+		"set": {
+			rules:    []*config.TransformRule{{Action: "set", Key: "team", Value: "apm"}},
+			wantMeta: map[string]string{"team": "apm"},
+		},
+		"metric-to-meta": {
+			rules: []*config.TransformRule{
+				{Action: "metric_to_meta", Key: "db.rows"},
+				{Action: "metric_to_meta", Key: "retries", Target: "http.retries"},
+			},
+			metrics:     map[string]float64{"db.rows": 12, "retries": 0.5, "other": 1},
+			wantMeta:    map[string]string{"db.rows": "12", "http.retries": "0.5"},
+			wantMetrics: map[string]float64{"other": 1},
+		},
+		"conditions": {
+			rules: []*config.TransformRule{
+				{Action: "set", Key: "matched", Value: "service", ServiceRe: regexp.MustCompile("^web")},
+				{Action: "set", Key: "skipped", Value: "service", ServiceRe: regexp.MustCompile("^db")},
+				{Action: "set", Key: "matched-op", Value: "operation", OperationRe: regexp.MustCompile(`^http\.`)},
+				{Action: "set", Key: "skipped-op", Value: "operation", ServiceRe: regexp.MustCompile("^web"), OperationRe: regexp.MustCompile("^sql")},
+			},
+			wantMeta: map[string]string{"matched": "service", "matched-op": "operation"},
+		},
+		"order": {
+			rules: []*config.TransformRule{
+				{Action: "copy", Key: "a", Target: "b"},
+				{Action: "hash", Key: "a"},
+				{Action: "delete", Key: "b"},
+			},
+			meta:     map[string]string{"a": "1"},
+			wantMeta: map[string]string{"a": "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := transformTestSpan(tt.meta, tt.metrics)
+			child := transformTestSpan(tt.meta, tt.metrics)
+			NewTransformer(tt.rules).Transform(pb.Trace{root, child})
+			for _, s := range []*pb.Span{root, child} {
+				if len(tt.wantMeta) == 0 {
+					assert.Len(t, s.Meta, 0)
+				} else {
+					assert.Equal(t, tt.wantMeta, s.Meta)
+				}
+				if len(tt.wantMetrics) == 0 {
+					assert.Len(t, s.Metrics, 0)
+				} else {
+					assert.Equal(t, tt.wantMetrics, s.Metrics)
+				}
+			}
+		})
+	}
+}
+
+func transformTestSpan(meta map[string]string, metrics map[string]float64) *pb.Span {
+	s := &pb.Span{Service: "web-store", Name: "http.request"}
+	if meta != nil {
+		s.Meta = make(map[string]string, len(meta))
+		for k, v := range meta {
+			s.Meta[k] = v
+		}
+	}
+	if metrics != nil {
+		s.Metrics = make(map[string]float64, len(metrics))
+		for k, v := range metrics {
+			s.Metrics[k] = v
+		}
+	}
+	return s
+}
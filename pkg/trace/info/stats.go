@@ -172,6 +172,9 @@ func (ts *TagStats) publish() {
 	for reason, count := range ts.SpansMalformed.tagValues() {
 		metrics.Count("datadog.trace_agent.normalizer.spans_malformed", count, append(tags, "reason:"+reason), 1)
 	}
+	for rule, count := range ts.TracesFilteredByRule.TagValues() {
+		metrics.Count("datadog.trace_agent.receiver.traces_filtered_by_rule", count, append(tags, "rule:"+rule), 1)
+	}
 	for priority, count := range ts.TracesPerSamplingPriority.TagValues() {
 		metrics.Count("datadog.trace_agent.receiver.traces_priority", count, append(tags, "priority:"+priority), 1)
 	}
@@ -286,6 +289,53 @@ func (s *SpansMalformed) String() string {
 	return mapToString(s.tagValues())
 }
 
+// TracesFilteredByRule contains the counts of traces filtered by each tag filtering rule.
+type TracesFilteredByRule struct {
+	mu sync.Mutex
+	// counts holds the number of filtered traces by rule. It is allocated upon the first count.
+	counts map[string]int64
+}
+
+// Count increments the number of traces filtered by the given rule by 1.
+func (s *TracesFilteredByRule) Count(rule string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[string]int64)
+	}
+	s.counts[rule]++
+}
+
+// TagValues returns a map with the number of traces filtered by each rule.
+func (s *TracesFilteredByRule) TagValues() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]int64, len(s.counts))
+	for rule, count := range s.counts {
+		stats[rule] = count
+	}
+	return stats
+}
+
+// reset sets stats to 0
+func (s *TracesFilteredByRule) reset() {
+	s.mu.Lock()
+	s.counts = nil
+	s.mu.Unlock()
+}
+
+// update absorbs recent stats on top of existing ones.
+func (s *TracesFilteredByRule) update(recent *TracesFilteredByRule) {
+	for rule, count := range recent.TagValues() {
+		s.mu.Lock()
+		if s.counts == nil {
+			s.counts = make(map[string]int64)
+		}
+		s.counts[rule] += count
+		s.mu.Unlock()
+	}
+}
+
 // maxAbsPriority specifies the absolute maximum priority for stats purposes. For example, with a value
 // of 10, the range of priorities reported will be [-10, 10].
 const maxAbsPriority = 10
@@ -343,6 +393,8 @@ type Stats struct {
 	SpansMalformed *SpansMalformed
 	// TracesFiltered is the number of traces filtered.
 	TracesFiltered int64
+	// TracesFilteredByRule contains stats about the count of traces filtered by tag filtering rule.
+	TracesFilteredByRule *TracesFilteredByRule
 	// TracesPriorityNone is the number of traces with no sampling priority.
 	TracesPriorityNone int64
 	// TracesPerPriority holds counters for each priority in position MaxAbsPriorityValue + priority.
@@ -372,8 +424,9 @@ type Stats struct {
 // NewStats returns new, ready to use stats.
 func NewStats() Stats {
 	return Stats{
-		TracesDropped:  new(TracesDropped),
-		SpansMalformed: new(SpansMalformed),
+		TracesDropped:        new(TracesDropped),
+		SpansMalformed:       new(SpansMalformed),
+		TracesFilteredByRule: new(TracesFilteredByRule),
 	}
 }
 
@@ -400,6 +453,7 @@ func (s *Stats) update(recent *Stats) {
 	atomic.AddInt64(&s.SpansMalformed.InvalidDuration, atomic.LoadInt64(&recent.SpansMalformed.InvalidDuration))
 	atomic.AddInt64(&s.SpansMalformed.InvalidHTTPStatusCode, atomic.LoadInt64(&recent.SpansMalformed.InvalidHTTPStatusCode))
 	atomic.AddInt64(&s.TracesFiltered, atomic.LoadInt64(&recent.TracesFiltered))
+	s.TracesFilteredByRule.update(recent.TracesFilteredByRule)
 	atomic.AddInt64(&s.TracesPriorityNone, atomic.LoadInt64(&recent.TracesPriorityNone))
 	atomic.AddInt64(&s.ClientDroppedP0Traces, atomic.LoadInt64(&recent.ClientDroppedP0Traces))
 	atomic.AddInt64(&s.ClientDroppedP0Spans, atomic.LoadInt64(&recent.ClientDroppedP0Spans))
@@ -437,6 +491,7 @@ func (s *Stats) reset() {
 	atomic.StoreInt64(&s.SpansMalformed.InvalidDuration, 0)
 	atomic.StoreInt64(&s.SpansMalformed.InvalidHTTPStatusCode, 0)
 	atomic.StoreInt64(&s.TracesFiltered, 0)
+	s.TracesFilteredByRule.reset()
 	atomic.StoreInt64(&s.TracesPriorityNone, 0)
 	atomic.StoreInt64(&s.ClientDroppedP0Traces, 0)
 	atomic.StoreInt64(&s.ClientDroppedP0Spans, 0)
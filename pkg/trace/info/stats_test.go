@@ -205,10 +205,13 @@ func TestReceiverStats(t *testing.T) {
 				tags: {
 					Tags: tags,
 					Stats: Stats{
-						TracesReceived:     1,
-						TracesDropped:      &TracesDropped{1, 2, 3, 4, 5, 6, 7, 8},
-						SpansMalformed:     &SpansMalformed{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
-						TracesFiltered:     4,
+						TracesReceived: 1,
+						TracesDropped:  &TracesDropped{1, 2, 3, 4, 5, 6, 7, 8},
+						SpansMalformed: &SpansMalformed{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+						TracesFiltered: 4,
+						TracesFilteredByRule: &TracesFilteredByRule{
+							counts: map[string]int64{"reject:key:value": 3},
+						},
 						TracesPriorityNone: 5,
 						TracesPerSamplingPriority: samplingPriorityStats{
 							[maxAbsPriority*2 + 1]int64{
@@ -237,7 +240,7 @@ func TestReceiverStats(t *testing.T) {
 
 	t.Run("Publish", func(t *testing.T) {
 		testStats().Publish()
-		assert.EqualValues(t, atomic.LoadInt64(&statsclient.counts), 39)
+		assert.EqualValues(t, atomic.LoadInt64(&statsclient.counts), 40)
 	})
 
 	t.Run("reset", func(t *testing.T) {
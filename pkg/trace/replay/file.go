@@ -0,0 +1,225 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements the capture file format used to record the payloads
+// received by the trace-agent, and the replay of such files to a running trace-agent.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// fileHeader starts every capture file, once decompressed. Its last byte is the
+// version of the file format.
+var fileHeader = []byte{'D', 'D', 'T', 'R', 'A', 'C', 'E', 'C', 'A', 'P', fileVersion}
+
+const (
+	// fileVersion is the current version of the capture file format.
+	fileVersion = 1
+
+	// maxFieldSize is the maximum size of a field of a record read from a capture file,
+	// protecting from allocating huge buffers when reading corrupted files.
+	maxFieldSize = 1 << 30
+)
+
+// Record is a request recorded in a capture file.
+type Record struct {
+	// Offset is the time elapsed between the start of the capture and the request.
+	Offset time.Duration
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request, including its query string.
+	Path string
+	// Header holds the HTTP headers of the request, e.g. the language and tracer version.
+	Header http.Header
+	// Body is the raw payload of the request.
+	Body []byte
+}
+
+// Writer writes records to a zstd compressed capture file. It is safe for concurrent use.
+type Writer struct {
+	mu  sync.Mutex
+	zw  *zstd.Encoder
+	bw  *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+// NewWriter returns a Writer writing a capture file to w, starting with its header.
+func NewWriter(w io.Writer) (*Writer, error) {
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		return nil, err
+	}
+	cw := &Writer{zw: zw, bw: bufio.NewWriter(zw)}
+	if _, err := cw.bw.Write(fileHeader); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write writes the given record.
+func (w *Writer) Write(rec *Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeUvarint(uint64(rec.Offset))
+	w.writeString(rec.Method)
+	w.writeString(rec.Path)
+	w.writeUvarint(uint64(len(rec.Header)))
+	for k, vs := range rec.Header {
+		w.writeString(k)
+		w.writeUvarint(uint64(len(vs)))
+		for _, v := range vs {
+			w.writeString(v)
+		}
+	}
+	w.writeUvarint(uint64(len(rec.Body)))
+	_, err := w.bw.Write(rec.Body)
+	return err
+}
+
+func (w *Writer) writeUvarint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	// errors are sticky in bufio.Writer and reported by the following writes.
+	w.bw.Write(w.buf[:n]) //nolint:errcheck
+}
+
+func (w *Writer) writeString(s string) {
+	w.writeUvarint(uint64(len(s)))
+	w.bw.WriteString(s) //nolint:errcheck
+}
+
+// Close flushes the written records and terminates the compressed stream. It does
+// not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.bw.Flush(); err != nil {
+		w.zw.Close()
+		return err
+	}
+	return w.zw.Close()
+}
+
+// Reader reads the records of a capture file.
+type Reader struct {
+	zr *zstd.Decoder
+	br *bufio.Reader
+}
+
+// NewReader returns a Reader reading the capture file from r, after checking its header.
+func NewReader(r io.Reader) (*Reader, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	cr := &Reader{zr: zr, br: bufio.NewReader(zr)}
+	hdr := make([]byte, len(fileHeader))
+	if _, err := io.ReadFull(cr.br, hdr); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("invalid capture file: %v", err)
+	}
+	if !bytes.Equal(hdr[:len(hdr)-1], fileHeader[:len(fileHeader)-1]) {
+		zr.Close()
+		return nil, errors.New("invalid capture file: bad header")
+	}
+	if v := hdr[len(hdr)-1]; v > fileVersion {
+		zr.Close()
+		return nil, fmt.Errorf("unsupported capture file version %d", v)
+	}
+	return cr, nil
+}
+
+// Next returns the next record of the file, or io.EOF when there are no more.
+func (r *Reader) Next() (*Record, error) {
+	offset, err := binary.ReadUvarint(r.br)
+	if err != nil {
+		// the end of the file can only be found at the start of a record.
+		return nil, err
+	}
+	rec := Record{Offset: time.Duration(offset)}
+	if rec.Method, err = r.readString(); err != nil {
+		return nil, err
+	}
+	if rec.Path, err = r.readString(); err != nil {
+		return nil, err
+	}
+	n, err := r.readSize()
+	if err != nil {
+		return nil, err
+	}
+	rec.Header = make(http.Header, n)
+	for i := 0; i < n; i++ {
+		k, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		nv, err := r.readSize()
+		if err != nil {
+			return nil, err
+		}
+		vs := make([]string, nv)
+		for j := range vs {
+			if vs[j], err = r.readString(); err != nil {
+				return nil, err
+			}
+		}
+		rec.Header[k] = vs
+	}
+	if rec.Body, err = r.readBytes(); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// readSize reads a size, turning io.EOF into io.ErrUnexpectedEOF as records are never
+// truncated in valid files.
+func (r *Reader) readSize() (int, error) {
+	n, err := binary.ReadUvarint(r.br)
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n > maxFieldSize {
+		return 0, fmt.Errorf("invalid capture file: field size %d exceeds %d", n, maxFieldSize)
+	}
+	return int(n), nil
+}
+
+func (r *Reader) readBytes() ([]byte, error) {
+	n, err := r.readSize()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.br, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *Reader) readString() (string, error) {
+	b, err := r.readBytes()
+	return string(b), err
+}
+
+// Close releases the resources of the Reader. It does not close the underlying io.Reader.
+func (r *Reader) Close() {
+	r.zr.Close()
+}
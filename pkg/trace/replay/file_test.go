@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, records []*Record) *bytes.Buffer {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	for _, rec := range records {
+		require.NoError(t, w.Write(rec))
+	}
+	require.NoError(t, w.Close())
+	return &buf
+}
+
+func TestReadWrite(t *testing.T) {
+	r, err := NewReader(writeTestFile(t, testRecords))
+	require.NoError(t, err)
+	defer r.Close()
+	for _, want := range testRecords {
+		got, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderInvalid(t *testing.T) {
+	t.Run("not-zstd", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader([]byte("not a capture file")))
+		if err == nil {
+			// zstd only fails on the first read
+			_, err = r.Next()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		for header, msg := range map[string]string{
+			"DDTRACECAP\xff": "unsupported capture file version 255",
+			"DDTRACECAT\x01": "invalid capture file: bad header",
+			"DD":             "invalid capture file: unexpected EOF",
+		} {
+			var buf bytes.Buffer
+			zw, err := zstd.NewWriter(&buf)
+			require.NoError(t, err)
+			zw.Write([]byte(header))
+			require.NoError(t, zw.Close())
+			_, err = NewReader(&buf)
+			assert.EqualError(t, err, msg)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf)
+		require.NoError(t, err)
+		w.writeUvarint(1)
+		w.writeString("PUT")
+		require.NoError(t, w.Close())
+		r, err := NewReader(&buf)
+		require.NoError(t, err)
+		_, err = r.Next()
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
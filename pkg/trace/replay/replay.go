@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Stats holds the results of a replay.
+type Stats struct {
+	// Sent is the number of requests sent.
+	Sent int
+	// Failed is the number of requests which were not accepted by the trace-agent.
+	Failed int
+}
+
+// Replay sends the requests of the capture file read from r to the trace-agent receiving
+// at the given URL, e.g. "http://localhost:8126". A speed of 1 keeps the original pace of
+// the requests, 2 sends them twice as fast, and 0 sends them as fast as possible.
+func Replay(ctx context.Context, r io.Reader, url string, speed float64) (Stats, error) {
+	var stats Stats
+	if speed < 0 {
+		return stats, fmt.Errorf("invalid replay speed %v", speed)
+	}
+	cr, err := NewReader(r)
+	if err != nil {
+		return stats, err
+	}
+	defer cr.Close()
+	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, err
+		}
+		if speed > 0 {
+			wait := time.Until(start.Add(time.Duration(float64(rec.Offset) / speed)))
+			if wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return stats, ctx.Err()
+				}
+			}
+		}
+		ok, err := send(ctx, client, url, rec)
+		if err != nil {
+			return stats, err
+		}
+		stats.Sent++
+		if !ok {
+			stats.Failed++
+		}
+	}
+}
+
+// send sends the recorded request to url, reporting whether it was accepted.
+func send(ctx context.Context, client *http.Client, url string, rec *Record) (ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, rec.Method, url+rec.Path, bytes.NewReader(rec.Body))
+	if err != nil {
+		return false, err
+	}
+	for k, vs := range rec.Header {
+		req.Header[k] = vs
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body) //nolint:errcheck
+	return resp.StatusCode/100 == 2, nil
+}
@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRecords = []*Record{
+	{
+		Offset: 0,
+		Method: http.MethodPut,
+		Path:   "/v0.4/traces",
+		Header: http.Header{
+			"Content-Type":                  {"application/msgpack"},
+			"Datadog-Meta-Lang":             {"go"},
+			"Datadog-Meta-Tracer-Version":   {"1.38.0"},
+			"X-Datadog-Trace-Count":         {"1"},
+			"Datadog-Client-Computed-Stats": {"yes"},
+		},
+		Body: []byte{0x91, 0x90},
+	},
+	{
+		Offset: 50 * time.Millisecond,
+		Method: http.MethodPost,
+		Path:   "/v0.6/stats?a=b",
+		Header: http.Header{"Datadog-Meta-Lang": {"python"}},
+		Body:   []byte("stats"),
+	},
+	{
+		Offset: 100 * time.Millisecond,
+		Method: http.MethodPut,
+		Path:   "/v0.5/traces",
+		Header: http.Header{},
+		Body:   []byte{},
+	},
+}
+
+func TestReplay(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []*Record
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, &Record{
+			Method: req.Method,
+			Path:   req.URL.RequestURI(),
+			Header: req.Header,
+			Body:   body,
+		})
+		if req.URL.Path == "/v0.5/traces" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	for _, speed := range []float64{0, 2} {
+		received = nil
+		start := time.Now()
+		stats, err := Replay(context.Background(), writeTestFile(t, testRecords), srv.URL, speed)
+		require.NoError(t, err)
+		if speed > 0 {
+			assert.GreaterOrEqual(t, int64(time.Since(start)), int64(50*time.Millisecond))
+		}
+		assert.Equal(t, Stats{Sent: 3, Failed: 1}, stats)
+		require.Len(t, received, len(testRecords))
+		for i, want := range testRecords {
+			got := received[i]
+			assert.Equal(t, want.Method, got.Method)
+			assert.Equal(t, want.Path, got.Path)
+			assert.Equal(t, want.Body, got.Body)
+			for k := range want.Header {
+				assert.Equal(t, want.Header.Get(k), got.Header.Get(k))
+			}
+		}
+	}
+
+	t.Run("invalid-speed", func(t *testing.T) {
+		_, err := Replay(context.Background(), writeTestFile(t, testRecords), srv.URL, -1)
+		assert.Error(t, err)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Replay(ctx, writeTestFile(t, testRecords), srv.URL, 1)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,352 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+const (
+	// KeyTailSamplingPolicy is the key of the tag set on the chunks kept by the TailSampler,
+	// holding the name of the policy which kept them.
+	KeyTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+	// tailFallbackPolicy is the name reported for traces kept by the fallback rate.
+	tailFallbackPolicy = "probabilistic"
+
+	// tailTickMin is the minimum interval at which buffered traces are checked for a decision.
+	tailTickMin = 100 * time.Millisecond
+)
+
+// TailChunk is a chunk buffered by the TailSampler.
+type TailChunk struct {
+	// Payload holds the metadata of the tracer payload the chunk was received in. Its chunks are not set.
+	// Chunks coming from the same payload share the same pointer.
+	Payload *pb.TracerPayload
+	// Chunk is the chunk itself.
+	Chunk *pb.TraceChunk
+	// Events holds the analyzed spans extracted from the chunk, to send instead of it if its
+	// trace is dropped. It is nil when there are none.
+	Events *pb.TraceChunk
+	// NumEvents holds the number of analyzed spans extracted from the chunk.
+	NumEvents int64
+}
+
+// TailSampledTrace holds the chunks of a trace on which the TailSampler made a decision.
+type TailSampledTrace struct {
+	Chunks []TailChunk
+	// Keep reports whether the trace was kept.
+	Keep bool
+}
+
+// tailPolicy keeps the traces having at least a span matching it.
+type tailPolicy struct {
+	name  string
+	match func(*pb.Span) bool
+}
+
+// tailTrace is a trace buffered by the TailSampler.
+type tailTrace struct {
+	traceID  uint64
+	deadline time.Time
+	chunks   []TailChunk
+	size     int64
+}
+
+// tailDecision is a decision remembered by the TailSampler, to apply it to the chunks of a trace
+// received after it was made.
+type tailDecision struct {
+	keep   bool
+	expire time.Time
+}
+
+// TailSampler buffers the chunks of traces for a decision window, then keeps or drops each
+// complete trace based on policies evaluated on all of its spans: latency over a threshold,
+// tag presence or error on any span, or a probabilistic fallback.
+type TailSampler struct {
+	// Variables access through the 'atomic' package must be 64bits aligned.
+	kept       int64
+	dropped    int64
+	shedTraces int64
+	shedSpans  int64
+
+	policies     []tailPolicy
+	fallbackRate float64
+	decisionWait time.Duration
+	maxMemory    int64
+	decide       func(TailSampledTrace)
+
+	mu sync.Mutex
+	// traces holds the buffered traces, by ID.
+	traces map[uint64]*tailTrace
+	// pending holds the buffered traces, by order of arrival.
+	pending []*tailTrace
+	// decided holds the recent decisions, by trace ID.
+	decided map[uint64]tailDecision
+	// memory holds the size of the buffered traces.
+	memory int64
+
+	exit chan struct{}
+	done chan struct{}
+}
+
+// NewTailSampler returns a TailSampler configured by conf. The decision made on each trace is
+// passed to decide, once it is complete or late chunks arrive.
+func NewTailSampler(conf *config.TailSamplingConfig, decide func(TailSampledTrace)) (*TailSampler, error) {
+	policies := make([]tailPolicy, 0, len(conf.Policies))
+	for i, p := range conf.Policies {
+		policy, err := newTailPolicy(p)
+		if err != nil {
+			return nil, fmt.Errorf("tail sampling policy #%d: %v", i, err)
+		}
+		policies = append(policies, policy)
+	}
+	if conf.DecisionWait <= 0 {
+		return nil, fmt.Errorf("invalid tail sampling decision wait: %s", conf.DecisionWait)
+	}
+	return &TailSampler{
+		policies:     policies,
+		fallbackRate: conf.FallbackRate,
+		decisionWait: conf.DecisionWait,
+		maxMemory:    conf.MaxMemoryBytes,
+		decide:       decide,
+		traces:       make(map[uint64]*tailTrace),
+		decided:      make(map[uint64]tailDecision),
+		exit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}, nil
+}
+
+func newTailPolicy(p config.TailSamplingPolicy) (tailPolicy, error) {
+	policy := tailPolicy{name: p.Name}
+	if policy.name == "" {
+		policy.name = p.Type
+	}
+	switch p.Type {
+	case "latency":
+		if p.LatencyThresholdMs <= 0 {
+			return policy, fmt.Errorf("invalid latency threshold: %v", p.LatencyThresholdMs)
+		}
+		threshold := int64(p.LatencyThresholdMs * float64(time.Millisecond))
+		policy.match = func(s *pb.Span) bool { return s.Duration > threshold }
+	case "tag":
+		if p.Key == "" {
+			return policy, fmt.Errorf("missing tag key")
+		}
+		key, value := p.Key, p.Value
+		policy.match = func(s *pb.Span) bool {
+			v, ok := s.Meta[key]
+			if !ok {
+				if m, ok := s.Metrics[key]; ok {
+					v = strconv.FormatFloat(m, 'f', -1, 64)
+				} else {
+					return false
+				}
+			}
+			return value == "" || v == value
+		}
+	case "error":
+		policy.match = func(s *pb.Span) bool { return s.Error != 0 }
+	default:
+		return policy, fmt.Errorf("unknown type %q", p.Type)
+	}
+	return policy, nil
+}
+
+// Start starts making the decisions on the buffered traces once their decision window is over.
+func (s *TailSampler) Start() {
+	tick := s.decisionWait / 10
+	if tick < tailTickMin {
+		tick = tailTickMin
+	}
+	go func() {
+		defer close(s.done)
+		decideTicker := time.NewTicker(tick)
+		defer decideTicker.Stop()
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case now := <-decideTicker.C:
+				s.flush(now, false)
+			case <-statsTicker.C:
+				s.report()
+			case <-s.exit:
+				// don't lose the buffered traces
+				s.flush(time.Now(), true)
+				s.report()
+				return
+			}
+		}
+	}()
+}
+
+// Stop makes a decision on all the buffered traces and stops the sampler.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	<-s.done
+}
+
+// Add buffers the given chunk until a decision is made on its trace. If the decision was
+// already made, it is applied to the chunk right away. The chunk and its spans must not
+// be modified afterwards.
+func (s *TailSampler) Add(now time.Time, c TailChunk) {
+	if len(c.Chunk.Spans) == 0 {
+		return
+	}
+	traceID := c.Chunk.Spans[0].TraceID
+	size := int64(c.Chunk.Msgsize())
+	if c.Events != nil {
+		size += int64(c.Events.Msgsize())
+	}
+
+	s.mu.Lock()
+	if d, ok := s.decided[traceID]; ok {
+		s.mu.Unlock()
+		s.apply(TailSampledTrace{Chunks: []TailChunk{c}, Keep: d.keep}, "")
+		return
+	}
+	t, ok := s.traces[traceID]
+	if !ok {
+		t = &tailTrace{traceID: traceID, deadline: now.Add(s.decisionWait)}
+		s.traces[traceID] = t
+		s.pending = append(s.pending, t)
+	}
+	t.chunks = append(t.chunks, c)
+	t.size += size
+	s.memory += size
+	shed := s.shedLocked(now)
+	s.mu.Unlock()
+
+	// the events of the shed traces are still sent
+	for _, t := range shed {
+		s.apply(TailSampledTrace{Chunks: t.chunks, Keep: false}, "")
+	}
+}
+
+// shedLocked drops the oldest buffered traces until their size fits in the memory budget, and
+// returns them. It must be called with s.mu held.
+func (s *TailSampler) shedLocked(now time.Time) []*tailTrace {
+	if s.maxMemory <= 0 {
+		return nil
+	}
+	var shed []*tailTrace
+	for s.memory > s.maxMemory && len(s.pending) > 0 {
+		t := s.popLocked()
+		shed = append(shed, t)
+		s.decided[t.traceID] = tailDecision{keep: false, expire: now.Add(s.decisionWait)}
+		atomic.AddInt64(&s.shedTraces, 1)
+		for _, c := range t.chunks {
+			atomic.AddInt64(&s.shedSpans, int64(len(c.Chunk.Spans)))
+		}
+		log.Debugf("Tail sampler over its memory budget (%d bytes), dropped trace %d.", s.maxMemory, t.traceID)
+	}
+	return shed
+}
+
+// popLocked removes the oldest buffered trace. It must be called with s.mu held.
+func (s *TailSampler) popLocked() *tailTrace {
+	t := s.pending[0]
+	s.pending[0] = nil
+	s.pending = s.pending[1:]
+	delete(s.traces, t.traceID)
+	s.memory -= t.size
+	return t
+}
+
+// flush makes a decision on the traces whose decision window is over at now, or on all the
+// buffered traces if all is true.
+func (s *TailSampler) flush(now time.Time, all bool) {
+	type decision struct {
+		trace  TailSampledTrace
+		policy string
+	}
+	var ready []decision
+	s.mu.Lock()
+	for id, d := range s.decided {
+		if now.After(d.expire) {
+			delete(s.decided, id)
+		}
+	}
+	for len(s.pending) > 0 && (all || !s.pending[0].deadline.After(now)) {
+		t := s.popLocked()
+		keep, policy := s.sample(t)
+		// remember the decision for the chunks arriving late
+		s.decided[t.traceID] = tailDecision{keep: keep, expire: now.Add(s.decisionWait)}
+		ready = append(ready, decision{trace: TailSampledTrace{Chunks: t.chunks, Keep: keep}, policy: policy})
+		if keep {
+			atomic.AddInt64(&s.kept, 1)
+		} else {
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, d := range ready {
+		s.apply(d.trace, d.policy)
+	}
+}
+
+// sample reports whether the trace t should be kept, along with the name of the policy keeping it.
+func (s *TailSampler) sample(t *tailTrace) (keep bool, policy string) {
+	for _, p := range s.policies {
+		for _, c := range t.chunks {
+			for _, span := range c.Chunk.Spans {
+				if p.match(span) {
+					return true, p.name
+				}
+			}
+		}
+	}
+	if SampleByRate(t.traceID, s.fallbackRate) {
+		return true, tailFallbackPolicy
+	}
+	return false, ""
+}
+
+// apply marks the chunks of t with the decision and passes it to s.decide. The spans may still be
+// read concurrently (e.g. by the concentrator), so the kept chunks are copied instead of modified.
+func (s *TailSampler) apply(t TailSampledTrace, policy string) {
+	if t.Keep {
+		for i, c := range t.Chunks {
+			chunk := *c.Chunk
+			if p, _ := GetSamplingPriority(&chunk); p < PriorityAutoKeep {
+				chunk.Priority = int32(PriorityAutoKeep)
+			}
+			if policy != "" {
+				tags := make(map[string]string, len(chunk.Tags)+1)
+				for k, v := range chunk.Tags {
+					tags[k] = v
+				}
+				tags[KeyTailSamplingPolicy] = policy
+				chunk.Tags = tags
+			}
+			t.Chunks[i].Chunk = &chunk
+		}
+	}
+	s.decide(t)
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	buffered, memory := len(s.traces), s.memory
+	s.mu.Unlock()
+	metrics.Gauge("datadog.trace_agent.sampler.tail.buffered_traces", float64(buffered), nil, 1)
+	metrics.Gauge("datadog.trace_agent.sampler.tail.memory_bytes", float64(memory), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.kept", atomic.SwapInt64(&s.kept, 0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.dropped", atomic.SwapInt64(&s.dropped, 0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.shed_traces", atomic.SwapInt64(&s.shedTraces, 0), nil, 1)
+	metrics.Count("datadog.trace_agent.sampler.tail.shed_spans", atomic.SwapInt64(&s.shedSpans, 0), nil, 1)
+}
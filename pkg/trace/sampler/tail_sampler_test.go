@@ -0,0 +1,262 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTailSampler returns a TailSampler with the given policies, along with the decisions it made.
+func newTestTailSampler(t *testing.T, policies ...config.TailSamplingPolicy) (*TailSampler, *[]TailSampledTrace) {
+	var decisions []TailSampledTrace
+	s, err := NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait: time.Second,
+		Policies:     policies,
+	}, func(t TailSampledTrace) {
+		decisions = append(decisions, t)
+	})
+	require.NoError(t, err)
+	return s, &decisions
+}
+
+func tailTestChunk(traceID uint64, spans ...*pb.Span) TailChunk {
+	for i, s := range spans {
+		s.TraceID = traceID
+		s.SpanID = uint64(i + 1)
+	}
+	return TailChunk{
+		Payload: &pb.TracerPayload{},
+		Chunk:   &pb.TraceChunk{Priority: int32(PriorityAutoDrop), Spans: spans},
+	}
+}
+
+func TestNewTailSamplerInvalid(t *testing.T) {
+	for name, conf := range map[string]*config.TailSamplingConfig{
+		"decision-wait": {},
+		"type":          {DecisionWait: time.Second, Policies: []config.TailSamplingPolicy{{Type: "unknown"}}},
+		"latency":       {DecisionWait: time.Second, Policies: []config.TailSamplingPolicy{{Type: "latency"}}},
+		"tag":           {DecisionWait: time.Second, Policies: []config.TailSamplingPolicy{{Type: "tag"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTailSampler(conf, func(TailSampledTrace) {})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	s, decisions := newTestTailSampler(t,
+		config.TailSamplingPolicy{Name: "slow", Type: "latency", LatencyThresholdMs: 500},
+		config.TailSamplingPolicy{Type: "tag", Key: "http.status_code", Value: "500"},
+		config.TailSamplingPolicy{Name: "flagged", Type: "tag", Key: "flagged"},
+		config.TailSamplingPolicy{Type: "error"},
+	)
+	now := time.Now()
+	for id, span := range map[uint64]*pb.Span{
+		1: {Duration: int64(time.Second)},
+		2: {Meta: map[string]string{"http.status_code": "500"}},
+		3: {Metrics: map[string]float64{"flagged": 1}},
+		4: {Error: 1},
+		5: {Duration: int64(time.Millisecond), Meta: map[string]string{"http.status_code": "200"}},
+	} {
+		// the matching span is not the first one of the trace
+		s.Add(now, tailTestChunk(id, &pb.Span{}, span))
+	}
+	s.flush(now.Add(time.Second), false)
+
+	policies := make(map[uint64]string)
+	for _, d := range *decisions {
+		require.Len(t, d.Chunks, 1)
+		c := d.Chunks[0].Chunk
+		if d.Keep {
+			assert.EqualValues(t, PriorityAutoKeep, c.Priority)
+		} else {
+			assert.EqualValues(t, PriorityAutoDrop, c.Priority)
+		}
+		policies[c.Spans[0].TraceID] = c.Tags[KeyTailSamplingPolicy]
+	}
+	assert.Equal(t, map[uint64]string{
+		1: "slow",
+		2: "tag",
+		3: "flagged",
+		4: "error",
+		5: "",
+	}, policies)
+}
+
+func TestTailSamplerFallback(t *testing.T) {
+	var kept int
+	s, err := NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait: time.Second,
+		FallbackRate: 1,
+	}, func(st TailSampledTrace) {
+		if st.Keep {
+			kept++
+			assert.Equal(t, tailFallbackPolicy, st.Chunks[0].Chunk.Tags[KeyTailSamplingPolicy])
+		}
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	for i := uint64(1); i <= 10; i++ {
+		s.Add(now, tailTestChunk(i, &pb.Span{}))
+	}
+	s.flush(now, true)
+	assert.Equal(t, 10, kept)
+}
+
+func TestTailSamplerDecisionWait(t *testing.T) {
+	assert := assert.New(t)
+	s, decisions := newTestTailSampler(t, config.TailSamplingPolicy{Type: "error"})
+	now := time.Now()
+
+	s.Add(now, tailTestChunk(1, &pb.Span{}))
+	s.Add(now.Add(500*time.Millisecond), tailTestChunk(2, &pb.Span{}))
+	// the error arrives in a later chunk, within the decision window
+	s.Add(now.Add(900*time.Millisecond), tailTestChunk(1, &pb.Span{Error: 1}))
+
+	s.flush(now.Add(999*time.Millisecond), false)
+	assert.Len(*decisions, 0)
+
+	s.flush(now.Add(time.Second), false)
+	require.Len(t, *decisions, 1)
+	assert.True((*decisions)[0].Keep)
+	assert.Len((*decisions)[0].Chunks, 2)
+
+	// chunks arriving after the decision follow it
+	s.Add(now.Add(1200*time.Millisecond), tailTestChunk(1, &pb.Span{}))
+	require.Len(t, *decisions, 2)
+	assert.True((*decisions)[1].Keep)
+	assert.Len((*decisions)[1].Chunks, 1)
+
+	s.flush(now.Add(1500*time.Millisecond), false)
+	require.Len(t, *decisions, 3)
+	assert.False((*decisions)[2].Keep)
+
+	// decisions are forgotten after a while
+	s.flush(now.Add(5*time.Second), false)
+	assert.Len(s.decided, 0)
+}
+
+func TestTailSamplerInputUnmodified(t *testing.T) {
+	s, decisions := newTestTailSampler(t, config.TailSamplingPolicy{Type: "error"})
+	now := time.Now()
+	c := tailTestChunk(1, &pb.Span{Error: 1})
+	c.Chunk.Tags = map[string]string{"a": "b"}
+	s.Add(now, c)
+	s.flush(now, true)
+
+	require.Len(t, *decisions, 1)
+	kept := (*decisions)[0].Chunks[0].Chunk
+	assert.Equal(t, map[string]string{"a": "b", KeyTailSamplingPolicy: "error"}, kept.Tags)
+	assert.Equal(t, map[string]string{"a": "b"}, c.Chunk.Tags)
+	assert.EqualValues(t, PriorityAutoDrop, c.Chunk.Priority)
+}
+
+func TestTailSamplerMaxMemory(t *testing.T) {
+	assert := assert.New(t)
+	var decisions []TailSampledTrace
+	size := int64(tailTestChunk(1, &pb.Span{Error: 1}).Chunk.Msgsize())
+	s, err := NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait:   time.Second,
+		MaxMemoryBytes: 2 * size,
+		Policies:       []config.TailSamplingPolicy{{Type: "error"}},
+	}, func(t TailSampledTrace) {
+		decisions = append(decisions, t)
+	})
+	require.NoError(t, err)
+	now := time.Now()
+
+	for i := uint64(1); i <= 3; i++ {
+		s.Add(now, tailTestChunk(i, &pb.Span{Error: 1}))
+	}
+	// the oldest trace was dropped to make room for the new one
+	assert.EqualValues(1, s.shedTraces)
+	assert.EqualValues(1, s.shedSpans)
+	assert.EqualValues(2*size, s.memory)
+	require.Len(t, decisions, 1)
+	assert.False(decisions[0].Keep)
+
+	s.Add(now, tailTestChunk(1, &pb.Span{Error: 1}))
+	require.Len(t, decisions, 2)
+	assert.False(decisions[1].Keep)
+
+	s.flush(now, true)
+	require.Len(t, decisions, 4)
+	for _, d := range decisions[2:] {
+		assert.True(d.Keep)
+	}
+	assert.EqualValues(0, s.memory)
+}
+
+func TestTailSamplerShedEvents(t *testing.T) {
+	var decisions []TailSampledTrace
+	c := tailTestChunk(1, &pb.Span{})
+	c.Events = &pb.TraceChunk{Spans: c.Chunk.Spans}
+	c.NumEvents = 1
+	s, err := NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait:   time.Second,
+		MaxMemoryBytes: int64(c.Chunk.Msgsize()),
+	}, func(t TailSampledTrace) {
+		decisions = append(decisions, t)
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.Add(now, c)
+	s.Add(now, tailTestChunk(2, &pb.Span{}))
+
+	// the shed trace is dropped right away, along with its events to send
+	require.Len(t, decisions, 1)
+	assert.False(t, decisions[0].Keep)
+	require.Len(t, decisions[0].Chunks, 1)
+	assert.Equal(t, c.Events, decisions[0].Chunks[0].Events)
+	assert.EqualValues(t, 1, decisions[0].Chunks[0].NumEvents)
+}
+
+func TestTailSamplerEventsMemory(t *testing.T) {
+	c := tailTestChunk(1, &pb.Span{})
+	c.Events = &pb.TraceChunk{Spans: c.Chunk.Spans, DroppedTrace: true}
+	c.NumEvents = 1
+	size := int64(c.Chunk.Msgsize() + c.Events.Msgsize())
+	s, err := NewTailSampler(&config.TailSamplingConfig{
+		DecisionWait:   time.Second,
+		MaxMemoryBytes: size,
+	}, func(TailSampledTrace) {})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.Add(now, c)
+	// the events are accounted in the memory of the trace
+	assert.EqualValues(t, size, s.memory)
+	assert.EqualValues(t, 0, s.shedTraces)
+
+	// the events of a new trace go over the memory budget
+	c = tailTestChunk(2, &pb.Span{})
+	c.Events = &pb.TraceChunk{Spans: c.Chunk.Spans, DroppedTrace: true}
+	s.Add(now, c)
+	assert.EqualValues(t, 1, s.shedTraces)
+	assert.EqualValues(t, size, s.memory)
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	var decisions []TailSampledTrace
+	s, err := NewTailSampler(&config.TailSamplingConfig{DecisionWait: time.Hour}, func(t TailSampledTrace) {
+		decisions = append(decisions, t)
+	})
+	require.NoError(t, err)
+	s.Start()
+	s.Add(time.Now(), tailTestChunk(1, &pb.Span{}))
+	s.Stop()
+	// buffered traces are not lost on exit
+	assert.Len(t, decisions, 1)
+}
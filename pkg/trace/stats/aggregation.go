@@ -17,6 +17,9 @@ import (
 const (
 	tagStatusCode = "http.status_code"
 	tagSynthetics = "synthetics"
+
+	// extraTagsSeparator separates the "key:value" tags in BucketsAggregationKey.ExtraTags.
+	extraTagsSeparator = "\x00"
 )
 
 // Aggregation contains all the dimension on which we aggregate statistics.
@@ -33,6 +36,9 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	// ExtraTags holds the "key:value" tags of the extra aggregation dimensions, joined by
+	// extraTagsSeparator. See config.AgentConfig.ExtraAggregationTags.
+	ExtraTags string
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -56,6 +62,66 @@ func getStatusCode(s *pb.Span) uint32 {
 	return uint32(c)
 }
 
+// getExtraTags returns the encoded "key:value" tags of the span for the given keys, in their order.
+// The keys missing from the span are skipped.
+func getExtraTags(s *pb.Span, keys []string) string {
+	var tags []string
+	for _, k := range keys {
+		if v, ok := s.Meta[k]; ok {
+			tags = append(tags, k+":"+v)
+		}
+	}
+	return encodeExtraTags(tags)
+}
+
+// filterExtraTags returns the encoded tags out of the given "key:value" tags having one of the
+// given keys, in the order of the keys.
+func filterExtraTags(tags []string, keys []string) string {
+	if len(tags) == 0 || len(keys) == 0 {
+		return ""
+	}
+	var filtered []string
+	for _, k := range keys {
+		for _, t := range tags {
+			if len(t) > len(k) && t[len(k)] == ':' && strings.HasPrefix(t, k) {
+				filtered = append(filtered, t)
+				break
+			}
+		}
+	}
+	return encodeExtraTags(filtered)
+}
+
+// extraTagSets holds distinct sets of encoded extra tags.
+type extraTagSets map[string]struct{}
+
+// admit reports whether the encoded tags are part of the sets, adding them unless the sets
+// already hold max elements. A max of 0 means no limit.
+func (sets *extraTagSets) admit(tags string, max int) bool {
+	if _, ok := (*sets)[tags]; ok {
+		return true
+	}
+	if max > 0 && len(*sets) >= max {
+		return false
+	}
+	if *sets == nil {
+		*sets = make(extraTagSets)
+	}
+	(*sets)[tags] = struct{}{}
+	return true
+}
+
+func encodeExtraTags(tags []string) string {
+	return strings.Join(tags, extraTagsSeparator)
+}
+
+func decodeExtraTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, extraTagsSeparator)
+}
+
 // NewAggregationFromSpan creates a new aggregation from the provided span and env
 func NewAggregationFromSpan(s *pb.Span, origin string, aggKey PayloadAggregationKey) Aggregation {
 	synthetics := strings.HasPrefix(origin, tagSynthetics)
@@ -81,6 +147,7 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			ExtraTags:  encodeExtraTags(g.ExtraTags),
 		},
 	}
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
 )
@@ -45,6 +46,11 @@ type ClientStatsAggregator struct {
 	agentEnv      string
 	agentHostname string
 
+	// extraAggregationTags holds the span tags by which stats are additionally aggregated.
+	extraAggregationTags []string
+	// maxExtraTagSets is the maximum number of distinct sets of extra tags in a bucket.
+	maxExtraTagSets int
+
 	exit chan struct{}
 	done chan struct{}
 }
@@ -61,6 +67,9 @@ func NewClientStatsAggregator(conf *config.AgentConfig, out chan pb.StatsPayload
 		oldestTs:      alignAggTs(time.Now().Add(bucketDuration - oldestBucketStart)),
 		exit:          make(chan struct{}),
 		done:          make(chan struct{}),
+
+		extraAggregationTags: conf.ExtraAggregationTags,
+		maxExtraTagSets:      conf.MaxExtraAggregationTagSets,
 	}
 }
 
@@ -121,6 +130,12 @@ func (a *ClientStatsAggregator) getAggregationBucketTime(now, bs time.Time) (tim
 }
 
 func (a *ClientStatsAggregator) add(now time.Time, p pb.ClientStatsPayload) {
+	var capped int64
+	defer func() {
+		if capped > 0 {
+			metrics.Count("datadog.trace_agent.stats.extra_tags_capped_spans", capped, nil, 1)
+		}
+	}()
 	for _, clientBucket := range p.Stats {
 		clientBucketStart := time.Unix(0, int64(clientBucket.Start))
 		ts, shifted := a.getAggregationBucketTime(now, clientBucketStart)
@@ -133,11 +148,31 @@ func (a *ClientStatsAggregator) add(now time.Time, p pb.ClientStatsPayload) {
 			b = &bucket{ts: ts}
 			a.buckets[ts.Unix()] = b
 		}
+		capped += a.filterExtraTags(b, clientBucket.Stats)
 		p.Stats = []pb.ClientStatsBucket{clientBucket}
 		a.flush(b.add(p))
 	}
 }
 
+// filterExtraTags removes the extra tags of the grouped stats which are not configured as extra
+// aggregation tags. The grouped stats bringing a new set of extra tags while the bucket already
+// holds the maximum number of them are stripped of all their extra tags. It returns the number of
+// spans (hits) affected by this limit.
+func (a *ClientStatsAggregator) filterExtraTags(b *bucket, stats []pb.ClientGroupedStats) (capped int64) {
+	for i, g := range stats {
+		if len(g.ExtraTags) == 0 {
+			continue
+		}
+		tags := filterExtraTags(g.ExtraTags, a.extraAggregationTags)
+		if tags != "" && !b.extraTagSets.admit(tags, a.maxExtraTagSets) {
+			tags = ""
+			capped += int64(g.Hits)
+		}
+		stats[i].ExtraTags = decodeExtraTags(tags)
+	}
+	return capped
+}
+
 func (a *ClientStatsAggregator) flush(p []pb.ClientStatsPayload) {
 	if len(p) == 0 {
 		return
@@ -170,6 +205,8 @@ type bucket struct {
 	n int
 	// agg contains the aggregated Hits/Errors/Duration counts
 	agg map[PayloadAggregationKey]map[BucketsAggregationKey]*aggregatedCounts
+	// extraTagSets holds the distinct sets of extra aggregation tags seen in the bucket.
+	extraTagSets extraTagSets
 }
 
 func (b *bucket) add(p pb.ClientStatsPayload) []pb.ClientStatsPayload {
@@ -236,6 +273,7 @@ func (b *bucket) aggregationToPayloads() []pb.ClientStatsPayload {
 				HTTPStatusCode: aggrKey.StatusCode,
 				Type:           aggrKey.Type,
 				Synthetics:     aggrKey.Synthetics,
+				ExtraTags:      decodeExtraTags(aggrKey.ExtraTags),
 				Hits:           counts.hits,
 				Errors:         counts.errors,
 				Duration:       counts.duration,
@@ -270,6 +308,7 @@ func newBucketAggregationKey(b pb.ClientGroupedStats) BucketsAggregationKey {
 		Type:       b.Type,
 		Synthetics: b.Synthetics,
 		StatusCode: b.HTTPStatusCode,
+		ExtraTags:  encodeExtraTags(b.ExtraTags),
 	}
 }
 
@@ -6,6 +6,7 @@
 package stats
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	fuzz "github.com/google/gofuzz"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var fuzzer = fuzz.NewWithSeed(1)
@@ -70,6 +72,10 @@ func getTestStatsWithStart(start time.Time) pb.ClientStatsPayload {
 	b := pb.ClientStatsBucket{}
 	fuzzer.Fuzz(&b)
 	b.Start = uint64(start.UnixNano())
+	for i := range b.Stats {
+		// extra tags are filtered by the aggregator
+		b.Stats[i].ExtraTags = nil
+	}
 	p := pb.ClientStatsPayload{}
 	fuzzer.Fuzz(&p)
 	p.Tags = nil
@@ -332,6 +338,42 @@ func TestCountAggregation(t *testing.T) {
 	}
 }
 
+func TestExtraAggregationTags(t *testing.T) {
+	assert := assert.New(t)
+	a := newTestAggregator()
+	a.extraAggregationTags = []string{"peer.service", "region"}
+	a.maxExtraTagSets = 2
+	testTime := time.Unix(time.Now().Unix(), 0)
+
+	payload := func(hits uint64, tags ...string) pb.ClientStatsPayload {
+		p := payloadWithCounts(testTime, BucketsAggregationKey{Service: "s"}, hits, 0, 0)
+		p.Stats[0].Stats[0].ExtraTags = tags
+		return p
+	}
+	a.add(testTime, payload(1, "region:us", "peer.service:db1", "other:x"))
+	a.add(testTime, payload(2, "peer.service:db1", "region:us"))
+	a.add(testTime, payload(4, "peer.service:db2"))
+	// over the limit of distinct sets
+	a.add(testTime, payload(8, "peer.service:db3"))
+	a.add(testTime, payload(16))
+	a.flushOnTime(testTime.Add(oldestBucketStart + time.Nanosecond))
+	require.Len(t, a.out, 5)
+	for i := 0; i < 4; i++ {
+		<-a.out
+	}
+	aggCounts := <-a.out
+	assertAggCountsPayload(t, aggCounts)
+	hits := make(map[string]uint64)
+	for _, g := range aggCounts.Stats[0].Stats[0].Stats {
+		hits[strings.Join(g.ExtraTags, ",")] = g.Hits
+	}
+	assert.Equal(map[string]uint64{
+		"peer.service:db1,region:us": 3,
+		"peer.service:db2":           4,
+		"":                           24,
+	}, hits)
+}
+
 func deepCopy(p pb.ClientStatsPayload) pb.ClientStatsPayload {
 	new := p
 	new.Stats = deepCopyStatsBucket(p.Stats)
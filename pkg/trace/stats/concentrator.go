@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/config/features"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
@@ -46,6 +47,14 @@ type Concentrator struct {
 	mu            sync.Mutex
 	agentEnv      string
 	agentHostname string
+
+	// extraAggregationTags holds the span tags by which stats are additionally aggregated.
+	extraAggregationTags []string
+	// maxExtraTagSets is the maximum number of distinct sets of extra tags in a bucket.
+	maxExtraTagSets int
+	// cappedSpans counts the spans aggregated without their extra tags since the last flush,
+	// because of maxExtraTagSets.
+	cappedSpans int64
 }
 
 // NewConcentrator initializes a new concentrator ready to be started
@@ -64,6 +73,9 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 		exit:          make(chan struct{}),
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
+
+		extraAggregationTags: conf.ExtraAggregationTags,
+		maxExtraTagSets:      conf.MaxExtraAggregationTagSets,
 	}
 	return &c
 }
@@ -179,7 +191,13 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
 			c.buckets[btime] = b
 		}
-		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey)
+		var extraTags string
+		if len(c.extraAggregationTags) > 0 {
+			extraTags = getExtraTags(s, c.extraAggregationTags)
+		}
+		if b.handleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey, extraTags, c.maxExtraTagSets) {
+			c.cappedSpans++
+		}
 	}
 }
 
@@ -212,7 +230,13 @@ func (c *Concentrator) flushNow(now int64) pb.StatsPayload {
 		log.Debugf("update oldestTs to %d", newOldestTs)
 		c.oldestTs = newOldestTs
 	}
+	capped := c.cappedSpans
+	c.cappedSpans = 0
 	c.mu.Unlock()
+	if capped > 0 {
+		log.Debugf("Aggregated %d spans without their extra tags, over the limit of %d distinct sets per bucket.", capped, c.maxExtraTagSets)
+		metrics.Count("datadog.trace_agent.stats.extra_tags_capped_spans", capped, nil, 1)
+	}
 	sb := make([]pb.ClientStatsPayload, 0, len(m))
 	for k, s := range m {
 		p := pb.ClientStatsPayload{
@@ -8,6 +8,7 @@ package stats
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -528,3 +529,42 @@ func TestIgnoresPartialSpans(t *testing.T) {
 	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
 	assert.Empty(stats.GetStats())
 }
+
+func TestConcentratorExtraAggregationTags(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	var spans []*pb.Span
+	for i, tags := range []map[string]string{
+		{"peer.service": "db1", "region": "us"},
+		{"peer.service": "db1", "region": "us"},
+		{"region": "eu", "peer.service": "db2", "other": "x"},
+		{"peer.service": "db3"},
+		{},
+	} {
+		span := testSpan(uint64(i+1), 0, 50, 5, "A1", "resource1", 0)
+		span.Meta = tags
+		spans = append(spans, span)
+	}
+	traceutil.ComputeTopLevel(spans)
+	testTrace := toProcessedTrace(spans, "none", "")
+
+	c := NewTestConcentrator(now)
+	c.extraAggregationTags = []string{"peer.service", "region"}
+	c.maxExtraTagSets = 2
+	c.addNow(testTrace, "")
+	assert.EqualValues(1, c.cappedSpans)
+
+	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+	assert.EqualValues(0, c.cappedSpans)
+	hits := make(map[string]uint64)
+	for _, g := range stats.Stats[0].Stats[0].Stats {
+		hits[strings.Join(g.ExtraTags, ",")] += g.Hits
+	}
+	assert.Equal(map[string]uint64{
+		"peer.service:db1,region:us": 2,
+		"peer.service:db2,region:eu": 1,
+		// over the limit, or without any of the extra tags
+		"": 2,
+	}, hits)
+}
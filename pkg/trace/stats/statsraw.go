@@ -72,6 +72,7 @@ func (s *groupedStats) export(a Aggregation) (pb.ClientGroupedStats, error) {
 		OkSummary:      okSummary,
 		ErrorSummary:   errSummary,
 		Synthetics:     a.Synthetics,
+		ExtraTags:      decodeExtraTags(a.ExtraTags),
 	}, nil
 }
 
@@ -101,6 +102,9 @@ type RawBucket struct {
 
 	// this should really remain private as it's subject to refactoring
 	data map[Aggregation]*groupedStats
+
+	// extraTagSets holds the distinct sets of extra aggregation tags seen in the bucket.
+	extraTagSets extraTagSets
 }
 
 // NewRawBucket opens a new calculation bucket for time ts and initializes it properly
@@ -145,11 +149,26 @@ func (sb *RawBucket) Export() map[PayloadAggregationKey]pb.ClientStatsBucket {
 
 // HandleSpan adds the span to this bucket stats, aggregated with the finest grain matching given aggregators
 func (sb *RawBucket) HandleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey) {
+	sb.handleSpan(s, weight, isTop, origin, aggKey, "", 0)
+}
+
+// handleSpan works like HandleSpan, additionally aggregating the span by the given encoded extra
+// tags. If the bucket already holds maxExtraTagSets other sets of extra tags, the span is aggregated
+// without them and capped is true. A maxExtraTagSets of 0 means no limit.
+func (sb *RawBucket) handleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey, extraTags string, maxExtraTagSets int) (capped bool) {
 	if aggKey.Env == "" {
 		panic("env should never be empty")
 	}
 	aggr := NewAggregationFromSpan(s, origin, aggKey)
+	if extraTags != "" {
+		if sb.extraTagSets.admit(extraTags, maxExtraTagSets) {
+			aggr.ExtraTags = extraTags
+		} else {
+			capped = true
+		}
+	}
 	sb.add(s, weight, isTop, aggr)
+	return capped
 }
 
 func (sb *RawBucket) add(s *pb.Span, weight float64, isTop bool, aggr Aggregation) {
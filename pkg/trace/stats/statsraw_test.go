@@ -298,3 +298,22 @@ var benchSpans = []*pb.Span{
 		Type:     "lamar",
 	},
 }
+
+func TestExtraTags(t *testing.T) {
+	assert := assert.New(t)
+	s := pb.Span{Meta: map[string]string{"region": "us", "peer.service": "db:1", "other": "x"}}
+	tags := getExtraTags(&s, []string{"peer.service", "missing", "region"})
+	assert.Equal([]string{"peer.service:db:1", "region:us"}, decodeExtraTags(tags))
+	assert.Equal("", getExtraTags(&s, []string{"missing"}))
+	assert.Nil(decodeExtraTags(""))
+
+	assert.Equal(tags, filterExtraTags([]string{"region:us", "other:x", "peer.service:db:1"}, []string{"peer.service", "region"}))
+	assert.Equal("", filterExtraTags([]string{"regions:us", "region"}, []string{"region"}))
+
+	var sets extraTagSets
+	assert.True(sets.admit("a", 2))
+	assert.True(sets.admit("b", 2))
+	assert.True(sets.admit("a", 2))
+	assert.False(sets.admit("c", 2))
+	assert.True(sets.admit("c", 0))
+}
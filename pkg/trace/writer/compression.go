@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// encodingGzip is the Content-Encoding of gzip compressed payloads.
+	encodingGzip = "gzip"
+	// encodingZstd is the Content-Encoding of zstd compressed payloads.
+	encodingZstd = "zstd"
+)
+
+// compressor compresses the payloads of a writer using the algorithm and level
+// found in its configuration.
+type compressor struct {
+	// encoding is the Content-Encoding of the compressed payloads.
+	encoding string
+	// level is the gzip compression level.
+	level int
+	// zstd is the encoder used when encoding is "zstd".
+	zstd *zstd.Encoder
+
+	// metricPrefix prefixes the names of the metrics reported by the compressor,
+	// e.g. "datadog.trace_agent.trace_writer".
+	metricPrefix string
+	tags         []string
+}
+
+// newCompressor returns a compressor configured by cfg, reporting metrics prefixed
+// by metricPrefix. It defaults to gzip at the gzip.BestSpeed level when cfg is nil
+// or invalid.
+func newCompressor(cfg *config.WriterConfig, metricPrefix string) *compressor {
+	c := &compressor{
+		encoding:     encodingGzip,
+		level:        gzip.BestSpeed,
+		metricPrefix: metricPrefix,
+	}
+	if cfg == nil {
+		c.tags = []string{"encoding:" + c.encoding}
+		return c
+	}
+	switch cfg.Compression {
+	case "", encodingGzip:
+		if cfg.CompressionLevel != 0 {
+			if cfg.CompressionLevel < gzip.HuffmanOnly || cfg.CompressionLevel > gzip.BestCompression {
+				log.Errorf("Invalid gzip compression level %d for %s, using %d.", cfg.CompressionLevel, metricPrefix, c.level)
+			} else {
+				c.level = cfg.CompressionLevel
+			}
+		}
+	case encodingZstd:
+		level := zstd.SpeedFastest
+		if cfg.CompressionLevel != 0 {
+			level = zstd.EncoderLevelFromZstd(cfg.CompressionLevel)
+		}
+		// a nil writer is allowed when only EncodeAll is used.
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
+		if err != nil {
+			log.Errorf("Error creating zstd encoder for %s, using gzip: %v", metricPrefix, err)
+			break
+		}
+		c.encoding = encodingZstd
+		c.zstd = enc
+	default:
+		log.Errorf("Unknown compression %q for %s, using gzip.", cfg.Compression, metricPrefix)
+	}
+	c.tags = []string{"encoding:" + c.encoding}
+	return c
+}
+
+// compress compresses src into dst, reporting the time it took and the compression ratio.
+func (c *compressor) compress(dst *bytes.Buffer, src []byte) error {
+	defer timing.Since(c.metricPrefix+".compress_ms", time.Now())
+	start := dst.Len()
+	if c.zstd != nil {
+		dst.Write(c.zstd.EncodeAll(src, make([]byte, 0, len(src)/2)))
+	} else {
+		gzipw, err := gzip.NewWriterLevel(dst, c.level)
+		if err != nil {
+			return err
+		}
+		if _, err := gzipw.Write(src); err != nil {
+			return err
+		}
+		if err := gzipw.Close(); err != nil {
+			return err
+		}
+	}
+	if n := dst.Len() - start; n > 0 {
+		metrics.Histogram(c.metricPrefix+".compression_ratio", float64(len(src))/float64(n), c.tags, 1)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decompress decompresses the body of a payload compressed with the given encoding.
+func decompress(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+	switch encoding {
+	case encodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		out, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		return out
+	case encodingZstd:
+		r, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		defer r.Close()
+		out, err := r.DecodeAll(body, nil)
+		require.NoError(t, err)
+		return out
+	}
+	t.Fatalf("unknown encoding %q", encoding)
+	return nil
+}
+
+func TestCompressor(t *testing.T) {
+	src := bytes.Repeat([]byte("a compressible trace payload "), 100)
+	for _, tt := range []struct {
+		cfg      *config.WriterConfig
+		encoding string
+		level    int
+	}{
+		{nil, encodingGzip, gzip.BestSpeed},
+		{&config.WriterConfig{}, encodingGzip, gzip.BestSpeed},
+		{&config.WriterConfig{Compression: "gzip", CompressionLevel: 6}, encodingGzip, 6},
+		{&config.WriterConfig{Compression: "gzip", CompressionLevel: 42}, encodingGzip, gzip.BestSpeed},
+		{&config.WriterConfig{Compression: "zstd"}, encodingZstd, 0},
+		{&config.WriterConfig{Compression: "zstd", CompressionLevel: 9}, encodingZstd, 0},
+		{&config.WriterConfig{Compression: "lz4"}, encodingGzip, gzip.BestSpeed},
+	} {
+		c := newCompressor(tt.cfg, "datadog.trace_agent.test_writer")
+		assert.Equal(t, tt.encoding, c.encoding)
+		if tt.encoding == encodingGzip {
+			assert.Equal(t, tt.level, c.level)
+		}
+
+		var dst bytes.Buffer
+		require.NoError(t, c.compress(&dst, src))
+		assert.Less(t, dst.Len(), len(src))
+		assert.Equal(t, src, decompress(t, tt.encoding, dst.Bytes()))
+	}
+}
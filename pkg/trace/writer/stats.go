@@ -6,9 +6,8 @@
 package writer
 
 import (
-	"compress/gzip"
+	"bytes"
 	"errors"
-	"io"
 	"math"
 	"strings"
 	"sync/atomic"
@@ -20,8 +19,6 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
-
-	"github.com/tinylib/msgp/msgp"
 )
 
 // pathStats is the target host API path for delivering stats.
@@ -40,11 +37,12 @@ const (
 
 // StatsWriter ingests stats buckets and flushes them to the API.
 type StatsWriter struct {
-	in      <-chan pb.StatsPayload
-	senders []*sender
-	stop    chan struct{}
-	stats   *info.StatsWriterInfo
-	conf    *config.AgentConfig
+	in       <-chan pb.StatsPayload
+	senders  []*sender
+	stop     chan struct{}
+	stats    *info.StatsWriterInfo
+	conf     *config.AgentConfig
+	compress *compressor
 
 	// syncMode reports whether the writer should flush on its own or only when FlushSync is called
 	syncMode  bool
@@ -64,6 +62,7 @@ func NewStatsWriter(cfg *config.AgentConfig, in <-chan pb.StatsPayload) *StatsWr
 		syncMode:  cfg.SynchronousFlushing,
 		easylog:   log.NewThrottled(5, 10*time.Second), // no more than 5 messages every 10 seconds
 		conf:      cfg,
+		compress:  newCompressor(cfg.StatsWriter, "datadog.trace_agent.stats_writer"),
 	}
 	climit := cfg.StatsWriter.ConnectionLimit
 	if climit == 0 {
@@ -141,9 +140,9 @@ func (w *StatsWriter) SendPayload(p pb.StatsPayload) {
 	req := newPayload(map[string]string{
 		headerLanguages:    strings.Join(info.Languages(), "|"),
 		"Content-Type":     "application/msgpack",
-		"Content-Encoding": "gzip",
+		"Content-Encoding": w.compress.encoding,
 	})
-	if err := encodePayload(req.body, p); err != nil {
+	if err := w.encodePayload(req.body, p); err != nil {
 		log.Errorf("Stats encoding error: %v", err)
 		return
 	}
@@ -161,18 +160,14 @@ func (w *StatsWriter) resetBuffer() {
 	w.payloads = make([]pb.StatsPayload, 0, len(w.payloads))
 }
 
-// encodePayload encodes the payload as Gzipped msgPack into w.
-func encodePayload(w io.Writer, payload pb.StatsPayload) error {
-	gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+// encodePayload encodes the payload as compressed msgPack into dst.
+func (w *StatsWriter) encodePayload(dst *bytes.Buffer, payload pb.StatsPayload) error {
+	b, err := payload.MarshalMsg(nil)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := gz.Close(); err != nil {
-			log.Errorf("Error closing gzip stream when writing stats payload: %v", err)
-		}
-	}()
-	return msgp.Encode(gz, &payload)
+	atomic.AddInt64(&w.stats.BytesUncompressed, int64(len(b)))
+	return w.compress.compress(dst, b)
 }
 
 // buildPayloads splits pb.ClientStatsPayload that have more than maxEntriesPerPayload
@@ -322,6 +317,7 @@ func (w *StatsWriter) report() {
 	metrics.Count("datadog.trace_agent.stats_writer.stats_buckets", atomic.SwapInt64(&w.stats.StatsBuckets, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.stats_entries", atomic.SwapInt64(&w.stats.StatsEntries, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.bytes", atomic.SwapInt64(&w.stats.Bytes, 0), nil, 1)
+	metrics.Count("datadog.trace_agent.stats_writer.bytes_uncompressed", atomic.SwapInt64(&w.stats.BytesUncompressed, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.retries", atomic.SwapInt64(&w.stats.Retries, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.splits", atomic.SwapInt64(&w.stats.Splits, 0), nil, 1)
 	metrics.Count("datadog.trace_agent.stats_writer.errors", atomic.SwapInt64(&w.stats.Errors, 0), nil, 1)
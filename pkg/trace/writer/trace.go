@@ -6,7 +6,6 @@
 package writer
 
 import (
-	"compress/gzip"
 	"errors"
 	"math"
 	"strings"
@@ -56,8 +55,9 @@ type TraceWriter struct {
 	senders   []*sender
 	stop      chan struct{}
 	stats     *info.TraceWriterInfo
-	wg        sync.WaitGroup // waits for gzippers
+	wg        sync.WaitGroup // waits for compressors
 	tick      time.Duration  // flush frequency
+	compress  *compressor
 
 	tracerPayloads []*pb.TracerPayload // tracer payloads buffered
 	bufferedSize   int                 // estimated buffer size
@@ -106,6 +106,7 @@ func NewTraceWriter(cfg *config.AgentConfig) *TraceWriter {
 		tw.tick = time.Duration(s*1000) * time.Millisecond
 	}
 	log.Debugf("Trace writer initialized (climit=%d qsize=%d)", climit, qsize)
+	tw.compress = newCompressor(cfg.TraceWriter, "datadog.trace_agent.trace_writer")
 	tw.senders = newSenders(cfg, tw, pathTraces, climit, qsize)
 	return tw
 }
@@ -244,27 +245,16 @@ func (w *TraceWriter) flush() {
 
 	w.wg.Add(1)
 	go func() {
-		defer timing.Since("datadog.trace_agent.trace_writer.compress_ms", time.Now())
 		defer w.wg.Done()
 		p := newPayload(map[string]string{
 			"Content-Type":     "application/x-protobuf",
-			"Content-Encoding": "gzip",
+			"Content-Encoding": w.compress.encoding,
 			headerLanguages:    strings.Join(info.Languages(), "|"),
 		})
-		gzipw, err := gzip.NewWriterLevel(p.body, gzip.BestSpeed)
-		if err != nil {
-			// it will never happen, unless an invalid compression is chosen;
-			// we know gzip.BestSpeed is valid.
-			log.Errorf("gzip.NewWriterLevel: %d", err)
+		if err := w.compress.compress(p.body, b); err != nil {
+			log.Errorf("Error compressing trace payload, data dropped: %v", err)
 			return
 		}
-		if _, err := gzipw.Write(b); err != nil {
-			log.Errorf("Error gzipping trace payload: %v", err)
-		}
-		if err := gzipw.Close(); err != nil {
-			log.Errorf("Error closing gzip stream when writing trace payload: %v", err)
-		}
-
 		sendPayloads(w.senders, p, w.syncMode)
 	}()
 }
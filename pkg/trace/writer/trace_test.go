@@ -6,8 +6,6 @@
 package writer
 
 import (
-	"compress/gzip"
-	"io/ioutil"
 	"reflect"
 	"runtime"
 	"sync"
@@ -53,6 +51,24 @@ func TestTraceWriter(t *testing.T) {
 		assert.Equal(t, 2, srv.Accepted())
 		payloadsContain(t, srv.Payloads(), testSpans)
 	})
+
+	t.Run("zstd", func(t *testing.T) {
+		srv := newTestServer()
+		cfg := *cfg
+		cfg.Endpoints = []*config.Endpoint{{APIKey: "123", Host: srv.URL}}
+		cfg.TraceWriter = &config.WriterConfig{ConnectionLimit: 200, QueueSize: 40, Compression: "zstd"}
+		testSpans := []*SampledChunks{randomSampledSpans(20, 8)}
+		tw := NewTraceWriter(&cfg)
+		tw.In = make(chan *SampledChunks)
+		go tw.Run()
+		tw.In <- testSpans[0]
+		tw.Stop()
+		assert.Equal(t, 1, srv.Accepted())
+		for _, p := range srv.Payloads() {
+			assert.Equal(t, "zstd", p.headers["Content-Encoding"])
+		}
+		payloadsContain(t, srv.Payloads(), testSpans)
+	})
 }
 
 func TestTraceWriterMultipleEndpointsConcurrent(t *testing.T) {
@@ -129,12 +145,9 @@ func payloadsContain(t *testing.T, payloads []*payload, sampledSpans []*SampledC
 	var all pb.AgentPayload
 	for _, p := range payloads {
 		assert := assert.New(t)
-		gzipr, err := gzip.NewReader(p.body)
-		assert.NoError(err)
-		slurp, err := ioutil.ReadAll(gzipr)
-		assert.NoError(err)
+		slurp := decompress(t, p.headers["Content-Encoding"], p.body.Bytes())
 		var payload pb.AgentPayload
-		err = proto.Unmarshal(slurp, &payload)
+		err := proto.Unmarshal(slurp, &payload)
 		assert.NoError(err)
 		assert.Equal(payload.HostName, testHostname)
 		assert.Equal(payload.Env, testEnv)
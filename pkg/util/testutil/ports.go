@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package testutil
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// GetAvailableTCPPort requests a random port number and makes sure it is available
+func GetAvailableTCPPort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	defer l.Close()
+
+	_, portString, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		return -1, fmt.Errorf("can't find an available tcp port: %s", err)
+	}
+	portInt, err := strconv.Atoi(portString)
+	if err != nil {
+		return -1, fmt.Errorf("can't convert tcp port: %s", err)
+	}
+
+	return portInt, nil
+}